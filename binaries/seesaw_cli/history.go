@@ -0,0 +1,118 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const historyFilename = ".seesaw_cli_history"
+
+// cmdHistory is a bounded command history that implements the
+// terminal.History interface and can be persisted to a file.
+type cmdHistory struct {
+	entries []string // Oldest entry first.
+	size    int
+}
+
+// newCmdHistory returns a new command history that retains at most size
+// entries.
+func newCmdHistory(size int) *cmdHistory {
+	if size < 0 {
+		size = 0
+	}
+	return &cmdHistory{size: size}
+}
+
+// Add adds a new, most recent entry to the history. Blank entries and
+// entries that duplicate the most recent entry are discarded.
+func (h *cmdHistory) Add(entry string) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" || h.size == 0 {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == entry {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+}
+
+// Len returns the number of entries in the history.
+func (h *cmdHistory) Len() int {
+	return len(h.entries)
+}
+
+// At returns the history entry at the given index, where index 0 is the
+// most recently added entry.
+func (h *cmdHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+// load reads history entries from the given file. Failure to read the file
+// results in the history being left unchanged.
+func (h *cmdHistory) load(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.Add(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// save writes the history entries to the given file, replacing any existing
+// content. The entries are written to a temporary file that is then renamed,
+// so that the history file is not left partially written.
+func (h *cmdHistory) save(filename string) error {
+	tmpFile := fmt.Sprintf("%s.%d", filename, os.Getpid())
+	defer os.Remove(tmpFile)
+	f, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, entry := range h.entries {
+		w.WriteString(entry)
+		w.WriteString("\n")
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filename)
+}
+
+// homeFile returns the path to the given file in the home directory of the
+// current user.
+func homeFile(filename string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, filename)
+}
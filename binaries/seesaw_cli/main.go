@@ -19,12 +19,16 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"os/user"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -36,9 +40,34 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 )
 
+const (
+	batchPrefix   = "> "
+	rcFilename    = ".seesawrc"
+	confirmPrompt = "Are you sure? [y/N] "
+	forceSuffix   = "!"
+)
+
 var (
 	command      = flag.String("c", "", "Command to execute")
-	engineSocket = flag.String("engine", seesaw.EngineSocket, "Seesaw Engine Socket")
+	commandFile  = flag.String("f", "", "File containing commands to execute")
+	keepGoing    = flag.Bool("k", false, "Continue executing commands from a file or stdin after an error")
+	engineSocket = flag.String("engine", seesaw.EngineSocket, "Seesaw Engine Socket, or tcp://host:port for a remote engine")
+	caCertFile   = flag.String("cacert", "", "CA certificates file used to verify a remote engine")
+	certFile     = flag.String("cert", "", "Client certificate file used for a remote engine")
+	keyFile      = flag.String("key", "", "Client key file used for a remote engine")
+	serverName   = flag.String("servername", "", "Expected server name of a remote engine")
+	histFile     = flag.String("histfile", homeFile(historyFilename), "File used to persist command history")
+	histSize     = flag.Int("histsize", 1000, "Maximum number of command history entries to retain")
+	rcFile       = flag.String("rcfile", homeFile(rcFilename), "File containing command alias definitions")
+	outputFormat = flag.String("o", "text", "Output format for command results (text or json)")
+	colorMode    = flag.String("color", "auto", "Colorize output (auto, always or never)")
+	timeout      = flag.Duration("timeout", 30*time.Second, "Maximum time to wait for a command to complete")
+	noReconnect  = flag.Bool("noreconnect", false, "Do not reconnect to the engine if the connection is lost")
+	noPager      = flag.Bool("nopager", false, "Do not display long command output via a pager")
+	yes          = flag.Bool("yes", false, "Execute destructive commands without requesting confirmation")
+	version      = flag.Bool("version", false, "Print the CLI and engine versions and exit")
+
+	history *cmdHistory
 
 	oldTermState *terminal.State
 	prompt       string
@@ -47,12 +76,14 @@ var (
 	seesawCLI    *cli.SeesawCLI
 	seesawConn   *conn.Seesaw
 	term         *terminal.Terminal
+	termLock     sync.Mutex // Protects term from concurrent resizes.
 )
 
 func exit() {
 	if oldTermState != nil {
 		terminal.Restore(syscall.Stdin, oldTermState) //将输出重新定位回原来的file去
 	}
+	saveHistory()
 	fmt.Printf("\n")
 	os.Exit(0)
 }
@@ -88,9 +119,52 @@ func terminalInit() {
 		fatalf("Failed to get raw terminal: %v", err)
 	}
 
+	termLock.Lock()
 	term = terminal.NewTerminal(os.Stdin, prompt)  //新建一个terminal，输出以prompt开头
+	termLock.Unlock()
+	resizeTerminal()
 	//设置一些按键
 	term.AutoCompleteCallback = autoComplete
+	if history != nil {
+		term.History = history
+	}
+}
+
+// resizeTerminal updates the size of the terminal to match the current
+// window size.
+func resizeTerminal() {
+	width, height, err := terminal.GetSize(syscall.Stdin)
+	if err != nil {
+		return
+	}
+	termLock.Lock()
+	defer termLock.Unlock()
+	if term != nil {
+		term.SetSize(width, height)
+	}
+}
+
+// loadHistory loads the command history from the history file. A missing or
+// unreadable history file results in an empty history.
+func loadHistory() {
+	history = newCmdHistory(*histSize)
+	if *histFile == "" {
+		return
+	}
+	if err := history.load(*histFile); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Failed to load history from %s: %v\n", *histFile, err)
+	}
+}
+
+// saveHistory writes the command history to the history file. The history is
+// saved as each command is read, as well as on exit.
+func saveHistory() {
+	if history == nil || *histFile == "" {
+		return
+	}
+	if err := history.save(*histFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save history to %s: %v\n", *histFile, err)
+	}
 }
 
 // commandChain builds a command chain from the given command slice.
@@ -106,6 +180,29 @@ func commandChain(chain []*cli.Command, args []string) string {
 	return strings.Join(s, " ")
 }
 
+// completeArg completes the final argument on the command line using the
+// given candidates. If there are multiple candidates they are listed and the
+// argument is completed to their longest common prefix.
+func completeArg(line string, args, candidates []string) []string {
+	completion := candidates[0]
+	if len(candidates) > 1 {
+		term.Write([]byte("\n"))
+		for _, c := range candidates {
+			term.Write([]byte(" " + c + "\n"))
+			for !strings.HasPrefix(c, completion) {
+				completion = completion[:len(completion)-1]
+			}
+		}
+	}
+	if len(args) > 0 && !strings.HasSuffix(line, " ") {
+		args = args[:len(args)-1]
+	}
+	if completion == "" {
+		return args
+	}
+	return append(args, completion)
+}
+
 // autoComplete attempts to complete the user's input when certain
 // characters are typed.
 func autoComplete(line string, pos int, key rune) (string, int, bool) {
@@ -113,11 +210,18 @@ func autoComplete(line string, pos int, key rune) (string, int, bool) {
 	case 0x01: // Ctrl-A
 		return line, 0, true
 	case 0x03: // Ctrl-C
-		exit()
+		// Only exit at an empty prompt, otherwise discard the line.
+		if line == "" {
+			exit()
+		}
+		return "", 0, true
 	case 0x05: // Ctrl-E
 		return line, len(line), true
 	case 0x09: // Ctrl-I (Tab)
 		_, _, chain, args := cli.FindCommand(string(line))
+		if candidates := seesawCLI.Complete(line); len(candidates) > 0 {
+			args = completeArg(line, args, candidates)
+		}
 		line := commandChain(chain, args)
 		return line, len(line), true
 	case 0x15: // Ctrl-U
@@ -136,7 +240,11 @@ func autoComplete(line string, pos int, key rune) (string, int, bool) {
 				term.Write([]byte(" " + c.Command))
 				term.Write([]byte("\n"))
 			}
-		} else if cmd == nil {
+		} else if cmd != nil {
+			for _, c := range seesawCLI.Complete(line[0:pos]) {
+				term.Write([]byte(" " + c + "\n"))
+			}
+		} else {
 			term.Write([]byte("Unknown command.\n"))
 		}
 
@@ -174,11 +282,29 @@ func interactive() {
 	//只要收到以下三个signal，就退出
 	signal.Notify(sigc, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
 	go func() {
-		<-sigc
-		exit()
+		for s := range sigc {
+			// An interrupt cancels the executing command, rather than
+			// exiting the CLI.
+			if s == syscall.SIGINT && seesawCLI.Interrupt() {
+				continue
+			}
+			exit()
+		}
 	}()
 
+	// Long output is only paged in interactive mode.
+	seesawCLI.SetPager(!*noPager)
+
+	// Track window size changes so that line editing wraps correctly.
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		for range winch {
+			resizeTerminal()
+		}
+	}()
 
+	loadHistory()
 	terminalInit()
 
 	for {
@@ -191,32 +317,219 @@ func interactive() {
 		if cmdline == "" {
 			continue
 		}
+		// Persist the history before executing the command, so that it
+		// is retained if the CLI exits via fatalf or a signal.
+		saveHistory()
 		//执行cmd
-		if err := seesawCLI.Execute(cmdline); err != nil {
+		if err := execute(cmdline); err != nil {
 			fmt.Println(err)
 		}
 	}
 }
 
+// forced strips the force suffix from the given command line, returning the
+// resulting command line and whether the suffix was present.
+func forced(cmdline string) (string, bool) {
+	if !strings.HasSuffix(cmdline, forceSuffix) {
+		return cmdline, false
+	}
+	return strings.TrimSpace(strings.TrimSuffix(cmdline, forceSuffix)), true
+}
+
+// confirm requests confirmation from the user before a destructive command is
+// executed. The response is not recorded in the command history.
+func confirm() bool {
+	cmdHistory, autoCompleteCallback := term.History, term.AutoCompleteCallback
+	term.History, term.AutoCompleteCallback = newCmdHistory(0), nil
+	term.SetPrompt(confirmPrompt)
+	defer func() {
+		term.History, term.AutoCompleteCallback = cmdHistory, autoCompleteCallback
+		term.SetPrompt(prompt)
+	}()
+	answer, err := term.ReadLine()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// execute executes the given command line from the interactive CLI. The
+// terminal is restored to its original state while the command executes, so
+// that a Ctrl-C results in a SIGINT that can interrupt the command.
+// Confirmation is requested before a destructive command is executed, unless
+// -yes was specified or the command line has a force suffix.
+func execute(cmdline string) error {
+	cmdline, force := forced(cmdline)
+	if !force && !*yes && seesawCLI.IsDestructive(cmdline) && !confirm() {
+		return errors.New("Command aborted.")
+	}
+	if oldTermState != nil {
+		terminal.Restore(syscall.Stdin, oldTermState)
+		defer terminal.MakeRaw(syscall.Stdin)
+	}
+	return seesawCLI.Execute(cmdline)
+}
+
+// batch executes the commands read from the given reader, one per line.
+// Blank lines and lines starting with '#' are ignored. Execution stops at the
+// first failed command, unless keepGoing is set. The number of failed
+// commands is returned.
+func batch(r io.Reader, keepGoing bool) (int, error) {
+	failed := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		cmdline := strings.TrimSpace(scanner.Text())
+		if cmdline == "" || strings.HasPrefix(cmdline, "#") {
+			continue
+		}
+		fmt.Printf("%s%s\n", batchPrefix, cmdline)
+		if err := executeNonInteractive(cmdline); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+			if !keepGoing {
+				break
+			}
+		}
+	}
+	return failed, scanner.Err()
+}
+
+// executeNonInteractive executes the given command line without requesting
+// confirmation. Destructive commands are refused unless -yes was specified or
+// the command line has a force suffix.
+func executeNonInteractive(cmdline string) error {
+	cmdline, force := forced(cmdline)
+	if !force && !*yes && seesawCLI.IsDestructive(cmdline) {
+		return fmt.Errorf("Refusing to execute destructive command %q without -yes", cmdline)
+	}
+	return seesawCLI.Execute(cmdline)
+}
+
+// dialEngine establishes a connection to the Seesaw Engine. A local engine
+// socket is connected to via IPC, while a tcp:// address results in an RPC
+// connection over mutually authenticated TLS.
+func dialEngine() (*conn.Seesaw, error) {
+	var seesawConn *conn.Seesaw
+	network, addr := conn.ParseEngineAddr(*engineSocket)
+	switch network {
+	case "tcp":
+		tlsConfig, err := conn.ClientTLSConfig(*caCertFile, *certFile, *keyFile, *serverName)
+		if err != nil {
+			return nil, err
+		}
+		ctx := ipc.NewContext(seesaw.SCRemoteCLI)
+		seesawConn = conn.NewSeesawTLS(ctx, tlsConfig)
+	default:
+		//为组件创建一个新的context
+		ctx := ipc.NewTrustedContext(seesaw.SCLocalCLI)
+
+		//建立一个新的ipc连接
+		var err error
+		if seesawConn, err = conn.NewSeesawIPC(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := seesawConn.Dial(addr); err != nil {
+		return nil, err
+	}
+	seesawConn.SetReconnect(!*noReconnect, func() {
+		fmt.Fprintln(os.Stderr, "Reconnected to engine.")
+	})
+	return seesawConn, nil
+}
+
+// printVersion prints the CLI version, followed by the engine version if the
+// engine can be reached.
+func printVersion(format cli.OutputFormat) {
+	versionCLI := cli.NewSeesawCLI(nil, exit)
+	versionCLI.SetOutputFormat(format)
+	err := versionCLI.PrintVersion(func() (*seesaw.ClusterStatus, error) {
+		seesawConn, err := dialEngine()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to connect to engine: %v", err)
+		}
+		defer seesawConn.Close()
+		return seesawConn.ClusterStatus()
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Engine version unavailable: %v\n", err)
+	}
+}
+
 func main() {
 	flag.Parse()
 
-	//为组件创建一个新的context
-	ctx := ipc.NewTrustedContext(seesaw.SCLocalCLI)
+	format, err := cli.ParseOutputFormat(*outputFormat)
+	if err != nil {
+		fatalf("Invalid output format: %v", err)
+	}
 
-	var err error
-	//建立一个新的ipc连接
-	seesawConn, err = conn.NewSeesawIPC(ctx)
+	var color bool
+	switch *colorMode {
+	case "always":
+		color = true
+	case "auto":
+		color = terminal.IsTerminal(syscall.Stdout)
+	case "never":
+	default:
+		fatalf("Invalid color mode %q", *colorMode)
+	}
 
-	if err != nil {
-		fatalf("Failed to connect to engine: %v", err)
+	if *version {
+		printVersion(format)
+		return
 	}
-	if err := seesawConn.Dial(*engineSocket); err != nil {
+
+	seesawConn, err = dialEngine()
+	if err != nil {
 		fatalf("Failed to connect to engine: %v", err)
 	}
 	defer seesawConn.Close()
 	//将engine和cli进行连接
 	seesawCLI = cli.NewSeesawCLI(seesawConn, exit)
+	seesawCLI.SetOutputFormat(format)
+	seesawCLI.SetColor(color)
+	seesawCLI.SetTimeout(*timeout)
+	if network, _ := conn.ParseEngineAddr(*engineSocket); network == "tcp" {
+		seesawCLI.SetRemote(true)
+	}
+	if *rcFile != "" {
+		if err := seesawCLI.LoadAliases(*rcFile); err != nil && !os.IsNotExist(err) {
+			fatalf("Failed to load aliases: %v", err)
+		}
+	}
+
+	if *commandFile != "" {
+		if *command != "" {
+			fatalf("Only one of -c and -f may be specified")
+		}
+		f, err := os.Open(*commandFile)
+		if err != nil {
+			fatalf("Failed to open command file: %v", err)
+		}
+		failed, err := batch(f, *keepGoing)
+		f.Close()
+		if err != nil {
+			fatalf("Failed to read command file: %v", err)
+		}
+		if failed > 0 {
+			fatalf("%d command(s) failed", failed)
+		}
+		return
+	}
+
+	// Commands piped via stdin are executed in the same way as a command file.
+	if *command == "" && !terminal.IsTerminal(syscall.Stdin) {
+		failed, err := batch(os.Stdin, *keepGoing)
+		if err != nil {
+			fatalf("Failed to read commands from stdin: %v", err)
+		}
+		if failed > 0 {
+			fatalf("%d command(s) failed", failed)
+		}
+		return
+	}
 
 	//如果没有指令，那么循环等待
 	if *command == "" {
@@ -224,7 +537,7 @@ func main() {
 		exit()
 	}
 	//如果有指令，执行
-	if err := seesawCLI.Execute(*command); err != nil {
+	if err := executeNonInteractive(*command); err != nil {
 		fatalf("%v", err)
 	}
 }
@@ -23,12 +23,17 @@ package main
 
 import (
 	"flag"
+	"strings"
 
 	"github.com/wy2745/seesaw/common/server"
 	"github.com/wy2745/seesaw/ecu"
+
+	log "github.com/golang/glog"
 )
 
 var (
+	clientIdentities = flag.String("client_identities", "",
+		"Comma separated list of cn=user pairs, mapping trusted TLS client certificate common names to users")
 	controlAddress = flag.String("control_address",
 		ecu.DefaultECUConfig().ControlAddress, "ECU control address")
 	monitorAddress = flag.String("monitor_address",
@@ -41,6 +46,17 @@ func main() {
 	ecuCfg := ecu.DefaultECUConfig()
 	ecuCfg.ControlAddress = *controlAddress
 	ecuCfg.MonitorAddress = *monitorAddress
+	ecuCfg.ClientIdentities = make(map[string]string)
+	for _, pair := range strings.Split(*clientIdentities, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			log.Exitf("Invalid client identity %q, want cn=user", pair)
+		}
+		ecuCfg.ClientIdentities[kv[0]] = kv[1]
+	}
 
 	ecu := ecu.NewECU(&ecuCfg)
 
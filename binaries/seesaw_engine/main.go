@@ -20,28 +20,59 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
 	"github.com/wy2745/seesaw/common/server"
 	"github.com/wy2745/seesaw/engine/config"
 	"github.com/wy2745/seesaw/engine"
+	"github.com/wy2745/seesaw/quagga"
 
 	conf "github.com/dlintw/goconf"
 	log "github.com/golang/glog"
 )
 
 var (
+	allowExecChecks = flag.Bool("allow-exec-checks", config.DefaultEngineConfig().AllowExecChecks,
+		"Allow healthchecks that execute external commands")
+	anycastHoldDown = flag.Duration("anycast-hold-down", config.DefaultEngineConfig().AnycastHoldDown,
+		"The time an anycast VIP must remain healthy after going down before its BGP route is re-advertised")
+	auditLog = flag.String("audit-log", config.DefaultEngineConfig().AuditLog,
+		"The file to write the audit log to, or \"syslog\" (disabled if empty)")
 	configFile = flag.String("conf", config.DefaultEngineConfig().ConfigFile,
 		"Seesaw configuration file")
 	clusterFile = flag.String("cluster", config.DefaultEngineConfig().ClusterFile,
 		"Seesaw cluster configuration file")
+	clusterFormat = flag.String("config-format", config.DefaultEngineConfig().ClusterFormat.String(),
+		"The format of the cluster configuration file - \"text\", \"yaml\" or \"auto\" to select by file extension")
+	convert = flag.String("convert", "",
+		"Convert the given cluster config file to YAML, writing it to stdout, and exit")
+	flapInterval = flag.Duration("flap-interval", config.DefaultEngineConfig().FlapInterval,
+		"The interval over which backend health transitions are counted")
+	flapThreshold = flag.Int("flap-threshold", config.DefaultEngineConfig().FlapThreshold,
+		"The number of health transitions per interval after which transition logging is suppressed for a backend (disabled if zero)")
+	garpCount = flag.Int("garp-count", config.DefaultEngineConfig().GARPCount,
+		"The number of gratuitous ARPs (or IPv6 neighbor advertisements) to send for each address after becoming master")
+	garpInterval = flag.Duration("garp-interval", config.DefaultEngineConfig().GARPInterval,
+		"The interval between gratuitous ARPs sent after becoming master")
+	metricsAddr = flag.String("metrics-addr", config.DefaultEngineConfig().MetricsAddress,
+		"The address on which to export Prometheus metrics (disabled if empty)")
 	nccSocket = flag.String("ncc_socket", config.DefaultEngineConfig().NCCSocket,
 		"Seesaw NCC socket")
+	shutdownPolicy = flag.String("shutdown-policy", config.DefaultEngineConfig().ShutdownPolicy.String(),
+		"Whether to \"keep\" or \"clear\" the VIPs and IPVS services on shutdown")
 	socketPath = flag.String("socket", config.DefaultEngineConfig().SocketPath,
 		"Seesaw Engine socket")
+	validate = flag.String("validate", "",
+		"Validate the given cluster config file and exit")
 )
 
 // cfgOpt returns the configuration option from the specified section. If the
@@ -71,9 +102,56 @@ func cfgIP(cfg *conf.ConfigFile, section, option string) (net.IP, error) {
 	return ip, nil
 }
 
+// validateConfig parses and validates a cluster config file, printing any
+// errors and warnings that are found. The returned exit status is non-zero if
+// the config is unusable or if any part of it would be ignored.
+func validateConfig(file string, format config.Format) int {
+	n, err := config.ReadConfigFormat(file, "", format)
+	if err != nil {
+		fmt.Printf("%s: invalid config: %v\n", file, err)
+		return 1
+	}
+	status := n.Cluster.Status
+	for _, e := range status.Errors {
+		fmt.Printf("%s: error: %s\n", file, e)
+	}
+	for _, w := range status.Warnings {
+		fmt.Printf("%s: warning: %s\n", file, w)
+	}
+	if len(status.Errors) > 0 {
+		fmt.Printf("%s: config has %d errors\n", file, len(status.Errors))
+		return 1
+	}
+	fmt.Printf("%s: config is valid (%d vservers)\n", file, len(n.Cluster.Vservers))
+	return 0
+}
+
+// convertConfig converts a cluster config file to YAML, writing it to stdout.
+// The returned exit status is non-zero if the conversion failed.
+func convertConfig(file string, format config.Format) int {
+	b, err := config.ConvertToYAML(file, format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: conversion failed: %v\n", file, err)
+		return 1
+	}
+	os.Stdout.Write(b)
+	return 0
+}
+
 func main() {
 	flag.Parse()
 
+	clusterFmt, err := config.FormatByName(*clusterFormat)
+	if err != nil {
+		log.Exitf("Invalid config-format: %v", err)
+	}
+	switch {
+	case *validate != "":
+		os.Exit(validateConfig(*validate, clusterFmt))
+	case *convert != "":
+		os.Exit(convertConfig(*convert, clusterFmt))
+	}
+
 	cfg, err := conf.ReadConfigFile(*configFile)
 	if err != nil {
 		log.Exitf("Failed to read configuration file: %v", err)
@@ -89,6 +167,18 @@ func main() {
 			log.Exitf("Unable to parse cluster anycast_enabled: %v", err)
 		}
 	}
+	routingProtocol := config.DefaultEngineConfig().RoutingProtocol
+	if opt := cfgOpt(cfg, "cluster", "routing"); opt != "" {
+		if routingProtocol, err = config.ParseRoutingProtocol(opt); err != nil {
+			log.Exitf("Unable to parse cluster routing: %v", err)
+		}
+	}
+	ospfArea := config.DefaultEngineConfig().OSPFArea
+	if opt := cfgOpt(cfg, "cluster", "ospf_area"); opt != "" {
+		if ospfArea, err = quagga.ParseOSPFArea(opt); err != nil {
+			log.Exitf("Unable to parse cluster ospf_area: %v", err)
+		}
+	}
 	clusterVIPv4, err := cfgIP(cfg, "cluster", "vip_ipv4")
 	if err != nil {
 		log.Exitf("Unable to get cluster vip_ipv4: %v", err)
@@ -127,6 +217,182 @@ func main() {
 		vrid = uint8(id)
 	}
 
+	// Optional IPVS connection synchronisation. The sync ID defaults to
+	// the VRID, since it needs to be unique to the cluster.
+	ipvsSyncInterface := cfgOpt(cfg, "ipvs_sync", "interface")
+	ipvsSyncGroup, err := cfgIP(cfg, "ipvs_sync", "group")
+	if err != nil {
+		log.Exitf("Unable to get ipvs_sync group: %v", err)
+	}
+	if ipvsSyncGroup != nil && !ipvsSyncGroup.IsMulticast() {
+		log.Exitf("IPVS sync group %v is not a multicast address", ipvsSyncGroup)
+	}
+	ipvsSyncID := vrid
+	if cfg.HasOption("ipvs_sync", "sync_id") {
+		id, err := cfg.GetInt("ipvs_sync", "sync_id")
+		if err != nil {
+			log.Exitf("Unable to get IPVS sync ID: %v", err)
+		}
+		if id < 0 || id > 255 {
+			log.Exitf("Invalid IPVS sync ID %d - must be between 0 and 255 inclusive", id)
+		}
+		ipvsSyncID = uint8(id)
+	}
+
+	// Optional dedicated interface and shared secret for VRRP advertisements.
+	// The secret is read from a file, so that it is not exposed to anyone
+	// who can read this configuration.
+	heartbeatInterface := cfgOpt(cfg, "heartbeat", "interface")
+	var heartbeatSecret []byte
+	if secretFile := cfgOpt(cfg, "heartbeat", "secret_file"); secretFile != "" {
+		secret, err := ioutil.ReadFile(secretFile)
+		if err != nil {
+			log.Exitf("Unable to read heartbeat secret: %v", err)
+		}
+		heartbeatSecret = bytes.TrimSpace(secret)
+		if len(heartbeatSecret) == 0 {
+			log.Exitf("Heartbeat secret file %q is empty", secretFile)
+		}
+	}
+
+	// Optional additional VRRP groups, for active/active operation. Each
+	// group has its own VRID and priority, along with the vservers that
+	// belong to it.
+	var haGroups []config.HAGroup
+	vrids := map[uint8]bool{vrid: true}
+	for _, section := range cfg.GetSections() {
+		if !strings.HasPrefix(section, "ha_group.") {
+			continue
+		}
+		g := config.HAGroup{Name: strings.TrimPrefix(section, "ha_group.")}
+		id, err := cfg.GetInt(section, "vrid")
+		if err != nil {
+			log.Exitf("Unable to get VRID for HA group %q: %v", g.Name, err)
+		}
+		if id < 1 || id > 255 {
+			log.Exitf("Invalid VRID %d for HA group %q - must be between 1 and 255 inclusive", id, g.Name)
+		}
+		if vrids[uint8(id)] {
+			log.Exitf("Duplicate VRID %d for HA group %q", id, g.Name)
+		}
+		vrids[uint8(id)] = true
+		g.VRID = uint8(id)
+		priority, err := cfg.GetInt(section, "priority")
+		if err != nil {
+			log.Exitf("Unable to get priority for HA group %q: %v", g.Name, err)
+		}
+		if priority < 1 || priority > 255 {
+			log.Exitf("Invalid priority %d for HA group %q - must be between 1 and 255 inclusive", priority, g.Name)
+		}
+		g.Priority = uint8(priority)
+		for _, vs := range strings.Split(cfgOpt(cfg, section, "vservers"), ",") {
+			if vs = strings.TrimSpace(vs); vs != "" {
+				g.Vservers = append(g.Vservers, vs)
+			}
+		}
+		haGroups = append(haGroups, g)
+	}
+	sort.Slice(haGroups, func(i, j int) bool { return haGroups[i].VRID < haGroups[j].VRID })
+
+	// Optional HA trackers, which reduce the priority advertised by this
+	// node while a dependency is unhealthy.
+	var haTrackers []config.HATracker
+	for _, section := range cfg.GetSections() {
+		if !strings.HasPrefix(section, "ha_track.") {
+			continue
+		}
+		t := config.HATracker{
+			Name:      strings.TrimPrefix(section, "ha_track."),
+			Interface: cfgOpt(cfg, section, "interface"),
+			Script:    cfgOpt(cfg, section, "script"),
+		}
+		checks := 0
+		if t.Interface != "" {
+			checks++
+		}
+		if t.Script != "" {
+			checks++
+		}
+		if cfg.HasOption(section, "vservers") {
+			checks++
+			if t.Vservers, err = cfg.GetInt(section, "vservers"); err != nil {
+				log.Exitf("Unable to get vservers for HA tracker %q: %v", t.Name, err)
+			}
+			if t.Vservers < 1 {
+				log.Exitf("Invalid vservers %d for HA tracker %q - must be at least 1", t.Vservers, t.Name)
+			}
+		}
+		if checks != 1 {
+			log.Exitf("HA tracker %q must specify exactly one of interface, script or vservers", t.Name)
+		}
+		weight, err := cfg.GetInt(section, "weight")
+		if err != nil {
+			log.Exitf("Unable to get weight for HA tracker %q: %v", t.Name, err)
+		}
+		if weight < 1 || weight > 254 {
+			log.Exitf("Invalid weight %d for HA tracker %q - must be between 1 and 254 inclusive", weight, t.Name)
+		}
+		t.Weight = uint8(weight)
+		haTrackers = append(haTrackers, t)
+	}
+	sort.Slice(haTrackers, func(i, j int) bool { return haTrackers[i].Name < haTrackers[j].Name })
+
+	// Optional BGP peers, which are configured in the BGP daemon.
+	var bgpPeers []*quagga.Peer
+	for _, section := range cfg.GetSections() {
+		if !strings.HasPrefix(section, "bgp_peer.") {
+			continue
+		}
+		name := strings.TrimPrefix(section, "bgp_peer.")
+		p := &quagga.Peer{}
+		if p.Address, err = cfgIP(cfg, section, "address"); err != nil {
+			log.Exitf("Invalid address for BGP peer %q: %v", name, err)
+		}
+		if p.RemoteAS, err = quagga.ParseASN(cfgOpt(cfg, section, "remote_as")); err != nil {
+			log.Exitf("Invalid remote AS for BGP peer %q: %v", name, err)
+		}
+		if las := cfgOpt(cfg, section, "local_as"); las != "" {
+			if p.LocalAS, err = quagga.ParseASN(las); err != nil {
+				log.Exitf("Invalid local AS for BGP peer %q: %v", name, err)
+			}
+		}
+		if cfg.HasOption(section, "multihop_ttl") {
+			ttl, err := cfg.GetInt(section, "multihop_ttl")
+			if err != nil {
+				log.Exitf("Unable to get multihop TTL for BGP peer %q: %v", name, err)
+			}
+			if ttl < 1 || ttl > 255 {
+				log.Exitf("Invalid multihop TTL %d for BGP peer %q - must be between 1 and 255 inclusive", ttl, name)
+			}
+			p.MultihopTTL = uint8(ttl)
+		}
+		if cfg.HasOption(section, "bfd") {
+			if p.BFDEnabled, err = cfg.GetBool(section, "bfd"); err != nil {
+				log.Exitf("Unable to get BFD for BGP peer %q: %v", name, err)
+			}
+		}
+		if interval := cfgOpt(cfg, section, "bfd_interval"); interval != "" {
+			if p.BFDInterval, err = time.ParseDuration(interval); err != nil {
+				log.Exitf("Invalid BFD interval for BGP peer %q: %v", name, err)
+			}
+		}
+		if cfg.HasOption(section, "bfd_multiplier") {
+			m, err := cfg.GetInt(section, "bfd_multiplier")
+			if err != nil {
+				log.Exitf("Unable to get BFD multiplier for BGP peer %q: %v", name, err)
+			}
+			if m < 2 || m > 255 {
+				log.Exitf("Invalid BFD multiplier %d for BGP peer %q - must be between 2 and 255 inclusive", m, name)
+			}
+			p.BFDMultiplier = uint8(m)
+		}
+		if err := p.Validate(); err != nil {
+			log.Exitf("Invalid BGP peer %q: %v", name, err)
+		}
+		bgpPeers = append(bgpPeers, p)
+	}
+	sort.Slice(bgpPeers, func(i, j int) bool { return bgpPeers[i].Address.String() < bgpPeers[j].Address.String() })
+
 	// Optional primary, secondary and tertiary configuration servers.
 	configServers := make([]string, 0)
 	for _, level := range []string{"primary", "secondary", "tertiary"} {
@@ -138,6 +404,18 @@ func main() {
 		configServers = config.DefaultEngineConfig().ConfigServers
 	}
 
+	// Optional URL to fetch the cluster configuration from, in place of the
+	// configuration servers, with an optional client certificate.
+	configURL := cfgOpt(cfg, "config_server", "url")
+	if configURL != "" && !strings.HasPrefix(configURL, "https://") {
+		log.Exitf("Config URL %q is not an HTTPS URL", configURL)
+	}
+	configURLCertFile := cfgOpt(cfg, "config_server", "cert_file")
+	configURLKeyFile := cfgOpt(cfg, "config_server", "key_file")
+	if (configURLCertFile == "") != (configURLKeyFile == "") {
+		log.Exitf("Config URL client certificate requires both cert_file and key_file")
+	}
+
 	nodeInterface := config.DefaultEngineConfig().NodeInterface
 	if opt := cfgOpt(cfg, "interface", "node"); opt != "" {
 		nodeInterface = opt
@@ -147,6 +425,25 @@ func main() {
 		lbInterface = opt
 	}
 
+	// Optional authorisation for IPC calls that change the engine state,
+	// listing the users that may make each call.
+	var ipcAuth config.IPCAuth
+	if cfg.HasSection("ipc_auth") {
+		opts, err := cfg.GetOptions("ipc_auth")
+		if err != nil {
+			log.Exitf("Unable to get ipc_auth options: %v", err)
+		}
+		ipcAuth = make(config.IPCAuth)
+		for _, opt := range opts {
+			call := strings.ToLower(opt)
+			for _, u := range strings.Split(cfgOpt(cfg, "ipc_auth", opt), ",") {
+				if u = strings.TrimSpace(u); u != "" {
+					ipcAuth[call] = append(ipcAuth[call], u)
+				}
+			}
+		}
+	}
+
 	// Additional anycast addresses.
 	serviceAnycastIPv4 := config.DefaultEngineConfig().ServiceAnycastIPv4
 	serviceAnycastIPv6 := config.DefaultEngineConfig().ServiceAnycastIPv6
@@ -171,24 +468,53 @@ func main() {
 		}
 	}
 
+	policy, err := config.ParseShutdownPolicy(*shutdownPolicy)
+	if err != nil {
+		log.Exitf("Invalid -shutdown-policy: %v", err)
+	}
+
 	// Override some of the defaults.
 	engineCfg := config.DefaultEngineConfig()
+	engineCfg.AllowExecChecks = *allowExecChecks
 	engineCfg.AnycastEnabled = anycastEnabled
+	engineCfg.AnycastHoldDown = *anycastHoldDown
+	engineCfg.AuditLog = *auditLog
+	engineCfg.BGPPeers = bgpPeers
 	engineCfg.ConfigFile = *configFile
 	engineCfg.ConfigServers = configServers
+	engineCfg.ConfigURL = configURL
+	engineCfg.ConfigURLCertFile = configURLCertFile
+	engineCfg.ConfigURLKeyFile = configURLKeyFile
 	engineCfg.ClusterFile = *clusterFile
+	engineCfg.ClusterFormat = clusterFmt
 	engineCfg.ClusterName = clusterName
 	engineCfg.ClusterVIP.IPv4Addr = clusterVIPv4
 	engineCfg.ClusterVIP.IPv6Addr = clusterVIPv6
+	engineCfg.FlapInterval = *flapInterval
+	engineCfg.FlapThreshold = *flapThreshold
+	engineCfg.HAGroups = haGroups
+	engineCfg.HATrackers = haTrackers
+	engineCfg.GARPCount = *garpCount
+	engineCfg.GARPInterval = *garpInterval
+	engineCfg.IPCAuthorization = ipcAuth
+	engineCfg.IPVSSyncGroup = ipvsSyncGroup
+	engineCfg.IPVSSyncID = ipvsSyncID
+	engineCfg.HeartbeatInterface = heartbeatInterface
+	engineCfg.HeartbeatSecret = heartbeatSecret
+	engineCfg.IPVSSyncInterface = ipvsSyncInterface
 	engineCfg.LBInterface = lbInterface
+	engineCfg.MetricsAddress = *metricsAddr
 	engineCfg.NCCSocket = *nccSocket
 	engineCfg.Node.IPv4Addr = nodeIPv4
 	engineCfg.Node.IPv6Addr = nodeIPv6
 	engineCfg.NodeInterface = nodeInterface
+	engineCfg.OSPFArea = ospfArea
 	engineCfg.Peer.IPv4Addr = peerIPv4
 	engineCfg.Peer.IPv6Addr = peerIPv6
+	engineCfg.RoutingProtocol = routingProtocol
 	engineCfg.ServiceAnycastIPv4 = serviceAnycastIPv4
 	engineCfg.ServiceAnycastIPv6 = serviceAnycastIPv6
+	engineCfg.ShutdownPolicy = policy
 	engineCfg.SocketPath = *socketPath
 	engineCfg.VRID = vrid
 
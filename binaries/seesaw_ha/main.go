@@ -51,6 +51,12 @@ var (
 	preempt = flag.Bool("preempt", false,
 		"If true, a higher priority node will preempt the mastership of a lower priority node")
 
+	preemptDelay = flag.Duration("preempt_delay", 0,
+		"How long a higher priority node waits before preempting the mastership of a lower priority node")
+
+	splitBrainDemote = flag.Bool("split_brain_demote", false,
+		"If true, this node yields mastership when another master with the same priority is detected")
+
 	statusReportInterval = flag.Duration("status_report_interval", 3*time.Second,
 		"How frequently to report the current HAStatus to the engine")
 
@@ -60,6 +66,9 @@ var (
 	statusReportRetryDelay = flag.Duration("status_report_retry_delay", 2*time.Second,
 		"Time between status report retries")
 
+	trackInterval = flag.Duration("track_interval", 2*time.Second,
+		"How frequently to check the HA trackers configured for this node")
+
 	testLocalAddr = flag.String("local_addr", "",
 		"Local IP Address - used only when test_mode=true")
 
@@ -110,6 +119,16 @@ func engine() ha.Engine {
 	return &ha.EngineClient{Socket: *engineSocket}
 }
 
+// nodes represents the Nodes for the VRRP groups that this node belongs to.
+type nodes []*ha.Node
+
+// Shutdown shuts down the Nodes for all VRRP groups.
+func (ns nodes) Shutdown() {
+	for _, n := range ns {
+		n.Shutdown()
+	}
+}
+
 func main() {
 	flag.Parse()
 
@@ -117,7 +136,7 @@ func main() {
 	engine := engine()
 	config := config(engine)
 	log.Infof("Received HAConfig: %v", config)
-	conn, err := ha.NewIPHAConn(config.LocalAddr, config.RemoteAddr)
+	conn, err := ha.NewIPHAConn(config.LocalAddr, config.RemoteAddr, config.HeartbeatInterface, config.HeartbeatSecret)
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
@@ -128,12 +147,50 @@ func main() {
 		ConfigCheckRetryDelay:   *configCheckRetryDelay,
 		MasterAdvertInterval:    *masterAdvertInterval,
 		Preempt:                 *preempt,
+		PreemptDelay:            *preemptDelay,
+		SplitBrainDemote:        *splitBrainDemote,
 		StatusReportInterval:    *statusReportInterval,
 		StatusReportMaxFailures: *statusReportMaxFailures,
 		StatusReportRetryDelay:  *statusReportRetryDelay,
+		TrackInterval:           *trackInterval,
+	}
+	if len(config.Trackers) > 0 && !*preempt {
+		log.Warningf("HA trackers are configured but preemption is disabled - a healthier backup will not take over mastership")
 	}
 	n := ha.NewNode(nc, conn, engine)
-	server.ShutdownHandler(n)
+
+	// Additional VRRP groups each run a separate Node, which reports its
+	// state to the engine for the group's VRID.
+	var groups nodes
+	for _, g := range config.Groups {
+		gconn, err := ha.NewIPHAConn(config.LocalAddr, config.RemoteAddr, config.HeartbeatInterface, config.HeartbeatSecret)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		gnc := nc
+		gnc.HAConfig = seesaw.HAConfig{
+			Enabled:    config.Enabled,
+			LocalAddr:  config.LocalAddr,
+			RemoteAddr: config.RemoteAddr,
+			Priority:   g.Priority,
+			VRID:       g.VRID,
+			Trackers:   config.Trackers,
+
+			HeartbeatInterface: config.HeartbeatInterface,
+			HeartbeatSecret:    config.HeartbeatSecret,
+		}
+		groups = append(groups, ha.NewNode(gnc, gconn, &ha.GroupEngine{Engine: engine, VRID: g.VRID}))
+	}
+	for _, gn := range groups {
+		go func(gn *ha.Node) {
+			if err := gn.Run(); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}(gn)
+	}
+	// Shut down the additional groups first, so that their shutdown
+	// advertisements are sent while the node's group is shutting down.
+	server.ShutdownHandler(append(groups, n))
 
 	if err = n.Run(); err != nil {
 		log.Fatalf("%v", err)
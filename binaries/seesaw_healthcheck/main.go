@@ -39,10 +39,19 @@ var (
 		healthcheck.DefaultServerConfig().ChannelSize,
 		"The size of the notification channel")
 
+	deterministic = flag.Bool("healthcheck_deterministic", false,
+		"Start healthchecks in a fixed order with a fixed jitter seed (for testing only)")
+
 	engineSocket = flag.String("engine",
 		healthcheck.DefaultServerConfig().EngineSocket,
 		"Seesaw Engine Socket")
 
+	metricsAddr = flag.String("metrics-addr", "",
+		"The address on which to export Prometheus metrics (disabled if empty)")
+
+	noJitter = flag.Bool("no_jitter", false,
+		"Disable jittering of the initial healthcheck")
+
 	maxFailures = flag.Int("max_failures",
 		healthcheck.DefaultServerConfig().MaxFailures,
 		"The maximum number of consecutive notification failures")
@@ -50,6 +59,10 @@ var (
 	retryDelay = flag.Duration("retry_delay",
 		healthcheck.DefaultServerConfig().RetryDelay,
 		"The time between notification RPC retries")
+
+	workers = flag.Int("healthcheck_workers",
+		healthcheck.DefaultServerConfig().Workers,
+		"The number of workers that execute healthchecks (unbounded if zero)")
 )
 
 func main() {
@@ -59,9 +72,13 @@ func main() {
 
 	cfg.BatchSize = *batchSize
 	cfg.ChannelSize = *channelSize
+	cfg.Deterministic = *deterministic
 	cfg.EngineSocket = *engineSocket
+	cfg.Jitter = !*noJitter
 	cfg.MaxFailures = *maxFailures
+	cfg.MetricsAddress = *metricsAddr
 	cfg.RetryDelay = *retryDelay
+	cfg.Workers = *workers
 
 	hc := healthcheck.NewServer(&cfg)
 	server.ShutdownHandler(hc)
@@ -0,0 +1,127 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const aliasKeyword = "alias"
+
+// parseAlias parses an alias definition of the form "name = expansion".
+func parseAlias(def string) (string, string, error) {
+	var name, expansion string
+	if i := strings.Index(def, "="); i >= 0 {
+		name, expansion = def[:i], def[i+1:]
+	} else if fields := strings.Fields(def); len(fields) > 0 {
+		name, expansion = fields[0], strings.Join(fields[1:], " ")
+	}
+	name = strings.TrimSpace(name)
+	expansion = strings.Join(strings.Fields(expansion), " ")
+	switch {
+	case name == "" || len(strings.Fields(name)) != 1:
+		return "", "", fmt.Errorf("invalid alias name %q", name)
+	case name == aliasKeyword:
+		return "", "", fmt.Errorf("cannot redefine %q", aliasKeyword)
+	case expansion == "":
+		return "", "", fmt.Errorf("alias %q has no expansion", name)
+	}
+	return name, expansion, nil
+}
+
+// SetAlias defines an alias that expands to the given command line.
+func (cli *SeesawCLI) SetAlias(name, expansion string) {
+	cli.aliases[name] = expansion
+}
+
+// LoadAliases loads alias definitions from the given file. Each definition is
+// a line of the form "alias name = expansion". Blank lines and lines starting
+// with '#' are ignored.
+func (cli *SeesawCLI) LoadAliases(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if fields[0] != aliasKeyword {
+			return fmt.Errorf("%s:%d: unknown directive %q", filename, n, fields[0])
+		}
+		name, expansion, err := parseAlias(strings.TrimPrefix(line, aliasKeyword))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", filename, n, err)
+		}
+		cli.SetAlias(name, expansion)
+	}
+	return scanner.Err()
+}
+
+// expandAlias expands an alias that appears as the first token of the given
+// command line. Only a single level of expansion is performed.
+func (cli *SeesawCLI) expandAlias(cmdline string) string {
+	fields := strings.Fields(cmdline)
+	if len(fields) == 0 {
+		return cmdline
+	}
+	expansion, ok := cli.aliases[fields[0]]
+	if !ok {
+		return cmdline
+	}
+	return strings.Join(append([]string{expansion}, fields[1:]...), " ")
+}
+
+func alias(cli *SeesawCLI, args []string) error {
+	if len(args) == 0 {
+		if len(cli.aliases) == 0 {
+			fmt.Println("No aliases defined.")
+			return nil
+		}
+		names := make([]string, 0, len(cli.aliases))
+		for name := range cli.aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("alias %s = %s\n", name, cli.aliases[name])
+		}
+		return nil
+	}
+	if len(args) == 1 && !strings.Contains(args[0], "=") {
+		expansion, ok := cli.aliases[args[0]]
+		if !ok {
+			return fmt.Errorf("No such alias - %s", args[0])
+		}
+		fmt.Printf("alias %s = %s\n", args[0], expansion)
+		return nil
+	}
+	name, expansion, err := parseAlias(strings.Join(args, " "))
+	if err != nil {
+		fmt.Println("alias [<name> [= <command>]]")
+		return errors.New("Incorrect arguments given.")
+	}
+	cli.SetAlias(name, expansion)
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+// ANSI escape sequences used for colored output.
+const (
+	colorGreen  = "\033[32m"
+	colorRed    = "\033[31m"
+	colorReset  = "\033[0m"
+	colorYellow = "\033[33m"
+)
+
+// SetColor enables or disables colored output. Colored output is never
+// produced when the output format is JSON.
+func (cli *SeesawCLI) SetColor(enabled bool) {
+	cli.color = enabled
+}
+
+// colorize returns the given value wrapped in the specified color, if colored
+// output is enabled.
+func (cli *SeesawCLI) colorize(color string, v interface{}) string {
+	if !cli.color || cli.jsonOutput() {
+		return fmt.Sprint(v)
+	}
+	return fmt.Sprintf("%s%v%s", color, v, colorReset)
+}
+
+// haStateString returns the string representation of an HAState, colored
+// according to the state.
+func (cli *SeesawCLI) haStateString(state seesaw.HAState) string {
+	switch state {
+	case seesaw.HAMaster:
+		return cli.colorize(colorGreen, state)
+	case seesaw.HABackup:
+		return cli.colorize(colorYellow, state)
+	case seesaw.HADisabled, seesaw.HAShutdown:
+		return state.String()
+	}
+	return cli.colorize(colorRed, state)
+}
@@ -0,0 +1,191 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+// This file contains types and functions that implement completion of
+// command arguments with values retrieved from the Seesaw Engine.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const completionCacheTTL = 2 * time.Second
+
+// argCompleter returns the candidate values for a command argument.
+type argCompleter func(cli *SeesawCLI) ([]string, error)
+
+// argCompleters provides the argument completers for commands that take a
+// dynamic argument, keyed by command chain.
+var argCompleters = map[string]argCompleter{
+	"drain backend":                   backendNames,
+	"override backend state default":  backendNames,
+	"override backend state disabled": backendNames,
+	"override backend state enabled":  backendNames,
+	"override healthcheck":            healthcheckIDs,
+	"override vserver state default":  vserverNames,
+	"override vserver state disabled": vserverNames,
+	"override vserver state enabled":  vserverNames,
+	"show backends":                   backendNames,
+	"show destinations":               destinationNames,
+	"show nodes":                      nodeNames,
+	"show stats":                      vserverNames,
+	"show vservers":                   vserverNames,
+}
+
+// completionCache caches the candidate values returned by an argCompleter.
+type completionCache struct {
+	values  []string
+	fetched time.Time
+}
+
+// Complete returns the candidate values for the argument that is currently
+// being entered at the end of the given command line. Candidates are only
+// returned for the first argument of commands that accept a dynamic argument,
+// such as a vserver name. Values retrieved from the Seesaw Engine are cached
+// for a short period, to avoid repeated requests when completing.
+func (cli *SeesawCLI) Complete(cmdline string) []string {
+	cmd, _, chain, args := FindCommand(cmdline)
+	if cmd == nil {
+		return nil
+	}
+	prefix := ""
+	if len(args) > 0 && !strings.HasSuffix(cmdline, " ") {
+		prefix = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	if len(args) > 0 {
+		return nil
+	}
+
+	var names []string
+	for _, c := range chain {
+		names = append(names, c.Command)
+	}
+	key := strings.Join(names, " ")
+	completer, ok := argCompleters[key]
+	if !ok {
+		return nil
+	}
+
+	cache, ok := cli.completions[key]
+	if !ok || time.Since(cache.fetched) > completionCacheTTL {
+		values, err := completer(cli)
+		if err != nil {
+			return nil
+		}
+		sort.Strings(values)
+		cache = &completionCache{values: values, fetched: time.Now()}
+		cli.completions[key] = cache
+	}
+
+	var candidates []string
+	for _, v := range cache.values {
+		if strings.HasPrefix(v, prefix) {
+			candidates = append(candidates, v)
+		}
+	}
+	return candidates
+}
+
+func vserverNames(cli *SeesawCLI) ([]string, error) {
+	vservers, err := cli.seesaw.Vservers()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(vservers))
+	for name := range vservers {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func backendNames(cli *SeesawCLI) ([]string, error) {
+	vservers, err := cli.seesaw.Vservers()
+	if err != nil {
+		return nil, err
+	}
+	backends := make(map[string]bool)
+	for _, v := range vservers {
+		for _, s := range v.Services {
+			for _, d := range s.Destinations {
+				backends[d.Backend.Hostname] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func destinationNames(cli *SeesawCLI) ([]string, error) {
+	vservers, err := cli.seesaw.Vservers()
+	if err != nil {
+		return nil, err
+	}
+	dests := make(map[string]bool)
+	for _, v := range vservers {
+		for _, s := range v.Services {
+			for _, d := range s.Destinations {
+				dests[d.Name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(dests))
+	for name := range dests {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func healthcheckIDs(cli *SeesawCLI) ([]string, error) {
+	vservers, err := cli.seesaw.Vservers()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[uint64]bool)
+	for _, v := range vservers {
+		for _, s := range v.Services {
+			for _, d := range s.Destinations {
+				for _, hc := range d.Healthchecks {
+					if hc.Id != 0 {
+						ids[hc.Id] = true
+					}
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(ids))
+	for id := range ids {
+		names = append(names, fmt.Sprintf("0x%x", id))
+	}
+	return names, nil
+}
+
+func nodeNames(cli *SeesawCLI) ([]string, error) {
+	cs, err := cli.seesaw.ClusterStatus()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cs.Nodes))
+	for _, n := range cs.Nodes {
+		names = append(names, n.Hostname)
+	}
+	return names, nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/engine/config"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+var defaultClusterFile = path.Join(seesaw.ConfigPath, "cluster.pb")
+
+func showConfig(cli *SeesawCLI, args []string) error {
+	diff := false
+	clusterFile := defaultClusterFile
+	switch {
+	case len(args) == 0:
+	case len(args) <= 2 && (args[0] == "--diff" || args[0] == "-diff"):
+		diff = true
+		if len(args) == 2 {
+			clusterFile = args[1]
+		}
+	default:
+		fmt.Println("show config [--diff [<file>]]")
+		return errors.New("Incorrect arguments given.")
+	}
+
+	if diff && cli.remote {
+		return errors.New("show config --diff is not supported for remote engines")
+	}
+
+	rc, err := cli.seesaw.RunningConfig()
+	if err != nil {
+		return fmt.Errorf("Failed to get running config: %v", err)
+	}
+	running, err := config.ParseConfig(rc.Text, rc.Site)
+	if err != nil {
+		return fmt.Errorf("Failed to parse running config: %v", err)
+	}
+	if diff {
+		return cli.diffConfig(running, clusterFile)
+	}
+	if cli.jsonOutput() {
+		return printJSON(running)
+	}
+	printConfig(running)
+	return nil
+}
+
+// diffConfig compares the running configuration against the configuration in
+// the given cluster file.
+func (cli *SeesawCLI) diffConfig(running *config.Cluster, clusterFile string) error {
+	n, err := config.ReadConfig(clusterFile, running.Site)
+	if err != nil {
+		return fmt.Errorf("Failed to read config from %s: %v", clusterFile, err)
+	}
+	// The status reflects when and how the running config was loaded, rather
+	// than the configuration itself.
+	onDisk := *n.Cluster
+	onDisk.Status = running.Status
+
+	diff := pretty.Compare(&onDisk, running)
+	if cli.jsonOutput() {
+		return printJSON(struct {
+			File  string
+			Drift bool
+			Diff  string
+		}{clusterFile, diff != "", diff})
+	}
+	if diff == "" {
+		fmt.Printf("Running config matches %s\n", clusterFile)
+		return nil
+	}
+	printHdr("Running config differs from %s (- on disk, + running)", clusterFile)
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "-"):
+			line = cli.colorize(colorRed, line)
+		case strings.HasPrefix(line, "+"):
+			line = cli.colorize(colorGreen, line)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func validateConfig(cli *SeesawCLI, args []string) error {
+	clusterFile := defaultClusterFile
+	switch {
+	case len(args) == 0:
+	case len(args) == 1:
+		clusterFile = args[0]
+	default:
+		fmt.Println("validate config [<file>]")
+		return errors.New("Incorrect arguments given.")
+	}
+
+	// The configuration file is only parsed and checked - it is not
+	// applied to the running engine.
+	n, err := config.ReadConfig(clusterFile, "")
+	if err != nil {
+		return fmt.Errorf("Invalid config in %s: %v", clusterFile, err)
+	}
+	status := n.Cluster.Status
+	if cli.jsonOutput() {
+		if err := printJSON(struct {
+			File     string
+			Vservers int
+			Errors   []string
+			Warnings []string
+		}{clusterFile, len(n.Cluster.Vservers), status.Errors, status.Warnings}); err != nil {
+			return err
+		}
+	} else {
+		if len(status.Errors) == 0 {
+			fmt.Printf("Config in %s is valid (%d vservers).\n", clusterFile, len(n.Cluster.Vservers))
+		} else {
+			printList("Errors", status.Errors)
+		}
+		if len(status.Warnings) > 0 {
+			printList("Warnings", status.Warnings)
+		}
+	}
+	if len(status.Errors) > 0 {
+		return fmt.Errorf("Config in %s has %d errors", clusterFile, len(status.Errors))
+	}
+	return nil
+}
+
+// printConfig prints a summary of the given cluster configuration.
+func printConfig(c *config.Cluster) {
+	printHdr("Running Config")
+	printVal("Site:", c.Site)
+	printFmt("Cluster VIP:", "%s (%s, %s)", c.VIP.Hostname, c.VIP.IPv4Printable(), c.VIP.IPv6Printable())
+	printVal("BGP Local ASN:", c.BGPLocalASN)
+	printVal("BGP Remote ASN:", c.BGPRemoteASN)
+	printVal("Last Update:", c.Status.LastUpdate.Format(timeStamp))
+
+	peers := make([]string, 0, len(c.BGPPeers))
+	for hostname := range c.BGPPeers {
+		peers = append(peers, hostname)
+	}
+	printList("BGP Peers", peers)
+
+	nodes := make([]string, 0, len(c.Nodes))
+	for _, n := range c.Nodes {
+		nodes = append(nodes, fmt.Sprintf("%s (%s, priority %d)", n.Hostname, n.IPv4Printable(), n.Priority))
+	}
+	printList("Nodes", nodes)
+
+	subnets := make([]string, 0, len(c.VIPSubnets))
+	for subnet := range c.VIPSubnets {
+		subnets = append(subnets, subnet)
+	}
+	printList("VIP Subnets", subnets)
+
+	vlans := make([]string, 0, len(c.VLANs))
+	for _, v := range c.VLANs {
+		vlans = append(vlans, fmt.Sprintf("VLAN ID %d - %s", v.ID, v.Hostname))
+	}
+	printList("VLANs", vlans)
+
+	vservers := make([]string, 0, len(c.Vservers))
+	for _, v := range c.Vservers {
+		status := ""
+		if !v.Enabled {
+			status = ", disabled"
+		}
+		vservers = append(vservers, fmt.Sprintf("%s (%d entries, %d backends%s)",
+			v.Name, len(v.Entries), len(v.Backends), status))
+	}
+	printList("Vservers", vservers)
+}
+
+// printList prints a sorted, numbered list of items with the given heading.
+func printList(heading string, items []string) {
+	sort.Strings(items)
+	fmt.Println()
+	fmt.Printf("  %s:\n", heading)
+	if len(items) == 0 {
+		fmt.Println("    None")
+		return
+	}
+	for i, item := range items {
+		fmt.Printf("    [%3d] %s\n", i+1, item)
+	}
+}
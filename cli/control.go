@@ -17,14 +17,35 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+
+	"github.com/wy2745/seesaw/common/seesaw"
 )
 
 func configReload(cli *SeesawCLI, args []string) error {
-	if err := cli.seesaw.ConfigReload(); err != nil {
+	changes, err := cli.seesaw.ReloadConfig()
+	if err != nil {
 		return fmt.Errorf("Config reload failed: %v", err)
 	}
-	fmt.Println("Configuration reload requested.")
+	if cli.jsonOutput() {
+		return printJSON(changes)
+	}
+	if changes.Empty() {
+		fmt.Printf("Configuration reloaded from %s, no changes found.\n", changes.Source)
+		return nil
+	}
+	printHdr("Configuration reloaded from %s", changes.Source)
+	printList("Vservers Added", changes.VserversAdded)
+	printList("Vservers Removed", changes.VserversRemoved)
+	printList("Vservers Updated", changes.VserversUpdated)
+	printList("Backends Added", changes.BackendsAdded)
+	printList("Backends Removed", changes.BackendsRemoved)
+	printList("Weights Changed", changes.WeightsChanged)
+	if changes.Other {
+		fmt.Println()
+		fmt.Println("  Non-vserver configuration also changed.")
+	}
 	return nil
 }
 
@@ -50,9 +71,74 @@ func configSource(cli *SeesawCLI, args []string) error {
 }
 
 func failover(cli *SeesawCLI, args []string) error {
-	if err := cli.seesaw.Failover(); err != nil {
-		return fmt.Errorf("Failover request failed: %v", err)
+	switch {
+	case len(args) == 0:
+		if err := cli.seesaw.Failover(); err != nil {
+			return fmt.Errorf("Failover request failed: %v", err)
+		}
+		fmt.Println("Failover requested.")
+	case len(args) == 2 && args[0] == "to":
+		if err := cli.seesaw.FailoverTo(args[1]); err != nil {
+			return fmt.Errorf("Failover request failed: %v", err)
+		}
+		fmt.Printf("Failover to %s requested.\n", args[1])
+	default:
+		fmt.Println("failover [to <node>]")
+		return errors.New("Incorrect arguments given.")
+	}
+	return nil
+}
+
+func maintenanceDisable(cli *SeesawCLI, args []string) error {
+	ms, err := cli.seesaw.Maintenance(false)
+	if err != nil {
+		return fmt.Errorf("Maintenance request failed: %v", err)
+	}
+	if cli.jsonOutput() {
+		return printJSON(ms)
 	}
-	fmt.Println("Failover requested.")
+	fmt.Println("Maintenance mode disabled.")
 	return nil
 }
+
+func maintenanceEnable(cli *SeesawCLI, args []string) error {
+	ms, err := cli.seesaw.Maintenance(true)
+	if err != nil {
+		return fmt.Errorf("Maintenance request failed: %v", err)
+	}
+	if cli.jsonOutput() {
+		return printJSON(ms)
+	}
+	fmt.Println("Maintenance mode enabled.")
+	printMaintenanceStatus(cli, ms)
+	return nil
+}
+
+func maintenanceStatus(cli *SeesawCLI, args []string) error {
+	ms, err := cli.seesaw.MaintenanceStatus()
+	if err != nil {
+		return fmt.Errorf("Failed to get maintenance status: %v", err)
+	}
+	if cli.jsonOutput() {
+		return printJSON(ms)
+	}
+	printMaintenanceStatus(cli, ms)
+	return nil
+}
+
+func printMaintenanceStatus(cli *SeesawCLI, ms *seesaw.MaintenanceStatus) {
+	since := "N/A"
+	if ms.Enabled && !ms.Since.IsZero() {
+		since = ms.Since.Format(timeStamp)
+	}
+	safe := "No"
+	if ms.Safe {
+		safe = "Yes"
+	}
+	printHdr("Maintenance Status")
+	printVal("Enabled:", ms.Enabled)
+	printVal("Since:", since)
+	printVal("HA State:", cli.haStateString(ms.HAState))
+	printVal("Active Vservers:", ms.Vservers)
+	printVal("Safe To Shutdown:", safe)
+}
@@ -19,28 +19,161 @@
 package cli
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/wy2745/seesaw/common/conn"
 )
 
+// OutputFormat specifies the format used for command output.
+type OutputFormat int
+
+const (
+	OutputText OutputFormat = iota
+	OutputJSON
+)
+
+var outputFormatNames = map[OutputFormat]string{
+	OutputText: "text",
+	OutputJSON: "json",
+}
+
+// String returns the string representation of an OutputFormat.
+func (f OutputFormat) String() string {
+	if name, ok := outputFormatNames[f]; ok {
+		return name
+	}
+	return "(unknown)"
+}
+
+// ParseOutputFormat returns the OutputFormat with the given name.
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	for f, n := range outputFormatNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return OutputText, fmt.Errorf("unknown output format %q", name)
+}
+
+// BuildVersion is the build version of the Seesaw CLI. This is stamped at
+// link time for release builds, e.g.:
+//
+//	go build -ldflags "-X github.com/wy2745/seesaw/cli.BuildVersion=1.2.3"
+var BuildVersion = "(unknown)"
+
 // SeesawCLI represents a Seesaw command line interface.
 type SeesawCLI struct {
-	seesaw *conn.Seesaw
-	exit   func()
+	conn    *conn.Seesaw
+	seesaw  *conn.Seesaw
+	exit    func()
+	format  OutputFormat
+	color   bool
+	pager   bool
+	remote  bool
+	timeout time.Duration
+
+	execLock sync.Mutex
+	execCtx  context.Context    // The context of the executing command.
+	cancel   context.CancelFunc // Cancels the executing command.
+
+	aliases     map[string]string
+	completions map[string]*completionCache
 }
 
 // NewSeesawCLI returns a new Seesaw command line interface.
 func NewSeesawCLI(conn *conn.Seesaw, exit func()) *SeesawCLI {
-	return &SeesawCLI{conn, exit}
+	return &SeesawCLI{
+		conn:        conn,
+		seesaw:      conn,
+		exit:        exit,
+		aliases:     make(map[string]string),
+		completions: make(map[string]*completionCache),
+	}
+}
+
+// SetOutputFormat sets the format used for the output of commands that
+// return structured data. Commands without a structured payload always
+// produce text output.
+func (cli *SeesawCLI) SetOutputFormat(format OutputFormat) {
+	cli.format = format
+}
+
+// SetTimeout sets the maximum amount of time that a command may execute for.
+// A zero timeout results in commands being executed without a deadline.
+func (cli *SeesawCLI) SetTimeout(timeout time.Duration) {
+	cli.timeout = timeout
+}
+
+// SetRemote specifies whether the Seesaw Engine is on a remote host, in which
+// case commands that compare the engine's state against local files are
+// refused.
+func (cli *SeesawCLI) SetRemote(remote bool) {
+	cli.remote = remote
+}
+
+// Interrupt interrupts the currently executing command, cancelling any RPCs
+// that are in flight. It returns true if a command was interrupted.
+func (cli *SeesawCLI) Interrupt() bool {
+	cli.execLock.Lock()
+	defer cli.execLock.Unlock()
+	if cli.cancel == nil {
+		return false
+	}
+	cli.cancel()
+	cli.cancel = nil
+	return true
+}
+
+// executing marks a command as executing and returns a function that must be
+// called once the command completes.
+func (cli *SeesawCLI) executing() func() {
+	cli.execLock.Lock()
+	defer cli.execLock.Unlock()
+	if cli.execCtx != nil {
+		// A nested execution shares the outer command's context.
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cli.execCtx, cli.cancel = ctx, cancel
+	return func() {
+		cancel()
+		cli.execLock.Lock()
+		defer cli.execLock.Unlock()
+		cli.execCtx, cli.cancel = nil, nil
+	}
+}
+
+// context returns the context of the executing command.
+func (cli *SeesawCLI) context() context.Context {
+	cli.execLock.Lock()
+	defer cli.execLock.Unlock()
+	if cli.execCtx == nil {
+		return context.Background()
+	}
+	return cli.execCtx
+}
+
+// interrupted returns a channel that is closed when the executing command is
+// interrupted.
+func (cli *SeesawCLI) interrupted() <-chan struct{} {
+	return cli.context().Done()
 }
 
-// Execute executes the given command line.
+// Execute executes the given command line, after expanding any alias.
 func (cli *SeesawCLI) Execute(cmdline string) error {
-	cmd, subcmds, _, args := FindCommand(cmdline)
+	cmd, subcmds, chain, args := FindCommand(cli.expandAlias(cmdline))
 	if cmd != nil {
-		return cmd.function(cli, args)
+		done := cli.executing()
+		defer done()
+		if len(chain) > 0 && unpagedCommands[chain[0].Command] {
+			return cli.execute(cmd, args)
+		}
+		return cli.paged(func() error { return cli.execute(cmd, args) })
 	}
 	if subcmds != nil {
 		return errors.New("Incomplete command.")
@@ -48,6 +181,38 @@ func (cli *SeesawCLI) Execute(cmdline string) error {
 	return errors.New("Unknown command.")
 }
 
+// execute executes the given command with the specified arguments. RPCs to
+// the Seesaw Engine are abandoned if the command is interrupted or the
+// configured timeout expires.
+func (cli *SeesawCLI) execute(cmd *Command, args []string) error {
+	return cli.withContext(func() error { return cmd.function(cli, args) })
+}
+
+// withContext calls the given function, abandoning any RPCs that it makes to
+// the Seesaw Engine once the executing command is interrupted or the
+// configured timeout expires.
+func (cli *SeesawCLI) withContext(f func() error) error {
+	parent := cli.context()
+	ctx, cancel := context.WithCancel(parent)
+	if cli.timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, cli.timeout)
+	}
+	defer cancel()
+	seesaw := cli.seesaw
+	cli.seesaw = cli.conn.WithContext(ctx)
+	defer func() { cli.seesaw = seesaw }()
+
+	err := f()
+	switch {
+	case err == nil:
+	case parent.Err() == context.Canceled:
+		return errors.New("Command interrupted.")
+	case ctx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("Command timed out after %v", cli.timeout)
+	}
+	return err
+}
+
 func exit(cli *SeesawCLI, args []string) error {
 	cli.exit()
 	return nil
@@ -58,58 +223,122 @@ type Command struct {
 	Command     string
 	Subcommands *[]Command
 	function    func(cli *SeesawCLI, args []string) error
+	Destructive bool // Requires confirmation before being executed.
 }
 
-var commands = []Command{
-	{"config", &commandConfig, nil},
-	{"exit", nil, exit},
-	{"quit", nil, exit}, // An alias for exit, matches JunOS behavior.
-	{"failover", nil, failover},
-	{"override", &commandOverride, nil},
-	{"show", &commandShow, nil},
+var commands []Command
+
+func init() {
+	// The command table is initialised here since some commands (e.g. watch)
+	// look up other commands, which would otherwise result in an
+	// initialisation cycle.
+	commands = []Command{
+		{"alias", nil, alias, false},
+		{"config", &commandConfig, nil, false},
+		{"drain", &commandDrain, nil, false},
+		{"exit", nil, exit, false},
+		{"quit", nil, exit, false}, // An alias for exit, matches JunOS behavior.
+		{"failover", nil, failover, true},
+		{"maintenance", &commandMaintenance, nil, false},
+		{"override", &commandOverride, nil, false},
+		{"show", &commandShow, nil, false},
+		{"validate", &commandValidate, nil, false},
+		{"version", nil, showVersion, false},
+		{"watch", nil, watch, false},
+	}
 }
 
 var commandConfig = []Command{
-	{"reload", nil, configReload},
-	{"source", nil, configSource},
-	{"status", nil, configStatus},
+	{"reload", nil, configReload, false},
+	{"source", nil, configSource, false},
+	{"status", nil, configStatus, false},
+}
+
+var commandDrain = []Command{
+	{"backend", nil, drainBackend, true},
+}
+
+var commandMaintenance = []Command{
+	{"disable", nil, maintenanceDisable, false},
+	{"enable", nil, maintenanceEnable, true},
+	{"status", nil, maintenanceStatus, false},
 }
 
 var commandOverride = []Command{
-	{"vserver", &commandOverrideVserver, nil},
+	{"backend", &commandOverrideBackend, nil, false},
+	{"healthcheck", nil, overrideHealthcheck, true},
+	{"vserver", &commandOverrideVserver, nil, false},
+}
+
+var commandOverrideBackend = []Command{
+	{"state", &commandOverrideBackendState, nil, false},
+}
+
+var commandOverrideBackendState = []Command{
+	{"default", nil, overrideBackendStateDefault, false},
+	{"disabled", nil, overrideBackendStateDisabled, true},
+	{"enabled", nil, overrideBackendStateEnabled, true},
 }
 
 var commandOverrideVserver = []Command{
-	{"state", &commandOverrideVserverState, nil},
+	{"state", &commandOverrideVserverState, nil, false},
 }
 
 var commandOverrideVserverState = []Command{
-	{"default", nil, overrideVserverStateDefault},
-	{"disabled", nil, overrideVserverStateDisabled},
-	{"enabled", nil, overrideVserverStateEnabled},
+	{"default", nil, overrideVserverStateDefault, false},
+	{"disabled", nil, overrideVserverStateDisabled, true},
+	{"enabled", nil, overrideVserverStateEnabled, false},
 }
 
 var commandShow = []Command{
-	{"bgp", &commandShowBGP, nil},
-	{"backends", nil, showBackend},
-	{"destinations", nil, showDestination},
-	{"ha", nil, showHAStatus},
-	{"nodes", nil, showNode},
-	{"version", nil, showVersion},
-	{"vlans", nil, showVLANs},
-	{"vservers", nil, showVserver},
-	{"warnings", nil, showWarning},
+	{"bgp", &commandShowBGP, showBGPStatus, false},
+	{"backends", nil, showBackend, false},
+	{"config", nil, showConfig, false},
+	{"connections", nil, showConnections, false},
+	{"destinations", nil, showDestination, false},
+	{"ha", nil, showHAStatus, false},
+	{"healthchecks", nil, showHealthchecks, false},
+	{"nodes", nil, showNode, false},
+	{"stats", nil, showStats, false},
+	{"version", nil, showVersion, false},
+	{"vlans", nil, showVLANs, false},
+	{"vservers", nil, showVserver, false},
+	{"warnings", nil, showWarning, false},
 }
 
 var commandShowBGP = []Command{
-	{"neighbors", nil, showBGPNeighbors},
+	{"neighbors", nil, showBGPNeighbors, false},
+}
+
+var commandValidate = []Command{
+	{"config", nil, validateConfig, false},
+}
+
+// matchesCommand returns true if the given string is a prefix of any of the
+// commands.
+func matchesCommand(cmds []Command, s string) bool {
+	for _, cmd := range cmds {
+		if strings.HasPrefix(cmd.Command, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDestructive returns true if the given command line results in the
+// execution of a destructive command, after expanding any alias.
+func (cli *SeesawCLI) IsDestructive(cmdline string) bool {
+	cmd, _, _, _ := FindCommand(cli.expandAlias(cmdline))
+	return cmd != nil && cmd.Destructive
 }
 
 // FindCommand tokenises a command line and attempts to locate the
 // corresponding Command. If a matching command is found it is returned,
 // along with the remaining arguments. If the command has sub-commands then
-// the list of sub-commands is returned instead. A chain of matched commands
-// is also returned, along with the slice of remaining arguments.
+// the list of sub-commands is returned instead. A command may have both a
+// function and sub-commands, in which case it is returned along with its
+// sub-commands when no arguments follow it. A chain of matched commands is
+// also returned, along with the slice of remaining arguments.
 func FindCommand(cmdline string) (*Command, *[]Command, []*Command, []string) {
 	var chain []*Command
 	var matches []Command
@@ -141,11 +370,19 @@ func FindCommand(cmdline string) (*Command, *[]Command, []*Command, []string) {
 		}
 		chain = append(chain, next)
 		if next.function != nil {
-			// We've reached a function.
-			return next, nil, chain, cmdstr[idx+1:]
-		} else {
-			cmds = next.Subcommands
+			// We've reached a function, unless the next argument is
+			// one of its sub-commands.
+			args := cmdstr[idx+1:]
+			switch {
+			case next.Subcommands == nil:
+				return next, nil, chain, args
+			case len(args) == 0:
+				return next, next.Subcommands, chain, args
+			case !matchesCommand(*next.Subcommands, args[0]):
+				return next, nil, chain, args
+			}
 		}
+		cmds = next.Subcommands
 	}
 	if next != nil {
 		return nil, next.Subcommands, chain, nil
@@ -0,0 +1,93 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+const drainPollInterval = 5 * time.Second
+
+// backendActiveConns returns the number of destinations for the given backend
+// and the total number of active connections to them.
+func backendActiveConns(vservers map[string]*seesaw.Vserver, hostname string) (int, uint32) {
+	var dests int
+	var conns uint32
+	for _, v := range vservers {
+		for _, s := range v.Services {
+			for _, d := range s.Destinations {
+				if d.Backend == nil || d.Backend.Hostname != hostname {
+					continue
+				}
+				dests++
+				if d.Stats != nil && d.Stats.DestinationStats != nil {
+					conns += d.Stats.ActiveConns
+				}
+			}
+		}
+	}
+	return dests, conns
+}
+
+func drainBackend(cli *SeesawCLI, args []string) error {
+	if len(args) != 1 {
+		fmt.Println("drain backend <backend>")
+		return errors.New("Incorrect arguments given.")
+	}
+	hostname := args[0]
+	vservers, err := cli.seesaw.Vservers()
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve list of vservers: %v", err)
+	}
+	dests, conns := backendActiveConns(vservers, hostname)
+	if dests == 0 {
+		return fmt.Errorf("No such backend - %s", hostname)
+	}
+	if err := cli.seesaw.DrainBackend(hostname); err != nil {
+		return fmt.Errorf("Drain backend failed - %s", err)
+	}
+	fmt.Printf("Draining backend %s (%d destinations, %d active connections)...\n",
+		hostname, dests, conns)
+
+	interrupt := cli.interrupted()
+	for conns > 0 {
+		select {
+		case <-interrupt:
+			fmt.Printf("Stopped waiting - backend %s remains drained with %d active connections.\n",
+				hostname, conns)
+			return nil
+		case <-time.After(drainPollInterval):
+		}
+		err := cli.withContext(func() error {
+			var err error
+			vservers, err = cli.seesaw.Vservers()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to retrieve list of vservers: %v", err)
+		}
+		var active uint32
+		if _, active = backendActiveConns(vservers, hostname); active != conns {
+			fmt.Printf("%d active connections remaining\n", active)
+		}
+		conns = active
+	}
+	fmt.Printf("Backend %s drained - no active connections.\n", hostname)
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+// jsonOutput returns true if command output should be rendered as JSON.
+func (cli *SeesawCLI) jsonOutput() bool {
+	return cli.format == OutputJSON
+}
+
+// printJSON prints the JSON encoding of the given value.
+func printJSON(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to encode JSON: %v", err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// jsonVserver provides a JSON encodable representation of a vserver. The
+// services map is keyed by a struct, which cannot be encoded as a JSON
+// object key, hence the services are provided as a sorted list instead.
+type jsonVserver struct {
+	*seesaw.Vserver
+	Services []*seesaw.Service
+}
+
+// newJSONVserver returns a JSON encodable representation of a vserver.
+func newJSONVserver(vserver *seesaw.Vserver) *jsonVserver {
+	var keys seesaw.ServiceKeys
+	for _, svc := range vserver.Services {
+		keys = append(keys, &svc.ServiceKey)
+	}
+	sort.Sort(keys)
+	services := make([]*seesaw.Service, 0, len(keys))
+	for _, sk := range keys {
+		services = append(services, vserver.Services[*sk])
+	}
+	return &jsonVserver{vserver, services}
+}
+
+// newJSONVservers returns a JSON encodable representation of a map of
+// vservers.
+func newJSONVservers(vservers map[string]*seesaw.Vserver) map[string]*jsonVserver {
+	jv := make(map[string]*jsonVserver)
+	for name, vserver := range vservers {
+		jv[name] = newJSONVserver(vserver)
+	}
+	return jv
+}
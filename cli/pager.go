@@ -0,0 +1,98 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const defaultPager = "less -R"
+
+// unpagedCommands are the top-level commands that produce output
+// progressively, or never complete, and hence are not displayed via a pager.
+var unpagedCommands = map[string]bool{
+	"drain": true,
+	"exit":  true,
+	"quit":  true,
+	"watch": true,
+}
+
+// SetPager enables or disables the display of long command output via a
+// pager. Output is only paged when stdout is a terminal and the output
+// exceeds the height of the terminal.
+func (cli *SeesawCLI) SetPager(enabled bool) {
+	cli.pager = enabled
+}
+
+// paged calls the given function, capturing anything that it writes to stdout
+// and displaying it via a pager if it does not fit on the terminal.
+func (cli *SeesawCLI) paged(f func() error) error {
+	if !cli.pager || !terminal.IsTerminal(syscall.Stdout) {
+		return f()
+	}
+	_, height, err := terminal.GetSize(syscall.Stdout)
+	if err != nil {
+		return f()
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return f()
+	}
+
+	var output bytes.Buffer
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(&output, r)
+		close(copied)
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	err = f()
+	os.Stdout = stdout
+	w.Close()
+	<-copied
+	r.Close()
+
+	if bytes.Count(output.Bytes(), []byte("\n")) < height {
+		stdout.Write(output.Bytes())
+		return err
+	}
+	page(&output, stdout)
+	return err
+}
+
+// page displays the given output via the user's pager, as specified by
+// $PAGER. If the pager cannot be run the output is written directly.
+func page(output *bytes.Buffer, stdout *os.File) {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+	b := output.Bytes()
+	cmd := exec.Command("/bin/sh", "-c", pager)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		stdout.Write(b)
+	}
+}
@@ -17,7 +17,6 @@
 package cli
 
 import (
-	"errors"
 	"fmt"
 	"net"
 	"sort"
@@ -30,11 +29,36 @@ import (
 )
 
 const (
+	connPageSize = 100
+
 	subIndent = 2
 	valIndent = 22
 	timeStamp = "Jan 2 15:04:05 MST"
 )
 
+func showBGPStatus(cli *SeesawCLI, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("Too many arguments")
+	}
+	peers, err := cli.seesaw.BGPStatus()
+	if err != nil {
+		return fmt.Errorf("Failed to get BGP status: %v", err)
+	}
+	if cli.jsonOutput() {
+		return printJSON(peers)
+	}
+	printHdr("BGP Peers")
+	for i, p := range peers {
+		bfd := ""
+		if p.BFDStatus != "" {
+			bfd = ", BFD " + p.BFDStatus
+		}
+		fmt.Printf("[%3d] %s AS %d (%s, %v%s) - %d prefixes advertised, %d received\n",
+			i+1, p.IP, p.ASN, p.State, p.Uptime, bfd, p.PrefixesAdvertised, p.PrefixesReceived)
+	}
+	return nil
+}
+
 func showBGPNeighbors(cli *SeesawCLI, args []string) error {
 	neighbors, err := cli.seesaw.BGPNeighbors()
 	if err != nil {
@@ -42,9 +66,16 @@ func showBGPNeighbors(cli *SeesawCLI, args []string) error {
 	}
 
 	if len(args) == 0 {
+		if cli.jsonOutput() {
+			return printJSON(neighbors)
+		}
 		printHdr("BGP Neighbors")
 		for i, n := range neighbors {
-			fmt.Printf("[%3d] %s (%v, %v)\n", i+1, n.IP, n.BGPState, n.Uptime)
+			bfd := ""
+			if n.BFDStatus != "" {
+				bfd = ", BFD " + n.BFDStatus
+			}
+			fmt.Printf("[%3d] %s (%v, %v%s)\n", i+1, n.IP, n.BGPState, n.Uptime, bfd)
 		}
 	} else if len(args) == 1 {
 		ip := net.ParseIP(args[0])
@@ -61,6 +92,9 @@ func showBGPNeighbors(cli *SeesawCLI, args []string) error {
 		if n == nil {
 			return fmt.Errorf("No such neighbor")
 		}
+		if cli.jsonOutput() {
+			return printJSON(n)
+		}
 		printHdr("BGP Neighbor")
 		printVal("IP Address:", n.IP)
 		printVal("Router ID:", n.RouterID)
@@ -68,6 +102,9 @@ func showBGPNeighbors(cli *SeesawCLI, args []string) error {
 		printVal("Description:", n.Description)
 		printVal("BGP State:", n.BGPState)
 		printVal("Duration:", n.Uptime)
+		if n.BFDStatus != "" {
+			printVal("BFD State:", n.BFDStatus)
+		}
 	} else {
 		return fmt.Errorf("Too many arguments")
 	}
@@ -88,6 +125,9 @@ func showVLANs(cli *SeesawCLI, args []string) error {
 	if len(args) == 0 {
 		// Display all VLANs.
 		sort.Sort(vlans)
+		if cli.jsonOutput() {
+			return printJSON(vlans.VLANs)
+		}
 		printHdr("VLANs")
 		for i, v := range vlans.VLANs {
 			fmt.Printf("[%3d] VLAN ID %d - %s\n", i+1, v.ID, v.Hostname)
@@ -123,6 +163,9 @@ func showVLANs(cli *SeesawCLI, args []string) error {
 		return fmt.Errorf("unknown value %q - must be a VLAN ID or IP address", args[0])
 	}
 
+	if cli.jsonOutput() {
+		return printJSON(vlan)
+	}
 	printHdr("VLAN")
 	printVal("ID:", vlan.ID)
 	printVal("Hostname:", vlan.Hostname)
@@ -141,6 +184,9 @@ func showHAStatus(cli *SeesawCLI, args []string) error {
 	if err != nil {
 		return fmt.Errorf("HA status: %v\n", err)
 	}
+	if cli.jsonOutput() {
+		return printJSON(ha)
+	}
 
 	durationStr := "N/A"
 	if !ha.Since.IsZero() {
@@ -149,10 +195,18 @@ func showHAStatus(cli *SeesawCLI, args []string) error {
 		durationStr = fmt.Sprintf("%s (since %s)", duration, since)
 	}
 
+	if ha.SplitBrain {
+		fmt.Println("*** SPLIT-BRAIN DETECTED - the peer is also claiming to be master ***")
+	}
 	printHdr("HA Status")
-	printVal("State:", ha.State)
+	printVal("State:", cli.haStateString(ha.State))
 	printVal("Duration:", durationStr)
 	printVal("Transitions:", ha.Transitions)
+	printVal("Priority:", ha.Priority)
+	printVal("Effective Priority:", ha.EffectivePriority)
+	if len(ha.FailedTrackers) > 0 {
+		printVal("Failed Trackers:", strings.Join(ha.FailedTrackers, ", "))
+	}
 	printVal("Advertisements Sent:", ha.Sent)
 	printVal("Advertisements Rcvd:", ha.Received)
 	printVal("Last Update:", ha.LastUpdate.Format(timeStamp))
@@ -165,6 +219,9 @@ func configStatus(cli *SeesawCLI, args []string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to get config status: %v", err)
 	}
+	if cli.jsonOutput() {
+		return printJSON(cs)
+	}
 	printHdr("Config Status")
 	printVal("Last Update", cs.LastUpdate.Format(timeStamp))
 	fmt.Println()
@@ -200,6 +257,9 @@ func showNode(cli *SeesawCLI, args []string) error {
 		if node == nil {
 			return fmt.Errorf("node %q not found", nodeName)
 		}
+		if cli.jsonOutput() {
+			return printJSON(node)
+		}
 		printHdr("Node")
 		printVal("Hostname:", node.Hostname)
 		printVal("Site:", cs.Site)
@@ -211,6 +271,9 @@ func showNode(cli *SeesawCLI, args []string) error {
 		printVal("Vservers Enabled:", node.VserversEnabled)
 		return nil
 	}
+	if cli.jsonOutput() {
+		return printJSON(cs.Nodes)
+	}
 	printHdr("Nodes")
 	for i, node := range cs.Nodes {
 		enabled := "enabled"
@@ -260,6 +323,13 @@ func showBackend(cli *SeesawCLI, args []string) error {
 		return fmt.Errorf("no backends found")
 	}
 
+	if cli.jsonOutput() {
+		for _, dests := range backendsMap {
+			sort.Sort(dests)
+		}
+		return printJSON(backendsMap)
+	}
+
 	backends := make([]string, 0)
 	for backend := range backendsMap {
 		backends = append(backends, backend)
@@ -269,8 +339,11 @@ func showBackend(cli *SeesawCLI, args []string) error {
 		// Exactly 1 backend found, print details.
 		printHdr("Backend")
 		fmt.Printf("  Hostname: %v\n", backends[0])
-		fmt.Printf("  Destinations:\n")
 		dests := backendsMap[backends[0]]
+		if override := backendOverride(dests); override != "" {
+			fmt.Printf("  Override: %v\n", override)
+		}
+		fmt.Printf("  Destinations:\n")
 		sort.Sort(dests)
 		for i, d := range dests {
 			fmt.Printf("  [%3d] %v\n", i+1, destSummary(d, vservers))
@@ -310,9 +383,29 @@ func backendSummary(host string, dests []*seesaw.Destination, vservers map[strin
 		status = " (partially disabled)"
 	}
 
+	if override := backendOverride(dests); override != "" {
+		status += fmt.Sprintf(" (override %v)", override)
+	}
+
 	return fmt.Sprintf("%v%v", host, status)
 }
 
+// backendOverride returns a description of the override state for a backend,
+// or an empty string if the backend is not overridden.
+func backendOverride(dests []*seesaw.Destination) string {
+	for _, d := range dests {
+		if d.Override == seesaw.OverrideDefault {
+			continue
+		}
+		if d.OverrideExpires.IsZero() {
+			return d.Override.String()
+		}
+		return fmt.Sprintf("%v, expires in %v", d.Override,
+			time.Until(d.OverrideExpires).Truncate(time.Second))
+	}
+	return ""
+}
+
 func showDestination(cli *SeesawCLI, args []string) error {
 	if len(args) > 1 {
 		fmt.Println("show destinations <vserver|destination>")
@@ -338,13 +431,16 @@ func showDestination(cli *SeesawCLI, args []string) error {
 		}
 	}
 
-	switch len(dests) {
-	case 0:
+	switch {
+	case len(dests) == 0:
 		if len(args) > 0 {
 			return fmt.Errorf("destination '%v...' not found", args[0])
 		}
 		return fmt.Errorf("no destinations found")
-	case 1:
+	case cli.jsonOutput():
+		sort.Sort(dests)
+		return printJSON(dests)
+	case len(dests) == 1:
 		// Exactly one destination found, print destination details.
 		d := dests[0]
 		vserverName := d.VserverName
@@ -355,6 +451,9 @@ func showDestination(cli *SeesawCLI, args []string) error {
 		printVal("Name:", d.Name)
 		printVal("Vserver:", vserverName)
 		printVal("Backend:", d.Backend.Hostname)
+		if d.Backend.UpperThreshold > 0 {
+			printFmt("Thresholds:", "%d upper, %d lower", d.Backend.UpperThreshold, d.Backend.LowerThreshold)
+		}
 		printVal("Enabled:", d.Enabled)
 		printVal("Healthy:", d.Healthy)
 		printVal("Active:", d.Active)
@@ -371,6 +470,68 @@ func showDestination(cli *SeesawCLI, args []string) error {
 	return nil
 }
 
+func showHealthchecks(cli *SeesawCLI, args []string) error {
+	if len(args) > 1 {
+		fmt.Println("show healthchecks [<destination>]")
+		return nil
+	}
+
+	vservers, err := cli.seesaw.Vservers()
+	if err != nil {
+		return fmt.Errorf("Failed to get vservers: %v", err)
+	}
+
+	var dests seesaw.Destinations = make([]*seesaw.Destination, 0)
+	for _, v := range vservers {
+		for _, s := range v.Services {
+			for _, d := range s.Destinations {
+				if len(args) == 0 || strings.HasPrefix(d.Name, args[0]) {
+					dests = append(dests, d)
+				}
+			}
+		}
+	}
+	if len(dests) == 0 {
+		if len(args) > 0 {
+			return fmt.Errorf("destination '%v...' not found", args[0])
+		}
+		return fmt.Errorf("no destinations found")
+	}
+	sort.Sort(dests)
+
+	if cli.jsonOutput() {
+		checks := make(map[string][]*seesaw.HealthcheckStatus)
+		for _, d := range dests {
+			checks[d.Name] = d.Healthchecks
+		}
+		return printJSON(checks)
+	}
+
+	printHdr("Healthchecks")
+	for _, d := range dests {
+		fmt.Printf("  %v\n", d.Name)
+		if len(d.Healthchecks) == 0 {
+			fmt.Printf("    No healthchecks\n")
+		}
+		for i, hc := range d.Healthchecks {
+			fmt.Printf("    [%3d] %v", i+1, hc.Description)
+			if hc.Id != 0 {
+				fmt.Printf(" (ID 0x%x)", hc.Id)
+			}
+			fmt.Println()
+			fmt.Printf("          %v - %v", hc.State, hc.Message)
+			if !hc.LastCheck.IsZero() {
+				fmt.Printf(" (latency %v at %v)", hc.Latency, hc.LastCheck.Format(timeStamp))
+			}
+			fmt.Println()
+			if hc.OverrideState != seesaw.OverrideDefault {
+				fmt.Printf("          override state %v\n", hc.OverrideState)
+			}
+		}
+	}
+	return nil
+}
+
 func destSummary(d *seesaw.Destination, vservers map[string]*seesaw.Vserver) string {
 	status := statusSummary(d.Enabled, d.Healthy, d.Active)
 	if v, ok := vservers[d.VserverName]; ok && !v.Enabled {
@@ -422,6 +583,9 @@ func showVserver(cli *SeesawCLI, args []string) error {
 	}
 
 	vservers = filterVservers(filter, vservers)
+	if cli.jsonOutput() {
+		return printJSON(newJSONVservers(vservers))
+	}
 	switch len(vservers) {
 	case 0:
 		msg := "No vservers found"
@@ -446,7 +610,10 @@ func showVserver(cli *SeesawCLI, args []string) error {
 		for i, name := range names {
 			v := vservers[name]
 			status := ""
-			if !v.Enabled {
+			switch {
+			case !v.DrainDeadline.IsZero():
+				status = " (draining)"
+			case !v.Enabled:
 				status = " (disabled)"
 			}
 			fmt.Printf("[%3d] %s%s\n", i+1, name, status)
@@ -476,6 +643,10 @@ func printVserver(vserver *seesaw.Vserver) {
 	printVal("Status:", status)
 	printFmt("IPv4 Address:", vserver.Host.IPv4Printable())
 	printFmt("IPv6 Address:", vserver.Host.IPv6Printable())
+	if !vserver.DrainDeadline.IsZero() {
+		printVal("Draining:", fmt.Sprintf("%d active connections (deadline %s)",
+			vserver.ActiveConns(), vserver.DrainDeadline.Format(timeStamp)))
+	}
 	fmt.Println()
 	fmt.Printf("  Services:\n")
 
@@ -491,7 +662,15 @@ func printVserver(vserver *seesaw.Vserver) {
 		if svc.OnePacket {
 			config = append(config, "one-packet mode")
 		}
-		if svc.Persistence > 0 {
+		if svc.SchedFallback {
+			config = append(config, fmt.Sprintf("%s-fallback", svc.Scheduler))
+		}
+		if svc.SchedPort {
+			config = append(config, fmt.Sprintf("%s-port", svc.Scheduler))
+		}
+		if svc.Persistence > 0 && svc.PersistencePrefix > 0 {
+			config = append(config, fmt.Sprintf("%ds persistence per /%d", svc.Persistence, svc.PersistencePrefix))
+		} else if svc.Persistence > 0 {
 			config = append(config, fmt.Sprintf("%ds persistence", svc.Persistence))
 		}
 		l := label(fmt.Sprintf("%s %s/%d", svc.AF, svc.Proto, svc.Port), 4, 18)
@@ -514,8 +693,124 @@ func printVserver(vserver *seesaw.Vserver) {
 	}
 }
 
+func showStats(cli *SeesawCLI, args []string) error {
+	if len(args) != 1 {
+		fmt.Println("show stats <vserver>")
+		return nil
+	}
+
+	stats, err := cli.seesaw.VserverStats(args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to get vserver statistics: %v", err)
+	}
+	if cli.jsonOutput() {
+		return printJSON(stats)
+	}
+
+	printHdr("Statistics for vserver %s", args[0])
+	if len(stats) == 0 {
+		fmt.Println("No destinations.")
+		return nil
+	}
+	for i, d := range stats {
+		fmt.Printf("[%3d] %s (%s)\n", i+1, d.Name, d.Backend)
+		printVal("Connections:", d.Connections)
+		printVal("Active conns:", d.ActiveConns)
+		printVal("Inactive conns:", d.InactiveConns)
+		printVal("Bytes in:", d.BytesIn)
+		printVal("Bytes out:", d.BytesOut)
+		printVal("Bytes/sec in:", d.BPSIn)
+		printVal("Bytes/sec out:", d.BPSOut)
+		printVal("Packets/sec in:", d.PPSIn)
+		printVal("Packets/sec out:", d.PPSOut)
+	}
+	return nil
+}
+
+func showConnections(cli *SeesawCLI, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("show connections <vserver> [<page>]")
+		return nil
+	}
+	page := 1
+	if len(args) == 2 {
+		var err error
+		if page, err = strconv.Atoi(args[1]); err != nil || page < 1 {
+			return fmt.Errorf("invalid page %q", args[1])
+		}
+	}
+
+	filter := &seesaw.ConnFilter{
+		Vserver: args[0],
+		Offset:  (page - 1) * connPageSize,
+		Limit:   connPageSize,
+	}
+	ce, err := cli.seesaw.Connections(filter)
+	if err != nil {
+		return fmt.Errorf("Failed to get connections: %v", err)
+	}
+	if cli.jsonOutput() {
+		return printJSON(ce.Entries)
+	}
+
+	pages := (ce.Total + connPageSize - 1) / connPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	printHdr("Connections for vserver %s (page %d of %d, %d total)", args[0], page, pages, ce.Total)
+	if len(ce.Entries) == 0 {
+		fmt.Println("No connections.")
+		return nil
+	}
+	for i, c := range ce.Entries {
+		fmt.Printf("[%4d] %v %s -> %s -> %s %s %v\n", filter.Offset+i+1, c.Proto,
+			net.JoinHostPort(c.ClientIP.String(), strconv.Itoa(int(c.ClientPort))),
+			net.JoinHostPort(c.VIP.String(), strconv.Itoa(int(c.VIPPort))),
+			net.JoinHostPort(c.BackendIP.String(), strconv.Itoa(int(c.BackendPort))),
+			c.State, c.Expires)
+	}
+	return nil
+}
+
 func showVersion(cli *SeesawCLI, args []string) error {
-	return errors.New("unimplemented")
+	return cli.PrintVersion(func() (*seesaw.ClusterStatus, error) {
+		cs, err := cli.seesaw.ClusterStatus()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get cluster status: %v", err)
+		}
+		return cs, nil
+	})
+}
+
+// PrintVersion prints the version of the CLI, followed by the version of the
+// Seesaw Engine from the cluster status returned by the given function. The
+// CLI version is printed before the function is called, so that it is still
+// available if the engine cannot be reached.
+func (cli *SeesawCLI) PrintVersion(clusterStatus func() (*seesaw.ClusterStatus, error)) error {
+	if !cli.jsonOutput() {
+		fmt.Printf("CLI version: %s (protocol version %d)\n", BuildVersion, seesaw.SeesawVersion)
+	}
+	cs, err := clusterStatus()
+	if cli.jsonOutput() {
+		v := struct {
+			CLIVersion      string
+			ProtocolVersion int
+			EngineVersion   int `json:",omitempty"`
+		}{CLIVersion: BuildVersion, ProtocolVersion: seesaw.SeesawVersion}
+		if err == nil {
+			v.EngineVersion = cs.Version
+		}
+		if jerr := printJSON(v); jerr != nil {
+			return jerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !cli.jsonOutput() {
+		fmt.Printf("Engine version: %d\n", cs.Version)
+	}
+	return nil
 }
 
 func showWarning(cli *SeesawCLI, args []string) error {
@@ -523,6 +818,9 @@ func showWarning(cli *SeesawCLI, args []string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to get config status: %v", err)
 	}
+	if cli.jsonOutput() {
+		return printJSON(cs.Warnings)
+	}
 	if len(cs.Warnings) == 0 {
 		fmt.Println("No warnings.")
 		return nil
@@ -19,6 +19,8 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
 )
@@ -52,3 +54,92 @@ func overrideVserver(cli *SeesawCLI, args []string, state seesaw.OverrideState)
 	}
 	return nil
 }
+
+func overrideBackendStateDefault(cli *SeesawCLI, args []string) error {
+	return overrideBackend(cli, args, seesaw.OverrideDefault)
+}
+
+func overrideBackendStateDisabled(cli *SeesawCLI, args []string) error {
+	return overrideBackend(cli, args, seesaw.OverrideDisable)
+}
+
+func overrideBackendStateEnabled(cli *SeesawCLI, args []string) error {
+	return overrideBackend(cli, args, seesaw.OverrideEnable)
+}
+
+func overrideBackend(cli *SeesawCLI, args []string, state seesaw.OverrideState) error {
+	if len(args) < 1 || len(args) > 2 || (state == seesaw.OverrideDefault && len(args) != 1) {
+		fmt.Println("override backend state <default|disabled|enabled> <backend> [<ttl>]")
+		return errors.New("Incorrect arguments given.")
+	}
+	hostname := args[0]
+	o := &seesaw.BackendOverride{Hostname: hostname, OverrideState: state}
+	if len(args) == 2 {
+		ttl, err := time.ParseDuration(args[1])
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("Invalid TTL - %s", args[1])
+		}
+		o.Expires = time.Now().Add(ttl)
+	}
+	vservers, err := cli.seesaw.Vservers()
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve list of vservers: %v", err)
+	}
+	if dests, _ := backendActiveConns(vservers, hostname); dests == 0 {
+		return fmt.Errorf("No such backend - %s", hostname)
+	}
+	if err := cli.seesaw.OverrideBackend(o); err != nil {
+		return fmt.Errorf("Override backend state failed - %s", err)
+	}
+	return nil
+}
+
+func overrideHealthcheck(cli *SeesawCLI, args []string) error {
+	usage := "override healthcheck <id> <disable|enable>"
+	if len(args) != 2 {
+		fmt.Println(usage)
+		return errors.New("Incorrect arguments given.")
+	}
+	id, err := strconv.ParseUint(args[0], 0, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid healthcheck ID - %s", args[0])
+	}
+	var state seesaw.OverrideState
+	switch args[1] {
+	case "disable":
+		state = seesaw.OverrideDisable
+	case "enable":
+		state = seesaw.OverrideDefault
+	default:
+		fmt.Println(usage)
+		return errors.New("Incorrect arguments given.")
+	}
+	vservers, err := cli.seesaw.Vservers()
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve list of vservers: %v", err)
+	}
+	if !healthcheckExists(vservers, id) {
+		return fmt.Errorf("No such healthcheck - %s", args[0])
+	}
+	if err := cli.seesaw.OverrideHealthcheck(&seesaw.HealthcheckOverride{Id: id, OverrideState: state}); err != nil {
+		return fmt.Errorf("Override healthcheck failed - %s", err)
+	}
+	return nil
+}
+
+// healthcheckExists returns true if a healthcheck with the given ID exists
+// for any of the given vservers.
+func healthcheckExists(vservers map[string]*seesaw.Vserver, id uint64) bool {
+	for _, v := range vservers {
+		for _, s := range v.Services {
+			for _, d := range s.Destinations {
+				for _, hc := range d.Healthchecks {
+					if hc.Id == id {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
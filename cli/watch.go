@@ -0,0 +1,84 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	clearScreen      = "\033[H\033[2J"
+	minWatchInterval = 1 * time.Second
+)
+
+// parseWatchInterval parses a watch interval, which is either a number of
+// seconds or a duration string (e.g. "500ms" or "1m").
+func parseWatchInterval(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	interval, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+	return interval, nil
+}
+
+func watch(cli *SeesawCLI, args []string) error {
+	if len(args) < 2 {
+		fmt.Println("watch <interval> <command>")
+		return errors.New("Incorrect arguments given.")
+	}
+	interval, err := parseWatchInterval(args[0])
+	if err != nil {
+		return err
+	}
+	if interval < minWatchInterval {
+		interval = minWatchInterval
+	}
+
+	cmdline := strings.Join(args[1:], " ")
+	cmd, _, chain, cmdArgs := FindCommand(cli.expandAlias(cmdline))
+	if cmd == nil {
+		return cli.Execute(cmdline)
+	}
+	if len(chain) > 0 && chain[0].Command == "watch" {
+		return errors.New("Cannot watch a watch command.")
+	}
+	if cmd.Destructive {
+		return errors.New("Cannot watch a destructive command.")
+	}
+
+	interrupt := cli.interrupted()
+	for {
+		fmt.Print(clearScreen)
+		fmt.Printf("Every %v: %s\t%s\n\n", interval, cmdline, time.Now().Format(timeStamp))
+		if err := cli.execute(cmd, cmdArgs); err != nil {
+			fmt.Println(err)
+		}
+		select {
+		case <-interrupt:
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
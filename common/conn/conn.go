@@ -19,7 +19,15 @@
 package conn
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/wy2745/seesaw/common/ipc"
 	"github.com/wy2745/seesaw/common/seesaw"
@@ -31,6 +39,8 @@ import (
 type EngineConn interface {
 	Close() error
 	Dial(addr string) error
+	SetReconnect(reconnect bool, notify func())
+	WithContext(ctx context.Context) EngineConn
 
 	ClusterStatus() (*seesaw.ClusterStatus, error)
 	ConfigStatus() (*seesaw.ConfigStatus, error)
@@ -38,19 +48,50 @@ type EngineConn interface {
 
 	ConfigSource(source string) (string, error)
 	ConfigReload() error
+	ReloadConfig() (*seesaw.ClusterChanges, error)
+	RunningConfig() (*seesaw.ClusterConfig, error)
 
 	BGPNeighbors() ([]*quagga.Neighbor, error)
+	BGPStatus() ([]seesaw.BGPPeerStatus, error)
 
 	VLANs() (*seesaw.VLANs, error)
 
 	Vservers() (map[string]*seesaw.Vserver, error)
+	VserverStats(vserver string) ([]seesaw.DestinationStats, error)
+	Connections(filter *seesaw.ConnFilter) (*seesaw.ConnEntries, error)
 	Backends() (map[string]*seesaw.Backend, error)
 
 	OverrideBackend(override *seesaw.BackendOverride) error
 	OverrideDestination(override *seesaw.DestinationOverride) error
+	OverrideHealthcheck(override *seesaw.HealthcheckOverride) error
 	OverrideVserver(override *seesaw.VserverOverride) error
 
+	DrainBackend(hostname string) error
+
 	Failover() error
+	FailoverTo(node string) error
+
+	Maintenance(enable bool) (*seesaw.MaintenanceStatus, error)
+	MaintenanceStatus() (*seesaw.MaintenanceStatus, error)
+}
+
+const (
+	tcpScheme  = "tcp://"
+	unixScheme = "unix://"
+)
+
+// ParseEngineAddr parses the given engine address and returns the network
+// and address that should be used to connect to the Seesaw Engine. Addresses
+// of the form tcp://host:port result in a "tcp" network, while addresses of
+// the form unix:///path or plain paths result in a "unix" network.
+func ParseEngineAddr(engine string) (network, addr string) {
+	switch {
+	case strings.HasPrefix(engine, tcpScheme):
+		return "tcp", strings.TrimPrefix(engine, tcpScheme)
+	case strings.HasPrefix(engine, unixScheme):
+		return "unix", strings.TrimPrefix(engine, unixScheme)
+	}
+	return "unix", engine
 }
 
 var engineConns = make(map[string]func(ctx *ipc.Context) EngineConn)
@@ -65,6 +106,215 @@ type Seesaw struct {
 	EngineConn
 }
 
+// WithContext returns a shallow copy of the Seesaw connection, for which RPCs
+// are aborted once the given context is done.
+func (s *Seesaw) WithContext(ctx context.Context) *Seesaw {
+	return &Seesaw{s.EngineConn.WithContext(ctx)}
+}
+
+// ClusterStatusContext requests the status of the Seesaw Cluster, aborting
+// the request once the given context is done.
+func (s *Seesaw) ClusterStatusContext(ctx context.Context) (*seesaw.ClusterStatus, error) {
+	return s.EngineConn.WithContext(ctx).ClusterStatus()
+}
+
+// ConfigStatusContext requests the status of the Seesaw Cluster's
+// configuration, aborting the request once the given context is done.
+func (s *Seesaw) ConfigStatusContext(ctx context.Context) (*seesaw.ConfigStatus, error) {
+	return s.EngineConn.WithContext(ctx).ConfigStatus()
+}
+
+// HAStatusContext requests the HA status of the Seesaw Node, aborting the
+// request once the given context is done.
+func (s *Seesaw) HAStatusContext(ctx context.Context) (*seesaw.HAStatus, error) {
+	return s.EngineConn.WithContext(ctx).HAStatus()
+}
+
+// call invokes the named RPC using the given client. If the context is
+// non-nil and becomes done before the RPC completes, the context error is
+// returned. The reply is decoded into a private copy, which is only stored
+// into reply if the RPC completes in time, so that a late reply cannot
+// modify it.
+func call(ctx context.Context, client *rpc.Client, method string, args, reply interface{}) error {
+	if client == nil {
+		return errors.New("not connected")
+	}
+	if ctx == nil {
+		return client.Call(method, args, reply)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	result := reply
+	if reply != nil {
+		result = reflect.New(reflect.TypeOf(reply).Elem()).Interface()
+	}
+	rpcCall := client.Go(method, args, result, make(chan *rpc.Call, 1))
+	select {
+	case <-rpcCall.Done:
+		if rpcCall.Error == nil && reply != nil {
+			reflect.ValueOf(reply).Elem().Set(reflect.ValueOf(result).Elem())
+		}
+		return rpcCall.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reconnectDelays are the delays between successive attempts to re-establish
+// a lost connection to the Seesaw Engine.
+var reconnectDelays = []time.Duration{
+	500 * time.Millisecond,
+	1 * time.Second,
+	1500 * time.Millisecond,
+	2 * time.Second,
+}
+
+// rpcClient is an RPC client that is shared between copies of an engine
+// connection. If reconnection is enabled, the client is re-established when
+// an RPC fails due to the connection to the Seesaw Engine being lost.
+type rpcClient struct {
+	lock      sync.Mutex
+	client    *rpc.Client
+	addr      string
+	dial      func(addr string) (*rpc.Client, error)
+	reconnect bool
+	notify    func()
+}
+
+// newRPCClient returns a new rpcClient that uses the given dial function to
+// establish connections.
+func newRPCClient(dial func(addr string) (*rpc.Client, error)) *rpcClient {
+	return &rpcClient{dial: dial}
+}
+
+// connect establishes a connection to the given address.
+func (rc *rpcClient) connect(addr string) error {
+	client, err := rc.dial(addr)
+	if err != nil {
+		return err
+	}
+	rc.lock.Lock()
+	rc.client = client
+	rc.addr = addr
+	rc.lock.Unlock()
+	return nil
+}
+
+// close closes the current connection.
+func (rc *rpcClient) close() error {
+	rc.lock.Lock()
+	client := rc.client
+	rc.client = nil
+	rc.lock.Unlock()
+	if client == nil {
+		return errors.New("No client to close")
+	}
+	if err := client.Close(); err != nil {
+		return fmt.Errorf("Close failed: %v", err)
+	}
+	return nil
+}
+
+// setReconnect enables or disables reconnection. The notify function, if
+// non-nil, is called after a connection has been successfully re-established.
+func (rc *rpcClient) setReconnect(reconnect bool, notify func()) {
+	rc.lock.Lock()
+	rc.reconnect = reconnect
+	rc.notify = notify
+	rc.lock.Unlock()
+}
+
+// current returns the current RPC client and whether reconnection is enabled.
+func (rc *rpcClient) current() (*rpc.Client, bool) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	return rc.client, rc.reconnect
+}
+
+// call invokes the named RPC. If the connection has been lost and
+// reconnection is enabled, the connection is re-established. An RPC that was
+// never sent is then retried, while the error for an RPC that may have been
+// processed is returned to the caller. If the context is done before the RPC
+// completes, the RPC is aborted by closing the connection, while an RPC whose
+// context is already done is not sent at all.
+func (rc *rpcClient) call(ctx context.Context, method string, args, reply interface{}) error {
+	if ctx != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	client, reconnect := rc.current()
+	err := call(ctx, client, method, args, reply)
+	if ctx != nil && err != nil && err == ctx.Err() {
+		rc.abort(client)
+		return err
+	}
+	if !reconnect || client == nil || (err != rpc.ErrShutdown && err != io.ErrUnexpectedEOF) {
+		return err
+	}
+	if rerr := rc.redial(ctx, client); rerr != nil {
+		return err
+	}
+	if err != rpc.ErrShutdown {
+		return err
+	}
+	client, _ = rc.current()
+	return call(ctx, client, method, args, reply)
+}
+
+// abort closes the given client, which fails any RPCs that are still pending
+// on it. The closed client remains current, so that a subsequent RPC fails
+// with rpc.ErrShutdown and, if reconnection is enabled, re-establishes the
+// connection.
+func (rc *rpcClient) abort(client *rpc.Client) {
+	if client != nil {
+		client.Close()
+	}
+}
+
+// redial re-establishes a lost connection, retrying with a short backoff.
+// The connection is only replaced if the current client is still the one
+// that failed, since another caller may have already reconnected.
+func (rc *rpcClient) redial(ctx context.Context, failed *rpc.Client) error {
+	rc.lock.Lock()
+	if rc.client != failed {
+		rc.lock.Unlock()
+		return nil
+	}
+	failed.Close()
+
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	var err error
+	var client *rpc.Client
+	for _, delay := range reconnectDelays {
+		select {
+		case <-done:
+			rc.lock.Unlock()
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if client, err = rc.dial(rc.addr); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		rc.lock.Unlock()
+		return err
+	}
+	rc.client = client
+	notify := rc.notify
+	rc.lock.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+	return nil
+}
+
 // NewSeesawIPC returns a new Seesaw IPC connection.
 func NewSeesawIPC(ctx *ipc.Context) (*Seesaw, error) {
 	if newConn, ok := engineConns["ipc"]; ok {
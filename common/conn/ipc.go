@@ -17,6 +17,7 @@
 package conn
 
 import (
+	"context"
 	"fmt"
 	"net/rpc"
 
@@ -32,41 +33,68 @@ func init() {
 // engineIPC contains the structures necessary for communication with the
 // Seesaw Engine via IPC.
 type engineIPC struct {
-	client *rpc.Client
+	client *rpcClient
 	ctx    *ipc.Context
+
+	callCtx context.Context
 }
 
 // newEngineIPC returns a new engine IPC interface.
 func newEngineIPC(ctx *ipc.Context) EngineConn {
-	return &engineIPC{ctx: ctx}
+	c := &engineIPC{ctx: ctx}
+	c.client = newRPCClient(c.dial)
+	return c
 }
 
-// Dial establishes a connection to the Seesaw Engine.
-func (c *engineIPC) Dial(addr string) error {
+// dial establishes a new IPC client connection to the Seesaw Engine.
+func (c *engineIPC) dial(addr string) (*rpc.Client, error) {
 	client, err := rpc.Dial("unix", addr)
 	if err != nil {
-		return fmt.Errorf("Dial failed: %v", err)
+		return nil, fmt.Errorf("Dial failed: %v", err)
 	}
-	c.client = client
-	return nil
+	return client, nil
+}
+
+// Dial establishes a connection to the Seesaw Engine.
+func (c *engineIPC) Dial(addr string) error {
+	return c.client.connect(addr)
+}
+
+// SetReconnect enables or disables automatic reconnection to the Seesaw
+// Engine. The notify function, if non-nil, is called after a lost connection
+// has been re-established.
+func (c *engineIPC) SetReconnect(reconnect bool, notify func()) {
+	c.client.setReconnect(reconnect, notify)
+}
+
+// WithContext returns a copy of the connection that aborts RPCs once the
+// given context is done. The context deadline, if any, is also passed to the
+// Seesaw Engine, which refuses calls that arrive after it has passed.
+func (c *engineIPC) WithContext(ctx context.Context) EngineConn {
+	cc := *c
+	cc.callCtx = ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		ipcCtx := *c.ctx
+		ipcCtx.Deadline = deadline
+		cc.ctx = &ipcCtx
+	}
+	return &cc
+}
+
+// call invokes the named RPC on the Seesaw Engine.
+func (c *engineIPC) call(method string, args, reply interface{}) error {
+	return c.client.call(c.callCtx, method, args, reply)
 }
 
 // Close closes an existing connection to the Seesaw Engine.
 func (c *engineIPC) Close() error {
-	if c.client == nil {
-		return fmt.Errorf("No client to close")
-	}
-	if err := c.client.Close(); err != nil {
-		return fmt.Errorf("Close failed: %v", err)
-	}
-	c.client = nil
-	return nil
+	return c.client.close()
 }
 
 // ClusterStatus requests the status of the Seesaw Cluster.
 func (c *engineIPC) ClusterStatus() (*seesaw.ClusterStatus, error) {
 	var cs seesaw.ClusterStatus
-	if err := c.client.Call("SeesawEngine.ClusterStatus", c.ctx, &cs); err != nil {
+	if err := c.call("SeesawEngine.ClusterStatus", c.ctx, &cs); err != nil {
 		return nil, err
 	}
 	return &cs, nil
@@ -75,7 +103,7 @@ func (c *engineIPC) ClusterStatus() (*seesaw.ClusterStatus, error) {
 // ConfigStatus requests the status of the Seesaw Cluster's configuration.
 func (c *engineIPC) ConfigStatus() (*seesaw.ConfigStatus, error) {
 	var cs seesaw.ConfigStatus
-	if err := c.client.Call("SeesawEngine.ConfigStatus", c.ctx, &cs); err != nil {
+	if err := c.call("SeesawEngine.ConfigStatus", c.ctx, &cs); err != nil {
 		return nil, err
 	}
 	return &cs, nil
@@ -84,7 +112,7 @@ func (c *engineIPC) ConfigStatus() (*seesaw.ConfigStatus, error) {
 // HAStatus requests the HA status of the Seesaw Node.
 func (c *engineIPC) HAStatus() (*seesaw.HAStatus, error) {
 	var ha seesaw.HAStatus
-	if err := c.client.Call("SeesawEngine.HAStatus", c.ctx, &ha); err != nil {
+	if err := c.call("SeesawEngine.HAStatus", c.ctx, &ha); err != nil {
 		return nil, err
 	}
 	return &ha, nil
@@ -95,7 +123,7 @@ func (c *engineIPC) HAStatus() (*seesaw.HAStatus, error) {
 // unchanged. The current configuration source is returned.
 func (c *engineIPC) ConfigSource(source string) (string, error) {
 	cs := &ipc.ConfigSource{c.ctx, source}
-	if err := c.client.Call("SeesawEngine.ConfigSource", cs, &source); err != nil {
+	if err := c.call("SeesawEngine.ConfigSource", cs, &source); err != nil {
 		return "", err
 	}
 	return source, nil
@@ -103,23 +131,53 @@ func (c *engineIPC) ConfigSource(source string) (string, error) {
 
 // ConfigReload requests the configuration to be reloaded.
 func (c *engineIPC) ConfigReload() error {
-	return c.client.Call("SeesawEngine.ConfigReload", c.ctx, nil)
+	return c.call("SeesawEngine.ConfigReload", c.ctx, nil)
+}
+
+// ReloadConfig reloads the configuration and returns a summary of the
+// changes that were found.
+func (c *engineIPC) ReloadConfig() (*seesaw.ClusterChanges, error) {
+	var changes seesaw.ClusterChanges
+	if err := c.call("SeesawEngine.ReloadConfig", c.ctx, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// RunningConfig requests the cluster configuration that is currently in use
+// by the Seesaw Engine.
+func (c *engineIPC) RunningConfig() (*seesaw.ClusterConfig, error) {
+	var cluster seesaw.ClusterConfig
+	if err := c.call("SeesawEngine.RunningConfig", c.ctx, &cluster); err != nil {
+		return nil, err
+	}
+	return &cluster, nil
 }
 
 // BGPNeighbors requests a list of all BGP neighbors that this seesaw is
 // peering with.
 func (c *engineIPC) BGPNeighbors() ([]*quagga.Neighbor, error) {
 	var bn quagga.Neighbors
-	if err := c.client.Call("SeesawEngine.BGPNeighbors", c.ctx, &bn); err != nil {
+	if err := c.call("SeesawEngine.BGPNeighbors", c.ctx, &bn); err != nil {
 		return nil, err
 	}
 	return bn.Neighbors, nil
 }
 
+// BGPStatus requests the status of the BGP sessions with the peers of this
+// seesaw.
+func (c *engineIPC) BGPStatus() ([]seesaw.BGPPeerStatus, error) {
+	var bs seesaw.BGPStatus
+	if err := c.call("SeesawEngine.BGPStatus", c.ctx, &bs); err != nil {
+		return nil, err
+	}
+	return bs.Peers, nil
+}
+
 // VLANs requests a list of VLANs configured on the cluster.
 func (c *engineIPC) VLANs() (*seesaw.VLANs, error) {
 	var v seesaw.VLANs
-	if err := c.client.Call("SeesawEngine.VLANs", c.ctx, &v); err != nil {
+	if err := c.call("SeesawEngine.VLANs", c.ctx, &v); err != nil {
 		return nil, err
 	}
 	return &v, nil
@@ -128,16 +186,37 @@ func (c *engineIPC) VLANs() (*seesaw.VLANs, error) {
 // Vservers requests a list of all vservers that are configured on the cluster.
 func (c *engineIPC) Vservers() (map[string]*seesaw.Vserver, error) {
 	var vm seesaw.VserverMap
-	if err := c.client.Call("SeesawEngine.Vservers", c.ctx, &vm); err != nil {
+	if err := c.call("SeesawEngine.Vservers", c.ctx, &vm); err != nil {
 		return nil, err
 	}
 	return vm.Vservers, nil
 }
 
+// VserverStats requests the statistics for the destinations of a vserver.
+func (c *engineIPC) VserverStats(vserver string) ([]seesaw.DestinationStats, error) {
+	var vs seesaw.VserverStats
+	args := &ipc.VserverStats{Ctx: c.ctx, Vserver: vserver}
+	if err := c.call("SeesawEngine.VserverStats", args, &vs); err != nil {
+		return nil, err
+	}
+	return vs.Destinations, nil
+}
+
+// Connections requests the IPVS connection table entries that match the
+// given filter.
+func (c *engineIPC) Connections(filter *seesaw.ConnFilter) (*seesaw.ConnEntries, error) {
+	var ce seesaw.ConnEntries
+	args := &ipc.Connections{Ctx: c.ctx, Filter: *filter}
+	if err := c.call("SeesawEngine.Connections", args, &ce); err != nil {
+		return nil, err
+	}
+	return &ce, nil
+}
+
 // Backends requests a list of all backends that are configured on the cluster.
 func (c *engineIPC) Backends() (map[string]*seesaw.Backend, error) {
 	var bm seesaw.BackendMap
-	if err := c.client.Call("SeesawEngine.Backends", c.ctx, &bm); err != nil {
+	if err := c.call("SeesawEngine.Backends", c.ctx, &bm); err != nil {
 		return nil, err
 	}
 	return bm.Backends, nil
@@ -146,22 +225,62 @@ func (c *engineIPC) Backends() (map[string]*seesaw.Backend, error) {
 // OverrideBackend requests that the specified BackendOverride be applied.
 func (c *engineIPC) OverrideBackend(backend *seesaw.BackendOverride) error {
 	override := &ipc.Override{Ctx: c.ctx, Backend: backend}
-	return c.client.Call("SeesawEngine.OverrideDestination", override, nil)
+	return c.call("SeesawEngine.OverrideBackend", override, nil)
 }
 
 // OverrideDestination requests that the specified DestinationOverride be applied.
 func (c *engineIPC) OverrideDestination(destination *seesaw.DestinationOverride) error {
 	override := &ipc.Override{Ctx: c.ctx, Destination: destination}
-	return c.client.Call("SeesawEngine.OverrideDestination", override, nil)
+	return c.call("SeesawEngine.OverrideDestination", override, nil)
+}
+
+// OverrideHealthcheck requests that the specified HealthcheckOverride be applied.
+func (c *engineIPC) OverrideHealthcheck(healthcheck *seesaw.HealthcheckOverride) error {
+	override := &ipc.Override{Ctx: c.ctx, Healthcheck: healthcheck}
+	return c.call("SeesawEngine.OverrideHealthcheck", override, nil)
 }
 
 // OverrideVserver requests that the specified VserverOverride be applied.
 func (c *engineIPC) OverrideVserver(vserver *seesaw.VserverOverride) error {
 	override := &ipc.Override{Ctx: c.ctx, Vserver: vserver}
-	return c.client.Call("SeesawEngine.OverrideVserver", override, nil)
+	return c.call("SeesawEngine.OverrideVserver", override, nil)
+}
+
+// DrainBackend requests that the specified backend be drained.
+func (c *engineIPC) DrainBackend(hostname string) error {
+	backend := &seesaw.BackendOverride{Hostname: hostname, OverrideState: seesaw.OverrideDrain}
+	override := &ipc.Override{Ctx: c.ctx, Backend: backend}
+	return c.call("SeesawEngine.DrainBackend", override, nil)
 }
 
 // Failover requests a failover between the Seesaw Nodes.
 func (c *engineIPC) Failover() error {
-	return c.client.Call("SeesawEngine.Failover", c.ctx, nil)
+	return c.call("SeesawEngine.Failover", c.ctx, nil)
+}
+
+// FailoverTo requests a failover that results in the specified Seesaw Node
+// becoming master.
+func (c *engineIPC) FailoverTo(node string) error {
+	args := &ipc.Failover{Ctx: c.ctx, Node: node}
+	return c.call("SeesawEngine.FailoverTo", args, nil)
+}
+
+// Maintenance requests that maintenance mode be enabled or disabled for the
+// Seesaw Node. The resulting maintenance status is returned.
+func (c *engineIPC) Maintenance(enable bool) (*seesaw.MaintenanceStatus, error) {
+	var ms seesaw.MaintenanceStatus
+	args := &ipc.Maintenance{Ctx: c.ctx, Enable: enable}
+	if err := c.call("SeesawEngine.Maintenance", args, &ms); err != nil {
+		return nil, err
+	}
+	return &ms, nil
+}
+
+// MaintenanceStatus requests the maintenance status of the Seesaw Node.
+func (c *engineIPC) MaintenanceStatus() (*seesaw.MaintenanceStatus, error) {
+	var ms seesaw.MaintenanceStatus
+	if err := c.call("SeesawEngine.MaintenanceStatus", c.ctx, &ms); err != nil {
+		return nil, err
+	}
+	return &ms, nil
 }
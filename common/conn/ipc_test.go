@@ -0,0 +1,244 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conn
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wy2745/seesaw/common/ipc"
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+// fakeEngine records the overrides received via the SeesawEngine RPCs.
+type fakeEngine struct {
+	calls []string
+}
+
+func (f *fakeEngine) record(method string, o *ipc.Override) {
+	switch {
+	case o.Backend != nil:
+		f.calls = append(f.calls, method+" backend "+o.Backend.Hostname)
+	case o.Destination != nil:
+		f.calls = append(f.calls, method+" destination "+o.Destination.DestinationName)
+	default:
+		f.calls = append(f.calls, method)
+	}
+}
+
+func (f *fakeEngine) OverrideBackend(args *ipc.Override, reply *int) error {
+	f.record("OverrideBackend", args)
+	return nil
+}
+
+func (f *fakeEngine) OverrideDestination(args *ipc.Override, reply *int) error {
+	f.record("OverrideDestination", args)
+	return nil
+}
+
+// blockingEngine is a fake Seesaw Engine whose ClusterStatus RPC blocks until
+// it is released.
+type blockingEngine struct {
+	deadlines chan time.Time
+	release   chan struct{}
+	done      chan struct{}
+}
+
+func (b *blockingEngine) ClusterStatus(ctx *ipc.Context, reply *seesaw.ClusterStatus) error {
+	b.deadlines <- ctx.Deadline
+	<-b.release
+	reply.Site = "late"
+	b.done <- struct{}{}
+	return nil
+}
+
+// serveEngine serves the given fake Seesaw Engine on a Unix domain socket,
+// returning the path to the socket and a function that stops the server.
+func serveEngine(t *testing.T, engine interface{}) (string, func()) {
+	dir, err := ioutil.TempDir("", "conn")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	sock := filepath.Join(dir, "engine")
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("SeesawEngine", engine); err != nil {
+		t.Fatalf("RegisterName failed: %v", err)
+	}
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go server.Accept(l)
+
+	return sock, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEngineIPCOverrides(t *testing.T) {
+	engine := &fakeEngine{}
+	sock, stop := serveEngine(t, engine)
+	defer stop()
+
+	c := newEngineIPC(ipc.NewTrustedContext(seesaw.SCLocalCLI))
+	if err := c.Dial(sock); err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.OverrideBackend(&seesaw.BackendOverride{Hostname: "backend1.example.com."}); err != nil {
+		t.Errorf("OverrideBackend failed: %v", err)
+	}
+	if err := c.OverrideDestination(&seesaw.DestinationOverride{VserverName: "dns", DestinationName: "dns/backend1"}); err != nil {
+		t.Errorf("OverrideDestination failed: %v", err)
+	}
+
+	want := []string{
+		"OverrideBackend backend backend1.example.com.",
+		"OverrideDestination destination dns/backend1",
+	}
+	if len(engine.calls) != len(want) {
+		t.Fatalf("Got RPCs %q, want %q", engine.calls, want)
+	}
+	for i := range want {
+		if engine.calls[i] != want[i] {
+			t.Errorf("RPC %d is %q, want %q", i, engine.calls[i], want[i])
+		}
+	}
+}
+
+func TestEngineIPCContextCancel(t *testing.T) {
+	engine := &blockingEngine{
+		deadlines: make(chan time.Time, 1),
+		release:   make(chan struct{}),
+		done:      make(chan struct{}, 1),
+	}
+	sock, stop := serveEngine(t, engine)
+	defer stop()
+
+	c := newEngineIPC(ipc.NewTrustedContext(seesaw.SCLocalCLI))
+	if err := c.Dial(sock); err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	want, _ := ctx.Deadline()
+	cc := c.WithContext(ctx).(*engineIPC)
+	var cs seesaw.ClusterStatus
+	if err := cc.call("SeesawEngine.ClusterStatus", cc.ctx, &cs); err != context.DeadlineExceeded {
+		t.Errorf("ClusterStatus returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := <-engine.deadlines; !got.Equal(want) {
+		t.Errorf("Engine received deadline %v, want %v", got, want)
+	}
+
+	// Once the blocked RPC completes, its reply must not be delivered, since
+	// the connection was closed when the RPC was aborted.
+	close(engine.release)
+	<-engine.done
+	if cs.Site != "" {
+		t.Errorf("Aborted ClusterStatus reply was modified to site %q", cs.Site)
+	}
+	if _, err := c.ClusterStatus(); err != rpc.ErrShutdown {
+		t.Errorf("ClusterStatus after abort returned %v, want %v", err, rpc.ErrShutdown)
+	}
+
+	// With reconnection enabled, the next RPC re-establishes the connection.
+	c.SetReconnect(true, nil)
+	status, err := c.ClusterStatus()
+	if err != nil {
+		t.Fatalf("ClusterStatus after reconnect failed: %v", err)
+	}
+	if status.Site != "late" {
+		t.Errorf("ClusterStatus after reconnect returned site %q, want %q", status.Site, "late")
+	}
+	if got := <-engine.deadlines; !got.IsZero() {
+		t.Errorf("Engine received deadline %v for RPC without a deadline", got)
+	}
+}
+
+// statusEngine is a fake Seesaw Engine that serves the status RPCs.
+type statusEngine struct{}
+
+func (statusEngine) ClusterStatus(ctx *ipc.Context, reply *seesaw.ClusterStatus) error {
+	reply.Site = "site1"
+	return nil
+}
+
+func (statusEngine) ConfigStatus(ctx *ipc.Context, reply *seesaw.ConfigStatus) error {
+	reply.Warnings = []string{"warning"}
+	return nil
+}
+
+func (statusEngine) HAStatus(ctx *ipc.Context, reply *seesaw.HAStatus) error {
+	reply.State = seesaw.HAMaster
+	return nil
+}
+
+func TestSeesawStatusContext(t *testing.T) {
+	sock, stop := serveEngine(t, statusEngine{})
+	defer stop()
+
+	s := &Seesaw{newEngineIPC(ipc.NewTrustedContext(seesaw.SCECU))}
+	if err := s.Dial(sock); err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if cs, err := s.ClusterStatusContext(ctx); err != nil {
+		t.Errorf("ClusterStatusContext failed: %v", err)
+	} else if cs.Site != "site1" {
+		t.Errorf("ClusterStatusContext returned site %q, want %q", cs.Site, "site1")
+	}
+	if cs, err := s.ConfigStatusContext(ctx); err != nil {
+		t.Errorf("ConfigStatusContext failed: %v", err)
+	} else if len(cs.Warnings) != 1 {
+		t.Errorf("ConfigStatusContext returned warnings %q, want 1 warning", cs.Warnings)
+	}
+	if ha, err := s.HAStatusContext(ctx); err != nil {
+		t.Errorf("HAStatusContext failed: %v", err)
+	} else if ha.State != seesaw.HAMaster {
+		t.Errorf("HAStatusContext returned state %v, want %v", ha.State, seesaw.HAMaster)
+	}
+
+	// A context that is already done fails the RPCs without sending them.
+	cancel()
+	if _, err := s.ClusterStatusContext(ctx); err != context.Canceled {
+		t.Errorf("ClusterStatusContext returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := s.ConfigStatusContext(ctx); err != context.Canceled {
+		t.Errorf("ConfigStatusContext returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := s.HAStatusContext(ctx); err != context.Canceled {
+		t.Errorf("HAStatusContext returned %v, want %v", err, context.Canceled)
+	}
+
+	// The calls without a context are unchanged.
+	if _, err := s.ClusterStatus(); err != nil {
+		t.Errorf("ClusterStatus failed: %v", err)
+	}
+}
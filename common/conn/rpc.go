@@ -17,9 +17,14 @@
 package conn
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/rpc"
+	"strings"
 
 	"github.com/wy2745/seesaw/common/ipc"
 	"github.com/wy2745/seesaw/common/seesaw"
@@ -33,50 +38,111 @@ func init() {
 // engineRPC contains the structures necessary for communication with the
 // Seesaw Engine via RPC.
 type engineRPC struct {
-	client *rpc.Client
-	conn   *tls.Conn
-	ctx    *ipc.Context
+	client    *rpcClient
+	ctx       *ipc.Context
+	tlsConfig *tls.Config
+
+	callCtx context.Context
 }
 
 // newEngineRPC returns a new engine RPC interface.
 func newEngineRPC(ctx *ipc.Context) EngineConn {
-	return &engineRPC{ctx: ctx}
+	return newEngineRPCTLS(ctx, nil)
 }
 
-// Dial establishes a connection to the Seesaw Engine.
-func (c *engineRPC) Dial(addr string) error {
-	// TODO(jsing): Configure CA certificate chain and disable insecure
-	// skip verify.
+// newEngineRPCTLS returns a new engine RPC interface that uses the given TLS
+// configuration.
+func newEngineRPCTLS(ctx *ipc.Context, tlsConfig *tls.Config) *engineRPC {
+	c := &engineRPC{ctx: ctx, tlsConfig: tlsConfig}
+	c.client = newRPCClient(c.dial)
+	return c
+}
+
+// NewSeesawTLS returns a new Seesaw RPC connection that is established using
+// the given TLS configuration. This allows for the server certificate to be
+// verified and for a client certificate to be presented to the server.
+func NewSeesawTLS(ctx *ipc.Context, tlsConfig *tls.Config) *Seesaw {
+	return &Seesaw{newEngineRPCTLS(ctx, tlsConfig)}
+}
+
+// ClientTLSConfig returns a TLS configuration that verifies the server
+// against the CA certificates in caFile and presents the client certificate
+// from certFile and keyFile.
+func ClientTLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	rootCACerts := x509.NewCertPool()
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA cert file: %v", err)
+	}
+	if ok := rootCACerts.AppendCertsFromPEM(data); !ok {
+		return nil, errors.New("failed to load CA certificates")
+	}
+	certs, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load X.509 key pair: %v", err)
+	}
 	tlsConfig := &tls.Config{
-		InsecureSkipVerify: true,
+		Certificates: []tls.Certificate{certs},
+		RootCAs:      rootCACerts,
+		ServerName:   serverName,
 	}
+	return tlsConfig, nil
+}
+
+// dial establishes a new RPC client connection to the Seesaw Engine.
+func (c *engineRPC) dial(addr string) (*rpc.Client, error) {
+	tlsConfig := c.tlsConfig
+	if tlsConfig == nil {
+		// TODO(jsing): Configure CA certificate chain and disable insecure
+		// skip verify.
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+	addr = strings.TrimPrefix(addr, tcpScheme)
 	conn, err := tls.Dial("tcp", addr, tlsConfig)
 	if err != nil {
-		return fmt.Errorf("Dial failed: %v", err)
+		return nil, fmt.Errorf("Dial failed: %v", err)
 	}
-	c.conn = conn
-	c.client = rpc.NewClient(conn)
-	c.ctx.Peer.Identity = fmt.Sprintf("tcp %s", c.conn.LocalAddr())
-	return nil
+	c.ctx.Peer.Identity = fmt.Sprintf("tcp %s", conn.LocalAddr())
+	return rpc.NewClient(conn), nil
+}
+
+// Dial establishes a connection to the Seesaw Engine. The address may
+// optionally be prefixed with a tcp:// scheme.
+func (c *engineRPC) Dial(addr string) error {
+	return c.client.connect(addr)
+}
+
+// SetReconnect enables or disables automatic reconnection to the Seesaw
+// Engine. The notify function, if non-nil, is called after a lost connection
+// has been re-established.
+func (c *engineRPC) SetReconnect(reconnect bool, notify func()) {
+	c.client.setReconnect(reconnect, notify)
+}
+
+// WithContext returns a copy of the connection that aborts RPCs once the
+// given context is done.
+func (c *engineRPC) WithContext(ctx context.Context) EngineConn {
+	cc := *c
+	cc.callCtx = ctx
+	return &cc
+}
+
+// call invokes the named RPC on the Seesaw Engine.
+func (c *engineRPC) call(method string, args, reply interface{}) error {
+	return c.client.call(c.callCtx, method, args, reply)
 }
 
 // Close closes an existing connection to the Seesaw Engine.
 func (c *engineRPC) Close() error {
-	if c.client == nil {
-		return fmt.Errorf("No client to close")
-	}
-	if err := c.client.Close(); err != nil {
-		return fmt.Errorf("Close failed: %v", err)
-	}
-	c.client = nil
-	c.conn = nil
-	return nil
+	return c.client.close()
 }
 
 // ClusterStatus requests the status of the Seesaw Cluster.
 func (c *engineRPC) ClusterStatus() (*seesaw.ClusterStatus, error) {
 	var cs seesaw.ClusterStatus
-	if err := c.client.Call("SeesawECU.ClusterStatus", c.ctx, &cs); err != nil {
+	if err := c.call("SeesawECU.ClusterStatus", c.ctx, &cs); err != nil {
 		return nil, err
 	}
 	return &cs, nil
@@ -85,7 +151,7 @@ func (c *engineRPC) ClusterStatus() (*seesaw.ClusterStatus, error) {
 // ConfigStatus requests the status of the Seesaw Cluster's configuration.
 func (c *engineRPC) ConfigStatus() (*seesaw.ConfigStatus, error) {
 	var cs seesaw.ConfigStatus
-	if err := c.client.Call("SeesawECU.ConfigStatus", c.ctx, &cs); err != nil {
+	if err := c.call("SeesawECU.ConfigStatus", c.ctx, &cs); err != nil {
 		return nil, err
 	}
 	return &cs, nil
@@ -94,7 +160,7 @@ func (c *engineRPC) ConfigStatus() (*seesaw.ConfigStatus, error) {
 // HAStatus requests the HA status of the Seesaw Node.
 func (c *engineRPC) HAStatus() (*seesaw.HAStatus, error) {
 	var ha seesaw.HAStatus
-	if err := c.client.Call("SeesawECU.HAStatus", c.ctx, &ha); err != nil {
+	if err := c.call("SeesawECU.HAStatus", c.ctx, &ha); err != nil {
 		return nil, err
 	}
 	return &ha, nil
@@ -105,7 +171,7 @@ func (c *engineRPC) HAStatus() (*seesaw.HAStatus, error) {
 // unchanged. The current configuration source is returned.
 func (c *engineRPC) ConfigSource(source string) (string, error) {
 	cs := &ipc.ConfigSource{c.ctx, source}
-	if err := c.client.Call("SeesawECU.ConfigSource", cs, &source); err != nil {
+	if err := c.call("SeesawECU.ConfigSource", cs, &source); err != nil {
 		return "", err
 	}
 	return source, nil
@@ -113,23 +179,53 @@ func (c *engineRPC) ConfigSource(source string) (string, error) {
 
 // ConfigReload requests the configuration to be reloaded.
 func (c *engineRPC) ConfigReload() error {
-	return c.client.Call("SeesawECU.ConfigReload", c.ctx, nil)
+	return c.call("SeesawECU.ConfigReload", c.ctx, nil)
+}
+
+// ReloadConfig reloads the configuration and returns a summary of the
+// changes that were found.
+func (c *engineRPC) ReloadConfig() (*seesaw.ClusterChanges, error) {
+	var changes seesaw.ClusterChanges
+	if err := c.call("SeesawECU.ReloadConfig", c.ctx, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// RunningConfig requests the cluster configuration that is currently in use
+// by the Seesaw Engine.
+func (c *engineRPC) RunningConfig() (*seesaw.ClusterConfig, error) {
+	var cluster seesaw.ClusterConfig
+	if err := c.call("SeesawECU.RunningConfig", c.ctx, &cluster); err != nil {
+		return nil, err
+	}
+	return &cluster, nil
 }
 
 // BGPNeighbors requests a list of all BGP neighbors that this seesaw is
 // peering with.
 func (c *engineRPC) BGPNeighbors() ([]*quagga.Neighbor, error) {
 	var bn quagga.Neighbors
-	if err := c.client.Call("SeesawECU.BGPNeighbors", c.ctx, &bn); err != nil {
+	if err := c.call("SeesawECU.BGPNeighbors", c.ctx, &bn); err != nil {
 		return nil, err
 	}
 	return bn.Neighbors, nil
 }
 
+// BGPStatus requests the status of the BGP sessions with the peers of this
+// seesaw.
+func (c *engineRPC) BGPStatus() ([]seesaw.BGPPeerStatus, error) {
+	var bs seesaw.BGPStatus
+	if err := c.call("SeesawECU.BGPStatus", c.ctx, &bs); err != nil {
+		return nil, err
+	}
+	return bs.Peers, nil
+}
+
 // VLANs requests a list of VLANs configured on the cluster.
 func (c *engineRPC) VLANs() (*seesaw.VLANs, error) {
 	var v seesaw.VLANs
-	if err := c.client.Call("SeesawEngine.VLANs", c.ctx, &v); err != nil {
+	if err := c.call("SeesawEngine.VLANs", c.ctx, &v); err != nil {
 		return nil, err
 	}
 	return &v, nil
@@ -138,16 +234,37 @@ func (c *engineRPC) VLANs() (*seesaw.VLANs, error) {
 // Vservers requests a list of all vservers that are configured on the cluster.
 func (c *engineRPC) Vservers() (map[string]*seesaw.Vserver, error) {
 	var vm seesaw.VserverMap
-	if err := c.client.Call("SeesawECU.Vservers", c.ctx, &vm); err != nil {
+	if err := c.call("SeesawECU.Vservers", c.ctx, &vm); err != nil {
 		return nil, err
 	}
 	return vm.Vservers, nil
 }
 
+// VserverStats requests the statistics for the destinations of a vserver.
+func (c *engineRPC) VserverStats(vserver string) ([]seesaw.DestinationStats, error) {
+	var vs seesaw.VserverStats
+	args := &ipc.VserverStats{Ctx: c.ctx, Vserver: vserver}
+	if err := c.call("SeesawECU.VserverStats", args, &vs); err != nil {
+		return nil, err
+	}
+	return vs.Destinations, nil
+}
+
+// Connections requests the IPVS connection table entries that match the
+// given filter.
+func (c *engineRPC) Connections(filter *seesaw.ConnFilter) (*seesaw.ConnEntries, error) {
+	var ce seesaw.ConnEntries
+	args := &ipc.Connections{Ctx: c.ctx, Filter: *filter}
+	if err := c.call("SeesawECU.Connections", args, &ce); err != nil {
+		return nil, err
+	}
+	return &ce, nil
+}
+
 // Backends requests a list of all backends that are configured on the cluster.
 func (c *engineRPC) Backends() (map[string]*seesaw.Backend, error) {
 	var bm seesaw.BackendMap
-	if err := c.client.Call("SeesawECU.Backends", c.ctx, &bm); err != nil {
+	if err := c.call("SeesawECU.Backends", c.ctx, &bm); err != nil {
 		return nil, err
 	}
 	return bm.Backends, nil
@@ -156,22 +273,62 @@ func (c *engineRPC) Backends() (map[string]*seesaw.Backend, error) {
 // OverrideBackend requests that the specified VserverOverride be applied.
 func (c *engineRPC) OverrideBackend(backend *seesaw.BackendOverride) error {
 	override := &ipc.Override{Ctx: c.ctx, Backend: backend}
-	return c.client.Call("SeesawECU.OverrideBackend", override, nil)
+	return c.call("SeesawECU.OverrideBackend", override, nil)
 }
 
 // OverrideDestination requests that the specified VserverOverride be applied.
 func (c *engineRPC) OverrideDestination(destination *seesaw.DestinationOverride) error {
 	override := &ipc.Override{Ctx: c.ctx, Destination: destination}
-	return c.client.Call("SeesawECU.OverrideDestination", override, nil)
+	return c.call("SeesawECU.OverrideDestination", override, nil)
+}
+
+// OverrideHealthcheck requests that the specified HealthcheckOverride be applied.
+func (c *engineRPC) OverrideHealthcheck(healthcheck *seesaw.HealthcheckOverride) error {
+	override := &ipc.Override{Ctx: c.ctx, Healthcheck: healthcheck}
+	return c.call("SeesawECU.OverrideHealthcheck", override, nil)
 }
 
 // OverrideVserver requests that the specified VserverOverride be applied.
 func (c *engineRPC) OverrideVserver(vserver *seesaw.VserverOverride) error {
 	override := &ipc.Override{Ctx: c.ctx, Vserver: vserver}
-	return c.client.Call("SeesawECU.OverrideVserver", override, nil)
+	return c.call("SeesawECU.OverrideVserver", override, nil)
+}
+
+// DrainBackend requests that the specified backend be drained.
+func (c *engineRPC) DrainBackend(hostname string) error {
+	backend := &seesaw.BackendOverride{Hostname: hostname, OverrideState: seesaw.OverrideDrain}
+	override := &ipc.Override{Ctx: c.ctx, Backend: backend}
+	return c.call("SeesawECU.DrainBackend", override, nil)
 }
 
 // Failover requests a failover between the Seesaw Nodes.
 func (c *engineRPC) Failover() error {
-	return c.client.Call("SeesawECU.Failover", c.ctx, nil)
+	return c.call("SeesawECU.Failover", c.ctx, nil)
+}
+
+// FailoverTo requests a failover that results in the specified Seesaw Node
+// becoming master.
+func (c *engineRPC) FailoverTo(node string) error {
+	args := &ipc.Failover{Ctx: c.ctx, Node: node}
+	return c.call("SeesawECU.FailoverTo", args, nil)
+}
+
+// Maintenance requests that maintenance mode be enabled or disabled for the
+// Seesaw Node. The resulting maintenance status is returned.
+func (c *engineRPC) Maintenance(enable bool) (*seesaw.MaintenanceStatus, error) {
+	var ms seesaw.MaintenanceStatus
+	args := &ipc.Maintenance{Ctx: c.ctx, Enable: enable}
+	if err := c.call("SeesawECU.Maintenance", args, &ms); err != nil {
+		return nil, err
+	}
+	return &ms, nil
+}
+
+// MaintenanceStatus requests the maintenance status of the Seesaw Node.
+func (c *engineRPC) MaintenanceStatus() (*seesaw.MaintenanceStatus, error) {
+	var ms seesaw.MaintenanceStatus
+	if err := c.call("SeesawECU.MaintenanceStatus", c.ctx, &ms); err != nil {
+		return nil, err
+	}
+	return &ms, nil
 }
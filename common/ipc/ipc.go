@@ -23,6 +23,7 @@ import (
 	"os"
 	"os/user"
 	"strings"
+	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
 )
@@ -66,6 +67,10 @@ type Context struct {
 	Peer      Peer // Untrusted - client provided
 	Proxy     Peer
 	User      string
+
+	// Deadline is the time after which the caller will have abandoned the
+	// call. A zero value means that the call has no deadline.
+	Deadline time.Time
 }
 
 // NewContext returns a new context for the given component.
@@ -99,6 +104,18 @@ func NewTrustedContext(component seesaw.Component) *Context {
 	return ctx
 }
 
+// NewTrustedRemoteContext returns a new trusted context for a remote peer,
+// where the identity of the remote user has been established by the given
+// proxy component (for example, via a verified TLS client certificate).
+func NewTrustedRemoteContext(peer Peer, proxy seesaw.Component, user string) *Context {
+	ctx := NewContext(proxy)
+	ctx.AuthType = ATTrusted
+	ctx.Proxy = ctx.Peer
+	ctx.Peer = peer
+	ctx.User = user
+	return ctx
+}
+
 // String returns the string representation of a context.
 func (ctx *Context) String() string {
 	if ctx == nil {
@@ -116,6 +133,11 @@ func (ctx *Context) String() string {
 	return strings.Join(s, " ")
 }
 
+// Expired returns whether the deadline for a context has passed.
+func (ctx *Context) Expired() bool {
+	return !ctx.Deadline.IsZero() && time.Now().After(ctx.Deadline)
+}
+
 // IsTrusted returns whether a context came from a trusted source.
 func (ctx *Context) IsTrusted() bool {
 	return ctx.AuthType == ATTrusted
@@ -127,6 +149,12 @@ type ConfigSource struct {
 	Source string
 }
 
+// Failover contains data for a targeted failover IPC.
+type Failover struct {
+	Ctx  *Context
+	Node string
+}
+
 // HAStatus contains data for a HA status IPC.
 type HAStatus struct {
 	Ctx    *Context
@@ -139,10 +167,36 @@ type HAState struct {
 	State seesaw.HAState
 }
 
+// HAGroupState contains data for a HA group state IPC.
+type HAGroupState struct {
+	Ctx   *Context
+	VRID  uint8
+	State seesaw.HAState
+}
+
+// Maintenance contains data for a maintenance mode IPC.
+type Maintenance struct {
+	Ctx    *Context
+	Enable bool
+}
+
+// VserverStats contains data for a vserver statistics IPC.
+type VserverStats struct {
+	Ctx     *Context
+	Vserver string
+}
+
+// Connections contains data for an IPVS connection table IPC.
+type Connections struct {
+	Ctx    *Context
+	Filter seesaw.ConnFilter
+}
+
 // Override contains data for an override IPC.
 type Override struct {
 	Ctx         *Context
 	Vserver     *seesaw.VserverOverride
 	Destination *seesaw.DestinationOverride
 	Backend     *seesaw.BackendOverride
+	Healthcheck *seesaw.HealthcheckOverride
 }
@@ -0,0 +1,91 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics contains utility functions for exporting metrics from
+// Seesaw v2 components, using the Prometheus text-based exposition format
+// described at https://prometheus.io/docs/instrumenting/exposition_formats/.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/golang/glog"
+)
+
+// ContentType is the HTTP content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteFunc writes a set of metrics in the text exposition format.
+type WriteFunc func(w io.Writer) error
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// EscapeLabel escapes a Prometheus label value.
+func EscapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// WriteHeader writes the HELP and TYPE lines for a metric.
+func WriteHeader(b *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+}
+
+// Timestamp returns the given time in seconds since the epoch, or zero if the
+// time is unset.
+func Timestamp(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// Handler returns an HTTP handler that serves the metrics written by the
+// given function.
+func Handler(write WriteFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := write(w); err != nil {
+			log.Warningf("Failed to write metrics to %v: %v", r.RemoteAddr, err)
+		}
+	})
+}
+
+// Serve starts an HTTP server that exports the metrics written by the given
+// function on /metrics, listening on the given address.
+func Serve(addr string, write WriteFunc) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %v: %v", addr, err)
+	}
+	log.Infof("Exporting metrics on %v", ln.Addr())
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(write))
+	metricsHTTP := &http.Server{
+		Handler:        mux,
+		ReadTimeout:    30 * time.Second,
+		WriteTimeout:   30 * time.Second,
+		MaxHeaderBytes: 1 << 20,
+	}
+	if err := metricsHTTP.Serve(ln); err != nil {
+		log.Errorf("Metrics server failed: %v", err)
+	}
+}
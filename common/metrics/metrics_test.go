@@ -0,0 +1,70 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEscapeLabel(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"web.example.com", "web.example.com"},
+		{`web "quoted"`, `web \"quoted\"`},
+		{`back\slash`, `back\\slash`},
+		{"new\nline", `new\nline`},
+	}
+	for _, test := range tests {
+		if got := EscapeLabel(test.label); got != test.want {
+			t.Errorf("EscapeLabel(%q) = %q, want %q", test.label, got, test.want)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	if got := Timestamp(time.Time{}); got != 0 {
+		t.Errorf("Timestamp of zero time = %g, want 0", got)
+	}
+	if got, want := Timestamp(time.Unix(1500000000, 500000000)), 1500000000.5; got != want {
+		t.Errorf("Timestamp = %g, want %g", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	write := func(w io.Writer) error {
+		var b bytes.Buffer
+		WriteHeader(&b, "seesaw_test_total", "counter", "A test counter.")
+		b.WriteString("seesaw_test_total 1\n")
+		_, err := w.Write(b.Bytes())
+		return err
+	}
+	w := httptest.NewRecorder()
+	Handler(write).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if got := w.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Got content type %q, want %q", got, ContentType)
+	}
+	want := "# HELP seesaw_test_total A test counter.\n" +
+		"# TYPE seesaw_test_total counter\n" +
+		"seesaw_test_total 1\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("Got metrics %q, want %q", got, want)
+	}
+}
@@ -119,6 +119,46 @@ type HAStatus struct {
 	Received       uint64
 	ReceivedQueued uint64
 	Transitions    uint64
+
+	// Priority is the configured priority of the node, while
+	// EffectivePriority is the priority being advertised after
+	// subtracting the weights of any failing trackers.
+	Priority          uint8
+	EffectivePriority uint8
+	FailedTrackers    []string
+
+	// SplitBrain is true if this node is master and has recently received
+	// advertisements from a peer that also claims to be master.
+	SplitBrain bool
+}
+
+// BGPPeerStatus indicates the status of a BGP session with a peer of a Seesaw
+// Node.
+type BGPPeerStatus struct {
+	IP                 net.IP
+	ASN                uint32
+	Description        string
+	State              string
+	Uptime             time.Duration
+	PrefixesAdvertised uint64
+	PrefixesReceived   uint64
+	BFDStatus          string // Empty if BFD is not enabled for the peer.
+}
+
+// BGPStatus contains the status of the BGP sessions for a Seesaw Node.
+type BGPStatus struct {
+	Peers []BGPPeerStatus
+}
+
+// MaintenanceStatus indicates the maintenance status for a Seesaw Node. A node
+// that is in maintenance may be safely taken down once it is no longer the HA
+// master and all of its vservers have been shut down.
+type MaintenanceStatus struct {
+	Enabled  bool
+	Since    time.Time
+	HAState  HAState
+	Vservers int
+	Safe     bool
 }
 
 // HealthcheckMode specifies the mode for a Healthcheck.
@@ -154,6 +194,14 @@ const (
 	HCTypeTCP
 	HCTypeTCPTLS
 	HCTypeUDP
+	HCTypeGRPC
+	HCTypeGRPCTLS
+	HCTypeExec
+	HCTypeSMTP
+	HCTypeSMTPStartTLS
+	HCTypeRedis
+	HCTypeMySQL
+	HCTypeNTP
 )
 
 // String returns the name for the given HealthcheckType.
@@ -177,6 +225,24 @@ func (h HealthcheckType) String() string {
 		return "TCP" // NB: Not TCPTLS
 	case HCTypeUDP:
 		return "UDP"
+	case HCTypeGRPC:
+		return "GRPC"
+	// TODO(angusc): Drop GRPCTLS as a separate type.
+	case HCTypeGRPCTLS:
+		return "GRPC" // NB: Not GRPCTLS
+	case HCTypeExec:
+		return "EXEC"
+	case HCTypeSMTP:
+		return "SMTP"
+	// TODO(angusc): Drop SMTPStartTLS as a separate type.
+	case HCTypeSMTPStartTLS:
+		return "SMTP" // NB: Not SMTPStartTLS
+	case HCTypeRedis:
+		return "REDIS"
+	case HCTypeMySQL:
+		return "MYSQL"
+	case HCTypeNTP:
+		return "NTP"
 	}
 	return "(unknown)"
 }
@@ -302,12 +368,14 @@ const (
 	LBModeNone LBMode = iota
 	LBModeDSR
 	LBModeNAT
+	LBModeTUN
 )
 
 var modeNames = map[LBMode]string{
 	LBModeNone: "None",
 	LBModeDSR:  "DSR",
 	LBModeNAT:  "NAT",
+	LBModeTUN:  "TUN",
 }
 
 // String returns the string representation of a LBMode.
@@ -328,6 +396,7 @@ const (
 	LBSchedulerLC
 	LBSchedulerWLC
 	LBSchedulerSH
+	LBSchedulerMH
 )
 
 var schedulerNames = map[LBScheduler]string{
@@ -337,6 +406,13 @@ var schedulerNames = map[LBScheduler]string{
 	LBSchedulerLC:   "lc",
 	LBSchedulerWLC:  "wlc",
 	LBSchedulerSH:   "sh",
+	LBSchedulerMH:   "mh",
+}
+
+// LBSchedulers returns the load balancer scheduling algorithms that are
+// supported by Seesaw.
+func LBSchedulers() []LBScheduler {
+	return []LBScheduler{LBSchedulerRR, LBSchedulerWRR, LBSchedulerLC, LBSchedulerWLC, LBSchedulerSH, LBSchedulerMH}
 }
 
 // String returns the string representation of a LBScheduler.
@@ -355,6 +431,7 @@ const (
 	OverrideDefault OverrideState = iota
 	OverrideDisable
 	OverrideEnable
+	OverrideDrain
 )
 
 // String returns the string representation of an OverrideState.
@@ -366,6 +443,8 @@ func (o OverrideState) String() string {
 		return "disabled"
 	case OverrideEnable:
 		return "enabled"
+	case OverrideDrain:
+		return "drain"
 	}
 	return "(unknown)"
 }
@@ -380,9 +459,13 @@ type VserverOverride struct {
 	OverrideState
 }
 
+// BackendOverride overrides the state of a backend for all vservers. An
+// enabled backend is considered to be healthy and a disabled backend is
+// considered to be unhealthy, regardless of the state of its healthchecks.
 type BackendOverride struct {
 	Hostname string
 	OverrideState
+	Expires time.Time // If non-zero, the time at which the override expires.
 }
 
 type DestinationOverride struct {
@@ -391,6 +474,14 @@ type DestinationOverride struct {
 	OverrideState
 }
 
+// HealthcheckOverride overrides a single healthcheck, identified by its
+// healthcheck ID. A disabled healthcheck is no longer performed and is
+// considered to be healthy.
+type HealthcheckOverride struct {
+	Id uint64
+	OverrideState
+}
+
 // Host contains the hostname, IP addresses, and IP masks for a host.
 type Host struct {
 	Hostname string
@@ -422,6 +513,7 @@ type ConfigMetadata struct {
 // ConfigStatus describes the status of the currently-loaded config.
 type ConfigStatus struct {
 	Attributes []ConfigMetadata
+	Errors     []string
 	LastUpdate time.Time
 	Warnings   []string
 }
@@ -433,14 +525,78 @@ type ClusterStatus struct {
 	Nodes
 }
 
+// ClusterChanges summarises the differences between two cluster
+// configurations. Backends are identified as "<vserver>/<backend>".
+type ClusterChanges struct {
+	Source string
+
+	VserversAdded   []string
+	VserversRemoved []string
+	VserversUpdated []string
+
+	BackendsAdded   []string
+	BackendsRemoved []string
+	WeightsChanged  []string
+
+	// Other is true if there are changes other than to vservers.
+	Other bool
+}
+
+// Empty returns true if no changes were found.
+func (c *ClusterChanges) Empty() bool {
+	return len(c.VserversAdded) == 0 && len(c.VserversRemoved) == 0 &&
+		len(c.VserversUpdated) == 0 && !c.Other
+}
+
+// String returns a summary of the changes.
+func (c *ClusterChanges) String() string {
+	if c.Empty() {
+		return "no changes"
+	}
+	return fmt.Sprintf("%d vservers added, %d removed, %d updated; %d backends added, %d removed, %d weights changed",
+		len(c.VserversAdded), len(c.VserversRemoved), len(c.VserversUpdated),
+		len(c.BackendsAdded), len(c.BackendsRemoved), len(c.WeightsChanged))
+}
+
+// ClusterConfig contains a cluster configuration that is in use by a Seesaw
+// Engine, as a protocol buffer in text format.
+type ClusterConfig struct {
+	Site string
+	Text string
+}
+
 // HAConfig represents the high availability configuration for a node in a
 // Seesaw cluster.
 type HAConfig struct {
-	Enabled    bool
-	LocalAddr  net.IP
-	RemoteAddr net.IP
-	Priority   uint8
-	VRID       uint8
+	Enabled            bool
+	LocalAddr          net.IP
+	RemoteAddr         net.IP
+	Priority           uint8
+	VRID               uint8
+	Groups             []HAGroup   // Additional VRRP groups for active/active operation.
+	Trackers           []HATracker // Health checks that reduce the advertised priority.
+	HeartbeatInterface string      // Interface for sending and receiving advertisements (any if empty).
+	HeartbeatSecret    []byte      // Shared secret for authenticating advertisements (none if empty).
+}
+
+// HAGroup represents an additional VRRP group, which allows a subset of the
+// vservers in a Seesaw cluster to be mastered by a different node.
+type HAGroup struct {
+	VRID     uint8
+	Priority uint8
+}
+
+// HATracker represents a health check for a dependency of a Seesaw node, such
+// as an uplink interface. While the check is failing, the weight of the
+// tracker is subtracted from the priority advertised by the node, so that
+// mastership moves to a healthier node. Exactly one of Interface, Script or
+// Vservers is specified.
+type HATracker struct {
+	Name      string
+	Interface string // Fails if the named interface is down.
+	Script    string // Fails if the command exits with a non-zero status.
+	Vservers  int    // Fails if fewer than this many vservers are healthy.
+	Weight    uint8
 }
 
 // VLAN represents a VLAN interface configuration.
@@ -467,6 +623,25 @@ type Vserver struct {
 	Enabled       bool
 	ConfigEnabled bool
 	Warnings      []string
+
+	// DrainDeadline is non-zero if the vserver has been removed and is
+	// draining active connections, in which case it will be stopped at
+	// or shortly after this time.
+	DrainDeadline time.Time
+}
+
+// ActiveConns returns the number of active connections for the vserver, per
+// the most recent IPVS statistics.
+func (v *Vserver) ActiveConns() uint32 {
+	var conns uint32
+	for _, svc := range v.Services {
+		for _, d := range svc.Destinations {
+			if d.Stats != nil && d.Stats.DestinationStats != nil {
+				conns += d.Stats.ActiveConns
+			}
+		}
+	}
+	return conns
 }
 
 // VserverEntry represents a port and protocol combination for a Vserver.
@@ -479,9 +654,14 @@ type VserverEntry struct {
 	OnePacket     bool
 	HighWatermark float32
 	LowWatermark  float32
+	SchedFallback bool
+	SchedPort     bool
 	// TODO(angusc): Rename these:
 	LThreshold int
 	UThreshold int
+
+	PersistencePrefixIPv4 int
+	PersistencePrefixIPv6 int
 }
 
 // VserverMap provides a map of vservers keyed by vserver name.
@@ -494,6 +674,7 @@ type ServiceKey struct {
 	AF
 	Proto IPProto
 	Port  uint16
+	Alias IP // The additional VIP for the service, if any.
 }
 
 // ServiceKeys represents a list of ServiceKey.
@@ -502,19 +683,22 @@ type ServiceKeys []*ServiceKey
 // Service represents a load balancing service.
 type Service struct {
 	ServiceKey
-	IP               net.IP
-	Mode             LBMode
-	Scheduler        LBScheduler
-	OnePacket        bool
-	Persistence      int
-	Stats            *ServiceStats
-	Destinations     map[string]*Destination // keyed by backend hostname
-	Enabled          bool
-	Healthy          bool
-	Active           bool
-	HighWatermark    float32
-	LowWatermark     float32
-	CurrentWatermark float32
+	IP                net.IP
+	Mode              LBMode
+	Scheduler         LBScheduler
+	OnePacket         bool
+	SchedFallback     bool
+	SchedPort         bool
+	Persistence       int
+	PersistencePrefix int // zero if each client address is treated individually
+	Stats             *ServiceStats
+	Destinations      map[string]*Destination // keyed by backend hostname
+	Enabled           bool
+	Healthy           bool
+	Active            bool
+	HighWatermark     float32
+	LowWatermark      float32
+	CurrentWatermark  float32
 }
 
 // ServiceStats contains statistics for a Service.
@@ -532,11 +716,71 @@ type Destination struct {
 	Enabled     bool
 	Healthy     bool
 	Active      bool
+
+	// Override is the state of the BackendOverride for the destination's
+	// backend, which expires at OverrideExpires if that is non-zero.
+	Override        OverrideState
+	OverrideExpires time.Time
+
+	Healthchecks []*HealthcheckStatus
+}
+
+// HealthcheckStatus represents the current status of a healthcheck for a
+// destination.
+type HealthcheckStatus struct {
+	Id          uint64
+	Description string
+	State       string
+	Message     string
+	LastCheck   time.Time
+	Latency     time.Duration
+	Failures    uint64
+	Successes   uint64
+	OverrideState
 }
 
-// DestinationStats contains statistics for a Destination.
+// DestinationStats contains statistics for a Destination. The destination
+// name and backend hostname are only populated for VserverStats.
 type DestinationStats struct {
 	*ipvs.DestinationStats
+	Name    string
+	Backend string
+}
+
+// VserverStats contains statistics for the destinations of a vserver.
+type VserverStats struct {
+	Destinations []DestinationStats
+}
+
+// ConnEntry represents an entry in the IPVS connection table.
+type ConnEntry struct {
+	Proto       IPProto
+	ClientIP    net.IP
+	ClientPort  uint16
+	VIP         net.IP
+	VIPPort     uint16
+	BackendIP   net.IP
+	BackendPort uint16
+	State       string
+	Expires     time.Duration
+}
+
+// ConnFilter specifies the IPVS connection table entries to be returned.
+// Empty fields match all entries. Matching entries are returned starting from
+// Offset, with at most Limit entries being returned.
+type ConnFilter struct {
+	Vserver string
+	VIP     net.IP
+	Backend net.IP
+	Offset  int
+	Limit   int
+}
+
+// ConnEntries contains a page of IPVS connection table entries, along with
+// the total number of entries that matched the filter.
+type ConnEntries struct {
+	Entries []ConnEntry
+	Total   int
 }
 
 // Destinations represents a list of Destination.
@@ -549,6 +793,11 @@ type Backend struct {
 	Weight    int32
 	Enabled   bool
 	InService bool
+
+	// UpperThreshold and LowerThreshold limit the number of connections
+	// to the backend, overriding those of the vserver entry if non-zero.
+	UpperThreshold int32
+	LowerThreshold int32
 }
 
 // BackendMap provides a map of backends keyed by backend hostname.
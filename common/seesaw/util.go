@@ -123,6 +123,8 @@ func (b *Backend) Copy(c *Backend) {
 	b.Enabled = c.Enabled
 	b.InService = c.InService
 	b.Weight = c.Weight
+	b.UpperThreshold = c.UpperThreshold
+	b.LowerThreshold = c.LowerThreshold
 	b.Host.Copy(&c.Host)
 }
 
@@ -145,6 +147,22 @@ func (h *HAConfig) Copy(c *HAConfig) {
 	h.RemoteAddr = copyIP(c.RemoteAddr)
 	h.Priority = c.Priority
 	h.VRID = c.VRID
+	h.Groups = nil
+	if c.Groups != nil {
+		h.Groups = make([]HAGroup, len(c.Groups))
+		copy(h.Groups, c.Groups)
+	}
+	h.Trackers = nil
+	if c.Trackers != nil {
+		h.Trackers = make([]HATracker, len(c.Trackers))
+		copy(h.Trackers, c.Trackers)
+	}
+	h.HeartbeatInterface = c.HeartbeatInterface
+	h.HeartbeatSecret = nil
+	if c.HeartbeatSecret != nil {
+		h.HeartbeatSecret = make([]byte, len(c.HeartbeatSecret))
+		copy(h.HeartbeatSecret, c.HeartbeatSecret)
+	}
 }
 
 // Clone creates an identical copy of the given Seesaw HAConfig.
@@ -160,13 +178,45 @@ func (h *HAConfig) Equal(other *HAConfig) bool {
 		h.LocalAddr.Equal(other.LocalAddr) &&
 		h.RemoteAddr.Equal(other.RemoteAddr) &&
 		h.Priority == other.Priority &&
-		h.VRID == other.VRID
+		h.VRID == other.VRID &&
+		reflect.DeepEqual(h.Groups, other.Groups) &&
+		reflect.DeepEqual(h.Trackers, other.Trackers) &&
+		h.HeartbeatInterface == other.HeartbeatInterface &&
+		bytes.Equal(h.HeartbeatSecret, other.HeartbeatSecret)
 }
 
 // String returns the string representation of an HAConfig.
 func (h HAConfig) String() string {
-	return fmt.Sprintf("Enabled: %v, LocalAddr: %v, RemoteAddr: %v, Priority: %d, VRID: %d",
+	s := fmt.Sprintf("Enabled: %v, LocalAddr: %v, RemoteAddr: %v, Priority: %d, VRID: %d",
 		h.Enabled, h.LocalAddr, h.RemoteAddr, h.Priority, h.VRID)
+	for _, g := range h.Groups {
+		s += fmt.Sprintf(", Group: {VRID: %d, Priority: %d}", g.VRID, g.Priority)
+	}
+	for _, t := range h.Trackers {
+		s += fmt.Sprintf(", Tracker: {%v}", t)
+	}
+	if h.HeartbeatInterface != "" {
+		s += fmt.Sprintf(", HeartbeatInterface: %s", h.HeartbeatInterface)
+	}
+	// The secret itself is deliberately omitted.
+	if len(h.HeartbeatSecret) > 0 {
+		s += ", HeartbeatAuth: true"
+	}
+	return s
+}
+
+// String returns the string representation of an HATracker.
+func (t HATracker) String() string {
+	var check string
+	switch {
+	case t.Interface != "":
+		check = fmt.Sprintf("Interface: %s", t.Interface)
+	case t.Script != "":
+		check = fmt.Sprintf("Script: %q", t.Script)
+	default:
+		check = fmt.Sprintf("Vservers: %d", t.Vservers)
+	}
+	return fmt.Sprintf("Name: %s, %s, Weight: %d", t.Name, check, t.Weight)
 }
 
 // String returns the string representation of an HAState.
@@ -216,6 +266,9 @@ func (v VLANs) Less(i, j int) bool {
 
 // String returns the string representation of a ServiceKey.
 func (sk ServiceKey) String() string {
+	if sk.Alias != (IP{}) {
+		return fmt.Sprintf("%s %s %d (%v)", sk.AF, sk.Proto, sk.Port, sk.Alias)
+	}
 	return fmt.Sprintf("%s %s %d", sk.AF, sk.Proto, sk.Port)
 }
 
@@ -228,7 +281,10 @@ func (sk ServiceKeys) Less(i, j int) bool {
 	if sk[i].Proto != sk[j].Proto {
 		return sk[i].Proto < sk[j].Proto
 	}
-	return sk[i].Port < sk[j].Port
+	if sk[i].Port != sk[j].Port {
+		return sk[i].Port < sk[j].Port
+	}
+	return bytes.Compare(sk[i].Alias[:], sk[j].Alias[:]) < 0
 }
 
 func (o *VserverOverride) Target() string       { return o.VserverName }
@@ -240,6 +296,9 @@ func (o *BackendOverride) State() OverrideState { return o.OverrideState }
 func (o *DestinationOverride) Target() string       { return o.DestinationName }
 func (o *DestinationOverride) State() OverrideState { return o.OverrideState }
 
+func (o *HealthcheckOverride) Target() string       { return fmt.Sprintf("healthcheck 0x%x", o.Id) }
+func (o *HealthcheckOverride) State() OverrideState { return o.OverrideState }
+
 // IP returns the destination IP address for a given address family.
 func (d *Destination) IP(af AF) net.IP {
 	switch af {
@@ -258,6 +317,13 @@ func (d Destinations) Less(i, j int) bool {
 	return d[i].Name < d[j].Name
 }
 
+// DestinationStatsByName is used to sort DestinationStats by name.
+type DestinationStatsByName []DestinationStats
+
+func (d DestinationStatsByName) Len() int           { return len(d) }
+func (d DestinationStatsByName) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d DestinationStatsByName) Less(i, j int) bool { return d[i].Name < d[j].Name }
+
 // copyIP creates a copy of an IP.
 func copyIP(src net.IP) net.IP {
 	return net.IP(copyBytes(src))
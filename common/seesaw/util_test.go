@@ -108,12 +108,16 @@ var backends = []Backend{
 		1,
 		true,
 		false,
+		0,
+		0,
 	},
 	{
 		newTestHost(1, "backend2", true, true),
 		2,
 		false,
 		false,
+		1000,
+		500,
 	},
 }
 
@@ -145,6 +149,34 @@ var haConfigs = []HAConfig{
 		Priority:   200,
 		VRID:       99,
 	},
+	{
+		Enabled:    true,
+		LocalAddr:  net.ParseIP("1.2.3.4"),
+		RemoteAddr: net.ParseIP("224.0.0.18"),
+		Priority:   100,
+		VRID:       60,
+		Groups:     []HAGroup{{VRID: 61, Priority: 200}, {VRID: 62, Priority: 50}},
+	},
+	{
+		Enabled:    true,
+		LocalAddr:  net.ParseIP("1.2.3.4"),
+		RemoteAddr: net.ParseIP("224.0.0.18"),
+		Priority:   100,
+		VRID:       60,
+		Trackers: []HATracker{
+			{Name: "uplink", Interface: "eth1", Weight: 50},
+			{Name: "vservers", Vservers: 3, Weight: 20},
+		},
+	},
+	{
+		Enabled:            true,
+		LocalAddr:          net.ParseIP("1.2.3.4"),
+		RemoteAddr:         net.ParseIP("224.0.0.18"),
+		Priority:           100,
+		VRID:               60,
+		HeartbeatInterface: "eth2",
+		HeartbeatSecret:    []byte("secret"),
+	},
 }
 
 func TestHAConfigClone(t *testing.T) {
@@ -346,24 +378,26 @@ func TestInSubnets(t *testing.T) {
 
 func TestServiceKeysSort(t *testing.T) {
 	sk := ServiceKeys{
-		{IPv4, IPProtoTCP, 80},
-		{IPv6, IPProtoTCP, 80},
-		{IPv4, IPProtoUDP, 53},
-		{IPv6, IPProtoTCP, 53},
-		{IPv4, IPProtoTCP, 443},
-		{IPv6, IPProtoTCP, 443},
-		{IPv4, IPProtoTCP, 53},
-		{IPv6, IPProtoUDP, 53},
+		{IPv4, IPProtoTCP, 80, IP{}},
+		{IPv6, IPProtoTCP, 80, IP{}},
+		{IPv4, IPProtoUDP, 53, IP{}},
+		{IPv6, IPProtoTCP, 53, IP{}},
+		{IPv4, IPProtoTCP, 443, IP{}},
+		{IPv6, IPProtoTCP, 443, IP{}},
+		{IPv4, IPProtoTCP, 53, IP{}},
+		{IPv6, IPProtoUDP, 53, IP{}},
+		{IPv4, IPProtoTCP, 80, ParseIP("192.168.36.10")},
 	}
 	want := ServiceKeys{
-		{IPv4, IPProtoTCP, 53},
-		{IPv4, IPProtoTCP, 80},
-		{IPv4, IPProtoTCP, 443},
-		{IPv4, IPProtoUDP, 53},
-		{IPv6, IPProtoTCP, 53},
-		{IPv6, IPProtoTCP, 80},
-		{IPv6, IPProtoTCP, 443},
-		{IPv6, IPProtoUDP, 53},
+		{IPv4, IPProtoTCP, 53, IP{}},
+		{IPv4, IPProtoTCP, 80, IP{}},
+		{IPv4, IPProtoTCP, 80, ParseIP("192.168.36.10")},
+		{IPv4, IPProtoTCP, 443, IP{}},
+		{IPv4, IPProtoUDP, 53, IP{}},
+		{IPv6, IPProtoTCP, 53, IP{}},
+		{IPv6, IPProtoTCP, 80, IP{}},
+		{IPv6, IPProtoTCP, 443, IP{}},
+		{IPv6, IPProtoUDP, 53, IP{}},
 	}
 	sort.Sort(sk)
 	for i := range want {
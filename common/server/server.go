@@ -108,6 +108,13 @@ func ServerRunDirectory(server string, owner, group int) error {
 // fatals on any accept error, including temporary failures and closure of
 // the listener.
 func RPCAccept(ln net.Listener, server *rpc.Server) error {
+	return Accept(ln, func(conn net.Conn) { server.ServeConn(conn) })
+}
+
+// Accept accepts connections on the listener and dispatches each connection
+// to the given handler in a new goroutine. Temporary accept errors are
+// retried and closure of the listener results in a nil error being returned.
+func Accept(ln net.Listener, handler func(conn net.Conn)) error {
 	errClosing := errors.New("use of closed network connection")
 	for {
 		conn, err := ln.Accept()
@@ -124,6 +131,28 @@ func RPCAccept(ln net.Listener, server *rpc.Server) error {
 			log.Errorf("RPC accept error: %v", err)
 			return err
 		}
-		go server.ServeConn(conn)
+		go handler(conn)
+	}
+}
+
+// PeerCredentials returns the credentials of the process at the other end of
+// the given Unix domain socket connection, as recorded by the kernel when the
+// connection was established.
+func PeerCredentials(conn net.Conn) (*syscall.Ucred, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a Unix domain socket", conn.RemoteAddr())
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
 	}
+	return cred, credErr
 }
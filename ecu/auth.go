@@ -25,6 +25,7 @@ import (
 
 	"github.com/wy2745/seesaw/common/conn"
 	"github.com/wy2745/seesaw/common/ipc"
+	"github.com/wy2745/seesaw/common/seesaw"
 )
 
 // authInit initialises the authentication system.
@@ -32,19 +33,31 @@ func (e *ECU) authInit() error {
 	return nil
 }
 
-// authenticate attempts to validate the given authentication token.
-func (e *ECU) authenticate(ctx *ipc.Context) (*ipc.Context, error) {
+// authenticate attempts to validate the given authentication token. If the
+// client identity has already been established via a verified TLS client
+// certificate, a trusted context is returned for the user that the identity
+// is mapped to. Identities that are not explicitly listed in the ECU
+// configuration are rejected, since any certificate issued by the CA would
+// otherwise be granted full access.
+func (e *ECU) authenticate(ctx *ipc.Context, identity string) (*ipc.Context, error) {
+	if identity != "" {
+		user, ok := e.cfg.ClientIdentities[identity]
+		if !ok {
+			return nil, fmt.Errorf("client identity %q is not authorised", identity)
+		}
+		return ipc.NewTrustedRemoteContext(ctx.Peer, seesaw.SCECU, user), nil
+	}
 	return nil, errors.New("unimplemented")
 }
 
-// authConnect attempts to authenticate the user using the given context. If
-// authentication succeeds an authenticated IPC connection to the Seesaw
-// Engine is returned.
-func (e *ECU) authConnect(ctx *ipc.Context) (*conn.Seesaw, error) {
+// authConnect attempts to authenticate the user using the given context and
+// client identity. If authentication succeeds an authenticated IPC connection
+// to the Seesaw Engine is returned.
+func (e *ECU) authConnect(ctx *ipc.Context, identity string) (*conn.Seesaw, error) {
 	if ctx == nil {
 		return nil, errors.New("context is nil")
 	}
-	authCtx, err := e.authenticate(ctx)
+	authCtx, err := e.authenticate(ctx, identity)
 	if err != nil {
 		return nil, fmt.Errorf("authentication failed: %v", err)
 	}
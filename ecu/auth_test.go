@@ -0,0 +1,177 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecu
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wy2745/seesaw/common/ipc"
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+// testCert is a certificate and key, signed by a test CA.
+type testCert struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, cn string, serial int64, ca *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	parent, signer := tmpl, key
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %v", err)
+	}
+	return &testCert{cert: cert, der: der, key: key}
+}
+
+func (c *testCert) writeFiles(t *testing.T, certFile, keyFile string) {
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if keyFile == "" {
+		return
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey failed: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestAuthenticate(t *testing.T) {
+	e := NewECU(&ECUConfig{
+		ClientIdentities: map[string]string{"cli.example.com": "jsing"},
+	})
+	peer := ipc.NewContext(seesaw.SCLocalCLI)
+
+	ctx, err := e.authenticate(peer, "cli.example.com")
+	if err != nil {
+		t.Fatalf("authenticate failed for listed identity: %v", err)
+	}
+	if ctx.AuthType != ipc.ATTrusted || ctx.User != "jsing" {
+		t.Errorf("authenticate returned context %v, want trusted context for user jsing", ctx)
+	}
+
+	for _, identity := range []string{"other.example.com", ""} {
+		if ctx, err := e.authenticate(peer, identity); err == nil {
+			t.Errorf("authenticate succeeded for identity %q with context %v", identity, ctx)
+		}
+	}
+}
+
+func TestControlUnlistedClientCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecu")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca.example.com", 1, nil)
+	server := newTestCert(t, "seesaw.example.com", 2, ca)
+	client := newTestCert(t, "unlisted.example.com", 3, ca)
+
+	cfg := &ECUConfig{
+		CACertsFile:      filepath.Join(dir, "ca.crt"),
+		ClientIdentities: map[string]string{"cli.example.com": "jsing"},
+		ECUCertFile:      filepath.Join(dir, "seesaw.crt"),
+		ECUKeyFile:       filepath.Join(dir, "seesaw.key"),
+		EngineSocket:     filepath.Join(dir, "engine"),
+	}
+	ca.writeFiles(t, cfg.CACertsFile, "")
+	server.writeFiles(t, cfg.ECUCertFile, cfg.ECUKeyFile)
+
+	e := NewECU(cfg)
+	tlsConfig, err := e.controlTLSConfig()
+	if err != nil {
+		t.Fatalf("controlTLSConfig failed: %v", err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		e.serveControl(conn)
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+		Certificates: []tls.Certificate{client.tlsCertificate()},
+		RootCAs:      roots,
+		ServerName:   "seesaw.example.com",
+	})
+	if err != nil {
+		t.Fatalf("TLS dial failed: %v", err)
+	}
+	rpcClient := rpc.NewClient(conn)
+	defer rpcClient.Close()
+
+	// The certificate is signed by the CA, but its identity is not listed
+	// and must not be granted access to the engine.
+	var cs seesaw.ClusterStatus
+	err = rpcClient.Call("SeesawECU.ClusterStatus", ipc.NewContext(seesaw.SCRemoteCLI), &cs)
+	if err == nil || !strings.Contains(err.Error(), "not authorised") {
+		t.Errorf("ClusterStatus with unlisted client certificate returned %v, want authorisation failure", err)
+	}
+}
@@ -22,6 +22,7 @@ package ecu
 import (
 	"errors"
 
+	"github.com/wy2745/seesaw/common/conn"
 	"github.com/wy2745/seesaw/common/ipc"
 	"github.com/wy2745/seesaw/common/seesaw"
 	"github.com/wy2745/seesaw/quagga"
@@ -31,18 +32,24 @@ import (
 
 // SeesawECU provides the RPC interface to the Seesaw ECU.
 type SeesawECU struct {
-	ecu *ECU
+	ecu      *ECU
+	identity string // Verified client identity, if any.
 }
 
 func (s *SeesawECU) trace(call string, ctx *ipc.Context) {
 	log.V(2).Infof("SeesawECU.%s called by %v", call, ctx)
 }
 
+// authConnect returns an authenticated IPC connection to the Seesaw Engine.
+func (s *SeesawECU) authConnect(ctx *ipc.Context) (*conn.Seesaw, error) {
+	return s.ecu.authConnect(ctx, s.identity)
+}
+
 // Failover requests the Seesaw Engine to relinquish master state.
 func (s *SeesawECU) Failover(ctx *ipc.Context, reply *int) error {
 	s.trace("Failover", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -54,11 +61,78 @@ func (s *SeesawECU) Failover(ctx *ipc.Context, reply *int) error {
 	return nil
 }
 
+// FailoverTo requests a failover that results in the specified node becoming
+// master.
+func (s *SeesawECU) FailoverTo(args *ipc.Failover, reply *int) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("FailoverTo", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	if err := authConn.FailoverTo(args.Node); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Maintenance requests that maintenance mode be enabled or disabled.
+func (s *SeesawECU) Maintenance(args *ipc.Maintenance, reply *seesaw.MaintenanceStatus) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("Maintenance", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	ms, err := authConn.Maintenance(args.Enable)
+	if err != nil {
+		return err
+	}
+
+	if reply != nil {
+		*reply = *ms
+	}
+	return nil
+}
+
+// MaintenanceStatus returns the maintenance status for the Seesaw Node.
+func (s *SeesawECU) MaintenanceStatus(ctx *ipc.Context, reply *seesaw.MaintenanceStatus) error {
+	s.trace("MaintenanceStatus", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	ms, err := authConn.MaintenanceStatus()
+	if err != nil {
+		return err
+	}
+
+	if reply != nil {
+		*reply = *ms
+	}
+	return nil
+}
+
 // ClusterStatus returns status information about this Seesaw Cluster.
 func (s *SeesawECU) ClusterStatus(ctx *ipc.Context, reply *seesaw.ClusterStatus) error {
 	s.trace("ClusterStatus", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -79,7 +153,7 @@ func (s *SeesawECU) ClusterStatus(ctx *ipc.Context, reply *seesaw.ClusterStatus)
 func (s *SeesawECU) HAStatus(ctx *ipc.Context, status *seesaw.HAStatus) error {
 	s.trace("HAStatus", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -100,7 +174,7 @@ func (s *SeesawECU) HAStatus(ctx *ipc.Context, status *seesaw.HAStatus) error {
 func (s *SeesawECU) ConfigStatus(ctx *ipc.Context, reply *seesaw.ConfigStatus) error {
 	s.trace("ConfigStatus", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -117,11 +191,32 @@ func (s *SeesawECU) ConfigStatus(ctx *ipc.Context, reply *seesaw.ConfigStatus) e
 	return nil
 }
 
+// RunningConfig returns the cluster configuration that is currently in use.
+func (s *SeesawECU) RunningConfig(ctx *ipc.Context, reply *seesaw.ClusterConfig) error {
+	s.trace("RunningConfig", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	cluster, err := authConn.RunningConfig()
+	if err != nil {
+		return err
+	}
+
+	if reply != nil {
+		*reply = *cluster
+	}
+	return nil
+}
+
 // ConfigReload requests a configuration reload.
 func (s *SeesawECU) ConfigReload(ctx *ipc.Context, reply *int) error {
 	s.trace("ConfigReload", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -133,6 +228,28 @@ func (s *SeesawECU) ConfigReload(ctx *ipc.Context, reply *int) error {
 	return nil
 }
 
+// ReloadConfig reloads the configuration and returns a summary of the
+// changes.
+func (s *SeesawECU) ReloadConfig(ctx *ipc.Context, reply *seesaw.ClusterChanges) error {
+	s.trace("ReloadConfig", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	changes, err := authConn.ReloadConfig()
+	if err != nil {
+		return err
+	}
+
+	if reply != nil {
+		*reply = *changes
+	}
+	return nil
+}
+
 // ConfigSource requests the configuration source be changed to the specified
 // source. The name of the original source is returned.
 func (s *SeesawECU) ConfigSource(args *ipc.ConfigSource, oldSource *string) error {
@@ -142,7 +259,7 @@ func (s *SeesawECU) ConfigSource(args *ipc.ConfigSource, oldSource *string) erro
 	ctx := args.Ctx
 	s.trace("ConfigSource", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -163,7 +280,7 @@ func (s *SeesawECU) ConfigSource(args *ipc.ConfigSource, oldSource *string) erro
 func (s *SeesawECU) BGPNeighbors(ctx *ipc.Context, reply *quagga.Neighbors) error {
 	s.trace("BGPNeighbors", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -180,11 +297,32 @@ func (s *SeesawECU) BGPNeighbors(ctx *ipc.Context, reply *quagga.Neighbors) erro
 	return nil
 }
 
+// BGPStatus returns the status of the BGP sessions with our peers.
+func (s *SeesawECU) BGPStatus(ctx *ipc.Context, reply *seesaw.BGPStatus) error {
+	s.trace("BGPStatus", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	peers, err := authConn.BGPStatus()
+	if err != nil {
+		return err
+	}
+
+	if reply != nil {
+		reply.Peers = peers
+	}
+	return nil
+}
+
 // VLANs returns a list of currently configured VLANs.
 func (s *SeesawECU) VLANs(ctx *ipc.Context, reply *seesaw.VLANs) error {
 	s.trace("VLANs", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -205,7 +343,7 @@ func (s *SeesawECU) VLANs(ctx *ipc.Context, reply *seesaw.VLANs) error {
 func (s *SeesawECU) Vservers(ctx *ipc.Context, reply *seesaw.VserverMap) error {
 	s.trace("Vservers", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -222,11 +360,61 @@ func (s *SeesawECU) Vservers(ctx *ipc.Context, reply *seesaw.VserverMap) error {
 	return nil
 }
 
+// Connections returns the IPVS connection table entries that match a filter.
+func (s *SeesawECU) Connections(args *ipc.Connections, reply *seesaw.ConnEntries) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("Connections", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	ce, err := authConn.Connections(&args.Filter)
+	if err != nil {
+		return err
+	}
+
+	if reply != nil {
+		*reply = *ce
+	}
+	return nil
+}
+
+// VserverStats returns the statistics for the destinations of a vserver.
+func (s *SeesawECU) VserverStats(args *ipc.VserverStats, reply *seesaw.VserverStats) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("VserverStats", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	stats, err := authConn.VserverStats(args.Vserver)
+	if err != nil {
+		return err
+	}
+
+	if reply != nil {
+		reply.Destinations = stats
+	}
+	return nil
+}
+
 // Backends returns a list of currently configured Backends.
 func (s *SeesawECU) Backends(ctx *ipc.Context, reply *int) error {
 	s.trace("Backends", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -251,7 +439,7 @@ func (s *SeesawECU) OverrideBackend(args *ipc.Override, reply *int) error {
 	ctx := args.Ctx
 	s.trace("OverrideBackend", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -271,7 +459,7 @@ func (s *SeesawECU) OverrideDestination(args *ipc.Override, reply *int) error {
 	ctx := args.Ctx
 	s.trace("OverrideDestination", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -283,6 +471,46 @@ func (s *SeesawECU) OverrideDestination(args *ipc.Override, reply *int) error {
 	return authConn.OverrideDestination(args.Destination)
 }
 
+// DrainBackend requests that the specified backend be drained.
+func (s *SeesawECU) DrainBackend(args *ipc.Override, reply *int) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("DrainBackend", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	if args.Backend == nil {
+		return errors.New("backend override is nil")
+	}
+	return authConn.DrainBackend(args.Backend.Hostname)
+}
+
+// OverrideHealthcheck requests that the specified HealthcheckOverride be applied.
+func (s *SeesawECU) OverrideHealthcheck(args *ipc.Override, reply *int) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("OverrideHealthcheck", ctx)
+
+	authConn, err := s.authConnect(ctx)
+	if err != nil {
+		return err
+	}
+	defer authConn.Close()
+
+	if args.Healthcheck == nil {
+		return errors.New("healthcheck override is nil")
+	}
+	return authConn.OverrideHealthcheck(args.Healthcheck)
+}
+
 // OverrideVserver requests that the specified VserverOverride be applied.
 func (s *SeesawECU) OverrideVserver(args *ipc.Override, reply *int) error {
 	if args == nil {
@@ -291,7 +519,7 @@ func (s *SeesawECU) OverrideVserver(args *ipc.Override, reply *int) error {
 	ctx := args.Ctx
 	s.trace("OverrideVserver", ctx)
 
-	authConn, err := s.ecu.authConnect(ctx)
+	authConn, err := s.authConnect(ctx)
 	if err != nil {
 		return err
 	}
@@ -51,13 +51,14 @@ var defaultConfig = ECUConfig{
 
 // ECUConfig provides configuration details for a Seesaw ECU.
 type ECUConfig struct {
-	CACertsFile    string
-	ControlAddress string
-	ECUCertFile    string
-	ECUKeyFile     string
-	EngineSocket   string
-	MonitorAddress string
-	UpdateInterval time.Duration
+	CACertsFile      string
+	ClientIdentities map[string]string // Trusted client certificate CNs, mapped to users.
+	ControlAddress   string
+	ECUCertFile      string
+	ECUKeyFile       string
+	EngineSocket     string
+	MonitorAddress   string
+	UpdateInterval   time.Duration
 }
 
 // DefaultECUConfig returns the default ECU configuration.
@@ -147,6 +148,8 @@ func (e *ECU) controlTLSConfig() (*tls.Config, error) {
 	// TODO(jsing): Make the server name configurable.
 	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{certs},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    rootCACerts,
 		RootCAs:      rootCACerts,
 		ServerName:   "seesaw.example.com",
 	}
@@ -169,12 +172,30 @@ func (e *ECU) control() {
 		log.Fatal("listen error:", err)
 	}
 
-	seesawRPC := rpc.NewServer()
-	seesawRPC.Register(&SeesawECU{e})
 	tlsListener := tls.NewListener(ln, tlsConfig)
-	go server.RPCAccept(tlsListener, seesawRPC)
+	go server.Accept(tlsListener, e.serveControl)
 
 	<-e.shutdownControl
 	ln.Close()
 	e.shutdownControl <- true
 }
+
+// serveControl services control RPCs on the given connection. If the client
+// presented a verified TLS certificate, the identity from the certificate is
+// made available for authentication of RPCs received on the connection.
+func (e *ECU) serveControl(conn net.Conn) {
+	var identity string
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			log.Warningf("TLS handshake with %v failed: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
+		if certs := tlsConn.ConnectionState().PeerCertificates; len(certs) > 0 {
+			identity = certs[0].Subject.CommonName
+		}
+	}
+	seesawRPC := rpc.NewServer()
+	seesawRPC.Register(&SeesawECU{ecu: e, identity: identity})
+	seesawRPC.ServeConn(conn)
+}
@@ -20,6 +20,7 @@ package ecu
 // that will be exported.
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -112,7 +113,11 @@ func (e *ecuStats) update() error {
 	}
 	defer seesawConn.Close()
 
-	clusterStatus, err := seesawConn.ClusterStatus()
+	// Bound the RPCs, so that a wedged engine does not stall the updates.
+	callCtx, cancel := context.WithTimeout(context.Background(), e.ecu.cfg.UpdateInterval)
+	defer cancel()
+
+	clusterStatus, err := seesawConn.ClusterStatusContext(callCtx)
 	if err != nil {
 		return fmt.Errorf("Failed to get cluster status: %v", err)
 	}
@@ -120,7 +125,7 @@ func (e *ecuStats) update() error {
 	e.stats.ClusterStatus = *clusterStatus
 	e.stats.lock.Unlock()
 
-	configStatus, err := seesawConn.ConfigStatus()
+	configStatus, err := seesawConn.ConfigStatusContext(callCtx)
 	if err != nil {
 		return fmt.Errorf("Failed to get config status: %v", err)
 	}
@@ -128,7 +133,7 @@ func (e *ecuStats) update() error {
 	e.stats.ConfigStatus = *configStatus
 	e.stats.lock.Unlock()
 
-	ha, err := seesawConn.HAStatus()
+	ha, err := seesawConn.HAStatusContext(callCtx)
 	if err != nil {
 		return fmt.Errorf("Failed to get HA status: %v", err)
 	}
@@ -136,7 +141,11 @@ func (e *ecuStats) update() error {
 	e.stats.HAStatus = *ha
 	e.stats.lock.Unlock()
 
-	neighbors, err := seesawConn.BGPNeighbors()
+	// The remaining RPCs have no context variants, so are bounded via a copy
+	// of the connection.
+	bounded := seesawConn.WithContext(callCtx)
+
+	neighbors, err := bounded.BGPNeighbors()
 	if err != nil {
 		return fmt.Errorf("Failed to get BGP neighbors: %v", err)
 	}
@@ -144,7 +153,7 @@ func (e *ecuStats) update() error {
 	e.stats.neighbors = neighbors
 	e.stats.lock.Unlock()
 
-	vlans, err := seesawConn.VLANs()
+	vlans, err := bounded.VLANs()
 	if err != nil {
 		return fmt.Errorf("Failed to get VLANs: %v", err)
 	}
@@ -152,7 +161,7 @@ func (e *ecuStats) update() error {
 	e.stats.vlans = vlans.VLANs
 	e.stats.lock.Unlock()
 
-	vservers, err := seesawConn.Vservers()
+	vservers, err := bounded.Vservers()
 	if err != nil {
 		return fmt.Errorf("Failed to get vservers: %v", err)
 	}
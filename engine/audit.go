@@ -0,0 +1,138 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+// This file contains functions for recording an audit log of the state
+// changes made by the engine and the users who requested them.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/wy2745/seesaw/common/ipc"
+	"github.com/wy2745/seesaw/common/seesaw"
+
+	log "github.com/golang/glog"
+)
+
+// auditSyslog is the audit log destination that sends events to syslog.
+const auditSyslog = "syslog"
+
+// auditActorEngine is the actor that is recorded for state changes that are
+// initiated by the engine itself.
+const auditActorEngine = "engine"
+
+// Audit event types.
+const (
+	auditBackendHealth = "backend_health"
+	auditConfigReload  = "config_reload"
+	auditConfigSource  = "config_source"
+	auditConfigUpdate  = "config_update"
+	auditFailover      = "failover"
+	auditHAState       = "ha_state"
+	auditMaintenance   = "maintenance"
+	auditOverride      = "override"
+	auditVIPAdded      = "vip_added"
+	auditVIPRemoved    = "vip_removed"
+)
+
+// auditEvent is a structured record of an engine state change.
+type auditEvent struct {
+	Time   time.Time   `json:"time"`
+	Event  string      `json:"event"`
+	Actor  string      `json:"actor"`
+	Target string      `json:"target,omitempty"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// auditLog writes audit events as lines of JSON.
+type auditLog struct {
+	w    io.WriteCloser
+	lock sync.Mutex
+}
+
+// newAuditLog returns an audit log that appends to the given file, or that
+// sends events to syslog if the destination is "syslog".
+func newAuditLog(dest string) (*auditLog, error) {
+	if dest == auditSyslog {
+		w, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_DAEMON, "seesaw_engine")
+		if err != nil {
+			return nil, err
+		}
+		return &auditLog{w: w}, nil
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{w: f}, nil
+}
+
+// record writes an audit event. Events recorded on a nil audit log are
+// discarded.
+func (a *auditLog) record(actor, event, target string, before, after interface{}) {
+	if a == nil {
+		return
+	}
+	b, err := json.Marshal(&auditEvent{
+		Time:   time.Now(),
+		Event:  event,
+		Actor:  actor,
+		Target: target,
+		Before: before,
+		After:  after,
+	})
+	if err != nil {
+		log.Errorf("Failed to encode %s audit event: %v", event, err)
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if _, err := a.w.Write(append(b, '\n')); err != nil {
+		log.Errorf("Failed to write %s audit event: %v", event, err)
+	}
+}
+
+// close closes the audit log.
+func (a *auditLog) close() error {
+	if a == nil {
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.w.Close()
+}
+
+// auditActor returns the actor to be recorded for an IPC call with the given
+// context. The actor is the user established by the peer credentials of the
+// connection, since the user in the context is supplied by the client. If the
+// caller cannot be identified, the unverified peer identity is recorded.
+func (s *SeesawEngine) auditActor(ctx *ipc.Context) string {
+	if user := s.ipcUser(ctx); user != "" {
+		return user
+	}
+	return fmt.Sprintf("%s (unverified)", ctx.Peer.Identity)
+}
+
+// auditOverride records an override that has been requested via IPC.
+func (e *Engine) auditOverride(actor string, o seesaw.Override) {
+	e.audit.record(actor, auditOverride, o.Target(), nil, o.State().String())
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wy2745/seesaw/common/ipc"
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+func readAuditEvents(t *testing.T, file string) []map[string]interface{} {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer f.Close()
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Failed to decode audit event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "audit.log")
+
+	// Events recorded on a nil audit log are discarded.
+	var nilLog *auditLog
+	nilLog.record(auditActorEngine, auditHAState, "", "backup", "master")
+
+	a, err := newAuditLog(file)
+	if err != nil {
+		t.Fatalf("newAuditLog failed: %v", err)
+	}
+	e := newTestEngine()
+	e.audit = a
+	e.audit.record(auditActorEngine, auditHAState, "", "backup", "master")
+	e.auditOverride("alice", &seesaw.BackendOverride{Hostname: "dns1-1.example.com", OverrideState: seesaw.OverrideDrain})
+	if err := a.close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	events := readAuditEvents(t, file)
+	if len(events) != 2 {
+		t.Fatalf("Got %d audit events, want 2", len(events))
+	}
+	for i, want := range []map[string]interface{}{
+		{"event": auditHAState, "actor": auditActorEngine, "before": "backup", "after": "master"},
+		{"event": auditOverride, "actor": "alice", "target": "dns1-1.example.com", "after": seesaw.OverrideDrain.String()},
+	} {
+		if _, ok := events[i]["time"]; !ok {
+			t.Errorf("Audit event %d has no time", i)
+		}
+		for k, v := range want {
+			if events[i][k] != v {
+				t.Errorf("Audit event %d has %s %v, want %v", i, k, events[i][k], v)
+			}
+		}
+	}
+}
+
+func TestAuditActor(t *testing.T) {
+	engine := newTestEngine()
+	user := uint32(os.Getuid()) + 1
+	forged := ipc.NewTrustedContext(seesaw.SCLocalCLI)
+	forged.User = "alice"
+	remote := ipc.NewTrustedRemoteContext(ipc.Peer{Component: seesaw.SCRemoteCLI}, seesaw.SCECU, "alice")
+	unknown := ipc.NewContext(seesaw.SCRemoteCLI)
+
+	tests := []struct {
+		desc string
+		peer *ipcPeer
+		ctx  *ipc.Context
+		want string
+	}{
+		{"local user", &ipcPeer{uid: user, user: "bob"}, ipc.NewTrustedContext(seesaw.SCLocalCLI), "bob"},
+		{"forged user", &ipcPeer{uid: user, user: "bob"}, forged, "bob"},
+		{"unprivileged proxy", &ipcPeer{uid: user, user: "bob"}, remote, "bob"},
+		{"ECU proxied user", &ipcPeer{uid: 0, user: "root"}, remote, "alice"},
+		{"unknown peer", nil, unknown, unknown.Peer.Identity + " (unverified)"},
+	}
+	for _, test := range tests {
+		s := &SeesawEngine{engine: engine, peer: test.peer}
+		if got := s.auditActor(test.ctx); got != test.want {
+			t.Errorf("Test %q: auditActor() = %q, want %q", test.desc, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+// This file contains types and functions that implement authorisation for
+// IPC calls to the Seesaw Engine.
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/wy2745/seesaw/common/ipc"
+	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/common/server"
+
+	log "github.com/golang/glog"
+)
+
+// changeCalls are the IPC calls that change the state of the engine, which
+// require authorisation. All other calls only require a trusted context.
+var changeCalls = map[string]bool{
+	"ConfigReload":        true,
+	"DrainBackend":        true,
+	"Failover":            true,
+	"FailoverTo":          true,
+	"HAGroupState":        true,
+	"HAState":             true,
+	"HAUpdate":            true,
+	"HealthState":         true,
+	"Maintenance":         true,
+	"OverrideBackend":     true,
+	"OverrideDestination": true,
+	"OverrideHealthcheck": true,
+	"OverrideVserver":     true,
+	"ReloadConfig":        true,
+}
+
+// ipcPeer identifies the process at the other end of an IPC connection, based
+// on the credentials of its Unix domain socket.
+type ipcPeer struct {
+	pid  int32
+	uid  uint32
+	user string
+}
+
+// newIPCPeer returns the ipcPeer for the given IPC connection.
+func newIPCPeer(conn net.Conn) (*ipcPeer, error) {
+	cred, err := server.PeerCredentials(conn)
+	if err != nil {
+		return nil, err
+	}
+	uid := strconv.FormatUint(uint64(cred.Uid), 10)
+	peer := &ipcPeer{pid: cred.Pid, uid: cred.Uid, user: uid}
+	if u, err := user.LookupId(uid); err == nil {
+		peer.user = u.Username
+	}
+	return peer, nil
+}
+
+// privileged returns whether the peer is running as root or as the same user
+// as the engine, which is the case for the other Seesaw components.
+func (p *ipcPeer) privileged() bool {
+	return p.uid == 0 || p.uid == uint32(os.Getuid())
+}
+
+// serveIPC serves IPC calls on the given connection, authorising them based
+// on the credentials of the connecting process.
+func (e *Engine) serveIPC(conn net.Conn) {
+	peer, err := newIPCPeer(conn)
+	if err != nil {
+		log.Warningf("Failed to get IPC peer credentials: %v", err)
+	}
+	seesawIPC := rpc.NewServer()
+	seesawIPC.Register(&SeesawEngine{engine: e, peer: peer})
+	seesawIPC.ServeConn(conn)
+}
+
+// ipcUser returns the user on whose behalf an IPC call is being made. This is
+// the user of the connecting process, unless the call has been proxied by the
+// ECU over a privileged connection, in which case it is the remote user from
+// the context. An empty string is returned if the caller is unknown.
+func (s *SeesawEngine) ipcUser(ctx *ipc.Context) string {
+	if s.peer == nil {
+		return ""
+	}
+	if s.peer.privileged() && ctx.Proxy.Component == seesaw.SCECU {
+		return ctx.User
+	}
+	return s.peer.user
+}
+
+// authorise returns an error if the named IPC call is not permitted for the
+// given context, or if the caller has already abandoned the call.
+func (s *SeesawEngine) authorise(call string, ctx *ipc.Context) error {
+	if !ctx.IsTrusted() {
+		return errors.New("insufficient access")
+	}
+	if ctx.Expired() {
+		return fmt.Errorf("%s: deadline exceeded", call)
+	}
+	if !changeCalls[call] {
+		return nil
+	}
+	return s.authoriseChange(call, ctx)
+}
+
+// authoriseChange returns an error if the caller is not authorised to make
+// the named IPC call, which changes the state of the engine. The user is
+// determined from the credentials of the connecting process, rather than
+// from the context, unless the call has been proxied by the ECU on behalf of
+// a remote user whose identity it has verified via a TLS client certificate.
+// Seesaw components are always authorised.
+func (s *SeesawEngine) authoriseChange(call string, ctx *ipc.Context) error {
+	auth := s.engine.config.IPCAuthorization
+	if auth == nil {
+		return nil
+	}
+	if s.peer == nil {
+		return fmt.Errorf("permission denied: unable to identify caller for %s", call)
+	}
+	if s.peer.privileged() && ctx.Proxy.Component != seesaw.SCECU {
+		return nil
+	}
+	user := s.ipcUser(ctx)
+	if !auth.Authorised(call, user) {
+		log.Warningf("SeesawEngine.%s denied for user %q (%v)", call, user, ctx)
+		return fmt.Errorf("permission denied: user %q is not authorised for %s", user, call)
+	}
+	return nil
+}
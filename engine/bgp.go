@@ -23,6 +23,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/wy2745/seesaw/common/seesaw"
 	"github.com/wy2745/seesaw/quagga"
 
 	log "github.com/golang/glog"
@@ -45,13 +46,59 @@ func newBGPManager(engine *Engine, interval time.Duration) *bgpManager {
 // run runs the BGP configuration manager.
 func (b *bgpManager) run() {
 	ticker := time.NewTicker(b.updateInterval)
+	peersConfigured := false
 	for {
+		if !peersConfigured {
+			peersConfigured = b.configurePeers()
+		}
 		log.V(1).Infof("Updating BGP state and statistics...")
 		b.update()
 		<-ticker.C
 	}
 }
 
+// peerStatus returns the status of the BGP sessions with our peers.
+func (b *bgpManager) peerStatus() []seesaw.BGPPeerStatus {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	peers := make([]seesaw.BGPPeerStatus, 0, len(b.neighbors))
+	for _, n := range b.neighbors {
+		peers = append(peers, seesaw.BGPPeerStatus{
+			IP:                 n.IP,
+			ASN:                n.ASN,
+			Description:        n.Description,
+			State:              n.BGPState.String(),
+			Uptime:             n.Uptime,
+			PrefixesAdvertised: n.PrefixesAdvertised,
+			PrefixesReceived:   n.PrefixesReceived,
+			BFDStatus:          n.BFDStatus,
+		})
+	}
+	return peers
+}
+
+// configurePeers configures the BGP peers from the engine configuration in
+// the BGP daemon, returning true on success.
+func (b *bgpManager) configurePeers() bool {
+	peers := b.engine.config.BGPPeers
+	if len(peers) == 0 {
+		return true
+	}
+	ncc := b.engine.ncc
+	if err := ncc.Dial(); err != nil {
+		log.Warningf("BGP manager failed to connect to NCC: %v", err)
+		return false
+	}
+	defer ncc.Close()
+
+	log.Infof("Configuring %d BGP peers", len(peers))
+	if err := ncc.BGPConfigurePeers(peers); err != nil {
+		log.Warningf("Failed to configure BGP peers: %v", err)
+		return false
+	}
+	return true
+}
+
 // update updates BGP related state and statistics from the BGP daemon.
 func (b *bgpManager) update() {
 	ncc := b.engine.ncc
@@ -0,0 +1,84 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// This file contains functions for summarising the differences between two
+// cluster configurations.
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+// DiffClusters returns a summary of the changes needed to move from one
+// cluster configuration to another.
+func DiffClusters(from, to *Cluster) *seesaw.ClusterChanges {
+	changes := &seesaw.ClusterChanges{}
+	if from == nil {
+		from = &Cluster{}
+	}
+	if to == nil {
+		to = &Cluster{}
+	}
+
+	for name, nv := range to.Vservers {
+		ov, ok := from.Vservers[name]
+		if !ok {
+			changes.VserversAdded = append(changes.VserversAdded, name)
+			continue
+		}
+		if reflect.DeepEqual(ov, nv) {
+			continue
+		}
+		changes.VserversUpdated = append(changes.VserversUpdated, name)
+		for key, nb := range nv.Backends {
+			ob, ok := ov.Backends[key]
+			switch {
+			case !ok:
+				changes.BackendsAdded = append(changes.BackendsAdded, name+"/"+key)
+			case ob.Weight != nb.Weight:
+				changes.WeightsChanged = append(changes.WeightsChanged,
+					fmt.Sprintf("%s/%s (%d -> %d)", name, key, ob.Weight, nb.Weight))
+			}
+		}
+		for key := range ov.Backends {
+			if _, ok := nv.Backends[key]; !ok {
+				changes.BackendsRemoved = append(changes.BackendsRemoved, name+"/"+key)
+			}
+		}
+	}
+	for name := range from.Vservers {
+		if _, ok := to.Vservers[name]; !ok {
+			changes.VserversRemoved = append(changes.VserversRemoved, name)
+		}
+	}
+
+	// Compare the remainder of the configuration, ignoring the status.
+	oc, nc := *from, *to
+	oc.Vservers, nc.Vservers = nil, nil
+	oc.Status = nc.Status
+	changes.Other = !reflect.DeepEqual(oc, nc)
+
+	for _, s := range [][]string{
+		changes.VserversAdded, changes.VserversRemoved, changes.VserversUpdated,
+		changes.BackendsAdded, changes.BackendsRemoved, changes.WeightsChanged,
+	} {
+		sort.Strings(s)
+	}
+	return changes
+}
@@ -19,6 +19,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -49,6 +50,7 @@ const (
 	SourceDisk
 	SourcePeer
 	SourceServer
+	SourceURL
 )
 
 var sourceNames = map[Source]string{
@@ -56,6 +58,7 @@ var sourceNames = map[Source]string{
 	SourceDisk:   "disk",
 	SourcePeer:   "peer",
 	SourceServer: "server",
+	SourceURL:    "url",
 }
 
 // SourceByName returns the source that has the given name.
@@ -76,6 +79,55 @@ func (s Source) String() string {
 	return "(unknown)"
 }
 
+// Format specifies the format of a cluster configuration file.
+type Format int
+
+const (
+	// FormatAuto selects the format based on the file extension, with
+	// ".yaml" and ".yml" files being YAML and all others being text.
+	FormatAuto Format = iota
+	// FormatText is a text-based protobuf.
+	FormatText
+	// FormatYAML is YAML, with the same structure as the protobuf.
+	FormatYAML
+)
+
+var formatNames = map[Format]string{
+	FormatAuto: "auto",
+	FormatText: "text",
+	FormatYAML: "yaml",
+}
+
+// FormatByName returns the format that has the given name.
+func FormatByName(name string) (Format, error) {
+	for f, n := range formatNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return -1, fmt.Errorf("unknown config format %q", name)
+}
+
+// String returns the string representation of a format.
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return "(unknown)"
+}
+
+// fileFormat returns the format of the given file, if the format is FormatAuto.
+func fileFormat(filename string, format Format) Format {
+	if format != FormatAuto {
+		return format
+	}
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatText
+}
+
 // Notification represents a configuration change notification.
 type Notification struct {
 	Cluster      *Cluster
@@ -90,17 +142,35 @@ func (n *Notification) String() string {
 	return fmt.Sprintf("config from %v (%v) at %v", n.Source, n.SourceDetail, n.Time)
 }
 
-// ReadConfig reads a cluster configuration file.
-func ReadConfig(filename, clusterName string) (*Notification, error) {
+// Text returns the cluster configuration from the notification as a protocol
+// buffer in text format.
+func (n *Notification) Text() string {
+	return proto.MarshalTextString(n.protobuf)
+}
+
+// ParseConfig parses a cluster configuration from a protocol buffer in text
+// format, as returned by Notification.Text.
+func ParseConfig(text, clusterName string) (*Cluster, error) {
 	p := &pb.Cluster{}
-	b, err := ioutil.ReadFile(filename)
-	if err != nil {
+	if err := proto.UnmarshalText(text, p); err != nil {
 		return nil, err
 	}
-	if err = proto.UnmarshalText(string(b), p); err != nil {
+	return protoToCluster(p, clusterName)
+}
+
+// ReadConfig reads a cluster configuration file, expanding any include
+// directives and environment variable references. The format of the file is
+// selected by its extension.
+func ReadConfig(filename, clusterName string) (*Notification, error) {
+	return ReadConfigFormat(filename, clusterName, FormatAuto)
+}
+
+// ReadConfigFormat reads a cluster configuration file in the given format.
+func ReadConfigFormat(filename, clusterName string, format Format) (*Notification, error) {
+	p, err := readProto(filename, format)
+	if err != nil {
 		return nil, err
 	}
-
 	c, err := protoToCluster(p, clusterName)
 	if err != nil {
 		return nil, err
@@ -116,16 +186,52 @@ func ConfigFromServer(cluster string) (*Notification, error) {
 	return n.configFromServer()
 }
 
-func saveConfig(p *pb.Cluster, file string, backup bool) error {
+// readProto reads a cluster configuration file in the given format and
+// returns the resulting protobuf.
+func readProto(filename string, format Format) (*pb.Cluster, error) {
+	text, err := expandConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := &pb.Cluster{}
+	switch fileFormat(filename, format) {
+	case FormatYAML:
+		err = yamlToProto(text, p)
+	default:
+		err = proto.UnmarshalText(text, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ConvertToYAML reads a cluster configuration file in the given format and
+// returns it in YAML format.
+func ConvertToYAML(filename string, format Format) ([]byte, error) {
+	p, err := readProto(filename, format)
+	if err != nil {
+		return nil, err
+	}
+	return protoToYAML(p)
+}
+
+func saveConfig(p *pb.Cluster, file string, format Format, backup bool) error {
 	if backup {
 		if err := backupConfig(file); err != nil {
 			log.Warningf("Failed to back up existing file %s: %v", file, err)
 		}
 	}
 	tmpFile := fmt.Sprintf("%s.%d", file, os.Getpid())
-	content := proto.MarshalTextString(p)
+	content := []byte(proto.MarshalTextString(p))
+	if fileFormat(file, format) == FormatYAML {
+		var err error
+		if content, err = protoToYAML(p); err != nil {
+			return fmt.Errorf("saveConfig(%q): %v", file, err)
+		}
+	}
 	defer os.Remove(tmpFile)
-	if err := ioutil.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+	if err := ioutil.WriteFile(tmpFile, content, 0644); err != nil {
 		return fmt.Errorf("saveConfig(%q): Write to %q failed: %v", file, tmpFile, err)
 	}
 	if err := os.Rename(tmpFile, file); err != nil {
@@ -172,6 +278,9 @@ func backupConfig(file string) error {
 }
 
 func protoToCluster(p *pb.Cluster, clusterName string) (*Cluster, error) {
+	if p.SeesawVip == nil {
+		return nil, errors.New("no seesaw_vip specified")
+	}
 	c := NewCluster(clusterName)
 	c.VIP = protoToHost(p.SeesawVip)
 	c.BGPLocalASN = uint32(p.GetBgpLocalAsn())
@@ -183,11 +292,134 @@ func protoToCluster(p *pb.Cluster, clusterName string) (*Cluster, error) {
 	addVIPSubnets(c, p)
 	addVLANs(c, p)
 	addVservers(c, p)
+	checkForwardingModes(c)
 	addWarnings(c, p)
 
+	if err := checkServiceConflicts(c); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
+// configError logs an error in the cluster configuration, which usually
+// results in part of the configuration being ignored, and records it in the
+// cluster status.
+func configError(c *Cluster, format string, a ...interface{}) {
+	err := fmt.Sprintf(format, a...)
+	log.Error(err)
+	c.Status.Errors = append(c.Status.Errors, err)
+}
+
+// checkForwardingModes adds an error to the cluster status for any backends
+// that cannot be reached using the forwarding mode of their vserver's entries.
+// DSR forwards packets by rewriting the destination MAC address, hence the
+// backends must be on a network that is directly attached to the Seesaw nodes.
+// NAT and tunnelled services do not have this restriction. The backends are
+// not removed, since the attached networks are derived from the cluster
+// configuration and may not be complete. If no networks are known for an
+// address family, backends in that address family are not checked.
+func checkForwardingModes(c *Cluster) {
+	var nets []*net.IPNet
+	known := make(map[seesaw.AF]bool)
+	addNet := func(n *net.IPNet) {
+		if n == nil || n.IP == nil || n.Mask == nil {
+			return
+		}
+		nets = append(nets, n)
+		if n.IP.To4() != nil {
+			known[seesaw.IPv4] = true
+		} else {
+			known[seesaw.IPv6] = true
+		}
+	}
+	addNets := func(h seesaw.Host) {
+		addNet(h.IPv4Net())
+		addNet(h.IPv6Net())
+	}
+	addNets(c.VIP)
+	for _, n := range c.Nodes {
+		addNets(n.Host)
+	}
+	for _, v := range c.VLANs {
+		addNets(v.Host)
+	}
+	for _, n := range c.VIPSubnets {
+		addNet(n)
+	}
+	attached := func(ip net.IP) bool {
+		af := seesaw.IPv6
+		if ip.To4() != nil {
+			af = seesaw.IPv4
+		}
+		if !known[af] {
+			return true
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for name, v := range c.Vservers {
+		for _, err := range checkDSRBackends(v, attached) {
+			configError(c, "%s: %v", name, err)
+		}
+	}
+}
+
+// checkDSRBackends returns an error for each backend that is not on a directly
+// attached network, if the vserver has DSR entries.
+func checkDSRBackends(v *Vserver, attached func(net.IP) bool) []error {
+	dsr := false
+	for _, e := range v.Entries {
+		if e.Mode == seesaw.LBModeDSR {
+			dsr = true
+			break
+		}
+	}
+	if !dsr {
+		return nil
+	}
+	var errs []error
+	for _, b := range v.Backends {
+		for _, ip := range []net.IP{b.IPv4Addr, b.IPv6Addr} {
+			if ip != nil && !attached(ip) {
+				errs = append(errs, fmt.Errorf("DSR backend %s (%v) is not on a directly attached network; use NAT or TUN mode", b.Hostname, ip))
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// checkServiceConflicts returns an error if more than one vserver in the
+// cluster provides the same service (that is, the same VIP, protocol and
+// port), since only one of them could be configured in IPVS.
+func checkServiceConflicts(c *Cluster) error {
+	names := make([]string, 0, len(c.Vservers))
+	for name := range c.Vservers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	owners := make(map[string]string)
+	for _, name := range names {
+		v := c.Vservers[name]
+		for _, ip := range v.Addresses() {
+			for _, e := range v.Entries {
+				svc := fmt.Sprintf("%v %v/%d", ip, e.Proto, e.Port)
+				if owner, ok := owners[svc]; ok {
+					return fmt.Errorf("vservers %q and %q both provide service %s", owner, name, svc)
+				}
+				owners[svc] = name
+			}
+		}
+	}
+	return nil
+}
+
 func protosToHealthchecks(pbs []*pb.Healthcheck, defaultPort uint16) []*Healthcheck {
 	var checks Healthchecks
 	checks = make([]*Healthcheck, 0, len(pbs))
@@ -243,6 +475,22 @@ func protoToHealthcheck(p *pb.Healthcheck, defaultPort uint16) *Healthcheck {
 		hcType = seesaw.HCTypeTCPTLS
 	case pb.Healthcheck_RADIUS:
 		hcType = seesaw.HCTypeRADIUS
+	case pb.Healthcheck_GRPC:
+		hcType = seesaw.HCTypeGRPC
+	case pb.Healthcheck_GRPC_TLS:
+		hcType = seesaw.HCTypeGRPCTLS
+	case pb.Healthcheck_EXEC:
+		hcType = seesaw.HCTypeExec
+	case pb.Healthcheck_SMTP:
+		hcType = seesaw.HCTypeSMTP
+	case pb.Healthcheck_SMTP_STARTTLS:
+		hcType = seesaw.HCTypeSMTPStartTLS
+	case pb.Healthcheck_REDIS:
+		hcType = seesaw.HCTypeRedis
+	case pb.Healthcheck_MYSQL:
+		hcType = seesaw.HCTypeMySQL
+	case pb.Healthcheck_NTP:
+		hcType = seesaw.HCTypeNTP
 	}
 	port := uint16(p.GetPort())
 	if port == 0 {
@@ -258,6 +506,12 @@ func protoToHealthcheck(p *pb.Healthcheck, defaultPort uint16) *Healthcheck {
 	hc.Proxy = p.GetProxy()
 	hc.Method = p.GetMethod()
 	hc.TLSVerify = p.GetTlsVerify()
+	hc.SlowThreshold = time.Duration(p.GetSlowThresholdMs()) * time.Millisecond
+	hc.ExpectWritable = p.GetExpectWritable()
+	hc.SourceIP = p.GetSourceIp()
+	hc.SourceInterface = p.GetSourceInterface()
+	hc.Body = p.GetBody()
+	hc.ConnectTimeout = time.Duration(p.GetConnectTimeout()) * time.Second
 	return hc
 }
 
@@ -276,7 +530,9 @@ func protoToHost(p *pb.Host) seesaw.Host {
 func addBGPPeers(c *Cluster, p *pb.Cluster) {
 	for _, p := range p.BgpPeer {
 		peer := protoToHost(p)
-		c.AddBGPPeer(&peer)
+		if err := c.AddBGPPeer(&peer); err != nil {
+			configError(c, "%v", err)
+		}
 	}
 }
 
@@ -315,7 +571,9 @@ func addNodes(c *Cluster, p *pb.Cluster) {
 			node.BGPEnabled = true
 			node.VserversEnabled = true
 		}
-		c.AddNode(node)
+		if err := c.AddNode(node); err != nil {
+			configError(c, "%v", err)
+		}
 	}
 
 	// Prioritise nodes by their IPv4 addresses.
@@ -339,12 +597,15 @@ func addNodes(c *Cluster, p *pb.Cluster) {
 func addVLANs(c *Cluster, p *pb.Cluster) {
 	for _, v := range p.Vlan {
 		h := protoToHost(v.Host)
-		c.AddVLAN(&seesaw.VLAN{
+		vlan := &seesaw.VLAN{
 			ID:           uint16(*v.VlanId),
 			Host:         h,
 			BackendCount: make(map[seesaw.AF]uint),
 			VIPCount:     make(map[seesaw.AF]uint),
-		})
+		}
+		if err := c.AddVLAN(vlan); err != nil {
+			configError(c, "%v", err)
+		}
 	}
 
 	// Determine number of backend and VIP addresses in each VLAN.
@@ -392,11 +653,11 @@ func addVIPSubnets(c *Cluster, p *pb.Cluster) {
 	for _, cidr := range p.DedicatedVipSubnet {
 		_, vipSubnet, err := net.ParseCIDR(cidr)
 		if err != nil {
-			log.Errorf("%v: Unable to parse VIP subnet %v: %v", c.Site, cidr, err)
+			configError(c, "%v: Unable to parse VIP subnet %v: %v", c.Site, cidr, err)
 			continue
 		}
 		if err := c.AddVIPSubnet(vipSubnet); err != nil {
-			log.Errorf("%v: Unable to add VIP subnet %v: %v", c.Site, cidr, err)
+			configError(c, "%v: Unable to add VIP subnet %v: %v", c.Site, cidr, err)
 		}
 	}
 }
@@ -407,12 +668,28 @@ func addVservers(c *Cluster, p *pb.Cluster) {
 		v := NewVserver(vs.GetName(), protoToHost(host))
 		v.Enabled = host.GetStatus() == pb.Host_PRODUCTION || host.GetStatus() == pb.Host_TESTING
 		v.UseFWM = vs.GetUseFwm()
+		v.SlowStartDuration = time.Duration(vs.GetSlowStartDuration()) * time.Second
+		v.DrainTimeout = time.Duration(vs.GetDrainTimeout()) * time.Second
+		v.Quiescent = vs.GetQuiescent()
 		v.Warnings = vs.GetWarning()
 		sort.Strings(v.Warnings)
 
-		for _, ip := range []net.IP{v.Host.IPv4Addr, v.Host.IPv6Addr} {
-			if ip != nil {
-				v.AddVIP(seesaw.NewVIP(ip, c.VIPSubnets))
+		for _, vip := range vs.GetAdditionalVip() {
+			ip, _ := parseCIDR(vip)
+			if ip == nil {
+				configError(c, "%v: invalid additional VIP %q", vs.GetName(), vip)
+				continue
+			}
+			if err := v.AddAdditionalVIP(ip); err != nil {
+				configError(c, "%v", err)
+			}
+		}
+		for _, ip := range v.Addresses() {
+			v.AddVIP(seesaw.NewVIP(ip, c.VIPSubnets))
+		}
+		for _, community := range vs.GetBgpCommunity() {
+			if err := v.AddBGPCommunity(community); err != nil {
+				configError(c, "%v", err)
 			}
 		}
 
@@ -425,7 +702,7 @@ func addVservers(c *Cluster, p *pb.Cluster) {
 				proto = seesaw.IPProtoUDP
 			default:
 				// TODO(angusc): Consider this VServer broken.
-				log.Errorf("%v: Unsupported IP protocol %v", vs.GetName(), ve.GetProtocol())
+				configError(c, "%v: Unsupported IP protocol %v", vs.GetName(), ve.GetProtocol())
 				continue
 			}
 			e := NewVserverEntry(uint16(ve.GetPort()), proto)
@@ -442,9 +719,11 @@ func addVservers(c *Cluster, p *pb.Cluster) {
 				scheduler = seesaw.LBSchedulerWLC
 			case pb.VserverEntry_SH:
 				scheduler = seesaw.LBSchedulerSH
+			case pb.VserverEntry_MH:
+				scheduler = seesaw.LBSchedulerMH
 			default:
 				// TODO(angusc): Consider this VServer broken.
-				log.Errorf("%v: Unsupported scheduler %v", vs.GetName(), ve.GetScheduler())
+				configError(c, "%v: Unsupported scheduler %v", vs.GetName(), ve.GetScheduler())
 				continue
 			}
 			e.Scheduler = scheduler
@@ -455,14 +734,24 @@ func addVservers(c *Cluster, p *pb.Cluster) {
 				mode = seesaw.LBModeDSR
 			case pb.VserverEntry_NAT:
 				mode = seesaw.LBModeNAT
+			case pb.VserverEntry_TUN:
+				mode = seesaw.LBModeTUN
 			default:
 				// TODO(angusc): Consider this VServer broken.
-				log.Errorf("%v: Unsupported mode %v", vs.GetName(), ve.GetMode())
+				configError(c, "%v: Unsupported mode %v", vs.GetName(), ve.GetMode())
 				continue
 			}
 			e.Mode = mode
 
 			e.Persistence = int(ve.GetPersistence())
+			if err := setPersistencePrefixes(e, ve); err != nil {
+				configError(c, "%v: %v", vs.GetName(), err)
+				continue
+			}
+			if ve.GetOnePacket() && e.Proto != seesaw.IPProtoUDP {
+				configError(c, "%v: One packet scheduling is only valid for UDP services, not %v", vs.GetName(), e.Proto)
+				continue
+			}
 			e.OnePacket = ve.GetOnePacket()
 			e.HighWatermark = ve.GetServerHighWatermark()
 			e.LowWatermark = ve.GetServerLowWatermark()
@@ -471,38 +760,96 @@ func addVservers(c *Cluster, p *pb.Cluster) {
 			}
 			e.LThreshold = int(ve.GetLthreshold())
 			e.UThreshold = int(ve.GetUthreshold())
+			if err := setSchedulerFlags(e, ve); err != nil {
+				configError(c, "%v: %v", vs.GetName(), err)
+				continue
+			}
 			for _, hc := range protosToHealthchecks(ve.Healthcheck, e.Port) {
 				if err := e.AddHealthcheck(hc); err != nil {
-					log.Warning(err)
+					configError(c, "%v", err)
 				}
 			}
 			if err := v.AddVserverEntry(e); err != nil {
-				log.Warning(err)
+				configError(c, "%v", err)
 			}
 		}
 		for _, backend := range vs.Backend {
-			status := backend.GetHost().GetStatus()
-			b := &seesaw.Backend{
-				Host:      protoToHost(backend.GetHost()),
-				Weight:    backend.GetWeight(),
-				Enabled:   status == pb.Host_PRODUCTION || status == pb.Host_TESTING,
-				InService: status != pb.Host_PROPOSED && status != pb.Host_BUILDING,
+			if err := v.AddBackend(protoToBackend(backend)); err != nil {
+				configError(c, "%v", err)
+			}
+		}
+		switch vs.GetOnAllDown() {
+		case pb.Vserver_WITHDRAW:
+			v.OnAllDown = OnAllDownWithdraw
+		case pb.Vserver_FALLBACK:
+			if vs.FallbackBackend == nil {
+				configError(c, "%v: fallback_backend is required for FALLBACK", vs.GetName())
+				break
 			}
-			if err := v.AddBackend(b); err != nil {
-				log.Warning(err)
+			if err := v.SetFallbackBackend(protoToBackend(vs.FallbackBackend)); err != nil {
+				configError(c, "%v", err)
+				break
 			}
+			v.OnAllDown = OnAllDownFallback
 		}
 		for _, hc := range protosToHealthchecks(vs.Healthcheck, 0) {
 			if err := v.AddHealthcheck(hc); err != nil {
-				log.Warning(err)
+				configError(c, "%v", err)
 			}
 		}
 		if err := c.AddVserver(v); err != nil {
-			log.Warning(err)
+			configError(c, "%v", err)
 		}
 	}
 }
 
+// protoToBackend converts a backend protobuf to a seesaw.Backend.
+func protoToBackend(backend *pb.Backend) *seesaw.Backend {
+	status := backend.GetHost().GetStatus()
+	return &seesaw.Backend{
+		Host:      protoToHost(backend.GetHost()),
+		Weight:    backend.GetWeight(),
+		Enabled:   status == pb.Host_PRODUCTION || status == pb.Host_TESTING,
+		InService: status != pb.Host_PROPOSED && status != pb.Host_BUILDING,
+
+		UpperThreshold: backend.GetUpperThreshold(),
+		LowerThreshold: backend.GetLowerThreshold(),
+	}
+}
+
+// setSchedulerFlags sets the scheduler flags for a VserverEntry. The flags
+// are scheduler specific and may only be used with their scheduler.
+func setSchedulerFlags(e *VserverEntry, ve *pb.VserverEntry) error {
+	mh := ve.GetMhFallback() || ve.GetMhPort()
+	sh := ve.GetShFallback() || ve.GetShPort()
+	switch {
+	case mh && e.Scheduler != seesaw.LBSchedulerMH:
+		return fmt.Errorf("mh scheduler flags are not valid with the %v scheduler", e.Scheduler)
+	case sh && e.Scheduler != seesaw.LBSchedulerSH:
+		return fmt.Errorf("sh scheduler flags are not valid with the %v scheduler", e.Scheduler)
+	}
+	e.SchedFallback = ve.GetMhFallback() || ve.GetShFallback()
+	e.SchedPort = ve.GetMhPort() || ve.GetShPort()
+	return nil
+}
+
+// setPersistencePrefixes sets the prefix lengths that are used to group
+// client addresses for persistence for a VserverEntry.
+func setPersistencePrefixes(e *VserverEntry, ve *pb.VserverEntry) error {
+	prefix4, prefix6 := int(ve.GetPersistencePrefixIpv4()), int(ve.GetPersistencePrefixIpv6())
+	switch {
+	case (prefix4 != 0 || prefix6 != 0) && e.Persistence <= 0:
+		return fmt.Errorf("persistence prefixes require persistence")
+	case prefix4 < 0 || prefix4 > 32:
+		return fmt.Errorf("invalid IPv4 persistence prefix length %d", prefix4)
+	case prefix6 < 0 || prefix6 > 128:
+		return fmt.Errorf("invalid IPv6 persistence prefix length %d", prefix6)
+	}
+	e.PersistencePrefixIPv4 = prefix4
+	e.PersistencePrefixIPv6 = prefix6
+	return nil
+}
+
 func addWarnings(c *Cluster, p *pb.Cluster) {
 	for _, mvs := range p.GetMisconfiguredVserver() {
 		warning := fmt.Sprintf("%s: %s", mvs.GetName(), mvs.GetErrorMessage())
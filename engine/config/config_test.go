@@ -19,10 +19,15 @@ package config
 // This file contains the unit tests for the config package.
 
 import (
+	"encoding/pem"
 	"io/ioutil"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -396,6 +401,13 @@ var vserverTests = []struct {
 				true,
 				false,
 				nil,
+				0,
+				0,
+				false,
+				OnAllDownBlackhole,
+				nil,
+				nil,
+				nil,
 			},
 			"dns.resolver@au-syd": {
 				"dns.resolver@au-syd",
@@ -437,6 +449,13 @@ var vserverTests = []struct {
 				true,
 				false,
 				nil,
+				0,
+				0,
+				false,
+				OnAllDownBlackhole,
+				nil,
+				nil,
+				nil,
 			},
 			"irc.server@au-syd": {
 				"irc.server@au-syd",
@@ -518,6 +537,13 @@ var vserverTests = []struct {
 				true,
 				false,
 				nil,
+				0,
+				0,
+				false,
+				OnAllDownBlackhole,
+				nil,
+				nil,
+				nil,
 			},
 		},
 	},
@@ -695,3 +721,693 @@ func compareHealthchecks(t *testing.T, got, want map[string]*Healthcheck) {
 		}
 	}
 }
+
+func TestAddHealthcheckValidation(t *testing.T) {
+	tests := []struct {
+		desc    string
+		hcType  seesaw.HealthcheckType
+		method  string
+		receive string
+		body    string
+		ok      bool
+	}{
+		{"default method", seesaw.HCTypeHTTP, "", "ok", "", true},
+		{"GET", seesaw.HCTypeHTTP, "GET", "ok", "", true},
+		{"HEAD", seesaw.HCTypeHTTPS, "HEAD", "", "", true},
+		{"HEAD with receive", seesaw.HCTypeHTTP, "HEAD", "ok", "", false},
+		{"lower case HEAD with receive", seesaw.HCTypeHTTPS, "head", "ok", "", false},
+		{"OPTIONS", seesaw.HCTypeHTTP, "OPTIONS", "", "", true},
+		{"POST with body", seesaw.HCTypeHTTP, "POST", "ok", `{"ping":1}`, true},
+		{"GET with body", seesaw.HCTypeHTTP, "GET", "", "foo", false},
+		{"unsupported method", seesaw.HCTypeHTTP, "DELETE", "", "", false},
+		{"non-HTTP healthcheck", seesaw.HCTypeTCP, "anything", "", "", true},
+	}
+	for _, test := range tests {
+		hc := NewHealthcheck(seesaw.HCModePlain, test.hcType, 80)
+		hc.Name = "test"
+		hc.Method = test.method
+		hc.Receive = test.receive
+		hc.Body = test.body
+
+		v := NewVserver("vserver", seesaw.Host{})
+		err := v.AddHealthcheck(hc)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: Vserver.AddHealthcheck returned %v, want success %t", test.desc, err, test.ok)
+		}
+		e := NewVserverEntry(80, seesaw.IPProtoTCP)
+		err = e.AddHealthcheck(hc)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: VserverEntry.AddHealthcheck returned %v, want success %t", test.desc, err, test.ok)
+		}
+	}
+}
+
+func TestAddBackendThresholds(t *testing.T) {
+	tests := []struct {
+		desc  string
+		upper int32
+		lower int32
+		ok    bool
+	}{
+		{"no thresholds", 0, 0, true},
+		{"upper threshold", 1000, 0, true},
+		{"both thresholds", 1000, 500, true},
+		{"lower equal to upper", 1000, 1000, false},
+		{"lower above upper", 500, 1000, false},
+		{"lower only", 0, 500, false},
+		{"negative", -1, 0, false},
+	}
+	for _, test := range tests {
+		v := NewVserver("web", seesaw.Host{Hostname: "web.example.com."})
+		b := &seesaw.Backend{
+			Host:           seesaw.Host{Hostname: "web1.example.com."},
+			Weight:         1,
+			UpperThreshold: test.upper,
+			LowerThreshold: test.lower,
+		}
+		err := v.AddBackend(b)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: AddBackend returned %v, want success %t", test.desc, err, test.ok)
+		}
+	}
+}
+
+func TestSetFallbackBackend(t *testing.T) {
+	v := NewVserver("web", seesaw.Host{Hostname: "web.example.com."})
+	b := &seesaw.Backend{
+		Host:   seesaw.Host{Hostname: "web1.example.com.", IPv4Addr: net.ParseIP("192.168.37.2")},
+		Weight: 1,
+	}
+	if err := v.AddBackend(b); err != nil {
+		t.Fatalf("AddBackend failed: %v", err)
+	}
+	tests := []struct {
+		desc string
+		ip   string
+		ok   bool
+	}{
+		{"distinct address", "192.168.37.3", true},
+		{"same address as backend", "192.168.37.2", false},
+	}
+	for _, test := range tests {
+		fb := &seesaw.Backend{
+			Host:   seesaw.Host{Hostname: "sorry.example.com.", IPv4Addr: net.ParseIP(test.ip)},
+			Weight: 1,
+		}
+		err := v.SetFallbackBackend(fb)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: SetFallbackBackend returned %v, want success %t", test.desc, err, test.ok)
+		}
+	}
+}
+
+func TestAddAdditionalVIP(t *testing.T) {
+	v := NewVserver("web", seesaw.Host{Hostname: "web.example.com.", IPv4Addr: net.ParseIP("192.168.36.1")})
+	tests := []struct {
+		desc string
+		ip   string
+		ok   bool
+	}{
+		{"IPv4 address", "192.168.36.2", true},
+		{"IPv6 address", "2012::2", true},
+		{"host address", "192.168.36.1", false},
+		{"duplicate address", "192.168.36.2", false},
+	}
+	for _, test := range tests {
+		err := v.AddAdditionalVIP(net.ParseIP(test.ip))
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: AddAdditionalVIP returned %v, want success %t", test.desc, err, test.ok)
+		}
+	}
+	if got, want := len(v.Addresses()), 3; got != want {
+		t.Errorf("Got %d addresses, want %d", got, want)
+	}
+
+	v.UseFWM = true
+	if err := v.AddAdditionalVIP(net.ParseIP("192.168.36.3")); err == nil {
+		t.Errorf("AddAdditionalVIP succeeded for a firewall mark vserver")
+	}
+}
+
+func TestAddBGPCommunity(t *testing.T) {
+	v := NewVserver("web", seesaw.Host{Hostname: "web.example.com.", IPv4Addr: net.ParseIP("192.168.255.1")})
+	tests := []struct {
+		community string
+		ok        bool
+	}{
+		{"64512:100", true},
+		{"no-export", true},
+		{"64512:1:2", true},
+		{"4200000000:1:2", true},
+		{"64512:100", false},
+		{"70000:100", false},
+		{"64512", false},
+		{"64512:1:2:3", false},
+		{"64512:abc", false},
+	}
+	for _, test := range tests {
+		err := v.AddBGPCommunity(test.community)
+		if got := err == nil; got != test.ok {
+			t.Errorf("AddBGPCommunity(%q) returned %v, want success %t", test.community, err, test.ok)
+		}
+	}
+	want := []string{"64512:100", "no-export", "64512:1:2", "4200000000:1:2"}
+	if !reflect.DeepEqual(v.BGPCommunities, want) {
+		t.Errorf("Got BGP communities %q, want %q", v.BGPCommunities, want)
+	}
+}
+
+func TestAddVserverEntryFWM(t *testing.T) {
+	newEntry := func(port uint16, scheduler seesaw.LBScheduler) *VserverEntry {
+		e := NewVserverEntry(port, seesaw.IPProtoTCP)
+		e.Scheduler = scheduler
+		e.Mode = seesaw.LBModeDSR
+		return e
+	}
+	for _, useFWM := range []bool{false, true} {
+		v := NewVserver("vserver", seesaw.Host{})
+		v.UseFWM = useFWM
+		if err := v.AddVserverEntry(newEntry(80, seesaw.LBSchedulerWRR)); err != nil {
+			t.Errorf("FWM %t: AddVserverEntry failed: %v", useFWM, err)
+		}
+		if err := v.AddVserverEntry(newEntry(443, seesaw.LBSchedulerWRR)); err != nil {
+			t.Errorf("FWM %t: AddVserverEntry with matching settings failed: %v", useFWM, err)
+		}
+		err := v.AddVserverEntry(newEntry(8080, seesaw.LBSchedulerSH))
+		if got := err == nil; got == useFWM {
+			t.Errorf("FWM %t: AddVserverEntry with different scheduler returned %v, want success %t", useFWM, err, !useFWM)
+		}
+	}
+}
+
+func TestSetSchedulerFlags(t *testing.T) {
+	tests := []struct {
+		desc      string
+		scheduler seesaw.LBScheduler
+		ve        *pb.VserverEntry
+		ok        bool
+		fallback  bool
+		port      bool
+	}{
+		{"no flags", seesaw.LBSchedulerWRR, &pb.VserverEntry{}, true, false, false},
+		{"sh flags", seesaw.LBSchedulerSH, &pb.VserverEntry{ShFallback: proto.Bool(true), ShPort: proto.Bool(true)}, true, true, true},
+		{"sh fallback", seesaw.LBSchedulerSH, &pb.VserverEntry{ShFallback: proto.Bool(true)}, true, true, false},
+		{"mh flags", seesaw.LBSchedulerMH, &pb.VserverEntry{MhFallback: proto.Bool(true), MhPort: proto.Bool(true)}, true, true, true},
+		{"sh flags with mh", seesaw.LBSchedulerMH, &pb.VserverEntry{ShFallback: proto.Bool(true)}, false, false, false},
+		{"mh flags with sh", seesaw.LBSchedulerSH, &pb.VserverEntry{MhPort: proto.Bool(true)}, false, false, false},
+		{"sh flags with wrr", seesaw.LBSchedulerWRR, &pb.VserverEntry{ShPort: proto.Bool(true)}, false, false, false},
+	}
+	for _, test := range tests {
+		e := NewVserverEntry(80, seesaw.IPProtoTCP)
+		e.Scheduler = test.scheduler
+		err := setSchedulerFlags(e, test.ve)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: setSchedulerFlags returned %v, want success %t", test.desc, err, test.ok)
+			continue
+		}
+		if e.SchedFallback != test.fallback || e.SchedPort != test.port {
+			t.Errorf("Test %q: got fallback %t, port %t, want fallback %t, port %t",
+				test.desc, e.SchedFallback, e.SchedPort, test.fallback, test.port)
+		}
+	}
+}
+
+func TestOnePacket(t *testing.T) {
+	p := &pb.Cluster{
+		SeesawVip: &pb.Host{Fqdn: proto.String("seesaw-vip.example.com.")},
+		Vserver: []*pb.Vserver{
+			{
+				Name:         proto.String("dns@example"),
+				EntryAddress: &pb.Host{Fqdn: proto.String("dns.example.com."), Ipv4: proto.String("192.168.255.1/24")},
+				Rp:           proto.String("foo"),
+				VserverEntry: []*pb.VserverEntry{
+					{Protocol: pb.Protocol_UDP.Enum(), Port: proto.Int32(53), OnePacket: proto.Bool(true)},
+					{Protocol: pb.Protocol_TCP.Enum(), Port: proto.Int32(53), OnePacket: proto.Bool(true)},
+				},
+			},
+		},
+	}
+	c := NewCluster("example")
+	addVservers(c, p)
+	v, ok := c.Vservers["dns@example"]
+	if !ok {
+		t.Fatalf("Vserver dns@example not found")
+	}
+	if e, ok := v.Entries["53/UDP"]; !ok || !e.OnePacket {
+		t.Errorf("Got UDP entry %+v, want one packet scheduling", e)
+	}
+	if e, ok := v.Entries["53/TCP"]; ok {
+		t.Errorf("Got TCP entry %+v with one packet scheduling, want none", e)
+	}
+}
+
+func TestSetPersistencePrefixes(t *testing.T) {
+	tests := []struct {
+		desc        string
+		persistence int
+		ve          *pb.VserverEntry
+		ok          bool
+		prefix4     int
+		prefix6     int
+	}{
+		{"no prefixes", 0, &pb.VserverEntry{}, true, 0, 0},
+		{"both prefixes", 300, &pb.VserverEntry{PersistencePrefixIpv4: proto.Int32(24), PersistencePrefixIpv6: proto.Int32(64)}, true, 24, 64},
+		{"ipv4 prefix", 300, &pb.VserverEntry{PersistencePrefixIpv4: proto.Int32(16)}, true, 16, 0},
+		{"without persistence", 0, &pb.VserverEntry{PersistencePrefixIpv4: proto.Int32(24)}, false, 0, 0},
+		{"ipv4 too long", 300, &pb.VserverEntry{PersistencePrefixIpv4: proto.Int32(33)}, false, 0, 0},
+		{"ipv6 too long", 300, &pb.VserverEntry{PersistencePrefixIpv6: proto.Int32(129)}, false, 0, 0},
+		{"negative", 300, &pb.VserverEntry{PersistencePrefixIpv4: proto.Int32(-1)}, false, 0, 0},
+	}
+	for _, test := range tests {
+		e := NewVserverEntry(80, seesaw.IPProtoTCP)
+		e.Persistence = test.persistence
+		err := setPersistencePrefixes(e, test.ve)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: setPersistencePrefixes returned %v, want success %t", test.desc, err, test.ok)
+			continue
+		}
+		if e.PersistencePrefixIPv4 != test.prefix4 || e.PersistencePrefixIPv6 != test.prefix6 {
+			t.Errorf("Test %q: got prefixes /%d, /%d, want /%d, /%d",
+				test.desc, e.PersistencePrefixIPv4, e.PersistencePrefixIPv6, test.prefix4, test.prefix6)
+		}
+	}
+}
+
+func TestCheckForwardingModes(t *testing.T) {
+	newVserver := func(name string, mode seesaw.LBMode, backend string) *Vserver {
+		v := NewVserver(name, seesaw.Host{Hostname: name + ".example.com.", IPv4Addr: net.ParseIP("192.168.255.1").To4()})
+		e := NewVserverEntry(80, seesaw.IPProtoTCP)
+		e.Mode = mode
+		if err := v.AddVserverEntry(e); err != nil {
+			t.Fatalf("AddVserverEntry failed: %v", err)
+		}
+		b := &seesaw.Backend{Host: seesaw.Host{Hostname: name + "-backend.example.com.", IPv4Addr: net.ParseIP(backend).To4()}}
+		if err := v.AddBackend(b); err != nil {
+			t.Fatalf("AddBackend failed: %v", err)
+		}
+		return v
+	}
+	tests := []struct {
+		desc    string
+		vserver *Vserver
+		ok      bool
+	}{
+		{"DSR on cluster network", newVserver("dsr1", seesaw.LBModeDSR, "192.168.36.2"), true},
+		{"DSR on VLAN", newVserver("dsr2", seesaw.LBModeDSR, "192.168.99.2"), true},
+		{"DSR on VIP subnet", newVserver("dsr4", seesaw.LBModeDSR, "192.168.100.2"), true},
+		{"DSR not attached", newVserver("dsr3", seesaw.LBModeDSR, "10.0.0.2"), false},
+		{"NAT not attached", newVserver("nat", seesaw.LBModeNAT, "10.0.0.2"), true},
+		{"TUN not attached", newVserver("tun", seesaw.LBModeTUN, "10.0.0.2"), true},
+	}
+	for _, test := range tests {
+		c := NewCluster("example")
+		c.VIP = seesaw.Host{
+			Hostname: "seesaw-vip.example.com.",
+			IPv4Addr: net.ParseIP("192.168.36.1").To4(),
+			IPv4Mask: net.CIDRMask(24, 32),
+		}
+		vlan := &seesaw.VLAN{
+			ID: 99,
+			Host: seesaw.Host{
+				Hostname: "vlan99.example.com.",
+				IPv4Addr: net.ParseIP("192.168.99.1").To4(),
+				IPv4Mask: net.CIDRMask(24, 32),
+			},
+		}
+		c.VLANs[vlan.Key()] = vlan
+		_, vipSubnet, _ := net.ParseCIDR("192.168.100.0/24")
+		c.VIPSubnets[vipSubnet.String()] = vipSubnet
+		c.Vservers[test.vserver.Name] = test.vserver
+		checkForwardingModes(c)
+		v, ok := c.Vservers[test.vserver.Name]
+		if !ok {
+			t.Errorf("Test %q: vserver was removed", test.desc)
+			continue
+		}
+		if got := len(v.Backends); got != 1 {
+			t.Errorf("Test %q: got %d backends, want 1", test.desc, got)
+		}
+		if got := len(c.Status.Errors) == 0; got != test.ok {
+			t.Errorf("Test %q: got errors %q", test.desc, c.Status.Errors)
+		}
+		if len(c.Status.Warnings) != 0 {
+			t.Errorf("Test %q: got warnings %q", test.desc, c.Status.Warnings)
+		}
+	}
+}
+
+func TestCheckServiceConflicts(t *testing.T) {
+	newVserver := func(name, ip string, port uint16, proto seesaw.IPProto) *Vserver {
+		v := NewVserver(name, seesaw.Host{Hostname: name + ".example.com.", IPv4Addr: net.ParseIP(ip).To4()})
+		if err := v.AddVserverEntry(NewVserverEntry(port, proto)); err != nil {
+			t.Fatalf("AddVserverEntry failed: %v", err)
+		}
+		return v
+	}
+	tests := []struct {
+		desc     string
+		vservers []*Vserver
+		ok       bool
+	}{
+		{
+			"different ports",
+			[]*Vserver{
+				newVserver("dns", "192.168.36.1", 53, seesaw.IPProtoUDP),
+				newVserver("web", "192.168.36.1", 80, seesaw.IPProtoTCP),
+			},
+			true,
+		},
+		{
+			"different protocols",
+			[]*Vserver{
+				newVserver("dns-udp", "192.168.36.1", 53, seesaw.IPProtoUDP),
+				newVserver("dns-tcp", "192.168.36.1", 53, seesaw.IPProtoTCP),
+			},
+			true,
+		},
+		{
+			"different VIPs",
+			[]*Vserver{
+				newVserver("web1", "192.168.36.1", 80, seesaw.IPProtoTCP),
+				newVserver("web2", "192.168.36.2", 80, seesaw.IPProtoTCP),
+			},
+			true,
+		},
+		{
+			"same service",
+			[]*Vserver{
+				newVserver("web1", "192.168.36.1", 80, seesaw.IPProtoTCP),
+				newVserver("web2", "192.168.36.1", 80, seesaw.IPProtoTCP),
+			},
+			false,
+		},
+	}
+	for _, test := range tests {
+		c := NewCluster("example")
+		for _, v := range test.vservers {
+			c.Vservers[v.Name] = v
+		}
+		err := checkServiceConflicts(c)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: checkServiceConflicts returned %v, want success %t", test.desc, err, test.ok)
+		}
+	}
+}
+
+func TestDiffClusters(t *testing.T) {
+	newBackend := func(hostname string, weight int32) *seesaw.Backend {
+		return &seesaw.Backend{
+			Host:    seesaw.Host{Hostname: hostname},
+			Weight:  weight,
+			Enabled: true,
+		}
+	}
+	newCluster := func(vservers ...*Vserver) *Cluster {
+		c := NewCluster("example")
+		for _, v := range vservers {
+			c.Vservers[v.Name] = v
+		}
+		return c
+	}
+	newTestVserver := func(name string, backends ...*seesaw.Backend) *Vserver {
+		v := NewVserver(name, seesaw.Host{Hostname: name})
+		for _, b := range backends {
+			v.Backends[b.Key()] = b
+		}
+		return v
+	}
+
+	old := newCluster(
+		newTestVserver("dns", newBackend("dns1", 1), newBackend("dns2", 1)),
+		newTestVserver("web", newBackend("web1", 1)),
+		newTestVserver("mail", newBackend("mail1", 1)),
+	)
+	updated := newCluster(
+		newTestVserver("dns", newBackend("dns1", 3), newBackend("dns3", 1)),
+		newTestVserver("web", newBackend("web1", 1)),
+		newTestVserver("ftp", newBackend("ftp1", 1)),
+	)
+	updated.Status.LastUpdate = time.Unix(1500000000, 0)
+
+	want := &seesaw.ClusterChanges{
+		VserversAdded:   []string{"ftp"},
+		VserversRemoved: []string{"mail"},
+		VserversUpdated: []string{"dns"},
+		BackendsAdded:   []string{"dns/dns3"},
+		BackendsRemoved: []string{"dns/dns2"},
+		WeightsChanged:  []string{"dns/dns1 (1 -> 3)"},
+	}
+	got := DiffClusters(old, updated)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffClusters() = %#v, want %#v", got, want)
+	}
+
+	// Status changes alone are not reported.
+	if got := DiffClusters(updated, updated); !got.Empty() {
+		t.Errorf("DiffClusters() for identical clusters = %v, want no changes", got)
+	}
+	same := *updated
+	same.Status = seesaw.ConfigStatus{}
+	if got := DiffClusters(updated, &same); !got.Empty() {
+		t.Errorf("DiffClusters() for status change = %v, want no changes", got)
+	}
+	other := *updated
+	other.BGPLocalASN = 64512
+	if got := DiffClusters(updated, &other); !got.Other {
+		t.Errorf("DiffClusters() for BGP change = %v, want other changes", got)
+	}
+}
+
+func TestConfigFromURL(t *testing.T) {
+	text, err := ioutil.ReadFile(filepath.Join(testDataDir, "nodes1.pb"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	p := &pb.Cluster{}
+	if err := proto.UnmarshalText(string(text), p); err != nil {
+		t.Fatalf("UnmarshalText failed: %v", err)
+	}
+	binary, err := proto.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var contentType string
+	var body []byte
+	status := http.StatusOK
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	cfg := DefaultEngineConfig()
+	cfg.CACertFile = caFile
+	cfg.ConfigURL = srv.URL + "/config/test"
+	n := &Notifier{engineCfg: &cfg}
+
+	tests := []struct {
+		desc        string
+		contentType string
+		body        []byte
+		status      int
+		ok          bool
+	}{
+		{"Binary protobuf", "application/x-protobuffer", binary, http.StatusOK, true},
+		{"Text protobuf", "text/plain; charset=utf-8", text, http.StatusOK, true},
+		{"Invalid protobuf", "application/x-protobuffer", text, http.StatusOK, false},
+		{"Unknown content type", "text/html", text, http.StatusOK, false},
+		{"HTTP error", "text/plain", text, http.StatusInternalServerError, false},
+	}
+	for _, test := range tests {
+		contentType, body, status = test.contentType, test.body, test.status
+		note, err := n.pullConfig(SourceURL)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: pullConfig returned %v, want success %t", test.desc, err, test.ok)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if note.Source != SourceURL || note.SourceDetail != cfg.ConfigURL {
+			t.Errorf("Test %q: got config from %v (%v), want %v (%v)", test.desc, note.Source, note.SourceDetail, SourceURL, cfg.ConfigURL)
+		}
+		if got, want := len(note.Cluster.Nodes), len(p.Node); got != want {
+			t.Errorf("Test %q: got %d nodes, want %d", test.desc, got, want)
+		}
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	if _, err := protoToCluster(&pb.Cluster{}, "test"); err == nil {
+		t.Errorf("protoToCluster succeeded without a seesaw_vip")
+	}
+
+	p := &pb.Cluster{
+		SeesawVip: &pb.Host{
+			Fqdn:   proto.String("seesaw-vip1.example.com."),
+			Ipv4:   proto.String("192.168.36.1/24"),
+			Status: pb.Host_PRODUCTION.Enum(),
+		},
+		DedicatedVipSubnet: []string{"192.168.100.0/24", "not-a-subnet"},
+		Vserver: []*pb.Vserver{
+			{
+				Name: proto.String("dns.resolver@au-syd"),
+				EntryAddress: &pb.Host{
+					Fqdn:   proto.String("dns-vip1.example.com."),
+					Ipv4:   proto.String("192.168.36.10/24"),
+					Status: pb.Host_PRODUCTION.Enum(),
+				},
+				Rp: proto.String("corp-dns@example.com"),
+				VserverEntry: []*pb.VserverEntry{
+					{
+						Protocol: pb.Protocol_UDP.Enum(),
+						Port:     proto.Int32(53),
+					},
+					{
+						Protocol:  pb.Protocol_TCP.Enum(),
+						Port:      proto.Int32(53),
+						OnePacket: proto.Bool(true),
+					},
+				},
+			},
+		},
+	}
+	c, err := protoToCluster(p, "test")
+	if err != nil {
+		t.Fatalf("protoToCluster failed: %v", err)
+	}
+	if got, want := len(c.Status.Errors), 2; got != want {
+		t.Errorf("Got %d config errors (%q), want %d", got, c.Status.Errors, want)
+	}
+	if got, want := len(c.Vservers["dns.resolver@au-syd"].Entries), 1; got != want {
+		t.Errorf("Got %d vserver entries, want %d", got, want)
+	}
+}
+
+func TestExpandConfig(t *testing.T) {
+	filename := filepath.Join(testDataDir, "include", "cluster.pb")
+	t.Setenv("TEST_SEESAW_RP", "corp-dns@example.com")
+	got, err := expandConfig(filename)
+	if err != nil {
+		t.Fatalf("expandConfig failed: %v", err)
+	}
+	want := `seesaw_vip <
+  fqdn: "seesaw-vip1.example.com."
+  ipv4: "192.168.36.1/24"
+  status: PRODUCTION
+>
+vserver <
+  name: "dns.resolver@au-syd"
+  entry_address <
+    fqdn: "dns-vip1.example.com."
+    ipv4: "192.168.36.10/24"
+    status: PRODUCTION
+  >
+  rp: "corp-dns@example.com"
+>
+
+`
+	if got != want {
+		t.Errorf("expandConfig(%q) = %q, want %q", filename, got, want)
+	}
+
+	t.Setenv("TEST_SEESAW_VIP", "seesaw-vip2.example.com.")
+	if got, err := expandConfig(filename); err != nil || !strings.Contains(got, `fqdn: "seesaw-vip2.example.com."`) {
+		t.Errorf("expandConfig(%q) = %q, %v, want the VIP from the environment", filename, got, err)
+	}
+
+	os.Unsetenv("TEST_SEESAW_RP")
+	if _, err := expandConfig(filename); err == nil {
+		t.Errorf("expandConfig(%q) succeeded with an undefined variable", filename)
+	}
+
+	filename = filepath.Join(testDataDir, "include", "cycle1.pb")
+	if _, err := expandConfig(filename); err == nil {
+		t.Errorf("expandConfig(%q) succeeded with an include cycle", filename)
+	}
+}
+
+func TestParseInclude(t *testing.T) {
+	tests := []struct {
+		line    string
+		path    string
+		include bool
+		ok      bool
+	}{
+		{`include "vservers/dns.pb"`, "vservers/dns.pb", true, true},
+		{`  include "/etc/seesaw/my vservers.pb"` + "\n", "/etc/seesaw/my vservers.pb", true, true},
+		{`vserver <`, "", false, true},
+		{`include vservers/dns.pb`, "", false, false},
+		{`include`, "", false, false},
+		{`include ""`, "", false, false},
+	}
+	for _, test := range tests {
+		path, include, err := parseInclude(test.line)
+		if (err == nil) != test.ok {
+			t.Errorf("parseInclude(%q) returned error %v, want ok %t", test.line, err, test.ok)
+			continue
+		}
+		if path != test.path || include != test.include {
+			t.Errorf("parseInclude(%q) = %q, %t, want %q, %t", test.line, path, include, test.path, test.include)
+		}
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	tests := []struct {
+		file   string
+		format Format
+		want   Format
+	}{
+		{"cluster.pb", FormatAuto, FormatText},
+		{"cluster.yaml", FormatAuto, FormatYAML},
+		{"cluster.yml", FormatAuto, FormatYAML},
+		{"cluster.yaml", FormatText, FormatText},
+		{"cluster.pb", FormatYAML, FormatYAML},
+	}
+	for _, test := range tests {
+		if got := fileFormat(test.file, test.format); got != test.want {
+			t.Errorf("fileFormat(%q, %v) = %v, want %v", test.file, test.format, got, test.want)
+		}
+	}
+	if _, err := FormatByName("json"); err == nil {
+		t.Errorf("FormatByName succeeded for unknown format")
+	}
+}
+
+func TestYAMLConfig(t *testing.T) {
+	text, err := ReadConfig(filepath.Join(testDataDir, "nodes1.pb"), "")
+	if err != nil {
+		t.Fatalf("ReadConfig failed for text config: %v", err)
+	}
+	yml, err := ReadConfig(filepath.Join(testDataDir, "nodes1.yaml"), "")
+	if err != nil {
+		t.Fatalf("ReadConfig failed for YAML config: %v", err)
+	}
+	if !yml.Cluster.Equal(text.Cluster) {
+		t.Errorf("YAML config = %#v, want %#v", yml.Cluster, text.Cluster)
+	}
+
+	b, err := protoToYAML(text.protobuf)
+	if err != nil {
+		t.Fatalf("protoToYAML failed: %v", err)
+	}
+	p := &pb.Cluster{}
+	if err := yamlToProto(string(b), p); err != nil {
+		t.Fatalf("yamlToProto failed: %v", err)
+	}
+	if !proto.Equal(p, text.protobuf) {
+		t.Errorf("YAML round trip = %v, want %v", p, text.protobuf)
+	}
+
+	if err := yamlToProto("seesaw_vip:\n  fqdn: seesaw-vip1.example.com.\n  no_such_field: 1\n", p); err == nil {
+		t.Errorf("yamlToProto succeeded with an unknown field")
+	}
+}
@@ -20,15 +20,20 @@ package config
 // for a seesaw engine.
 
 import (
+	"fmt"
 	"net"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/quagga"
 )
 
 var defaultEngineConfig = EngineConfig{
+	AllowExecChecks:         false,
 	AnycastEnabled:          true,
+	AnycastHoldDown:         10 * time.Second,
 	BGPUpdateInterval:       15 * time.Second,
 	CACertFile:              path.Join(seesaw.ConfigPath, "ssl", "ca.crt"),
 	ConfigFile:              path.Join(seesaw.ConfigPath, "seesaw.cfg"),
@@ -38,6 +43,10 @@ var defaultEngineConfig = EngineConfig{
 	ConfigServerTimeout:     20 * time.Second,
 	ClusterFile:             path.Join(seesaw.ConfigPath, "cluster.pb"),
 	DummyInterface:          "dummy0",
+	FlapInterval:            1 * time.Minute,
+	FlapThreshold:           5,
+	GARPCount:               3,
+	GARPInterval:            1 * time.Second,
 	GratuitousARPInterval:   10 * time.Second,
 	HAStateTimeout:          30 * time.Second,
 	LBInterface:             "eth1",
@@ -47,6 +56,8 @@ var defaultEngineConfig = EngineConfig{
 	RoutingTableID:          2,
 	ServiceAnycastIPv4:      []net.IP{seesaw.TestAnycastHost().IPv4Addr},
 	ServiceAnycastIPv6:      []net.IP{seesaw.TestAnycastHost().IPv6Addr},
+	ShutdownPolicy:          ShutdownClear,
+	SlowStartInterval:       1 * time.Second,
 	SocketPath:              seesaw.EngineSocket,
 	StatsInterval:           15 * time.Second,
 	SyncPort:                10258,
@@ -61,33 +72,177 @@ func DefaultEngineConfig() EngineConfig {
 
 // EngineConfig provides configuration details for an Engine.
 type EngineConfig struct {
-	AnycastEnabled          bool          // Flag to enable or disable anycast.
-	BGPUpdateInterval       time.Duration // The BGP update interval.
-	CACertFile              string        // The path to the SSL/TLS CA cert file.
-	ClusterFile             string        // The path to the cluster protobuf file.
-	ClusterName             string        // The name of the cluster the engine is running in.
-	ClusterVIP              seesaw.Host   // The VIP for this Seesaw Cluster.
-	ConfigInterval          time.Duration // The cluster configuration update interval.
-	ConfigFile              string        // The path to the engine config file.
-	ConfigServers           []string      // The list of configuration servers (hostnames) in priority order.
-	ConfigServerPort        int           // The configuration server port number.
-	ConfigServerTimeout     time.Duration // The configuration server client timeout (per TCP connection).
-	DummyInterface          string        // The dummy network interface.
-	GratuitousARPInterval   time.Duration // The interval for gratuitous ARP messages.
-	HAStateTimeout          time.Duration // The timeout for receiving HAState updates.
-	LBInterface             string        // The network interface to use for load balancing.
-	MaxPeerConfigSyncErrors int           // The number of allowable peer config sync errors.
-	NCCSocket               string        // The Network Control Center socket.
-	NodeInterface           string        // The primary network interface for this node.
-	Node                    seesaw.Host   // The node the engine is running on.
-	Peer                    seesaw.Host   // The node's peer.
-	RoutingTableID          uint8         // The routing table ID to use for load balanced traffic.
-	ServiceAnycastIPv4      []net.IP      // IPv4 anycast addresses that are always advertised.
-	ServiceAnycastIPv6      []net.IP      // IPv6 anycast addresses that are always advertised.
-	SocketPath              string        // The path to the engine socket.
-	StatsInterval           time.Duration // The statistics update interval.
-	SyncPort                int           // The port for sync'ing with this node's peer.
-	VMAC                    string        // The VMAC address to use for the load balancing network interface.
-	VRID                    uint8         // The VRRP virtual router ID for the cluster.
-	VRRPDestIP              net.IP        // The destination IP for VRRP advertisements.
+	AllowExecChecks         bool            // Flag to enable or disable exec healthchecks.
+	AnycastEnabled          bool            // Flag to enable or disable anycast.
+	AnycastHoldDown         time.Duration   // The time an anycast VIP must stay up after going down before it is re-advertised.
+	AuditLog                string          // The audit log file, or "syslog" (disabled if empty).
+	BGPPeers                []*quagga.Peer  // BGP peers to configure in the BGP daemon.
+	BGPUpdateInterval       time.Duration   // The BGP update interval.
+	CACertFile              string          // The path to the SSL/TLS CA cert file.
+	ClusterFile             string          // The path to the cluster protobuf file.
+	ClusterFormat           Format          // The format of the cluster protobuf file.
+	ClusterName             string          // The name of the cluster the engine is running in.
+	ClusterVIP              seesaw.Host     // The VIP for this Seesaw Cluster.
+	ConfigInterval          time.Duration   // The cluster configuration update interval.
+	ConfigFile              string          // The path to the engine config file.
+	ConfigServers           []string        // The list of configuration servers (hostnames) in priority order.
+	ConfigServerPort        int             // The configuration server port number.
+	ConfigServerTimeout     time.Duration   // The configuration server client timeout (per TCP connection).
+	ConfigURL               string          // The HTTPS URL to fetch the cluster configuration from (disabled if empty).
+	ConfigURLCertFile       string          // The path to the client cert file for the config URL (no client auth if empty).
+	ConfigURLKeyFile        string          // The path to the client key file for the config URL.
+	DummyInterface          string          // The dummy network interface.
+	FlapInterval            time.Duration   // The interval over which backend health transitions are counted.
+	FlapThreshold           int             // The number of transitions per interval before a backend is flapping (disabled if zero).
+	GARPCount               int             // The number of gratuitous ARPs sent for each address on becoming master.
+	GARPInterval            time.Duration   // The interval between gratuitous ARPs sent on becoming master.
+	GratuitousARPInterval   time.Duration   // The interval for gratuitous ARP messages.
+	HAGroups                []HAGroup       // Additional VRRP groups for active/active operation.
+	HAStateTimeout          time.Duration   // The timeout for receiving HAState updates.
+	HATrackers              []HATracker     // Health checks that reduce the node's advertised HA priority.
+	HeartbeatInterface      string          // The network interface for VRRP advertisements (any if empty).
+	HeartbeatSecret         []byte          // The shared secret for authenticating VRRP advertisements.
+	IPCAuthorization        IPCAuth         // The users that may make IPC calls that change the engine state (unrestricted if nil).
+	IPVSSyncGroup           net.IP          // The multicast group for IPVS connection sync (kernel default if nil).
+	IPVSSyncID              uint8           // The sync ID for IPVS connection sync.
+	IPVSSyncInterface       string          // The network interface for IPVS connection sync (disabled if empty).
+	LBInterface             string          // The network interface to use for load balancing.
+	MaxPeerConfigSyncErrors int             // The number of allowable peer config sync errors.
+	MetricsAddress          string          // The address on which to export Prometheus metrics (disabled if empty).
+	NCCSocket               string          // The Network Control Center socket.
+	NodeInterface           string          // The primary network interface for this node.
+	OSPFArea                quagga.OSPFArea // The OSPF area that anycast VIPs are advertised into.
+	Node                    seesaw.Host     // The node the engine is running on.
+	Peer                    seesaw.Host     // The node's peer.
+	RoutingProtocol         RoutingProtocol // The routing protocol used to advertise anycast VIPs.
+	RoutingTableID          uint8           // The routing table ID to use for load balanced traffic.
+	ServiceAnycastIPv4      []net.IP        // IPv4 anycast addresses that are always advertised.
+	ServiceAnycastIPv6      []net.IP        // IPv6 anycast addresses that are always advertised.
+	SlowStartInterval       time.Duration   // The interval for ramping the weight of slow starting backends.
+	ShutdownPolicy          ShutdownPolicy  // The policy for the load balancing state on shutdown.
+	SocketPath              string          // The path to the engine socket.
+	StatsInterval           time.Duration   // The statistics update interval.
+	SyncPort                int             // The port for sync'ing with this node's peer.
+	VMAC                    string          // The VMAC address to use for the load balancing network interface.
+	VRID                    uint8           // The VRRP virtual router ID for the cluster.
+	VRRPDestIP              net.IP          // The destination IP for VRRP advertisements.
+}
+
+// HAGroup specifies an additional VRRP group, with its own VRID and priority.
+// The VIPs for the vservers in the group are only configured while this node
+// is the master for the group, which allows the nodes in a cluster to share
+// the load in an active/active configuration.
+type HAGroup struct {
+	Name     string
+	VRID     uint8
+	Priority uint8
+	Vservers []string
+}
+
+// HATracker represents a health check for a dependency of this node. While the
+// check is failing, its weight is subtracted from the priority advertised by
+// the HA component. Exactly one of Interface, Script or Vservers is specified.
+type HATracker struct {
+	Name      string
+	Interface string
+	Script    string
+	Vservers  int
+	Weight    uint8
+}
+
+// IPCAuth specifies the users that are authorised to make IPC calls that
+// change the state of the engine, keyed by the lower-cased name of the call
+// (for example, "failover"). The users listed for IPCAuthAll are authorised
+// to make all such calls.
+type IPCAuth map[string][]string
+
+// IPCAuthAll is the IPCAuth key for users that are authorised to make all IPC
+// calls.
+const IPCAuthAll = "all"
+
+// Authorised returns whether the given user is authorised to make the named
+// IPC call. All users are authorised if the IPCAuth is nil.
+func (a IPCAuth) Authorised(call, user string) bool {
+	if a == nil {
+		return true
+	}
+	for _, key := range []string{strings.ToLower(call), IPCAuthAll} {
+		for _, u := range a[key] {
+			if u == user {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RoutingProtocol specifies the routing protocol that is used to advertise
+// anycast VIPs.
+type RoutingProtocol int
+
+const (
+	// RoutingBGP advertises anycast VIPs via the BGP daemon.
+	RoutingBGP RoutingProtocol = iota
+	// RoutingOSPF advertises anycast VIPs as host routes via the OSPF
+	// daemon.
+	RoutingOSPF
+)
+
+var routingProtocolNames = map[RoutingProtocol]string{
+	RoutingBGP:  "bgp",
+	RoutingOSPF: "ospf",
+}
+
+// String returns the string representation of a RoutingProtocol.
+func (p RoutingProtocol) String() string {
+	if name, ok := routingProtocolNames[p]; ok {
+		return name
+	}
+	return "(unknown)"
+}
+
+// ParseRoutingProtocol returns the RoutingProtocol with the given name.
+func ParseRoutingProtocol(name string) (RoutingProtocol, error) {
+	for p, n := range routingProtocolNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown routing protocol %q", name)
+}
+
+// ShutdownPolicy specifies what happens to the load balancing state when the
+// engine shuts down.
+type ShutdownPolicy int
+
+const (
+	// ShutdownClear releases HA mastership, then withdraws all VIPs and
+	// clears the IPVS table.
+	ShutdownClear ShutdownPolicy = iota
+	// ShutdownKeep leaves the VIPs and IPVS table in place, so that traffic
+	// continues to be forwarded until the engine is restarted.
+	ShutdownKeep
+)
+
+var shutdownPolicyNames = map[ShutdownPolicy]string{
+	ShutdownClear: "clear",
+	ShutdownKeep:  "keep",
+}
+
+// String returns the string representation of a ShutdownPolicy.
+func (p ShutdownPolicy) String() string {
+	if name, ok := shutdownPolicyNames[p]; ok {
+		return name
+	}
+	return "(unknown)"
+}
+
+// ParseShutdownPolicy returns the ShutdownPolicy with the given name.
+func ParseShutdownPolicy(name string) (ShutdownPolicy, error) {
+	for p, n := range shutdownPolicyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown shutdown policy %q", name)
 }
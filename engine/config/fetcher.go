@@ -26,6 +26,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	pb "github.com/wy2745/seesaw/pb/config"
@@ -195,3 +196,61 @@ func (f *fetcher) fetch(handler fetchHandler) (string, []byte, error) {
 func (f *fetcher) config() (string, []byte, error) {
 	return f.fetch(fetchConfig)
 }
+
+// fetchURL fetches a configuration protobuf from the configured config URL,
+// presenting the configured client certificate (if any) to the server. The
+// response may contain either a binary or a text-based protobuf.
+func fetchURL(cfg *EngineConfig) (*pb.Cluster, error) {
+	if cfg.ConfigURL == "" {
+		return nil, errors.New("no config URL")
+	}
+	certs, err := certPool(cfg.CACertFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{RootCAs: certs}
+	if cfg.ConfigURLCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ConfigURLCertFile, cfg.ConfigURLKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return errors.New("HTTP redirect prohibited")
+		},
+		Timeout: cfg.ConfigServerTimeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig:   tlsConfig,
+		},
+	}
+	resp, err := client.Get(cfg.ConfigURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received HTTP status %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pb.Cluster{}
+	switch ct := resp.Header.Get("Content-Type"); {
+	case ct == "application/x-protobuffer":
+		err = proto.Unmarshal(body, p)
+	case strings.HasPrefix(ct, "text/plain"):
+		err = proto.UnmarshalText(string(body), p)
+	default:
+		return nil, fmt.Errorf("unexpected Content-Type: %q", ct)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// This file contains functions for expanding include directives and
+// environment variable references in cluster configuration files.
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// envVarRE matches an environment variable reference of the form ${VAR} or
+// ${VAR:-default}.
+var envVarRE = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandConfig reads the given cluster configuration file and returns its
+// contents, with environment variable references substituted and the contents
+// of included files inserted in place of each include directive. An include
+// directive is a line of the form:
+//
+//	include "<path>"
+//
+// where a relative path is resolved relative to the directory containing the
+// file with the directive.
+func expandConfig(filename string) (string, error) {
+	var b strings.Builder
+	if err := expandFile(&b, filename, nil); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// expandFile writes the expanded contents of the given file to b. The files
+// that are currently being expanded are given by parents, in order to detect
+// include cycles.
+func expandFile(b *strings.Builder, filename string, parents []string) error {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	for _, parent := range parents {
+		if parent == path {
+			return fmt.Errorf("include cycle: %s", strings.Join(append(parents, path), " -> "))
+		}
+	}
+	parents = append(parents, path)
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	for i, line := range strings.SplitAfter(string(content), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			b.WriteString(line)
+			continue
+		}
+		line, err := expandEnv(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", filename, i+1, err)
+		}
+		include, ok, err := parseInclude(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", filename, i+1, err)
+		}
+		if !ok {
+			b.WriteString(line)
+			continue
+		}
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(filename), include)
+		}
+		if err := expandFile(b, include, parents); err != nil {
+			return err
+		}
+		b.WriteString("\n")
+	}
+	return nil
+}
+
+// parseInclude returns the path from an include directive, if the given line
+// contains one.
+func parseInclude(line string) (string, bool, error) {
+	f := strings.Fields(line)
+	if len(f) == 0 || f[0] != "include" {
+		return "", false, nil
+	}
+	arg := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "include"))
+	path, err := strconv.Unquote(arg)
+	if err != nil || path == "" {
+		return "", false, fmt.Errorf("invalid include directive %q", strings.TrimSpace(line))
+	}
+	return path, true, nil
+}
+
+// expandEnv substitutes the values of the environment variables that are
+// referenced in s. A reference to a variable that is not set results in an
+// error, unless a default is given, which is also used if the variable is
+// empty.
+func expandEnv(s string) (string, error) {
+	var err error
+	s = envVarRE.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envVarRE.FindStringSubmatch(ref)
+		value, ok := os.LookupEnv(m[1])
+		if m[2] != "" && value == "" {
+			return m[3]
+		}
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", m[1])
+		}
+		return value
+	})
+	return s, err
+}
@@ -38,7 +38,7 @@ type Notifier struct {
 	// Immutable fields.
 	C         <-chan Notification
 	outgoing  chan<- Notification
-	reload    chan bool
+	reload    chan chan<- reloadResult
 	shutdown  chan bool
 	engineCfg *EngineConfig
 
@@ -59,11 +59,14 @@ func NewNotifier(ec *EngineConfig) (*Notifier, error) {
 	n := &Notifier{
 		C:         outgoing,
 		outgoing:  outgoing,
-		reload:    make(chan bool, 1),
+		reload:    make(chan chan<- reloadResult, 1),
 		shutdown:  make(chan bool, 1),
 		engineCfg: ec,
 		source:    SourcePeer,
 	}
+	if ec.ConfigURL != "" {
+		n.source = SourceURL
+	}
 
 	note, err := n.bootstrap()
 	if err != nil {
@@ -74,7 +77,7 @@ func NewNotifier(ec *EngineConfig) (*Notifier, error) {
 	if note.Source != SourceDisk {
 		dNote, _ := n.pullConfig(SourceDisk)
 		if dNote == nil || !dNote.Cluster.Equal(note.Cluster) {
-			if err := saveConfig(note.protobuf, n.engineCfg.ClusterFile, true); err != nil {
+			if err := saveConfig(note.protobuf, n.engineCfg.ClusterFile, n.engineCfg.ClusterFormat, true); err != nil {
 				log.Warningf("Failed to save config to %s: %v", n.engineCfg.ClusterFile, err)
 			}
 		}
@@ -104,16 +107,36 @@ func (n *Notifier) SetSource(source Source) {
 	}
 }
 
+// reloadResult contains the outcome of a configuration reload.
+type reloadResult struct {
+	changes *seesaw.ClusterChanges
+	err     error
+}
+
 // Reload requests an immediate reload from the configuration source.
 func (n *Notifier) Reload() error {
 	select {
-	case n.reload <- true:
+	case n.reload <- nil:
 	default:
 		return errors.New("reload request already queued")
 	}
 	return nil
 }
 
+// ReloadChanges performs an immediate reload from the configuration source
+// and returns a summary of the changes that were found. The changes are
+// applied once the resulting notification is processed.
+func (n *Notifier) ReloadChanges() (*seesaw.ClusterChanges, error) {
+	result := make(chan reloadResult, 1)
+	select {
+	case n.reload <- result:
+	default:
+		return nil, errors.New("reload request already queued")
+	}
+	r := <-result
+	return r.changes, r.err
+}
+
 // Shutdown shuts down a Notifier.
 func (n *Notifier) Shutdown() {
 	n.shutdown <- true
@@ -126,16 +149,20 @@ func (n *Notifier) run() {
 		select {
 		case <-n.shutdown:
 			return
-		case <-n.reload:
-			n.configCheck()
+		case result := <-n.reload:
+			changes, err := n.configCheck()
+			if result != nil {
+				result <- reloadResult{changes, err}
+			}
 		case <-configTicker.C:
 			n.configCheck()
 		}
 	}
 }
 
-// configCheck checks for configuration changes.
-func (n *Notifier) configCheck() {
+// configCheck checks for configuration changes and returns a summary of
+// the changes that were found.
+func (n *Notifier) configCheck() (*seesaw.ClusterChanges, error) {
 	log.Infof("Checking for config changes...")
 
 	s := n.Source()
@@ -145,7 +172,7 @@ func (n *Notifier) configCheck() {
 		log.Errorf("Failed to pull configuration from peer: %v", err)
 		n.peerFailures++
 		if n.peerFailures < n.engineCfg.MaxPeerConfigSyncErrors {
-			return
+			return nil, err
 		}
 		log.Infof("Sync from peer failed %v times, falling back to config server",
 			n.engineCfg.MaxPeerConfigSyncErrors)
@@ -155,7 +182,7 @@ func (n *Notifier) configCheck() {
 	n.peerFailures = 0
 	if err != nil {
 		log.Errorf("Failed to pull configuration: %v", err)
-		return
+		return nil, err
 	}
 
 	if s != SourceDisk && s != SourcePeer {
@@ -163,13 +190,15 @@ func (n *Notifier) configCheck() {
 		newMeta := note.protobuf.Metadata
 		if oldMeta != nil && newMeta != nil && oldMeta.GetLastUpdated() > newMeta.GetLastUpdated() {
 			log.Infof("Ignoring out-of-date config from %v", note.SourceDetail)
-			return
+			return nil, fmt.Errorf("ignoring out-of-date config from %v", note.SourceDetail)
 		}
 	}
 
+	changes := DiffClusters(last.Cluster, note.Cluster)
+	changes.Source = fmt.Sprintf("%v (%v)", note.Source, note.SourceDetail)
 	if note.Cluster.Equal(last.Cluster) {
 		log.Infof("No config changes found")
-		return
+		return changes, nil
 	}
 
 	// If there's only metadata differences, note it so we can skip some processing later.
@@ -187,10 +216,11 @@ func (n *Notifier) configCheck() {
 	log.Infof("Sent config update notification")
 
 	if s != SourceDisk {
-		if err := saveConfig(note.protobuf, n.engineCfg.ClusterFile, !note.MetadataOnly); err != nil {
+		if err := saveConfig(note.protobuf, n.engineCfg.ClusterFile, n.engineCfg.ClusterFormat, !note.MetadataOnly); err != nil {
 			log.Warningf("Failed to save config to %s: %v", n.engineCfg.ClusterFile, err)
 		}
 	}
+	return changes, nil
 }
 
 func (n *Notifier) pullConfig(s Source) (*Notification, error) {
@@ -201,6 +231,8 @@ func (n *Notifier) pullConfig(s Source) (*Notification, error) {
 		return n.configFromPeer()
 	case SourceServer:
 		return n.configFromServer()
+	case SourceURL:
+		return n.configFromURL()
 	}
 	return nil, fmt.Errorf("pullConfig: Unsupported Notifier source %v", s)
 }
@@ -208,6 +240,13 @@ func (n *Notifier) pullConfig(s Source) (*Notification, error) {
 func (n *Notifier) bootstrap() (*Notification, error) {
 	var note *Notification
 	var err error
+	if n.engineCfg.ConfigURL != "" {
+		if note, err = n.pullConfig(SourceURL); err == nil {
+			return note, nil
+		}
+		log.Warningf("Failed to load cluster config from URL: %v", err)
+	}
+
 	if note, err = n.pullConfig(SourcePeer); err == nil {
 		return note, nil
 	}
@@ -227,7 +266,7 @@ func (n *Notifier) bootstrap() (*Notification, error) {
 }
 
 func (n *Notifier) configFromDisk() (*Notification, error) {
-	return ReadConfig(n.engineCfg.ClusterFile, n.engineCfg.ClusterName)
+	return ReadConfigFormat(n.engineCfg.ClusterFile, n.engineCfg.ClusterName, n.engineCfg.ClusterFormat)
 }
 
 func (n *Notifier) configFromPeer() (*Notification, error) {
@@ -254,3 +293,16 @@ func (n *Notifier) configFromServer() (*Notification, error) {
 	}
 	return &Notification{c, false, p, SourceServer, source, time.Now()}, nil
 }
+
+func (n *Notifier) configFromURL() (*Notification, error) {
+	url := n.engineCfg.ConfigURL
+	p, err := fetchURL(n.engineCfg)
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed from %v: %v", url, err)
+	}
+	c, err := protoToCluster(p, n.engineCfg.ClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration from %v: %v", url, err)
+	}
+	return &Notification{c, false, p, SourceURL, url, time.Now()}, nil
+}
@@ -0,0 +1,6 @@
+seesaw_vip <
+  fqdn: "${TEST_SEESAW_VIP:-seesaw-vip1.example.com.}"
+  ipv4: "192.168.36.1/24"
+  status: PRODUCTION
+>
+include "vservers/dns.pb"
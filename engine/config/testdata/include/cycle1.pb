@@ -0,0 +1 @@
+include "cycle2.pb"
@@ -0,0 +1 @@
+include "cycle1.pb"
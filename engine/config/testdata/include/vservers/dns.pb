@@ -0,0 +1,9 @@
+vserver <
+  name: "dns.resolver@au-syd"
+  entry_address <
+    fqdn: "dns-vip1.example.com."
+    ipv4: "192.168.36.10/24"
+    status: PRODUCTION
+  >
+  rp: "${TEST_SEESAW_RP}"
+>
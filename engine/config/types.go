@@ -19,12 +19,15 @@ package config
 // This file contains types used for Seesaw Engine configuration.
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/quagga"
 )
 
 // Cluster represents the configuration for a load balancing cluster.
@@ -51,6 +54,7 @@ func NewCluster(site string) *Cluster {
 		Vservers:   make(map[string]*Vserver),
 		VLANs:      make(map[uint16]*seesaw.VLAN),
 		Status: seesaw.ConfigStatus{
+			Errors:     make([]string, 0),
 			Warnings:   make([]string, 0),
 			Attributes: make([]seesaw.ConfigMetadata, 0),
 		},
@@ -123,6 +127,62 @@ type Vserver struct {
 	Enabled      bool
 	UseFWM       bool
 	Warnings     []string
+
+	// SlowStartDuration is the time over which the weight of a backend
+	// that becomes healthy is ramped up to its configured weight.
+	SlowStartDuration time.Duration
+
+	// DrainTimeout is the maximum time to wait for active connections to
+	// drain when the vserver is removed.
+	DrainTimeout time.Duration
+
+	// Quiescent specifies that unhealthy backends are given a weight of
+	// zero, rather than being removed from IPVS.
+	Quiescent bool
+
+	// OnAllDown is the action to take when all backends for a service are
+	// down.
+	OnAllDown OnAllDown
+
+	// FallbackBackend is the backend that is used when all other backends
+	// for a service are down, if OnAllDown is OnAllDownFallback.
+	FallbackBackend *seesaw.Backend
+
+	// AdditionalVIPs are the addresses that are served by the vserver in
+	// addition to those of its Host.
+	AdditionalVIPs []net.IP
+
+	// BGPCommunities are the BGP communities that the routes for the
+	// vserver's anycast VIPs are tagged with.
+	BGPCommunities []string
+}
+
+// OnAllDown specifies the action to take when all backends for a service are
+// down.
+type OnAllDown int
+
+const (
+	// OnAllDownBlackhole leaves the VIP configured without any backends.
+	OnAllDownBlackhole OnAllDown = iota
+	// OnAllDownWithdraw removes unicast VIPs from the load balancing
+	// interface while they have no healthy services.
+	OnAllDownWithdraw
+	// OnAllDownFallback sends connections to the fallback backend.
+	OnAllDownFallback
+)
+
+var onAllDownNames = map[OnAllDown]string{
+	OnAllDownBlackhole: "blackhole",
+	OnAllDownWithdraw:  "withdraw",
+	OnAllDownFallback:  "fallback",
+}
+
+// String returns the string representation of an OnAllDown action.
+func (a OnAllDown) String() string {
+	if name, ok := onAllDownNames[a]; ok {
+		return name
+	}
+	return "(unknown)"
 }
 
 // NewVserver creates a new, initialised Vserver structure.
@@ -143,12 +203,63 @@ func (v *Vserver) Key() string {
 	return v.Name
 }
 
+// Addresses returns the addresses served by a Vserver, being those of its Host
+// followed by its additional VIPs.
+func (v *Vserver) Addresses() []net.IP {
+	ips := make([]net.IP, 0, 2+len(v.AdditionalVIPs))
+	for _, ip := range []net.IP{v.IPv4Addr, v.IPv6Addr} {
+		if ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return append(ips, v.AdditionalVIPs...)
+}
+
+// AddAdditionalVIP adds an additional VIP to a Vserver.
+func (v *Vserver) AddAdditionalVIP(ip net.IP) error {
+	if v.UseFWM {
+		return fmt.Errorf("Vserver %q uses firewall marks and cannot have additional VIPs", v.Name)
+	}
+	for _, addr := range v.Addresses() {
+		if addr.Equal(ip) {
+			return fmt.Errorf("Vserver %q already has address %v", v.Name, ip)
+		}
+	}
+	v.AdditionalVIPs = append(v.AdditionalVIPs, ip)
+	return nil
+}
+
+// AddBGPCommunity adds a BGP community to a Vserver.
+func (v *Vserver) AddBGPCommunity(community string) error {
+	if err := quagga.ValidateCommunities([]string{community}); err != nil {
+		return fmt.Errorf("Vserver %q: %v", v.Name, err)
+	}
+	for _, c := range v.BGPCommunities {
+		if c == community {
+			return fmt.Errorf("Vserver %q already has BGP community %q", v.Name, community)
+		}
+	}
+	v.BGPCommunities = append(v.BGPCommunities, community)
+	return nil
+}
+
 // AddVserverEntry adds an VserverEntry to a Vserver.
 func (v *Vserver) AddVserverEntry(e *VserverEntry) error {
 	key := e.Key()
 	if _, ok := v.Entries[key]; ok {
 		return fmt.Errorf("Vserver %q already contains VserverEntry %q", v.Name, key)
 	}
+	// A firewall mark based vserver has a single IPVS service for all of
+	// its entries, hence the entries must agree on the service settings.
+	if v.UseFWM {
+		for otherKey, other := range v.Entries {
+			if !e.fwmEqual(other) {
+				return fmt.Errorf("Vserver %q uses firewall marks but VserverEntry %q has different service settings to VserverEntry %q",
+					v.Name, key, otherKey)
+			}
+			break
+		}
+	}
 	v.Entries[key] = e
 	return nil
 }
@@ -156,6 +267,9 @@ func (v *Vserver) AddVserverEntry(e *VserverEntry) error {
 // AddBackend adds a Backend to a Vserver.
 func (v *Vserver) AddBackend(backend *seesaw.Backend) error {
 	key := backend.Key()
+	if err := validateThresholds(backend); err != nil {
+		return fmt.Errorf("Vserver %q Backend %q: %v", v.Name, key, err)
+	}
 	if _, ok := v.Backends[key]; ok {
 		return fmt.Errorf("Vserver %q already contains Backend %q", v.Name, key)
 	}
@@ -163,9 +277,44 @@ func (v *Vserver) AddBackend(backend *seesaw.Backend) error {
 	return nil
 }
 
+// SetFallbackBackend sets the fallback backend for a Vserver. The fallback
+// backend must not share an address with any of the other backends.
+func (v *Vserver) SetFallbackBackend(backend *seesaw.Backend) error {
+	key := backend.Key()
+	if err := validateThresholds(backend); err != nil {
+		return fmt.Errorf("Vserver %q fallback Backend %q: %v", v.Name, key, err)
+	}
+	for _, b := range v.Backends {
+		for _, ip := range []net.IP{backend.IPv4Addr, backend.IPv6Addr} {
+			if ip != nil && (ip.Equal(b.IPv4Addr) || ip.Equal(b.IPv6Addr)) {
+				return fmt.Errorf("Vserver %q fallback Backend %q has the same address as Backend %q", v.Name, key, b.Key())
+			}
+		}
+	}
+	v.FallbackBackend = backend
+	return nil
+}
+
+// validateThresholds checks that the connection thresholds for a backend are
+// valid. The lower threshold may only be set along with a larger upper
+// threshold.
+func validateThresholds(b *seesaw.Backend) error {
+	switch {
+	case b.UpperThreshold < 0 || b.LowerThreshold < 0:
+		return fmt.Errorf("negative connection threshold")
+	case b.LowerThreshold > 0 && b.LowerThreshold >= b.UpperThreshold:
+		return fmt.Errorf("lower threshold %d is not less than upper threshold %d",
+			b.LowerThreshold, b.UpperThreshold)
+	}
+	return nil
+}
+
 // AddHealthcheck adds a Healthcheck to a Vserver.
 func (v *Vserver) AddHealthcheck(h *Healthcheck) error {
 	key := h.Key()
+	if err := h.validate(); err != nil {
+		return fmt.Errorf("Vserver %q Healthcheck %q: %v", v.Name, key, err)
+	}
 	if _, ok := v.Healthchecks[key]; ok {
 		return fmt.Errorf("Vserver %q already contains Healthcheck %q", v.Name, key)
 	}
@@ -196,7 +345,15 @@ type VserverEntry struct {
 	LowWatermark  float32
 	LThreshold    int
 	UThreshold    int
+	SchedFallback bool
+	SchedPort     bool
 	Healthchecks  map[string]*Healthcheck // by Healthcheck.Key()
+
+	// PersistencePrefixIPv4 and PersistencePrefixIPv6 are the prefix
+	// lengths used to group client addresses for persistence. Zero means
+	// that each client address is treated individually.
+	PersistencePrefixIPv4 int
+	PersistencePrefixIPv6 int
 }
 
 // NewVserverEntry creates a new, initialised VserverEntry structure.
@@ -211,6 +368,9 @@ func NewVserverEntry(port uint16, proto seesaw.IPProto) *VserverEntry {
 // AddHealthcheck adds a Healthcheck to a VserverEntry.
 func (v *VserverEntry) AddHealthcheck(h *Healthcheck) error {
 	key := h.Key()
+	if err := h.validate(); err != nil {
+		return fmt.Errorf("VserverEntry %q Healthcheck %q: %v", v.Key(), key, err)
+	}
 	if _, ok := v.Healthchecks[key]; ok {
 		return fmt.Errorf("VserverEntry %q already contains Healthcheck %q", v.Key(), key)
 	}
@@ -218,6 +378,24 @@ func (v *VserverEntry) AddHealthcheck(h *Healthcheck) error {
 	return nil
 }
 
+// fwmEqual returns true if the VserverEntry has the same IPVS service
+// settings as another VserverEntry, such that both can be served by the same
+// firewall mark based service.
+func (v *VserverEntry) fwmEqual(other *VserverEntry) bool {
+	return v.Scheduler == other.Scheduler &&
+		v.Mode == other.Mode &&
+		v.Persistence == other.Persistence &&
+		v.OnePacket == other.OnePacket &&
+		v.HighWatermark == other.HighWatermark &&
+		v.LowWatermark == other.LowWatermark &&
+		v.LThreshold == other.LThreshold &&
+		v.UThreshold == other.UThreshold &&
+		v.SchedFallback == other.SchedFallback &&
+		v.SchedPort == other.SchedPort &&
+		v.PersistencePrefixIPv4 == other.PersistencePrefixIPv4 &&
+		v.PersistencePrefixIPv6 == other.PersistencePrefixIPv6
+}
+
 // Key returns the unique identifier for a VserverEntry.
 func (v *VserverEntry) Key() string {
 	return fmt.Sprintf("%d/%s", v.Port, v.Proto)
@@ -236,6 +414,11 @@ func (v *VserverEntry) Snapshot() *seesaw.VserverEntry {
 		LowWatermark:  v.LowWatermark,
 		LThreshold:    v.LThreshold,
 		UThreshold:    v.UThreshold,
+		SchedFallback: v.SchedFallback,
+		SchedPort:     v.SchedPort,
+
+		PersistencePrefixIPv4: v.PersistencePrefixIPv4,
+		PersistencePrefixIPv6: v.PersistencePrefixIPv6,
 	}
 }
 
@@ -255,6 +438,22 @@ type Healthcheck struct {
 	Proxy     bool          // Perform healthchecks against an HTTP proxy.
 	Method    string        // The request method for an HTTP/S healthcheck.
 	TLSVerify bool          // Do TLS verification.
+
+	// Latency above which a successful healthcheck is logged as slow.
+	SlowThreshold time.Duration
+
+	// Require that a MySQL backend is not read-only.
+	ExpectWritable bool
+
+	// The local IP address and network interface to send healthchecks from.
+	SourceIP        string
+	SourceInterface string
+
+	// The request body for an HTTP/S POST healthcheck.
+	Body string
+
+	// The time allowed for establishing a connection to the backend.
+	ConnectTimeout time.Duration
 }
 
 // NewHealthcheck creates a new, initialised Healthcheck structure.
@@ -271,6 +470,39 @@ func (h *Healthcheck) Key() string {
 	return h.Name
 }
 
+// httpMethods contains the request methods that are supported for HTTP/S
+// healthchecks.
+var httpMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"POST":    true,
+}
+
+// validate checks that the Healthcheck configuration is usable.
+func (h *Healthcheck) validate() error {
+	if h.ConnectTimeout < 0 || (h.Timeout > 0 && h.ConnectTimeout > h.Timeout) {
+		return fmt.Errorf("connect timeout %v exceeds timeout %v", h.ConnectTimeout, h.Timeout)
+	}
+	if h.Type != seesaw.HCTypeHTTP && h.Type != seesaw.HCTypeHTTPS {
+		return nil
+	}
+	method := strings.ToUpper(h.Method)
+	if method == "" {
+		method = "GET"
+	}
+	if !httpMethods[method] {
+		return fmt.Errorf("unsupported HTTP method %q", h.Method)
+	}
+	if method == "HEAD" && h.Receive != "" {
+		return errors.New("HEAD requests have no response body to match against")
+	}
+	if method != "POST" && h.Body != "" {
+		return fmt.Errorf("a request body cannot be sent with %s requests", method)
+	}
+	return nil
+}
+
 // Healthchecks is a list of Healthchecks.
 type Healthchecks []*Healthcheck
 
@@ -297,6 +529,10 @@ func (h Healthchecks) Less(i, j int) bool {
 		return h[i].Method < h[j].Method
 	}
 
+	if h[i].Body != h[j].Body {
+		return h[i].Body < h[j].Body
+	}
+
 	if h[i].Code != h[j].Code {
 		return h[i].Code < h[j].Code
 	}
@@ -319,5 +555,9 @@ func (h Healthchecks) Less(i, j int) bool {
 		return h[i].Timeout < h[j].Timeout
 	}
 
+	if h[i].ConnectTimeout != h[j].ConnectTimeout {
+		return h[i].ConnectTimeout < h[j].ConnectTimeout
+	}
+
 	return false
 }
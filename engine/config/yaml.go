@@ -0,0 +1,80 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// This file contains functions for converting cluster configurations between
+// YAML and protobufs. The YAML structure mirrors the protobuf, using the
+// protobuf field names and enum value names, for example:
+//
+//	seesaw_vip:
+//	  fqdn: seesaw-vip1.example.com.
+//	  ipv4: 192.168.36.1/24
+//	  status: PRODUCTION
+//	vserver:
+//	- name: dns.resolver@au-syd
+//	  vserver_entry:
+//	  - protocol: UDP
+//	    port: 53
+//	    scheduler: WRR
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/wy2745/seesaw/pb/config"
+
+	"github.com/golang/protobuf/jsonpb"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlToProto converts a YAML cluster configuration to a protobuf. Fields that
+// do not exist in the protobuf result in an error.
+func yamlToProto(text string, p *pb.Cluster) error {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(text), &v); err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("invalid YAML config: %v", err)
+	}
+	return jsonpb.Unmarshal(bytes.NewReader(b), p)
+}
+
+// protoToYAML converts a cluster configuration protobuf to YAML.
+func protoToYAML(p *pb.Cluster) ([]byte, error) {
+	m := &jsonpb.Marshaler{OrigName: true}
+	s, err := m.MarshalToString(p)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, hence parse it as YAML in order to preserve the
+	// field order, then reset the style so that it is output in block form.
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(s), &node); err != nil {
+		return nil, err
+	}
+	resetStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// resetStyle resets the style of a YAML node and its children.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		resetStyle(n)
+	}
+}
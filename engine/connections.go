@@ -0,0 +1,185 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+// This file contains functions for reading entries from the IPVS connection
+// table.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+
+	log "github.com/golang/glog"
+)
+
+// maxConnEntries is the maximum number of connection table entries that are
+// returned for a single request.
+const maxConnEntries = 1000
+
+var ipvsConnFile = "/proc/net/ip_vs_conn"
+
+// parseConnAddr parses an address from the IPVS connection table. IPv4
+// addresses are given in hexadecimal, while IPv6 addresses are given in their
+// standard form.
+func parseConnAddr(s string) (net.IP, error) {
+	if len(s) == 2*net.IPv4len {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IPv4 address %q", s)
+		}
+		return net.IP(b).To16(), nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IPv6 address %q", s)
+	}
+	return ip, nil
+}
+
+// parseConnPort parses a hexadecimal port from the IPVS connection table.
+func parseConnPort(s string) (uint16, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 2 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// parseConnEntry parses a line from the IPVS connection table, which is of
+// the form:
+//
+//	Pro FromIP   FPrt ToIP     TPrt DestIP   DPrt State       Expires PEName PEData
+//	TCP C0A82402 D431 C0A8FF01 0050 C0A82403 0050 ESTABLISHED     899
+func parseConnEntry(line string) (*seesaw.ConnEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 9 {
+		return nil, fmt.Errorf("too few fields in %q", line)
+	}
+	ce := &seesaw.ConnEntry{State: fields[7]}
+	switch fields[0] {
+	case "TCP":
+		ce.Proto = seesaw.IPProtoTCP
+	case "UDP":
+		ce.Proto = seesaw.IPProtoUDP
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", fields[0])
+	}
+	var err error
+	for i, addr := range []struct {
+		ip   *net.IP
+		port *uint16
+	}{
+		{&ce.ClientIP, &ce.ClientPort},
+		{&ce.VIP, &ce.VIPPort},
+		{&ce.BackendIP, &ce.BackendPort},
+	} {
+		if *addr.ip, err = parseConnAddr(fields[1+2*i]); err != nil {
+			return nil, err
+		}
+		if *addr.port, err = parseConnPort(fields[2+2*i]); err != nil {
+			return nil, err
+		}
+	}
+	expires, err := strconv.ParseUint(fields[8], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry %q", fields[8])
+	}
+	ce.Expires = time.Duration(expires) * time.Second
+	return ce, nil
+}
+
+// connections returns the entries from the IPVS connection table that match
+// the given filter.
+func (e *Engine) connections(filter *seesaw.ConnFilter) (*seesaw.ConnEntries, error) {
+	var vips []net.IP
+	if filter.VIP != nil {
+		vips = append(vips, filter.VIP)
+	}
+	if filter.Vserver != "" {
+		e.vserverLock.RLock()
+		vserver, ok := e.vserverSnapshots[filter.Vserver]
+		e.vserverLock.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("vserver %q not found", filter.Vserver)
+		}
+		for _, ip := range []net.IP{vserver.IPv4Addr, vserver.IPv6Addr} {
+			if ip != nil {
+				vips = append(vips, ip)
+			}
+		}
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > maxConnEntries {
+		limit = maxConnEntries
+	}
+
+	f, err := os.Open(ipvsConnFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ce := &seesaw.ConnEntries{Entries: make([]seesaw.ConnEntry, 0)}
+	scanner := bufio.NewScanner(f)
+	for first := true; scanner.Scan(); first = false {
+		// The first line is a header.
+		if first {
+			continue
+		}
+		entry, err := parseConnEntry(scanner.Text())
+		if err != nil {
+			log.V(1).Infof("Skipping IPVS connection entry: %v", err)
+			continue
+		}
+		if !connMatches(entry, vips, filter.Backend) {
+			continue
+		}
+		if ce.Total >= filter.Offset && len(ce.Entries) < limit {
+			ce.Entries = append(ce.Entries, *entry)
+		}
+		ce.Total++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ce, nil
+}
+
+// connMatches returns true if the connection table entry is for one of the
+// given VIPs and the given backend. A nil VIP list or a nil backend matches
+// all entries.
+func connMatches(entry *seesaw.ConnEntry, vips []net.IP, backend net.IP) bool {
+	if backend != nil && !backend.Equal(entry.BackendIP) {
+		return false
+	}
+	if len(vips) == 0 {
+		return true
+	}
+	for _, vip := range vips {
+		if vip.Equal(entry.VIP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+const testConnTable = `Pro FromIP   FPrt ToIP     TPrt DestIP   DPrt State       Expires PEName PEData
+TCP C0A82402 D431 C0A8FF01 0050 C0A82403 0050 ESTABLISHED     899
+TCP C0A82405 D432 C0A8FF01 0050 C0A82404 0050 TIME_WAIT        60
+UDP C0A82406 1F90 C0A8FF02 0035 C0A82403 0035 UDP             300
+TCP 2015:cafe:0036:0000:0000:0000:0000:0002 D433 2015:cafe:00ff:0000:0000:0000:0000:0001 0050 2015:cafe:0036:0000:0000:0000:0000:0003 0050 SYN_RECV 30
+SCTP C0A82407 1F90 C0A8FF02 0035 C0A82403 0035 ESTABLISHED     10
+`
+
+func TestParseConnEntry(t *testing.T) {
+	ce, err := parseConnEntry("TCP C0A82402 D431 C0A8FF01 0050 C0A82403 0050 ESTABLISHED     899")
+	if err != nil {
+		t.Fatalf("parseConnEntry failed: %v", err)
+	}
+	want := &seesaw.ConnEntry{
+		Proto:       seesaw.IPProtoTCP,
+		ClientIP:    net.ParseIP("192.168.36.2"),
+		ClientPort:  0xd431,
+		VIP:         net.ParseIP("192.168.255.1"),
+		VIPPort:     80,
+		BackendIP:   net.ParseIP("192.168.36.3"),
+		BackendPort: 80,
+		State:       "ESTABLISHED",
+		Expires:     899 * time.Second,
+	}
+	if !ce.ClientIP.Equal(want.ClientIP) || !ce.VIP.Equal(want.VIP) || !ce.BackendIP.Equal(want.BackendIP) {
+		t.Errorf("parseConnEntry got addresses %v, %v, %v, want %v, %v, %v",
+			ce.ClientIP, ce.VIP, ce.BackendIP, want.ClientIP, want.VIP, want.BackendIP)
+	}
+	if ce.Proto != want.Proto || ce.ClientPort != want.ClientPort || ce.VIPPort != want.VIPPort ||
+		ce.BackendPort != want.BackendPort || ce.State != want.State || ce.Expires != want.Expires {
+		t.Errorf("parseConnEntry got %+v, want %+v", ce, want)
+	}
+
+	for _, line := range []string{
+		"TCP C0A82402 D431 C0A8FF01",
+		"TCP C0A8240 D431 C0A8FF01 0050 C0A82403 0050 ESTABLISHED 899",
+		"TCP C0A82402 D4 C0A8FF01 0050 C0A82403 0050 ESTABLISHED 899",
+		"TCP C0A82402 D431 C0A8FF01 0050 C0A82403 0050 ESTABLISHED soon",
+	} {
+		if _, err := parseConnEntry(line); err == nil {
+			t.Errorf("parseConnEntry(%q) succeeded, want error", line)
+		}
+	}
+}
+
+func TestConnections(t *testing.T) {
+	f, err := ioutil.TempFile("", "ip_vs_conn")
+	if err != nil {
+		t.Fatalf("TempFile failed: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(testConnTable); err != nil {
+		t.Fatalf("WriteString failed: %v", err)
+	}
+	f.Close()
+
+	oldConnFile := ipvsConnFile
+	ipvsConnFile = f.Name()
+	defer func() { ipvsConnFile = oldConnFile }()
+
+	e := newTestEngine()
+	e.vserverSnapshots["web"] = &seesaw.Vserver{
+		Name: "web",
+		Host: seesaw.Host{
+			IPv4Addr: net.ParseIP("192.168.255.1"),
+			IPv6Addr: net.ParseIP("2015:cafe:ff::1"),
+		},
+	}
+
+	tests := []struct {
+		desc    string
+		filter  seesaw.ConnFilter
+		total   int
+		entries int
+	}{
+		{"all", seesaw.ConnFilter{}, 4, 4},
+		{"vserver", seesaw.ConnFilter{Vserver: "web"}, 3, 3},
+		{"VIP", seesaw.ConnFilter{VIP: net.ParseIP("192.168.255.2")}, 1, 1},
+		{"backend", seesaw.ConnFilter{Backend: net.ParseIP("192.168.36.3")}, 2, 2},
+		{"page", seesaw.ConnFilter{Offset: 1, Limit: 2}, 4, 2},
+		{"last page", seesaw.ConnFilter{Offset: 3, Limit: 2}, 4, 1},
+	}
+	for _, test := range tests {
+		ce, err := e.connections(&test.filter)
+		if err != nil {
+			t.Errorf("Test %q: connections failed: %v", test.desc, err)
+			continue
+		}
+		if ce.Total != test.total || len(ce.Entries) != test.entries {
+			t.Errorf("Test %q: got %d of %d entries, want %d of %d",
+				test.desc, len(ce.Entries), ce.Total, test.entries, test.total)
+		}
+	}
+
+	if _, err := e.connections(&seesaw.ConnFilter{Vserver: "unknown"}); err == nil {
+		t.Errorf("connections for unknown vserver succeeded, want error")
+	}
+}
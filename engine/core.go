@@ -25,14 +25,15 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"net/rpc"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/wy2745/seesaw/common/metrics"
 	"github.com/wy2745/seesaw/common/seesaw"
 	"github.com/wy2745/seesaw/common/server"
 	"github.com/wy2745/seesaw/engine/config"
+	"github.com/wy2745/seesaw/ipvs"
 	ncclient "github.com/wy2745/seesaw/ncc/client"
 	ncctypes "github.com/wy2745/seesaw/ncc/types"
 
@@ -42,12 +43,17 @@ import (
 const (
 	fwmAllocBase = 1 << 8
 	fwmAllocSize = 8000
+
+	// overrideExpiryInterval is the interval at which overrides are checked
+	// for expiry.
+	overrideExpiryInterval = 10 * time.Second
 )
 
 // Engine contains the data necessary to run the Seesaw v2 Engine.
 type Engine struct {
 	config   *config.EngineConfig
 	notifier *config.Notifier
+	audit    *auditLog
 
 	fwmAlloc *markAllocator
 
@@ -57,9 +63,12 @@ type Engine struct {
 
 	ncc         ncclient.NCC
 	lbInterface ncclient.LBInterface
+	lbUp        bool // whether the LB interface has been brought up
 
-	cluster     *config.Cluster
-	clusterLock sync.RWMutex
+	cluster          *config.Cluster
+	clusterText      string // cluster configuration in protobuf text format
+	configGeneration uint64 // number of cluster configurations received
+	clusterLock      sync.RWMutex
 
 	shutdown    chan bool
 	shutdownARP chan bool
@@ -72,14 +81,30 @@ type Engine struct {
 	overrides    map[string]seesaw.Override
 	overrideChan chan seesaw.Override
 
+	maintenance     seesaw.MaintenanceStatus
+	maintenanceLock sync.RWMutex
+	maintenanceChan chan *maintenanceRequest
+
 	vlans    map[uint16]*seesaw.VLAN
 	vlanLock sync.RWMutex
 
-	vservers map[string]*vserver
+	vservers       map[string]*vserver
+	vserverConfigs map[string]*config.Vserver
+
+	// drainingVservers contains vservers that have been removed from the
+	// cluster configuration and are draining active connections.
+	drainingVservers map[string]*vserver
+
+	// unsupportedSchedulers contains the IPVS schedulers that are not
+	// supported by the running kernel.
+	unsupportedSchedulers map[seesaw.LBScheduler]bool
 
 	vserverSnapshots map[string]*seesaw.Vserver
 	vserverLock      sync.RWMutex
 	vserverChan      chan *seesaw.Vserver
+
+	ipvsBatches   ipvsBatchStats
+	ipvsBatchLock sync.Mutex
 }
 
 // NewEngine returns an initialised Engine struct.
@@ -97,9 +122,14 @@ func NewEngine(cfg *config.EngineConfig) *Engine {
 		overrides:    make(map[string]seesaw.Override),
 		overrideChan: make(chan seesaw.Override),
 
+		maintenanceChan: make(chan *maintenanceRequest),
+
 		vlans:    make(map[uint16]*seesaw.VLAN),
 		vservers: make(map[string]*vserver),
 
+		vserverConfigs:   make(map[string]*config.Vserver),
+		drainingVservers: make(map[string]*vserver),
+
 		shutdown:    make(chan bool),
 		shutdownARP: make(chan bool),
 		shutdownIPC: make(chan bool),
@@ -120,7 +150,16 @@ func NewEngine(cfg *config.EngineConfig) *Engine {
 func (e *Engine) Run() {
 	log.Infof("Seesaw Engine starting for %s", e.config.ClusterName)
 
+	if e.config.AuditLog != "" {
+		a, err := newAuditLog(e.config.AuditLog)
+		if err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+		e.audit = a
+	}
+
 	e.initNetwork()
+	e.initSchedulers()
 
 	n, err := config.NewNotifier(e.config)
 	if err != nil {
@@ -128,7 +167,7 @@ func (e *Engine) Run() {
 	}
 	e.notifier = n
 
-	if e.config.AnycastEnabled {
+	if e.config.AnycastEnabled && e.config.RoutingProtocol == config.RoutingBGP {
 		go e.bgpManager.run()
 	}
 	go e.hcManager.run()
@@ -139,6 +178,9 @@ func (e *Engine) Run() {
 	go e.syncRPC()
 	go e.engineIPC()
 	go e.gratuitousARP()
+	if e.config.MetricsAddress != "" {
+		go metrics.Serve(e.config.MetricsAddress, e.exportMetrics)
+	}
 
 	e.manager()
 }
@@ -165,6 +207,12 @@ func (e *Engine) setHAState(state seesaw.HAState) {
 	e.haManager.stateChan <- state
 }
 
+// setHAGroupState tells the engine what the current HA state is for an
+// additional VRRP group.
+func (e *Engine) setHAGroupState(vrid uint8, state seesaw.HAState) {
+	e.haManager.groupChan <- haGroupState{vrid, state}
+}
+
 // setHAStatus tells the engine what the current HA status is.
 func (e *Engine) setHAStatus(status seesaw.HAStatus) {
 	e.haManager.statusChan <- status
@@ -177,13 +225,31 @@ func (e *Engine) haConfig() (*seesaw.HAConfig, error) {
 		return nil, err
 	}
 	// TODO(jsing): This does not allow for IPv6-only operation.
-	return &seesaw.HAConfig{
-		Enabled:    n.State != seesaw.HADisabled,
+	// HA peering is disabled while in maintenance, which stops the HA
+	// component from contending for mastership.
+	hac := &seesaw.HAConfig{
+		Enabled:    n.State != seesaw.HADisabled && !e.inMaintenance(),
 		LocalAddr:  e.config.Node.IPv4Addr,
 		RemoteAddr: e.config.VRRPDestIP,
 		Priority:   n.Priority,
 		VRID:       e.config.VRID,
-	}, nil
+
+		HeartbeatInterface: e.config.HeartbeatInterface,
+		HeartbeatSecret:    e.config.HeartbeatSecret,
+	}
+	for _, g := range e.config.HAGroups {
+		hac.Groups = append(hac.Groups, seesaw.HAGroup{VRID: g.VRID, Priority: g.Priority})
+	}
+	for _, t := range e.config.HATrackers {
+		hac.Trackers = append(hac.Trackers, seesaw.HATracker{
+			Name:      t.Name,
+			Interface: t.Interface,
+			Script:    t.Script,
+			Vservers:  t.Vservers,
+			Weight:    t.Weight,
+		})
+	}
+	return hac, nil
 }
 
 // thisNode returns the Node for the machine on which this engine is running.
@@ -216,9 +282,7 @@ func (e *Engine) engineIPC() {
 	}
 	defer os.Remove(e.config.SocketPath)
 
-	seesawIPC := rpc.NewServer()
-	seesawIPC.Register(&SeesawEngine{e})
-	go server.RPCAccept(ln, seesawIPC)
+	go server.Accept(ln, e.serveIPC)
 
 	<-e.shutdownIPC
 	ln.Close()
@@ -252,8 +316,8 @@ func (e *Engine) initNetwork() {
 	defer e.ncc.Close()
 
 	if e.config.AnycastEnabled {
-		if err := e.ncc.BGPWithdrawAll(); err != nil {
-			log.Fatalf("Failed to withdraw all BGP advertisements: %v", err)
+		if err := e.withdrawAllRoutes(); err != nil {
+			log.Fatalf("Failed to withdraw all %v advertisements: %v", e.config.RoutingProtocol, err)
 		}
 	}
 	if err := e.ncc.IPVSFlush(); err != nil {
@@ -263,6 +327,8 @@ func (e *Engine) initNetwork() {
 	lbCfg := &ncctypes.LBConfig{
 		ClusterVIP:     e.config.ClusterVIP,
 		DummyInterface: e.config.DummyInterface,
+		GARPCount:      e.config.GARPCount,
+		GARPInterval:   e.config.GARPInterval,
 		NodeInterface:  e.config.NodeInterface,
 		Node:           e.config.Node,
 		RoutingTableID: e.config.RoutingTableID,
@@ -279,13 +345,8 @@ func (e *Engine) initNetwork() {
 	}
 }
 
-// initAnycast initialises the anycast configuration.
-func (e *Engine) initAnycast() {
-	if err := e.ncc.Dial(); err != nil {
-		log.Fatalf("Failed to connect to NCC: %v", err)
-	}
-	defer e.ncc.Close()
-
+// anycastVIPs returns the service anycast VIPs that are always advertised.
+func (e *Engine) anycastVIPs() []*seesaw.VIP {
 	vips := make([]*seesaw.VIP, 0)
 	if e.config.ClusterVIP.IPv4Addr != nil {
 		for _, ip := range e.config.ServiceAnycastIPv4 {
@@ -297,12 +358,22 @@ func (e *Engine) initAnycast() {
 			vips = append(vips, seesaw.NewVIP(ip, nil))
 		}
 	}
-	for _, vip := range vips {
+	return vips
+}
+
+// initAnycast initialises the anycast configuration.
+func (e *Engine) initAnycast() {
+	if err := e.ncc.Dial(); err != nil {
+		log.Fatalf("Failed to connect to NCC: %v", err)
+	}
+	defer e.ncc.Close()
+
+	for _, vip := range e.anycastVIPs() {
 		if err := e.lbInterface.AddVIP(vip); err != nil {
 			log.Fatalf("Failed to add VIP %v: %v", vip, err)
 		}
-		log.Infof("Advertising BGP route for %v", vip)
-		if err := e.ncc.BGPAdvertiseVIP(vip.IP.IP()); err != nil {
+		log.Infof("Advertising %v route for %v", e.config.RoutingProtocol, vip)
+		if err := e.advertiseRoute(vip.IP.IP(), nil); err != nil {
 			log.Fatalf("Failed to advertise VIP %v: %v", vip, err)
 		}
 	}
@@ -346,6 +417,8 @@ func (e *Engine) gratuitousARP() {
 // manager is responsible for managing and co-ordinating various parts of the
 // seesaw engine.
 func (e *Engine) manager() {
+	expiryTicker := time.NewTicker(overrideExpiryInterval)
+	defer expiryTicker.Stop()
 	for {
 		select {
 		case n := <-e.notifier.C:
@@ -355,51 +428,50 @@ func (e *Engine) manager() {
 
 			e.clusterLock.Lock()
 			e.cluster = n.Cluster
+			e.clusterText = n.Text()
+			e.configGeneration++
 			e.clusterLock.Unlock()
+			e.audit.record(auditActorEngine, auditConfigUpdate, n.Source.String(), nil, n.String())
 
 			if n.MetadataOnly {
 				log.Infof("Only metadata changes found, processing complete.")
 				continue
 			}
 
-			if ha, err := e.haConfig(); err != nil {
-				log.Errorf("Manager failed to determine haConfig: %v", err)
-			} else if ha.Enabled {
-				e.haManager.enable()
-			} else {
-				e.haManager.disable()
-			}
-
-			node, err := e.thisNode()
-			if err != nil {
-				log.Errorf("Manager failed to identify local node: %v", err)
+			if e.inMaintenance() {
+				log.Infof("In maintenance, deferring processing of cluster config.")
 				continue
 			}
-			if !node.VserversEnabled {
-				e.shutdownVservers()
-				e.deleteVLANs()
-				continue
-			}
-
-			// Process new cluster configuration.
-			e.updateVLANs()
-
-			// TODO(jsing): Ensure this does not block.
-			e.updateVservers()
+			e.applyConfig()
 
 		case state := <-e.haManager.stateChan:
 			log.Infof("Received HA state notification %v", state)
 			e.haManager.setState(state)
+			e.updateMaintenance()
 
 		case status := <-e.haManager.statusChan:
 			log.Infof("Received HA status notification (%v)", status.State)
 			e.haManager.setStatus(status)
+			e.updateMaintenance()
+
+		case gs := <-e.haManager.groupChan:
+			log.Infof("Received HA state notification %v for VRID %d", gs.state, gs.vrid)
+			e.haManager.setGroupState(gs.vrid, gs.state)
 
 		case <-e.haManager.timer():
 			log.Infof("Timed out waiting for HAState")
 			e.haManager.setState(seesaw.HAUnknown)
+			e.updateMaintenance()
+
+		case req := <-e.maintenanceChan:
+			e.setMaintenance(req.enable)
+			close(req.done)
 
 		case svs := <-e.vserverChan:
+			if _, ok := e.drainingVservers[svs.Name]; ok {
+				e.updateDrain(svs)
+				break
+			}
 			if _, ok := e.vservers[svs.Name]; !ok {
 				log.Infof("Received vserver snapshot for unconfigured vserver %s, ignoring", svs.Name)
 				break
@@ -418,12 +490,28 @@ func (e *Engine) manager() {
 				sn.DestinationOverride = o
 			case *seesaw.VserverOverride:
 				sn.VserverOverride = o
+			case *seesaw.HealthcheckOverride:
+				// Healthcheck IDs are local to this node.
+				sn = nil
+			}
+			if sn != nil {
+				e.syncServer.notify(sn)
 			}
-			e.syncServer.notify(sn)
 			e.handleOverride(override)
 
+		case <-expiryTicker.C:
+			e.expireOverrides(time.Now())
+
 		case <-e.shutdown:
-			log.Info("Shutting down engine...")
+			log.Infof("Shutting down engine (%v shutdown policy)...", e.config.ShutdownPolicy)
+			clearState := e.config.ShutdownPolicy == config.ShutdownClear
+
+			// Let the peer take over before the load balancing state
+			// is removed. This needs the IPC server, which receives
+			// state updates from the HA component.
+			if clearState {
+				e.releaseMastership(e.config.HAStateTimeout)
+			}
 
 			// Tell other components to shutdown and then wait for
 			// them to do so.
@@ -433,9 +521,28 @@ func (e *Engine) manager() {
 			<-e.shutdownRPC
 
 			e.syncClient.disable()
-			e.shutdownVservers()
+			if e.config.IPVSSyncInterface != "" {
+				if err := e.ncc.Dial(); err != nil {
+					log.Errorf("Failed to connect to NCC: %v", err)
+				} else {
+					e.stopIPVSSync()
+					e.ncc.Close()
+				}
+			}
+			if clearState {
+				e.withdrawAnycast()
+				e.shutdownVservers()
+				e.flushIPVS()
+			} else {
+				log.Info("Leaving VIPs and IPVS services in place")
+			}
 			e.hcManager.shutdown()
-			e.deleteVLANs()
+			if clearState {
+				e.deleteVLANs()
+			}
+			if err := e.audit.close(); err != nil {
+				log.Errorf("Failed to close audit log: %v", err)
+			}
 
 			log.Info("Shutdown complete")
 			return
@@ -443,6 +550,74 @@ func (e *Engine) manager() {
 	}
 }
 
+// releaseMastership requests that the HA component relinquish mastership and
+// waits, for up to the given timeout, for the node to leave the master state.
+func (e *Engine) releaseMastership(timeout time.Duration) {
+	if e.haManager.state() != seesaw.HAMaster {
+		return
+	}
+	log.Infof("Releasing HA mastership for shutdown")
+	if err := e.haManager.requestFailover(true); err != nil {
+		log.Warningf("Failed to request failover for shutdown: %v", err)
+		return
+	}
+	deadline := time.After(timeout)
+	for e.haManager.state() == seesaw.HAMaster {
+		select {
+		case state := <-e.haManager.stateChan:
+			e.haManager.setState(state)
+		case status := <-e.haManager.statusChan:
+			e.haManager.setStatus(status)
+		case <-deadline:
+			log.Warningf("Timed out waiting to release HA mastership")
+			return
+		}
+	}
+}
+
+// flushIPVS removes all services and destinations from the IPVS table.
+func (e *Engine) flushIPVS() {
+	if err := e.ncc.Dial(); err != nil {
+		log.Errorf("Failed to connect to NCC: %v", err)
+		return
+	}
+	defer e.ncc.Close()
+
+	log.Infof("Flushing IPVS table")
+	if err := e.ncc.IPVSFlush(); err != nil {
+		log.Errorf("Failed to flush IPVS table: %v", err)
+	}
+}
+
+// applyConfig applies the current cluster configuration to the HA state,
+// VLANs and vservers.
+func (e *Engine) applyConfig() {
+	if ha, err := e.haConfig(); err != nil {
+		log.Errorf("Manager failed to determine haConfig: %v", err)
+	} else if ha.Enabled {
+		e.haManager.enable()
+	} else {
+		e.haManager.disable()
+	}
+
+	node, err := e.thisNode()
+	if err != nil {
+		log.Errorf("Manager failed to identify local node: %v", err)
+		return
+	}
+	if !node.VserversEnabled {
+		e.shutdownVservers()
+		e.deleteVLANs()
+		return
+	}
+
+	// Process new cluster configuration.
+	e.updateVLANs()
+
+	// TODO(jsing): Ensure this does not block.
+	e.updateVservers()
+}
+
 // updateVservers processes a list of vserver configurations then stops
 // deleted vservers, spawns new vservers and updates the existing vservers.
 func (e *Engine) updateVservers() {
@@ -450,23 +625,51 @@ func (e *Engine) updateVservers() {
 	cluster := e.cluster
 	e.clusterLock.RUnlock()
 
-	// Delete vservers that no longer exist in the new configuration.
+	// Delete vservers that no longer exist in the new configuration. Those
+	// with a drain timeout are drained of active connections first.
 	for name, vserver := range e.vservers {
-		if cluster.Vservers[name] == nil {
-			log.Infof("Stopping unconfigured vserver %s", name)
-			vserver.stop()
-			<-vserver.stopped
-			delete(e.vservers, name)
-			e.vserverLock.Lock()
-			delete(e.vserverSnapshots, name)
-			e.vserverLock.Unlock()
+		if cluster.Vservers[name] != nil {
+			continue
+		}
+		delete(e.vservers, name)
+		if cfg := e.vserverConfigs[name]; cfg != nil && cfg.DrainTimeout > 0 {
+			log.Infof("Draining unconfigured vserver %s for up to %v", name, cfg.DrainTimeout)
+			vserver.startDrain(time.Now().Add(cfg.DrainTimeout))
+			e.drainingVservers[name] = vserver
+			continue
+		}
+		log.Infof("Stopping unconfigured vserver %s", name)
+		e.stopVserver(name, vserver)
+	}
+
+	// Vservers that use IPVS schedulers that are not supported by the
+	// running kernel are neither started nor updated.
+	vservers := make(map[string]*config.Vserver)
+	for name, vc := range cluster.Vservers {
+		if err := e.checkSchedulers(vc); err != nil {
+			log.Errorf("Not applying configuration for vserver %s: %v", name, err)
+			continue
+		}
+		vservers[name] = vc
+	}
+
+	// Stop draining vservers that would conflict with the new configuration.
+	for name, vserver := range e.drainingVservers {
+		for _, config := range vservers {
+			if vserversConflict(config, e.vserverConfigs[name]) {
+				log.Infof("Stopping draining vserver %s, which conflicts with vserver %s", name, config.Name)
+				delete(e.drainingVservers, name)
+				e.stopVserver(name, vserver)
+				break
+			}
 		}
 	}
 
 	// Spawn new vservers and provide current configurations.
-	for _, config := range cluster.Vservers {
+	for _, config := range vservers {
 		if e.vservers[config.Name] == nil {
 			vserver := newVserver(e)
+			vserver.standby = e.vserverStandby(config.Name)
 			go vserver.run()
 			e.vservers[config.Name] = vserver
 		}
@@ -474,19 +677,108 @@ func (e *Engine) updateVservers() {
 	for _, override := range e.overrides {
 		e.distributeOverride(override)
 	}
-	for _, config := range cluster.Vservers {
+	for _, config := range vservers {
 		e.vservers[config.Name].updateConfig(config)
+		e.vserverConfigs[config.Name] = config
+	}
+}
+
+// initSchedulers determines which IPVS schedulers are not supported by the
+// running kernel.
+func (e *Engine) initSchedulers() {
+	e.unsupportedSchedulers = make(map[seesaw.LBScheduler]bool)
+	for _, s := range seesaw.LBSchedulers() {
+		ok, err := ipvs.SchedulerSupported(s.String())
+		if err != nil {
+			log.Warningf("Unable to determine kernel support for IPVS scheduler %v: %v", s, err)
+			continue
+		}
+		if !ok {
+			log.Warningf("IPVS scheduler %v is not supported by the running kernel", s)
+			e.unsupportedSchedulers[s] = true
+		}
+	}
+}
+
+// checkSchedulers returns an error if the given vserver configuration uses
+// an IPVS scheduler that is not supported by the running kernel.
+func (e *Engine) checkSchedulers(vc *config.Vserver) error {
+	for _, ve := range vc.Entries {
+		if e.unsupportedSchedulers[ve.Scheduler] {
+			return fmt.Errorf("IPVS scheduler %v for %s is not supported by the running kernel", ve.Scheduler, ve.Key())
+		}
+	}
+	return nil
+}
+
+// stopVserver stops the given vserver and waits for it to complete.
+func (e *Engine) stopVserver(name string, v *vserver) {
+	v.stop()
+	<-v.stopped
+	delete(e.vserverConfigs, name)
+	e.vserverLock.Lock()
+	delete(e.vserverSnapshots, name)
+	e.vserverLock.Unlock()
+}
+
+// vserversConflict returns true if the given vserver configurations share
+// a VIP, in which case their IPVS services may collide.
+func vserversConflict(a, b *config.Vserver) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if a.Name == b.Name {
+		return true
+	}
+	for _, ipA := range a.Addresses() {
+		for _, ipB := range b.Addresses() {
+			if ipA.Equal(ipB) {
+				return true
+			}
+		}
 	}
+	return false
+}
+
+// updateDrain updates the snapshot for a draining vserver, stopping the
+// vserver once it has no active connections or its drain deadline passes.
+func (e *Engine) updateDrain(svs *seesaw.Vserver) {
+	e.vserverLock.Lock()
+	e.vserverSnapshots[svs.Name] = svs
+	e.vserverLock.Unlock()
+
+	if svs.DrainDeadline.IsZero() {
+		return
+	}
+	conns := svs.ActiveConns()
+	switch {
+	case conns == 0:
+		log.Infof("Vserver %s has drained, stopping", svs.Name)
+	case time.Now().After(svs.DrainDeadline):
+		log.Infof("Vserver %s drain timed out with %d active connections, stopping", svs.Name, conns)
+	default:
+		log.Infof("Vserver %s draining, %d active connections", svs.Name, conns)
+		return
+	}
+	v := e.drainingVservers[svs.Name]
+	delete(e.drainingVservers, svs.Name)
+	e.stopVserver(svs.Name, v)
 }
 
 // shutdownVservers shuts down all running vservers.
 func (e *Engine) shutdownVservers() {
+	// Draining vservers are stopped without waiting for them to drain.
+	for name, v := range e.drainingVservers {
+		e.vservers[name] = v
+		delete(e.drainingVservers, name)
+	}
 	for _, v := range e.vservers {
 		v.stop()
 	}
 	for name, v := range e.vservers {
 		<-v.stopped
 		delete(e.vservers, name)
+		delete(e.vserverConfigs, name)
 	}
 	e.vserverLock.Lock()
 	e.vserverSnapshots = make(map[string]*seesaw.Vserver)
@@ -571,10 +863,26 @@ func (e *Engine) handleOverride(o seesaw.Override) {
 	}
 }
 
+// expireOverrides clears BackendOverrides that have expired. Each node expires
+// overrides independently, hence no sync notification is sent.
+func (e *Engine) expireOverrides(now time.Time) {
+	for _, o := range e.overrides {
+		bo, ok := o.(*seesaw.BackendOverride)
+		if !ok || bo.Expires.IsZero() || now.Before(bo.Expires) {
+			continue
+		}
+		log.Infof("Override for backend %s has expired", bo.Hostname)
+		cleared := &seesaw.BackendOverride{Hostname: bo.Hostname, OverrideState: seesaw.OverrideDefault}
+		e.audit.record(auditActorEngine, auditOverride, bo.Hostname, bo.State().String(), cleared.State().String())
+		e.handleOverride(cleared)
+	}
+}
+
 // distributeOverride distributes an Override to the appropriate vservers.
 func (e *Engine) distributeOverride(o seesaw.Override) {
 	// Send VserverOverrides and DestinationOverrides to the appropriate vserver.
-	// Send BackendOverrides to all vservers.
+	// Send BackendOverrides to all vservers. HealthcheckOverrides are handled
+	// by the healthcheck manager.
 	switch override := o.(type) {
 	case *seesaw.VserverOverride:
 		if vserver, ok := e.vservers[override.VserverName]; ok {
@@ -588,6 +896,8 @@ func (e *Engine) distributeOverride(o seesaw.Override) {
 		for _, vserver := range e.vservers {
 			vserver.queueOverride(o)
 		}
+	case *seesaw.HealthcheckOverride:
+		e.hcManager.override(override)
 	}
 }
 
@@ -602,10 +912,19 @@ func (e *Engine) becomeMaster() {
 	e.syncClient.disable()
 	e.hcManager.enable()
 	e.notifier.SetSource(config.SourceServer)
+	e.startIPVSSync(ipvs.SyncMaster)
+	e.lbInterfaceUp()
+}
 
+// lbInterfaceUp brings the LB interface up, if it is not already up.
+func (e *Engine) lbInterfaceUp() {
+	if e.lbUp {
+		return
+	}
 	if err := e.lbInterface.Up(); err != nil {
 		log.Fatalf("Failed to bring LB interface up: %v", err)
 	}
+	e.lbUp = true
 }
 
 // becomeBackup performs the neccesary actions for the Seesaw Engine to
@@ -617,12 +936,23 @@ func (e *Engine) becomeBackup() {
 	defer e.ncc.Close()
 
 	e.syncClient.enable()
-	e.hcManager.disable()
 	e.notifier.SetSource(config.SourcePeer)
+	e.startIPVSSync(ipvs.SyncBackup)
+
+	// With HA groups, the backup node continues to serve the vservers in
+	// the groups that it is master for. VIPs are configured based on the
+	// state of each group, rather than by bringing the LB interface down.
+	if len(e.config.HAGroups) > 0 {
+		e.hcManager.enable()
+		e.lbInterfaceUp()
+		return
+	}
 
+	e.hcManager.disable()
 	if err := e.lbInterface.Down(); err != nil {
 		log.Fatalf("Failed to bring LB interface down: %v", err)
 	}
+	e.lbUp = false
 
 	// TODO(jsing): Once peer synchronisation is implemented, make this
 	// a time-based expiration that commences once communication with the
@@ -630,6 +960,38 @@ func (e *Engine) becomeBackup() {
 	e.hcManager.expire()
 }
 
+// startIPVSSync starts the IPVS connection synchronisation daemon for the
+// given state, stopping any existing daemons. The master node sends updates
+// for its connections to the peer's backup daemon, so that the peer can
+// continue to serve them after a failover. The NCC connection must already be
+// established.
+func (e *Engine) startIPVSSync(state ipvs.SyncState) {
+	if e.config.IPVSSyncInterface == "" {
+		return
+	}
+	e.stopIPVSSync()
+	d := &ipvs.SyncDaemon{
+		State:     state,
+		Interface: e.config.IPVSSyncInterface,
+		SyncID:    e.config.IPVSSyncID,
+		Group:     e.config.IPVSSyncGroup,
+	}
+	if err := e.ncc.IPVSStartSyncDaemon(d); err != nil {
+		log.Errorf("Failed to start IPVS %v: %v", d, err)
+	}
+}
+
+// stopIPVSSync stops the IPVS connection synchronisation daemons. The NCC
+// connection must already be established.
+func (e *Engine) stopIPVSSync() {
+	for _, state := range []ipvs.SyncState{ipvs.SyncMaster, ipvs.SyncBackup} {
+		// An error is returned if the daemon is not running.
+		if err := e.ncc.IPVSStopSyncDaemon(state); err != nil {
+			log.V(1).Infof("Failed to stop IPVS %v sync daemon: %v", state, err)
+		}
+	}
+}
+
 // markAllocator handles the allocation of marks.
 type markAllocator struct {
 	lock  sync.RWMutex
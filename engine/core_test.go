@@ -0,0 +1,164 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/engine/config"
+	"github.com/wy2745/seesaw/ipvs"
+)
+
+func TestIPVSSync(t *testing.T) {
+	e := newTestEngine()
+	ncc := e.ncc.(*dummyNCC)
+
+	// Connection synchronisation is disabled by default.
+	e.startIPVSSync(ipvs.SyncMaster)
+	if len(ncc.syncDaemons) != 0 {
+		t.Errorf("Got %d sync daemons with sync disabled, want 0", len(ncc.syncDaemons))
+	}
+
+	e.config.IPVSSyncInterface = "eth1"
+	e.config.IPVSSyncID = 60
+	for _, state := range []ipvs.SyncState{ipvs.SyncMaster, ipvs.SyncBackup, ipvs.SyncMaster} {
+		e.startIPVSSync(state)
+		if len(ncc.syncDaemons) != 1 {
+			t.Errorf("Got %d sync daemons in %v state, want 1", len(ncc.syncDaemons), state)
+		}
+		d, ok := ncc.syncDaemons[state]
+		if !ok {
+			t.Errorf("No %v sync daemon running", state)
+			continue
+		}
+		if d.Interface != "eth1" || d.SyncID != 60 {
+			t.Errorf("Got %v, want interface eth1 and sync ID 60", d)
+		}
+	}
+
+	e.stopIPVSSync()
+	if len(ncc.syncDaemons) != 0 {
+		t.Errorf("Got %d sync daemons after stop, want 0", len(ncc.syncDaemons))
+	}
+}
+
+func TestExpireOverrides(t *testing.T) {
+	e := newTestEngine()
+	now := time.Now()
+	for _, o := range []seesaw.Override{
+		&seesaw.BackendOverride{Hostname: "expired", OverrideState: seesaw.OverrideDisable, Expires: now.Add(-time.Second)},
+		&seesaw.BackendOverride{Hostname: "unexpired", OverrideState: seesaw.OverrideDisable, Expires: now.Add(time.Minute)},
+		&seesaw.BackendOverride{Hostname: "permanent", OverrideState: seesaw.OverrideEnable},
+	} {
+		e.handleOverride(o)
+	}
+
+	e.expireOverrides(now)
+	if _, ok := e.overrides["expired"]; ok {
+		t.Errorf("Expired override was not cleared")
+	}
+	for _, name := range []string{"unexpired", "permanent"} {
+		if _, ok := e.overrides[name]; !ok {
+			t.Errorf("Override for %s was cleared, want retained", name)
+		}
+	}
+}
+
+func TestReleaseMastership(t *testing.T) {
+	e := newTestEngine()
+
+	// A node that is not the master has nothing to release.
+	e.releaseMastership(time.Second)
+	if e.haManager.failover() {
+		t.Errorf("Failover requested when not master")
+	}
+
+	// A master requests a failover, then waits for the HA state to change.
+	e.haManager.status.State = seesaw.HAMaster
+	e.releaseMastership(10 * time.Millisecond)
+	if !e.haManager.failover() {
+		t.Errorf("Failover not requested when master")
+	}
+}
+
+func TestHAGroups(t *testing.T) {
+	e := newTestEngine()
+	e.config.HAGroups = []config.HAGroup{
+		{Name: "dns", VRID: 61, Priority: 200, Vservers: []string{vserverConfig.Name}},
+	}
+	lbIF := e.lbInterface.(*dummyLBInterface)
+
+	hac, err := func() (*seesaw.HAConfig, error) {
+		n := &seesaw.Node{Host: seesaw.Host{IPv4Addr: net.ParseIP("192.168.36.2")}, Priority: 100}
+		e.config.Node = n.Host
+		e.cluster = &config.Cluster{Nodes: map[string]*seesaw.Node{n.Key(): n}}
+		return e.haConfig()
+	}()
+	if err != nil {
+		t.Fatalf("haConfig() failed: %v", err)
+	}
+	if want := []seesaw.HAGroup{{VRID: 61, Priority: 200}}; len(hac.Groups) != 1 || hac.Groups[0] != want[0] {
+		t.Errorf("haConfig().Groups = %v, want %v", hac.Groups, want)
+	}
+
+	vip := seesaw.NewVIP(net.ParseIP("192.168.36.1"), nil)
+	vc := vserverConfig
+	vc.Host = seesaw.Host{
+		Hostname: "dns-vip1.example.com",
+		IPv4Addr: vip.IP.IP(),
+		IPv4Mask: net.CIDRMask(24, 32),
+	}
+	vc.VIPs = map[string]*seesaw.VIP{vip.IP.String(): vip}
+	vserver := newTestVserver(e)
+	vserver.standby = e.vserverStandby(vc.Name)
+	e.vservers[vc.Name] = vserver
+	vserver.handleConfigUpdate(&vc)
+
+	checkVIP := func(desc string, want bool) {
+		if got := lbIF.vips[*vip]; got != want {
+			t.Errorf("%s: VIP %v configured = %t, want %t", desc, vip.IP, got, want)
+		}
+	}
+	checkVIP("initial", false)
+
+	// The VIP is only configured while this node is master for the group,
+	// regardless of the node's HA state.
+	e.haManager.setGroupState(61, seesaw.HAMaster)
+	vserver.handleStandby(<-vserver.standbyChan)
+	checkVIP("group master", true)
+
+	e.haManager.setGroupState(61, seesaw.HABackup)
+	vserver.handleStandby(<-vserver.standbyChan)
+	checkVIP("group backup", false)
+
+	// Group states are reset when the node's HA state is lost.
+	e.haManager.setGroupState(61, seesaw.HAMaster)
+	vserver.handleStandby(<-vserver.standbyChan)
+	e.haManager.setState(seesaw.HAUnknown)
+	if got := e.haManager.groupState(61); got != seesaw.HAUnknown {
+		t.Errorf("Group state after HA state lost = %v, want %v", got, seesaw.HAUnknown)
+	}
+	vserver.handleStandby(<-vserver.standbyChan)
+	checkVIP("HA state lost", false)
+
+	// States for unknown groups are ignored.
+	e.haManager.setGroupState(99, seesaw.HAMaster)
+	if got := e.haManager.groupState(99); got != seesaw.HAUnknown {
+		t.Errorf("Group state for unknown VRID = %v, want %v", got, seesaw.HAUnknown)
+	}
+}
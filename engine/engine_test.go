@@ -32,7 +32,9 @@ import (
 	log "github.com/golang/glog"
 )
 
-type dummyNCC struct{}
+type dummyNCC struct {
+	syncDaemons map[ipvs.SyncState]*ipvs.SyncDaemon
+}
 
 func (nc *dummyNCC) NewLBInterface(name string, cfg *ncctypes.LBConfig) ncclient.LBInterface {
 	return nil
@@ -42,12 +44,17 @@ func (nc *dummyNCC) Close() error
 func (nc *dummyNCC) ARPSendGratuitous(iface string, ip net.IP) error                      { return nil }
 func (nc *dummyNCC) BGPConfig() ([]string, error)                                         { return nil, nil }
 func (nc *dummyNCC) BGPNeighbors() ([]*quagga.Neighbor, error)                            { return nil, nil }
+func (nc *dummyNCC) BGPConfigurePeers(peers []*quagga.Peer) error                         { return nil }
 func (nc *dummyNCC) BGPWithdrawAll() error                                                { return nil }
 func (nc *dummyNCC) BGPAdvertiseVIP(ip net.IP) error                                      { return nil }
+func (nc *dummyNCC) BGPAdvertiseVIPCommunities(ip net.IP, c []string) error               { return nil }
 func (nc *dummyNCC) BGPWithdrawVIP(ip net.IP) error                                       { return nil }
+func (nc *dummyNCC) OSPFAdvertiseVIP(ip net.IP, area quagga.OSPFArea) error               { return nil }
+func (nc *dummyNCC) OSPFWithdrawVIP(ip net.IP, area quagga.OSPFArea) error                { return nil }
+func (nc *dummyNCC) OSPFWithdrawAll(area quagga.OSPFArea) error                           { return nil }
 func (nc *dummyNCC) IPVSFlush() error                                                     { return nil }
 func (nc *dummyNCC) IPVSGetServices() ([]*ipvs.Service, error)                            { return nil, nil }
-func (nc *dummyNCC) IPVSGetService(svc *ipvs.Service) (*ipvs.Service, error)              { return nil, nil }
+func (nc *dummyNCC) IPVSGetService(svc *ipvs.Service) (*ipvs.Service, error)              { return svc, nil }
 func (nc *dummyNCC) IPVSAddService(svc *ipvs.Service) error                               { return nil }
 func (nc *dummyNCC) IPVSUpdateService(svc *ipvs.Service) error                            { return nil }
 func (nc *dummyNCC) IPVSDeleteService(svc *ipvs.Service) error                            { return nil }
@@ -56,6 +63,22 @@ func (nc *dummyNCC) IPVSUpdateDestination(svc *ipvs.Service, dst *ipvs.Destinati
 func (nc *dummyNCC) IPVSDeleteDestination(svc *ipvs.Service, dst *ipvs.Destination) error { return nil }
 func (nc *dummyNCC) RouteDefaultIPv4() (net.IP, error)                                    { return nil, nil }
 
+func (nc *dummyNCC) IPVSApplyBatch(b *ipvs.Batch) (*ipvs.BatchResult, error) {
+	return &ipvs.BatchResult{Errors: make([]string, b.Len())}, nil
+}
+
+func (nc *dummyNCC) IPVSStartSyncDaemon(d *ipvs.SyncDaemon) error {
+	if nc.syncDaemons == nil {
+		nc.syncDaemons = make(map[ipvs.SyncState]*ipvs.SyncDaemon)
+	}
+	nc.syncDaemons[d.State] = d
+	return nil
+}
+func (nc *dummyNCC) IPVSStopSyncDaemon(state ipvs.SyncState) error {
+	delete(nc.syncDaemons, state)
+	return nil
+}
+
 type dummyLBInterface struct {
 	vips     map[seesaw.VIP]bool
 	vlans    map[uint16]bool
@@ -0,0 +1,106 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+// This file contains functions for detecting flapping destinations and rate
+// limiting the logging of their health transitions.
+
+import (
+	"time"
+
+	log "github.com/golang/glog"
+)
+
+// flapState tracks the health transitions of a destination over the current
+// flap interval.
+type flapState struct {
+	start       time.Time // The start of the current interval.
+	transitions int       // The number of transitions in the current interval.
+	suppressed  int       // The number of transitions that were not logged.
+}
+
+// recordTransition records a health transition for the given destination. If
+// the destination has exceeded the flap threshold for the current interval, a
+// warning is logged and further transitions are not logged until the
+// interval ends.
+func (v *vserver) recordTransition(d *destination, now time.Time) {
+	threshold := v.engine.config.FlapThreshold
+	if threshold <= 0 {
+		return
+	}
+	name := d.name()
+	f := v.flaps[name]
+	if f == nil || now.Sub(f.start) >= v.engine.config.FlapInterval {
+		v.summariseFlap(name, f)
+		f = &flapState{start: now}
+		v.flaps[name] = f
+	}
+	f.transitions++
+	switch {
+	case f.transitions == threshold+1:
+		log.Warningf("%v: destination %v flapping detected (%d health transitions in %v), suppressing transition logging",
+			v, d, f.transitions, now.Sub(f.start))
+		fallthrough
+	case f.transitions > threshold:
+		f.suppressed++
+	}
+}
+
+// flapping returns true if logging of health transitions for the given
+// destination is currently being suppressed.
+func (v *vserver) flapping(d *destination) bool {
+	threshold := v.engine.config.FlapThreshold
+	if threshold <= 0 {
+		return false
+	}
+	f := v.flaps[d.name()]
+	return f != nil && f.transitions > threshold
+}
+
+// checkFlapping returns true if logging of health transitions is being
+// suppressed for all of the destinations for the given check.
+func (v *vserver) checkFlapping(c *check) bool {
+	if len(c.dests) == 0 {
+		return false
+	}
+	for _, d := range c.dests {
+		if !v.flapping(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// summariseFlaps logs a summary for each destination whose flap interval has
+// ended and had transitions suppressed, then discards the expired state.
+func (v *vserver) summariseFlaps(now time.Time) {
+	for name, f := range v.flaps {
+		if now.Sub(f.start) < v.engine.config.FlapInterval {
+			continue
+		}
+		v.summariseFlap(name, f)
+		delete(v.flaps, name)
+	}
+}
+
+// summariseFlap logs a summary of the health transitions for a destination
+// over a flap interval, if any were suppressed.
+func (v *vserver) summariseFlap(name string, f *flapState) {
+	if f == nil || f.suppressed == 0 {
+		return
+	}
+	log.Warningf("%v: destination %v flapped %d times in %v (%d transitions not logged)",
+		v, name, f.transitions, v.engine.config.FlapInterval, f.suppressed)
+}
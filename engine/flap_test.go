@@ -0,0 +1,79 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlapping(t *testing.T) {
+	e := newTestEngine()
+	e.config.FlapThreshold = 3
+	e.config.FlapInterval = time.Minute
+	v := newTestVserver(e)
+	v.handleConfigUpdate(&vserverConfig)
+
+	var dst *destination
+	var chk *check
+	for _, c := range v.checks {
+		if len(c.dests) == 1 {
+			chk, dst = c, c.dests[0]
+			break
+		}
+	}
+	if dst == nil {
+		t.Fatalf("Failed to find a check with a single destination")
+	}
+
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		v.recordTransition(dst, start.Add(time.Duration(i)*time.Second))
+		if got, want := v.flapping(dst), i > 3; got != want {
+			t.Errorf("After %d transitions flapping = %t, want %t", i, got, want)
+		}
+		if got, want := v.checkFlapping(chk), i > 3; got != want {
+			t.Errorf("After %d transitions checkFlapping = %t, want %t", i, got, want)
+		}
+	}
+	if f := v.flaps[dst.name()]; f == nil || f.transitions != 5 || f.suppressed != 2 {
+		t.Errorf("Got flap state %+v, want 5 transitions with 2 suppressed", f)
+	}
+
+	// The flap state is discarded once the interval ends.
+	v.summariseFlaps(start.Add(30 * time.Second))
+	if !v.flapping(dst) {
+		t.Errorf("Flap state discarded before interval ended")
+	}
+	v.summariseFlaps(start.Add(2 * time.Minute))
+	if v.flapping(dst) {
+		t.Errorf("Destination still flapping after interval ended")
+	}
+
+	// A transition in a new interval starts counting again.
+	v.recordTransition(dst, start.Add(3*time.Minute))
+	if f := v.flaps[dst.name()]; f == nil || f.transitions != 1 || f.suppressed != 0 {
+		t.Errorf("Got flap state %+v, want 1 transition", f)
+	}
+
+	// Flap detection is disabled with a zero threshold.
+	e.config.FlapThreshold = 0
+	for i := 0; i < 10; i++ {
+		v.recordTransition(dst, start.Add(3*time.Minute))
+	}
+	if v.flapping(dst) {
+		t.Errorf("Destination flapping with flap detection disabled")
+	}
+}
@@ -21,10 +21,12 @@ package engine
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/engine/config"
 
 	log "github.com/golang/glog"
 )
@@ -39,6 +41,15 @@ type haManager struct {
 	timeout         time.Duration
 	stateChan       chan seesaw.HAState
 	statusChan      chan seesaw.HAStatus
+
+	groups    map[uint8]seesaw.HAState // by VRID, protected by statusLock
+	groupChan chan haGroupState
+}
+
+// haGroupState contains the HA state for an additional VRRP group.
+type haGroupState struct {
+	vrid  uint8
+	state seesaw.HAState
 }
 
 // newHAManager creates a new haManager with the given HA state timeout.
@@ -54,6 +65,8 @@ func newHAManager(engine *Engine, timeout time.Duration) *haManager {
 		timeout:    timeout,
 		stateChan:  make(chan seesaw.HAState, 1),
 		statusChan: make(chan seesaw.HAStatus, 1),
+		groups:     make(map[uint8]seesaw.HAState),
+		groupChan:  make(chan haGroupState, 1),
 	}
 }
 
@@ -109,6 +122,61 @@ func (h *haManager) requestFailover(peer bool) error {
 	return nil
 }
 
+// requestFailoverTo requests a failover that results in the named node
+// becoming master. The target must be either this node or its peer, and must
+// currently be in backup state. If node is empty, this is the same as
+// requestFailover.
+func (h *haManager) requestFailoverTo(node string) error {
+	if node == "" {
+		return h.requestFailover(false)
+	}
+
+	self := h.engine.config.Node.Hostname
+	peer := h.engine.config.Peer.Hostname
+	state := h.state()
+	switch {
+	case matchHostname(node, self):
+		if state == seesaw.HAMaster {
+			return fmt.Errorf("Node %s is already master", self)
+		}
+		if state != seesaw.HABackup {
+			return fmt.Errorf("Node %s cannot become master (current state is %v)", self, state)
+		}
+
+	case matchHostname(node, peer):
+		if state != seesaw.HAMaster {
+			return fmt.Errorf("Node %s is not master (current state is %v)", self, state)
+		}
+		peerState, err := h.engine.syncClient.haState()
+		if err != nil {
+			return fmt.Errorf("Failed to get HA state for peer %s: %v", peer, err)
+		}
+		if peerState != seesaw.HABackup {
+			return fmt.Errorf("Node %s cannot become master (current state is %v)", peer, peerState)
+		}
+
+	default:
+		return fmt.Errorf("Unknown node %q (expected %s or %s)", node, self, peer)
+	}
+
+	return h.requestFailover(false)
+}
+
+// matchHostname returns true if name refers to the given hostname, either in
+// full or by its short (unqualified) name.
+func matchHostname(name, hostname string) bool {
+	name = strings.TrimSuffix(name, ".")
+	hostname = strings.TrimSuffix(hostname, ".")
+	if name == "" || hostname == "" {
+		return false
+	}
+	if strings.EqualFold(name, hostname) {
+		return true
+	}
+	short := strings.SplitN(hostname, ".", 2)[0]
+	return !strings.Contains(name, ".") && strings.EqualFold(name, short)
+}
+
 // setState sets the HAState of the engine and dispatches events when the state
 // changes.
 func (h *haManager) setState(s seesaw.HAState) {
@@ -127,6 +195,7 @@ func (h *haManager) setState(s seesaw.HAState) {
 			h.engine.becomeBackup()
 		}
 		log.Infof("HA state transition %v -> %v complete", state, s)
+		h.engine.audit.record(auditActorEngine, auditHAState, "", state.String(), s.String())
 	}
 
 	now := time.Now()
@@ -136,6 +205,54 @@ func (h *haManager) setState(s seesaw.HAState) {
 	h.status.Since = now
 	h.status.LastUpdate = now
 	h.statusLock.Unlock()
+
+	if state != s {
+		h.engine.updateHAGroup(h.engine.config.VRID)
+	}
+
+	// The group states are no longer known if the HA component is not
+	// running. This is applied regardless of whether the node's state has
+	// changed, since group states may be reported while the node's state
+	// is still unknown.
+	if s != seesaw.HAMaster && s != seesaw.HABackup {
+		for _, g := range h.engine.config.HAGroups {
+			h.setGroupState(g.VRID, seesaw.HAUnknown)
+		}
+	}
+}
+
+// groupState returns the HA state known by the engine for the VRRP group with
+// the given VRID. The HA state of the node is returned for the node's VRID.
+func (h *haManager) groupState(vrid uint8) seesaw.HAState {
+	h.statusLock.RLock()
+	defer h.statusLock.RUnlock()
+	if vrid == h.engine.config.VRID {
+		return h.status.State
+	}
+	if s, ok := h.groups[vrid]; ok {
+		return s
+	}
+	return seesaw.HAUnknown
+}
+
+// setGroupState sets the HA state for an additional VRRP group and updates
+// the vservers in the group when the state changes.
+func (h *haManager) setGroupState(vrid uint8, s seesaw.HAState) {
+	if h.engine.haGroup(vrid) == nil {
+		log.Warningf("Ignoring HA state %v for unknown VRID %d", s, vrid)
+		return
+	}
+	state := h.groupState(vrid)
+	if state == s {
+		return
+	}
+	log.Infof("HA state transition %v -> %v for VRID %d", state, s, vrid)
+	h.statusLock.Lock()
+	h.groups[vrid] = s
+	h.statusLock.Unlock()
+
+	h.engine.updateHAGroup(vrid)
+	h.engine.audit.record(auditActorEngine, auditHAState, fmt.Sprintf("VRID %d", vrid), state.String(), s.String())
 }
 
 // setStatus updates the engine HAStatus.
@@ -147,6 +264,10 @@ func (h *haManager) setStatus(s seesaw.HAStatus) {
 	h.status.Sent = s.Sent
 	h.status.Received = s.Received
 	h.status.Transitions = s.Transitions
+	h.status.Priority = s.Priority
+	h.status.EffectivePriority = s.EffectivePriority
+	h.status.FailedTrackers = s.FailedTrackers
+	h.status.SplitBrain = s.SplitBrain
 	h.statusLock.Unlock()
 }
 
@@ -162,3 +283,53 @@ func (h *haManager) timer() <-chan time.Time {
 	h.statusLock.RUnlock()
 	return time.After(deadline.Sub(time.Now()))
 }
+
+// haGroup returns the additional VRRP group with the given VRID, or nil if
+// there is no such group.
+func (e *Engine) haGroup(vrid uint8) *config.HAGroup {
+	for i := range e.config.HAGroups {
+		if e.config.HAGroups[i].VRID == vrid {
+			return &e.config.HAGroups[i]
+		}
+	}
+	return nil
+}
+
+// vserverVRID returns the VRID of the VRRP group that the named vserver
+// belongs to. Vservers that are not assigned to an additional VRRP group
+// belong to the node's VRRP group.
+func (e *Engine) vserverVRID(name string) uint8 {
+	for _, g := range e.config.HAGroups {
+		for _, vs := range g.Vservers {
+			if vs == name {
+				return g.VRID
+			}
+		}
+	}
+	return e.config.VRID
+}
+
+// vserverStandby returns true if the VIPs for the named vserver should not be
+// configured on this node, since this node is not the master for the
+// vserver's VRRP group. This only applies when additional VRRP groups are
+// configured - otherwise the VIPs are controlled by the state of the LB
+// interface.
+func (e *Engine) vserverStandby(name string) bool {
+	if len(e.config.HAGroups) == 0 {
+		return false
+	}
+	return e.haManager.groupState(e.vserverVRID(name)) != seesaw.HAMaster
+}
+
+// updateHAGroup notifies the vservers in the VRRP group with the given VRID of
+// a change to the group's HA state.
+func (e *Engine) updateHAGroup(vrid uint8) {
+	if len(e.config.HAGroups) == 0 {
+		return
+	}
+	for name, v := range e.vservers {
+		if e.vserverVRID(name) == vrid {
+			v.queueStandby(e.vserverStandby(name))
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -53,11 +54,12 @@ type healthcheckManager struct {
 	next          healthcheck.Id
 	vserverChecks map[string]map[checkKey]*check // keyed by vserver name
 
-	cfgs    map[healthcheck.Id]*healthcheck.Config
-	checks  map[healthcheck.Id]*check
-	ids     map[checkKey]healthcheck.Id
-	enabled bool
-	lock    sync.RWMutex // Guards cfgs, checks, enabled and ids.
+	cfgs     map[healthcheck.Id]*healthcheck.Config
+	checks   map[healthcheck.Id]*check
+	ids      map[checkKey]healthcheck.Id
+	disabled map[healthcheck.Id]bool
+	enabled  bool
+	lock     sync.RWMutex // Guards cfgs, checks, disabled, enabled and ids.
 
 	quit    chan bool
 	stopped chan bool
@@ -71,6 +73,7 @@ func newHealthcheckManager(e *Engine) *healthcheckManager {
 		marks:         make(map[seesaw.IP]uint32),
 		markAlloc:     newMarkAllocator(dsrMarkBase, dsrMarkSize),
 		ncc:           ncclient.NewNCC(e.config.NCCSocket),
+		disabled:      make(map[healthcheck.Id]bool),
 		next:          healthcheck.Id((uint64(os.Getpid()) & 0xFFFF) << 48),
 		vserverChecks: make(map[string]map[checkKey]*check),
 		quit:          make(chan bool),
@@ -81,14 +84,64 @@ func newHealthcheckManager(e *Engine) *healthcheckManager {
 
 // configs returns the healthcheck Configs for a Seesaw Engine. The returned
 // map should only be read, not mutated. If the healthcheckManager is disabled,
-// then nil is returned.
+// then nil is returned. Healthchecks that have been disabled via an override
+// are excluded.
 func (h *healthcheckManager) configs() map[healthcheck.Id]*healthcheck.Config {
 	h.lock.RLock()
 	defer h.lock.RUnlock()
 	if !h.enabled {
 		return nil
 	}
-	return h.cfgs
+	if len(h.disabled) == 0 {
+		return h.cfgs
+	}
+	cfgs := make(map[healthcheck.Id]*healthcheck.Config, len(h.cfgs))
+	for id, cfg := range h.cfgs {
+		if !h.disabled[id] {
+			cfgs[id] = cfg
+		}
+	}
+	return cfgs
+}
+
+// override applies a HealthcheckOverride. A disabled healthcheck is no longer
+// performed and is reported to its vserver as being healthy, until the
+// override is cleared.
+func (h *healthcheckManager) override(o *seesaw.HealthcheckOverride) {
+	id := healthcheck.Id(o.Id)
+	disable := o.State() == seesaw.OverrideDisable
+
+	h.lock.Lock()
+	if h.disabled[id] == disable {
+		h.lock.Unlock()
+		return
+	}
+	if disable {
+		h.disabled[id] = true
+	} else {
+		delete(h.disabled, id)
+	}
+	h.lock.Unlock()
+
+	if disable {
+		log.Infof("Disabling healthcheck 0x%x", id)
+	} else {
+		log.Infof("Enabling healthcheck 0x%x", id)
+	}
+	h.queueHealthState(overrideNotification(id, disable))
+}
+
+// overrideNotification returns the Notification that is used to report the
+// state of a healthcheck when it is disabled, or the override is cleared.
+func overrideNotification(id healthcheck.Id, disabled bool) *healthcheck.Notification {
+	msg := "Healthcheck disabled by override"
+	if !disabled {
+		msg = "Override cleared - waiting for healthcheck"
+	}
+	return &healthcheck.Notification{
+		Id:     id,
+		Status: healthcheck.Status{State: healthcheck.StateHealthy, Message: msg},
+	}
 }
 
 // update updates the healthchecks for a vserver.
@@ -153,12 +206,15 @@ func (h *healthcheckManager) buildMaps() {
 		// Create a new healthcheck configuration if one did not
 		// previously exist, or if the check configuration changed.
 		cfg, ok := cfgs[id]
-		if !ok || *checks[id].healthcheck != *c.healthcheck {
-			newCfg, err := h.newConfig(id, key, c.healthcheck)
+		if !ok || *checks[id].healthcheck != *c.healthcheck || checks[id].hostname() != c.hostname() {
+			newCfg, err := h.newConfig(id, key, c.healthcheck, c.hostname())
 			if err != nil {
 				log.Error(err)
 				continue
 			}
+			if c.vserver != nil {
+				newCfg.Vserver = c.vserver.String()
+			}
 			cfg = newCfg
 		}
 
@@ -171,8 +227,20 @@ func (h *healthcheckManager) buildMaps() {
 	h.ids = newIDs
 	h.cfgs = newCfgs
 	h.checks = newChecks
+	var disabled []healthcheck.Id
+	for id := range h.disabled {
+		if newChecks[id] != nil {
+			disabled = append(disabled, id)
+		}
+	}
 	h.lock.Unlock()
 
+	// Vservers may have reinitialised their checks, so report the state of
+	// disabled healthchecks again.
+	for _, id := range disabled {
+		h.queueHealthState(overrideNotification(id, true))
+	}
+
 	h.pruneMarks()
 }
 
@@ -182,12 +250,17 @@ func (h *healthcheckManager) healthState(n *healthcheck.Notification) error {
 
 	h.lock.RLock()
 	enabled := h.enabled
+	disabled := h.disabled[n.Id]
 	h.lock.RUnlock()
 
 	if !enabled {
 		log.Warningf("Healthcheck manager is disabled; ignoring healthcheck notification %v", n)
 		return nil
 	}
+	if disabled {
+		log.V(1).Infof("Healthcheck is disabled by override; ignoring healthcheck notification %v", n)
+		return nil
+	}
 
 	h.engine.syncServer.notify(&SyncNote{Type: SNTHealthcheck, Healthcheck: n})
 
@@ -200,6 +273,7 @@ func (h *healthcheckManager) queueHealthState(n *healthcheck.Notification) error
 	h.lock.RLock()
 	cfg := h.cfgs[n.Id]
 	check := h.checks[n.Id]
+	disabled := h.disabled[n.Id]
 	h.lock.RUnlock()
 
 	if cfg == nil || check == nil {
@@ -209,15 +283,19 @@ func (h *healthcheckManager) queueHealthState(n *healthcheck.Notification) error
 
 	note := &checkNotification{
 		key:         check.key,
+		id:          n.Id,
 		description: cfg.Checker.String(),
 		status:      n.Status,
 	}
+	if disabled {
+		note.override = seesaw.OverrideDisable
+	}
 	check.vserver.queueCheckNotification(note)
 
 	return nil
 }
 
-func (h *healthcheckManager) newConfig(id healthcheck.Id, key checkKey, hc *config.Healthcheck) (*healthcheck.Config, error) {
+func (h *healthcheckManager) newConfig(id healthcheck.Id, key checkKey, hc *config.Healthcheck, hostname string) (*healthcheck.Config, error) {
 	host := key.backendIP.IP()
 	port := int(hc.Port)
 	mark := 0
@@ -245,6 +323,32 @@ func (h *healthcheckManager) newConfig(id healthcheck.Id, key checkKey, hc *conf
 		dns.Question.Qtype = queryType
 
 		checker = dns
+	case seesaw.HCTypeExec:
+		if !h.engine.config.AllowExecChecks {
+			return nil, errors.New("exec healthchecks are not enabled")
+		}
+		args := strings.Fields(hc.Send)
+		if len(args) == 0 {
+			return nil, errors.New("exec healthcheck has no command")
+		}
+		// The command is always run against the backend, even for DSR.
+		exec := healthcheck.NewExecChecker(host, port)
+		target = &exec.Target
+		exec.Command = args[0]
+		exec.Args = args[1:]
+		checker = exec
+	case seesaw.HCTypeGRPC:
+		grpc := healthcheck.NewGRPCChecker(ip, port)
+		target = &grpc.Target
+		grpc.Service = hc.Send
+		checker = grpc
+	case seesaw.HCTypeGRPCTLS:
+		grpc := healthcheck.NewGRPCChecker(ip, port)
+		target = &grpc.Target
+		grpc.Service = hc.Send
+		grpc.Secure = true
+		grpc.TLSVerify = hc.TLSVerify
+		checker = grpc
 	case seesaw.HCTypeHTTP:
 		http := healthcheck.NewHTTPChecker(ip, port)
 		target = &http.Target
@@ -259,8 +363,9 @@ func (h *healthcheckManager) newConfig(id healthcheck.Id, key checkKey, hc *conf
 		}
 		http.Proxy = hc.Proxy
 		if hc.Method != "" {
-			http.Method = hc.Method
+			http.Method = strings.ToUpper(hc.Method)
 		}
+		http.Body = hc.Body
 		checker = http
 	case seesaw.HCTypeHTTPS:
 		https := healthcheck.NewHTTPChecker(ip, port)
@@ -278,8 +383,9 @@ func (h *healthcheckManager) newConfig(id healthcheck.Id, key checkKey, hc *conf
 		https.TLSVerify = hc.TLSVerify
 		https.Proxy = hc.Proxy
 		if hc.Method != "" {
-			https.Method = hc.Method
+			https.Method = strings.ToUpper(hc.Method)
 		}
+		https.Body = hc.Body
 		checker = https
 	case seesaw.HCTypeICMP:
 		// DSR cannot be used with ICMP (at least for now).
@@ -289,6 +395,49 @@ func (h *healthcheckManager) newConfig(id healthcheck.Id, key checkKey, hc *conf
 		ping := healthcheck.NewPingChecker(ip)
 		target = &ping.Target
 		checker = ping
+	case seesaw.HCTypeMySQL:
+		mysql := healthcheck.NewMySQLChecker(ip, port)
+		target = &mysql.Target
+		// The send value is of the form "<username>:<password>", since
+		// the password may itself contain colons.
+		if hc.Send != "" {
+			send := strings.SplitN(hc.Send, ":", 2)
+			if len(send) != 2 || send[0] == "" {
+				return nil, errors.New("MySQL healthcheck has invalid send value")
+			}
+			mysql.Username = send[0]
+			mysql.Password = send[1]
+		}
+		mysql.Query = hc.Receive
+		mysql.ExpectWritable = hc.ExpectWritable
+		if mysql.Username == "" && (mysql.Query != "" || mysql.ExpectWritable) {
+			return nil, errors.New("MySQL healthcheck requires credentials to run queries")
+		}
+		checker = mysql
+	case seesaw.HCTypeNTP:
+		ntp := healthcheck.NewNTPChecker(ip, port)
+		target = &ntp.Target
+		// The receive value is the acceptable stratum range, of the
+		// form "<min>-<max>".
+		if hc.Receive != "" {
+			strata := strings.SplitN(hc.Receive, "-", 2)
+			if len(strata) != 2 {
+				return nil, errors.New("NTP healthcheck has invalid stratum range")
+			}
+			min, err := strconv.Atoi(strata[0])
+			if err != nil {
+				return nil, fmt.Errorf("NTP healthcheck has invalid minimum stratum %q", strata[0])
+			}
+			max, err := strconv.Atoi(strata[1])
+			if err != nil {
+				return nil, fmt.Errorf("NTP healthcheck has invalid maximum stratum %q", strata[1])
+			}
+			if min < 1 || max > 15 || min > max {
+				return nil, fmt.Errorf("NTP healthcheck has invalid stratum range %d-%d", min, max)
+			}
+			ntp.MinStratum, ntp.MaxStratum = min, max
+		}
+		checker = ntp
 	case seesaw.HCTypeRADIUS:
 		radius := healthcheck.NewRADIUSChecker(ip, port)
 		target = &radius.Target
@@ -305,6 +454,37 @@ func (h *healthcheckManager) newConfig(id healthcheck.Id, key checkKey, hc *conf
 			radius.Response = hc.Receive
 		}
 		checker = radius
+	case seesaw.HCTypeRedis:
+		redis := healthcheck.NewRedisChecker(ip, port)
+		target = &redis.Target
+		// The send value is of the form "<db>:<password>", since the
+		// password may itself contain colons.
+		if hc.Send != "" {
+			send := strings.SplitN(hc.Send, ":", 2)
+			if len(send) != 2 {
+				return nil, errors.New("Redis healthcheck has invalid send value")
+			}
+			if send[0] != "" {
+				db, err := strconv.Atoi(send[0])
+				if err != nil || db < 0 {
+					return nil, fmt.Errorf("Redis healthcheck has invalid database %q", send[0])
+				}
+				redis.DB = db
+			}
+			redis.Password = send[1]
+		}
+		redis.Role = hc.Receive
+		checker = redis
+	case seesaw.HCTypeSMTP, seesaw.HCTypeSMTPStartTLS:
+		smtp := healthcheck.NewSMTPChecker(ip, port)
+		target = &smtp.Target
+		smtp.Hostname = hc.Send
+		if hc.Code != 0 {
+			smtp.EHLOCode = hc.Code
+		}
+		smtp.StartTLS = hc.Type == seesaw.HCTypeSMTPStartTLS
+		smtp.TLSVerify = hc.TLSVerify
+		checker = smtp
 	case seesaw.HCTypeTCP:
 		tcp := healthcheck.NewTCPChecker(ip, port)
 		target = &tcp.Target
@@ -330,13 +510,25 @@ func (h *healthcheckManager) newConfig(id healthcheck.Id, key checkKey, hc *conf
 	}
 
 	target.Host = host
+	target.Hostname = hostname
 	target.Mark = mark
 	target.Mode = hc.Mode
+	if hc.SourceIP != "" {
+		target.SourceIP = net.ParseIP(hc.SourceIP)
+		if target.SourceIP == nil {
+			return nil, fmt.Errorf("invalid healthcheck source IP %q", hc.SourceIP)
+		}
+	}
+	target.SourceInterface = hc.SourceInterface
+	target.ConnectTimeout = hc.ConnectTimeout
 
 	hcc := healthcheck.NewConfig(id, checker)
+	hcc.Backend = host.String()
+	hcc.Name = key.name
 	hcc.Interval = hc.Interval
 	hcc.Timeout = hc.Timeout
 	hcc.Retries = hc.Retries
+	hcc.SlowThreshold = hc.SlowThreshold
 
 	return hcc, nil
 }
@@ -143,15 +143,18 @@ var hcTests = []struct {
 		},
 		map[checkKey]*healthcheck.Config{
 			key1: {
+				Backend:  "1.1.1.2",
+				Name:     "HTTP/3901_0",
 				Interval: 100 * time.Second,
 				Timeout:  50 * time.Second,
 				Checker: &healthcheck.HTTPChecker{
 					Target: healthcheck.Target{
-						IP:    net.ParseIP("1.1.1.2"),
-						Host:  net.ParseIP("1.1.1.2"),
-						Mode:  seesaw.HCModePlain,
-						Port:  3901,
-						Proto: seesaw.IPProtoTCP,
+						IP:       net.ParseIP("1.1.1.2"),
+						Host:     net.ParseIP("1.1.1.2"),
+						Hostname: "dns1-1.example.com.",
+						Mode:     seesaw.HCModePlain,
+						Port:     3901,
+						Proto:    seesaw.IPProtoTCP,
 					},
 					Secure:       false,
 					TLSVerify:    true,
@@ -162,15 +165,18 @@ var hcTests = []struct {
 				},
 			},
 			key2: {
+				Backend:  "2012::cafd",
+				Name:     "HTTP/3901_0",
 				Interval: 100 * time.Second,
 				Timeout:  50 * time.Second,
 				Checker: &healthcheck.HTTPChecker{
 					Target: healthcheck.Target{
-						IP:    net.ParseIP("2012::cafd"),
-						Host:  net.ParseIP("2012::cafd"),
-						Mode:  seesaw.HCModePlain,
-						Port:  3901,
-						Proto: seesaw.IPProtoTCP,
+						IP:       net.ParseIP("2012::cafd"),
+						Host:     net.ParseIP("2012::cafd"),
+						Hostname: "dns1-1.example.com.",
+						Mode:     seesaw.HCModePlain,
+						Port:     3901,
+						Proto:    seesaw.IPProtoTCP,
 					},
 					Secure:       false,
 					TLSVerify:    true,
@@ -181,30 +187,36 @@ var hcTests = []struct {
 				},
 			},
 			key3: {
+				Backend:  "1.1.1.2",
+				Name:     "TCP/81_0",
 				Interval: 100 * time.Second,
 				Timeout:  50 * time.Second,
 				Checker: &healthcheck.TCPChecker{
 					Target: healthcheck.Target{
-						IP:    net.ParseIP("1.1.1.2"),
-						Host:  net.ParseIP("1.1.1.2"),
-						Mode:  seesaw.HCModePlain,
-						Port:  81,
-						Proto: seesaw.IPProtoTCP,
+						IP:       net.ParseIP("1.1.1.2"),
+						Host:     net.ParseIP("1.1.1.2"),
+						Hostname: "dns1-1.example.com.",
+						Mode:     seesaw.HCModePlain,
+						Port:     81,
+						Proto:    seesaw.IPProtoTCP,
 					},
 					Send:    "some tcp request",
 					Receive: "some tcp response",
 				},
 			},
 			key4: {
+				Backend:  "2012::cafd",
+				Name:     "TCP/81_0",
 				Interval: 100 * time.Second,
 				Timeout:  50 * time.Second,
 				Checker: &healthcheck.TCPChecker{
 					Target: healthcheck.Target{
-						IP:    net.ParseIP("2012::cafd"),
-						Host:  net.ParseIP("2012::cafd"),
-						Mode:  seesaw.HCModePlain,
-						Port:  81,
-						Proto: seesaw.IPProtoTCP,
+						IP:       net.ParseIP("2012::cafd"),
+						Host:     net.ParseIP("2012::cafd"),
+						Hostname: "dns1-1.example.com.",
+						Mode:     seesaw.HCModePlain,
+						Port:     81,
+						Proto:    seesaw.IPProtoTCP,
 					},
 					Send:    "some tcp request",
 					Receive: "some tcp response",
@@ -458,3 +470,200 @@ func TestHealthcheckUpdates(t *testing.T) {
 		}
 	}
 }
+
+func TestExecHealthcheckAllowed(t *testing.T) {
+	engine := newTestEngine()
+	hcm := newHealthcheckManager(engine)
+	key := checkKey{
+		vserverIP:       seesaw.ParseIP("1.1.1.1"),
+		backendIP:       seesaw.ParseIP("1.1.1.2"),
+		healthcheckType: seesaw.HCTypeExec,
+		healthcheckPort: 80,
+		name:            "EXEC/80_0",
+	}
+	hc := config.NewHealthcheck(seesaw.HCModePlain, seesaw.HCTypeExec, 80)
+	hc.Send = "/usr/local/bin/check --verbose"
+
+	if _, err := hcm.newConfig(1, key, hc, ""); err == nil {
+		t.Errorf("newConfig succeeded for exec healthcheck without AllowExecChecks")
+	}
+
+	engine.config.AllowExecChecks = true
+	cfg, err := hcm.newConfig(1, key, hc, "")
+	if err != nil {
+		t.Fatalf("newConfig failed for exec healthcheck: %v", err)
+	}
+	exec, ok := cfg.Checker.(*healthcheck.ExecChecker)
+	if !ok {
+		t.Fatalf("Got checker %T, want *healthcheck.ExecChecker", cfg.Checker)
+	}
+	if exec.Command != "/usr/local/bin/check" || len(exec.Args) != 1 || exec.Args[0] != "--verbose" {
+		t.Errorf("Got command %q with args %q", exec.Command, exec.Args)
+	}
+}
+
+func TestRedisHealthcheckConfig(t *testing.T) {
+	hcm := newHealthcheckManager(newTestEngine())
+	key := checkKey{
+		vserverIP:       seesaw.ParseIP("1.1.1.1"),
+		backendIP:       seesaw.ParseIP("1.1.1.2"),
+		healthcheckType: seesaw.HCTypeRedis,
+		healthcheckPort: 6379,
+		name:            "REDIS/6379_0",
+	}
+	for _, test := range []struct {
+		send     string
+		db       int
+		password string
+		ok       bool
+	}{
+		{"", 0, "", true},
+		{":secret", 0, "secret", true},
+		{"2:", 2, "", true},
+		{"3:se:cret", 3, "se:cret", true},
+		{"secret", 0, "", false},
+		{"x:secret", 0, "", false},
+		{"-1:secret", 0, "", false},
+	} {
+		hc := config.NewHealthcheck(seesaw.HCModePlain, seesaw.HCTypeRedis, 6379)
+		hc.Send = test.send
+		hc.Receive = "master"
+		cfg, err := hcm.newConfig(1, key, hc, "")
+		if (err == nil) != test.ok {
+			t.Errorf("newConfig with send %q got error %v, want ok %v", test.send, err, test.ok)
+			continue
+		}
+		if !test.ok {
+			continue
+		}
+		redis, ok := cfg.Checker.(*healthcheck.RedisChecker)
+		if !ok {
+			t.Fatalf("Got checker %T, want *healthcheck.RedisChecker", cfg.Checker)
+		}
+		if redis.DB != test.db || redis.Password != test.password || redis.Role != "master" {
+			t.Errorf("newConfig with send %q got db %d, password %q, role %q", test.send, redis.DB, redis.Password, redis.Role)
+		}
+	}
+}
+
+func TestHealthcheckSourceConfig(t *testing.T) {
+	hcm := newHealthcheckManager(newTestEngine())
+	key := checkKey{
+		vserverIP:       seesaw.ParseIP("1.1.1.1"),
+		backendIP:       seesaw.ParseIP("1.1.1.2"),
+		healthcheckType: seesaw.HCTypeTCP,
+		healthcheckPort: 80,
+		name:            "TCP/80_0",
+	}
+	for _, test := range []struct {
+		ip string
+		ok bool
+	}{
+		{"", true},
+		{"10.0.0.1", true},
+		{"2001:db8::1", true},
+		{"10.0.0", false},
+	} {
+		hc := config.NewHealthcheck(seesaw.HCModePlain, seesaw.HCTypeTCP, 80)
+		hc.SourceIP = test.ip
+		hc.SourceInterface = "eth1"
+		cfg, err := hcm.newConfig(1, key, hc, "")
+		if (err == nil) != test.ok {
+			t.Errorf("newConfig with source IP %q got error %v, want ok %v", test.ip, err, test.ok)
+			continue
+		}
+		if !test.ok {
+			continue
+		}
+		tcp, ok := cfg.Checker.(*healthcheck.TCPChecker)
+		if !ok {
+			t.Fatalf("Got checker %T, want *healthcheck.TCPChecker", cfg.Checker)
+		}
+		if got, want := tcp.SourceIP, net.ParseIP(test.ip); !got.Equal(want) {
+			t.Errorf("newConfig with source IP %q got source IP %v", test.ip, got)
+		}
+		if tcp.SourceInterface != "eth1" {
+			t.Errorf("newConfig got source interface %q, want %q", tcp.SourceInterface, "eth1")
+		}
+	}
+}
+
+func TestHealthcheckOverride(t *testing.T) {
+	engine := newTestEngine()
+	hcm := newHealthcheckManager(engine)
+	hcm.enable()
+	vserver := newTestVserver(engine)
+	vserver.handleConfigUpdate(&vserverConfig)
+	for _, c := range vserver.checks {
+		hc := *c.healthcheck
+		hc.Type = seesaw.HCTypeTCP
+		c.healthcheck = &hc
+	}
+	hcm.update(vserver.config.Name, vserver.checks)
+
+	var key checkKey
+	for k := range vserver.checks {
+		key = k
+		break
+	}
+	id := hcm.ids[key]
+	if _, ok := hcm.configs()[id]; !ok {
+		t.Fatalf("Healthcheck 0x%x not found in configs", id)
+	}
+
+	// Disabling the healthcheck should remove it from the configs and
+	// report it as healthy.
+	hcm.override(&seesaw.HealthcheckOverride{Id: uint64(id), OverrideState: seesaw.OverrideDisable})
+	if _, ok := hcm.configs()[id]; ok {
+		t.Errorf("Disabled healthcheck 0x%x found in configs", id)
+	}
+	if got, want := len(hcm.configs()), len(hcm.cfgs)-1; got != want {
+		t.Errorf("Got %d configs, want %d", got, want)
+	}
+	vserver.handleCheckNotification(<-vserver.notify)
+	c := vserver.checks[key]
+	if c.status.State != healthcheck.StateHealthy || c.override != seesaw.OverrideDisable {
+		t.Errorf("Disabled healthcheck has state %v, override %v, want %v, %v",
+			c.status.State, c.override, healthcheck.StateHealthy, seesaw.OverrideDisable)
+	}
+	var status *seesaw.HealthcheckStatus
+	for _, d := range c.dests {
+		for _, s := range d.healthcheckStatus() {
+			if s.Id == uint64(id) {
+				status = s
+			}
+		}
+	}
+	if status == nil || status.OverrideState != seesaw.OverrideDisable {
+		t.Errorf("Got healthcheck status %+v, want override state %v", status, seesaw.OverrideDisable)
+	}
+
+	// Notifications from the healthcheck component are ignored, while the
+	// healthcheck is disabled.
+	n := &healthcheck.Notification{Id: id, Status: healthcheck.Status{State: healthcheck.StateUnhealthy}}
+	if err := hcm.healthState(n); err != nil {
+		t.Fatalf("healthState failed: %v", err)
+	}
+	select {
+	case n := <-vserver.notify:
+		t.Errorf("Got unexpected notification for disabled healthcheck: %+v", n)
+	default:
+	}
+
+	// The disabled state is retained across updates.
+	hcm.update(vserver.config.Name, vserver.checks)
+	if _, ok := hcm.configs()[id]; ok {
+		t.Errorf("Disabled healthcheck 0x%x found in configs after update", id)
+	}
+	vserver.handleCheckNotification(<-vserver.notify)
+
+	// Clearing the override enables the healthcheck.
+	hcm.override(&seesaw.HealthcheckOverride{Id: uint64(id), OverrideState: seesaw.OverrideDefault})
+	if _, ok := hcm.configs()[id]; !ok {
+		t.Errorf("Enabled healthcheck 0x%x not found in configs", id)
+	}
+	vserver.handleCheckNotification(<-vserver.notify)
+	if c.override != seesaw.OverrideDefault {
+		t.Errorf("Enabled healthcheck has override %v, want %v", c.override, seesaw.OverrideDefault)
+	}
+}
@@ -20,11 +20,13 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/wy2745/seesaw/common/ipc"
 	"github.com/wy2745/seesaw/common/seesaw"
 	"github.com/wy2745/seesaw/engine/config"
 	"github.com/wy2745/seesaw/healthcheck"
+	"github.com/wy2745/seesaw/ipvs"
 	"github.com/wy2745/seesaw/quagga"
 
 	log "github.com/golang/glog"
@@ -41,6 +43,7 @@ func init() {
 // SeesawEngine provides the IPC interface to the Seesaw Engine.
 type SeesawEngine struct {
 	engine *Engine
+	peer   *ipcPeer
 }
 
 func (s *SeesawEngine) trace(call string, ctx *ipc.Context) {
@@ -54,11 +57,80 @@ func (s *SeesawEngine) Failover(ctx *ipc.Context, reply *int) error {
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("Failover", ctx); err != nil {
+		return err
+	}
+
+	if err := s.engine.haManager.requestFailover(false); err != nil {
+		return err
+	}
+	s.engine.audit.record(s.auditActor(ctx), auditFailover, "", nil, nil)
+	return nil
+}
+
+// FailoverTo requests a failover that results in the specified node becoming
+// master. If no node is specified, this is the same as Failover.
+func (s *SeesawEngine) FailoverTo(args *ipc.Failover, reply *int) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("FailoverTo", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("FailoverTo", ctx); err != nil {
+		return err
 	}
 
-	return s.engine.haManager.requestFailover(false)
+	if err := s.engine.haManager.requestFailoverTo(args.Node); err != nil {
+		return err
+	}
+	s.engine.audit.record(s.auditActor(ctx), auditFailover, args.Node, nil, nil)
+	return nil
+}
+
+// Maintenance enables or disables maintenance mode for this node and returns
+// the resulting maintenance status.
+func (s *SeesawEngine) Maintenance(args *ipc.Maintenance, reply *seesaw.MaintenanceStatus) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("Maintenance", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("Maintenance", ctx); err != nil {
+		return err
+	}
+
+	before := s.engine.maintenanceStatus().Enabled
+	s.engine.requestMaintenance(args.Enable)
+	s.engine.audit.record(s.auditActor(ctx), auditMaintenance, "", before, args.Enable)
+	if reply != nil {
+		*reply = *s.engine.maintenanceStatus()
+	}
+	return nil
+}
+
+// MaintenanceStatus returns the maintenance status for this node.
+func (s *SeesawEngine) MaintenanceStatus(ctx *ipc.Context, reply *seesaw.MaintenanceStatus) error {
+	s.trace("MaintenanceStatus", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("MaintenanceStatus", ctx); err != nil {
+		return err
+	}
+
+	if reply != nil {
+		*reply = *s.engine.maintenanceStatus()
+	}
+	return nil
 }
 
 // HAConfig returns the high-availability configuration for this node as
@@ -69,8 +141,8 @@ func (s *SeesawEngine) HAConfig(ctx *ipc.Context, reply *seesaw.HAConfig) error
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("HAConfig", ctx); err != nil {
+		return err
 	}
 
 	c, err := s.engine.haConfig()
@@ -96,8 +168,8 @@ func (s *SeesawEngine) HAUpdate(args *ipc.HAStatus, failover *bool) error {
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("HAUpdate", ctx); err != nil {
+		return err
 	}
 
 	s.engine.setHAStatus(args.Status)
@@ -119,14 +191,34 @@ func (s *SeesawEngine) HAState(args *ipc.HAState, reply *int) error {
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("HAState", ctx); err != nil {
+		return err
 	}
 
 	s.engine.setHAState(args.State)
 	return nil
 }
 
+// HAGroupState advises the Engine of the current high-availability state of
+// an additional VRRP group, as determined by the Seesaw HA component.
+func (s *SeesawEngine) HAGroupState(args *ipc.HAGroupState, reply *int) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("HAGroupState", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("HAGroupState", ctx); err != nil {
+		return err
+	}
+
+	s.engine.setHAGroupState(args.VRID, args.State)
+	return nil
+}
+
 // HAStatus returns the current HA status from the Seesaw Engine.
 func (s *SeesawEngine) HAStatus(ctx *ipc.Context, status *seesaw.HAStatus) error {
 	s.trace("HAStatus", ctx)
@@ -134,8 +226,8 @@ func (s *SeesawEngine) HAStatus(ctx *ipc.Context, status *seesaw.HAStatus) error
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("HAStatus", ctx); err != nil {
+		return err
 	}
 
 	if status != nil {
@@ -152,8 +244,8 @@ func (s *SeesawEngine) Healthchecks(ctx *ipc.Context, reply *healthcheck.Checks)
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("Healthchecks", ctx); err != nil {
+		return err
 	}
 
 	configs := s.engine.hcManager.configs()
@@ -175,8 +267,8 @@ func (s *SeesawEngine) HealthState(args *healthcheck.HealthState, reply *int) er
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("HealthState", ctx); err != nil {
+		return err
 	}
 
 	for _, n := range args.Notifications {
@@ -195,8 +287,8 @@ func (s *SeesawEngine) ClusterStatus(ctx *ipc.Context, reply *seesaw.ClusterStat
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("ClusterStatus", ctx); err != nil {
+		return err
 	}
 
 	s.engine.clusterLock.RLock()
@@ -223,8 +315,8 @@ func (s *SeesawEngine) ConfigStatus(ctx *ipc.Context, reply *seesaw.ConfigStatus
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("ConfigStatus", ctx); err != nil {
+		return err
 	}
 
 	s.engine.clusterLock.RLock()
@@ -249,6 +341,32 @@ func (s *SeesawEngine) ConfigStatus(ctx *ipc.Context, reply *seesaw.ConfigStatus
 	return nil
 }
 
+// RunningConfig returns the cluster configuration that is currently in use.
+func (s *SeesawEngine) RunningConfig(ctx *ipc.Context, reply *seesaw.ClusterConfig) error {
+	s.trace("RunningConfig", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("RunningConfig", ctx); err != nil {
+		return err
+	}
+
+	s.engine.clusterLock.RLock()
+	cluster := s.engine.cluster
+	text := s.engine.clusterText
+	s.engine.clusterLock.RUnlock()
+
+	if cluster == nil {
+		return errors.New("no cluster configuration loaded")
+	}
+	if reply != nil {
+		reply.Site = cluster.Site
+		reply.Text = text
+	}
+	return nil
+}
+
 // ConfigReload requests a configuration reload.
 func (s *SeesawEngine) ConfigReload(ctx *ipc.Context, reply *int) error {
 	s.trace("ConfigReload", ctx)
@@ -256,11 +374,40 @@ func (s *SeesawEngine) ConfigReload(ctx *ipc.Context, reply *int) error {
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("ConfigReload", ctx); err != nil {
+		return err
+	}
+
+	if err := s.engine.notifier.Reload(); err != nil {
+		return err
+	}
+	s.engine.audit.record(s.auditActor(ctx), auditConfigReload, s.engine.notifier.Source().String(), nil, nil)
+	return nil
+}
+
+// ReloadConfig reloads the configuration from the configuration source and
+// returns a summary of the changes, which are applied incrementally to the
+// running vservers.
+func (s *SeesawEngine) ReloadConfig(ctx *ipc.Context, reply *seesaw.ClusterChanges) error {
+	s.trace("ReloadConfig", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("ReloadConfig", ctx); err != nil {
+		return err
 	}
 
-	return s.engine.notifier.Reload()
+	changes, err := s.engine.notifier.ReloadChanges()
+	if err != nil {
+		return err
+	}
+	log.Infof("Configuration reloaded from %s: %v", changes.Source, changes)
+	s.engine.audit.record(s.auditActor(ctx), auditConfigReload, changes.Source, nil, changes.String())
+	if reply != nil {
+		*reply = *changes
+	}
+	return nil
 }
 
 // ConfigSource requests the configuration source be changed to the specified
@@ -275,22 +422,27 @@ func (s *SeesawEngine) ConfigSource(args *ipc.ConfigSource, oldSource *string) e
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("ConfigSource", ctx); err != nil {
+		return err
 	}
 
+	before := s.engine.notifier.Source().String()
 	if oldSource != nil {
-		*oldSource = s.engine.notifier.Source().String()
+		*oldSource = before
 	}
 	newSource := args.Source
 	if newSource == "" {
 		return nil
 	}
+	if err := s.authoriseChange("ConfigSource", ctx); err != nil {
+		return err
+	}
 	source, err := config.SourceByName(newSource)
 	if err != nil {
 		return err
 	}
 	s.engine.notifier.SetSource(source)
+	s.engine.audit.record(s.auditActor(ctx), auditConfigSource, "", before, source.String())
 	return nil
 }
 
@@ -301,8 +453,8 @@ func (s *SeesawEngine) BGPNeighbors(ctx *ipc.Context, reply *quagga.Neighbors) e
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("BGPNeighbors", ctx); err != nil {
+		return err
 	}
 
 	if reply == nil {
@@ -314,6 +466,24 @@ func (s *SeesawEngine) BGPNeighbors(ctx *ipc.Context, reply *quagga.Neighbors) e
 	return nil
 }
 
+// BGPStatus returns the status of the BGP sessions with our peers.
+func (s *SeesawEngine) BGPStatus(ctx *ipc.Context, reply *seesaw.BGPStatus) error {
+	s.trace("BGPStatus", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("BGPStatus", ctx); err != nil {
+		return err
+	}
+
+	if reply == nil {
+		return fmt.Errorf("BGPStatus is nil")
+	}
+	reply.Peers = s.engine.bgpManager.peerStatus()
+	return nil
+}
+
 // VLANs returns a list of VLANs configured for this cluster.
 func (s *SeesawEngine) VLANs(ctx *ipc.Context, reply *seesaw.VLANs) error {
 	s.trace("VLANs", ctx)
@@ -321,8 +491,8 @@ func (s *SeesawEngine) VLANs(ctx *ipc.Context, reply *seesaw.VLANs) error {
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("VLANs", ctx); err != nil {
+		return err
 	}
 
 	if reply == nil {
@@ -344,8 +514,8 @@ func (s *SeesawEngine) Vservers(ctx *ipc.Context, reply *seesaw.VserverMap) erro
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("Vservers", ctx); err != nil {
+		return err
 	}
 
 	if reply == nil {
@@ -360,6 +530,77 @@ func (s *SeesawEngine) Vservers(ctx *ipc.Context, reply *seesaw.VserverMap) erro
 	return nil
 }
 
+// VserverStats returns the IPVS statistics for the destinations of a vserver.
+func (s *SeesawEngine) VserverStats(args *ipc.VserverStats, reply *seesaw.VserverStats) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("VserverStats", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("VserverStats", ctx); err != nil {
+		return err
+	}
+
+	if reply == nil {
+		return fmt.Errorf("VserverStats is nil")
+	}
+	s.engine.vserverLock.RLock()
+	vserver, ok := s.engine.vserverSnapshots[args.Vserver]
+	s.engine.vserverLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("vserver %q not found", args.Vserver)
+	}
+
+	reply.Destinations = make([]seesaw.DestinationStats, 0)
+	for _, svc := range vserver.Services {
+		for _, d := range svc.Destinations {
+			stats := seesaw.DestinationStats{
+				DestinationStats: &ipvs.DestinationStats{},
+				Name:             d.Name,
+			}
+			if d.Backend != nil {
+				stats.Backend = d.Backend.Hostname
+			}
+			if d.Stats != nil && d.Stats.DestinationStats != nil {
+				*stats.DestinationStats = *d.Stats.DestinationStats
+			}
+			reply.Destinations = append(reply.Destinations, stats)
+		}
+	}
+	sort.Sort(seesaw.DestinationStatsByName(reply.Destinations))
+	return nil
+}
+
+// Connections returns the IPVS connection table entries that match a filter.
+func (s *SeesawEngine) Connections(args *ipc.Connections, reply *seesaw.ConnEntries) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("Connections", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("Connections", ctx); err != nil {
+		return err
+	}
+
+	if reply == nil {
+		return fmt.Errorf("ConnEntries is nil")
+	}
+	ce, err := s.engine.connections(&args.Filter)
+	if err != nil {
+		return err
+	}
+	*reply = *ce
+	return nil
+}
+
 // OverrideBackend passes a BackendOverride to the engine.
 func (s *SeesawEngine) OverrideBackend(args *ipc.Override, reply *int) error {
 	if args == nil {
@@ -371,14 +612,15 @@ func (s *SeesawEngine) OverrideBackend(args *ipc.Override, reply *int) error {
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("OverrideBackend", ctx); err != nil {
+		return err
 	}
 
 	if args.Backend == nil {
 		return errors.New("backend is nil")
 	}
 	s.engine.queueOverride(args.Backend)
+	s.engine.auditOverride(s.auditActor(ctx), args.Backend)
 	return nil
 }
 
@@ -393,14 +635,68 @@ func (s *SeesawEngine) OverrideDestination(args *ipc.Override, reply *int) error
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("OverrideDestination", ctx); err != nil {
+		return err
 	}
 
 	if args.Destination == nil {
 		return errors.New("destination is nil")
 	}
 	s.engine.queueOverride(args.Destination)
+	s.engine.auditOverride(s.auditActor(ctx), args.Destination)
+	return nil
+}
+
+// OverrideHealthcheck passes a HealthcheckOverride to the engine.
+func (s *SeesawEngine) OverrideHealthcheck(args *ipc.Override, reply *int) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("OverrideHealthcheck", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("OverrideHealthcheck", ctx); err != nil {
+		return err
+	}
+
+	if args.Healthcheck == nil {
+		return errors.New("healthcheck is nil")
+	}
+	s.engine.queueOverride(args.Healthcheck)
+	s.engine.auditOverride(s.auditActor(ctx), args.Healthcheck)
+	return nil
+}
+
+// DrainBackend passes a BackendOverride to the engine that results in the
+// backend being drained. Drained backends have their IPVS destinations
+// updated with a weight of zero, so that no new connections are sent to them
+// while existing connections are left intact.
+func (s *SeesawEngine) DrainBackend(args *ipc.Override, reply *int) error {
+	if args == nil {
+		return errors.New("args is nil")
+	}
+	ctx := args.Ctx
+	s.trace("DrainBackend", ctx)
+	if ctx == nil {
+		return errors.New("context is nil")
+	}
+
+	if err := s.authorise("DrainBackend", ctx); err != nil {
+		return err
+	}
+
+	if args.Backend == nil {
+		return errors.New("backend is nil")
+	}
+	o := &seesaw.BackendOverride{
+		Hostname:      args.Backend.Hostname,
+		OverrideState: seesaw.OverrideDrain,
+	}
+	s.engine.queueOverride(o)
+	s.engine.auditOverride(s.auditActor(ctx), o)
 	return nil
 }
 
@@ -415,14 +711,15 @@ func (s *SeesawEngine) OverrideVserver(args *ipc.Override, reply *int) error {
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("OverrideVserver", ctx); err != nil {
+		return err
 	}
 
 	if args.Vserver == nil {
 		return errors.New("vserver is nil")
 	}
 	s.engine.queueOverride(args.Vserver)
+	s.engine.auditOverride(s.auditActor(ctx), args.Vserver)
 	return nil
 }
 
@@ -433,8 +730,8 @@ func (s *SeesawEngine) Backends(ctx *ipc.Context, reply *int) error {
 		return errors.New("context is nil")
 	}
 
-	if !ctx.IsTrusted() {
-		return errors.New("insufficient access")
+	if err := s.authorise("Backends", ctx); err != nil {
+		return err
 	}
 
 	// TODO(jsing): Implement this function.
@@ -17,9 +17,113 @@
 package engine
 
 import (
+	"os"
 	"testing"
+	"time"
+
+	"github.com/wy2745/seesaw/common/ipc"
+	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/engine/config"
+	"github.com/wy2745/seesaw/ipvs"
 )
 
 func TestEnableDisableBackend(t *testing.T) {
 	// TODO(angusc): Implement this function.
 }
+
+func TestVserverStats(t *testing.T) {
+	engine := newTestEngine()
+	seesawEngine := &SeesawEngine{engine: engine}
+	engine.vserverSnapshots["vserver1"] = &seesaw.Vserver{
+		Name: "vserver1",
+		Services: map[seesaw.ServiceKey]*seesaw.Service{
+			serviceKey1: {
+				ServiceKey: serviceKey1,
+				Destinations: map[string]*seesaw.Destination{
+					backend1.Hostname: {
+						Name:    "vserver1/1.1.1.10:53/udp",
+						Backend: backend1,
+						Stats: &seesaw.DestinationStats{
+							DestinationStats: &ipvs.DestinationStats{ActiveConns: 5},
+						},
+					},
+					backend2.Hostname: {
+						Name:    "vserver1/1.1.1.11:53/udp",
+						Backend: backend2,
+						Stats:   &seesaw.DestinationStats{},
+					},
+				},
+			},
+		},
+	}
+
+	ctx := ipc.NewTrustedContext(seesaw.SCLocalCLI)
+	var reply seesaw.VserverStats
+	if err := seesawEngine.VserverStats(&ipc.VserverStats{Ctx: ctx, Vserver: "vserver1"}, &reply); err != nil {
+		t.Fatalf("VserverStats failed: %v", err)
+	}
+	if got, want := len(reply.Destinations), 2; got != want {
+		t.Fatalf("Got %d destinations, want %d", got, want)
+	}
+	for i, backend := range []*seesaw.Backend{backend1, backend2} {
+		if got, want := reply.Destinations[i].Backend, backend.Hostname; got != want {
+			t.Errorf("Destination %d has backend %q, want %q", i, got, want)
+		}
+	}
+	if got, want := reply.Destinations[0].ActiveConns, uint32(5); got != want {
+		t.Errorf("Got %d active connections, want %d", got, want)
+	}
+
+	if err := seesawEngine.VserverStats(&ipc.VserverStats{Ctx: ctx, Vserver: "vserver2"}, &reply); err == nil {
+		t.Error("VserverStats succeeded for non-existent vserver")
+	}
+}
+
+func TestAuthorise(t *testing.T) {
+	engine := newTestEngine()
+	engine.config.IPCAuthorization = config.IPCAuth{
+		"failover":        {"alice"},
+		config.IPCAuthAll: {"ops"},
+	}
+	user := uint32(os.Getuid()) + 1
+	local := ipc.NewTrustedContext(seesaw.SCLocalCLI)
+	remote := func(user string) *ipc.Context {
+		return ipc.NewTrustedRemoteContext(ipc.Peer{Component: seesaw.SCRemoteCLI}, seesaw.SCECU, user)
+	}
+	expired := ipc.NewTrustedContext(seesaw.SCLocalCLI)
+	expired.Deadline = time.Now().Add(-time.Second)
+
+	tests := []struct {
+		desc string
+		peer *ipcPeer
+		call string
+		ctx  *ipc.Context
+		ok   bool
+	}{
+		{"read-only call", &ipcPeer{uid: user, user: "bob"}, "Vservers", local, true},
+		{"untrusted context", &ipcPeer{uid: user, user: "alice"}, "Vservers", ipc.NewAuthContext(seesaw.SCLocalCLI, ""), false},
+		{"authorised user", &ipcPeer{uid: user, user: "alice"}, "Failover", local, true},
+		{"user not authorised for call", &ipcPeer{uid: user, user: "alice"}, "OverrideBackend", local, false},
+		{"user authorised for all calls", &ipcPeer{uid: user, user: "ops"}, "OverrideBackend", local, true},
+		{"unauthorised user", &ipcPeer{uid: user, user: "bob"}, "Failover", local, false},
+		{"unprivileged proxy", &ipcPeer{uid: user, user: "bob"}, "Failover", remote("alice"), false},
+		{"unknown peer", nil, "Failover", local, false},
+		{"seesaw component", &ipcPeer{uid: 0, user: "root"}, "HAState", ipc.NewTrustedContext(seesaw.SCHA), true},
+		{"ECU authorised user", &ipcPeer{uid: 0, user: "root"}, "Failover", remote("alice"), true},
+		{"ECU unauthorised user", &ipcPeer{uid: 0, user: "root"}, "Failover", remote("eve"), false},
+		{"expired deadline", &ipcPeer{uid: 0, user: "root"}, "Vservers", expired, false},
+	}
+	for _, test := range tests {
+		s := &SeesawEngine{engine: engine, peer: test.peer}
+		err := s.authorise(test.call, test.ctx)
+		if got := err == nil; got != test.ok {
+			t.Errorf("Test %q: authorise(%q) returned %v, want success %t", test.desc, test.call, err, test.ok)
+		}
+	}
+
+	engine.config.IPCAuthorization = nil
+	s := &SeesawEngine{engine: engine, peer: &ipcPeer{uid: user, user: "bob"}}
+	if err := s.authorise("Failover", local); err != nil {
+		t.Errorf("authorise failed without IPC authorisation: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+// This file contains functions to manage maintenance mode for a Seesaw
+// Engine. While in maintenance, the node relinquishes HA mastership,
+// withdraws its anycast advertisements and defers configuration changes,
+// shutting down its vservers once it is no longer the HA master.
+
+import (
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+
+	log "github.com/golang/glog"
+)
+
+// maintenanceRequest is a request to enable or disable maintenance mode. The
+// done channel is closed once the request has been processed.
+type maintenanceRequest struct {
+	enable bool
+	done   chan struct{}
+}
+
+// inMaintenance returns true if the node is in maintenance mode.
+func (e *Engine) inMaintenance() bool {
+	e.maintenanceLock.RLock()
+	defer e.maintenanceLock.RUnlock()
+	return e.maintenance.Enabled
+}
+
+// maintenanceStatus returns the current maintenance status.
+func (e *Engine) maintenanceStatus() *seesaw.MaintenanceStatus {
+	e.maintenanceLock.RLock()
+	ms := e.maintenance
+	e.maintenanceLock.RUnlock()
+
+	ms.HAState = e.haManager.state()
+	e.vserverLock.RLock()
+	ms.Vservers = len(e.vserverSnapshots)
+	e.vserverLock.RUnlock()
+	ms.Safe = ms.Enabled && ms.HAState != seesaw.HAMaster && ms.Vservers == 0
+	return &ms
+}
+
+// requestMaintenance requests that maintenance mode be enabled or disabled
+// and waits for the request to be processed by the manager.
+func (e *Engine) requestMaintenance(enable bool) {
+	req := &maintenanceRequest{enable: enable, done: make(chan struct{})}
+	e.maintenanceChan <- req
+	<-req.done
+}
+
+// setMaintenance enables or disables maintenance mode.
+func (e *Engine) setMaintenance(enable bool) {
+	if enable == e.inMaintenance() {
+		return
+	}
+	e.maintenanceLock.Lock()
+	e.maintenance.Enabled = enable
+	e.maintenance.Since = time.Now()
+	e.maintenanceLock.Unlock()
+
+	if !enable {
+		log.Infof("Leaving maintenance mode")
+		e.advertiseAnycast()
+		e.applyConfig()
+		return
+	}
+
+	log.Infof("Entering maintenance mode")
+	e.withdrawAnycast()
+	if e.haManager.state() == seesaw.HAMaster {
+		if err := e.haManager.requestFailover(true); err != nil {
+			log.Warningf("Failed to request failover for maintenance: %v", err)
+		}
+	}
+	e.updateMaintenance()
+}
+
+// updateMaintenance shuts down the vservers and VLANs for a node that is in
+// maintenance, once it is no longer the HA master.
+func (e *Engine) updateMaintenance() {
+	if !e.inMaintenance() || e.haManager.state() == seesaw.HAMaster {
+		return
+	}
+	e.vlanLock.RLock()
+	vlans := len(e.vlans)
+	e.vlanLock.RUnlock()
+	if len(e.vservers) == 0 && len(e.drainingVservers) == 0 && vlans == 0 {
+		return
+	}
+	log.Infof("Shutting down vservers for maintenance")
+	e.shutdownVservers()
+	e.deleteVLANs()
+}
+
+// advertiseAnycast advertises routes for the service anycast VIPs.
+func (e *Engine) advertiseAnycast() {
+	if !e.config.AnycastEnabled {
+		return
+	}
+	if err := e.ncc.Dial(); err != nil {
+		log.Errorf("Failed to connect to NCC: %v", err)
+		return
+	}
+	defer e.ncc.Close()
+
+	for _, vip := range e.anycastVIPs() {
+		log.Infof("Advertising %v route for %v", e.config.RoutingProtocol, vip)
+		if err := e.advertiseRoute(vip.IP.IP(), nil); err != nil {
+			log.Errorf("Failed to advertise VIP %v: %v", vip, err)
+		}
+	}
+}
+
+// withdrawAnycast withdraws all anycast route advertisements.
+func (e *Engine) withdrawAnycast() {
+	if !e.config.AnycastEnabled {
+		return
+	}
+	if err := e.ncc.Dial(); err != nil {
+		log.Errorf("Failed to connect to NCC: %v", err)
+		return
+	}
+	defer e.ncc.Close()
+
+	log.Infof("Withdrawing all %v advertisements", e.config.RoutingProtocol)
+	if err := e.withdrawAllRoutes(); err != nil {
+		log.Errorf("Failed to withdraw all %v advertisements: %v", e.config.RoutingProtocol, err)
+	}
+}
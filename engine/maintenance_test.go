@@ -0,0 +1,86 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"net"
+	"testing"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/engine/config"
+)
+
+func TestMaintenance(t *testing.T) {
+	e := newTestEngine()
+	node := &seesaw.Node{
+		Host:     seesaw.Host{Hostname: "seesaw1.example.com", IPv4Addr: net.ParseIP("192.168.36.2")},
+		State:    seesaw.HAUnknown,
+		Priority: 255,
+	}
+	e.config.Node = node.Host
+	e.cluster = &config.Cluster{Nodes: map[string]*seesaw.Node{node.Key(): node}}
+	vlan := &seesaw.VLAN{ID: 1}
+	if err := e.lbInterface.AddVLAN(vlan); err != nil {
+		t.Fatalf("AddVLAN() failed: %v", err)
+	}
+	e.vlans[vlan.Key()] = vlan
+	e.vserverSnapshots["vserver1"] = &seesaw.Vserver{Name: "vserver1"}
+
+	if ms := e.maintenanceStatus(); ms.Enabled || ms.Safe {
+		t.Errorf("Initial maintenance status = %+v, want disabled and unsafe", ms)
+	}
+	hac, err := e.haConfig()
+	if err != nil {
+		t.Fatalf("haConfig() failed: %v", err)
+	}
+	if !hac.Enabled {
+		t.Errorf("haConfig().Enabled = false before maintenance, want true")
+	}
+
+	e.setMaintenance(true)
+	ms := e.maintenanceStatus()
+	if !ms.Enabled {
+		t.Errorf("Maintenance status Enabled = false, want true")
+	}
+	if ms.Since.IsZero() {
+		t.Errorf("Maintenance status Since is zero")
+	}
+	if ms.Vservers != 0 {
+		t.Errorf("Maintenance status Vservers = %d, want 0", ms.Vservers)
+	}
+	if !ms.Safe {
+		t.Errorf("Maintenance status Safe = false, want true")
+	}
+	if len(e.vlans) != 0 {
+		t.Errorf("Got %d VLANs in maintenance, want 0", len(e.vlans))
+	}
+	if hac, err = e.haConfig(); err != nil {
+		t.Fatalf("haConfig() failed: %v", err)
+	}
+	if hac.Enabled {
+		t.Errorf("haConfig().Enabled = true in maintenance, want false")
+	}
+
+	e.setMaintenance(false)
+	if ms := e.maintenanceStatus(); ms.Enabled || ms.Safe {
+		t.Errorf("Maintenance status after disable = %+v, want disabled and unsafe", ms)
+	}
+	if hac, err = e.haConfig(); err != nil {
+		t.Fatalf("haConfig() failed: %v", err)
+	}
+	if !hac.Enabled {
+		t.Errorf("haConfig().Enabled = false after maintenance, want true")
+	}
+}
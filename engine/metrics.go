@@ -0,0 +1,212 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+// This file contains routines for exporting the engine's operational state
+// as Prometheus metrics.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wy2745/seesaw/common/metrics"
+	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/ipvs"
+)
+
+// haStates are the HA states that are exported as labels of the HA state
+// metric.
+var haStates = []seesaw.HAState{
+	seesaw.HAUnknown,
+	seesaw.HABackup,
+	seesaw.HADisabled,
+	seesaw.HAError,
+	seesaw.HAMaster,
+	seesaw.HAShutdown,
+}
+
+// engineMetrics contains the engine state that is exported as metrics.
+type engineMetrics struct {
+	haState          seesaw.HAState
+	configGeneration uint64
+	configUpdate     time.Time
+	ipvsBatches      ipvsBatchStats
+	vservers         []*vserverMetrics
+}
+
+// ipvsBatchStats contains statistics for the IPVS batches applied by the
+// engine.
+type ipvsBatchStats struct {
+	batches    uint64
+	operations uint64
+	failed     uint64
+	duration   time.Duration // total time taken by the NCC to apply batches
+}
+
+// recordIPVSBatch updates the IPVS batch statistics with the given result.
+func (e *Engine) recordIPVSBatch(r *ipvs.BatchResult) {
+	e.ipvsBatchLock.Lock()
+	defer e.ipvsBatchLock.Unlock()
+	e.ipvsBatches.batches++
+	e.ipvsBatches.operations += uint64(len(r.Errors))
+	e.ipvsBatches.failed += uint64(r.Failed())
+	e.ipvsBatches.duration += r.Duration
+}
+
+// vserverMetrics contains the metrics for a single vserver.
+type vserverMetrics struct {
+	name            string
+	backends        int
+	healthyBackends int
+	activeConns     uint64
+	connections     uint64
+}
+
+// newVserverMetrics returns the metrics for the given vserver snapshot. A
+// backend is considered healthy if any of its destinations are healthy.
+func newVserverMetrics(vs *seesaw.Vserver) *vserverMetrics {
+	m := &vserverMetrics{name: vs.Name}
+	healthy := make(map[string]bool)
+	for _, svc := range vs.Services {
+		for name, d := range svc.Destinations {
+			healthy[name] = healthy[name] || d.Healthy
+			if d.Stats != nil && d.Stats.DestinationStats != nil {
+				m.activeConns += uint64(d.Stats.ActiveConns)
+				m.connections += uint64(d.Stats.Connections)
+			}
+		}
+	}
+	m.backends = len(healthy)
+	for _, ok := range healthy {
+		if ok {
+			m.healthyBackends++
+		}
+	}
+	return m
+}
+
+// writeMetrics writes the given engine metrics in the Prometheus text
+// exposition format.
+func writeMetrics(w io.Writer, m *engineMetrics) error {
+	var b bytes.Buffer
+
+	metrics.WriteHeader(&b, "seesaw_engine_ha_state", "gauge",
+		"The HA state of the node, with a value of 1 for the current state.")
+	for _, state := range haStates {
+		value := 0
+		if state == m.haState {
+			value = 1
+		}
+		fmt.Fprintf(&b, "seesaw_engine_ha_state{state=\"%s\"} %d\n",
+			strings.ToLower(state.String()), value)
+	}
+
+	metrics.WriteHeader(&b, "seesaw_engine_config_generation", "counter",
+		"Number of cluster configurations received by the engine.")
+	fmt.Fprintf(&b, "seesaw_engine_config_generation %d\n", m.configGeneration)
+
+	metrics.WriteHeader(&b, "seesaw_engine_config_last_update_timestamp_seconds", "gauge",
+		"Time of the last cluster configuration update in seconds since the epoch.")
+	fmt.Fprintf(&b, "seesaw_engine_config_last_update_timestamp_seconds %g\n", metrics.Timestamp(m.configUpdate))
+
+	metrics.WriteHeader(&b, "seesaw_engine_ipvs_batch_duration_seconds", "summary",
+		"Time taken to apply IPVS batches in seconds.")
+	fmt.Fprintf(&b, "seesaw_engine_ipvs_batch_duration_seconds_sum %g\n", m.ipvsBatches.duration.Seconds())
+	fmt.Fprintf(&b, "seesaw_engine_ipvs_batch_duration_seconds_count %d\n", m.ipvsBatches.batches)
+
+	metrics.WriteHeader(&b, "seesaw_engine_ipvs_batch_operations_total", "counter",
+		"Number of operations in applied IPVS batches.")
+	fmt.Fprintf(&b, "seesaw_engine_ipvs_batch_operations_total %d\n", m.ipvsBatches.operations)
+
+	metrics.WriteHeader(&b, "seesaw_engine_ipvs_batch_failed_operations_total", "counter",
+		"Number of operations in applied IPVS batches that failed.")
+	fmt.Fprintf(&b, "seesaw_engine_ipvs_batch_failed_operations_total %d\n", m.ipvsBatches.failed)
+
+	metrics.WriteHeader(&b, "seesaw_engine_vservers", "gauge",
+		"Number of vservers running on the engine.")
+	fmt.Fprintf(&b, "seesaw_engine_vservers %d\n", len(m.vservers))
+
+	vserverFamilies := []struct {
+		name  string
+		kind  string
+		help  string
+		value func(*vserverMetrics) uint64
+	}{
+		{
+			"seesaw_engine_vserver_backends", "gauge",
+			"Number of backends for the vserver.",
+			func(v *vserverMetrics) uint64 { return uint64(v.backends) },
+		},
+		{
+			"seesaw_engine_vserver_healthy_backends", "gauge",
+			"Number of healthy backends for the vserver.",
+			func(v *vserverMetrics) uint64 { return uint64(v.healthyBackends) },
+		},
+		{
+			"seesaw_engine_vserver_active_connections", "gauge",
+			"Number of active IPVS connections for the vserver.",
+			func(v *vserverMetrics) uint64 { return v.activeConns },
+		},
+		{
+			"seesaw_engine_vserver_connections_total", "counter",
+			"Total number of IPVS connections for the vserver.",
+			func(v *vserverMetrics) uint64 { return v.connections },
+		},
+	}
+	for _, vf := range vserverFamilies {
+		metrics.WriteHeader(&b, vf.name, vf.kind, vf.help)
+		for _, v := range m.vservers {
+			fmt.Fprintf(&b, "%s{vserver=\"%s\"} %d\n", vf.name, metrics.EscapeLabel(v.name), vf.value(v))
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// currentMetrics returns the metrics for the current state of the engine,
+// with vservers ordered by name.
+func (e *Engine) currentMetrics() *engineMetrics {
+	m := &engineMetrics{haState: e.haManager.state()}
+
+	e.clusterLock.RLock()
+	m.configGeneration = e.configGeneration
+	if e.cluster != nil {
+		m.configUpdate = e.cluster.Status.LastUpdate
+	}
+	e.clusterLock.RUnlock()
+
+	e.ipvsBatchLock.Lock()
+	m.ipvsBatches = e.ipvsBatches
+	e.ipvsBatchLock.Unlock()
+
+	e.vserverLock.RLock()
+	for _, vs := range e.vserverSnapshots {
+		m.vservers = append(m.vservers, newVserverMetrics(vs))
+	}
+	e.vserverLock.RUnlock()
+	sort.Slice(m.vservers, func(i, j int) bool { return m.vservers[i].name < m.vservers[j].name })
+
+	return m
+}
+
+// exportMetrics writes the metrics for the current state of the engine.
+func (e *Engine) exportMetrics(w io.Writer) error {
+	return writeMetrics(w, e.currentMetrics())
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wy2745/seesaw/common/metrics"
+	"github.com/wy2745/seesaw/common/seesaw"
+	"github.com/wy2745/seesaw/engine/config"
+	"github.com/wy2745/seesaw/ipvs"
+)
+
+func TestMetrics(t *testing.T) {
+	e := newTestEngine()
+	e.haManager.status.State = seesaw.HAMaster
+	e.cluster = &config.Cluster{}
+	e.configGeneration = 3
+
+	dest := func(healthy bool, active, conns uint32) *seesaw.Destination {
+		return &seesaw.Destination{
+			Healthy: healthy,
+			Stats: &seesaw.DestinationStats{
+				DestinationStats: &ipvs.DestinationStats{
+					Stats:       ipvs.Stats{Connections: conns},
+					ActiveConns: active,
+				},
+			},
+		}
+	}
+	e.vserverSnapshots["web"] = &seesaw.Vserver{
+		Name: "web",
+		Services: map[seesaw.ServiceKey]*seesaw.Service{
+			{AF: seesaw.IPv4, Proto: seesaw.IPProtoTCP, Port: 80}: {
+				Destinations: map[string]*seesaw.Destination{
+					"backend1": dest(true, 2, 10),
+					"backend2": dest(false, 0, 5),
+				},
+			},
+			{AF: seesaw.IPv4, Proto: seesaw.IPProtoTCP, Port: 443}: {
+				Destinations: map[string]*seesaw.Destination{
+					"backend1": dest(false, 1, 20),
+					"backend2": dest(true, 3, 30),
+				},
+			},
+		},
+	}
+	e.vserverSnapshots[`dns "quoted"`] = &seesaw.Vserver{Name: `dns "quoted"`}
+	e.recordIPVSBatch(&ipvs.BatchResult{Errors: []string{"", "", ""}, Duration: time.Second})
+	e.recordIPVSBatch(&ipvs.BatchResult{Errors: []string{"", "add destination: file exists"}, Duration: 500 * time.Millisecond})
+
+	w := httptest.NewRecorder()
+	metrics.Handler(e.exportMetrics).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if got, want := w.Header().Get("Content-Type"), metrics.ContentType; got != want {
+		t.Errorf("Got content type %q, want %q", got, want)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE seesaw_engine_ha_state gauge\n",
+		`seesaw_engine_ha_state{state="master"} 1` + "\n",
+		`seesaw_engine_ha_state{state="backup"} 0` + "\n",
+		"seesaw_engine_config_generation 3\n",
+		"# TYPE seesaw_engine_ipvs_batch_duration_seconds summary\n",
+		"seesaw_engine_ipvs_batch_duration_seconds_sum 1.5\n",
+		"seesaw_engine_ipvs_batch_duration_seconds_count 2\n",
+		"seesaw_engine_ipvs_batch_operations_total 5\n",
+		"seesaw_engine_ipvs_batch_failed_operations_total 1\n",
+		"seesaw_engine_vservers 2\n",
+		`seesaw_engine_vserver_backends{vserver="web"} 2` + "\n",
+		`seesaw_engine_vserver_healthy_backends{vserver="web"} 2` + "\n",
+		`seesaw_engine_vserver_active_connections{vserver="web"} 6` + "\n",
+		`seesaw_engine_vserver_connections_total{vserver="web"} 65` + "\n",
+		`seesaw_engine_vserver_backends{vserver="dns \"quoted\""} 0` + "\n",
+		"# TYPE seesaw_engine_vserver_connections_total counter\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics do not contain %q:\n%s", want, body)
+		}
+	}
+
+	// Vservers are ordered by name.
+	if dns, web := strings.Index(body, `vserver="dns`), strings.Index(body, `vserver="web"`); dns > web {
+		t.Errorf("Vserver metrics are not ordered by name:\n%s", body)
+	}
+}
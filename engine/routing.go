@@ -0,0 +1,74 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+// This file contains functions that advertise and withdraw the routes for
+// anycast VIPs, via the routing protocol that is configured for the cluster.
+// The caller must already be connected to the NCC.
+
+import (
+	"net"
+
+	"github.com/wy2745/seesaw/engine/config"
+
+	log "github.com/golang/glog"
+)
+
+// routeAdvertiser returns true if the configured routing protocol is able to
+// advertise the given VIP. OSPF is only able to advertise IPv4 addresses.
+func (e *Engine) routeAdvertiser(vip net.IP) bool {
+	if e.config.RoutingProtocol == config.RoutingOSPF && vip.To4() == nil {
+		log.Warningf("Not advertising %v - OSPF only supports IPv4", vip)
+		return false
+	}
+	return true
+}
+
+// advertiseRoute advertises a route for the given anycast VIP. The BGP
+// communities are ignored if the routing protocol is not BGP.
+func (e *Engine) advertiseRoute(vip net.IP, communities []string) error {
+	if !e.routeAdvertiser(vip) {
+		return nil
+	}
+	switch e.config.RoutingProtocol {
+	case config.RoutingOSPF:
+		return e.ncc.OSPFAdvertiseVIP(vip, e.config.OSPFArea)
+	default:
+		return e.ncc.BGPAdvertiseVIPCommunities(vip, communities)
+	}
+}
+
+// withdrawRoute withdraws the route for the given anycast VIP.
+func (e *Engine) withdrawRoute(vip net.IP) error {
+	if !e.routeAdvertiser(vip) {
+		return nil
+	}
+	switch e.config.RoutingProtocol {
+	case config.RoutingOSPF:
+		return e.ncc.OSPFWithdrawVIP(vip, e.config.OSPFArea)
+	default:
+		return e.ncc.BGPWithdrawVIP(vip)
+	}
+}
+
+// withdrawAllRoutes withdraws the routes for all anycast VIPs.
+func (e *Engine) withdrawAllRoutes() error {
+	switch e.config.RoutingProtocol {
+	case config.RoutingOSPF:
+		return e.ncc.OSPFWithdrawAll(e.config.OSPFArea)
+	default:
+		return e.ncc.BGPWithdrawAll()
+	}
+}
@@ -197,6 +197,14 @@ func (s *SeesawSync) Failover(arg int, reply *int) error {
 	return s.sync.engine.haManager.requestFailover(true)
 }
 
+// HAState returns our current HA state.
+func (s *SeesawSync) HAState(arg int, state *seesaw.HAState) error {
+	if state != nil {
+		*state = s.sync.engine.haManager.state()
+	}
+	return nil
+}
+
 // Healthchecks requests the current healthchecks from the peer Seesaw node.
 func (s *SeesawSync) Healthchecks(arg int, reply *int) error {
 	return errors.New("unimplemented")
@@ -420,6 +428,19 @@ func (sc *syncClient) failover() error {
 	return nil
 }
 
+// haState returns the HA state of the peer node.
+func (sc *syncClient) haState() (seesaw.HAState, error) {
+	if err := sc.dial(); err != nil {
+		return seesaw.HAUnknown, err
+	}
+	defer sc.close()
+	var state seesaw.HAState
+	if err := sc.client.Call("SeesawSync.HAState", 0, &state); err != nil {
+		return seesaw.HAUnknown, err
+	}
+	return state, nil
+}
+
 // runOnce establishes a connection to the synchronisation server, registers
 // for notifications, polls for notifications, then deregisters.
 func (sc *syncClient) runOnce() {
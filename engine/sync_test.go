@@ -22,6 +22,8 @@ import (
 	"net"
 	"testing"
 	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
 )
 
 func newLocalTCPListener(n string) (*net.TCPListener, *net.TCPAddr, error) {
@@ -214,3 +216,71 @@ noteLoop:
 		t.Errorf("Did not receive desync notification")
 	}
 }
+
+func TestSyncFailoverTo(t *testing.T) {
+	ln, client, _, _, err := newSyncTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// The sync test engine is its own peer, hence the peer is in the same
+	// HA state as the node itself.
+	e := client.engine
+	e.syncClient = client
+	e.config.Node.Hostname = "seesaw1-1.example.com."
+	e.config.Peer.Hostname = "seesaw1-2.example.com."
+
+	e.haManager.status.State = seesaw.HAMaster
+	tests := []struct {
+		node string
+		ok   bool
+	}{
+		{"seesaw1-1", false},         // Already master.
+		{"seesaw1-2", false},         // Peer is not in backup state.
+		{"seesaw1-3", false},         // Unknown node.
+		{"seesaw1-2.example", false}, // Partial names do not match.
+	}
+	for _, test := range tests {
+		err := e.haManager.requestFailoverTo(test.node)
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("requestFailoverTo(%q) returned %v", test.node, err)
+		}
+	}
+	if e.haManager.failover() {
+		t.Errorf("Failover is pending after refused requests")
+	}
+
+	// The peer cannot be reached once the sync server has gone away.
+	ln.Close()
+	if err := e.haManager.requestFailoverTo("seesaw1-2.example.com"); err == nil {
+		t.Errorf("requestFailoverTo succeeded with unreachable peer")
+	}
+
+	if err := e.haManager.requestFailoverTo(""); err != nil {
+		t.Errorf("requestFailoverTo with no node failed: %v", err)
+	}
+	if !e.haManager.failover() {
+		t.Errorf("Failover is not pending after untargeted request")
+	}
+}
+
+func TestMatchHostname(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		want     bool
+	}{
+		{"seesaw1-1.example.com.", "seesaw1-1.example.com.", true},
+		{"seesaw1-1.example.com", "seesaw1-1.example.com.", true},
+		{"SEESAW1-1", "seesaw1-1.example.com.", true},
+		{"seesaw1-1.example", "seesaw1-1.example.com.", false},
+		{"seesaw1-2", "seesaw1-1.example.com.", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		if got := matchHostname(test.name, test.hostname); got != test.want {
+			t.Errorf("matchHostname(%q, %q) = %v, want %v", test.name, test.hostname, got, test.want)
+		}
+	}
+}
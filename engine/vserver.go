@@ -22,6 +22,7 @@ package engine
 import (
 	"fmt"
 	"net"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -47,14 +48,27 @@ type vserver struct {
 	active     map[seesaw.IP]bool
 	lbVservers map[seesaw.IP]*seesaw.Vserver // vservers with configured iptables rules
 	vips       map[seesaw.VIP]bool           // unicast VIPs
-
-	vserverOverride seesaw.VserverOverride
-	overrideChan    chan seesaw.Override
-
-	notify  chan *checkNotification
-	update  chan *config.Vserver
-	quit    chan bool
-	stopped chan bool
+	standby    bool                          // this node is not master for the vserver's VRRP group
+
+	advertised  map[seesaw.IP]bool        // anycast VIPs with an advertised route
+	anycastDown map[seesaw.IP]time.Time   // when each anycast VIP last went down
+	holdDowns   map[seesaw.IP]*time.Timer // deferred route advertisements, by anycast VIP
+
+	vserverOverride  seesaw.VserverOverride
+	backendOverrides map[string]*seesaw.BackendOverride // enabled or disabled backends, by hostname
+	overrideChan     chan seesaw.Override
+	drained          map[string]bool // drained backends, by hostname
+	drainDeadline    time.Time       // non-zero while draining prior to removal
+
+	flaps map[string]*flapState // health transitions, by destination name
+
+	notify      chan *checkNotification
+	update      chan *config.Vserver
+	drainStart  chan time.Time
+	standbyChan chan bool
+	holdDownEnd chan seesaw.IP
+	quit        chan bool
+	stopped     chan bool
 }
 
 // newVserver returns an initialised vserver struct.
@@ -68,12 +82,23 @@ func newVserver(e *Engine) *vserver {
 		lbVservers: make(map[seesaw.IP]*seesaw.Vserver),
 		vips:       make(map[seesaw.VIP]bool),
 
-		overrideChan: make(chan seesaw.Override, 5),
+		advertised:  make(map[seesaw.IP]bool),
+		anycastDown: make(map[seesaw.IP]time.Time),
+		holdDowns:   make(map[seesaw.IP]*time.Timer),
+
+		backendOverrides: make(map[string]*seesaw.BackendOverride),
+		overrideChan:     make(chan seesaw.Override, 5),
+		drained:          make(map[string]bool),
 
-		notify:  make(chan *checkNotification, 20),
-		update:  make(chan *config.Vserver, 1),
-		quit:    make(chan bool, 1),
-		stopped: make(chan bool, 1),
+		flaps: make(map[string]*flapState),
+
+		notify:      make(chan *checkNotification, 20),
+		update:      make(chan *config.Vserver, 1),
+		drainStart:  make(chan time.Time, 1),
+		standbyChan: make(chan bool, 5),
+		holdDownEnd: make(chan seesaw.IP, 5),
+		quit:        make(chan bool, 1),
+		stopped:     make(chan bool, 1),
 	}
 }
 
@@ -91,6 +116,7 @@ type serviceKey struct {
 	fwm   uint32
 	proto seesaw.IPProto
 	port  uint16
+	alias seesaw.IP // the additional VIP for the service, if any
 }
 
 // service contains the running state for a vserver service.
@@ -115,6 +141,12 @@ func (svc *service) ipvsService() *ipvs.Service {
 	if svc.ventry.OnePacket {
 		flags |= ipvs.SFOnePacket
 	}
+	if svc.ventry.SchedFallback {
+		flags |= ipvs.SFSched1
+	}
+	if svc.ventry.SchedPort {
+		flags |= ipvs.SFSched2
+	}
 	var ip net.IP
 	switch {
 	case svc.fwm > 0 && svc.af == seesaw.IPv4:
@@ -124,6 +156,10 @@ func (svc *service) ipvsService() *ipvs.Service {
 	default:
 		ip = svc.ip.IP()
 	}
+	var netmask net.IPMask
+	if prefix, bits := svc.persistencePrefix(); prefix > 0 && prefix < bits {
+		netmask = net.CIDRMask(prefix, bits)
+	}
 	return &ipvs.Service{
 		Address:      ip,
 		Protocol:     ipvs.IPProto(svc.proto),
@@ -132,7 +168,19 @@ func (svc *service) ipvsService() *ipvs.Service {
 		FirewallMark: svc.fwm,
 		Flags:        flags,
 		Timeout:      uint32(svc.ventry.Persistence),
+		Netmask:      netmask,
+	}
+}
+
+// persistencePrefix returns the prefix length used to group client addresses
+// for persistence, along with the address length in bits, for the address
+// family of the service. A zero prefix length means that each client address
+// is treated individually.
+func (svc *service) persistencePrefix() (int, int) {
+	if svc.af == seesaw.IPv6 {
+		return svc.ventry.PersistencePrefixIPv6, 8 * net.IPv6len
 	}
+	return svc.ventry.PersistencePrefixIPv4, 8 * net.IPv4len
 }
 
 // ipvsEqual returns true if two services have the same IPVS configuration.
@@ -175,6 +223,15 @@ type destination struct {
 	checks  []*check
 	healthy bool
 	active  bool
+
+	// slowStart is the time at which the destination became healthy, if
+	// its weight is still being ramped up.
+	slowStart time.Time
+
+	// fallback is true if this is the destination for the vserver's
+	// fallback backend, which is only healthy while all other destinations
+	// for the service are unhealthy.
+	fallback bool
 }
 
 // ipvsDestination returns an IPVS Destination for the given destination.
@@ -187,14 +244,20 @@ func (dst *destination) ipvsDestination() *ipvs.Destination {
 		flags |= ipvs.DFForwardRoute
 	case seesaw.LBModeNAT:
 		flags |= ipvs.DFForwardMasq
+	case seesaw.LBModeTUN:
+		flags |= ipvs.DFForwardTunnel
+	}
+	lower, upper := uint32(dst.service.ventry.LThreshold), uint32(dst.service.ventry.UThreshold)
+	if dst.backend != nil && dst.backend.UpperThreshold > 0 {
+		lower, upper = uint32(dst.backend.LowerThreshold), uint32(dst.backend.UpperThreshold)
 	}
 	return &ipvs.Destination{
 		Address:        dst.ip.IP(),
 		Port:           dst.service.port,
 		Weight:         dst.weight,
 		Flags:          flags,
-		LowerThreshold: uint32(dst.service.ventry.LThreshold),
-		UpperThreshold: uint32(dst.service.ventry.UThreshold),
+		LowerThreshold: lower,
+		UpperThreshold: upper,
 	}
 }
 
@@ -259,8 +322,10 @@ type check struct {
 	vserver     *vserver
 	dests       []*destination
 	healthcheck *config.Healthcheck
+	id          healthcheck.Id
 	description string
 	status      healthcheck.Status
+	override    seesaw.OverrideState
 }
 
 // newCheck returns an initialised check.
@@ -272,11 +337,23 @@ func newCheck(key checkKey, v *vserver, h *config.Healthcheck) *check {
 	}
 }
 
+// hostname returns the hostname of the backend that is being healthchecked.
+func (c *check) hostname() string {
+	for _, d := range c.dests {
+		if d.backend != nil {
+			return d.backend.Hostname
+		}
+	}
+	return ""
+}
+
 // checkNotification represents a healthcheck status update.
 type checkNotification struct {
 	key         checkKey
+	id          healthcheck.Id
 	description string
 	status      healthcheck.Status
+	override    seesaw.OverrideState
 }
 
 // vserverChecks represents the current set of healthchecks for a vserver.
@@ -293,23 +370,14 @@ func (v *vserver) expandServices() map[serviceKey]*service {
 	}
 
 	svcs := make(map[serviceKey]*service)
-	for _, af := range seesaw.AFs() {
-		var ip net.IP
-		switch af {
-		case seesaw.IPv4:
-			ip = v.config.Host.IPv4Addr
-		case seesaw.IPv6:
-			ip = v.config.Host.IPv6Addr
-		}
-		if ip == nil {
-			continue
-		}
+	addServices := func(af seesaw.AF, ip net.IP, alias seesaw.IP) {
 		for _, entry := range v.config.Entries {
 			svc := &service{
 				serviceKey: serviceKey{
 					af:    af,
 					proto: entry.Proto,
 					port:  entry.Port,
+					alias: alias,
 				},
 				ip:      seesaw.NewIP(ip),
 				ventry:  entry,
@@ -321,6 +389,25 @@ func (v *vserver) expandServices() map[serviceKey]*service {
 			svcs[svc.serviceKey] = svc
 		}
 	}
+	for _, af := range seesaw.AFs() {
+		var ip net.IP
+		switch af {
+		case seesaw.IPv4:
+			ip = v.config.Host.IPv4Addr
+		case seesaw.IPv6:
+			ip = v.config.Host.IPv6Addr
+		}
+		if ip == nil {
+			continue
+		}
+		addServices(af, ip, seesaw.IP{})
+	}
+	// The services for additional VIPs are keyed by their address, so that
+	// they are distinct from those for the vserver's own addresses.
+	for _, ip := range v.config.AdditionalVIPs {
+		alias := seesaw.NewIP(ip)
+		addServices(alias.AF(), ip, alias)
+	}
 	return svcs
 }
 
@@ -342,11 +429,12 @@ func (v *vserver) expandFWMServices() map[serviceKey]*service {
 
 		// Persistence, etc., is stored in the VserverEntry. For FWM services, these
 		// values must be the same for all VserverEntries, so just use the first
-		// one.
+		// one, by key.
 		var ventry *config.VserverEntry
-		for _, entry := range v.config.Entries {
-			ventry = entry
-			break
+		for key, entry := range v.config.Entries {
+			if ventry == nil || key < ventry.Key() {
+				ventry = entry
+			}
 		}
 
 		if v.fwm[af] == 0 {
@@ -377,29 +465,100 @@ func (v *vserver) expandFWMServices() map[serviceKey]*service {
 func (v *vserver) expandDests(svc *service) map[destinationKey]*destination {
 	dsts := make(map[destinationKey]*destination, len(v.config.Backends))
 	for _, backend := range v.config.Backends {
-		var ip net.IP
-		switch svc.af {
-		case seesaw.IPv4:
-			ip = backend.Host.IPv4Addr
-		case seesaw.IPv6:
-			ip = backend.Host.IPv6Addr
+		if dst := v.newDestination(svc, backend); dst != nil {
+			dsts[dst.destinationKey] = dst
 		}
-		if ip == nil {
-			continue
-		}
-		dst := &destination{
-			destinationKey: newDestinationKey(ip),
-			service:        svc,
-			backend:        backend,
-			weight:         backend.Weight,
+	}
+	if v.config.OnAllDown == config.OnAllDownFallback && v.config.FallbackBackend != nil {
+		if dst := v.newDestination(svc, v.config.FallbackBackend); dst != nil {
+			dst.fallback = true
+			dsts[dst.destinationKey] = dst
 		}
-		dst.ipvsDst = dst.ipvsDestination()
-		dst.stats = &seesaw.DestinationStats{}
-		dsts[dst.destinationKey] = dst
 	}
 	return dsts
 }
 
+// newDestination returns a destination for the given service and backend, or
+// nil if the backend does not have an address in the service's address family.
+func (v *vserver) newDestination(svc *service, backend *seesaw.Backend) *destination {
+	var ip net.IP
+	switch svc.af {
+	case seesaw.IPv4:
+		ip = backend.Host.IPv4Addr
+	case seesaw.IPv6:
+		ip = backend.Host.IPv6Addr
+	}
+	if ip == nil {
+		return nil
+	}
+	dst := &destination{
+		destinationKey: newDestinationKey(ip),
+		service:        svc,
+		backend:        backend,
+		weight:         v.backendWeight(backend),
+	}
+	dst.ipvsDst = dst.ipvsDestination()
+	dst.stats = &seesaw.DestinationStats{}
+	return dst
+}
+
+// backendWeight returns the IPVS weight that should be used for destinations
+// of the given backend. Drained backends have a weight of zero.
+func (v *vserver) backendWeight(backend *seesaw.Backend) int32 {
+	if v.drained[backend.Hostname] || !v.drainDeadline.IsZero() {
+		return 0
+	}
+	return backend.Weight
+}
+
+// destinationWeight returns the IPVS weight that should be used for the given
+// destination. This is the backend weight, scaled by the lowest weight that is
+// reported by the destination's healthy healthchecks. Healthchecks that do not
+// report a weight are ignored. Unhealthy destinations of a quiescent vserver
+// have a weight of zero.
+func (v *vserver) destinationWeight(d *destination) int32 {
+	if v.config.Quiescent && !d.healthy {
+		return 0
+	}
+	weight := v.backendWeight(d.backend)
+	percent := 100
+	for _, c := range d.checks {
+		if c.status.State == healthcheck.StateHealthy && c.status.Weighted && c.status.Weight < percent {
+			percent = c.status.Weight
+		}
+	}
+	if percent != 100 && weight != 0 {
+		scaled := int32((int64(weight)*int64(percent) + 50) / 100)
+		if scaled == 0 && percent > 0 {
+			scaled = 1
+		}
+		weight = scaled
+	}
+	return v.slowStartWeight(d, weight, time.Now())
+}
+
+// slowStartWeight returns the given weight for a destination, adjusted for
+// slow start. While slow starting, the weight is ramped linearly from one up
+// to the given weight over the vserver's slow start duration.
+func (v *vserver) slowStartWeight(d *destination, weight int32, now time.Time) int32 {
+	duration := v.config.SlowStartDuration
+	if d.slowStart.IsZero() || duration <= 0 || weight <= 1 {
+		return weight
+	}
+	elapsed := now.Sub(d.slowStart)
+	if elapsed >= duration {
+		return weight
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	scaled := int32(int64(weight) * int64(elapsed) / int64(duration))
+	if scaled < 1 {
+		scaled = 1
+	}
+	return scaled
+}
+
 // expandChecks returns a list of checks that have been expanded from the
 // vserver configuration.
 func (v *vserver) expandChecks() map[checkKey]*check {
@@ -407,12 +566,13 @@ func (v *vserver) expandChecks() map[checkKey]*check {
 	for _, svc := range v.services {
 		for _, dest := range svc.dests {
 			dest.checks = make([]*check, 0)
-			if !dest.backend.Enabled {
+			if !dest.backend.Enabled || dest.fallback {
 				continue
 			}
+			vip := v.checkIP(svc)
 			for _, hc := range v.config.Healthchecks {
 				// vserver-level healthchecks
-				key := newCheckKey(svc.ip, dest.ip, 0, 0, hc)
+				key := newCheckKey(vip, dest.ip, 0, 0, hc)
 				c := checks[key]
 				if c == nil {
 					c = newCheck(key, v, hc)
@@ -426,7 +586,7 @@ func (v *vserver) expandChecks() map[checkKey]*check {
 			if v.config.UseFWM {
 				for _, ve := range svc.vserver.config.Entries {
 					for _, hc := range ve.Healthchecks {
-						key := newCheckKey(svc.ip, dest.ip, ve.Port, ve.Proto, hc)
+						key := newCheckKey(vip, dest.ip, ve.Port, ve.Proto, hc)
 						c := newCheck(key, v, hc)
 						checks[key] = c
 						dest.checks = append(dest.checks, c)
@@ -435,9 +595,12 @@ func (v *vserver) expandChecks() map[checkKey]*check {
 				}
 			} else {
 				for _, hc := range svc.ventry.Healthchecks {
-					key := newCheckKey(svc.ip, dest.ip, svc.port, svc.proto, hc)
-					c := newCheck(key, v, hc)
-					checks[key] = c
+					key := newCheckKey(vip, dest.ip, svc.port, svc.proto, hc)
+					c := checks[key]
+					if c == nil {
+						c = newCheck(key, v, hc)
+						checks[key] = c
+					}
 					dest.checks = append(dest.checks, c)
 					c.dests = append(c.dests, dest)
 				}
@@ -447,6 +610,19 @@ func (v *vserver) expandChecks() map[checkKey]*check {
 	return checks
 }
 
+// checkIP returns the VIP that is used to healthcheck the destinations of a
+// service. Services for additional VIPs share the healthchecks of the first
+// address of the same address family, so that each backend is only checked
+// once.
+func (v *vserver) checkIP(svc *service) seesaw.IP {
+	for _, addr := range v.config.Addresses() {
+		if ip := seesaw.NewIP(addr); ip.AF() == svc.af {
+			return ip
+		}
+	}
+	return svc.ip
+}
+
 // healthchecks returns the vserverChecks for a vserver.
 func (v *vserver) healthchecks() vserverChecks {
 	vc := vserverChecks{vserverName: v.config.Name}
@@ -461,6 +637,7 @@ func (v *vserver) healthchecks() vserverChecks {
 // notifications.
 func (v *vserver) run() {
 	statsTicker := time.NewTicker(v.engine.config.StatsInterval)
+	slowStartTicker := time.NewTicker(v.engine.config.SlowStartInterval)
 	for {
 		select {
 		case <-v.quit:
@@ -470,6 +647,7 @@ func (v *vserver) run() {
 			// same vserver go routine.
 			v.downAll()
 			statsTicker.Stop()
+			slowStartTicker.Stop()
 			v.engine.hcManager.vcc <- vserverChecks{vserverName: v.config.Name}
 			v.unconfigureVIPs()
 
@@ -493,8 +671,23 @@ func (v *vserver) run() {
 		case n := <-v.notify:
 			v.handleCheckNotification(n)
 
+		case deadline := <-v.drainStart:
+			v.handleDrain(deadline)
+
+		case standby := <-v.standbyChan:
+			v.handleStandby(standby)
+
+		case ip := <-v.holdDownEnd:
+			v.handleHoldDownEnd(ip)
+
 		case <-statsTicker.C:
 			v.updateStats()
+			v.summariseFlaps(time.Now())
+
+		case <-slowStartTicker.C:
+			if !v.updateSlowStart(time.Now()) {
+				continue
+			}
 		}
 
 		// Something changed - export a new vserver snapshot.
@@ -519,6 +712,15 @@ func (v *vserver) stop() {
 	}
 }
 
+// startDrain tells a running vserver that it should drain active connections
+// prior to being stopped, which should occur by the given deadline.
+func (v *vserver) startDrain(deadline time.Time) {
+	select {
+	case v.drainStart <- deadline:
+	default:
+	}
+}
+
 // updateConfig queues a vserver configuration update for processing. This
 // will block if a configuration update is already pending.
 func (v *vserver) updateConfig(config *config.Vserver) {
@@ -538,6 +740,43 @@ func (v *vserver) queueOverride(o seesaw.Override) {
 	v.overrideChan <- o
 }
 
+// queueStandby queues a change to the vserver's standby state for processing.
+// This is called from the HA manager and must not block - only the most
+// recent standby state matters, so stale queued states are discarded if the
+// channel is full.
+func (v *vserver) queueStandby(standby bool) {
+	for {
+		select {
+		case v.standbyChan <- standby:
+			return
+		default:
+		}
+		select {
+		case <-v.standbyChan:
+		default:
+		}
+	}
+}
+
+// handleStandby configures or unconfigures the vserver's unicast VIPs, when
+// this node becomes the master or stops being the master for the vserver's
+// VRRP group.
+func (v *vserver) handleStandby(standby bool) {
+	if v.standby == standby {
+		return
+	}
+	v.standby = standby
+	if standby {
+		log.Infof("%v: entering standby", v)
+		v.unconfigureVIPs()
+		return
+	}
+	log.Infof("%v: leaving standby", v)
+	if v.enabled {
+		v.configureVIPs()
+	}
+}
+
 // handleConfigUpdate updates the internal structures of a vserver using the
 // new configuration.
 func (v *vserver) handleConfigUpdate(config *config.Vserver) {
@@ -605,8 +844,12 @@ func (v *vserver) handleConfigUpdate(config *config.Vserver) {
 			return
 		}
 
+		communitiesChanged := !reflect.DeepEqual(config.BGPCommunities, v.config.BGPCommunities)
 		v.config = config
 		v.configUpdate()
+		if communitiesChanged {
+			v.updateBGPCommunities()
+		}
 	}
 }
 
@@ -632,6 +875,7 @@ func (v *vserver) configInit(config *config.Vserver) {
 	v.checks = v.expandChecks()
 	if v.enabled {
 		v.configureVIPs()
+		v.updateFallbacks()
 	}
 	return
 }
@@ -665,6 +909,22 @@ func (v *vserver) configUpdate() {
 		newDests := v.expandDests(svc)
 		for destKey, newDest := range newDests {
 			if dest, ok := svc.dests[destKey]; ok {
+				if weightOnlyChange(dest.backend, newDest.backend) {
+					// Retain the existing destination so that its running state
+					// is preserved and update the IPVS destination in place.
+					log.Infof("%v: service %v: updating weight for destination %v", v, svc, dest)
+					dest.backend = newDest.backend
+					v.updateDestinationWeight(dest)
+					continue
+				}
+				// Carry over the state that determines the effective weight, so
+				// that the IPVS destination is only updated if its configuration
+				// has changed.
+				newDest.checks = dest.checks
+				newDest.healthy = dest.healthy
+				newDest.slowStart = dest.slowStart
+				newDest.weight = v.destinationWeight(newDest)
+				newDest.ipvsDst = newDest.ipvsDestination()
 				dest.update(newDest)
 				continue
 			}
@@ -678,6 +938,10 @@ func (v *vserver) configUpdate() {
 				if dest.active {
 					dest.healthy = false
 					svc.updateState()
+					// A quiesced destination remains active until it is deleted.
+					if dest.active {
+						dest.down()
+					}
 				}
 				log.Infof("%v: service %v: deleting destination: %v", v, svc, dest)
 				delete(svc.dests, destKey)
@@ -701,16 +965,46 @@ func (v *vserver) configUpdate() {
 	checks := v.expandChecks()
 	for k, oldCheck := range v.checks {
 		if checks[k] != nil {
+			checks[k].id = oldCheck.id
 			checks[k].description = oldCheck.description
 			checks[k].status = oldCheck.status
+			checks[k].override = oldCheck.override
 		}
 	}
 	v.checks = checks
+	for _, svc := range v.services {
+		for _, dst := range svc.dests {
+			v.updateDestinationWeight(dst)
+		}
+	}
 	// TODO(baptr): Should this only happen if it's enabled?
 	v.configureVIPs()
+	v.updateFallbacks()
 	return
 }
 
+// updateFallbacks updates the state of services that have a fallback
+// destination, so that the fallback is brought up without waiting for a
+// healthcheck notification.
+func (v *vserver) updateFallbacks() {
+	for _, svc := range v.services {
+		if svc.fallback() != nil {
+			svc.updateState()
+		}
+	}
+}
+
+// weightOnlyChange returns true if the only difference between two backends is
+// their weight.
+func weightOnlyChange(old, new *seesaw.Backend) bool {
+	if old.Weight == new.Weight {
+		return false
+	}
+	b := *new
+	b.Weight = old.Weight
+	return reflect.DeepEqual(*old, b)
+}
+
 // deleteService deletes a service for a vserver.
 func (v *vserver) deleteService(s *service) {
 	if s.active {
@@ -738,11 +1032,21 @@ func (v *vserver) handleCheckNotification(n *checkNotification) {
 	}
 
 	transition := (check.status.State != n.status.State)
+	reweight := check.status.Weighted != n.status.Weighted || check.status.Weight != n.status.Weight
+	check.id = n.id
 	check.description = n.description
 	check.status = n.status
+	check.override = n.override
 	if transition {
-		log.Infof("%v: healthcheck %s - %v (%s)", v, n.description, n.status.State, n.status.Message)
+		if !v.checkFlapping(check) {
+			log.Infof("%v: healthcheck %s - %v (%s)", v, n.description, n.status.State, n.status.Message)
+		}
+	} else if reweight {
+		log.Infof("%v: healthcheck %s - weight %d%% (%s)", v, n.description, n.status.Weight, n.status.Message)
+	}
+	if transition || reweight {
 		for _, d := range check.dests {
+			v.updateDestinationWeight(d)
 			d.updateState()
 		}
 	}
@@ -761,7 +1065,10 @@ func (v *vserver) handleOverride(o seesaw.Override) {
 			// enable state not changed - nothing to do
 			return
 		}
-	// TODO(angusc): handle backend and destination overrides.
+	case *seesaw.BackendOverride:
+		v.handleBackendOverride(override)
+		return
+	// TODO(angusc): handle destination overrides.
 	default:
 		return
 	}
@@ -770,6 +1077,113 @@ func (v *vserver) handleOverride(o seesaw.Override) {
 	}
 }
 
+// handleBackendOverride processes a BackendOverride. A drained backend has its
+// weight set to zero, while an enabled or disabled backend has the health
+// state of its destinations forced up or down.
+func (v *vserver) handleBackendOverride(o *seesaw.BackendOverride) {
+	hostname := o.Hostname
+	drain := o.State() == seesaw.OverrideDrain
+	if v.drained[hostname] != drain {
+		if drain {
+			log.Infof("%v: draining backend %v", v, hostname)
+			v.drained[hostname] = true
+		} else {
+			log.Infof("%v: undraining backend %v", v, hostname)
+			delete(v.drained, hostname)
+		}
+		v.updateBackendWeight(hostname)
+	}
+
+	old := v.backendOverrides[hostname]
+	switch o.State() {
+	case seesaw.OverrideEnable, seesaw.OverrideDisable:
+		v.backendOverrides[hostname] = o
+		if old != nil && old.State() == o.State() {
+			return
+		}
+		log.Infof("%v: backend %v overridden to %v", v, hostname, o.State())
+	default:
+		if old == nil {
+			return
+		}
+		log.Infof("%v: override for backend %v cleared", v, hostname)
+		delete(v.backendOverrides, hostname)
+	}
+	for _, svc := range v.services {
+		for _, dst := range svc.dests {
+			if dst.backend.Hostname == hostname && !dst.fallback {
+				dst.updateState()
+			}
+		}
+	}
+}
+
+// updateBackendWeight updates the weight of all destinations for the given
+// backend, updating the IPVS destinations for those that are active.
+func (v *vserver) updateBackendWeight(hostname string) {
+	for _, svc := range v.services {
+		for _, dst := range svc.dests {
+			if dst.backend.Hostname != hostname {
+				continue
+			}
+			v.updateDestinationWeight(dst)
+		}
+	}
+}
+
+// updateDestinationWeight updates the weight of the given destination, if it
+// has changed.
+func (v *vserver) updateDestinationWeight(dst *destination) {
+	weight := v.destinationWeight(dst)
+	if weight == dst.weight {
+		return
+	}
+	newDst := *dst
+	newDst.weight = weight
+	newDst.ipvsDst = newDst.ipvsDestination()
+	dst.update(&newDst)
+}
+
+// handleDrain sets the weight of all destinations to zero, so that no new
+// connections are scheduled, and records the deadline for the drain. A
+// vserver that is not active has nothing to drain and its deadline is set to
+// the current time.
+func (v *vserver) handleDrain(deadline time.Time) {
+	if len(v.active) == 0 {
+		v.drainDeadline = time.Now()
+		return
+	}
+	log.Infof("%v: draining active connections until %v", v, deadline)
+	v.drainDeadline = deadline
+	for _, svc := range v.services {
+		for _, dst := range svc.dests {
+			v.updateDestinationWeight(dst)
+		}
+	}
+	v.updateStats()
+}
+
+// updateSlowStart updates the weights of destinations that are slow
+// starting, ending slow start for those where the slow start duration has
+// elapsed. It returns true if any destinations were slow starting.
+func (v *vserver) updateSlowStart(now time.Time) bool {
+	slowStarting := false
+	for _, svc := range v.services {
+		for _, dst := range svc.dests {
+			if dst.slowStart.IsZero() {
+				continue
+			}
+			slowStarting = true
+			if !dst.healthy || now.Sub(dst.slowStart) >= v.config.SlowStartDuration {
+				log.Infof("%v: %v slow start complete for destination %v", v, svc, dst)
+				dst.slowStart = time.Time{}
+			}
+			v.updateDestinationWeight(dst)
+		}
+	}
+	return slowStarting
+}
+
 // vserverEnabled returns true if a vserver having the given configuration
 // and override state should be enabled.
 func vserverEnabled(config *config.Vserver, os seesaw.OverrideState) bool {
@@ -805,6 +1219,7 @@ func (v *vserver) snapshot() *seesaw.Vserver {
 		Enabled:       v.enabled,
 		ConfigEnabled: v.config.Enabled,
 		Warnings:      v.config.Warnings,
+		DrainDeadline: v.drainDeadline,
 	}
 	for _, ve := range v.config.Entries {
 		sv.Entries = append(sv.Entries, ve.Snapshot())
@@ -832,13 +1247,25 @@ func (d *destination) updateState() {
 			}
 		}
 	}
+	// A BackendOverride takes precedence over the state of the checks.
+	if o := d.service.vserver.backendOverrides[d.backend.Hostname]; o != nil && !d.fallback {
+		healthy = d.backend.Enabled && o.State() == seesaw.OverrideEnable
+	}
 
 	if d.healthy == healthy {
 		return
 	}
+	d.service.vserver.engine.audit.record(auditActorEngine, auditBackendHealth, d.name(),
+		healthString(d.healthy), healthString(healthy))
+	d.service.vserver.recordTransition(d, time.Now())
 	d.healthy = healthy
+	d.startSlowStart()
 
 	switch {
+	case d.service.fallback() != nil:
+		// The fallback destination may need to be brought up or down.
+		d.service.updateState()
+
 	case d.service.active && d.healthy:
 		// The service is already active. Bringing up the dest will have no effect
 		// on the service or vserver state.
@@ -850,10 +1277,40 @@ func (d *destination) updateState() {
 	}
 }
 
-// up brings up a destination.
+// healthString returns a description of the given health state.
+func healthString(healthy bool) string {
+	if healthy {
+		return "healthy"
+	}
+	return "unhealthy"
+}
+
+// startSlowStart starts ramping the weight of a destination that has become
+// healthy, if slow start is configured for the vserver.
+func (d *destination) startSlowStart() {
+	v := d.service.vserver
+	if !d.healthy || v.config.SlowStartDuration <= 0 {
+		d.slowStart = time.Time{}
+		return
+	}
+	log.Infof("%v: %v slow starting destination %v over %v", v, d.service, d, v.config.SlowStartDuration)
+	d.slowStart = time.Now()
+	d.weight = v.destinationWeight(d)
+	d.ipvsDst = d.ipvsDestination()
+}
+
+// up brings up a destination. A destination that has been quiesced is
+// already active and has its weight restored.
 func (d *destination) up() {
-	d.active = true
-	log.Infof("%v: %v backend %v up", d.service.vserver, d.service, d)
+	weight := d.service.vserver.destinationWeight(d)
+	if d.active {
+		if !d.service.vserver.flapping(d) {
+			log.Infof("%v: %v backend %v restored", d.service.vserver, d.service, d)
+		}
+		d.updateIPVSWeight(weight)
+		return
+	}
+	d.activate()
 
 	ncc := d.service.vserver.ncc
 	if err := ncc.Dial(); err != nil {
@@ -865,10 +1322,23 @@ func (d *destination) up() {
 	}
 }
 
+// activate marks an inactive destination as active and computes its IPVS
+// destination. The caller is responsible for adding the IPVS destination.
+func (d *destination) activate() {
+	d.active = true
+	d.weight = d.service.vserver.destinationWeight(d)
+	d.ipvsDst = d.ipvsDestination()
+	if !d.service.vserver.flapping(d) {
+		log.Infof("%v: %v backend %v up", d.service.vserver, d.service, d)
+	}
+}
+
 // down takes down a destination.
 func (d *destination) down() {
 	d.active = false
-	log.Infof("%v: %v backend %v down", d.service.vserver, d.service, d)
+	if !d.service.vserver.flapping(d) {
+		log.Infof("%v: %v backend %v down", d.service.vserver, d.service, d)
+	}
 
 	ncc := d.service.vserver.ncc
 	if err := ncc.Dial(); err != nil {
@@ -880,6 +1350,34 @@ func (d *destination) down() {
 	}
 }
 
+// quiesce sets the weight of an unhealthy destination to zero, rather than
+// deleting it, so that existing connections are retained until they close.
+func (d *destination) quiesce() {
+	if d.ipvsDst.Weight == 0 {
+		return
+	}
+	if !d.service.vserver.flapping(d) {
+		log.Infof("%v: %v backend %v quiesced", d.service.vserver, d.service, d)
+	}
+	d.updateIPVSWeight(0)
+}
+
+// updateIPVSWeight sets the weight of an active destination and updates the
+// IPVS destination.
+func (d *destination) updateIPVSWeight(weight int32) {
+	d.weight = weight
+	d.ipvsDst = d.ipvsDestination()
+
+	ncc := d.service.vserver.ncc
+	if err := ncc.Dial(); err != nil {
+		log.Fatalf("%v: failed to connect to NCC: %v", d.service.vserver, err)
+	}
+	defer ncc.Close()
+	if err := ncc.IPVSUpdateDestination(d.service.ipvsSvc, d.ipvsDst); err != nil {
+		log.Fatalf("%v: failed to update destination %v: %v", d.service.vserver, d, err)
+	}
+}
+
 // update updates a destination while preserving its running state.
 func (d *destination) update(dest *destination) {
 	if d.destinationKey != dest.destinationKey {
@@ -892,6 +1390,7 @@ func (d *destination) update(dest *destination) {
 
 	dest.active = d.active
 	dest.healthy = d.healthy
+	dest.slowStart = d.slowStart
 	dest.stats = d.stats
 	*d = *dest
 
@@ -924,7 +1423,7 @@ func (d *destination) update(dest *destination) {
 
 // snapshot exports the current running state of a destination.
 func (d *destination) snapshot() *seesaw.Destination {
-	return &seesaw.Destination{
+	sd := &seesaw.Destination{
 		Backend:     d.backend,
 		Name:        d.name(),
 		VserverName: d.service.vserver.String(),
@@ -933,7 +1432,45 @@ func (d *destination) snapshot() *seesaw.Destination {
 		Weight:      d.weight,
 		Healthy:     d.healthy,
 		Active:      d.active,
+
+		Healthchecks: d.healthcheckStatus(),
+	}
+	v := d.service.vserver
+	if o := v.backendOverrides[d.backend.Hostname]; o != nil {
+		sd.Override = o.State()
+		sd.OverrideExpires = o.Expires
+	} else if v.drained[d.backend.Hostname] {
+		sd.Override = seesaw.OverrideDrain
 	}
+	return sd
+}
+
+// healthcheckStatus exports the current status of the healthchecks for a
+// destination.
+func (d *destination) healthcheckStatus() []*seesaw.HealthcheckStatus {
+	if len(d.checks) == 0 {
+		return nil
+	}
+	status := make([]*seesaw.HealthcheckStatus, 0, len(d.checks))
+	for _, c := range d.checks {
+		description := c.description
+		if description == "" {
+			description = c.key.String()
+		}
+		status = append(status, &seesaw.HealthcheckStatus{
+			Id:          uint64(c.id),
+			Description: description,
+			State:       c.status.State.String(),
+			Message:     c.status.Message,
+			LastCheck:   c.status.LastCheck,
+			Latency:     c.status.Latency,
+			Failures:    c.status.Failures,
+			Successes:   c.status.Successes,
+
+			OverrideState: c.override,
+		})
+	}
+	return status
 }
 
 // updateState updates the state of a service based on the state of its
@@ -949,7 +1486,12 @@ func (s *service) updateState() {
 
 	numBackends := 0
 	numHealthyDests := 0
+	var fallback *destination
 	for _, d := range s.dests {
+		if d.fallback {
+			fallback = d
+			continue
+		}
 		if d.backend.InService {
 			numBackends++
 		}
@@ -973,6 +1515,16 @@ func (s *service) updateState() {
 		healthy = float32(numHealthyDests)/float32(numBackends) >= threshold
 	}
 
+	// The fallback destination keeps the service up while all other
+	// destinations are unhealthy.
+	if fallback != nil && fallback.healthy != (numHealthyDests == 0 && fallback.backend.Enabled) {
+		fallback.healthy = !fallback.healthy
+		log.Infof("%v: %v fallback destination %v is now %v", s.vserver, s, fallback, healthString(fallback.healthy))
+	}
+	if fallback != nil && fallback.healthy {
+		healthy = true
+	}
+
 	if s.healthy == healthy {
 		// no change in service state, just update destinations
 		s.updateDests()
@@ -1004,6 +1556,17 @@ func (s *service) updateState() {
 	}
 }
 
+// fallback returns the fallback destination for a service, or nil if it does
+// not have one.
+func (s *service) fallback() *destination {
+	for _, d := range s.dests {
+		if d.fallback {
+			return d
+		}
+	}
+	return nil
+}
+
 // updateDests brings the destinations for a service up or down based on the
 // state of the service and the health of each destination.
 func (s *service) updateDests() {
@@ -1016,6 +1579,8 @@ func (s *service) updateDests() {
 			continue
 		}
 		switch {
+		case !d.healthy && d.active && s.vserver.config.Quiescent && !d.fallback:
+			d.quiesce()
 		case !d.healthy && d.active:
 			d.down()
 		case d.healthy && !d.active:
@@ -1024,24 +1589,51 @@ func (s *service) updateDests() {
 	}
 }
 
-// up brings up a service and all healthy destinations.
+// up brings up a service and all healthy destinations. The IPVS service and
+// its destinations are added in a single IPVS batch, with the service being
+// added *before* the destinations.
 func (s *service) up() {
 	s.active = true
 	log.Infof("%v: %v service up", s.vserver, s)
 
+	var b ipvs.Batch
+	var dests []*destination
+	b.AddService(*s.ipvsSvc)
+	for _, d := range s.dests {
+		if d.healthy && !d.active {
+			d.activate()
+			b.AddDestination(*s.ipvsSvc, *d.ipvsDst)
+			dests = append(dests, d)
+		}
+	}
+
 	ncc := s.vserver.ncc
 	if err := ncc.Dial(); err != nil {
 		log.Fatalf("%v: failed to connect to NCC: %v", s.vserver, err)
 	}
 	defer ncc.Close()
 
-	log.Infof("%v: adding IPVS service %v", s.vserver, s.ipvsSvc)
-	if err := ncc.IPVSAddService(s.ipvsSvc); err != nil {
+	log.Infof("%v: adding IPVS service %v with %d destinations", s.vserver, s.ipvsSvc, b.Len()-1)
+	result, err := ncc.IPVSApplyBatch(&b)
+	if err != nil {
 		log.Fatalf("%v: failed to add service %v: %v", s.vserver, s, err)
 	}
-
-	// Update destinations *after* the IPVS service exists.
-	s.updateDests()
+	s.vserver.engine.recordIPVSBatch(result)
+	if len(result.Errors) != b.Len() {
+		log.Fatalf("%v: failed to add service %v: got %d results for %d IPVS operations",
+			s.vserver, s, len(result.Errors), b.Len())
+	}
+	if err := result.Errors[0]; err != "" {
+		log.Fatalf("%v: failed to add service %v: %v", s.vserver, s, err)
+	}
+	// A destination that could not be added is left inactive, so that adding
+	// it is retried when the service state is next updated.
+	for i, d := range dests {
+		if err := result.Errors[i+1]; err != "" {
+			log.Errorf("%v: failed to add destination %v: %v", s.vserver, d, err)
+			d.active = false
+		}
+	}
 }
 
 // down takes down all destinations for a service, then takes down the
@@ -1139,10 +1731,13 @@ func (s *service) snapshot() *seesaw.Service {
 			AF:    s.af,
 			Proto: s.proto,
 			Port:  s.port,
+			Alias: s.alias,
 		},
 		Mode:          s.ventry.Mode,
 		Scheduler:     s.ventry.Scheduler,
 		OnePacket:     s.ventry.OnePacket,
+		SchedFallback: s.ventry.SchedFallback,
+		SchedPort:     s.ventry.SchedPort,
 		Persistence:   s.ventry.Persistence,
 		IP:            s.ip.IP(),
 		Healthy:       s.healthy,
@@ -1153,6 +1748,9 @@ func (s *service) snapshot() *seesaw.Service {
 		LowWatermark:  s.ventry.LowWatermark,
 		HighWatermark: s.ventry.HighWatermark,
 	}
+	if prefix, bits := s.persistencePrefix(); prefix > 0 && prefix < bits {
+		ss.PersistencePrefix = prefix
+	}
 	for _, d := range s.dests {
 		sd := d.snapshot()
 		ss.Destinations[sd.Backend.Hostname] = sd
@@ -1240,15 +1838,13 @@ func (v *vserver) up(ip seesaw.IP) {
 	v.active[ip] = true
 	v.updateServices(ip)
 
-	// If this is an anycast VIP, start advertising a BGP route.
+	// If this is an anycast VIP, start advertising a route.
 	nip := ip.IP()
+	if !seesaw.IsAnycast(nip) && v.config.OnAllDown == config.OnAllDownWithdraw {
+		v.configureVIPs()
+	}
 	if seesaw.IsAnycast(nip) {
-		// TODO(jsing): Create an LBVserver that only encapsulates
-		// the necessary state, rather than storing a full vserver
-		// snapshot.
-		lbVserver := v.snapshot()
-		lbVserver.Services = nil
-		lbVserver.Warnings = nil
+		lbVserver := v.lbVserver(ip)
 		if err := v.engine.lbInterface.AddVserver(lbVserver, ip.AF()); err != nil {
 			log.Fatalf("%v: failed to add Vserver: %v", v, err)
 		}
@@ -1261,10 +1857,7 @@ func (v *vserver) up(ip seesaw.IP) {
 		// TODO(angusc): Filter out anycast VIPs for non-anycast clusters further
 		// upstream.
 		if v.engine.config.AnycastEnabled {
-			log.Infof("%v: advertising BGP route for %v", v, ip)
-			if err := ncc.BGPAdvertiseVIP(nip); err != nil {
-				log.Fatalf("%v: failed to advertise VIP %v: %v", v, ip, err)
-			}
+			v.advertiseVIP(ip)
 		} else {
 			log.Warningf("%v: %v is an anycast VIP, but anycast is not enabled", v, ip)
 		}
@@ -1273,6 +1866,96 @@ func (v *vserver) up(ip seesaw.IP) {
 	log.Infof("%v: VIP %v up", v, ip)
 }
 
+// advertiseVIP starts advertising a route for an anycast VIP. If the VIP
+// went down less than the anycast hold-down ago, the advertisement is
+// deferred until the VIP has remained up for the duration of the hold-down.
+func (v *vserver) advertiseVIP(ip seesaw.IP) {
+	if v.advertised[ip] || v.holdDowns[ip] != nil {
+		return
+	}
+	if down, ok := v.anycastDown[ip]; ok {
+		if wait := v.engine.config.AnycastHoldDown - time.Since(down); wait > 0 {
+			log.Infof("%v: deferring %v route for %v for %v (hold-down)", v, v.engine.config.RoutingProtocol, ip, wait)
+			v.holdDowns[ip] = time.AfterFunc(wait, func() { v.holdDownEnd <- ip })
+			return
+		}
+	}
+
+	ncc := v.engine.ncc
+	if err := ncc.Dial(); err != nil {
+		log.Fatalf("%v: failed to connect to NCC: %v", v, err)
+	}
+	defer ncc.Close()
+
+	log.Infof("%v: advertising %v route for %v", v, v.engine.config.RoutingProtocol, ip)
+	if err := v.engine.advertiseRoute(ip.IP(), v.config.BGPCommunities); err != nil {
+		log.Fatalf("%v: failed to advertise VIP %v: %v", v, ip, err)
+	}
+	v.advertised[ip] = true
+}
+
+// withdrawVIP stops advertising the route for an anycast VIP, cancelling
+// any deferred advertisement, and starts the VIP's hold-down.
+func (v *vserver) withdrawVIP(ip seesaw.IP) {
+	v.anycastDown[ip] = time.Now()
+	if t := v.holdDowns[ip]; t != nil {
+		t.Stop()
+		delete(v.holdDowns, ip)
+	}
+	if !v.advertised[ip] {
+		return
+	}
+
+	ncc := v.engine.ncc
+	if err := ncc.Dial(); err != nil {
+		log.Fatalf("%v: failed to connect to NCC: %v", v, err)
+	}
+	defer ncc.Close()
+
+	log.Infof("%v: withdrawing %v route for %v", v, v.engine.config.RoutingProtocol, ip)
+	if err := v.engine.withdrawRoute(ip.IP()); err != nil {
+		log.Fatalf("%v: failed to withdraw VIP %v: %v", v, ip, err)
+	}
+	delete(v.advertised, ip)
+}
+
+// handleHoldDownEnd advertises the route for an anycast VIP once its
+// hold-down has expired, provided that the VIP is still up.
+func (v *vserver) handleHoldDownEnd(ip seesaw.IP) {
+	if v.holdDowns[ip] == nil {
+		return
+	}
+	if down, ok := v.anycastDown[ip]; ok && time.Since(down) < v.engine.config.AnycastHoldDown {
+		// The VIP went down and came back up after the timer fired -
+		// wait for the current hold-down to expire.
+		return
+	}
+	delete(v.holdDowns, ip)
+	if v.active[ip] && v.engine.config.AnycastEnabled {
+		v.advertiseVIP(ip)
+	}
+}
+
+// updateBGPCommunities re-advertises the BGP routes for the active anycast VIPs
+// of a vserver, so that they are tagged with its current BGP communities.
+func (v *vserver) updateBGPCommunities() {
+	if !v.engine.config.AnycastEnabled || v.engine.config.RoutingProtocol != config.RoutingBGP {
+		return
+	}
+	ncc := v.engine.ncc
+	if err := ncc.Dial(); err != nil {
+		log.Fatalf("%v: failed to connect to NCC: %v", v, err)
+	}
+	defer ncc.Close()
+
+	for ip := range v.advertised {
+		log.Infof("%v: updating BGP communities for %v to %q", v, ip, v.config.BGPCommunities)
+		if err := ncc.BGPAdvertiseVIPCommunities(ip.IP(), v.config.BGPCommunities); err != nil {
+			log.Errorf("%v: failed to update BGP communities for %v: %v", v, ip, err)
+		}
+	}
+}
+
 // downAll takes down all IP addresses and services for a vserver.
 func (v *vserver) downAll() {
 	for _, s := range v.services {
@@ -1294,15 +1977,10 @@ func (v *vserver) down(ip seesaw.IP) {
 	}
 	defer ncc.Close()
 
-	// If this is an anycast VIP, withdraw the BGP route.
+	// If this is an anycast VIP, withdraw the route.
 	nip := ip.IP()
 	if seesaw.IsAnycast(nip) {
-		if v.engine.config.AnycastEnabled {
-			log.Infof("%v: withdrawing BGP route for %v", v, ip)
-			if err := ncc.BGPWithdrawVIP(nip); err != nil {
-				log.Fatalf("%v: failed to withdraw VIP %v: %v", v, ip, err)
-			}
-		}
+		v.withdrawVIP(ip)
 		vip := seesaw.NewVIP(nip, nil)
 		if err := v.engine.lbInterface.DeleteVIP(vip); err != nil {
 			log.Fatalf("%v: failed to remove VIP %v: %v", v, ip, err)
@@ -1312,6 +1990,14 @@ func (v *vserver) down(ip seesaw.IP) {
 		}
 		delete(v.lbVservers, ip)
 	}
+	if !seesaw.IsAnycast(nip) && v.config.OnAllDown == config.OnAllDownWithdraw {
+		for vip := range v.vips {
+			if vip.IP.Equal(ip) {
+				log.Infof("%v: withdrawing VIP %v", v, ip)
+				v.unconfigureVIP(&vip)
+			}
+		}
+	}
 	// TODO(jsing): Should we delay while the BGP routes propagate?
 
 	delete(v.active, ip)
@@ -1326,8 +2012,31 @@ func (v *vserver) updateStats() {
 	}
 }
 
-// configureVIPs configures VIPs on the load balancing interface.
+// lbVserver returns the vserver that is used to configure the load balancing
+// interface for the given VIP. For an additional VIP, the address of the
+// vserver's Host is replaced with that of the VIP.
+func (v *vserver) lbVserver(ip seesaw.IP) *seesaw.Vserver {
+	// TODO(jsing): Create a ncc.LBVserver that only encapsulates
+	// the necessary state, rather than storing a full vserver
+	// snapshot.
+	lbVserver := v.snapshot()
+	lbVserver.Services = nil
+	lbVserver.Warnings = nil
+	switch ip.AF() {
+	case seesaw.IPv4:
+		lbVserver.IPv4Addr = ip.IP()
+	case seesaw.IPv6:
+		lbVserver.IPv6Addr = ip.IP()
+	}
+	return lbVserver
+}
+
+// configureVIPs configures VIPs on the load balancing interface. No VIPs are
+// configured while the vserver is in standby.
 func (v *vserver) configureVIPs() {
+	if v.standby {
+		return
+	}
 	ncc := v.engine.ncc
 	if err := ncc.Dial(); err != nil {
 		log.Fatalf("%v: failed to connect to NCC: %v", v, err)
@@ -1343,13 +2052,13 @@ func (v *vserver) configureVIPs() {
 		if vip.Type == seesaw.AnycastVIP {
 			continue
 		}
+		// With the withdraw policy, a unicast VIP is only configured while
+		// at least one of its services is healthy.
+		if v.config.OnAllDown == config.OnAllDownWithdraw && !v.active[vip.IP] {
+			continue
+		}
 
-		// TODO(jsing): Create a ncc.LBVserver that only encapsulates
-		// the necessary state, rather than storing a full vserver
-		// snapshot.
-		lbVserver := v.snapshot()
-		lbVserver.Services = nil
-		lbVserver.Warnings = nil
+		lbVserver := v.lbVserver(vip.IP)
 		if err := v.engine.lbInterface.AddVserver(lbVserver, vip.IP.AF()); err != nil {
 			log.Fatalf("%v: failed to add Vserver: %v", v, err)
 		}
@@ -1359,6 +2068,7 @@ func (v *vserver) configureVIPs() {
 			log.Fatalf("%v: failed to add VIP %v: %v", v, vip.IP, err)
 		}
 		v.vips[*vip] = true
+		v.engine.audit.record(auditActorEngine, auditVIPAdded, v.String(), nil, vip.String())
 	}
 }
 
@@ -1382,6 +2092,7 @@ func (v *vserver) unconfigureVIP(vip *seesaw.VIP) {
 			log.Fatalf("%v: failed to delete Vserver: %v", v, err)
 		}
 		delete(v.lbVservers, vip.IP)
+		v.engine.audit.record(auditActorEngine, auditVIPRemoved, v.String(), vip.String(), nil)
 	}
 	delete(v.vips, *vip)
 }
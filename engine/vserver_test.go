@@ -17,6 +17,7 @@
 package engine
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"path/filepath"
@@ -28,6 +29,8 @@ import (
 	"github.com/wy2745/seesaw/common/seesaw"
 	"github.com/wy2745/seesaw/engine/config"
 	"github.com/wy2745/seesaw/healthcheck"
+	"github.com/wy2745/seesaw/ipvs"
+	"github.com/wy2745/seesaw/quagga"
 	"github.com/kylelemons/godebug/pretty"
 
 	log "github.com/golang/glog"
@@ -314,7 +317,7 @@ var expectedServiceStates = map[serviceKey]struct {
 	testStates
 	dests map[destinationKey]testStates
 }{
-	serviceKey{seesaw.IPv4, 0, seesaw.IPProtoUDP, 53}: {
+	serviceKey{seesaw.IPv4, 0, seesaw.IPProtoUDP, 53, seesaw.IP{}}: {
 		seesaw.ParseIP("192.168.255.1"),
 		testStates{
 			active:  []bool{false, false, true, true, false, false, false},
@@ -331,7 +334,7 @@ var expectedServiceStates = map[serviceKey]struct {
 			},
 		},
 	},
-	serviceKey{seesaw.IPv4, 0, seesaw.IPProtoTCP, 8053}: {
+	serviceKey{seesaw.IPv4, 0, seesaw.IPProtoTCP, 8053, seesaw.IP{}}: {
 		seesaw.ParseIP("192.168.255.1"),
 		testStates{
 			active:  []bool{false, false, true, true, false, false, false},
@@ -348,7 +351,7 @@ var expectedServiceStates = map[serviceKey]struct {
 			},
 		},
 	},
-	serviceKey{seesaw.IPv6, 0, seesaw.IPProtoUDP, 53}: {
+	serviceKey{seesaw.IPv6, 0, seesaw.IPProtoUDP, 53, seesaw.IP{}}: {
 		seesaw.ParseIP("2012::1"),
 		testStates{
 			active:  []bool{false, false, true, true, true, false, false},
@@ -365,7 +368,7 @@ var expectedServiceStates = map[serviceKey]struct {
 			},
 		},
 	},
-	serviceKey{seesaw.IPv6, 0, seesaw.IPProtoTCP, 8053}: {
+	serviceKey{seesaw.IPv6, 0, seesaw.IPProtoTCP, 8053, seesaw.IP{}}: {
 		seesaw.ParseIP("2012::1"),
 		testStates{
 			active:  []bool{false, false, true, true, true, true, false},
@@ -787,6 +790,253 @@ func TestVserverOverride(t *testing.T) {
 	}
 }
 
+func checkBackendWeights(v *vserver, hostname string, drained bool) []error {
+	errs := make([]error, 0)
+	for _, svc := range v.services {
+		for _, dst := range svc.dests {
+			want := dst.backend.Weight
+			if dst.backend.Hostname == hostname && drained {
+				want = 0
+			}
+			if dst.weight != want || dst.ipvsDst.Weight != want {
+				errs = append(errs, fmt.Errorf("Destination %v has weight %d (IPVS %d), want %d",
+					dst, dst.weight, dst.ipvsDst.Weight, want))
+			}
+			if !dst.active {
+				errs = append(errs, fmt.Errorf("Expected destination %v to be active", dst))
+			}
+		}
+	}
+	return errs
+}
+
+func TestDrainBackend(t *testing.T) {
+	vserver := newTestVserver(nil)
+	vserver.handleConfigUpdate(&vserverConfig)
+
+	// Bring everything up.
+	for _, c := range vserver.checks {
+		n := &checkNotification{key: c.key, status: statusHealthy}
+		vserver.handleCheckNotification(n)
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+
+	// Drain a backend - its destinations should remain active with a zero
+	// weight.
+	hostname := backend1.Hostname
+	o := &seesaw.BackendOverride{Hostname: hostname, OverrideState: seesaw.OverrideDrain}
+	vserver.handleOverride(o)
+	for _, err := range checkBackendWeights(vserver, hostname, true) {
+		t.Error(err)
+	}
+
+	// The drain should persist across configuration updates.
+	vserver.handleConfigUpdate(&vserverConfig)
+	for _, err := range checkBackendWeights(vserver, hostname, true) {
+		t.Error(err)
+	}
+
+	// Remove the drain override, the weight should be restored.
+	o = &seesaw.BackendOverride{Hostname: hostname, OverrideState: seesaw.OverrideDefault}
+	vserver.handleOverride(o)
+	for _, err := range checkBackendWeights(vserver, hostname, false) {
+		t.Error(err)
+	}
+}
+
+func TestBackendOverride(t *testing.T) {
+	vserver := newTestVserver(nil)
+	vserver.handleConfigUpdate(&vserverConfig)
+
+	hostname := backend1.Hostname
+	var dests []*destination
+	for _, svc := range vserver.services {
+		for _, dst := range svc.dests {
+			if dst.backend.Hostname == hostname {
+				dests = append(dests, dst)
+			}
+		}
+	}
+	if len(dests) == 0 {
+		t.Fatalf("No destinations found for %v", hostname)
+	}
+	checkDests := func(desc string, want bool, state seesaw.OverrideState) {
+		for _, dst := range dests {
+			if dst.healthy != want || dst.active != want {
+				t.Errorf("%s: destination %v is healthy %t, active %t, want %t",
+					desc, dst, dst.healthy, dst.active, want)
+			}
+			if got := dst.snapshot().Override; got != state {
+				t.Errorf("%s: destination %v has override %v, want %v", desc, dst, got, state)
+			}
+		}
+	}
+
+	// An enabled backend is healthy, regardless of its healthchecks.
+	vserver.handleOverride(&seesaw.BackendOverride{Hostname: hostname, OverrideState: seesaw.OverrideEnable})
+	checkDests("enabled", true, seesaw.OverrideEnable)
+
+	// A disabled backend is unhealthy, regardless of its healthchecks.
+	for _, c := range vserver.checks {
+		vserver.handleCheckNotification(&checkNotification{key: c.key, status: statusHealthy})
+	}
+	vserver.handleOverride(&seesaw.BackendOverride{Hostname: hostname, OverrideState: seesaw.OverrideDisable})
+	checkDests("disabled", false, seesaw.OverrideDisable)
+
+	// The override should persist across configuration updates.
+	vserver.handleConfigUpdate(&vserverConfig)
+	checkDests("config update", false, seesaw.OverrideDisable)
+
+	// Clearing the override returns to the state of the healthchecks.
+	vserver.handleOverride(&seesaw.BackendOverride{Hostname: hostname, OverrideState: seesaw.OverrideDefault})
+	checkDests("cleared", true, seesaw.OverrideDefault)
+}
+
+func TestWeightedHealthcheck(t *testing.T) {
+	vserver := newTestVserver(nil)
+	vserver.handleConfigUpdate(&vserverConfig)
+
+	// Bring everything up.
+	for _, c := range vserver.checks {
+		n := &checkNotification{key: c.key, status: statusHealthy}
+		vserver.handleCheckNotification(n)
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+
+	key := checkKey{
+		vserverIP:       seesaw.ParseIP("192.168.255.1"),
+		backendIP:       seesaw.ParseIP("1.1.1.11"),
+		serviceProtocol: seesaw.IPProtoUDP,
+		servicePort:     53,
+		healthcheckPort: 1,
+		name:            "NONE/1_0",
+	}
+	check := vserver.checks[key]
+	if check == nil {
+		t.Fatalf("Failed to find check %v", key)
+	}
+
+	checkWeights := func(want int32) {
+		for _, svc := range vserver.services {
+			for _, dst := range svc.dests {
+				w := dst.backend.Weight
+				for _, c := range dst.checks {
+					if c == check {
+						w = want
+					}
+				}
+				if dst.weight != w || dst.ipvsDst.Weight != w {
+					t.Errorf("Destination %v has weight %d (IPVS %d), want %d",
+						dst, dst.weight, dst.ipvsDst.Weight, w)
+				}
+				if !dst.active {
+					t.Errorf("Expected destination %v to be active", dst)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		status healthcheck.Status
+		want   int32
+	}{
+		{healthcheck.Status{State: healthcheck.StateHealthy, Weighted: true, Weight: 50}, 1},
+		{healthcheck.Status{State: healthcheck.StateHealthy, Weighted: true, Weight: 0}, 0},
+		{healthcheck.Status{State: healthcheck.StateHealthy, Weighted: true, Weight: 100}, 2},
+		{healthcheck.Status{State: healthcheck.StateHealthy, Weighted: true, Weight: 10}, 1},
+		{statusHealthy, 2},
+	}
+	for _, test := range tests {
+		vserver.handleCheckNotification(&checkNotification{key: key, status: test.status})
+		checkWeights(test.want)
+	}
+
+	// The weight should persist across configuration updates.
+	status := healthcheck.Status{State: healthcheck.StateHealthy, Weighted: true, Weight: 0}
+	vserver.handleCheckNotification(&checkNotification{key: key, status: status})
+	vserver.handleConfigUpdate(&vserverConfig)
+	check = vserver.checks[key]
+	checkWeights(0)
+}
+
+func TestSlowStart(t *testing.T) {
+	duration := 10 * time.Second
+	v := newTestVserver(nil)
+	v.config = &config.Vserver{SlowStartDuration: duration}
+	start := time.Unix(1500000000, 0)
+	d := &destination{slowStart: start}
+	for _, test := range []struct {
+		elapsed time.Duration
+		weight  int32
+		want    int32
+	}{
+		{0, 100, 1},
+		{-time.Second, 100, 1},
+		{time.Second, 100, 10},
+		{5 * time.Second, 100, 50},
+		{5 * time.Second, 1, 1},
+		{5 * time.Second, 0, 0},
+		{duration, 100, 100},
+		{2 * duration, 100, 100},
+	} {
+		if got := v.slowStartWeight(d, test.weight, start.Add(test.elapsed)); got != test.want {
+			t.Errorf("slowStartWeight(%d) after %v = %d, want %d", test.weight, test.elapsed, got, test.want)
+		}
+	}
+	if got := v.slowStartWeight(&destination{}, 100, start); got != 100 {
+		t.Errorf("slowStartWeight(100) without slow start = %d, want 100", got)
+	}
+
+	// Destinations that become healthy start with a reduced weight, then
+	// ramp up to the backend weight.
+	vc := vserverConfig
+	vc.SlowStartDuration = duration
+	vserver := newTestVserver(nil)
+	vserver.handleConfigUpdate(&vc)
+	for _, c := range vserver.checks {
+		vserver.handleCheckNotification(&checkNotification{key: c.key, status: statusHealthy})
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+	for _, svc := range vserver.services {
+		for _, dst := range svc.dests {
+			if dst.slowStart.IsZero() {
+				t.Errorf("Destination %v is not slow starting", dst)
+			}
+			if dst.ipvsDst.Weight != 1 {
+				t.Errorf("Destination %v has IPVS weight %d, want 1", dst, dst.ipvsDst.Weight)
+			}
+		}
+	}
+
+	// Slow start should persist across configuration updates.
+	vserver.handleConfigUpdate(&vc)
+	if !vserver.updateSlowStart(time.Now()) {
+		t.Errorf("Expected destinations to be slow starting after configuration update")
+	}
+
+	vserver.updateSlowStart(time.Now().Add(duration))
+	for _, svc := range vserver.services {
+		for _, dst := range svc.dests {
+			if !dst.slowStart.IsZero() {
+				t.Errorf("Destination %v is still slow starting", dst)
+			}
+			if w := dst.backend.Weight; dst.weight != w || dst.ipvsDst.Weight != w {
+				t.Errorf("Destination %v has weight %d (IPVS %d), want %d",
+					dst, dst.weight, dst.ipvsDst.Weight, w)
+			}
+		}
+	}
+	if vserver.updateSlowStart(time.Now()) {
+		t.Errorf("Expected no destinations to be slow starting")
+	}
+}
+
 var (
 	serviceKey1 = seesaw.ServiceKey{
 		AF:    seesaw.IPv4,
@@ -1009,8 +1259,10 @@ func TestVserverSnapshot(t *testing.T) {
 			}
 			expectedBackend := expectedDst.Backend
 			backend := dst.Backend
+			checks := dst.Healthchecks
 			expectedDst.Backend = nil
 			dst.Backend = nil
+			dst.Healthchecks = nil
 			if !reflect.DeepEqual(expectedDst, dst) {
 				t.Errorf("Snapshot destination mismatch - got %#v, want %#v",
 					dst, expectedDst)
@@ -1019,8 +1271,18 @@ func TestVserverSnapshot(t *testing.T) {
 				t.Errorf("Snapshot backend mismatch - got %#v, want %#v",
 					backend, expectedBackend)
 			}
+			if len(checks) == 0 {
+				t.Errorf("Snapshot destination %s has no healthchecks", dst.Name)
+			}
+			for _, hc := range checks {
+				if hc.State != healthcheck.StateUnknown.String() {
+					t.Errorf("Snapshot destination %s healthcheck %s has state %s, want %v",
+						dst.Name, hc.Description, hc.State, healthcheck.StateUnknown)
+				}
+			}
 			expectedDst.Backend = expectedBackend
 			dst.Backend = backend
+			dst.Healthchecks = checks
 		}
 	}
 }
@@ -1826,3 +2088,745 @@ func TestReIPVserver(t *testing.T) {
 		}
 	}
 }
+
+func TestDrain(t *testing.T) {
+	vserver := newTestVserver(nil)
+	vc := vserverConfig
+	vserver.handleConfigUpdate(&vc)
+	for _, c := range vserver.checks {
+		vserver.handleCheckNotification(&checkNotification{key: c.key, status: statusHealthy})
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+
+	// Draining an active vserver sets all destination weights to zero.
+	deadline := time.Now().Add(time.Minute)
+	vserver.handleDrain(deadline)
+	for _, svc := range vserver.services {
+		for _, dst := range svc.dests {
+			if dst.ipvsDst.Weight != 0 {
+				t.Errorf("Destination %v has IPVS weight %d while draining, want 0", dst, dst.ipvsDst.Weight)
+			}
+		}
+	}
+	if got := vserver.snapshot().DrainDeadline; !got.Equal(deadline) {
+		t.Errorf("Snapshot drain deadline = %v, want %v", got, deadline)
+	}
+
+	// An inactive vserver has nothing to drain.
+	inactive := newTestVserver(nil)
+	inactive.handleConfigUpdate(&vc)
+	before := time.Now()
+	inactive.handleDrain(deadline)
+	if inactive.drainDeadline.Before(before) || inactive.drainDeadline.After(time.Now()) {
+		t.Errorf("Inactive vserver drain deadline = %v, want current time", inactive.drainDeadline)
+	}
+}
+
+func TestVserversConflict(t *testing.T) {
+	a := &config.Vserver{Name: "a", Host: seesaw.Host{IPv4Addr: net.ParseIP("192.168.36.1")}}
+	b := &config.Vserver{Name: "b", Host: seesaw.Host{IPv4Addr: net.ParseIP("192.168.36.1")}}
+	c := &config.Vserver{Name: "c", Host: seesaw.Host{IPv4Addr: net.ParseIP("192.168.36.2")}}
+	for _, test := range []struct {
+		a, b *config.Vserver
+		want bool
+	}{
+		{a, a, true},
+		{a, b, true},
+		{a, c, false},
+		{a, nil, false},
+	} {
+		if got := vserversConflict(test.a, test.b); got != test.want {
+			t.Errorf("vserversConflict(%v, %v) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestMaglevScheduler(t *testing.T) {
+	ventry := &config.VserverEntry{
+		Port:          53,
+		Proto:         seesaw.IPProtoUDP,
+		Scheduler:     seesaw.LBSchedulerMH,
+		SchedFallback: true,
+		SchedPort:     true,
+	}
+	svc := &service{ventry: ventry, ip: seesaw.NewIP(net.ParseIP("192.168.36.1"))}
+	ipvsSvc := svc.ipvsService()
+	if ipvsSvc.Scheduler != "mh" {
+		t.Errorf("IPVS scheduler = %q, want %q", ipvsSvc.Scheduler, "mh")
+	}
+	if want := ipvs.SFSched1 | ipvs.SFSched2; ipvsSvc.Flags != want {
+		t.Errorf("IPVS flags = %#x, want %#x", ipvsSvc.Flags, want)
+	}
+
+	e := newTestEngine()
+	vc := &config.Vserver{Name: "dns", Entries: map[string]*config.VserverEntry{ventry.Key(): ventry}}
+	if err := e.checkSchedulers(vc); err != nil {
+		t.Errorf("checkSchedulers failed: %v", err)
+	}
+	e.unsupportedSchedulers = map[seesaw.LBScheduler]bool{seesaw.LBSchedulerMH: true}
+	if err := e.checkSchedulers(vc); err == nil {
+		t.Errorf("checkSchedulers with unsupported mh scheduler succeeded, want error")
+	}
+}
+
+func TestPersistencePrefix(t *testing.T) {
+	tests := []struct {
+		desc    string
+		ip      string
+		prefix4 int
+		prefix6 int
+		want    net.IPMask
+	}{
+		{"no prefix", "192.168.36.1", 0, 0, nil},
+		{"ipv4 prefix", "192.168.36.1", 24, 64, net.CIDRMask(24, 32)},
+		{"full ipv4 prefix", "192.168.36.1", 32, 64, nil},
+		{"ipv6 prefix", "2015:cafe:36::1", 24, 64, net.CIDRMask(64, 128)},
+		{"full ipv6 prefix", "2015:cafe:36::1", 24, 128, nil},
+	}
+	for _, test := range tests {
+		ventry := &config.VserverEntry{
+			Port:                  80,
+			Proto:                 seesaw.IPProtoTCP,
+			Scheduler:             seesaw.LBSchedulerWRR,
+			Persistence:           300,
+			PersistencePrefixIPv4: test.prefix4,
+			PersistencePrefixIPv6: test.prefix6,
+		}
+		ip := net.ParseIP(test.ip)
+		svc := &service{serviceKey: serviceKey{af: seesaw.IPv6}, ip: seesaw.NewIP(ip), ventry: ventry}
+		if ip.To4() != nil {
+			svc.af = seesaw.IPv4
+		}
+		ipvsSvc := svc.ipvsService()
+		if ipvsSvc.Flags&ipvs.SFPersistent == 0 {
+			t.Errorf("Test %q: IPVS service is not persistent", test.desc)
+		}
+		if !bytes.Equal(ipvsSvc.Netmask, test.want) {
+			t.Errorf("Test %q: IPVS netmask = %v, want %v", test.desc, ipvsSvc.Netmask, test.want)
+		}
+	}
+}
+
+func TestDestinationThresholds(t *testing.T) {
+	ventry := &config.VserverEntry{
+		Port:       80,
+		Proto:      seesaw.IPProtoTCP,
+		Mode:       seesaw.LBModeDSR,
+		LThreshold: 100,
+		UThreshold: 200,
+	}
+	tests := []struct {
+		desc         string
+		backend      *seesaw.Backend
+		lower, upper uint32
+	}{
+		{"vserver entry thresholds", &seesaw.Backend{}, 100, 200},
+		{"backend thresholds", &seesaw.Backend{UpperThreshold: 1000, LowerThreshold: 500}, 500, 1000},
+		{"backend upper threshold", &seesaw.Backend{UpperThreshold: 1000}, 0, 1000},
+	}
+	for _, test := range tests {
+		dst := &destination{
+			service: &service{serviceKey: serviceKey{port: 80}, ventry: ventry},
+			backend: test.backend,
+		}
+		ipvsDst := dst.ipvsDestination()
+		if ipvsDst.LowerThreshold != test.lower || ipvsDst.UpperThreshold != test.upper {
+			t.Errorf("Test %q: got thresholds %d-%d, want %d-%d", test.desc,
+				ipvsDst.LowerThreshold, ipvsDst.UpperThreshold, test.lower, test.upper)
+		}
+	}
+}
+
+func TestDestinationForwarding(t *testing.T) {
+	tests := []struct {
+		mode seesaw.LBMode
+		want ipvs.DestinationFlags
+	}{
+		{seesaw.LBModeDSR, ipvs.DFForwardRoute},
+		{seesaw.LBModeNAT, ipvs.DFForwardMasq},
+		{seesaw.LBModeTUN, ipvs.DFForwardTunnel},
+	}
+	for _, test := range tests {
+		ventry := &config.VserverEntry{Port: 80, Proto: seesaw.IPProtoTCP, Mode: test.mode}
+		dst := &destination{service: &service{serviceKey: serviceKey{port: 80}, ventry: ventry}}
+		if got := dst.ipvsDestination().Flags & ipvs.DFForwardMask; got != test.want {
+			t.Errorf("%v mode: got forwarding flags %#x, want %#x", test.mode, got, test.want)
+		}
+	}
+}
+
+// countingNCC is a dummy NCC that counts IPVS destination operations and
+// records IPVS batches. Operations in a batch may be failed by index.
+type countingNCC struct {
+	dummyNCC
+	adds, updates, deletes int
+	batches                []*ipvs.Batch
+	batchErrors            map[int]string
+}
+
+func (nc *countingNCC) IPVSApplyBatch(b *ipvs.Batch) (*ipvs.BatchResult, error) {
+	nc.batches = append(nc.batches, b)
+	result, err := nc.dummyNCC.IPVSApplyBatch(b)
+	for i, e := range nc.batchErrors {
+		if i < len(result.Errors) {
+			result.Errors[i] = e
+		}
+	}
+	return result, err
+}
+
+func (nc *countingNCC) IPVSAddDestination(svc *ipvs.Service, dst *ipvs.Destination) error {
+	nc.adds++
+	return nil
+}
+
+func (nc *countingNCC) IPVSUpdateDestination(svc *ipvs.Service, dst *ipvs.Destination) error {
+	nc.updates++
+	return nil
+}
+
+func (nc *countingNCC) IPVSDeleteDestination(svc *ipvs.Service, dst *ipvs.Destination) error {
+	nc.deletes++
+	return nil
+}
+
+// bgpNCC is a dummy NCC that records BGP advertisements.
+type bgpNCC struct {
+	dummyNCC
+	adverts []string
+}
+
+func (nc *bgpNCC) BGPAdvertiseVIPCommunities(ip net.IP, communities []string) error {
+	nc.adverts = append(nc.adverts, fmt.Sprintf("%v %q", ip, communities))
+	return nil
+}
+
+func TestBGPCommunities(t *testing.T) {
+	engine := newTestEngine()
+	engine.config.AnycastEnabled = true
+	ncc := &bgpNCC{}
+	engine.ncc = ncc
+	vserver := newTestVserver(engine)
+	vc := vserverConfig
+	vc.BGPCommunities = []string{"64512:100"}
+	vserver.handleConfigUpdate(&vc)
+
+	ip := seesaw.ParseIP("192.168.255.1")
+	vserver.up(ip)
+	want := []string{`192.168.255.1 ["64512:100"]`}
+	if !reflect.DeepEqual(ncc.adverts, want) {
+		t.Fatalf("Got BGP advertisements %q, want %q", ncc.adverts, want)
+	}
+
+	// Changing the communities re-advertises the active anycast VIP.
+	vc2 := vc
+	vc2.BGPCommunities = []string{"64512:200", "64512:1:2"}
+	vserver.handleConfigUpdate(&vc2)
+	want = append(want, `192.168.255.1 ["64512:200" "64512:1:2"]`)
+	if !reflect.DeepEqual(ncc.adverts, want) {
+		t.Fatalf("Got BGP advertisements %q, want %q", ncc.adverts, want)
+	}
+
+	// Other changes do not.
+	vc3 := vc2
+	vc3.Quiescent = true
+	vserver.handleConfigUpdate(&vc3)
+	if !reflect.DeepEqual(ncc.adverts, want) {
+		t.Errorf("Got BGP advertisements %q, want %q", ncc.adverts, want)
+	}
+}
+
+func (nc *bgpNCC) BGPWithdrawVIP(ip net.IP) error {
+	nc.adverts = append(nc.adverts, fmt.Sprintf("withdraw %v", ip))
+	return nil
+}
+
+func (nc *bgpNCC) OSPFAdvertiseVIP(ip net.IP, area quagga.OSPFArea) error {
+	nc.adverts = append(nc.adverts, fmt.Sprintf("%v area %v", ip, area))
+	return nil
+}
+
+func (nc *bgpNCC) OSPFWithdrawVIP(ip net.IP, area quagga.OSPFArea) error {
+	nc.adverts = append(nc.adverts, fmt.Sprintf("withdraw %v area %v", ip, area))
+	return nil
+}
+
+func TestAnycastAllDown(t *testing.T) {
+	tests := []struct {
+		routing           config.RoutingProtocol
+		advert, withdrawn string
+	}{
+		{config.RoutingBGP, "192.168.255.1 []", "withdraw 192.168.255.1"},
+		{config.RoutingOSPF, "192.168.255.1 area 0.0.0.10", "withdraw 192.168.255.1 area 0.0.0.10"},
+	}
+	for _, test := range tests {
+		testAnycastAllDown(t, test.routing, test.advert, test.withdrawn)
+	}
+}
+
+func testAnycastAllDown(t *testing.T, routing config.RoutingProtocol, advert, withdrawn string) {
+	engine := newTestEngine()
+	engine.config.AnycastEnabled = true
+	engine.config.AnycastHoldDown = 50 * time.Millisecond
+	engine.config.RoutingProtocol = routing
+	engine.config.OSPFArea = 10
+	ncc := &bgpNCC{}
+	engine.ncc = ncc
+	vserver := newTestVserver(engine)
+	vip := seesaw.NewVIP(net.ParseIP("192.168.255.1"), nil)
+	vc := vserverConfig
+	vc.Host = seesaw.Host{
+		Hostname: "anycast-vip1.example.com",
+		IPv4Addr: vip.IP.IP(),
+		IPv4Mask: net.CIDRMask(32, 32),
+	}
+	vc.VIPs = map[string]*seesaw.VIP{vip.IP.String(): vip}
+	vserver.handleConfigUpdate(&vc)
+
+	notifyChecks := func(status healthcheck.Status) {
+		for k := range vserver.checks {
+			vserver.handleCheckNotification(&checkNotification{key: k, status: status})
+		}
+	}
+	var want []string
+	checkAdverts := func(desc string) {
+		if !reflect.DeepEqual(ncc.adverts, want) {
+			t.Fatalf("%v %s: got route updates %q, want %q", routing, desc, ncc.adverts, want)
+		}
+	}
+
+	notifyChecks(statusHealthy)
+	want = append(want, advert)
+	checkAdverts("healthy")
+
+	// The route is withdrawn as soon as the last backend goes down.
+	notifyChecks(statusUnhealthy)
+	want = append(want, withdrawn)
+	checkAdverts("all down")
+
+	// Recovering within the hold-down defers the advertisement, and going
+	// down again cancels it.
+	notifyChecks(statusHealthy)
+	checkAdverts("recovered within hold-down")
+	notifyChecks(statusUnhealthy)
+	checkAdverts("down within hold-down")
+	if len(vserver.holdDowns) != 0 {
+		t.Errorf("%v: got %d pending advertisements after going down, want 0", routing, len(vserver.holdDowns))
+	}
+
+	// The route is re-advertised once the VIP has remained up for the
+	// duration of the hold-down.
+	notifyChecks(statusHealthy)
+	checkAdverts("recovered")
+	select {
+	case ip := <-vserver.holdDownEnd:
+		vserver.handleHoldDownEnd(ip)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%v: hold-down did not expire", routing)
+	}
+	want = append(want, advert)
+	checkAdverts("hold-down expired")
+}
+
+func TestServiceUpBatch(t *testing.T) {
+	ncc := &countingNCC{}
+	vserver := newTestVserver(nil)
+	vserver.ncc = ncc
+	vc := vserverConfig
+	vserver.handleConfigUpdate(&vc)
+
+	var svc *service
+	for _, s := range vserver.services {
+		if len(s.dests) > 1 {
+			svc = s
+			break
+		}
+	}
+	if svc == nil {
+		t.Fatalf("No service found with multiple destinations")
+	}
+	for _, d := range svc.dests {
+		d.healthy = true
+	}
+	svc.up()
+
+	// The service and all of its healthy destinations are added in a single
+	// batch, with the service being added first.
+	if len(ncc.batches) != 1 {
+		t.Fatalf("Service up resulted in %d IPVS batches, want 1", len(ncc.batches))
+	}
+	b := ncc.batches[0]
+	if got, want := b.Len(), 1+len(svc.dests); got != want {
+		t.Errorf("IPVS batch has %d operations, want %d", got, want)
+	}
+	for i, e := range b.Entries {
+		want := ipvs.BatchAddDestination
+		if i == 0 {
+			want = ipvs.BatchAddService
+		}
+		if e.Op != want {
+			t.Errorf("IPVS batch operation %d is %v, want %v", i, e.Op, want)
+		}
+	}
+	if ncc.adds != 0 {
+		t.Errorf("Service up resulted in %d individual IPVS destination adds, want 0", ncc.adds)
+	}
+	for _, d := range svc.dests {
+		if !d.active {
+			t.Errorf("Destination %v is not active after service up", d)
+		}
+	}
+	if got := vserver.engine.ipvsBatches.batches; got != 1 {
+		t.Errorf("Engine recorded %d IPVS batches, want 1", got)
+	}
+}
+
+func TestServiceUpBatchFailure(t *testing.T) {
+	ncc := &countingNCC{batchErrors: map[int]string{1: "add destination failed"}}
+	vserver := newTestVserver(nil)
+	vserver.ncc = ncc
+	vc := vserverConfig
+	vserver.handleConfigUpdate(&vc)
+
+	var svc *service
+	for _, s := range vserver.services {
+		if len(s.dests) > 1 {
+			svc = s
+			break
+		}
+	}
+	if svc == nil {
+		t.Fatalf("No service found with multiple destinations")
+	}
+	for _, d := range svc.dests {
+		d.healthy = true
+	}
+	svc.up()
+
+	// The destination that failed to be added is skipped, while the service
+	// and the remaining destinations are brought up.
+	if !svc.active {
+		t.Errorf("Service %v is not active after service up", svc)
+	}
+	failed := ncc.batches[0].Entries[1].Destination
+	for _, d := range svc.dests {
+		want := !d.ipvsDst.Address.Equal(failed.Address)
+		if d.active != want {
+			t.Errorf("Destination %v is active %t after service up, want %t", d, d.active, want)
+		}
+	}
+}
+
+func TestWeightOnlyUpdate(t *testing.T) {
+	ncc := &countingNCC{}
+	vserver := newTestVserver(nil)
+	vserver.ncc = ncc
+	vc := vserverConfig
+	vserver.handleConfigUpdate(&vc)
+
+	// Weighted healthchecks scale the backend weight, which must be retained
+	// when the weight is updated.
+	status := healthcheck.Status{State: healthcheck.StateHealthy, Weighted: true, Weight: 50}
+	for _, c := range vserver.checks {
+		vserver.handleCheckNotification(&checkNotification{key: c.key, status: status})
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+
+	var dests []*destination
+	for _, svc := range vserver.services {
+		for _, dst := range svc.dests {
+			if dst.backend.Hostname == backend1.Hostname {
+				dst.stats.DestinationStats = &ipvs.DestinationStats{ActiveConns: 10}
+				dests = append(dests, dst)
+			}
+		}
+	}
+	if len(dests) == 0 {
+		t.Fatalf("No destinations found for %v", backend1.Hostname)
+	}
+
+	// Change only the weight of a backend.
+	backend := backend1.Clone()
+	backend.Weight = backend1.Weight + 5
+	vc.Backends = map[string]*seesaw.Backend{
+		backend.Hostname:  backend,
+		backend2.Hostname: backend2,
+	}
+	ncc.adds, ncc.updates, ncc.deletes = 0, 0, 0
+	vserver.handleConfigUpdate(&vc)
+
+	if ncc.adds != 0 || ncc.deletes != 0 {
+		t.Errorf("Weight change resulted in %d IPVS destination adds and %d deletes, want 0",
+			ncc.adds, ncc.deletes)
+	}
+	if ncc.updates != len(dests) {
+		t.Errorf("Weight change resulted in %d IPVS destination updates, want %d",
+			ncc.updates, len(dests))
+	}
+	for _, dst := range dests {
+		svcDst := dst.service.dests[dst.destinationKey]
+		if svcDst != dst {
+			t.Errorf("Destination %v was replaced", dst)
+			continue
+		}
+		if !dst.active || !dst.healthy {
+			t.Errorf("Destination %v is not active and healthy after weight change", dst)
+		}
+		if want := backend.Weight / 2; dst.weight != want || dst.ipvsDst.Weight != want {
+			t.Errorf("Destination %v has weight %d (IPVS %d), want %d",
+				dst, dst.weight, dst.ipvsDst.Weight, want)
+		}
+		if dst.stats.ActiveConns != 10 {
+			t.Errorf("Destination %v has %d active connections, want 10", dst, dst.stats.ActiveConns)
+		}
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+}
+
+func TestQuiescent(t *testing.T) {
+	ncc := &countingNCC{}
+	vserver := newTestVserver(nil)
+	vserver.ncc = ncc
+	vc := vserverConfig
+	vc.Quiescent = true
+	vserver.handleConfigUpdate(&vc)
+	for _, c := range vserver.checks {
+		vserver.handleCheckNotification(&checkNotification{key: c.key, status: statusHealthy})
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+
+	var dests []*destination
+	checks := make(map[checkKey]bool)
+	for _, svc := range vserver.services {
+		for _, dst := range svc.dests {
+			if dst.backend.Hostname != backend1.Hostname {
+				continue
+			}
+			dests = append(dests, dst)
+			for _, c := range dst.checks {
+				checks[c.key] = true
+			}
+		}
+	}
+	if len(dests) == 0 {
+		t.Fatalf("No destinations found for %v", backend1.Hostname)
+	}
+	notifyChecks := func(status healthcheck.Status) {
+		for key := range checks {
+			vserver.handleCheckNotification(&checkNotification{key: key, status: status})
+		}
+	}
+
+	// A failing backend should be quiesced rather than deleted.
+	ncc.adds, ncc.updates, ncc.deletes = 0, 0, 0
+	notifyChecks(statusUnhealthy)
+	if ncc.deletes != 0 {
+		t.Errorf("Quiescing resulted in %d IPVS destination deletes, want 0", ncc.deletes)
+	}
+	if ncc.updates != len(dests) {
+		t.Errorf("Quiescing resulted in %d IPVS destination updates, want %d", ncc.updates, len(dests))
+	}
+	for _, dst := range dests {
+		if dst.healthy || !dst.active {
+			t.Errorf("Destination %v is healthy %t, active %t, want unhealthy and active",
+				dst, dst.healthy, dst.active)
+		}
+		if dst.weight != 0 || dst.ipvsDst.Weight != 0 {
+			t.Errorf("Quiesced destination %v has weight %d (IPVS %d), want 0",
+				dst, dst.weight, dst.ipvsDst.Weight)
+		}
+	}
+
+	// The weight should remain at zero across configuration updates.
+	vserver.handleConfigUpdate(&vc)
+	for _, dst := range dests {
+		if dst.weight != 0 || dst.ipvsDst.Weight != 0 {
+			t.Errorf("Quiesced destination %v has weight %d (IPVS %d) after config update, want 0",
+				dst, dst.weight, dst.ipvsDst.Weight)
+		}
+	}
+
+	// A recovered backend should have its weight restored.
+	ncc.adds, ncc.updates, ncc.deletes = 0, 0, 0
+	notifyChecks(statusHealthy)
+	if ncc.adds != 0 {
+		t.Errorf("Restoring resulted in %d IPVS destination adds, want 0", ncc.adds)
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+	for _, dst := range dests {
+		if dst.ipvsDst.Weight != backend1.Weight {
+			t.Errorf("Restored destination %v has IPVS weight %d, want %d",
+				dst, dst.ipvsDst.Weight, backend1.Weight)
+		}
+	}
+
+	// A quiesced backend is deleted when it is removed from the config.
+	notifyChecks(statusUnhealthy)
+	ncc.adds, ncc.updates, ncc.deletes = 0, 0, 0
+	vc.Backends = map[string]*seesaw.Backend{backend2.Hostname: backend2}
+	vserver.handleConfigUpdate(&vc)
+	if ncc.deletes != len(dests) {
+		t.Errorf("Removing backend resulted in %d IPVS destination deletes, want %d",
+			ncc.deletes, len(dests))
+	}
+}
+
+func TestOnAllDownWithdraw(t *testing.T) {
+	e := newTestEngine()
+	vserver := newTestVserver(e)
+	lbIF := e.lbInterface.(*dummyLBInterface)
+	vip := seesaw.NewVIP(net.ParseIP("192.168.36.1"), nil)
+	vc := vserverConfig
+	vc.Host = seesaw.Host{
+		Hostname: "dns-vip1.example.com",
+		IPv4Addr: vip.IP.IP(),
+		IPv4Mask: net.CIDRMask(24, 32),
+	}
+	vc.VIPs = map[string]*seesaw.VIP{vip.IP.String(): vip}
+	vc.OnAllDown = config.OnAllDownWithdraw
+	vserver.handleConfigUpdate(&vc)
+
+	notifyChecks := func(status healthcheck.Status) {
+		for k := range vserver.checks {
+			vserver.handleCheckNotification(&checkNotification{key: k, status: status})
+		}
+	}
+	checkVIP := func(desc string, want bool) {
+		if got := lbIF.vips[*vip]; got != want {
+			t.Errorf("%s: VIP %v configured = %t, want %t", desc, vip.IP, got, want)
+		}
+		if got := vserver.vips[*vip]; got != want {
+			t.Errorf("%s: vserver VIP %v configured = %t, want %t", desc, vip.IP, got, want)
+		}
+	}
+
+	checkVIP("initial", false)
+	notifyChecks(statusHealthy)
+	checkVIP("healthy", true)
+	notifyChecks(statusUnhealthy)
+	checkVIP("unhealthy", false)
+	notifyChecks(statusHealthy)
+	checkVIP("recovered", true)
+}
+
+func TestOnAllDownFallback(t *testing.T) {
+	vserver := newTestVserver(nil)
+	fallback := newTestBackend(9)
+	vc := vserverConfig
+	vc.OnAllDown = config.OnAllDownFallback
+	vc.FallbackBackend = fallback
+	vserver.handleConfigUpdate(&vc)
+
+	fallbacks := make(map[*service]*destination)
+	for _, svc := range vserver.services {
+		dst := svc.fallback()
+		if dst == nil {
+			t.Fatalf("Service %v has no fallback destination", svc)
+		}
+		if len(dst.checks) != 0 {
+			t.Errorf("Fallback destination %v has %d checks, want 0", dst, len(dst.checks))
+		}
+		fallbacks[svc] = dst
+	}
+	checkFallbacks := func(desc string, want bool) {
+		for svc, dst := range fallbacks {
+			if dst.healthy != want || dst.active != want {
+				t.Errorf("%s: fallback destination %v is healthy %t, active %t, want %t",
+					desc, dst, dst.healthy, dst.active, want)
+			}
+			if !svc.active {
+				t.Errorf("%s: service %v is inactive, want active", desc, svc)
+			}
+		}
+	}
+
+	// With no healthy backends, the fallback is used.
+	checkFallbacks("initial", true)
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+
+	// Once a backend is healthy, the fallback is no longer used.
+	for _, c := range vserver.checks {
+		vserver.handleCheckNotification(&checkNotification{key: c.key, status: statusHealthy})
+	}
+	checkFallbacks("healthy", false)
+
+	for _, c := range vserver.checks {
+		vserver.handleCheckNotification(&checkNotification{key: c.key, status: statusUnhealthy})
+	}
+	checkFallbacks("unhealthy", true)
+}
+
+func TestAdditionalVIPs(t *testing.T) {
+	e := newTestEngine()
+	vserver := newTestVserver(e)
+	lbIF := e.lbInterface.(*dummyLBInterface)
+	vserver.handleConfigUpdate(&vserverConfig)
+	wantChecks := len(vserver.checks)
+
+	alias := net.ParseIP("192.168.36.10")
+	vip := seesaw.NewVIP(alias, nil)
+	vc := vserverConfig
+	vc.AdditionalVIPs = []net.IP{alias}
+	vc.VIPs = map[string]*seesaw.VIP{vip.IP.String(): vip}
+	vserver.handleConfigUpdate(&vc)
+
+	var aliases []*service
+	for key, svc := range vserver.services {
+		if key.alias == vip.IP {
+			aliases = append(aliases, svc)
+		}
+	}
+	if got, want := len(aliases), len(vc.Entries); got != want {
+		t.Fatalf("Got %d services for additional VIP, want %d", got, want)
+	}
+	if got := len(vserver.checks); got != wantChecks {
+		t.Errorf("Got %d checks with an additional VIP, want %d", got, wantChecks)
+	}
+	if !lbIF.vips[*vip] {
+		t.Errorf("Additional VIP %v is not configured", vip.IP)
+	}
+	if got := vserver.lbVservers[vip.IP].IPv4Addr; !got.Equal(alias) {
+		t.Errorf("Load balancing vserver for %v has IPv4 address %v", vip.IP, got)
+	}
+
+	for _, c := range vserver.checks {
+		vserver.handleCheckNotification(&checkNotification{key: c.key, status: statusHealthy})
+	}
+	for _, err := range checkAllUp(vserver) {
+		t.Error(err)
+	}
+	for _, svc := range aliases {
+		if !svc.ip.Equal(vip.IP) {
+			t.Errorf("Service %v has IP %v, want %v", svc, svc.ip, vip.IP)
+		}
+		for _, dst := range svc.dests {
+			if !dst.healthy || !dst.active {
+				t.Errorf("Destination %v for %v is healthy %t, active %t, want healthy and active",
+					dst, svc, dst.healthy, dst.active)
+			}
+		}
+	}
+
+	// Removing the additional VIP removes its services.
+	vserver.handleConfigUpdate(&vserverConfig)
+	for key := range vserver.services {
+		if key.alias == vip.IP {
+			t.Errorf("Service %v remains after additional VIP was removed", vserver.services[key])
+		}
+	}
+	if lbIF.vips[*vip] {
+		t.Errorf("Additional VIP %v remains configured after being removed", vip.IP)
+	}
+}
@@ -67,9 +67,12 @@ type NodeConfig struct {
 	ConfigCheckRetryDelay   time.Duration
 	MasterAdvertInterval    time.Duration
 	Preempt                 bool
+	PreemptDelay            time.Duration
+	SplitBrainDemote        bool
 	StatusReportInterval    time.Duration
 	StatusReportMaxFailures int
 	StatusReportRetryDelay  time.Duration
+	TrackInterval           time.Duration
 }
 
 // Node represents one member of a high availability cluster.
@@ -83,6 +86,11 @@ type Node struct {
 	receiveCount         uint64
 	masterDownInterval   time.Duration
 	lastMasterAdvertTime time.Time
+	preemptStart         time.Time
+	lastPreemptAdvert    time.Time
+	lastSplitBrainAdvert time.Time // Last advertisement from a conflicting master.
+	trackPenalty         int       // Total weight of the failing trackers.
+	failedTrackers       []string  // Names of the failing trackers.
 	errChannel           chan error
 	recvChannel          chan *advertisement
 	stopSenderChannel    chan seesaw.HAState
@@ -124,7 +132,8 @@ func (n *Node) state() seesaw.HAState {
 	return n.haStatus.State
 }
 
-// setState changes the HA state for this node.
+// setState changes the HA state for this node. Any preemption delay in
+// progress is abandoned and any split-brain is cleared when the state changes.
 func (n *Node) setState(s seesaw.HAState) {
 	n.statusLock.Lock()
 	defer n.statusLock.Unlock()
@@ -132,6 +141,8 @@ func (n *Node) setState(s seesaw.HAState) {
 		n.haStatus.State = s
 		n.haStatus.Since = time.Now()
 		n.haStatus.Transitions++
+		n.preemptStart = time.Time{}
+		n.lastSplitBrainAdvert = time.Time{}
 	}
 }
 
@@ -142,15 +153,20 @@ func (n *Node) status() seesaw.HAStatus {
 	n.haStatus.Sent = atomic.LoadUint64(&n.sendCount)
 	n.haStatus.Received = atomic.LoadUint64(&n.receiveCount)
 	n.haStatus.ReceivedQueued = uint64(len(n.recvChannel))
+	n.haStatus.Priority = n.Priority
+	n.haStatus.EffectivePriority = effectivePriority(n.Priority, n.trackPenalty)
+	n.haStatus.FailedTrackers = n.failedTrackers
+	n.haStatus.SplitBrain = n.splitBrain(time.Now())
 	return n.haStatus
 }
 
-// newAdvertisement creates a new advertisement with this Node's VRID and priority.
+// newAdvertisement creates a new advertisement with this Node's VRID and
+// effective priority.
 func (n *Node) newAdvertisement() *advertisement {
 	return &advertisement{
 		VersionType: vrrpVersionType,
 		VRID:        n.VRID,
-		Priority:    n.Priority,
+		Priority:    n.effectivePriority(),
 		AdvertInt:   uint16(n.MasterAdvertInterval / time.Millisecond / 10), // AdvertInt is in centiseconds
 	}
 }
@@ -159,6 +175,8 @@ func (n *Node) newAdvertisement() *advertisement {
 // advertisements, and periodically notifies the engine of the current state. Run does not return
 // until Shutdown is called or an unrecoverable error occurs.
 func (n *Node) Run() error {
+	n.updateTrackers()
+	go n.trackHealth()
 	go n.receiveAdvertisements()
 	go n.reportStatus()
 	go n.checkConfig()
@@ -261,14 +279,24 @@ func (n *Node) doMasterTasks() seesaw.HAState {
 				advert.VRID, n.VRID)
 			return seesaw.HAMaster
 		}
-		if advert.Priority == n.Priority {
+		priority := n.effectivePriority()
+		if advert.Priority != 0 && advert.Priority <= priority {
+			n.detectSplitBrain(advert.Priority, priority)
+		}
+		if advert.Priority == priority {
 			// TODO(angusc): RFC 5798 says we should compare IP addresses at this point.
+			if n.SplitBrainDemote {
+				log.Infof("doMasterTasks: peer priority (%v) == my priority (%v) - becoming BACKUP",
+					advert.Priority, priority)
+				n.lastMasterAdvertTime = time.Now()
+				return seesaw.HABackup
+			}
 			log.Warningf("doMasterTasks: ignoring advertisement with my priority (%v)", advert.Priority)
 			return seesaw.HAMaster
 		}
-		if advert.Priority > n.Priority {
+		if advert.Priority > priority {
 			log.Infof("doMasterTasks: peer priority (%v) > my priority (%v) - becoming BACKUP",
-				advert.Priority, n.Priority)
+				advert.Priority, priority)
 			n.lastMasterAdvertTime = time.Now()
 			return seesaw.HABackup
 		}
@@ -284,6 +312,26 @@ func (n *Node) doMasterTasks() seesaw.HAState {
 	return seesaw.HAMaster
 }
 
+// detectSplitBrain records the receipt of an advertisement from a peer that
+// also claims to be master, while this node is master.
+func (n *Node) detectSplitBrain(peerPriority, priority uint8) {
+	now := time.Now()
+	n.statusLock.Lock()
+	detected := !n.splitBrain(now)
+	n.lastSplitBrainAdvert = now
+	n.statusLock.Unlock()
+	if detected {
+		log.Errorf("doMasterTasks: SPLIT-BRAIN DETECTED - received advertisement from another master "+
+			"(peer priority %v, my priority %v)", peerPriority, priority)
+	}
+}
+
+// splitBrain returns true if an advertisement has been received from another
+// master within the master down interval. The caller must hold statusLock.
+func (n *Node) splitBrain(now time.Time) bool {
+	return !n.lastSplitBrainAdvert.IsZero() && now.Sub(n.lastSplitBrainAdvert) <= n.masterDownInterval
+}
+
 func (n *Node) doBackupTasks() seesaw.HAState {
 	deadline := n.lastMasterAdvertTime.Add(n.masterDownInterval)
 	remaining := deadline.Sub(time.Now())
@@ -327,10 +375,15 @@ func (n *Node) backupHandleAdvertisement(advert *advertisement) seesaw.HAState {
 		log.Infof("backupHandleAdvertisement: peer priority is 0 - becoming MASTER")
 		return seesaw.HAMaster
 
-	case n.Preempt && advert.Priority < n.Priority:
-		log.Infof("backupHandleAdvertisement: peer priority (%v) < my priority (%v) - becoming MASTER",
-			advert.Priority, n.Priority)
-		return seesaw.HAMaster
+	case n.Preempt && advert.Priority < n.effectivePriority():
+		if n.preemptDelayExpired() {
+			log.Infof("backupHandleAdvertisement: peer priority (%v) < my priority (%v) - becoming MASTER",
+				advert.Priority, n.effectivePriority())
+			return seesaw.HAMaster
+		}
+
+	default:
+		n.preemptStart = time.Time{}
 	}
 
 	// Per RFC 5798, set the masterDownInterval based on the advert interval received from the
@@ -340,6 +393,26 @@ func (n *Node) backupHandleAdvertisement(advert *advertisement) seesaw.HAState {
 	return seesaw.HABackup
 }
 
+// preemptDelayExpired returns true if this node has been able to preempt the
+// current master for at least the preemption delay. Any advertisement that
+// does not allow preemption restarts the delay, as does a gap of more than the
+// master down interval between advertisements that allow preemption.
+func (n *Node) preemptDelayExpired() bool {
+	now := time.Now()
+	if !n.preemptStart.IsZero() && now.Sub(n.lastPreemptAdvert) > n.masterDownInterval {
+		log.Infof("backupHandleAdvertisement: restarting preemption delay after %v without preemptable advertisements", now.Sub(n.lastPreemptAdvert))
+		n.preemptStart = time.Time{}
+	}
+	n.lastPreemptAdvert = now
+	if n.preemptStart.IsZero() {
+		n.preemptStart = now
+		if n.PreemptDelay > 0 {
+			log.Infof("backupHandleAdvertisement: delaying preemption for %v", n.PreemptDelay)
+		}
+	}
+	return now.Sub(n.preemptStart) >= n.PreemptDelay
+}
+
 func (n *Node) queueAdvertisement(advert *advertisement) {
 	if queueLen := len(n.recvChannel); queueLen > 0 {
 		log.Warningf("queueAdvertisement: %v advertisements already queued", queueLen)
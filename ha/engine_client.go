@@ -35,6 +35,8 @@ type Engine interface {
 	HAConfig() (*seesaw.HAConfig, error)
 	HAState(seesaw.HAState) error
 	HAUpdate(seesaw.HAStatus) (bool, error)
+	HAGroupState(vrid uint8, state seesaw.HAState) error
+	HealthyVservers() (int, error)
 }
 
 // EngineClient implements the Engine interface. It connects to the Seesaw
@@ -98,6 +100,111 @@ func (e *EngineClient) HAUpdate(status seesaw.HAStatus) (bool, error) {
 	return failover, nil
 }
 
+// HAGroupState informs the Seesaw Engine of the current HAState for the
+// VRRP group with the given VRID.
+func (e *EngineClient) HAGroupState(vrid uint8, state seesaw.HAState) error {
+	engineConn, err := net.DialTimeout("unix", e.Socket, engineTimeout)
+	if err != nil {
+		return fmt.Errorf("HAGroupState: Dial failed: %v", err)
+	}
+	engineConn.SetDeadline(time.Now().Add(engineTimeout))
+	engine := rpc.NewClient(engineConn)
+	defer engine.Close()
+
+	var reply int
+	ctx := ipc.NewTrustedContext(seesaw.SCHA)
+	if err := engine.Call("SeesawEngine.HAGroupState", &ipc.HAGroupState{Ctx: ctx, VRID: vrid, State: state}, &reply); err != nil {
+		return fmt.Errorf("HAGroupState: SeesawEngine.HAGroupState failed: %v", err)
+	}
+	return nil
+}
+
+// HealthyVservers returns the number of vservers on the Seesaw Engine that
+// have at least one healthy service.
+func (e *EngineClient) HealthyVservers() (int, error) {
+	engineConn, err := net.DialTimeout("unix", e.Socket, engineTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("HealthyVservers: Dial failed: %v", err)
+	}
+	engineConn.SetDeadline(time.Now().Add(engineTimeout))
+	engine := rpc.NewClient(engineConn)
+	defer engine.Close()
+
+	var vm seesaw.VserverMap
+	ctx := ipc.NewTrustedContext(seesaw.SCHA)
+	if err := engine.Call("SeesawEngine.Vservers", ctx, &vm); err != nil {
+		return 0, fmt.Errorf("HealthyVservers: SeesawEngine.Vservers failed: %v", err)
+	}
+	healthy := 0
+	for _, vs := range vm.Vservers {
+		for _, svc := range vs.Services {
+			if svc.Healthy {
+				healthy++
+				break
+			}
+		}
+	}
+	return healthy, nil
+}
+
+// GroupEngine implements the Engine interface for a Node that participates
+// in an additional VRRP group. The state of the group is reported to the
+// underlying Engine.
+type GroupEngine struct {
+	Engine Engine
+	VRID   uint8
+}
+
+// HAConfig returns the HAConfig for the VRRP group. HA peering is disabled if
+// the group is no longer configured. The node's trackers and heartbeat
+// settings also apply to the group.
+func (e *GroupEngine) HAConfig() (*seesaw.HAConfig, error) {
+	c, err := e.Engine.HAConfig()
+	if err != nil {
+		return nil, err
+	}
+	gc := &seesaw.HAConfig{
+		LocalAddr:  c.LocalAddr,
+		RemoteAddr: c.RemoteAddr,
+		VRID:       e.VRID,
+		Trackers:   c.Trackers,
+
+		HeartbeatInterface: c.HeartbeatInterface,
+		HeartbeatSecret:    c.HeartbeatSecret,
+	}
+	for _, g := range c.Groups {
+		if g.VRID == e.VRID {
+			gc.Enabled = c.Enabled
+			gc.Priority = g.Priority
+		}
+	}
+	return gc, nil
+}
+
+// HAState informs the underlying Engine of the current HAState for the group.
+func (e *GroupEngine) HAState(state seesaw.HAState) error {
+	return e.Engine.HAGroupState(e.VRID, state)
+}
+
+// HAUpdate informs the underlying Engine of the current HAState for the
+// group. Failovers are only requested for the node's VRRP group, hence false
+// is always returned.
+func (e *GroupEngine) HAUpdate(status seesaw.HAStatus) (bool, error) {
+	return false, e.Engine.HAGroupState(e.VRID, status.State)
+}
+
+// HAGroupState informs the underlying Engine of the current HAState for the
+// VRRP group with the given VRID.
+func (e *GroupEngine) HAGroupState(vrid uint8, state seesaw.HAState) error {
+	return e.Engine.HAGroupState(vrid, state)
+}
+
+// HealthyVservers returns the number of healthy vservers on the underlying
+// Engine.
+func (e *GroupEngine) HealthyVservers() (int, error) {
+	return e.Engine.HealthyVservers()
+}
+
 // DummyEngine implements the Engine interface for testing purposes.
 type DummyEngine struct {
 	Config *seesaw.HAConfig
@@ -117,3 +224,13 @@ func (e *DummyEngine) HAState(state seesaw.HAState) error {
 func (e *DummyEngine) HAUpdate(status seesaw.HAStatus) (bool, error) {
 	return false, nil
 }
+
+// HAGroupState does nothing.
+func (e *DummyEngine) HAGroupState(vrid uint8, state seesaw.HAState) error {
+	return nil
+}
+
+// HealthyVservers returns zero.
+func (e *DummyEngine) HealthyVservers() (int, error) {
+	return 0, nil
+}
@@ -19,6 +19,8 @@ package ha
 // This file contains the unit tests for the ha package.
 
 import (
+	"bytes"
+	"net"
 	"testing"
 	"time"
 
@@ -170,6 +172,83 @@ func TestPreempt(t *testing.T) {
 	}
 }
 
+func TestPreemptDelay(t *testing.T) {
+	node := newTestNode()
+	node.Preempt = true
+	node.PreemptDelay = time.Hour
+	preemptAdvert := vrrpTestAdvert
+	preemptAdvert.AdvertInt = 100
+	node.queueAdvertisement(&preemptAdvert)
+	node.runOnce()
+	if node.state() != seesaw.HABackup {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HABackup, node.state())
+	}
+
+	// An advertisement from a higher priority peer restarts the delay.
+	advert := preemptAdvert
+	advert.Priority = 255
+	node.queueAdvertisement(&advert)
+	node.runOnce()
+	if !node.preemptStart.IsZero() {
+		t.Errorf("Expected preemption delay to be reset, started at %v", node.preemptStart)
+	}
+
+	// So does a gap between advertisements that allow preemption.
+	node.queueAdvertisement(&preemptAdvert)
+	node.runOnce()
+	node.preemptStart = node.preemptStart.Add(-node.PreemptDelay)
+	node.lastPreemptAdvert = node.lastPreemptAdvert.Add(-time.Minute)
+	node.queueAdvertisement(&preemptAdvert)
+	node.runOnce()
+	if node.state() != seesaw.HABackup {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HABackup, node.state())
+	}
+
+	// Once the delay has expired, the node preempts the current master.
+	node.preemptStart = node.preemptStart.Add(-node.PreemptDelay)
+	node.queueAdvertisement(&preemptAdvert)
+	node.runOnce()
+	if node.state() != seesaw.HAMaster {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HAMaster, node.state())
+	}
+	if !node.preemptStart.IsZero() {
+		t.Errorf("Expected preemption delay to be reset on becoming master, started at %v", node.preemptStart)
+	}
+
+	// clean up
+	node.becomeBackup()
+}
+
+func TestPreemptDelayDisable(t *testing.T) {
+	node := newTestNode()
+	node.Preempt = true
+	node.PreemptDelay = time.Hour
+	preemptAdvert := vrrpTestAdvert
+	preemptAdvert.AdvertInt = 100
+	node.queueAdvertisement(&preemptAdvert)
+	node.runOnce()
+	if node.preemptStart.IsZero() {
+		t.Fatalf("Expected preemption delay to be started")
+	}
+	node.preemptStart = node.preemptStart.Add(-node.PreemptDelay)
+
+	// Disabling the node abandons the preemption delay, which starts again
+	// once the node returns to backup.
+	node.setState(seesaw.HADisabled)
+	if !node.preemptStart.IsZero() {
+		t.Errorf("Expected preemption delay to be reset on disable, started at %v", node.preemptStart)
+	}
+	node.setState(seesaw.HABackup)
+	node.queueAdvertisement(&preemptAdvert)
+	node.runOnce()
+	if node.state() != seesaw.HABackup {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HABackup, node.state())
+	}
+	if node.preemptStart.IsZero() {
+		t.Errorf("Expected preemption delay to be restarted")
+	}
+}
+
 func TestShutdown(t *testing.T) {
 	node := newTestNode()
 	advert := vrrpTestAdvert
@@ -184,6 +263,72 @@ func TestShutdown(t *testing.T) {
 	node.becomeBackup()
 }
 
+func TestSplitBrain(t *testing.T) {
+	node := newTestNode()
+	node.runOnce()
+	if node.state() != seesaw.HAMaster {
+		t.Fatalf("Expected state to be %v but was %v", seesaw.HAMaster, node.state())
+	}
+	// Keep split-brain detections current for the duration of the test.
+	node.masterDownInterval = time.Minute
+	if node.status().SplitBrain {
+		t.Errorf("Split-brain reported without a conflicting master")
+	}
+
+	// An advertisement from another master with the same priority.
+	advert := vrrpTestAdvert
+	advert.Priority = 100
+	node.queueAdvertisement(&advert)
+	node.runOnce()
+	if node.state() != seesaw.HAMaster {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HAMaster, node.state())
+	}
+	if !node.status().SplitBrain {
+		t.Errorf("Split-brain not reported after advertisement from another master")
+	}
+
+	// The split-brain is cleared once no conflicting advertisements have
+	// been received within the master down interval.
+	node.statusLock.Lock()
+	node.lastSplitBrainAdvert = time.Now().Add(-2 * time.Minute)
+	node.statusLock.Unlock()
+	if node.status().SplitBrain {
+		t.Errorf("Split-brain still reported after master down interval")
+	}
+
+	// clean up
+	node.becomeBackup()
+	if node.status().SplitBrain {
+		t.Errorf("Split-brain still reported after becoming backup")
+	}
+}
+
+func TestSplitBrainDemote(t *testing.T) {
+	node := newTestNode()
+	node.SplitBrainDemote = true
+	node.runOnce()
+	if node.state() != seesaw.HAMaster {
+		t.Fatalf("Expected state to be %v but was %v", seesaw.HAMaster, node.state())
+	}
+
+	// A lower priority master does not cause this node to yield.
+	advert := vrrpTestAdvert
+	advert.Priority = 50
+	node.queueAdvertisement(&advert)
+	node.runOnce()
+	if node.state() != seesaw.HAMaster {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HAMaster, node.state())
+	}
+
+	// An equal priority master does.
+	advert.Priority = 100
+	node.queueAdvertisement(&advert)
+	node.runOnce()
+	if node.state() != seesaw.HABackup {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HABackup, node.state())
+	}
+}
+
 func TestIPChecksum(t *testing.T) {
 	// test data from RFC1071
 	b := []byte{0x0, 0x1, 0xf2, 0x03, 0xf4, 0xf5, 0xf6, 0xf7}
@@ -201,3 +346,39 @@ func TestIPChecksum(t *testing.T) {
 		t.Errorf("Want checksum %x but was %x", want, chksum)
 	}
 }
+
+func TestAdvertisementAuth(t *testing.T) {
+	src, dst := net.ParseIP("192.168.10.2"), net.ParseIP("224.0.0.18")
+	payload := []byte{0x31, 0x1, 0x64, 0x0, 0x0, 0x32, 0x12, 0x34}
+	auth := authData([]byte("secret"), payload, src, dst)
+	c := &IPHAConn{secret: []byte("secret")}
+
+	tests := []struct {
+		desc    string
+		payload []byte
+		src     net.IP
+		ok      bool
+	}{
+		{"authenticated", append(append([]byte{}, payload...), auth...), src, true},
+		{"unauthenticated", payload, src, false},
+		{"wrong source", append(append([]byte{}, payload...), auth...), net.ParseIP("192.168.10.4"), false},
+		{"tampered", append([]byte{0x31, 0x1, 0xff, 0x0, 0x0, 0x32, 0x12, 0x34}, auth...), src, false},
+		{"wrong secret", append(append([]byte{}, payload...), authData([]byte("other"), payload, src, dst)...), src, false},
+	}
+	for _, test := range tests {
+		got, err := c.checkAuth(&packet{src: test.src, dst: dst, payload: test.payload})
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("checkAuth (%s) returned error %v", test.desc, err)
+			continue
+		}
+		if test.ok && !bytes.Equal(got, payload) {
+			t.Errorf("checkAuth (%s) returned payload %x, want %x", test.desc, got, payload)
+		}
+	}
+
+	// Without a secret the payload is returned unchanged.
+	c = &IPHAConn{}
+	if got, err := c.checkAuth(&packet{src: src, dst: dst, payload: payload}); err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("checkAuth without secret returned %x, %v", got, err)
+	}
+}
@@ -20,11 +20,17 @@ package ha
 This file contains the networking layer of the ha package. It sends and receives VRRP v3
 advertisements per RFC 5798.
 
+If a heartbeat secret is configured, an HMAC-SHA256 of each advertisement is appended to the
+VRRP payload, and advertisements without a valid HMAC are dropped. This is not part of RFC 5798,
+hence all nodes in the cluster must be configured with the same secret.
+
 TODO(angusc): Include a list of IPvX Addrs in the VRRP advertisements.
 */
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
 	"net"
@@ -131,16 +137,31 @@ func setsockopt(f *os.File, level, opt, value int) error {
 	return nil
 }
 
+// authDataSize is the number of bytes of authentication data that follow an
+// authenticated advertisement.
+const authDataSize = sha256.Size
+
 // IPHAConn implements the HAConn interface.
 type IPHAConn struct {
 	sendConn *net.IPConn
 	recvConn *net.IPConn
 	laddr    net.IP
 	raddr    net.IP
+	secret   []byte
 }
 
-// NewIPHAConn creates a new IPHAConn.
-func NewIPHAConn(laddr, raddr net.IP) (HAConn, error) {
+// NewIPHAConn creates a new IPHAConn. If iface is non-empty, advertisements are
+// only sent and received via the named interface, using its address as the
+// local address. If secret is non-empty, advertisements are authenticated
+// using the secret.
+func NewIPHAConn(laddr, raddr net.IP, iface string, secret []byte) (HAConn, error) {
+	if iface != "" {
+		var err error
+		if laddr, err = interfaceAddr(iface, raddr.To4() != nil); err != nil {
+			return nil, err
+		}
+	}
+
 	sendConn, err := IPConn(laddr, raddr)
 	if err != nil {
 		return nil, err
@@ -163,14 +184,62 @@ func NewIPHAConn(laddr, raddr net.IP) (HAConn, error) {
 		}
 	}
 
+	if iface != "" {
+		log.Infof("Using interface %s", iface)
+		if err := bindToDevice(sendConn, iface); err != nil {
+			return nil, err
+		}
+		if recvConn != sendConn {
+			if err := bindToDevice(recvConn, iface); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return &IPHAConn{
 		sendConn: sendConn,
 		recvConn: recvConn,
 		laddr:    laddr,
 		raddr:    raddr,
+		secret:   secret,
 	}, nil
 }
 
+// interfaceAddr returns the first IPv4 or IPv6 address for the named interface.
+func interfaceAddr(iface string, ipv4 bool) (net.IP, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err != nil {
+			continue
+		}
+		if (ip.To4() != nil) == ipv4 {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("ha.interfaceAddr(%q): No suitable address found", iface)
+}
+
+// bindToDevice restricts the given IPConn to the named network interface.
+func bindToDevice(c *net.IPConn, iface string) error {
+	f, err := c.File()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := syscall.BindToDevice(int(f.Fd()), iface); err != nil {
+		return fmt.Errorf("ha.bindToDevice(%q): %v", iface, err)
+	}
+	return nil
+}
+
 // ListenMulticastIPv4 creates a net.IPConn to receive multicast messages for the given group
 // address. laddr specifies which network interface to use when joining the group.
 func ListenMulticastIPv4(gaddr, laddr net.IP) (*net.IPConn, error) {
@@ -270,13 +339,20 @@ func (c *IPHAConn) receive() (*advertisement, error) {
 			}
 		}
 		return nil, err
-	} else if len(p.payload) != vrrpAdvertSize {
+	}
+
+	payload, err := c.checkAuth(p)
+	if err != nil {
+		log.Warningf("IPHAConn.receive: Dropping advertisement from %v: %v", p.src, err)
+		return nil, nil
+	}
+	if len(payload) != vrrpAdvertSize {
 		// Ignore
 		return nil, nil
 	}
 
 	advert := &advertisement{}
-	reader := bytes.NewReader(p.payload)
+	reader := bytes.NewReader(payload)
 	if err := binary.Read(reader, binary.BigEndian, advert); err != nil {
 		return nil, err
 	}
@@ -308,6 +384,32 @@ func (c *IPHAConn) receive() (*advertisement, error) {
 	return advert, nil
 }
 
+// authData returns the authentication data for the given VRRP payload, sent
+// from src to dst.
+func authData(secret, payload []byte, src, dst net.IP) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(src.To16())
+	mac.Write(dst.To16())
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// checkAuth verifies the authentication data for a received packet, if a
+// secret is configured, and returns the VRRP payload.
+func (c *IPHAConn) checkAuth(p *packet) ([]byte, error) {
+	if len(c.secret) == 0 {
+		return p.payload, nil
+	}
+	if len(p.payload) != vrrpAdvertSize+authDataSize {
+		return nil, fmt.Errorf("unauthenticated advertisement (length %d)", len(p.payload))
+	}
+	payload, auth := p.payload[:vrrpAdvertSize], p.payload[vrrpAdvertSize:]
+	if !hmac.Equal(auth, authData(c.secret, payload, p.src, p.dst)) {
+		return nil, fmt.Errorf("authentication failed")
+	}
+	return payload, nil
+}
+
 // packet encapsulates information about a received IP packet.
 type packet struct {
 	src     net.IP
@@ -317,9 +419,9 @@ type packet struct {
 }
 
 var (
-	// Up to 60 bytes for the IPv4 header + 8 bytes for the VRRP payload, rounded
-	// to the next power of 2.
-	recvBuffer = make([]byte, 96)
+	// Up to 60 bytes for the IPv4 header + 8 bytes for the VRRP payload + 32
+	// bytes of authentication data, rounded to the next power of 2.
+	recvBuffer = make([]byte, 128)
 
 	// Per RFC 3542 10240 bytes should "always be large enough".
 	oobBuffer = make([]byte, 10240)
@@ -432,6 +534,9 @@ func (c *IPHAConn) send(advert *advertisement, timeout time.Duration) error {
 	if err := binary.Write(buf, binary.BigEndian, advert); err != nil {
 		return err
 	}
+	if len(c.secret) > 0 {
+		buf.Write(authData(c.secret, buf.Bytes(), c.laddr, c.raddr))
+	}
 
 	if _, err := c.sendConn.WriteToIP(buf.Bytes(), &net.IPAddr{IP: c.raddr}); err != nil {
 		return err
@@ -0,0 +1,151 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ha
+
+// This file contains functions that track the health of a node's
+// dependencies, and reduce the priority that it advertises while any of them
+// are unhealthy.
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+
+	log "github.com/golang/glog"
+)
+
+// sysClassNet is the sysfs directory containing network interface state.
+var sysClassNet = "/sys/class/net"
+
+// trackHealth periodically checks the trackers for this node.
+func (n *Node) trackHealth() {
+	if len(n.Trackers) == 0 {
+		return
+	}
+	for _ = range time.Tick(n.TrackInterval) {
+		n.updateTrackers()
+	}
+}
+
+// updateTrackers checks each of the trackers for this node and updates the
+// node's effective priority.
+func (n *Node) updateTrackers() {
+	n.statusLock.RLock()
+	wasFailed := make(map[string]bool)
+	for _, name := range n.failedTrackers {
+		wasFailed[name] = true
+	}
+	n.statusLock.RUnlock()
+
+	penalty := 0
+	var failed []string
+	for _, t := range n.Trackers {
+		err := n.checkTracker(t)
+		switch {
+		case err != nil:
+			if !wasFailed[t.Name] {
+				log.Warningf("Tracker %q failed, reducing priority by %d: %v", t.Name, t.Weight, err)
+			}
+			failed = append(failed, t.Name)
+			penalty += int(t.Weight)
+		case wasFailed[t.Name]:
+			log.Infof("Tracker %q recovered, restoring priority by %d", t.Name, t.Weight)
+		}
+	}
+
+	n.statusLock.Lock()
+	changed := penalty != n.trackPenalty
+	n.trackPenalty = penalty
+	n.failedTrackers = failed
+	n.statusLock.Unlock()
+
+	if changed {
+		log.Infof("Effective priority is now %d (configured priority %d)", n.effectivePriority(), n.Priority)
+	}
+}
+
+// checkTracker returns an error if the given tracker is failing.
+func (n *Node) checkTracker(t seesaw.HATracker) error {
+	switch {
+	case t.Interface != "":
+		return checkInterface(t.Interface)
+
+	case t.Script != "":
+		ctx, cancel := context.WithTimeout(context.Background(), n.TrackInterval)
+		defer cancel()
+		if err := exec.CommandContext(ctx, "/bin/sh", "-c", t.Script).Run(); err != nil {
+			return fmt.Errorf("script %q failed: %v", t.Script, err)
+		}
+		return nil
+
+	default:
+		healthy, err := n.engine.HealthyVservers()
+		if err != nil {
+			return fmt.Errorf("failed to get healthy vservers: %v", err)
+		}
+		if healthy < t.Vservers {
+			return fmt.Errorf("%d vservers are healthy, want at least %d", healthy, t.Vservers)
+		}
+		return nil
+	}
+}
+
+// checkInterface returns an error if the named interface is administratively
+// down, or if the kernel reports that its link is down.
+func checkInterface(name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return fmt.Errorf("interface %s is down", name)
+	}
+	state, err := ioutil.ReadFile(filepath.Join(sysClassNet, name, "operstate"))
+	if err != nil {
+		// The link state is not available - rely on the interface flags.
+		return nil
+	}
+	switch s := strings.TrimSpace(string(state)); s {
+	case "up", "unknown":
+		return nil
+	default:
+		return fmt.Errorf("interface %s link is %s", name, s)
+	}
+}
+
+// effectivePriority returns the priority advertised by this node, which is
+// the configured priority less the weights of any failing trackers. The
+// effective priority is never less than one, since a priority of zero
+// indicates that the master is shutting down.
+func (n *Node) effectivePriority() uint8 {
+	n.statusLock.RLock()
+	defer n.statusLock.RUnlock()
+	return effectivePriority(n.Priority, n.trackPenalty)
+}
+
+func effectivePriority(priority uint8, penalty int) uint8 {
+	p := int(priority) - penalty
+	if p < 1 {
+		p = 1
+	}
+	return uint8(p)
+}
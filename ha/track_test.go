@@ -0,0 +1,137 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+// trackEngine is a DummyEngine that reports a fixed number of healthy vservers.
+type trackEngine struct {
+	DummyEngine
+	healthy int
+}
+
+func (e *trackEngine) HealthyVservers() (int, error) {
+	return e.healthy, nil
+}
+
+func newTrackNode(engine *trackEngine, trackers []seesaw.HATracker) *Node {
+	nc := NodeConfig{
+		HAConfig: seesaw.HAConfig{
+			Enabled:  true,
+			Priority: 100,
+			VRID:     1,
+			Trackers: trackers,
+		},
+		MasterAdvertInterval: 60 * time.Second,
+		TrackInterval:        5 * time.Second,
+	}
+	n := NewNode(nc, &dummyHAConn{}, engine)
+	n.masterDownInterval = 1
+	return n
+}
+
+func TestEffectivePriority(t *testing.T) {
+	tests := []struct {
+		priority uint8
+		penalty  int
+		want     uint8
+	}{
+		{100, 0, 100},
+		{100, 30, 70},
+		{100, 99, 1},
+		{100, 100, 1},
+		{100, 500, 1},
+	}
+	for _, test := range tests {
+		if got := effectivePriority(test.priority, test.penalty); got != test.want {
+			t.Errorf("effectivePriority(%d, %d) = %d, want %d", test.priority, test.penalty, got, test.want)
+		}
+	}
+}
+
+func TestTrackers(t *testing.T) {
+	engine := &trackEngine{healthy: 2}
+	node := newTrackNode(engine, []seesaw.HATracker{
+		{Name: "lo", Interface: "lo", Weight: 10},
+		{Name: "missing", Interface: "seesaw-missing0", Weight: 20},
+		{Name: "false", Script: "exit 1", Weight: 30},
+		{Name: "true", Script: "true", Weight: 40},
+		{Name: "vservers", Vservers: 2, Weight: 5},
+	})
+
+	node.updateTrackers()
+	status := node.status()
+	if got, want := status.EffectivePriority, uint8(50); got != want {
+		t.Errorf("Effective priority is %d, want %d", got, want)
+	}
+	if got, want := status.Priority, uint8(100); got != want {
+		t.Errorf("Priority is %d, want %d", got, want)
+	}
+	want := []string{"missing", "false"}
+	if len(status.FailedTrackers) != len(want) {
+		t.Fatalf("Failed trackers are %q, want %q", status.FailedTrackers, want)
+	}
+	for i := range want {
+		if status.FailedTrackers[i] != want[i] {
+			t.Errorf("Failed tracker %d is %q, want %q", i, status.FailedTrackers[i], want[i])
+		}
+	}
+	if got, want := node.newAdvertisement().Priority, uint8(50); got != want {
+		t.Errorf("Advertised priority is %d, want %d", got, want)
+	}
+
+	// Losing a healthy vserver fails the vservers tracker.
+	engine.healthy = 1
+	node.updateTrackers()
+	if got, want := node.status().EffectivePriority, uint8(45); got != want {
+		t.Errorf("Effective priority is %d, want %d", got, want)
+	}
+}
+
+func TestTrackerDemotesMaster(t *testing.T) {
+	engine := &trackEngine{healthy: 0}
+	node := newTrackNode(engine, []seesaw.HATracker{
+		{Name: "vservers", Vservers: 1, Weight: 50},
+	})
+	engine.healthy = 1
+	node.updateTrackers()
+	node.runOnce()
+	if node.state() != seesaw.HAMaster {
+		t.Fatalf("Expected state to be %v but was %v", seesaw.HAMaster, node.state())
+	}
+
+	// A peer with priority 80 does not take over from a healthy master...
+	advert := vrrpTestAdvert
+	advert.Priority = 80
+	node.queueAdvertisement(&advert)
+	node.runOnce()
+	if node.state() != seesaw.HAMaster {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HAMaster, node.state())
+	}
+
+	// ... but does once the master's tracker fails.
+	engine.healthy = 0
+	node.updateTrackers()
+	node.queueAdvertisement(&advert)
+	node.runOnce()
+	if node.state() != seesaw.HABackup {
+		t.Errorf("Expected state to be %v but was %v", seesaw.HABackup, node.state())
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// This file contains the clock that is used to schedule healthchecks, which
+// may be replaced in order to run the scheduler without real sleeps.
+
+import (
+	"time"
+)
+
+// deterministicSeed is the seed used to derive the jitter for each
+// healthcheck when deterministic scheduling is enabled.
+const deterministicSeed = 1
+
+// Clock provides the current time and the timers used by the healthcheck
+// scheduler.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at regular intervals, as per time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock is a Clock that uses the system time.
+type realClock struct{}
+
+// Now returns the current system time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns a channel that receives the current time once the given
+// duration has elapsed.
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTicker returns a Ticker that ticks at the given interval.
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTicker is a Ticker that wraps a time.Ticker.
+type realTicker struct {
+	*time.Ticker
+}
+
+// C returns the channel on which ticks are delivered.
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
@@ -0,0 +1,230 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only advances when instructed to.
+type fakeClock struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a timer or ticker that is driven by a fakeClock.
+type fakeTimer struct {
+	clock  *fakeClock
+	when   time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Unix(1500000000, 0)}
+	c.cond = sync.NewCond(&c.lock)
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) newTimer(d, period time.Duration) *fakeTimer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := &fakeTimer{
+		clock:  c,
+		when:   c.now.Add(d),
+		period: period,
+		c:      make(chan time.Time, 1),
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.newTimer(d, 0).c
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	return c.newTimer(d, d)
+}
+
+// advance moves the clock forward by the given duration, firing any timers
+// that expire. As with time.Ticker, ticks are dropped for slow receivers.
+func (c *fakeClock) advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		for !t.when.After(c.now) {
+			select {
+			case t.c <- t.when:
+			default:
+			}
+			if t.period == 0 {
+				break
+			}
+			t.when = t.when.Add(t.period)
+		}
+		if t.when.After(c.now) {
+			timers = append(timers, t)
+		}
+	}
+	c.timers = timers
+}
+
+// waitForTimers blocks until at least n timers are pending.
+func (c *fakeClock) waitForTimers(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() {
+	c := t.clock
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			break
+		}
+	}
+}
+
+// flipChecker alternates between succeeding and failing.
+type flipChecker struct {
+	checks int32
+}
+
+func (hc *flipChecker) String() string {
+	return "FLIP"
+}
+
+func (hc *flipChecker) Check(timeout time.Duration) *Result {
+	n := atomic.AddInt32(&hc.checks, 1)
+	return &Result{Success: n%2 == 1}
+}
+
+func TestCheckRunClock(t *testing.T) {
+	clock := newFakeClock()
+	notify := make(chan *Notification, 10)
+	hc := NewCheck(notify)
+	hc.clock = clock
+	go hc.Run(nil)
+	defer hc.Stop()
+
+	config := NewConfig(1, &flipChecker{})
+	config.Interval = 10 * time.Second
+	hc.Update(config)
+
+	start := clock.Now()
+	for i, want := range []State{StateHealthy, StateUnhealthy, StateHealthy} {
+		if i > 0 {
+			clock.advance(config.Interval)
+		}
+		select {
+		case n := <-notify:
+			if n.State != want {
+				t.Errorf("Check %d got state %v, want %v", i, n.State, want)
+			}
+			if got, want := n.LastCheck, start.Add(time.Duration(i)*config.Interval); !got.Equal(want) {
+				t.Errorf("Check %d got last check %v, want %v", i, got, want)
+			}
+		case <-time.After(timeout):
+			t.Fatalf("Check %d did not produce a notification", i)
+		}
+	}
+}
+
+func TestServerDeterministic(t *testing.T) {
+	const checks = 5
+	interval := 10 * time.Second
+
+	// The expected order is determined by the jitter for each check.
+	delays := make(map[Id]time.Duration)
+	var want []Id
+	for id := Id(1); id <= checks; id++ {
+		r := rand.New(rand.NewSource(deterministicSeed + int64(id)))
+		delays[id] = time.Duration(r.Int63n(int64(interval)))
+		want = append(want, id)
+	}
+	sort.Slice(want, func(i, j int) bool { return delays[want[i]] < delays[want[j]] })
+
+	for run := 0; run < 2; run++ {
+		clock := newFakeClock()
+		cfg := DefaultServerConfig()
+		cfg.Clock = clock
+		cfg.Deterministic = true
+		s := NewServer(&cfg)
+		go s.manager()
+
+		configs := make(map[Id]*Config)
+		for id := Id(1); id <= checks; id++ {
+			config := NewConfig(id, &fakeChecker{succeed: true})
+			config.Interval = interval
+			configs[id] = config
+		}
+		s.configs <- configs
+
+		// Wait for the manager's tickers and the initial delay for
+		// each healthcheck.
+		clock.waitForTimers(2 + checks)
+
+		var got []Id
+		var elapsed time.Duration
+		for _, id := range want {
+			clock.advance(delays[id] - elapsed)
+			elapsed = delays[id]
+			select {
+			case n := <-s.notify:
+				got = append(got, n.Id)
+			case <-time.After(timeout):
+				t.Fatalf("Run %d: no notification for healthcheck %d", run, id)
+			}
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("Run %d: got healthcheck order %v, want %v", run, got, want)
+				break
+			}
+		}
+
+		s.lock.Lock()
+		for _, hc := range s.healthchecks {
+			hc.Stop()
+		}
+		s.lock.Unlock()
+	}
+}
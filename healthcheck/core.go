@@ -23,10 +23,12 @@ import (
 	"math/rand"
 	"net"
 	"net/rpc"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/wy2745/seesaw/common/ipc"
+	"github.com/wy2745/seesaw/common/metrics"
 	"github.com/wy2745/seesaw/common/seesaw"
 
 	log "github.com/golang/glog"
@@ -34,13 +36,23 @@ import (
 
 const engineTimeout = 10 * time.Second
 
+// maxBackoffFactor determines the maximum interval for a check with backoff
+// enabled, relative to its configured interval, if no maximum is given.
+const maxBackoffFactor = 8
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 
 	gob.Register(&DNSChecker{})
+	gob.Register(&ExecChecker{})
+	gob.Register(&GRPCChecker{})
 	gob.Register(&HTTPChecker{})
+	gob.Register(&MySQLChecker{})
+	gob.Register(&NTPChecker{})
 	gob.Register(&PingChecker{})
 	gob.Register(&RADIUSChecker{})
+	gob.Register(&RedisChecker{})
+	gob.Register(&SMTPChecker{})
 	gob.Register(&TCPChecker{})
 	gob.Register(&UDPChecker{})
 }
@@ -85,6 +97,19 @@ type Target struct {
 	Mode  seesaw.HealthcheckMode
 	Port  int
 	Proto seesaw.IPProto
+
+	// Hostname is the name of the backend that is being healthchecked.
+	Hostname string
+
+	// SourceIP and SourceInterface specify the local IP address and
+	// network interface that healthchecks are sent from. The source IP
+	// address must be configured locally.
+	SourceIP        net.IP
+	SourceInterface string
+
+	// ConnectTimeout limits the time taken to establish a connection to
+	// the target. If zero, only the overall healthcheck timeout applies.
+	ConnectTimeout time.Duration
 }
 
 // String returns the string representation of a healthcheck target.
@@ -93,7 +118,33 @@ func (t Target) String() string {
 	if t.Mode == seesaw.HCModeDSR {
 		via = fmt.Sprintf(" (via %s mark %d)", t.Host, t.Mark)
 	}
-	return fmt.Sprintf("%s %s%s", t.addr(), t.Mode, via)
+	var from string
+	if t.SourceIP != nil {
+		from = fmt.Sprintf(" from %s", t.SourceIP)
+	}
+	if t.SourceInterface != "" {
+		from = fmt.Sprintf("%s dev %s", from, t.SourceInterface)
+	}
+	return fmt.Sprintf("%s %s%s%s", t.addr(), t.Mode, via, from)
+}
+
+// target returns the healthcheck target.
+func (t *Target) target() *Target {
+	return t
+}
+
+// connectTimeout returns the timeout for establishing a connection, given
+// the overall healthcheck timeout.
+func (t *Target) connectTimeout(timeout time.Duration) time.Duration {
+	if t.ConnectTimeout > 0 && (timeout <= 0 || t.ConnectTimeout < timeout) {
+		return t.ConnectTimeout
+	}
+	return timeout
+}
+
+// source returns the source address and interface for the healthcheck.
+func (t *Target) source() source {
+	return source{ip: t.SourceIP, iface: t.SourceInterface}
 }
 
 // addr returns the address string for the healthcheck target.
@@ -134,6 +185,17 @@ type Result struct {
 	Success bool
 	time.Duration
 	Err error
+
+	// Weighted indicates that the checker reported a Weight, which is the
+	// percentage (0 to 100) of the backend weight that should be used.
+	Weighted bool
+	Weight   int
+
+	// CertExpiring indicates that the certificate presented by the backend
+	// has expired or expires within the configured warning window, at
+	// CertExpiry.
+	CertExpiring bool
+	CertExpiry   time.Time
 }
 
 // String returns the string representation of a healthcheck result.
@@ -148,7 +210,7 @@ func (r *Result) String() string {
 func complete(start time.Time, msg string, success bool, err error) *Result {
 	// TODO(jsing): Make this clock skew safe.
 	duration := time.Since(start)
-	return &Result{msg, success, duration, err}
+	return &Result{Message: msg, Success: success, Duration: duration, Err: err}
 }
 
 // Notification stores a status notification for a healthcheck.
@@ -174,20 +236,122 @@ type Checks struct {
 }
 
 // Config contains the configuration for a healthcheck.
+//
+// A healthy check becomes unhealthy after FallRetries consecutive failures
+// and an unhealthy check becomes healthy after RiseRetries consecutive
+// successes. If FallRetries is zero, Retries + 1 consecutive failures are
+// required. If RiseRetries is zero, a single success is sufficient. While
+// a state transition is pending, checks are made every RetryDelay rather
+// than at the configured interval, if a non-zero RetryDelay is given.
+//
+// If Backoff is enabled, the interval between checks is doubled for each
+// failure once the check is unhealthy, up to MaxInterval. The interval is
+// reset on the first successful check.
+//
+// If a non-zero SlowThreshold is given, a warning is logged for each
+// successful check that takes longer than the threshold to complete.
+//
+// Vserver, Backend and Name identify the healthcheck when its results are
+// exported as metrics.
 type Config struct {
 	Id
-	Interval time.Duration
-	Timeout  time.Duration
-	Retries  int
+	Vserver       string
+	Backend       string
+	Name          string
+	Interval      time.Duration
+	Timeout       time.Duration
+	Retries       int
+	RiseRetries   int
+	FallRetries   int
+	RiseTime      time.Duration
+	FallTime      time.Duration
+	RetryDelay    time.Duration
+	Backoff       bool
+	MaxInterval   time.Duration
+	SlowThreshold time.Duration
 	Checker
 }
 
+// fallThreshold returns the number of consecutive failures that are needed
+// for a healthy check to become unhealthy.
+func (c *Config) fallThreshold() uint64 {
+	if c.FallRetries > 0 {
+		return uint64(c.FallRetries)
+	}
+	return uint64(c.Retries) + 1
+}
+
+// riseThreshold returns the number of consecutive successes that are needed
+// for an unhealthy check to become healthy.
+func (c *Config) riseThreshold() uint64 {
+	if c.RiseRetries > 0 {
+		return uint64(c.RiseRetries)
+	}
+	return 1
+}
+
+// fallen returns true if a healthy check should become unhealthy, given the
+// number of consecutive failures and how long it has been failing for.
+func (c *Config) fallen(failed uint64, failing time.Duration) bool {
+	return failed >= c.fallThreshold() && failing >= c.FallTime
+}
+
+// risen returns true if an unhealthy check should become healthy, given the
+// number of consecutive successes and how long it has been succeeding for.
+func (c *Config) risen(succeeded uint64, succeeding time.Duration) bool {
+	return succeeded >= c.riseThreshold() && succeeding >= c.RiseTime
+}
+
+// slow returns true if the given result is for a successful check that took
+// longer than the configured slow threshold.
+func (c *Config) slow(result *Result) bool {
+	return result.Success && c.SlowThreshold > 0 && result.Duration > c.SlowThreshold
+}
+
+// targeter is implemented by checkers that have a healthcheck target.
+type targeter interface {
+	target() *Target
+}
+
+// setDefaults derives an unset interval or timeout from the other. If only
+// the interval is set, the timeout defaults to half of the interval and if
+// only the timeout is set, the interval defaults to twice the timeout.
+func (c *Config) setDefaults() {
+	switch {
+	case c.Interval > 0 && c.Timeout <= 0:
+		c.Timeout = c.Interval / 2
+	case c.Interval <= 0 && c.Timeout > 0:
+		c.Interval = 2 * c.Timeout
+	}
+}
+
+// Validate checks the timeouts for a healthcheck configuration. The timeout
+// must be less than the interval, otherwise checks overlap with the next
+// check that is scheduled. The connect timeout must not exceed the timeout.
+func (c *Config) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval %v must be positive", c.Interval)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout %v must be positive", c.Timeout)
+	}
+	if c.Timeout >= c.Interval {
+		return fmt.Errorf("timeout %v must be less than interval %v", c.Timeout, c.Interval)
+	}
+	if t, ok := c.Checker.(targeter); ok {
+		if ct := t.target().ConnectTimeout; ct < 0 || ct > c.Timeout {
+			return fmt.Errorf("connect timeout %v must be between 0 and timeout %v", ct, c.Timeout)
+		}
+	}
+	return nil
+}
+
 // NewConfig returns an initialised Config.
 func NewConfig(id Id, checker Checker) *Config {
 	return &Config{
 		Id:       id,
 		Interval: 5 * time.Second,
-		Timeout:  30 * time.Second,
+		Timeout:  2500 * time.Millisecond,
 		Retries:  0,
 		Checker:  checker,
 	}
@@ -196,11 +360,16 @@ func NewConfig(id Id, checker Checker) *Config {
 // Status represents the current status of a healthcheck instance.
 type Status struct {
 	LastCheck time.Time
-	Duration  time.Duration
+	Latency   time.Duration // Duration of the most recent check.
 	Failures  uint64
 	Successes uint64
 	State
 	Message string
+
+	// Weighted and Weight contain the weight reported by the most recent
+	// successful check, if any.
+	Weighted bool
+	Weight   int
 }
 
 // Check represents a healthcheck instance.
@@ -209,12 +378,20 @@ type Check struct {
 
 	lock      sync.RWMutex
 	blocking  bool
+	jitter    bool
+	rand      *rand.Rand // Source for jitter, the global source if nil.
+	clock     Clock
+	pool      *workerPool
 	start     time.Time
 	failed    uint64
+	succeeded uint64
 	failures  uint64
 	successes uint64
 	state     State
+	since     time.Time // Start of the current run of consecutive results.
 	result    *Result
+	weighted  bool
+	weight    int
 
 	update chan Config
 	notify chan<- *Notification
@@ -225,6 +402,7 @@ type Check struct {
 func NewCheck(notify chan<- *Notification) *Check {
 	return &Check{
 		state:  StateUnknown,
+		clock:  realClock{},
 		notify: notify,
 		update: make(chan Config, 1),
 		quit:   make(chan bool, 1),
@@ -240,9 +418,11 @@ func (hc *Check) Status() Status {
 		Failures:  hc.failures,
 		Successes: hc.successes,
 		State:     hc.state,
+		Weighted:  hc.weighted,
+		Weight:    hc.weight,
 	}
 	if hc.result != nil {
-		status.Duration = hc.result.Duration
+		status.Latency = hc.result.Duration
 		status.Message = hc.result.String()
 	}
 	return status
@@ -270,10 +450,31 @@ func (hc *Check) Run(start <-chan time.Time) {
 	if start != nil {
 		<-start
 	}
+
+	// Offset the first healthcheck by a random fraction of the interval,
+	// so that healthchecks started together are spread over the interval.
+	if delay := hc.jitterDelay(); delay > 0 {
+		select {
+		case <-hc.clock.After(delay):
+		case <-hc.quit:
+			return
+		}
+	}
 	log.Infof("Starting healthchecker for %d (%s)", hc.Id, hc)
 
-	ticker := time.NewTicker(hc.Interval)
-	hc.healthcheck()
+	interval := hc.Interval
+	ticker := hc.clock.NewTicker(interval)
+	var retry <-chan time.Time
+	healthcheck := func() {
+		retry = hc.retryTimer(hc.healthcheck())
+		if next := hc.nextInterval(interval); next != interval {
+			log.Infof("%d: Checking every %v", hc.Id, next)
+			ticker.Stop()
+			interval = next
+			ticker = hc.clock.NewTicker(interval)
+		}
+	}
+	healthcheck()
 	for {
 		select {
 		case <-hc.quit:
@@ -282,27 +483,64 @@ func (hc *Check) Run(start <-chan time.Time) {
 			return
 
 		case config := <-hc.update:
-			if hc.Interval != config.Interval {
+			if hc.Interval != config.Interval || (hc.Backoff && !config.Backoff) {
 				ticker.Stop()
 				if start != nil {
 					<-start
 				}
-				ticker = time.NewTicker(config.Interval)
+				interval = config.Interval
+				ticker = hc.clock.NewTicker(interval)
 			}
 			hc.Config = config
 
-		case <-ticker.C:
-			hc.healthcheck()
+		case <-ticker.C():
+			healthcheck()
+
+		case <-retry:
+			healthcheck()
 		}
 	}
 }
 
-// healthcheck executes the given checker.
-func (hc *Check) healthcheck() {
+// nextInterval returns the interval at which the next check should be made.
+// If backoff is enabled, the interval is doubled for each failure while the
+// check is unhealthy, up to the maximum interval. Otherwise the configured
+// interval is returned.
+func (hc *Check) nextInterval(current time.Duration) time.Duration {
+	if !hc.Backoff || hc.state != StateUnhealthy || hc.failed == 0 {
+		return hc.Interval
+	}
+	max := hc.MaxInterval
+	if max <= 0 {
+		max = maxBackoffFactor * hc.Interval
+	}
+	next := 2 * current
+	if next > max {
+		next = max
+	}
+	if next < hc.Interval {
+		next = hc.Interval
+	}
+	return next
+}
+
+// retryTimer returns a channel that fires after the configured retry delay,
+// if a state transition is pending. Otherwise a nil channel is returned.
+func (hc *Check) retryTimer(pending bool) <-chan time.Time {
+	if !pending || hc.RetryDelay <= 0 {
+		return nil
+	}
+	return hc.clock.After(hc.RetryDelay)
+}
+
+// healthcheck executes the given checker. It returns true if the result
+// differs from the current state, but the state transition is pending until
+// further consecutive results are obtained.
+func (hc *Check) healthcheck() bool {
 	if hc.Checker == nil {
-		return
+		return false
 	}
-	start := time.Now()
+	start := hc.clock.Now()
 	result := hc.execute()
 
 	status := "SUCCESS"
@@ -310,35 +548,59 @@ func (hc *Check) healthcheck() {
 		status = "FAILURE"
 	}
 	log.Infof("%d: (%s) %s: %v", hc.Id, hc, status, result)
+	if hc.Config.slow(result) {
+		log.Warningf("%d: (%s) Slow healthcheck - took %v, threshold is %v",
+			hc.Id, hc, result.Duration, hc.SlowThreshold)
+	}
+	if result.CertExpiring {
+		log.Warningf("%d: (%s) Certificate expires at %v", hc.Id, hc, result.CertExpiry)
+	}
 
 	hc.lock.Lock()
 
 	hc.start = start
 	hc.result = result
 
+	reweight := false
 	var state State
 	if result.Success {
+		reweight = hc.weighted != result.Weighted || hc.weight != result.Weight
+		hc.weighted, hc.weight = result.Weighted, result.Weight
 		state = StateHealthy
+		if hc.succeeded == 0 {
+			hc.since = start
+		}
 		hc.failed = 0
+		hc.succeeded++
 		hc.successes++
 	} else {
+		if hc.failed == 0 {
+			hc.since = start
+		}
 		hc.failed++
+		hc.succeeded = 0
 		hc.failures++
 		state = StateUnhealthy
 	}
 
-	if hc.state == StateHealthy && hc.failed > 0 && hc.failed <= uint64(hc.Config.Retries) {
-		log.Infof("%d: Failure %d - retrying...", hc.Id, hc.failed)
-		state = StateHealthy
+	pending := false
+	switch {
+	case hc.state == StateHealthy && hc.failed > 0 && !hc.Config.fallen(hc.failed, start.Sub(hc.since)):
+		log.Infof("%d: Failure %d after %v - retrying...", hc.Id, hc.failed, start.Sub(hc.since))
+		state, pending = StateHealthy, true
+	case hc.state == StateUnhealthy && hc.succeeded > 0 && !hc.Config.risen(hc.succeeded, start.Sub(hc.since)):
+		log.Infof("%d: Success %d after %v - retrying...", hc.Id, hc.succeeded, start.Sub(hc.since))
+		state, pending = StateUnhealthy, true
 	}
 	transition := (hc.state != state)
 	hc.state = state
 
 	hc.lock.Unlock()
 
-	if transition {
+	if transition || (reweight && state == StateHealthy) {
 		hc.Notify()
 	}
+	return pending
 }
 
 // Notify generates a healthcheck notification for this checker.
@@ -350,19 +612,31 @@ func (hc *Check) Notify() {
 }
 
 // execute invokes the given healthcheck checker with the configured timeout.
+// If a worker pool is in use, the checker is invoked by the next available
+// worker, with a warning being logged if the check cannot be scheduled within
+// the healthcheck interval. The timeout only starts once a worker has been
+// obtained.
 func (hc *Check) execute() *Result {
 	ch := make(chan *Result, 1)
 	checker := hc.Checker
-	go func() {
+	timeout := hc.Timeout
+	probe := func() {
 		// TODO(jsing): Determine a way to ensure that this go routine
 		// does not linger.
-		ch <- checker.Check(hc.Timeout)
-	}()
+		ch <- checker.Check(timeout)
+	}
+	if hc.pool == nil {
+		go probe()
+	} else if !hc.pool.submit(probe, hc.Interval) {
+		log.Warningf("%d: (%s) Healthcheck could not be scheduled within %v - all %d workers are busy",
+			hc.Id, hc, hc.Interval, hc.pool.workers)
+		hc.pool.submit(probe, 0)
+	}
 	select {
 	case result := <-ch:
 		return result
-	case <-time.After(hc.Timeout):
-		return &Result{"Timed out", false, hc.Timeout, nil}
+	case <-hc.clock.After(hc.Timeout):
+		return &Result{Message: "Timed out", Success: false, Duration: hc.Timeout}
 	}
 }
 
@@ -374,6 +648,22 @@ func (hc *Check) Stop() {
 	}
 }
 
+// Jitter enables or disables jittering of the initial healthcheck.
+func (hc *Check) Jitter(jitter bool) {
+	hc.jitter = jitter
+}
+
+// jitterDelay returns the delay before the initial healthcheck.
+func (hc *Check) jitterDelay() time.Duration {
+	if !hc.jitter || hc.Interval <= 0 {
+		return 0
+	}
+	if hc.rand != nil {
+		return time.Duration(hc.rand.Int63n(int64(hc.Interval)))
+	}
+	return time.Duration(rand.Int63n(int64(hc.Interval)))
+}
+
 // Blocking enables or disables blocking updates for a healthcheck.
 func (hc *Check) Blocking(block bool) {
 	len := 0
@@ -386,26 +676,42 @@ func (hc *Check) Blocking(block bool) {
 
 // Update queues a healthcheck configuration update for processing.
 func (hc *Check) Update(config *Config) {
+	c := *config
+	c.setDefaults()
+	if err := c.Validate(); err != nil {
+		log.Warningf("%d: (%s) Invalid healthcheck configuration: %v", c.Id, c.Checker, err)
+	}
 	if hc.blocking {
-		hc.update <- *config
+		hc.update <- c
 		return
 	}
 	select {
-	case hc.update <- *config:
+	case hc.update <- c:
 	default:
 		log.Warningf("Unable to update %d (%s), last update still queued", hc.Id, hc)
 	}
 }
 
 // ServerConfig specifies the configuration for a healthcheck server.
+//
+// If Deterministic is enabled, healthchecks are started in order of ID
+// without being staggered, with the jitter for each healthcheck being
+// derived from a fixed seed. Together with a Clock that is controlled by
+// the caller, this allows healthchecks to be scheduled reproducibly in
+// tests.
 type ServerConfig struct {
 	BatchDelay     time.Duration
 	BatchSize      int
 	ChannelSize    int
 	EngineSocket   string
+	Jitter         bool
 	MaxFailures    int
+	MetricsAddress string
 	NotifyInterval time.Duration
 	RetryDelay     time.Duration
+	Workers        int   // Number of healthcheck workers, unbounded if zero.
+	Clock          Clock // Clock used for scheduling, the system clock if nil.
+	Deterministic  bool
 }
 
 var defaultServerConfig = ServerConfig{
@@ -413,6 +719,7 @@ var defaultServerConfig = ServerConfig{
 	BatchSize:      100,
 	ChannelSize:    1000,
 	EngineSocket:   seesaw.EngineSocket,
+	Jitter:         true,
 	MaxFailures:    10,
 	NotifyInterval: 15 * time.Second,
 	RetryDelay:     2 * time.Second,
@@ -426,8 +733,12 @@ func DefaultServerConfig() ServerConfig {
 // Server contains the data needed to run a healthcheck server.
 type Server struct {
 	config *ServerConfig
+	clock  Clock
 
+	lock         sync.RWMutex
 	healthchecks map[Id]*Check
+	current      map[Id]*Config
+	pool         *workerPool
 	configs      chan map[Id]*Config
 	notify       chan *Notification
 	batch        []*Notification
@@ -441,10 +752,20 @@ func NewServer(cfg *ServerConfig) *Server {
 		defaultCfg := DefaultServerConfig()
 		cfg = &defaultCfg
 	}
+	var pool *workerPool
+	if cfg.Workers > 0 {
+		pool = newWorkerPool(cfg.Workers)
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
 	return &Server{
 		config: cfg,
+		clock:  clock,
 
 		healthchecks: make(map[Id]*Check),
+		pool:         pool,
 		notify:       make(chan *Notification, cfg.ChannelSize),
 		configs:      make(chan map[Id]*Config),
 		batch:        make([]*Notification, 0, cfg.BatchSize),
@@ -466,6 +787,9 @@ func (s *Server) Run() {
 	go s.updater()
 	go s.notifier()
 	go s.manager()
+	if s.config.MetricsAddress != "" {
+		go metrics.Serve(s.config.MetricsAddress, s.exportMetrics)
+	}
 
 	<-s.quit
 }
@@ -513,11 +837,12 @@ func (s *Server) updater() {
 // stop and remove deleted healthchecks, spawn new healthchecks and provide
 // the current configurations to each of the running healthchecks.
 func (s *Server) manager() {
-	checkTicker := time.NewTicker(50 * time.Millisecond)
-	notifyTicker := time.NewTicker(s.config.NotifyInterval)
+	checkTicker := s.clock.NewTicker(50 * time.Millisecond)
+	notifyTicker := s.clock.NewTicker(s.config.NotifyInterval)
 	for {
 		select {
 		case configs := <-s.configs:
+			s.lock.Lock()
 
 			// Remove healthchecks that have been deleted.
 			for id, hc := range s.healthchecks {
@@ -528,11 +853,19 @@ func (s *Server) manager() {
 			}
 
 			// Spawn new healthchecks.
-			for id := range configs {
+			for _, id := range sortedIds(configs) {
 				if s.healthchecks[id] == nil {
 					hc := NewCheck(s.notify)
+					hc.Jitter(s.config.Jitter)
+					hc.clock = s.clock
+					hc.pool = s.pool
 					s.healthchecks[id] = hc
-					go hc.Run(checkTicker.C)
+					if s.config.Deterministic {
+						hc.rand = rand.New(rand.NewSource(deterministicSeed + int64(id)))
+						go hc.Run(nil)
+					} else {
+						go hc.Run(checkTicker.C())
+					}
 				}
 			}
 
@@ -540,7 +873,9 @@ func (s *Server) manager() {
 			for id, hc := range s.healthchecks {
 				hc.Update(configs[id])
 			}
-		case <-notifyTicker.C:
+			s.current = configs
+			s.lock.Unlock()
+		case <-notifyTicker.C():
 			// Send status notifications for all healthchecks.
 			for _, hc := range s.healthchecks {
 				hc.Notify()
@@ -549,6 +884,17 @@ func (s *Server) manager() {
 	}
 }
 
+// sortedIds returns the IDs of the given healthcheck configurations in
+// ascending order.
+func sortedIds(configs map[Id]*Config) []Id {
+	ids := make([]Id, 0, len(configs))
+	for id := range configs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
 // notifier batches healthcheck notifications and sends them to the Seesaw
 // Engine.
 func (s *Server) notifier() {
@@ -561,7 +907,7 @@ func (s *Server) notifier() {
 			// Collect until BatchDelay passes, or BatchSize are queued.
 			switch len(s.batch) {
 			case 1:
-				timer = time.After(s.config.BatchDelay)
+				timer = s.clock.After(s.config.BatchDelay)
 			case s.config.BatchSize:
 				err = s.send()
 				timer = nil
@@ -19,6 +19,7 @@ package healthcheck
 // This file contains helper routines for dialing connections.
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -62,10 +63,81 @@ func sockaddrToString(sa syscall.Sockaddr) string {
 	}
 }
 
+// source specifies the local IP address and network interface that healthcheck
+// traffic is sent from. If either is unset the system default is used.
+type source struct {
+	ip    net.IP
+	iface string
+}
+
+// validate ensures that the source IP address is configured locally, on the
+// source interface if one is specified, and that it is of the same address
+// family as the target IP address.
+func (s source) validate(target net.IP) error {
+	if s.ip != nil && (s.ip.To4() == nil) != (target.To4() == nil) {
+		return fmt.Errorf("source IP %v and target IP %v are different address families", s.ip, target)
+	}
+	var addrs []net.Addr
+	var err error
+	if s.iface != "" {
+		iface, ierr := net.InterfaceByName(s.iface)
+		if ierr != nil {
+			return fmt.Errorf("invalid source interface %q: %v", s.iface, ierr)
+		}
+		if s.ip == nil {
+			return nil
+		}
+		addrs, err = iface.Addrs()
+	} else {
+		if s.ip == nil {
+			return nil
+		}
+		addrs, err = net.InterfaceAddrs()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get local addresses: %v", err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(s.ip) {
+			return nil
+		}
+	}
+	if s.iface != "" {
+		return fmt.Errorf("source IP %v is not configured on interface %s", s.ip, s.iface)
+	}
+	return fmt.Errorf("source IP %v is not configured locally", s.ip)
+}
+
+// control returns a function that binds a socket to the source interface,
+// for use with net.Dialer and net.ListenConfig.
+func (s source) control() func(network, address string, c syscall.RawConn) error {
+	if s.iface == "" {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		var err error
+		if cerr := c.Control(func(fd uintptr) {
+			err = bindToDevice(int(fd), s.iface)
+		}); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+}
+
+// bindToDevice binds the given socket to a network interface.
+func bindToDevice(fd int, iface string) error {
+	if err := syscall.SetsockoptString(fd, syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface); err != nil {
+		return os.NewSyscallError("failed to bind to device", err)
+	}
+	return nil
+}
+
 // dialTCP dials a TCP connection to the specified host and sets marking on the
 // socket. The host must be given as an IP address. A mark of zero results in a
-// normal (non-marked) connection.
-func dialTCP(network, addr string, timeout time.Duration, mark int) (nc net.Conn, err error) {
+// normal (non-marked) connection. The connection is made from the given source
+// address and interface, if specified.
+func dialTCP(network, addr string, timeout time.Duration, mark int, src source) (nc net.Conn, err error) {
 	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, err
@@ -104,6 +176,20 @@ func dialTCP(network, addr string, timeout time.Duration, mark int) (nc net.Conn
 		return nil, fmt.Errorf("unsupported network %q", network)
 	}
 
+	if err := src.validate(ip); err != nil {
+		return nil, err
+	}
+	var lsa syscall.Sockaddr
+	if ip4 := src.ip.To4(); ip4 != nil {
+		sa := &syscall.SockaddrInet4{}
+		copy(sa.Addr[:], ip4)
+		lsa = sa
+	} else if src.ip != nil {
+		sa := &syscall.SockaddrInet6{}
+		copy(sa.Addr[:], src.ip.To16())
+		lsa = sa
+	}
+
 	c := &conn{}
 
 	defer func() {
@@ -123,6 +209,17 @@ func dialTCP(network, addr string, timeout time.Duration, mark int) (nc net.Conn
 		}
 	}
 
+	if src.iface != "" {
+		if err := bindToDevice(c.fd, src.iface); err != nil {
+			return nil, err
+		}
+	}
+	if lsa != nil {
+		if err := syscall.Bind(c.fd, lsa); err != nil {
+			return nil, os.NewSyscallError("bind", err)
+		}
+	}
+
 	if err := setSocketTimeout(c.fd, timeout); err != nil {
 		return nil, err
 	}
@@ -133,7 +230,7 @@ func dialTCP(network, addr string, timeout time.Duration, mark int) (nc net.Conn
 		return nil, err
 	}
 
-	lsa, _ := syscall.Getsockname(c.fd)
+	lsa, _ = syscall.Getsockname(c.fd)
 	rsa, _ = syscall.Getpeername(c.fd)
 	name := fmt.Sprintf("%s %s -> %s", network, sockaddrToString(lsa), sockaddrToString(rsa))
 	c.f = os.NewFile(uintptr(c.fd), name)
@@ -153,9 +250,21 @@ func dialTCP(network, addr string, timeout time.Duration, mark int) (nc net.Conn
 }
 
 // dialUDP dials a UDP connection to the specified host and sets marking on the
-// socket. A mark of zero results in a normal (non-marked) connection.
-func dialUDP(network, addr string, timeout time.Duration, mark int) (*net.UDPConn, error) {
-	d := net.Dialer{Timeout: timeout}
+// socket. A mark of zero results in a normal (non-marked) connection. The
+// connection is made from the given source address and interface, if
+// specified.
+func dialUDP(network, addr string, timeout time.Duration, mark int, src source) (*net.UDPConn, error) {
+	raddr, err := net.ResolveUDPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := src.validate(raddr.IP); err != nil {
+		return nil, err
+	}
+	d := net.Dialer{Timeout: timeout, Control: src.control()}
+	if src.ip != nil {
+		d.LocalAddr = &net.UDPAddr{IP: src.ip}
+	}
 	conn, err := d.Dial(network, addr)
 	if err != nil {
 		return nil, err
@@ -194,6 +303,20 @@ func dialUDP(network, addr string, timeout time.Duration, mark int) (*net.UDPCon
 	return udpConn, nil
 }
 
+// listenPacket listens for packets on the given network, using the given
+// source address and interface, if specified.
+func listenPacket(network string, target net.IP, src source) (net.PacketConn, error) {
+	if err := src.validate(target); err != nil {
+		return nil, err
+	}
+	var laddr string
+	if src.ip != nil {
+		laddr = src.ip.String()
+	}
+	lc := net.ListenConfig{Control: src.control()}
+	return lc.ListenPacket(context.Background(), network, laddr)
+}
+
 // setSocketMark sets packet marking on the given socket.
 func setSocketMark(fd, mark int) error {
 	if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_MARK, mark); err != nil {
@@ -19,6 +19,7 @@
 package healthcheck
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"strings"
@@ -47,6 +48,14 @@ type DNSChecker struct {
 	Target
 	Question dns.Question
 	Answer   string
+
+	// TLS specifies that the query should be performed via DNS-over-TLS.
+	// TLSServerName is the server name that is sent via SNI and that the
+	// certificate is verified against. If empty, the target IP address is
+	// used.
+	TLS           bool
+	TLSVerify     bool
+	TLSServerName string
 }
 
 // NewDNSChecker returns an initialised DNSChecker.
@@ -61,6 +70,7 @@ func NewDNSChecker(ip net.IP, port int) *DNSChecker {
 			Qclass: dns.ClassINET,
 			Qtype:  dns.TypeA,
 		},
+		TLSVerify: true,
 	}
 }
 
@@ -70,7 +80,18 @@ func questionToString(q dns.Question) string {
 
 // String returns the string representation of a DNS healthcheck.
 func (hc *DNSChecker) String() string {
-	return fmt.Sprintf("DNS %s %s", questionToString(hc.Question), hc.Target)
+	var s string
+	if hc.TLS {
+		attr := []string{"tls"}
+		if hc.TLSVerify {
+			attr = append(attr, "verify")
+		}
+		if hc.TLSServerName != "" {
+			attr = append(attr, fmt.Sprintf("server name %s", hc.TLSServerName))
+		}
+		s = fmt.Sprintf(" [%s]", strings.Join(attr, "; "))
+	}
+	return fmt.Sprintf("DNS %s%s %s", questionToString(hc.Question), s, hc.Target)
 }
 
 // Check executes a DNS healthcheck.
@@ -80,6 +101,9 @@ func (hc *DNSChecker) Check(timeout time.Duration) *Result {
 	}
 
 	msg := fmt.Sprintf("DNS %s query to port %d", questionToString(hc.Question), hc.Port)
+	if hc.TLS {
+		msg = fmt.Sprintf("%s over TLS", msg)
+	}
 	start := time.Now()
 	if timeout == time.Duration(0) {
 		timeout = defaultDNSTimeout
@@ -109,8 +133,17 @@ func (hc *DNSChecker) Check(timeout time.Duration) *Result {
 		Question: []dns.Question{hc.Question},
 	}
 
-	// TODO(mharo): don't assume UDP
-	conn, err := dialUDP(hc.network(), hc.addr(), timeout, hc.Mark)
+	var conn net.Conn
+	var err error
+	if hc.TLS {
+		// DNS-over-TLS is always carried over TCP.
+		target := hc.Target
+		target.Proto = seesaw.IPProtoTCP
+		conn, err = dialTCP(target.network(), target.addr(), hc.connectTimeout(timeout), hc.Mark, hc.source())
+	} else {
+		// TODO(mharo): don't assume UDP
+		conn, err = dialUDP(hc.network(), hc.addr(), timeout, hc.Mark, hc.source())
+	}
 	if err != nil {
 		return complete(start, msg, false, err)
 	}
@@ -122,6 +155,22 @@ func (hc *DNSChecker) Check(timeout time.Duration) *Result {
 		return complete(start, msg, false, err)
 	}
 
+	if hc.TLS {
+		serverName := hc.TLSServerName
+		if serverName == "" {
+			serverName = hc.IP.String()
+		}
+		tlsConn := tls.Client(conn, &tls.Config{
+			InsecureSkipVerify: !hc.TLSVerify,
+			ServerName:         serverName,
+		})
+		if err := tlsConn.Handshake(); err != nil {
+			msg = fmt.Sprintf("%s; TLS handshake failed", msg)
+			return complete(start, msg, false, err)
+		}
+		conn = tlsConn
+	}
+
 	dnsConn := &dns.Conn{Conn: conn}
 	if err := dnsConn.WriteMsg(q); err != nil {
 		msg = fmt.Sprintf("%s; failed to send request", msg)
@@ -0,0 +1,206 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// This file contains routines for establishing healthcheck connections via
+// an egress proxy, using either SOCKS5 or HTTP CONNECT.
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+func init() {
+	proxy.RegisterDialerType("http", newHTTPConnectDialer)
+}
+
+// proxyError is returned when a connection to the proxy itself cannot be
+// established or is rejected by the proxy, as opposed to the proxy being
+// unable to connect to the backend.
+type proxyError struct {
+	proxy string
+	err   error
+}
+
+// Error returns the string representation of a proxy error.
+func (e *proxyError) Error() string {
+	return fmt.Sprintf("failed to connect to proxy %s: %v", e.proxy, e.err)
+}
+
+// redactProxyURL returns the given proxy URL with any password redacted.
+func redactProxyURL(proxyURL string) string {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Redacted()
+}
+
+// proxyForwarder establishes TCP connections to a proxy, using the given
+// firewall mark and source address.
+type proxyForwarder struct {
+	deadline time.Time
+	mark     int
+	src      source
+}
+
+// Dial establishes a TCP connection to the proxy at the given address,
+// which must be an IP address and port.
+func (f *proxyForwarder) Dial(network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, &proxyError{addr, err}
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, &proxyError{addr, errors.New("proxy address must be an IP address")}
+	}
+	network = "tcp4"
+	if ip.To4() == nil {
+		network = "tcp6"
+	}
+	conn, err := dialTCP(network, addr, time.Until(f.deadline), f.mark, f.src)
+	if err != nil {
+		return nil, &proxyError{addr, err}
+	}
+	if err := conn.SetDeadline(f.deadline); err != nil {
+		conn.Close()
+		return nil, &proxyError{addr, err}
+	}
+	return conn, nil
+}
+
+// dialProxy establishes a TCP connection to the given address via the
+// proxy specified by proxyURL, which has either a socks5 or http scheme.
+// Credentials for the proxy may be provided as user information in the URL.
+func dialProxy(proxyURL, addr string, deadline time.Time, mark int, src source) (net.Conn, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.New("invalid proxy URL")
+	}
+	d, err := proxy.FromURL(u, &proxyForwarder{deadline, mark, src})
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.Dial("tcp", addr)
+	if err != nil {
+		var pe *proxyError
+		if errors.As(err, &pe) {
+			return nil, pe
+		}
+		// SOCKS5 authentication failures are not otherwise
+		// distinguishable from the proxy failing to connect.
+		if strings.Contains(err.Error(), "authentication") {
+			return nil, &proxyError{u.Host, err}
+		}
+		return nil, fmt.Errorf("backend %s unreachable via proxy %s: %v", addr, u.Host, err)
+	}
+	return conn, nil
+}
+
+// httpConnectDialer establishes connections via an HTTP proxy, using the
+// CONNECT method.
+type httpConnectDialer struct {
+	proxy   string
+	auth    string
+	forward proxy.Dialer
+}
+
+// newHTTPConnectDialer returns a proxy.Dialer for the given HTTP proxy URL.
+func newHTTPConnectDialer(u *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+	}
+	d := &httpConnectDialer{
+		proxy:   net.JoinHostPort(u.Hostname(), port),
+		forward: forward,
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := u.User.Username() + ":" + password
+		d.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+	return d, nil
+}
+
+// Dial establishes a connection to the given address via the HTTP proxy.
+func (d *httpConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := d.forward.Dial(network, d.proxy)
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{"User-Agent": {defaultHTTPUserAgent}},
+	}
+	if d.auth != "" {
+		req.Header.Set("Proxy-Authorization", d.auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, &proxyError{d.proxy, err}
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, &proxyError{d.proxy, err}
+	}
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		conn.Close()
+		return nil, &proxyError{d.proxy, fmt.Errorf("CONNECT returned %s", resp.Status)}
+	case resp.StatusCode != http.StatusOK:
+		conn.Close()
+		return nil, fmt.Errorf("CONNECT returned %s", resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{conn, br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn that is read via a buffered reader, which may
+// contain data that has already been read from the connection.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read reads data from the buffered connection.
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// dialTarget establishes a TCP connection to the healthcheck target, via
+// the given proxy if proxyURL is non-empty.
+func (t *Target) dialTarget(proxyURL string, timeout time.Duration) (net.Conn, error) {
+	if proxyURL == "" {
+		return dialTCP(t.network(), t.addr(), t.connectTimeout(timeout), t.Mark, t.source())
+	}
+	return dialProxy(proxyURL, t.addr(), time.Now().Add(t.connectTimeout(timeout)), t.Mark, t.source())
+}
@@ -0,0 +1,117 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Exec healthcheck implementation.
+
+package healthcheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultExecTimeout = 10 * time.Second
+
+	// execOutputLen is the maximum length of command output that is
+	// included in a healthcheck message.
+	execOutputLen = 256
+)
+
+// ExecChecker contains configuration specific to an exec healthcheck, which
+// runs an external command and considers the target healthy if the command
+// exits successfully. The target IP address and port are appended to the
+// command arguments and are also provided via the SEESAW_HC_IP, SEESAW_HC_PORT
+// and SEESAW_HC_HOST environment variables.
+type ExecChecker struct {
+	Target
+	Command string
+	Args    []string
+}
+
+// NewExecChecker returns an initialised ExecChecker.
+func NewExecChecker(ip net.IP, port int) *ExecChecker {
+	return &ExecChecker{
+		Target: Target{
+			IP:   ip,
+			Port: port,
+		},
+	}
+}
+
+// String returns the string representation of an exec healthcheck.
+func (hc *ExecChecker) String() string {
+	cmd := strings.Join(append([]string{hc.Command}, hc.Args...), " ")
+	return fmt.Sprintf("EXEC %q %s", cmd, hc.Target)
+}
+
+// Check executes an exec healthcheck. The command is killed if it has not
+// completed within the given timeout.
+func (hc *ExecChecker) Check(timeout time.Duration) *Result {
+	msg := fmt.Sprintf("Exec %s for %s", hc.Command, hc.addr())
+	start := time.Now()
+	if timeout == time.Duration(0) {
+		timeout = defaultExecTimeout
+	}
+
+	port := strconv.Itoa(hc.Port)
+	args := append(append([]string{}, hc.Args...), hc.IP.String(), port)
+	cmd := exec.Command(hc.Command, args...)
+	cmd.Env = append(os.Environ(),
+		"SEESAW_HC_IP="+hc.IP.String(),
+		"SEESAW_HC_PORT="+port,
+		"SEESAW_HC_HOST="+hc.Host.String())
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	// Run the command in its own process group, so that any processes
+	// that it spawns are also killed on timeout.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		msg = fmt.Sprintf("%s; failed to start command", msg)
+		return complete(start, msg, false, err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		msg = fmt.Sprintf("%s; killed after %v", msg, timeout)
+		return complete(start, msg, false, errors.New("command timed out"))
+	}
+	if err != nil {
+		output := strings.TrimSpace(out.String())
+		if len(output) > execOutputLen {
+			output = output[:execOutputLen] + "..."
+		}
+		msg = fmt.Sprintf("%s; %v - %q", msg, err, output)
+		return complete(start, msg, false, err)
+	}
+	return complete(start, msg, true, nil)
+}
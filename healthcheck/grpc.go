@@ -0,0 +1,166 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// gRPC healthcheck implementation.
+
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultGRPCTimeout = 5 * time.Second
+)
+
+// GRPCChecker contains configuration specific to a gRPC healthcheck, which
+// uses the standard grpc.health.v1.Health/Check service.
+type GRPCChecker struct {
+	Target
+	Secure    bool
+	TLSVerify bool
+
+	// TLSServerName is the server name that is sent via SNI and that
+	// the certificate is verified against. If empty, the target IP
+	// address is used.
+	TLSServerName string
+
+	// Service is the name of the service to query. An empty name queries
+	// the overall health of the server.
+	Service string
+
+	// RPCTimeout bounds the time spent waiting for the Check RPC. If zero,
+	// the healthcheck timeout applies. The time spent establishing the
+	// connection is bounded by the target's ConnectTimeout.
+	RPCTimeout time.Duration
+}
+
+// NewGRPCChecker returns an initialised GRPCChecker.
+func NewGRPCChecker(ip net.IP, port int) *GRPCChecker {
+	return &GRPCChecker{
+		Target: Target{
+			IP:    ip,
+			Port:  port,
+			Proto: seesaw.IPProtoTCP,
+		},
+		TLSVerify: true,
+	}
+}
+
+// String returns the string representation of a gRPC healthcheck.
+func (hc *GRPCChecker) String() string {
+	attr := []string{}
+	if hc.Service != "" {
+		attr = append(attr, fmt.Sprintf("service %q", hc.Service))
+	}
+	if hc.Secure {
+		attr = append(attr, "secure")
+		if hc.TLSVerify {
+			attr = append(attr, "verify")
+		}
+		if hc.TLSServerName != "" {
+			attr = append(attr, fmt.Sprintf("server name %s", hc.TLSServerName))
+		}
+	}
+	var s string
+	if len(attr) > 0 {
+		s = fmt.Sprintf(" [%s]", strings.Join(attr, "; "))
+	}
+	return fmt.Sprintf("gRPC%s %s", s, hc.Target)
+}
+
+// Check executes a gRPC healthcheck.
+func (hc *GRPCChecker) Check(timeout time.Duration) *Result {
+	msg := fmt.Sprintf("gRPC health check to %s", hc.addr())
+	if hc.Service != "" {
+		msg = fmt.Sprintf("%s for service %q", msg, hc.Service)
+	}
+	start := time.Now()
+	if timeout == time.Duration(0) {
+		timeout = defaultGRPCTimeout
+	}
+
+	// Establish the connection up front, so that marking is applied and
+	// connection failures are reported separately from RPC failures.
+	conn, err := dialTCP(hc.network(), hc.addr(), hc.connectTimeout(timeout), hc.Mark, hc.source())
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to connect", msg)
+		return complete(start, msg, false, err)
+	}
+	defer conn.Close()
+
+	creds := insecure.NewCredentials()
+	if hc.Secure {
+		host, _, err := net.SplitHostPort(hc.addr())
+		if err != nil {
+			msg = msg + "; failed to split host"
+			return complete(start, msg, false, err)
+		}
+		if hc.TLSServerName != "" {
+			host = hc.TLSServerName
+		}
+		creds = credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: !hc.TLSVerify,
+			ServerName:         host,
+		})
+	}
+
+	// The connection is handed to gRPC exactly once - any attempt to
+	// reconnect will fail rather than dialing without marking.
+	var dialed int32
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		if !atomic.CompareAndSwapInt32(&dialed, 0, 1) {
+			return nil, fmt.Errorf("connection to %s already used", addr)
+		}
+		return conn, nil
+	}
+	client, err := grpc.NewClient("passthrough:///"+hc.addr(),
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(creds))
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to create client", msg)
+		return complete(start, msg, false, err)
+	}
+	defer client.Close()
+
+	rpcTimeout := timeout - time.Since(start)
+	if hc.RPCTimeout > 0 && hc.RPCTimeout < rpcTimeout {
+		rpcTimeout = hc.RPCTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	req := &healthpb.HealthCheckRequest{Service: hc.Service}
+	resp, err := healthpb.NewHealthClient(client).Check(ctx, req, grpc.WaitForReady(true))
+	if err != nil {
+		msg = fmt.Sprintf("%s; RPC failed", msg)
+		return complete(start, msg, false, err)
+	}
+	status := resp.GetStatus()
+	msg = fmt.Sprintf("%s; got %s", msg, status)
+	return complete(start, msg, status == healthpb.HealthCheckResponse_SERVING, nil)
+}
@@ -0,0 +1,251 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// This file contains routines for performing half-open TCP connects, where a
+// SYN is sent and a SYN-ACK is considered to be a success. The connection is
+// then reset, rather than being established. This requires raw sockets and
+// hence CAP_NET_RAW.
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// errRawUnavailable is returned when raw sockets cannot be used, typically
+// due to the process lacking CAP_NET_RAW.
+var errRawUnavailable = errors.New("raw sockets unavailable")
+
+const (
+	tcpHeaderLen = 20
+
+	tcpFlagSYN = 0x02
+	tcpFlagRST = 0x04
+	tcpFlagACK = 0x10
+
+	halfOpenWindow = 1024
+)
+
+// tcpSegment represents the header of a TCP segment without options.
+type tcpSegment struct {
+	srcPort uint16
+	dstPort uint16
+	seq     uint32
+	ack     uint32
+	flags   byte
+	window  uint16
+}
+
+// marshal returns the wire format of the TCP segment, including a checksum
+// for the given source and destination addresses.
+func (s *tcpSegment) marshal(src, dst net.IP) []byte {
+	b := make([]byte, tcpHeaderLen)
+	binary.BigEndian.PutUint16(b[0:2], s.srcPort)
+	binary.BigEndian.PutUint16(b[2:4], s.dstPort)
+	binary.BigEndian.PutUint32(b[4:8], s.seq)
+	binary.BigEndian.PutUint32(b[8:12], s.ack)
+	b[12] = (tcpHeaderLen / 4) << 4
+	b[13] = s.flags
+	binary.BigEndian.PutUint16(b[14:16], s.window)
+	binary.BigEndian.PutUint16(b[16:18], tcpChecksum(src, dst, b))
+	return b
+}
+
+// parseTCPSegment parses the header of a TCP segment.
+func parseTCPSegment(b []byte) (*tcpSegment, error) {
+	if len(b) < tcpHeaderLen {
+		return nil, fmt.Errorf("short TCP segment: %d bytes", len(b))
+	}
+	return &tcpSegment{
+		srcPort: binary.BigEndian.Uint16(b[0:2]),
+		dstPort: binary.BigEndian.Uint16(b[2:4]),
+		seq:     binary.BigEndian.Uint32(b[4:8]),
+		ack:     binary.BigEndian.Uint32(b[8:12]),
+		flags:   b[13],
+		window:  binary.BigEndian.Uint16(b[14:16]),
+	}, nil
+}
+
+// tcpChecksum calculates the checksum for a TCP segment, as per RFC 793 for
+// IPv4 and RFC 8200 section 8.1 for IPv6.
+func tcpChecksum(src, dst net.IP, seg []byte) uint16 {
+	var sum uint32
+	add := func(b []byte) {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(b[i])<<8 | uint32(b[i+1])
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		add(src4)
+		add(dst4)
+	} else {
+		add(src.To16())
+		add(dst.To16())
+	}
+	sum += syscall.IPPROTO_TCP + uint32(len(seg))
+	add(seg)
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// reserveTCPPort binds a TCP socket to an ephemeral port on the given IP
+// address, which prevents the port from being used by other connections.
+// Since the socket is never connected or listening, the kernel will also
+// reset any connection that is established to this port.
+func reserveTCPPort(ip net.IP) (int, uint16, error) {
+	var family int
+	var sa syscall.Sockaddr
+	if ip4 := ip.To4(); ip4 != nil {
+		sa4 := &syscall.SockaddrInet4{}
+		copy(sa4.Addr[:], ip4)
+		family, sa = syscall.AF_INET, sa4
+	} else {
+		sa6 := &syscall.SockaddrInet6{}
+		copy(sa6.Addr[:], ip.To16())
+		family, sa = syscall.AF_INET6, sa6
+	}
+	fd, err := syscall.Socket(family, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, 0, os.NewSyscallError("socket", err)
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return -1, 0, os.NewSyscallError("bind", err)
+	}
+	lsa, err := syscall.Getsockname(fd)
+	if err != nil {
+		syscall.Close(fd)
+		return -1, 0, os.NewSyscallError("getsockname", err)
+	}
+	switch lsa := lsa.(type) {
+	case *syscall.SockaddrInet4:
+		return fd, uint16(lsa.Port), nil
+	case *syscall.SockaddrInet6:
+		return fd, uint16(lsa.Port), nil
+	}
+	syscall.Close(fd)
+	return -1, 0, fmt.Errorf("unexpected socket address %T", lsa)
+}
+
+// halfOpenTCP sends a TCP SYN to the given IP address and port and waits for
+// a SYN-ACK, which is answered with a RST. errRawUnavailable is returned if
+// raw sockets cannot be used.
+func halfOpenTCP(ip net.IP, port int, timeout time.Duration, mark int, src source) error {
+	network, udpNetwork := "ip4:tcp", "udp4"
+	if ip.To4() == nil {
+		network, udpNetwork = "ip6:tcp", "udp6"
+	}
+	deadline := time.Now().Add(timeout)
+
+	// Determine the local address that is used to reach the target, which
+	// is needed for the TCP checksum.
+	udpConn, err := dialUDP(udpNetwork, net.JoinHostPort(ip.String(), strconv.Itoa(port)), timeout, mark, src)
+	if err != nil {
+		return err
+	}
+	local := udpConn.LocalAddr().(*net.UDPAddr).IP
+	udpConn.Close()
+
+	fd, lport, err := reserveTCPPort(local)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) {
+				if mark != 0 {
+					err = setSocketMark(int(fd), mark)
+				}
+				if err == nil && src.iface != "" {
+					err = bindToDevice(int(fd), src.iface)
+				}
+			}); cerr != nil {
+				return cerr
+			}
+			return err
+		},
+	}
+	c, err := lc.ListenPacket(context.Background(), network, local.String())
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return errRawUnavailable
+		}
+		return err
+	}
+	defer c.Close()
+	if err := c.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	seq := rand.Uint32()
+	syn := &tcpSegment{
+		srcPort: lport,
+		dstPort: uint16(port),
+		seq:     seq,
+		flags:   tcpFlagSYN,
+		window:  halfOpenWindow,
+	}
+	if _, err := c.WriteTo(syn.marshal(local, ip), &net.IPAddr{IP: ip}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := c.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		if ipAddr, ok := addr.(*net.IPAddr); !ok || !ip.Equal(ipAddr.IP) {
+			continue
+		}
+		seg, err := parseTCPSegment(buf[:n])
+		if err != nil || seg.srcPort != uint16(port) || seg.dstPort != lport {
+			continue
+		}
+		if seg.flags&tcpFlagACK == 0 || seg.ack != seq+1 {
+			continue
+		}
+		if seg.flags&tcpFlagRST != 0 {
+			return os.NewSyscallError("connect", syscall.ECONNREFUSED)
+		}
+		if seg.flags&tcpFlagSYN == 0 {
+			continue
+		}
+		rst := &tcpSegment{
+			srcPort: lport,
+			dstPort: uint16(port),
+			seq:     seq + 1,
+			flags:   tcpFlagRST,
+		}
+		_, err = c.WriteTo(rst.marshal(local, ip), &net.IPAddr{IP: ip})
+		return err
+	}
+}
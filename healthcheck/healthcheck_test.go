@@ -17,12 +17,41 @@
 package healthcheck
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 const timeout = 1 * time.Second
@@ -193,6 +222,809 @@ func TestHTTPCheckerSecure(t *testing.T) {
 	testHTTPChecker(t, true)
 }
 
+func testGRPCChecker(t *testing.T, n string) {
+	l, a, err := newLocalTCPListener(n)
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := grpc.NewServer()
+	health := grpchealth.NewServer()
+	health.SetServingStatus("serving", healthpb.HealthCheckResponse_SERVING)
+	health.SetServingStatus("notserving", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, health)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	for _, test := range []struct {
+		service  string
+		expected bool
+	}{
+		{"", true},
+		{"serving", true},
+		{"notserving", false},
+		{"unknown", false},
+	} {
+		hc := NewGRPCChecker(a.IP, a.Port)
+		hc.Service = test.service
+		if result := hc.Check(timeout); result.Success != test.expected {
+			t.Errorf("gRPC healthcheck %v to %v failed: %v", hc, a, result)
+		}
+	}
+
+	// Plaintext server with TLS enabled.
+	hc := NewGRPCChecker(a.IP, a.Port)
+	hc.Secure = true
+	hc.TLSVerify = false
+	if result := hc.Check(timeout); result.Success {
+		t.Errorf("gRPC healthcheck %v to %v succeeded: %v", hc, a, result)
+	}
+}
+
+func TestGRPCChecker(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		testGRPCChecker(t, n)
+	}
+}
+
+func testHTTPCheckerBodyMatch(t *testing.T, n string) {
+	l, a, err := newLocalTCPListener(n)
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := newLocalHTTPServer(l)
+	mux := srv.Config.Handler.(*http.ServeMux)
+	mux.Handle("/infinite", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "status: ok")
+		for {
+			if _, err := fmt.Fprintln(w, "padding"); err != nil {
+				return
+			}
+		}
+	}))
+	srv.Start()
+	defer srv.Close()
+
+	for _, test := range []struct {
+		request      string
+		response     string
+		bodyMatch    string
+		maxBodyBytes int
+		expected     bool
+	}{
+		{"/", "", "Test Server", 0, true},
+		{"/", "", "^<html>.*</html>$", 0, false},
+		{"/", "", "(?s)^<html>.*</html>\n$", 0, true},
+		{"/", "<html>", "Test Server", 0, true},
+		{"/", "<body>", "Test Server", 0, false},
+		{"/", "", "Test Server", 10, false},
+		{"/", "", "unhealthy", 0, false},
+		{"/", "", "(", 0, false},
+		{"/healthz", "", "^ok$", 0, false},
+		{"/healthz", "", "(?m)^ok$", 0, true},
+		{"/infinite", "", "status: ok", 0, true},
+		{"/infinite", "", "status: error", 0, false},
+	} {
+		hc := NewHTTPChecker(a.IP, a.Port)
+		hc.Request = test.request
+		hc.Response = test.response
+		hc.BodyMatch = test.bodyMatch
+		hc.MaxBodyBytes = test.maxBodyBytes
+		result := hc.Check(timeout)
+		if result.Success != test.expected {
+			t.Errorf("HTTP healthcheck %v to %v failed: %v", hc, a, result)
+		}
+		if !result.Success && test.response == "" && test.bodyMatch != "(" &&
+			!strings.Contains(result.Message, fmt.Sprintf("%q", test.bodyMatch)) {
+			t.Errorf("HTTP healthcheck message %q does not include pattern %q", result.Message, test.bodyMatch)
+		}
+	}
+}
+
+func TestHTTPCheckerBodyMatch(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		testHTTPCheckerBodyMatch(t, n)
+	}
+}
+
+func testHTTPCheckerFollowRedirects(t *testing.T, n string) {
+	l, a, err := newLocalTCPListener(n)
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := newLocalHTTPServer(l)
+	mux := srv.Config.Handler.(*http.ServeMux)
+	mux.Handle("/redirect", http.RedirectHandler("/healthz", http.StatusFound))
+	mux.Handle("/loop", http.RedirectHandler("/loop", http.StatusFound))
+	mux.Handle("/offsite", http.RedirectHandler("http://192.0.2.1/healthz", http.StatusFound))
+	mux.Handle("/chain/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/chain/"))
+		if err != nil || i <= 0 {
+			http.Redirect(w, r, "/healthz", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/chain/%d", i-1), http.StatusFound)
+	}))
+	srv.Start()
+	defer srv.Close()
+
+	for _, test := range []struct {
+		request      string
+		follow       bool
+		maxRedirects int
+		expected     bool
+		message      string
+	}{
+		{"/redirect", false, 0, false, "302 Found"},
+		{"/redirect", true, 0, true, "from http://"},
+		{"/loop", true, 0, false, "redirect loop"},
+		{"/offsite", true, 0, false, "not on the same backend"},
+		{"/chain/3", true, 0, true, ""},
+		{"/chain/3", true, 2, false, "stopped after 2 redirects"},
+	} {
+		hc := NewHTTPChecker(a.IP, a.Port)
+		hc.Request = test.request
+		hc.FollowRedirects = test.follow
+		hc.MaxRedirects = test.maxRedirects
+		result := hc.Check(timeout)
+		if result.Success != test.expected {
+			t.Errorf("HTTP healthcheck %v to %v got success %v, want %v: %v", hc, a, result.Success, test.expected, result)
+		}
+		if !strings.Contains(result.String(), test.message) {
+			t.Errorf("HTTP healthcheck %v result %q does not contain %q", hc, result, test.message)
+		}
+	}
+}
+
+func TestHTTPCheckerFollowRedirects(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		testHTTPCheckerFollowRedirects(t, n)
+	}
+}
+
+func TestHTTPCheckerRequestTemplate(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		l, a, err := newLocalTCPListener(n)
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		srv := newLocalHTTPServer(l)
+		mux := srv.Config.Handler.(*http.ServeMux)
+		mux.Handle("/echo/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, r.URL.Path)
+		}))
+		srv.Start()
+		defer srv.Close()
+
+		for _, test := range []struct {
+			request  string
+			hostname string
+			response string
+			expected bool
+			message  string
+		}{
+			{"/echo/{ip}/{port}", "", fmt.Sprintf("/echo/%s/%d", a.IP, a.Port), true, ""},
+			{"/echo/nodes/{name}/status", "backend1.example.com", "/echo/nodes/backend1.example.com/status", true, ""},
+			{"/echo/{name}", "", "", false, "no backend hostname for placeholder {name}"},
+			{"/echo/{ip}/{foo}", "", "", false, "unknown placeholder {foo}"},
+		} {
+			hc := NewHTTPChecker(a.IP, a.Port)
+			hc.Target.Host = a.IP
+			hc.Hostname = test.hostname
+			hc.Request = test.request
+			hc.Response = test.response
+			result := hc.Check(timeout)
+			if result.Success != test.expected {
+				t.Errorf("HTTP healthcheck %v to %v got success %v, want %v: %v", hc, a, result.Success, test.expected, result)
+			}
+			if !strings.Contains(result.String(), test.message) {
+				t.Errorf("HTTP healthcheck %v result %q does not contain %q", hc, result, test.message)
+			}
+		}
+	}
+
+	// For DSR healthchecks the target is the VIP, while {ip} is the backend.
+	hc := NewHTTPChecker(net.ParseIP("192.168.0.1"), 80)
+	hc.Target.Host = net.ParseIP("2001:db8::2")
+	hc.Request = "/nodes/{ip}:{port}"
+	if got, err := hc.request(); err != nil || got != "/nodes/2001:db8::2:80" {
+		t.Errorf("HTTP request %q got %q, %v, want %q", hc.Request, got, err, "/nodes/2001:db8::2:80")
+	}
+}
+
+func TestHTTPCheckerHeaders(t *testing.T) {
+	l, a, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := newLocalHTTPServer(l)
+	mux := srv.Config.Handler.(*http.ServeMux)
+	mux.Handle("/headers", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Host: %s\n", r.Host)
+		r.Header.Write(w)
+	}))
+	srv.Start()
+	defer srv.Close()
+
+	for _, test := range []struct {
+		host      string
+		headers   map[string]string
+		bodyMatch string
+	}{
+		{"", nil, "(?m)^User-Agent: Seesaw-Healthcheck/2\r$"},
+		{"", map[string]string{"user-agent": "probe/1"}, "(?m)^User-Agent: probe/1\r$"},
+		{"", map[string]string{"X-Custom": "foo", "Authorization": "Bearer x"}, "(?ms)^Authorization: Bearer x\r$.*^User-Agent: Seesaw-Healthcheck/2\r$.*^X-Custom: foo\r$"},
+		{"", map[string]string{"host": "a.example.com"}, "^Host: a.example.com\n"},
+		{"b.example.com", map[string]string{"Host": "a.example.com"}, "^Host: b.example.com\n"},
+	} {
+		hc := NewHTTPChecker(a.IP, a.Port)
+		hc.Request = "/headers"
+		hc.Host = test.host
+		hc.Headers = test.headers
+		hc.BodyMatch = test.bodyMatch
+		if result := hc.Check(timeout); !result.Success {
+			t.Errorf("HTTP healthcheck %v to %v failed: %v", hc, a, result)
+		}
+	}
+}
+
+func TestHTTPCheckerMethods(t *testing.T) {
+	l, a, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := newLocalHTTPServer(l)
+	mux := srv.Config.Handler.(*http.ServeMux)
+	mux.Handle("/echo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %q", r.Method, body)
+	}))
+	srv.Start()
+	defer srv.Close()
+
+	for _, test := range []struct {
+		method    string
+		body      string
+		response  string
+		bodyMatch string
+		expected  bool
+	}{
+		{"GET", "", `GET ""`, "", true},
+		{"OPTIONS", "", `OPTIONS ""`, "", true},
+		{"POST", "", `POST ""`, "", true},
+		{"POST", `{"ping":1}`, "", `^POST "{\\"ping\\":1}"$`, true},
+		{"POST", "foo", `POST "bar"`, "", false},
+		{"HEAD", "", "", "", true},
+		{"HEAD", "", `HEAD ""`, "", false},
+		{"HEAD", "", "", "HEAD", false},
+	} {
+		hc := NewHTTPChecker(a.IP, a.Port)
+		hc.Request = "/echo"
+		hc.Method = test.method
+		hc.Body = test.body
+		hc.Response = test.response
+		hc.BodyMatch = test.bodyMatch
+		if result := hc.Check(timeout); result.Success != test.expected {
+			t.Errorf("HTTP healthcheck %v to %v with body %q got success %t, want %t: %v",
+				hc, a, test.body, result.Success, test.expected, result)
+		}
+	}
+}
+
+func TestHTTPCheckerWeight(t *testing.T) {
+	l, a, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := newLocalHTTPServer(l)
+	mux := srv.Config.Handler.(*http.ServeMux)
+	mux.Handle("/load", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if load := r.URL.Query().Get("load"); load != "" {
+			w.Header().Set("X-Load", load)
+		}
+	}))
+	srv.Start()
+	defer srv.Close()
+
+	for _, test := range []struct {
+		load    string
+		success bool
+		weight  int
+	}{
+		{"80", true, 80},
+		{"0", true, 0},
+		{"100%", true, 100},
+		{" 25% ", true, 25},
+		{"", false, 0},
+		{"101", false, 0},
+		{"-1", false, 0},
+		{"lots", false, 0},
+	} {
+		hc := NewHTTPChecker(a.IP, a.Port)
+		hc.Request = "/load?load=" + url.QueryEscape(test.load)
+		hc.WeightHeader = "x-load"
+		result := hc.Check(timeout)
+		if result.Success != test.success {
+			t.Errorf("HTTP healthcheck %v to %v returned success %v, want %v: %v",
+				hc, a, result.Success, test.success, result)
+			continue
+		}
+		if result.Weighted != test.success || result.Weight != test.weight {
+			t.Errorf("HTTP healthcheck %v to %v returned weight (%v, %d), want (%v, %d)",
+				hc, a, result.Weighted, result.Weight, test.success, test.weight)
+		}
+	}
+
+	// A checker without a weight header is not weighted.
+	hc := NewHTTPChecker(a.IP, a.Port)
+	hc.Request = "/load?load=50"
+	if result := hc.Check(timeout); !result.Success || result.Weighted {
+		t.Errorf("HTTP healthcheck %v to %v returned success %v, weighted %v, want true, false",
+			hc, a, result.Success, result.Weighted)
+	}
+}
+
+func TestHTTPCheckerServerName(t *testing.T) {
+	l, a, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+
+	var serverName, host string
+	srv := newLocalHTTPServer(l)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverName, host = r.TLS.ServerName, r.Host
+	})
+	srv.StartTLS()
+	defer srv.Close()
+
+	hc := NewHTTPChecker(a.IP, a.Port)
+	hc.Secure = true
+	hc.TLSVerify = false
+	hc.TLSServerName = "sni.example.com"
+	hc.Host = "host.example.com"
+	if result := hc.Check(timeout); !result.Success {
+		t.Fatalf("HTTP healthcheck %v to %v failed: %v", hc, a, result)
+	}
+	if serverName != hc.TLSServerName {
+		t.Errorf("Got server name %q, want %q", serverName, hc.TLSServerName)
+	}
+	if host != hc.Host {
+		t.Errorf("Got host %q, want %q", host, hc.Host)
+	}
+
+	// The test server certificate is neither trusted nor valid for this name.
+	hc.TLSVerify = true
+	if result := hc.Check(timeout); result.Success {
+		t.Errorf("HTTP healthcheck %v to %v succeeded: %v", hc, a, result)
+	}
+}
+
+func TestTLSConstraints(t *testing.T) {
+	aesGCM := []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	}
+	chaCha := []uint16{
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	}
+	// The CBC cipher suites are needed for TLS 1.0.
+	serverCipherSuites := append([]uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	}, aesGCM...)
+	tests := []struct {
+		desc             string
+		serverMaxVersion uint16
+		minVersion       uint16
+		maxVersion       uint16
+		cipherSuites     []uint16
+		expected         bool
+		message          string
+	}{
+		{"no constraints", 0, 0, 0, nil, true, ""},
+		{"minimum met", 0, tls.VersionTLS13, 0, nil, true, "negotiated TLS 1.3"},
+		{"minimum not met", tls.VersionTLS12, tls.VersionTLS13, 0, nil, false, "negotiated TLS 1.2, minimum is TLS 1.3"},
+		{"legacy allowed", tls.VersionTLS10, tls.VersionTLS10, 0, nil, true, "negotiated TLS 1.0"},
+		{"maximum", 0, 0, tls.VersionTLS12, nil, true, "negotiated TLS 1.2"},
+		{"permitted cipher suite", 0, 0, tls.VersionTLS12, aesGCM, true, "negotiated TLS 1.2 with TLS_ECDHE_"},
+		{"unsupported cipher suite", 0, 0, tls.VersionTLS12, chaCha, false, ""},
+		{"TLS 1.3 cipher suite", 0, 0, 0, aesGCM, false, "negotiated TLS 1.3 with cipher suite TLS_AES_"},
+	}
+	for _, test := range tests {
+		l, a, err := newLocalTCPListener("tcp4")
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		srv := newLocalHTTPServer(l)
+		srv.TLS = &tls.Config{
+			MinVersion:   tls.VersionTLS10,
+			MaxVersion:   test.serverMaxVersion,
+			CipherSuites: serverCipherSuites,
+		}
+		srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+		srv.StartTLS()
+
+		hc := NewHTTPChecker(a.IP, a.Port)
+		hc.Secure = true
+		hc.TLSVerify = false
+		hc.TLSMinVersion = test.minVersion
+		hc.TLSMaxVersion = test.maxVersion
+		hc.TLSCipherSuites = test.cipherSuites
+
+		tc := NewTCPChecker(a.IP, a.Port)
+		tc.Secure = true
+		tc.TLSMinVersion = test.minVersion
+		tc.TLSMaxVersion = test.maxVersion
+		tc.TLSCipherSuites = test.cipherSuites
+
+		for _, c := range []Checker{hc, tc} {
+			result := c.Check(timeout)
+			if result.Success != test.expected {
+				t.Errorf("Test %q: healthcheck %v got success %t, want %t: %v",
+					test.desc, c, result.Success, test.expected, result)
+			}
+			if got := result.String(); !strings.Contains(got, test.message) {
+				t.Errorf("Test %q: healthcheck %v got result %q, want it to contain %q",
+					test.desc, c, got, test.message)
+			}
+		}
+		srv.Close()
+	}
+}
+
+// writeTestCert generates a self-signed certificate and key, writing them
+// to PEM encoded files in the given directory.
+func writeTestCert(dir, name string) (certFile, keyFile string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		return "", "", err
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
+
+// newTestServerCert generates a self-signed server certificate that
+// expires at the given time.
+func newTestServerCert(notAfter time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "server"},
+		NotBefore:    notAfter.Add(-48 * time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+func TestHTTPCheckerCertExpiry(t *testing.T) {
+	tests := []struct {
+		desc     string
+		expiry   time.Duration
+		warn     time.Duration
+		fail     bool
+		expected bool
+		expiring bool
+	}{
+		{"no warning window", time.Hour, 0, false, true, false},
+		{"outside warning window", 48 * time.Hour, 24 * time.Hour, false, true, false},
+		{"within warning window", time.Hour, 24 * time.Hour, false, true, true},
+		{"within warning window with fail", time.Hour, 24 * time.Hour, true, true, true},
+		{"expired", -time.Hour, 24 * time.Hour, false, true, true},
+		{"expired with fail", -time.Hour, 0, true, false, true},
+	}
+	for _, test := range tests {
+		cert, err := newTestServerCert(time.Now().Add(test.expiry))
+		if err != nil {
+			t.Fatalf("Failed to generate server certificate: %v", err)
+		}
+		l, a, err := newLocalTCPListener("tcp4")
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		srv := newLocalHTTPServer(l)
+		srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+		srv.StartTLS()
+
+		hc := NewHTTPChecker(a.IP, a.Port)
+		hc.Secure = true
+		hc.TLSVerify = false
+		hc.CertExpiryWarn = test.warn
+		hc.CertExpiryFail = test.fail
+		result := hc.Check(timeout)
+		if result.Success != test.expected {
+			t.Errorf("Test %q: HTTP healthcheck %v got success %t, want %t: %v",
+				test.desc, hc, result.Success, test.expected, result)
+		}
+		if result.CertExpiring != test.expiring {
+			t.Errorf("Test %q: HTTP healthcheck %v got certificate expiring %t, want %t: %v",
+				test.desc, hc, result.CertExpiring, test.expiring, result)
+		}
+		if test.expiring && !strings.Contains(result.Message, "certificate expire") {
+			t.Errorf("Test %q: HTTP healthcheck %v message %q does not report certificate expiry",
+				test.desc, hc, result.Message)
+		}
+		srv.Close()
+	}
+}
+
+func TestHTTPCheckerClientCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "healthcheck")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile, err := writeTestCert(dir, "client")
+	if err != nil {
+		t.Fatalf("Failed to create client certificate: %v", err)
+	}
+	_, otherKeyFile, err := writeTestCert(dir, "other")
+	if err != nil {
+		t.Fatalf("Failed to create client certificate: %v", err)
+	}
+
+	l, a, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := newLocalHTTPServer(l)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	hc := NewHTTPChecker(a.IP, a.Port)
+	hc.Secure = true
+	hc.TLSVerify = false
+	if result := hc.Check(timeout); result.Success {
+		t.Errorf("HTTP healthcheck %v to %v succeeded without client cert: %v", hc, a, result)
+	}
+
+	for _, files := range [][2]string{
+		{certFile, ""},
+		{certFile, filepath.Join(dir, "missing.key")},
+		{certFile, otherKeyFile},
+	} {
+		hc.ClientCertFile, hc.ClientKeyFile = files[0], files[1]
+		if err := hc.LoadClientCert(); err == nil {
+			t.Errorf("LoadClientCert(%q, %q) succeeded", files[0], files[1])
+		}
+	}
+
+	hc.ClientCertFile, hc.ClientKeyFile = certFile, keyFile
+	if err := hc.LoadClientCert(); err != nil {
+		t.Fatalf("LoadClientCert(%q, %q) failed: %v", certFile, keyFile, err)
+	}
+	if result := hc.Check(timeout); !result.Success {
+		t.Errorf("HTTP healthcheck %v to %v failed: %v", hc, a, result)
+	}
+}
+
+func dnsHandler(w dns.ResponseWriter, r *dns.Msg) {
+	m := &dns.Msg{}
+	m.SetReply(r)
+	q := r.Question[0]
+	if q.Name == "www.example.com." {
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 60}
+		switch q.Qtype {
+		case dns.TypeA:
+			m.Answer = []dns.RR{&dns.A{Hdr: hdr, A: net.ParseIP("192.168.0.1")}}
+		case dns.TypeAAAA:
+			m.Answer = []dns.RR{&dns.AAAA{Hdr: hdr, AAAA: net.ParseIP("2001:db8::1")}}
+		}
+	}
+	w.WriteMsg(m)
+}
+
+func TestDNSChecker(t *testing.T) {
+	for _, n := range []string{"udp4", "udp6"} {
+		c, a, err := newLocalUDPConn(n)
+		if err != nil {
+			t.Fatalf("Failed to get UDPConn: %v", err)
+		}
+		srv := &dns.Server{PacketConn: c, Handler: dns.HandlerFunc(dnsHandler)}
+		go srv.ActivateAndServe()
+
+		for _, test := range []struct {
+			qtype    uint16
+			answer   string
+			expected bool
+		}{
+			{dns.TypeA, "192.168.0.1", true},
+			{dns.TypeA, "192.168.0.2", false},
+			{dns.TypeAAAA, "2001:db8::1", true},
+			{dns.TypeAAAA, "2001:db8::2", false},
+		} {
+			hc := NewDNSChecker(a.IP, a.Port)
+			hc.Question.Name = "www.example.com"
+			hc.Question.Qtype = test.qtype
+			hc.Answer = test.answer
+			if result := hc.Check(timeout); result.Success != test.expected {
+				t.Errorf("DNS healthcheck %v to %v failed: %v", hc, a, result)
+			}
+		}
+		srv.Shutdown()
+	}
+}
+
+func testDNSCheckerTLS(t *testing.T, n string) {
+	dir, err := ioutil.TempDir("", "healthcheck")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile, err := writeTestCert(dir, "server")
+	if err != nil {
+		t.Fatalf("Failed to create server certificate: %v", err)
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %v", err)
+	}
+
+	l, a, err := newLocalTCPListener(n)
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	tl := tls.NewListener(l, &tls.Config{Certificates: []tls.Certificate{cert}})
+	srv := &dns.Server{Listener: tl, Handler: dns.HandlerFunc(dnsHandler)}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	for _, test := range []struct {
+		name     string
+		answer   string
+		expected bool
+	}{
+		{"www.example.com", "192.168.0.1", true},
+		{"www.example.com", "192.168.0.2", false},
+		{"mail.example.com", "192.168.0.1", false},
+	} {
+		hc := NewDNSChecker(a.IP, a.Port)
+		hc.TLS = true
+		hc.TLSVerify = false
+		hc.Question.Name = test.name
+		hc.Answer = test.answer
+		if result := hc.Check(timeout); result.Success != test.expected {
+			t.Errorf("DNS healthcheck %v to %v failed: %v", hc, a, result)
+		}
+	}
+
+	// Certificate verification should fail as a TLS error.
+	hc := NewDNSChecker(a.IP, a.Port)
+	hc.TLS = true
+	hc.Question.Name = "www.example.com"
+	hc.Answer = "192.168.0.1"
+	result := hc.Check(timeout)
+	if result.Success {
+		t.Fatalf("DNS healthcheck %v to %v succeeded: %v", hc, a, result)
+	}
+	if !strings.Contains(result.Message, "TLS handshake failed") {
+		t.Errorf("DNS healthcheck message %q does not report TLS failure", result.Message)
+	}
+}
+
+func TestDNSCheckerTLS(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		testDNSCheckerTLS(t, n)
+	}
+}
+
+func TestHTTPCheckerHostIPv6(t *testing.T) {
+	l, a, err := newLocalTCPListener("tcp6")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	var host string
+	srv := newLocalHTTPServer(l)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	})
+	srv.Start()
+	defer srv.Close()
+
+	hc := NewHTTPChecker(a.IP, a.Port)
+	if result := hc.Check(timeout); !result.Success {
+		t.Fatalf("HTTP healthcheck %v to %v failed: %v", hc, a, result)
+	}
+	if want := "[::1]"; host != want {
+		t.Errorf("Got host %q, want %q", host, want)
+	}
+}
+
+// http2OnlyHandler rejects requests that are not made using HTTP/2.
+func http2OnlyHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func testHTTP2Checker(t *testing.T, secure bool) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		testHTTP2CheckerNetwork(t, n, secure)
+	}
+}
+
+func testHTTP2CheckerNetwork(t *testing.T, n string, secure bool) {
+	l, a, err := newLocalTCPListener(n)
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+
+	srv := newLocalHTTPServer(l)
+	if secure {
+		srv.Config.Handler = http2OnlyHandler(srv.Config.Handler)
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+	} else {
+		srv.Config.Handler = h2c.NewHandler(http2OnlyHandler(srv.Config.Handler), &http2.Server{})
+		srv.Start()
+	}
+	defer srv.Close()
+
+	hc := NewHTTPChecker(a.IP, a.Port)
+	hc.Secure = secure
+	hc.TLSVerify = false
+	hc.HTTP2 = true
+	for _, ht := range httpTests {
+		ht.configure(hc)
+		if result := hc.Check(timeout); result.Success != ht.expected {
+			t.Errorf("HTTP/2 healthcheck %v to %v failed: %v", ht, a, result)
+		}
+	}
+
+	// HTTP/1.1 requests should be rejected by the server.
+	httpTests[1].configure(hc)
+	hc.HTTP2 = false
+	if result := hc.Check(timeout); result.Success {
+		t.Errorf("HTTP healthcheck %v to %v succeeded without HTTP/2: %v",
+			httpTests[1], a, result)
+	}
+}
+
+func TestHTTP2Checker(t *testing.T) {
+	testHTTP2Checker(t, false)
+}
+
+func TestHTTP2CheckerSecure(t *testing.T) {
+	testHTTP2Checker(t, true)
+}
+
 type tcpTest struct {
 	send     string
 	receive  string
@@ -266,11 +1098,1022 @@ func TestTCPChecker(t *testing.T) {
 	}
 }
 
-type udpTest struct {
-	send     string
-	receive  string
-	expected bool
-}
+func TestTCPCheckerHalfOpen(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		l, a, err := newLocalTCPListener(n)
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		accepted := make(chan bool, 10)
+		go func() {
+			for {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				c.Close()
+				accepted <- true
+			}
+		}()
+
+		hc := NewTCPChecker(a.IP, a.Port)
+		hc.HalfOpen = true
+		result := hc.Check(timeout)
+		if !result.Success {
+			t.Errorf("TCP healthcheck %v to %v failed: %v", hc, a, result)
+		}
+		if strings.HasPrefix(result.Message, "TCP half-open connect") {
+			select {
+			case <-accepted:
+				t.Errorf("TCP healthcheck %v to %v established a connection", hc, a)
+			case <-time.After(100 * time.Millisecond):
+			}
+		} else {
+			t.Logf("Half-open connect unavailable: %v", result)
+		}
+
+		hc.Send = "foo"
+		if result := hc.Check(timeout); result.Success {
+			t.Errorf("TCP healthcheck %v to %v succeeded: %v", hc, a, result)
+		}
+
+		hc = NewTCPChecker(a.IP, a.Port)
+		hc.HalfOpen = true
+		l.Close()
+		time.Sleep(100 * time.Millisecond)
+		if result := hc.Check(timeout); result.Success {
+			t.Errorf("TCP healthcheck %v to %v succeeded: %v", hc, a, result)
+		}
+	}
+}
+
+func TestTCPChecksum(t *testing.T) {
+	for _, test := range []struct {
+		src, dst string
+	}{
+		{"192.168.0.1", "192.168.0.2"},
+		{"2001:db8::1", "2001:db8::2"},
+	} {
+		src, dst := net.ParseIP(test.src), net.ParseIP(test.dst)
+		seg := &tcpSegment{
+			srcPort: 40000,
+			dstPort: 80,
+			seq:     0x01020304,
+			flags:   tcpFlagSYN,
+			window:  halfOpenWindow,
+		}
+		b := seg.marshal(src, dst)
+		if cs := tcpChecksum(src, dst, b); cs != 0 {
+			t.Errorf("TCP segment from %v to %v has invalid checksum (%#04x)", src, dst, cs)
+		}
+		got, err := parseTCPSegment(b)
+		if err != nil {
+			t.Fatalf("Failed to parse TCP segment: %v", err)
+		}
+		if *got != *seg {
+			t.Errorf("Parsed TCP segment %+v, want %+v", got, seg)
+		}
+	}
+}
+
+type tcpExpectTest struct {
+	send     string
+	expect   string
+	expected bool
+}
+
+var tcpExpectTests = []tcpExpectTest{
+	{"", "", true},
+	{"", "foo", true},
+	{"foo", "^foo$", true},
+	{"foo\n", "^fo+\n", true},
+	{"foo", "bar", false},
+	{"foo", "(", false},
+}
+
+func testTCPCheckerExpect(t *testing.T, n string) {
+	l, a, err := newLocalTCPListener(n)
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	defer l.Close()
+	go tcpEchoHandler(l)
+
+	for _, tt := range tcpExpectTests {
+		hc := NewTCPChecker(a.IP, a.Port)
+		hc.Send = tt.send
+		hc.Expect = tt.expect
+		hc.ReadTimeout = 100 * time.Millisecond
+		if result := hc.Check(timeout); result.Success != tt.expected {
+			t.Errorf("TCP healthcheck %v to %v failed: %v", tt, a, result)
+		}
+	}
+
+	// A non-matching response should include a truncated snippet.
+	hc := NewTCPChecker(a.IP, a.Port)
+	hc.Send = strings.Repeat("x", 2*tcpSnippetLen)
+	hc.Expect = "^y"
+	hc.ReadTimeout = 100 * time.Millisecond
+	result := hc.Check(timeout)
+	if result.Success {
+		t.Fatalf("TCP healthcheck %v to %v succeeded: %v", hc, a, result)
+	}
+	want := fmt.Sprintf("%q", strings.Repeat("x", tcpSnippetLen)+"...")
+	if !strings.Contains(result.Message, want) {
+		t.Errorf("TCP healthcheck message %q does not contain %s", result.Message, want)
+	}
+}
+
+func TestTCPCheckerExpect(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		testTCPCheckerExpect(t, n)
+	}
+}
+
+// mysqlServer is a minimal MySQL server that supports mysql_native_password
+// authentication, COM_QUERY and COM_QUIT.
+type mysqlServer struct {
+	user       string
+	password   string
+	readOnly   string
+	authSwitch bool
+	quit       chan bool
+}
+
+func (s *mysqlServer) serve(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+func (s *mysqlServer) handle(c net.Conn) {
+	defer c.Close()
+	mc := &mysqlConn{conn: c, r: bufio.NewReader(c)}
+	nonce := []byte("abcdefghij0123456789")
+	plugin := mysqlNativePassword
+	if s.authSwitch {
+		plugin = mysqlCachingSHA2Password
+	}
+	caps := uint32(mysqlClientLongPassword | mysqlClientProtocol41 |
+		mysqlClientSecureConnection | mysqlClientPluginAuth)
+	hs := []byte("\x0a5.7.99-test\x00\x01\x00\x00\x00")
+	hs = append(hs, nonce[:8]...)
+	hs = append(hs, 0, byte(caps), byte(caps>>8), mysqlCharsetUTF8, 0x02, 0x00,
+		byte(caps>>16), byte(caps>>24), byte(len(nonce)+1))
+	hs = append(hs, make([]byte, 10)...)
+	hs = append(hs, nonce[8:]...)
+	hs = append(hs, 0)
+	hs = append(hs, plugin...)
+	hs = append(hs, 0)
+	if err := mc.writePacket(hs); err != nil {
+		return
+	}
+
+	pkt, err := mc.readPacket()
+	if err != nil || len(pkt) < 32 {
+		return
+	}
+	user, rest, _ := nulString(pkt[32:])
+	auth := rest[1 : 1+int(rest[0])]
+	if s.authSwitch {
+		if err := mc.writePacket(append([]byte("\xfemysql_native_password\x00"), append(nonce, 0)...)); err != nil {
+			return
+		}
+		if auth, err = mc.readPacket(); err != nil {
+			return
+		}
+	}
+	want, _ := mysqlScramble(mysqlNativePassword, s.password, nonce)
+	if user != s.user || !bytes.Equal(auth, want) {
+		mc.writePacket([]byte("\xff\x15\x04#28000Access denied"))
+		return
+	}
+	if err := mc.writePacket([]byte{mysqlOK, 0, 0, 2, 0, 0, 0}); err != nil {
+		return
+	}
+
+	for {
+		mc.seq = 0
+		pkt, err := mc.readPacket()
+		if err != nil || len(pkt) == 0 {
+			return
+		}
+		switch pkt[0] {
+		case mysqlComQuit:
+			s.quit <- true
+			return
+		case mysqlComQuery:
+			var value string
+			switch string(pkt[1:]) {
+			case "SELECT 1":
+				value = "1"
+			case mysqlReadOnlyQuery:
+				value = s.readOnly
+			default:
+				mc.writePacket([]byte("\xff\x28\x04#42000Syntax error"))
+				continue
+			}
+			eof := []byte{mysqlEOF, 0, 0, 2, 0}
+			for _, p := range [][]byte{
+				{1},
+				[]byte("\x03def\x00\x00\x00\x01v\x00\x0c\x3f\x00\x01\x00\x00\x00\x08\x81\x00\x00\x00\x00"),
+				eof,
+				append([]byte{byte(len(value))}, value...),
+				eof,
+			} {
+				if err := mc.writePacket(p); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func TestMySQLChecker(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		for _, test := range []struct {
+			user       string
+			password   string
+			query      string
+			writable   bool
+			readOnly   string
+			authSwitch bool
+			expected   bool
+		}{
+			{"", "", "", false, "0", false, true},
+			{"seesaw", "secret", "", false, "0", false, true},
+			{"seesaw", "secret", "SELECT 1", false, "0", false, true},
+			{"seesaw", "secret", "SELECT 1", true, "0", false, true},
+			{"seesaw", "secret", "SELECT 1", true, "1", false, false},
+			{"seesaw", "secret", "SELECT", false, "0", false, false},
+			{"seesaw", "wrong", "", false, "0", false, false},
+			{"other", "secret", "", false, "0", false, false},
+			{"seesaw", "secret", "SELECT 1", true, "0", true, true},
+			{"seesaw", "wrong", "", false, "0", true, false},
+		} {
+			l, a, err := newLocalTCPListener(n)
+			if err != nil {
+				t.Fatalf("Failed to get TCP listener: %v", err)
+			}
+			srv := &mysqlServer{
+				user:       "seesaw",
+				password:   "secret",
+				readOnly:   test.readOnly,
+				authSwitch: test.authSwitch,
+				quit:       make(chan bool, 1),
+			}
+			go srv.serve(l)
+
+			hc := NewMySQLChecker(a.IP, a.Port)
+			hc.Username = test.user
+			hc.Password = test.password
+			hc.Query = test.query
+			hc.ExpectWritable = test.writable
+			result := hc.Check(timeout)
+			if result.Success != test.expected {
+				t.Errorf("MySQL healthcheck %v to %v got success %v, want %v: %v",
+					hc, a, result.Success, test.expected, result)
+			}
+			if result.Success && test.user != "" {
+				select {
+				case <-srv.quit:
+				case <-time.After(timeout):
+					t.Errorf("MySQL healthcheck %v to %v did not send COM_QUIT", hc, a)
+				}
+			}
+			l.Close()
+		}
+	}
+}
+
+func TestMySQLHandshakeErr(t *testing.T) {
+	l, a, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		mc := &mysqlConn{conn: c, r: bufio.NewReader(c)}
+		mc.writePacket([]byte("\xff\x10\x04Too many connections"))
+	}()
+	hc := NewMySQLChecker(a.IP, a.Port)
+	result := hc.Check(timeout)
+	if result.Success {
+		t.Fatalf("MySQL healthcheck %v to %v succeeded: %v", hc, a, result)
+	}
+	if !strings.Contains(result.String(), "Too many connections") {
+		t.Errorf("MySQL healthcheck %v to %v got %q, want MySQL error", hc, a, result)
+	}
+}
+
+// proxyConn is a connection from which a PROXY protocol header has been
+// read.
+type proxyConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// readProxyHeader reads a PROXY protocol header from the given reader and
+// returns a summary of its contents.
+func readProxyHeader(r *bufio.Reader) (string, error) {
+	sig, err := r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(sig, proxyV2Signature) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(line, "\r\n"), nil
+	}
+	hdr := make([]byte, len(proxyV2Signature)+4)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return "", err
+	}
+	data := make([]byte, int(hdr[14])<<8|int(hdr[15]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", err
+	}
+	if hdr[12] != proxyV2VersionCommand {
+		return "", fmt.Errorf("unexpected version/command %x", hdr[12])
+	}
+	n := net.IPv4len
+	if hdr[13] == proxyV2TCPv6 {
+		n = net.IPv6len
+	}
+	if len(data) < 2*n+4 {
+		return "", fmt.Errorf("short PROXY header")
+	}
+	src, dst := net.IP(data[:n]), net.IP(data[n:2*n])
+	ports := data[2*n:]
+	summary := fmt.Sprintf("PROXYv2 %x %s %s %d %d", hdr[13], src, dst,
+		int(ports[0])<<8|int(ports[1]), int(ports[2])<<8|int(ports[3]))
+	for tlv := data[2*n+4:]; len(tlv) >= 3; {
+		l := int(tlv[1])<<8 | int(tlv[2])
+		if tlv[0] == proxyV2TypeAuthority {
+			summary += fmt.Sprintf(" authority %s", tlv[3:3+l])
+		}
+		tlv = tlv[3+l:]
+	}
+	return summary, nil
+}
+
+// proxyHeaderInfo contains a summary of a received PROXY protocol header, along
+// with the actual remote address of the connection.
+type proxyHeaderInfo struct {
+	header string
+	remote *net.TCPAddr
+}
+
+// proxyListener is a listener that reads a PROXY protocol header from each
+// accepted connection, sending the header information on the given channel.
+type proxyListener struct {
+	net.Listener
+	headers chan proxyHeaderInfo
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(c)
+	hdr, err := readProxyHeader(r)
+	if err != nil {
+		hdr = fmt.Sprintf("error: %v", err)
+	}
+	l.headers <- proxyHeaderInfo{hdr, c.RemoteAddr().(*net.TCPAddr)}
+	return &proxyConn{c, r}, nil
+}
+
+func newProxyListener(n string) (*proxyListener, *net.TCPAddr, error) {
+	l, a, err := newLocalTCPListener(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &proxyListener{l, make(chan proxyHeaderInfo, 10)}, a, nil
+}
+
+// wantProxyHeader returns the expected summary of a PROXY protocol header.
+func wantProxyHeader(version ProxyProtocol, src net.IP, remote, dst *net.TCPAddr, authority string) string {
+	if src == nil {
+		src = remote.IP
+	}
+	if version == ProxyProtocolV1 {
+		family := "TCP4"
+		if dst.IP.To4() == nil {
+			family = "TCP6"
+		}
+		return fmt.Sprintf("PROXY %s %s %s %d %d", family, src, dst.IP, remote.Port, dst.Port)
+	}
+	family := proxyV2TCPv4
+	if dst.IP.To4() == nil {
+		family = proxyV2TCPv6
+	}
+	want := fmt.Sprintf("PROXYv2 %x %s %s %d %d", family, src, dst.IP, remote.Port, dst.Port)
+	if authority != "" {
+		want += " authority " + authority
+	}
+	return want
+}
+
+var proxyProtocolTests = []struct {
+	version   ProxyProtocol
+	source    string
+	authority string
+}{
+	{ProxyProtocolV1, "", ""},
+	{ProxyProtocolV1, "192.0.2.1", ""},
+	{ProxyProtocolV1, "2001:db8::1", ""},
+	{ProxyProtocolV2, "", ""},
+	{ProxyProtocolV2, "192.0.2.1", "www.example.com"},
+	{ProxyProtocolV2, "2001:db8::1", "www.example.com"},
+}
+
+func testHTTPCheckerProxyProtocol(t *testing.T, n string) {
+	l, a, err := newProxyListener(n)
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := newLocalHTTPServer(l)
+	srv.Start()
+	defer srv.Close()
+
+	for _, test := range proxyProtocolTests {
+		source := net.ParseIP(test.source)
+		if source != nil && (source.To4() == nil) != (a.IP.To4() == nil) {
+			continue
+		}
+		hc := NewHTTPChecker(a.IP, a.Port)
+		hc.Request = "/healthz"
+		hc.Response = "ok\n"
+		hc.Host = test.authority
+		hc.ProxyProtocol = test.version
+		hc.ProxySourceIP = source
+		if result := hc.Check(timeout); !result.Success {
+			t.Errorf("HTTP healthcheck %v to %v failed: %v", hc, a, result)
+		}
+		info := <-l.headers
+		want := wantProxyHeader(test.version, source, info.remote, a, test.authority)
+		if info.header != want {
+			t.Errorf("Got PROXY header %q, want %q", info.header, want)
+		}
+	}
+}
+
+func TestHTTPCheckerProxyProtocol(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		testHTTPCheckerProxyProtocol(t, n)
+	}
+}
+
+func TestTCPCheckerProxyProtocol(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		l, a, err := newProxyListener(n)
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		go func() {
+			for {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					defer c.Close()
+					io.Copy(c, c)
+				}()
+			}
+		}()
+
+		for _, test := range proxyProtocolTests {
+			source := net.ParseIP(test.source)
+			if source != nil && (source.To4() == nil) != (a.IP.To4() == nil) {
+				continue
+			}
+			hc := NewTCPChecker(a.IP, a.Port)
+			hc.Send = "foo"
+			hc.Receive = "foo"
+			hc.TLSServerName = test.authority
+			hc.ProxyProtocol = test.version
+			hc.ProxySourceIP = source
+			if result := hc.Check(timeout); !result.Success {
+				t.Errorf("TCP healthcheck %v to %v failed: %v", hc, a, result)
+			}
+			info := <-l.headers
+			want := wantProxyHeader(test.version, source, info.remote, a, test.authority)
+			if info.header != want {
+				t.Errorf("Got PROXY header %q, want %q", info.header, want)
+			}
+		}
+
+		// Mismatched address families cannot be advertised.
+		hc := NewTCPChecker(a.IP, a.Port)
+		hc.ProxyProtocol = ProxyProtocolV1
+		hc.ProxySourceIP = net.ParseIP("2001:db8::1")
+		if a.IP.To4() == nil {
+			hc.ProxySourceIP = net.ParseIP("192.0.2.1")
+		}
+		if result := hc.Check(timeout); result.Success {
+			t.Errorf("TCP healthcheck %v to %v succeeded: %v", hc, a, result)
+		}
+		<-l.headers
+		l.Close()
+	}
+}
+
+// pipeConns copies data between the given connections until either is
+// closed.
+func pipeConns(a, b net.Conn) {
+	go func() {
+		io.Copy(a, b)
+		a.Close()
+	}()
+	io.Copy(b, a)
+	b.Close()
+}
+
+// newHTTPConnectProxy starts an HTTP proxy that supports the CONNECT method,
+// requiring the given credentials if non-empty.
+func newHTTPConnectProxy(t *testing.T, credentials string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "CONNECT" {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+		if credentials != "" && r.Header.Get("Proxy-Authorization") != auth {
+			http.Error(w, "", http.StatusProxyAuthRequired)
+			return
+		}
+		backend, err := net.DialTimeout("tcp", r.Host, timeout)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Failed to hijack proxy connection: %v", err)
+			backend.Close()
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		pipeConns(conn, backend)
+	}))
+	return srv
+}
+
+// socks5Proxy is a minimal SOCKS5 proxy that supports the CONNECT command
+// for IPv4 destinations, with optional username/password authentication.
+type socks5Proxy struct {
+	user     string
+	password string
+}
+
+func (p *socks5Proxy) serve(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(c)
+	}
+}
+
+func (p *socks5Proxy) handle(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(r, make([]byte, hdr[1])); err != nil {
+		return
+	}
+	if p.user == "" {
+		c.Write([]byte{5, 0})
+	} else {
+		c.Write([]byte{5, 2})
+		// Version, username length, username, password length, password.
+		b := make([]byte, 2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return
+		}
+		user := make([]byte, b[1])
+		if _, err := io.ReadFull(r, user); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(r, b[:1]); err != nil {
+			return
+		}
+		password := make([]byte, b[0])
+		if _, err := io.ReadFull(r, password); err != nil {
+			return
+		}
+		if string(user) != p.user || string(password) != p.password {
+			c.Write([]byte{1, 1})
+			return
+		}
+		c.Write([]byte{1, 0})
+	}
+
+	// Version, command, reserved, IPv4 address type, address and port.
+	req := make([]byte, 10)
+	if _, err := io.ReadFull(r, req); err != nil || req[1] != 1 || req[3] != 1 {
+		c.Write([]byte{5, 7, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	addr := &net.TCPAddr{IP: net.IP(req[4:8]), Port: int(req[8])<<8 | int(req[9])}
+	backend, err := net.DialTimeout("tcp", addr.String(), timeout)
+	if err != nil {
+		c.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	pipeConns(&bufferedConn{c, r}, backend)
+}
+
+func TestEgressProxy(t *testing.T) {
+	l, backend, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	srv := newLocalHTTPServer(l)
+	srv.Start()
+	defer srv.Close()
+
+	// An address that nothing is listening on.
+	l, closed, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	l.Close()
+
+	httpProxy := newHTTPConnectProxy(t, "user:secret")
+	defer httpProxy.Close()
+
+	l, socksAddr, err := newLocalTCPListener("tcp4")
+	if err != nil {
+		t.Fatalf("Failed to get TCP listener: %v", err)
+	}
+	defer l.Close()
+	go (&socks5Proxy{user: "user", password: "secret"}).serve(l)
+
+	httpProxyURL := "http://user:secret@" + httpProxy.Listener.Addr().String()
+	socksProxyURL := "socks5://user:secret@" + socksAddr.String()
+
+	tests := []struct {
+		desc     string
+		proxyURL string
+		target   *net.TCPAddr
+		err      string
+	}{
+		{"HTTP CONNECT", httpProxyURL, backend, ""},
+		{"HTTP CONNECT bad credentials", "http://user:wrong@" + httpProxy.Listener.Addr().String(), backend, "failed to connect to proxy"},
+		{"HTTP CONNECT backend down", httpProxyURL, closed, "unreachable via proxy"},
+		{"HTTP CONNECT proxy down", "http://user:secret@" + closed.String(), backend, "failed to connect to proxy"},
+		{"SOCKS5", socksProxyURL, backend, ""},
+		{"SOCKS5 bad credentials", "socks5://user:wrong@" + socksAddr.String(), backend, "failed to connect to proxy"},
+		{"SOCKS5 backend down", socksProxyURL, closed, "unreachable via proxy"},
+		{"SOCKS5 proxy down", "socks5://" + closed.String(), backend, "failed to connect to proxy"},
+		{"unsupported scheme", "ftp://" + socksAddr.String(), backend, "unknown scheme"},
+	}
+	for _, test := range tests {
+		tc := NewTCPChecker(test.target.IP, test.target.Port)
+		tc.ProxyURL = test.proxyURL
+		hc := NewHTTPChecker(test.target.IP, test.target.Port)
+		hc.Request = "/healthz"
+		hc.Response = "ok\n"
+		hc.ProxyURL = test.proxyURL
+		for _, c := range []Checker{tc, hc} {
+			if strings.Contains(c.String(), "secret") {
+				t.Errorf("Test %q: healthcheck %v contains proxy password", test.desc, c)
+			}
+			result := c.Check(timeout)
+			if want := test.err == ""; result.Success != want {
+				t.Errorf("Test %q: healthcheck %v got success %t, want %t: %v",
+					test.desc, c, result.Success, want, result)
+			}
+			if test.err != "" && (result.Err == nil || !strings.Contains(result.Err.Error(), test.err)) {
+				t.Errorf("Test %q: healthcheck %v got error %v, want error containing %q",
+					test.desc, c, result.Err, test.err)
+			}
+		}
+	}
+}
+
+// smtpServer is a minimal SMTP server that supports EHLO, STARTTLS and QUIT.
+type smtpServer struct {
+	greeting  string
+	tlsConfig *tls.Config
+	hostnames chan string
+}
+
+func (s *smtpServer) serve(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+func (s *smtpServer) handle(c net.Conn) {
+	defer c.Close()
+	tp := textproto.NewConn(c)
+	tp.PrintfLine("%s", s.greeting)
+	secure := false
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.Fields(line)
+		if len(cmd) == 0 {
+			tp.PrintfLine("500 Empty command")
+			continue
+		}
+		switch strings.ToUpper(cmd[0]) {
+		case "EHLO":
+			if len(cmd) > 1 {
+				s.hostnames <- cmd[1]
+			}
+			if s.tlsConfig != nil && !secure {
+				tp.PrintfLine("250-mx.example.com")
+				tp.PrintfLine("250 STARTTLS")
+			} else {
+				tp.PrintfLine("250 mx.example.com")
+			}
+		case "STARTTLS":
+			if s.tlsConfig == nil || secure {
+				tp.PrintfLine("502 Not implemented")
+				continue
+			}
+			tp.PrintfLine("220 Ready to start TLS")
+			tc := tls.Server(c, s.tlsConfig)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			tp = textproto.NewConn(tc)
+			secure = true
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("500 Unknown command")
+		}
+	}
+}
+
+func testSMTPChecker(t *testing.T, n string, tlsConfig *tls.Config) {
+	for _, test := range []struct {
+		greeting string
+		tls      bool
+		starttls bool
+		ehloCode int
+		expected bool
+		message  string
+	}{
+		{"220 mx.example.com ESMTP", false, false, 0, true, "mx.example.com ESMTP"},
+		{"554 mx.example.com No service", false, false, 0, false, "554"},
+		{"220 mx.example.com ESMTP", false, false, 251, false, ""},
+		{"220 mx.example.com ESMTP", false, true, 0, false, "STARTTLS not supported"},
+		{"220 mx.example.com ESMTP", true, true, 0, true, ""},
+		{"220 mx.example.com ESMTP", true, false, 0, true, ""},
+	} {
+		l, a, err := newLocalTCPListener(n)
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		srv := &smtpServer{greeting: test.greeting, hostnames: make(chan string, 2)}
+		if test.tls {
+			srv.tlsConfig = tlsConfig
+		}
+		go srv.serve(l)
+
+		hc := NewSMTPChecker(a.IP, a.Port)
+		hc.Hostname = "check.example.com"
+		hc.StartTLS = test.starttls
+		hc.TLSVerify = false
+		if test.ehloCode != 0 {
+			hc.EHLOCode = test.ehloCode
+		}
+		result := hc.Check(timeout)
+		l.Close()
+		if result.Success != test.expected {
+			t.Errorf("SMTP healthcheck %v to %v got success %v, want %v: %v", hc, a, result.Success, test.expected, result)
+			continue
+		}
+		if !strings.Contains(result.String(), test.message) {
+			t.Errorf("SMTP healthcheck %v to %v got message %q, want %q", hc, a, result, test.message)
+		}
+		if !test.expected {
+			continue
+		}
+		ehlos := 1
+		if test.starttls {
+			ehlos = 2
+		}
+		for i := 0; i < ehlos; i++ {
+			if got := <-srv.hostnames; got != hc.Hostname {
+				t.Errorf("SMTP server got EHLO %q, want %q", got, hc.Hostname)
+			}
+		}
+	}
+}
+
+func TestSMTPChecker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "healthcheck")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile, err := writeTestCert(dir, "server")
+	if err != nil {
+		t.Fatalf("Failed to create server certificate: %v", err)
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	for _, n := range []string{"tcp4", "tcp6"} {
+		testSMTPChecker(t, n, tlsConfig)
+	}
+}
+
+// redisServer is a minimal Redis server that supports AUTH, SELECT, PING and
+// INFO replication.
+type redisServer struct {
+	password string
+	role     string
+	pong     string
+}
+
+func (s *redisServer) serve(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+// readCommand reads a RESP encoded command.
+func (s *redisServer) readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var l int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &l); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, l+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:l])
+	}
+	return args, nil
+}
+
+func (s *redisServer) handle(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	authed := s.password == ""
+	for {
+		args, err := s.readCommand(r)
+		if err != nil || len(args) == 0 {
+			return
+		}
+		var reply string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			if len(args) == 2 && args[1] == s.password {
+				authed = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT":
+			if len(args) == 2 && args[1] == "1" {
+				reply = "+OK\r\n"
+			} else {
+				reply = "-ERR DB index is out of range\r\n"
+			}
+		case cmd == "PING":
+			reply = fmt.Sprintf("+%s\r\n", s.pong)
+		case cmd == "INFO":
+			info := fmt.Sprintf("# Replication\r\nrole:%s\r\nconnected_slaves:0\r\n", s.role)
+			reply = fmt.Sprintf("$%d\r\n%s\r\n", len(info), info)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(c, reply); err != nil {
+			return
+		}
+	}
+}
+
+func TestRedisChecker(t *testing.T) {
+	for _, n := range []string{"tcp4", "tcp6"} {
+		l, a, err := newLocalTCPListener(n)
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		srv := &redisServer{password: "secret", role: "slave", pong: "PONG"}
+		go srv.serve(l)
+
+		for _, test := range []struct {
+			password string
+			db       int
+			role     string
+			expected bool
+		}{
+			{"secret", 0, "", true},
+			{"secret", 1, "", true},
+			{"secret", 1, "slave", true},
+			{"secret", 1, "master", false},
+			{"secret", 2, "", false},
+			{"wrong", 0, "", false},
+			{"", 0, "", false},
+		} {
+			hc := NewRedisChecker(a.IP, a.Port)
+			hc.Password = test.password
+			hc.DB = test.db
+			hc.Role = test.role
+			if result := hc.Check(timeout); result.Success != test.expected {
+				t.Errorf("Redis healthcheck %v to %v got success %v, want %v: %v",
+					hc, a, result.Success, test.expected, result)
+			}
+		}
+
+		l.Close()
+
+		// An unexpected PING reply should fail.
+		l, a, err = newLocalTCPListener(n)
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		go (&redisServer{pong: "PANG"}).serve(l)
+		hc := NewRedisChecker(a.IP, a.Port)
+		if result := hc.Check(timeout); result.Success {
+			t.Errorf("Redis healthcheck %v to %v succeeded: %v", hc, a, result)
+		}
+		l.Close()
+	}
+}
+
+func TestRedisReply(t *testing.T) {
+	for _, test := range []struct {
+		reply string
+		want  string
+		ok    bool
+	}{
+		{"+PONG\r\n", "PONG", true},
+		{":42\r\n", "42", true},
+		{"$5\r\nhello\r\n", "hello", true},
+		{"$0\r\n\r\n", "", true},
+		{"-ERR failed\r\n", "", false},
+		{"$-1\r\n", "", false},
+		{"$5\r\nhel", "", false},
+		{"$x\r\n", "", false},
+		{"+PONG\n", "", false},
+		{"*1\r\n$4\r\nPONG\r\n", "", false},
+	} {
+		got, err := readRedisReply(bufio.NewReader(strings.NewReader(test.reply)))
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("readRedisReply(%q) = %q, %v; want %q, ok %v", test.reply, got, err, test.want, test.ok)
+		}
+	}
+}
+
+type udpTest struct {
+	send     string
+	receive  string
+	expected bool
+}
 
 func (ut udpTest) configure(hc *UDPChecker) {
 	hc.Send = ut.send
@@ -331,9 +2174,276 @@ func TestUDPChecker(t *testing.T) {
 	}
 }
 
+func testUDPCheckerExpect(t *testing.T, n string) {
+	c, a, err := newLocalUDPConn(n)
+	if err != nil {
+		t.Fatalf("Failed to get UDPConn: %v", err)
+	}
+	defer c.Close()
+	go udpEchoHandler(c)
+
+	for _, test := range []struct {
+		send     string
+		receive  string
+		expect   string
+		expected bool
+	}{
+		{"foo", "", "^foo$", true},
+		{"foo", "fo", "^foo$", true},
+		{"foo", "", "^fo+$", true},
+		{"\x00\x01\x02", "", "^\x00\x01", true},
+		{"foo", "", "bar", false},
+		{"foo", "bar", "foo", false},
+		{"foo", "", "(", false},
+	} {
+		hc := NewUDPChecker(a.IP, a.Port)
+		hc.Send = test.send
+		hc.Receive = test.receive
+		hc.Expect = test.expect
+		if result := hc.Check(timeout); result.Success != test.expected {
+			t.Errorf("UDP healthcheck %v to %v failed: %v", hc, a, result)
+		}
+	}
+
+	// A lack of response is a failure, once the read timeout expires.
+	s, sa, err := newLocalUDPConn(n)
+	if err != nil {
+		t.Fatalf("Failed to get UDPConn: %v", err)
+	}
+	defer s.Close()
+	hc := NewUDPChecker(sa.IP, sa.Port)
+	hc.Send = "foo"
+	hc.Expect = "foo"
+	hc.ReadTimeout = 50 * time.Millisecond
+	start := time.Now()
+	if result := hc.Check(timeout); result.Success {
+		t.Errorf("UDP healthcheck %v to %v succeeded: %v", hc, sa, result)
+	}
+	if d := time.Since(start); d >= timeout {
+		t.Errorf("UDP healthcheck took %v, want less than %v", d, timeout)
+	}
+}
+
+func TestUDPCheckerExpect(t *testing.T) {
+	for _, n := range []string{"udp4", "udp6"} {
+		testUDPCheckerExpect(t, n)
+	}
+}
+
+// ntpHandler responds to NTP requests on the given connection, using the
+// given function to modify each response.
+func ntpHandler(c *net.UDPConn, modify func(resp []byte) []byte) {
+	req := make([]byte, 512)
+	for {
+		n, addr, err := c.ReadFromUDP(req)
+		if err != nil {
+			return
+		}
+		if n < ntpPacketSize {
+			continue
+		}
+		now := ntpTimestamp(time.Now())
+		resp := make([]byte, ntpPacketSize)
+		resp[0] = ntpVersion<<3 | ntpModeServer
+		resp[1] = 2
+		copy(resp[24:32], req[40:48])
+		binary.BigEndian.PutUint64(resp[32:40], now)
+		binary.BigEndian.PutUint64(resp[40:48], now)
+		if modify != nil {
+			resp = modify(resp)
+		}
+		c.WriteToUDP(resp, addr)
+	}
+}
+
+func TestNTPChecker(t *testing.T) {
+	for _, test := range []struct {
+		desc       string
+		modify     func(resp []byte) []byte
+		minStratum int
+		maxStratum int
+		expected   bool
+	}{
+		{"synchronised", nil, 1, 4, true},
+		{"stratum too high", func(b []byte) []byte { b[1] = 5; return b }, 1, 4, false},
+		{"stratum too low", func(b []byte) []byte { b[1] = 1; return b }, 2, 4, false},
+		{"unsynchronised", func(b []byte) []byte { b[1] = 0; return b }, 1, 15, false},
+		{"leap alarm", func(b []byte) []byte { b[0] |= ntpLeapAlarm << 6; return b }, 1, 4, false},
+		{"leap second", func(b []byte) []byte { b[0] |= 1 << 6; return b }, 1, 4, true},
+		{"client mode", func(b []byte) []byte { b[0] = ntpVersion<<3 | ntpModeClient; return b }, 1, 4, false},
+		{"originate mismatch", func(b []byte) []byte { b[31]++; return b }, 1, 4, false},
+		{"short packet", func(b []byte) []byte { return b[:40] }, 1, 4, false},
+	} {
+		c, a, err := newLocalUDPConn("udp4")
+		if err != nil {
+			t.Fatalf("Failed to get UDPConn: %v", err)
+		}
+		go ntpHandler(c, test.modify)
+
+		hc := NewNTPChecker(a.IP, a.Port)
+		hc.MinStratum = test.minStratum
+		hc.MaxStratum = test.maxStratum
+		result := hc.Check(timeout)
+		if result.Success != test.expected {
+			t.Errorf("NTP healthcheck %v (%s) = %v, want success %v", hc, test.desc, result, test.expected)
+		}
+		if result.Err == nil && !strings.Contains(result.Message, "stratum") {
+			t.Errorf("NTP healthcheck %v (%s) message %q does not report stratum", hc, test.desc, result.Message)
+		}
+		c.Close()
+	}
+
+	// A lack of response is a failure.
+	c, a, err := newLocalUDPConn("udp4")
+	if err != nil {
+		t.Fatalf("Failed to get UDPConn: %v", err)
+	}
+	defer c.Close()
+	hc := NewNTPChecker(a.IP, a.Port)
+	if result := hc.Check(100 * time.Millisecond); result.Success {
+		t.Errorf("NTP healthcheck %v to %v succeeded: %v", hc, a, result)
+	}
+}
+
+func TestSourceIP(t *testing.T) {
+	for _, test := range []struct {
+		network  string
+		source   string
+		nonlocal string
+		other    string
+	}{
+		{"4", "127.0.0.1", "192.0.2.1", "::1"},
+		{"6", "::1", "2001:db8::1", "127.0.0.1"},
+	} {
+		l, a, err := newLocalTCPListener("tcp" + test.network)
+		if err != nil {
+			t.Fatalf("Failed to get TCP listener: %v", err)
+		}
+		defer l.Close()
+		go tcpEchoHandler(l)
+
+		c, ua, err := newLocalUDPConn("udp" + test.network)
+		if err != nil {
+			t.Fatalf("Failed to get UDPConn: %v", err)
+		}
+		defer c.Close()
+		go udpEchoHandler(c)
+
+		for _, st := range []struct {
+			ip      string
+			iface   string
+			message string
+		}{
+			{test.source, "", ""},
+			{test.nonlocal, "", "is not configured locally"},
+			{test.other, "", "different address families"},
+			{test.source, "nonexistent0", "invalid source interface"},
+		} {
+			ip := net.ParseIP(st.ip)
+			tcp := NewTCPChecker(a.IP, a.Port)
+			tcp.SourceIP, tcp.SourceInterface = ip, st.iface
+			udp := NewUDPChecker(ua.IP, ua.Port)
+			udp.SourceIP, udp.SourceInterface = ip, st.iface
+			udp.Send, udp.Receive = "foo", "foo"
+
+			for _, hc := range []Checker{tcp, udp} {
+				result := hc.Check(timeout)
+				if result.Success != (st.message == "") {
+					t.Errorf("Healthcheck %v got success %v: %v", hc, result.Success, result)
+				}
+				if !strings.Contains(result.String(), st.message) {
+					t.Errorf("Healthcheck %v result %q does not contain %q", hc, result, st.message)
+				}
+			}
+		}
+	}
+}
+
+func TestExecChecker(t *testing.T) {
+	for _, test := range []struct {
+		script   string
+		expected bool
+		message  string
+	}{
+		{"exit 0", true, ""},
+		{`test "$0:$1" = "192.168.0.1:80"`, true, ""},
+		{`test "$SEESAW_HC_IP:$SEESAW_HC_PORT" = "192.168.0.1:80"`, true, ""},
+		{"echo broken; exit 1", false, "broken"},
+		{"sleep 5", false, "killed after"},
+	} {
+		hc := NewExecChecker(net.ParseIP("192.168.0.1"), 80)
+		hc.Command = "/bin/sh"
+		hc.Args = []string{"-c", test.script}
+		result := hc.Check(500 * time.Millisecond)
+		if result.Success != test.expected {
+			t.Errorf("Exec healthcheck %v failed: %v", hc, result)
+		}
+		if !strings.Contains(result.Message, test.message) {
+			t.Errorf("Exec healthcheck message %q does not contain %q", result.Message, test.message)
+		}
+	}
+}
+
+func TestPingCheckerIPv6(t *testing.T) {
+	for _, test := range []struct {
+		ip       string
+		network  string
+		echoType byte
+	}{
+		{"192.168.0.1", "ip4:icmp", ICMP4_ECHO_REQUEST},
+		{"2001:db8::1", "ip6:ipv6-icmp", ICMP6_ECHO_REQUEST},
+	} {
+		hc := NewPingChecker(net.ParseIP(test.ip))
+		if got := hc.network(); got != test.network {
+			t.Errorf("Ping healthcheck to %s got network %q, want %q", test.ip, got, test.network)
+		}
+		echo := newICMPEchoRequest(hc.Proto, hc.ID, 1, 64, []byte("Healthcheck"))
+		if len(echo) == 0 || echo[0] != test.echoType {
+			t.Errorf("Ping healthcheck to %s got echo request %v, want type %d", test.ip, echo, test.echoType)
+		}
+	}
+}
+
+func TestPingCheckerCount(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "::1"} {
+		hc := NewPingChecker(net.ParseIP(ip))
+		hc.Count = 3
+		hc.EchoInterval = 10 * time.Millisecond
+		result := hc.Check(timeout)
+		if strings.Contains(result.Message, "raw sockets unavailable") {
+			t.Skipf("Ping healthcheck requires raw sockets: %v", result)
+		}
+		if !result.Success {
+			t.Errorf("Ping healthcheck %v failed: %v", hc, result)
+			continue
+		}
+		if !strings.Contains(result.Message, "3/3 replies received, 0% loss, average RTT") {
+			t.Errorf("Ping healthcheck %v got message %q, want replies and RTT", hc, result.Message)
+		}
+	}
+}
+
+func TestPingLoss(t *testing.T) {
+	for _, test := range []struct {
+		sent, received int
+		loss           float64
+	}{
+		{0, 0, 0},
+		{5, 5, 0},
+		{5, 4, 20},
+		{4, 1, 75},
+		{3, 0, 100},
+	} {
+		if got := pingLoss(test.sent, test.received); got != test.loss {
+			t.Errorf("pingLoss(%d, %d) = %v, want %v", test.sent, test.received, got, test.loss)
+		}
+	}
+}
+
 type fakeChecker struct {
 	succeed bool
 	sleepy  bool
+	latency time.Duration
 }
 
 func (hc *fakeChecker) String() string {
@@ -344,7 +2454,7 @@ func (hc *fakeChecker) Check(timeout time.Duration) *Result {
 	if hc.sleepy {
 		time.Sleep(500 * time.Millisecond)
 	}
-	return &Result{Success: hc.succeed}
+	return &Result{Success: hc.succeed, Duration: hc.latency}
 }
 
 func TestCheckRetries(t *testing.T) {
@@ -414,6 +2524,229 @@ func TestCheckRetries(t *testing.T) {
 	}
 }
 
+func TestCheckLatency(t *testing.T) {
+	notify := make(chan *Notification, 10)
+	checker := &fakeChecker{succeed: true, latency: 200 * time.Millisecond}
+	hc := NewCheck(notify)
+	hc.Config = *NewConfig(1, checker)
+	hc.Config.SlowThreshold = 100 * time.Millisecond
+
+	hc.healthcheck()
+	if got, want := hc.Status().Latency, checker.latency; got != want {
+		t.Errorf("Got latency %v, want %v", got, want)
+	}
+	if n := <-notify; n.Latency != checker.latency {
+		t.Errorf("Got notification latency %v, want %v", n.Latency, checker.latency)
+	}
+
+	for _, test := range []struct {
+		result *Result
+		slow   bool
+	}{
+		{&Result{Success: true, Duration: 50 * time.Millisecond}, false},
+		{&Result{Success: true, Duration: 100 * time.Millisecond}, false},
+		{&Result{Success: true, Duration: 150 * time.Millisecond}, true},
+		{&Result{Success: false, Duration: 150 * time.Millisecond}, false},
+	} {
+		if got := hc.Config.slow(test.result); got != test.slow {
+			t.Errorf("slow(%v) = %v, want %v", test.result.Duration, got, test.slow)
+		}
+	}
+	hc.Config.SlowThreshold = 0
+	if hc.Config.slow(&Result{Success: true, Duration: time.Hour}) {
+		t.Error("slow() = true with no threshold, want false")
+	}
+}
+
+func TestCheckRiseFall(t *testing.T) {
+	notify := make(chan *Notification, 10)
+	checker := &fakeChecker{}
+	hc := NewCheck(notify)
+	hc.Config = *NewConfig(1, checker)
+	hc.Config.RiseRetries = 3
+	hc.Config.FallRetries = 2
+
+	for i, test := range []struct {
+		succeed bool
+		state   State
+	}{
+		// The initial result is always applied immediately.
+		{false, StateUnhealthy},
+		{true, StateUnhealthy},
+		{true, StateUnhealthy},
+		{false, StateUnhealthy},
+		{true, StateUnhealthy},
+		{true, StateUnhealthy},
+		{true, StateHealthy},
+		{false, StateHealthy},
+		{true, StateHealthy},
+		{false, StateHealthy},
+		{false, StateUnhealthy},
+	} {
+		checker.succeed = test.succeed
+		hc.healthcheck()
+		if hc.state != test.state {
+			t.Errorf("Check %d: got state %v, want %v", i+1, hc.state, test.state)
+		}
+	}
+
+	for i, state := range []State{StateUnhealthy, StateHealthy, StateUnhealthy} {
+		select {
+		case n := <-notify:
+			if n.State != state {
+				t.Errorf("Notification %d got unexpected state %v, want %v", i+1, n.State, state)
+			}
+		default:
+			t.Errorf("Expected state change notification not received")
+		}
+	}
+}
+
+func TestCheckRiseFallTime(t *testing.T) {
+	notify := make(chan *Notification, 10)
+	checker := &fakeChecker{}
+	hc := NewCheck(notify)
+	hc.Config = *NewConfig(1, checker)
+	hc.Config.RiseTime = time.Hour
+	hc.Config.FallTime = time.Hour
+
+	for i, test := range []struct {
+		succeed bool
+		elapsed bool
+		state   State
+	}{
+		// The initial result is always applied immediately.
+		{false, false, StateUnhealthy},
+		{true, false, StateUnhealthy},
+		{true, false, StateUnhealthy},
+		{false, false, StateUnhealthy},
+		{true, false, StateUnhealthy},
+		{true, true, StateHealthy},
+		{false, false, StateHealthy},
+		{true, false, StateHealthy},
+		{false, false, StateHealthy},
+		{false, false, StateHealthy},
+		{false, true, StateUnhealthy},
+	} {
+		if test.elapsed {
+			hc.since = hc.since.Add(-time.Hour)
+		}
+		checker.succeed = test.succeed
+		hc.healthcheck()
+		if hc.state != test.state {
+			t.Errorf("Check %d: got state %v, want %v", i+1, hc.state, test.state)
+		}
+	}
+
+	for i, state := range []State{StateUnhealthy, StateHealthy, StateUnhealthy} {
+		select {
+		case n := <-notify:
+			if n.State != state {
+				t.Errorf("Notification %d got unexpected state %v, want %v", i+1, n.State, state)
+			}
+		default:
+			t.Errorf("Expected state change notification not received")
+		}
+	}
+
+	// With zero timers, transitions are immediate.
+	hc.Config.RiseTime = 0
+	hc.Config.FallTime = 0
+	checker.succeed = true
+	hc.healthcheck()
+	if hc.state != StateHealthy {
+		t.Errorf("Got state %v with no rise time, want %v", hc.state, StateHealthy)
+	}
+}
+
+func TestCheckBackoff(t *testing.T) {
+	notify := make(chan *Notification, 10)
+	checker := &fakeChecker{}
+	hc := NewCheck(notify)
+	hc.Config = *NewConfig(1, checker)
+	hc.Config.Interval = time.Second
+	hc.Config.MaxInterval = 5 * time.Second
+	hc.Config.FallRetries = 2
+	hc.Config.Backoff = true
+
+	interval := hc.Interval
+	for i, test := range []struct {
+		succeed  bool
+		interval time.Duration
+	}{
+		{true, 1 * time.Second},
+		// Pending state transitions must not be delayed.
+		{false, 1 * time.Second},
+		{false, 2 * time.Second},
+		{false, 4 * time.Second},
+		{false, 5 * time.Second},
+		{false, 5 * time.Second},
+		{true, 1 * time.Second},
+	} {
+		checker.succeed = test.succeed
+		hc.healthcheck()
+		interval = hc.nextInterval(interval)
+		if interval != test.interval {
+			t.Errorf("Check %d: got interval %v, want %v", i+1, interval, test.interval)
+		}
+	}
+
+	// Without backoff the interval remains constant.
+	hc.Config.Backoff = false
+	checker.succeed = false
+	for i := 0; i < 3; i++ {
+		hc.healthcheck()
+		if got := hc.nextInterval(hc.Interval); got != hc.Interval {
+			t.Errorf("Got interval %v without backoff, want %v", got, hc.Interval)
+		}
+	}
+}
+
+// countingChecker succeeds for the given number of checks, after which it
+// fails.
+type countingChecker struct {
+	successes int
+}
+
+func (hc *countingChecker) String() string {
+	return "COUNTING"
+}
+
+func (hc *countingChecker) Check(timeout time.Duration) *Result {
+	hc.successes--
+	return &Result{Success: hc.successes >= 0}
+}
+
+func TestCheckRetryDelay(t *testing.T) {
+	notify := make(chan *Notification, 10)
+	hc := NewCheck(notify)
+	go hc.Run(nil)
+	defer hc.Stop()
+
+	config := NewConfig(1, &countingChecker{successes: 1})
+	config.Interval = 300 * time.Millisecond
+	config.FallRetries = 3
+	config.RetryDelay = 20 * time.Millisecond
+	start := time.Now()
+	hc.Update(config)
+	if n := <-notify; n.State != StateHealthy {
+		t.Fatalf("Got unexpected state %v, want %v", n.State, StateHealthy)
+	}
+
+	// The first failure occurs at the next interval, with the retries
+	// following well before the subsequent interval.
+	n := <-notify
+	if n.State != StateUnhealthy {
+		t.Errorf("Got unexpected state %v, want %v", n.State, StateUnhealthy)
+	}
+	if n.Failures != 3 {
+		t.Errorf("Got %d failures, want 3", n.Failures)
+	}
+	if d := time.Since(start); d >= 2*config.Interval {
+		t.Errorf("State transition took %v, want less than %v", d, 2*config.Interval)
+	}
+}
+
 func TestCheckRun(t *testing.T) {
 	notify := make(chan *Notification, 10)
 	hc := NewCheck(notify)
@@ -468,6 +2801,159 @@ func TestCheckRun(t *testing.T) {
 	}
 }
 
+// concurrencyChecker records the maximum number of checks that are executed
+// concurrently.
+type concurrencyChecker struct {
+	lock    sync.Mutex
+	running int
+	max     int
+}
+
+func (hc *concurrencyChecker) String() string {
+	return "CONCURRENCY"
+}
+
+func (hc *concurrencyChecker) Check(timeout time.Duration) *Result {
+	hc.lock.Lock()
+	hc.running++
+	if hc.running > hc.max {
+		hc.max = hc.running
+	}
+	hc.lock.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	hc.lock.Lock()
+	hc.running--
+	hc.lock.Unlock()
+	return &Result{Success: true}
+}
+
+func TestCheckWorkerPool(t *testing.T) {
+	const workers = 2
+	pool := newWorkerPool(workers)
+	checker := &concurrencyChecker{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3*workers; i++ {
+		hc := NewCheck(nil)
+		hc.Config = *NewConfig(Id(i), checker)
+		hc.Interval = time.Millisecond
+		hc.Timeout = timeout
+		hc.pool = pool
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result := hc.execute(); !result.Success {
+				t.Errorf("Healthcheck %d failed: %v", hc.Id, result)
+			}
+		}()
+	}
+	wg.Wait()
+	if checker.max > workers {
+		t.Errorf("Got %d concurrent healthchecks, want at most %d", checker.max, workers)
+	}
+
+	// A probe cannot be submitted while all workers are busy.
+	release := make(chan bool)
+	for i := 0; i < workers; i++ {
+		pool.submit(func() { <-release }, 0)
+	}
+	if pool.submit(func() {}, 10*time.Millisecond) {
+		t.Error("Submitted probe to saturated worker pool")
+	}
+	close(release)
+	if !pool.submit(func() {}, timeout) {
+		t.Error("Failed to submit probe to idle worker pool")
+	}
+}
+
+func TestCheckJitter(t *testing.T) {
+	hc := NewCheck(make(chan *Notification, 10))
+	hc.Interval = 100 * time.Millisecond
+	if d := hc.jitterDelay(); d != 0 {
+		t.Errorf("Jitter delay without jitter got %v, want 0", d)
+	}
+	hc.Jitter(true)
+	for i := 0; i < 100; i++ {
+		if d := hc.jitterDelay(); d < 0 || d >= hc.Interval {
+			t.Fatalf("Jitter delay got %v, want [0, %v)", d, hc.Interval)
+		}
+	}
+
+	notify := make(chan *Notification, 10)
+	hc = NewCheck(notify)
+	hc.Jitter(true)
+	go hc.Run(nil)
+
+	config := NewConfig(1, &fakeChecker{succeed: true})
+	config.Interval = 500 * time.Millisecond
+	start := time.Now()
+	hc.Update(config)
+
+	select {
+	case n := <-notify:
+		if n.State != StateHealthy {
+			t.Errorf("Unexpected state - got %v, want %v", n.State, StateHealthy)
+		}
+	case <-time.After(2 * config.Interval):
+		t.Errorf("Expected state change notification not received")
+	}
+	if d := time.Since(start); d >= config.Interval+100*time.Millisecond {
+		t.Errorf("Initial healthcheck took %v, want less than %v", d, config.Interval)
+	}
+	hc.Stop()
+}
+
+func TestConfigTimeouts(t *testing.T) {
+	for _, test := range []struct {
+		desc           string
+		interval       time.Duration
+		timeout        time.Duration
+		connectTimeout time.Duration
+		wantInterval   time.Duration
+		wantTimeout    time.Duration
+		valid          bool
+	}{
+		{"both set", 10 * time.Second, 5 * time.Second, 0, 10 * time.Second, 5 * time.Second, true},
+		{"interval only", 10 * time.Second, 0, 0, 10 * time.Second, 5 * time.Second, true},
+		{"timeout only", 0, 3 * time.Second, 0, 6 * time.Second, 3 * time.Second, true},
+		{"neither set", 0, 0, 0, 0, 0, false},
+		{"timeout equals interval", 5 * time.Second, 5 * time.Second, 0, 5 * time.Second, 5 * time.Second, false},
+		{"timeout exceeds interval", 5 * time.Second, 30 * time.Second, 0, 5 * time.Second, 30 * time.Second, false},
+		{"connect timeout", 10 * time.Second, 5 * time.Second, time.Second, 10 * time.Second, 5 * time.Second, true},
+		{"connect timeout equals timeout", 10 * time.Second, 5 * time.Second, 5 * time.Second, 10 * time.Second, 5 * time.Second, true},
+		{"connect timeout exceeds timeout", 10 * time.Second, 5 * time.Second, 6 * time.Second, 10 * time.Second, 5 * time.Second, false},
+	} {
+		checker := NewTCPChecker(net.ParseIP("127.0.0.1"), 80)
+		checker.ConnectTimeout = test.connectTimeout
+		config := NewConfig(1, checker)
+		config.Interval = test.interval
+		config.Timeout = test.timeout
+		config.setDefaults()
+		if config.Interval != test.wantInterval || config.Timeout != test.wantTimeout {
+			t.Errorf("%s: got interval %v and timeout %v, want %v and %v",
+				test.desc, config.Interval, config.Timeout, test.wantInterval, test.wantTimeout)
+		}
+		if err := config.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: Validate() = %v, want valid %t", test.desc, err, test.valid)
+		}
+	}
+
+	// The connect timeout only applies if it is less than the timeout.
+	target := &Target{ConnectTimeout: time.Second}
+	for _, test := range []struct {
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{5 * time.Second, time.Second},
+		{500 * time.Millisecond, 500 * time.Millisecond},
+		{0, time.Second},
+	} {
+		if got := target.connectTimeout(test.timeout); got != test.want {
+			t.Errorf("connectTimeout(%v) = %v, want %v", test.timeout, got, test.want)
+		}
+	}
+}
+
 func TestCheckTimeout(t *testing.T) {
 	notify := make(chan *Notification, 10)
 	hc := NewCheck(notify)
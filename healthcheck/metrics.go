@@ -0,0 +1,132 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// This file contains routines for exporting healthcheck results as
+// Prometheus metrics.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/wy2745/seesaw/common/metrics"
+)
+
+// checkMetrics contains the metrics for a single healthcheck.
+type checkMetrics struct {
+	vserver string
+	backend string
+	check   string
+	status  Status
+}
+
+// labels returns the Prometheus label set for the healthcheck.
+func (m *checkMetrics) labels() string {
+	return fmt.Sprintf(`{vserver="%s",backend="%s",check="%s"}`,
+		metrics.EscapeLabel(m.vserver), metrics.EscapeLabel(m.backend), metrics.EscapeLabel(m.check))
+}
+
+// metricFamilies describes the metrics that are exported for each
+// healthcheck.
+var metricFamilies = []struct {
+	name  string
+	kind  string
+	help  string
+	value func(*Status) float64
+}{
+	{
+		"seesaw_healthcheck_up", "gauge",
+		"Whether the healthcheck is healthy (1) or not (0).",
+		func(s *Status) float64 {
+			if s.State == StateHealthy {
+				return 1
+			}
+			return 0
+		},
+	},
+	{
+		"seesaw_healthcheck_duration_seconds", "gauge",
+		"Duration of the most recent healthcheck in seconds.",
+		func(s *Status) float64 { return s.Latency.Seconds() },
+	},
+	{
+		"seesaw_healthcheck_last_check_timestamp_seconds", "gauge",
+		"Time of the most recent healthcheck in seconds since the epoch.",
+		func(s *Status) float64 { return metrics.Timestamp(s.LastCheck) },
+	},
+	{
+		"seesaw_healthcheck_failures_total", "counter",
+		"Total number of failed healthchecks.",
+		func(s *Status) float64 { return float64(s.Failures) },
+	},
+	{
+		"seesaw_healthcheck_successes_total", "counter",
+		"Total number of successful healthchecks.",
+		func(s *Status) float64 { return float64(s.Successes) },
+	},
+}
+
+// writeMetrics writes the given healthcheck metrics in the Prometheus text
+// exposition format.
+func writeMetrics(w io.Writer, checks []*checkMetrics) error {
+	var b bytes.Buffer
+	for _, mf := range metricFamilies {
+		metrics.WriteHeader(&b, mf.name, mf.kind, mf.help)
+		for _, m := range checks {
+			fmt.Fprintf(&b, "%s%s %g\n", mf.name, m.labels(), mf.value(&m.status))
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// checkMetrics returns the metrics for the healthchecks that are currently
+// running, ordered by healthcheck ID.
+func (s *Server) checkMetrics() []*checkMetrics {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	ids := make([]Id, 0, len(s.healthchecks))
+	for id := range s.healthchecks {
+		if s.current[id] != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	checks := make([]*checkMetrics, 0, len(ids))
+	for _, id := range ids {
+		cfg := s.current[id]
+		name := cfg.Name
+		if name == "" && cfg.Checker != nil {
+			name = cfg.Checker.String()
+		}
+		checks = append(checks, &checkMetrics{
+			vserver: cfg.Vserver,
+			backend: cfg.Backend,
+			check:   name,
+			status:  s.healthchecks[id].Status(),
+		})
+	}
+	return checks
+}
+
+// exportMetrics writes the metrics for the healthchecks that are currently
+// running.
+func (s *Server) exportMetrics(w io.Writer) error {
+	return writeMetrics(w, s.checkMetrics())
+}
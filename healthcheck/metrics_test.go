@@ -0,0 +1,95 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wy2745/seesaw/common/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	s := NewServer(nil)
+
+	healthy := NewConfig(2, NewTCPChecker(net.ParseIP("10.0.0.2"), 80))
+	healthy.Vserver = "web.example.com"
+	healthy.Backend = "10.0.0.2"
+	healthy.Name = "TCP/80_0"
+	unhealthy := NewConfig(1, NewTCPChecker(net.ParseIP("10.0.0.1"), 80))
+	unhealthy.Vserver = `web "quoted"`
+	unhealthy.Backend = "10.0.0.1"
+	unhealthy.Name = "TCP/80_0"
+	unknown := NewConfig(3, NewTCPChecker(net.ParseIP("10.0.0.3"), 80))
+
+	s.current = map[Id]*Config{1: unhealthy, 2: healthy, 3: unknown}
+	for id := range s.current {
+		s.healthchecks[id] = NewCheck(s.notify)
+	}
+	s.healthchecks[1].state = StateUnhealthy
+	s.healthchecks[1].failures = 3
+	s.healthchecks[1].start = time.Unix(1500000000, 0)
+	s.healthchecks[1].result = &Result{Message: "failed", Success: false, Duration: 1500 * time.Millisecond}
+	s.healthchecks[2].state = StateHealthy
+	s.healthchecks[2].successes = 5
+	s.healthchecks[2].start = time.Unix(1500000000, 500000000)
+	s.healthchecks[2].result = &Result{Message: "ok", Success: true, Duration: 25 * time.Millisecond}
+
+	// A healthcheck that is no longer configured is not exported.
+	s.healthchecks[4] = NewCheck(s.notify)
+
+	w := httptest.NewRecorder()
+	metrics.Handler(s.exportMetrics).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if got, want := w.Header().Get("Content-Type"), metrics.ContentType; got != want {
+		t.Errorf("Got content type %q, want %q", got, want)
+	}
+
+	unhealthyLabels := `{vserver="web \"quoted\"",backend="10.0.0.1",check="TCP/80_0"}`
+	healthyLabels := `{vserver="web.example.com",backend="10.0.0.2",check="TCP/80_0"}`
+	unknownLabels := `{vserver="",backend="",check="` + unknown.Checker.String() + `"}`
+	want := strings.Join([]string{
+		"# HELP seesaw_healthcheck_up Whether the healthcheck is healthy (1) or not (0).",
+		"# TYPE seesaw_healthcheck_up gauge",
+		"seesaw_healthcheck_up" + unhealthyLabels + " 0",
+		"seesaw_healthcheck_up" + healthyLabels + " 1",
+		"seesaw_healthcheck_up" + unknownLabels + " 0",
+		"# HELP seesaw_healthcheck_duration_seconds Duration of the most recent healthcheck in seconds.",
+		"# TYPE seesaw_healthcheck_duration_seconds gauge",
+		"seesaw_healthcheck_duration_seconds" + unhealthyLabels + " 1.5",
+		"seesaw_healthcheck_duration_seconds" + healthyLabels + " 0.025",
+		"seesaw_healthcheck_duration_seconds" + unknownLabels + " 0",
+		"# HELP seesaw_healthcheck_last_check_timestamp_seconds Time of the most recent healthcheck in seconds since the epoch.",
+		"# TYPE seesaw_healthcheck_last_check_timestamp_seconds gauge",
+		"seesaw_healthcheck_last_check_timestamp_seconds" + unhealthyLabels + " 1.5e+09",
+		"seesaw_healthcheck_last_check_timestamp_seconds" + healthyLabels + " 1.5000000005e+09",
+		"seesaw_healthcheck_last_check_timestamp_seconds" + unknownLabels + " 0",
+		"# HELP seesaw_healthcheck_failures_total Total number of failed healthchecks.",
+		"# TYPE seesaw_healthcheck_failures_total counter",
+		"seesaw_healthcheck_failures_total" + unhealthyLabels + " 3",
+		"seesaw_healthcheck_failures_total" + healthyLabels + " 0",
+		"seesaw_healthcheck_failures_total" + unknownLabels + " 0",
+		"# HELP seesaw_healthcheck_successes_total Total number of successful healthchecks.",
+		"# TYPE seesaw_healthcheck_successes_total counter",
+		"seesaw_healthcheck_successes_total" + unhealthyLabels + " 0",
+		"seesaw_healthcheck_successes_total" + healthyLabels + " 5",
+		"seesaw_healthcheck_successes_total" + unknownLabels + " 0",
+	}, "\n") + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("Got metrics:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,496 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// MySQL healthcheck implementation.
+
+package healthcheck
+
+// This implements the minimal subset of the MySQL client/server protocol
+// needed to complete the connection handshake, authenticate using the
+// mysql_native_password or caching_sha2_password (fast path) plugins and
+// run simple queries. See
+// https://dev.mysql.com/doc/dev/mysql-server/latest/PAGE_PROTOCOL.html.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+const (
+	defaultMySQLTimeout = 10 * time.Second
+
+	mysqlMaxPacketLen = 1 << 24
+
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientConnectWithDB    = 0x00000008
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSecureConnection = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+
+	mysqlCharsetUTF8 = 33
+
+	mysqlComQuit  = 0x01
+	mysqlComQuery = 0x03
+
+	mysqlOK         = 0x00
+	mysqlAuthMore   = 0x01
+	mysqlEOF        = 0xfe
+	mysqlAuthSwitch = 0xfe
+	mysqlErr        = 0xff
+
+	mysqlNativePassword      = "mysql_native_password"
+	mysqlCachingSHA2Password = "caching_sha2_password"
+
+	mysqlReadOnlyQuery = "SELECT @@global.read_only"
+)
+
+// MySQLChecker contains configuration specific to a MySQL healthcheck.
+//
+// If no Username is given, the check only verifies that the server sends a
+// valid handshake, after which the connection is closed. Otherwise the check
+// authenticates, optionally runs Query and checks that the server is not
+// read-only, before closing the connection with COM_QUIT.
+type MySQLChecker struct {
+	Target
+	Username string
+	Password string
+	Database string
+
+	// Query is run once authenticated, if non-empty. The query must not
+	// return an error.
+	Query string
+
+	// ExpectWritable requires that @@global.read_only is 0.
+	ExpectWritable bool
+}
+
+// NewMySQLChecker returns an initialised MySQLChecker.
+func NewMySQLChecker(ip net.IP, port int) *MySQLChecker {
+	return &MySQLChecker{
+		Target: Target{
+			IP:    ip,
+			Port:  port,
+			Proto: seesaw.IPProtoTCP,
+		},
+	}
+}
+
+// String returns the string representation of a MySQL healthcheck.
+func (hc *MySQLChecker) String() string {
+	attr := []string{}
+	if hc.Username != "" {
+		attr = append(attr, fmt.Sprintf("user %s", hc.Username))
+	}
+	if hc.Database != "" {
+		attr = append(attr, fmt.Sprintf("database %s", hc.Database))
+	}
+	if hc.Query != "" {
+		attr = append(attr, fmt.Sprintf("query %q", hc.Query))
+	}
+	if hc.ExpectWritable {
+		attr = append(attr, "writable")
+	}
+	var s string
+	if len(attr) > 0 {
+		s = fmt.Sprintf(" [%s]", strings.Join(attr, "; "))
+	}
+	return fmt.Sprintf("MySQL%s %s", s, hc.Target)
+}
+
+// mysqlConn provides packet level access to a MySQL connection.
+type mysqlConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	seq  byte
+}
+
+// readPacket reads a packet from the connection.
+func (c *mysqlConn) readPacket() ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := int(hdr[0]) | int(hdr[1])<<8 | int(hdr[2])<<16
+	if hdr[3] != c.seq {
+		return nil, fmt.Errorf("got packet sequence %d, want %d", hdr[3], c.seq)
+	}
+	c.seq++
+	if n == mysqlMaxPacketLen-1 {
+		return nil, errors.New("multi-packet payloads are not supported")
+	}
+	pkt := make([]byte, n)
+	if _, err := io.ReadFull(c.r, pkt); err != nil {
+		return nil, err
+	}
+	if len(pkt) > 0 && pkt[0] == mysqlErr {
+		return nil, mysqlError(pkt)
+	}
+	return pkt, nil
+}
+
+// writePacket writes a packet to the connection.
+func (c *mysqlConn) writePacket(pkt []byte) error {
+	n := len(pkt)
+	hdr := []byte{byte(n), byte(n >> 8), byte(n >> 16), c.seq}
+	c.seq++
+	return writeFull(c.conn, append(hdr, pkt...))
+}
+
+// command sends the given command, starting a new packet sequence.
+func (c *mysqlConn) command(cmd byte, arg string) error {
+	c.seq = 0
+	return c.writePacket(append([]byte{cmd}, arg...))
+}
+
+// query runs the given query and returns the first column of the first row
+// of the result set, if any.
+func (c *mysqlConn) query(q string) (string, error) {
+	if err := c.command(mysqlComQuery, q); err != nil {
+		return "", err
+	}
+	pkt, err := c.readPacket()
+	if err != nil {
+		return "", err
+	}
+	if len(pkt) > 0 && pkt[0] == mysqlOK {
+		return "", nil
+	}
+	columns, _, ok := lenEncInt(pkt)
+	if !ok || columns == 0 {
+		return "", errors.New("malformed result set")
+	}
+
+	// Column definitions are followed by an EOF packet, then rows
+	// followed by a final EOF packet. Note that the column count packet
+	// cannot be mistaken for an EOF packet.
+	for !isMySQLEOF(pkt) {
+		if pkt, err = c.readPacket(); err != nil {
+			return "", err
+		}
+	}
+	var value string
+	for row := 0; ; row++ {
+		if pkt, err = c.readPacket(); err != nil {
+			return "", err
+		}
+		if isMySQLEOF(pkt) {
+			return value, nil
+		}
+		if row == 0 && len(pkt) > 0 && pkt[0] != 0xfb {
+			v, _, ok := lenEncString(pkt)
+			if !ok {
+				return "", errors.New("malformed row")
+			}
+			value = v
+		}
+	}
+}
+
+// isMySQLEOF returns true if the given packet is an EOF packet.
+func isMySQLEOF(pkt []byte) bool {
+	return len(pkt) > 0 && len(pkt) < 9 && pkt[0] == mysqlEOF
+}
+
+// mysqlError returns an error for the given ERR packet.
+func mysqlError(pkt []byte) error {
+	if len(pkt) < 3 {
+		return errors.New("malformed error packet")
+	}
+	code := binary.LittleEndian.Uint16(pkt[1:3])
+	msg := pkt[3:]
+	if len(msg) > 0 && msg[0] == '#' && len(msg) >= 6 {
+		msg = msg[6:]
+	}
+	return fmt.Errorf("MySQL error %d: %s", code, msg)
+}
+
+// lenEncInt decodes a length encoded integer, returning the value and the
+// remaining bytes.
+func lenEncInt(b []byte) (uint64, []byte, bool) {
+	if len(b) == 0 {
+		return 0, nil, false
+	}
+	var n int
+	switch b[0] {
+	case 0xfc:
+		n = 2
+	case 0xfd:
+		n = 3
+	case 0xfe:
+		n = 8
+	default:
+		if b[0] > 0xfb {
+			return 0, nil, false
+		}
+		return uint64(b[0]), b[1:], true
+	}
+	if len(b) < n+1 {
+		return 0, nil, false
+	}
+	var v uint64
+	for i := n; i > 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, b[n+1:], true
+}
+
+// lenEncString decodes a length encoded string, returning the value and the
+// remaining bytes.
+func lenEncString(b []byte) (string, []byte, bool) {
+	n, b, ok := lenEncInt(b)
+	if !ok || uint64(len(b)) < n {
+		return "", nil, false
+	}
+	return string(b[:n]), b[n:], true
+}
+
+// nulString decodes a NUL terminated string, returning the value and the
+// remaining bytes.
+func nulString(b []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(b[:i]), b[i+1:], true
+}
+
+// mysqlHandshake contains the relevant fields of an initial handshake.
+type mysqlHandshake struct {
+	version      string
+	capabilities uint32
+	nonce        []byte
+	plugin       string
+}
+
+// parseMySQLHandshake parses a protocol version 10 initial handshake packet.
+func parseMySQLHandshake(pkt []byte) (*mysqlHandshake, error) {
+	if len(pkt) == 0 {
+		return nil, errors.New("empty handshake")
+	}
+	if pkt[0] != 10 {
+		return nil, fmt.Errorf("unsupported protocol version %d", pkt[0])
+	}
+	version, b, ok := nulString(pkt[1:])
+	if !ok || len(b) < 4+8+1+2 {
+		return nil, errors.New("malformed handshake")
+	}
+	hs := &mysqlHandshake{version: version}
+	b = b[4:] // Connection ID.
+	hs.nonce = append(hs.nonce, b[:8]...)
+	b = b[9:]
+	hs.capabilities = uint32(binary.LittleEndian.Uint16(b[:2]))
+	b = b[2:]
+	if len(b) < 1+2+2+1+10 {
+		return hs, nil
+	}
+	hs.capabilities |= uint32(binary.LittleEndian.Uint16(b[3:5])) << 16
+	nonceLen := int(b[5])
+	b = b[16:]
+	if hs.capabilities&mysqlClientSecureConnection != 0 {
+		n := nonceLen - 8
+		if n < 13 {
+			n = 13
+		}
+		if len(b) < n {
+			return nil, errors.New("malformed handshake")
+		}
+		// The second part of the nonce is NUL terminated.
+		hs.nonce = append(hs.nonce, bytes.TrimRight(b[:n], "\x00")...)
+		b = b[n:]
+	}
+	if hs.capabilities&mysqlClientPluginAuth != 0 {
+		hs.plugin, _, _ = nulString(b)
+	}
+	return hs, nil
+}
+
+// mysqlScramble returns the authentication response for the given plugin.
+func mysqlScramble(plugin, password string, nonce []byte) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+	switch plugin {
+	case "", mysqlNativePassword:
+		// SHA1(password) XOR SHA1(nonce + SHA1(SHA1(password)))
+		h1 := sha1.Sum([]byte(password))
+		h2 := sha1.Sum(h1[:])
+		h3 := sha1.Sum(append(append([]byte{}, nonce...), h2[:]...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	case mysqlCachingSHA2Password:
+		// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + nonce)
+		h1 := sha256.Sum256([]byte(password))
+		h2 := sha256.Sum256(h1[:])
+		h3 := sha256.Sum256(append(h2[:], nonce...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:], nil
+	}
+	return nil, fmt.Errorf("unsupported authentication plugin %q", plugin)
+}
+
+// authenticate sends a handshake response and completes authentication.
+func (hc *MySQLChecker) authenticate(c *mysqlConn, hs *mysqlHandshake) error {
+	if hs.capabilities&mysqlClientProtocol41 == 0 {
+		return errors.New("server does not support protocol 4.1")
+	}
+	plugin := hs.plugin
+	if plugin == "" {
+		plugin = mysqlNativePassword
+	}
+	auth, err := mysqlScramble(plugin, hc.Password, hs.nonce)
+	if err != nil {
+		return err
+	}
+
+	flags := uint32(mysqlClientLongPassword | mysqlClientProtocol41 |
+		mysqlClientSecureConnection | mysqlClientPluginAuth)
+	if hc.Database != "" {
+		flags |= mysqlClientConnectWithDB
+	}
+	pkt := make([]byte, 4+4+1+23)
+	binary.LittleEndian.PutUint32(pkt[0:4], flags)
+	binary.LittleEndian.PutUint32(pkt[4:8], mysqlMaxPacketLen-1)
+	pkt[8] = mysqlCharsetUTF8
+	pkt = append(pkt, hc.Username...)
+	pkt = append(pkt, 0, byte(len(auth)))
+	pkt = append(pkt, auth...)
+	if hc.Database != "" {
+		pkt = append(pkt, hc.Database...)
+		pkt = append(pkt, 0)
+	}
+	pkt = append(pkt, plugin...)
+	pkt = append(pkt, 0)
+	if err := c.writePacket(pkt); err != nil {
+		return err
+	}
+
+	for {
+		pkt, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(pkt) == 0:
+			return errors.New("empty authentication response")
+		case pkt[0] == mysqlOK:
+			return nil
+		case pkt[0] == mysqlAuthSwitch:
+			name, data, ok := nulString(pkt[1:])
+			if !ok {
+				return errors.New("malformed authentication switch request")
+			}
+			plugin = name
+			auth, err := mysqlScramble(plugin, hc.Password, bytes.TrimRight(data, "\x00"))
+			if err != nil {
+				return err
+			}
+			if err := c.writePacket(auth); err != nil {
+				return err
+			}
+		case pkt[0] == mysqlAuthMore && plugin == mysqlCachingSHA2Password && len(pkt) == 2:
+			switch pkt[1] {
+			case 3:
+				// Fast authentication succeeded, an OK packet follows.
+			case 4:
+				return errors.New("caching_sha2_password requires full authentication")
+			default:
+				return fmt.Errorf("unexpected authentication status %d", pkt[1])
+			}
+		default:
+			return fmt.Errorf("unexpected authentication response 0x%x", pkt[0])
+		}
+	}
+}
+
+// Check executes a MySQL healthcheck.
+func (hc *MySQLChecker) Check(timeout time.Duration) *Result {
+	msg := fmt.Sprintf("MySQL to %s", hc.addr())
+	start := time.Now()
+	if timeout == time.Duration(0) {
+		timeout = defaultMySQLTimeout
+	}
+
+	conn, err := dialTCP(hc.network(), hc.addr(), hc.connectTimeout(timeout), hc.Mark, hc.source())
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to connect", msg)
+		return complete(start, msg, false, err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(start.Add(timeout))
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to set deadline", msg)
+		return complete(start, msg, false, err)
+	}
+
+	c := &mysqlConn{conn: conn, r: bufio.NewReader(conn)}
+	pkt, err := c.readPacket()
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to read handshake", msg)
+		return complete(start, msg, false, err)
+	}
+	hs, err := parseMySQLHandshake(pkt)
+	if err != nil {
+		msg = fmt.Sprintf("%s; invalid handshake", msg)
+		return complete(start, msg, false, err)
+	}
+	msg = fmt.Sprintf("%s (version %s)", msg, hs.version)
+	if hc.Username == "" {
+		return complete(start, msg, true, nil)
+	}
+
+	if err := hc.authenticate(c, hs); err != nil {
+		msg = fmt.Sprintf("%s; authentication failed", msg)
+		return complete(start, msg, false, err)
+	}
+
+	if hc.Query != "" {
+		if _, err := c.query(hc.Query); err != nil {
+			msg = fmt.Sprintf("%s; query %q failed", msg, hc.Query)
+			return complete(start, msg, false, err)
+		}
+	}
+	if hc.ExpectWritable {
+		readOnly, err := c.query(mysqlReadOnlyQuery)
+		if err != nil {
+			msg = fmt.Sprintf("%s; query %q failed", msg, mysqlReadOnlyQuery)
+			return complete(start, msg, false, err)
+		}
+		if readOnly != "0" {
+			msg = fmt.Sprintf("%s; server is read-only", msg)
+			return complete(start, msg, false, nil)
+		}
+	}
+
+	if err := c.command(mysqlComQuit, ""); err != nil {
+		msg = fmt.Sprintf("%s; failed to quit", msg)
+		return complete(start, msg, false, err)
+	}
+	return complete(start, msg, true, nil)
+}
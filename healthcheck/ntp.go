@@ -0,0 +1,176 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// NTP healthcheck implementation.
+
+package healthcheck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+const (
+	defaultNTPTimeout = 5 * time.Second
+
+	defaultNTPMinStratum = 1
+	defaultNTPMaxStratum = 15
+
+	ntpPacketSize = 48
+	ntpVersion    = 4
+	ntpModeClient = 3
+	ntpModeServer = 4
+	ntpLeapAlarm  = 3
+
+	// ntpEpochOffset is the number of seconds between the NTP epoch
+	// (1900-01-01) and the Unix epoch (1970-01-01).
+	ntpEpochOffset = 2208988800
+)
+
+// NTPChecker contains configuration specific to an NTP healthcheck.
+type NTPChecker struct {
+	Target
+
+	// MinStratum and MaxStratum specify the range of strata that are
+	// acceptable for the server.
+	MinStratum int
+	MaxStratum int
+}
+
+// NewNTPChecker returns an initialised NTPChecker.
+func NewNTPChecker(ip net.IP, port int) *NTPChecker {
+	return &NTPChecker{
+		Target: Target{
+			IP:    ip,
+			Port:  port,
+			Proto: seesaw.IPProtoUDP,
+		},
+		MinStratum: defaultNTPMinStratum,
+		MaxStratum: defaultNTPMaxStratum,
+	}
+}
+
+// String returns the string representation of an NTP healthcheck.
+func (hc *NTPChecker) String() string {
+	return fmt.Sprintf("NTP [stratum %d-%d] %s", hc.MinStratum, hc.MaxStratum, hc.Target)
+}
+
+// ntpTimestamp returns the NTP timestamp for the given time.
+func ntpTimestamp(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// ntpTime returns the time for the given NTP timestamp.
+func ntpTime(ts uint64) time.Time {
+	secs := int64(ts>>32) - ntpEpochOffset
+	nsecs := int64((ts & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(secs, nsecs)
+}
+
+// ntpResponse contains the fields of interest from an NTP server response.
+type ntpResponse struct {
+	leap     uint8
+	stratum  uint8
+	receive  time.Time
+	transmit time.Time
+}
+
+// parseNTPResponse parses an NTP server response to the given request.
+func parseNTPResponse(b, req []byte) (*ntpResponse, error) {
+	if len(b) < ntpPacketSize {
+		return nil, fmt.Errorf("short NTP packet: %d bytes", len(b))
+	}
+	if mode := b[0] & 0x7; mode != ntpModeServer {
+		return nil, fmt.Errorf("unexpected NTP mode %d", mode)
+	}
+	// The originate timestamp must be the transmit timestamp that was
+	// sent in the request.
+	if !bytes.Equal(b[24:32], req[40:48]) {
+		return nil, errors.New("NTP originate timestamp does not match request")
+	}
+	transmit := binary.BigEndian.Uint64(b[40:48])
+	if transmit == 0 {
+		return nil, errors.New("NTP transmit timestamp is zero")
+	}
+	return &ntpResponse{
+		leap:     b[0] >> 6,
+		stratum:  b[1],
+		receive:  ntpTime(binary.BigEndian.Uint64(b[32:40])),
+		transmit: ntpTime(transmit),
+	}, nil
+}
+
+// Check executes an NTP healthcheck.
+func (hc *NTPChecker) Check(timeout time.Duration) *Result {
+	msg := fmt.Sprintf("NTP query to %s", hc.addr())
+	start := time.Now()
+	if timeout == time.Duration(0) {
+		timeout = defaultNTPTimeout
+	}
+
+	conn, err := dialUDP(hc.network(), hc.addr(), timeout, hc.Mark, hc.source())
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to create socket", msg)
+		return complete(start, msg, false, err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(start.Add(timeout))
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to set deadline", msg)
+		return complete(start, msg, false, err)
+	}
+
+	req := make([]byte, ntpPacketSize)
+	req[0] = ntpVersion<<3 | ntpModeClient
+	sent := time.Now()
+	binary.BigEndian.PutUint64(req[40:48], ntpTimestamp(sent))
+	if _, err := conn.Write(req); err != nil {
+		msg = fmt.Sprintf("%s; failed to send request", msg)
+		return complete(start, msg, false, err)
+	}
+
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	received := time.Now()
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to read response", msg)
+		return complete(start, msg, false, err)
+	}
+	resp, err := parseNTPResponse(buf[:n], req)
+	if err != nil {
+		msg = fmt.Sprintf("%s; invalid response", msg)
+		return complete(start, msg, false, err)
+	}
+
+	offset := (resp.receive.Sub(sent) + resp.transmit.Sub(received)) / 2
+	msg = fmt.Sprintf("%s; stratum %d, offset %v", msg, resp.stratum, offset)
+	if resp.leap == ntpLeapAlarm {
+		msg = fmt.Sprintf("%s; leap indicator is alarm (unsynchronised)", msg)
+		return complete(start, msg, false, nil)
+	}
+	if int(resp.stratum) < hc.MinStratum || int(resp.stratum) > hc.MaxStratum {
+		msg = fmt.Sprintf("%s; stratum not within %d-%d", msg, hc.MinStratum, hc.MaxStratum)
+		return complete(start, msg, false, nil)
+	}
+	return complete(start, msg, true, nil)
+}
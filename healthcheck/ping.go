@@ -20,10 +20,12 @@ package healthcheck
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
@@ -31,6 +33,10 @@ import (
 
 const (
 	defaultPingTimeout = time.Second
+
+	// defaultPingEchoInterval is the interval between echo requests, if
+	// EchoInterval is not set.
+	defaultPingEchoInterval = 100 * time.Millisecond
 )
 
 var nextPingCheckerID uint16
@@ -45,6 +51,15 @@ type PingChecker struct {
 	Target
 	ID     uint16
 	Seqnum uint16
+
+	// Count is the number of echo requests that are sent, spaced by
+	// EchoInterval. The healthcheck fails if the percentage of requests
+	// that are not replied to exceeds LossThreshold, or if no replies are
+	// received. If Count is zero a single echo request is sent, which must
+	// be replied to.
+	Count         int
+	EchoInterval  time.Duration
+	LossThreshold float64
 }
 
 // NewPingChecker returns an initialised PingChecker.
@@ -66,22 +81,78 @@ func NewPingChecker(ip net.IP) *PingChecker {
 
 // String returns the string representation of a Ping healthcheck.
 func (hc *PingChecker) String() string {
-	return fmt.Sprintf("PING %s", hc.IP)
+	if hc.Count <= 1 {
+		return fmt.Sprintf("PING %s", hc.IP)
+	}
+	attr := []string{
+		fmt.Sprintf("count %d", hc.Count),
+		fmt.Sprintf("interval %v", hc.echoInterval()),
+		fmt.Sprintf("loss threshold %g%%", hc.LossThreshold),
+	}
+	return fmt.Sprintf("PING [%s] %s", strings.Join(attr, "; "), hc.IP)
+}
+
+// echoInterval returns the interval between echo requests.
+func (hc *PingChecker) echoInterval() time.Duration {
+	if hc.EchoInterval > 0 {
+		return hc.EchoInterval
+	}
+	return defaultPingEchoInterval
+}
+
+// pingLoss returns the percentage of echo requests that were not replied to.
+func pingLoss(sent, received int) float64 {
+	if sent == 0 {
+		return 0
+	}
+	return 100 * float64(sent-received) / float64(sent)
 }
 
 // Check executes a ping healthcheck.
 func (hc *PingChecker) Check(timeout time.Duration) *Result {
 	msg := fmt.Sprintf("ICMP ping to host %v", hc.IP)
-	seq := hc.Seqnum
-	hc.Seqnum++
-	echo := newICMPEchoRequest(hc.Proto, hc.ID, seq, 64, []byte("Healthcheck"))
 	start := time.Now()
 	if timeout == time.Duration(0) {
 		timeout = defaultPingTimeout
 	}
-	err := exchangeICMPEcho(hc.network(), hc.IP, timeout, echo)
-	success := err == nil
-	return complete(start, msg, success, err)
+	if hc.Count <= 1 {
+		seq := hc.Seqnum
+		hc.Seqnum++
+		echo := newICMPEchoRequest(hc.Proto, hc.ID, seq, 64, []byte("Healthcheck"))
+		err := exchangeICMPEcho(hc.network(), hc.IP, timeout, echo, hc.source())
+		if errors.Is(err, os.ErrPermission) {
+			msg = fmt.Sprintf("%s; raw sockets unavailable (CAP_NET_RAW is required)", msg)
+			return complete(start, msg, false, nil)
+		}
+		success := err == nil
+		return complete(start, msg, success, err)
+	}
+
+	echoes := make([]icmpMsg, hc.Count)
+	for i := range echoes {
+		echoes[i] = newICMPEchoRequest(hc.Proto, hc.ID, hc.Seqnum, 64, []byte("Healthcheck"))
+		hc.Seqnum++
+	}
+	rtts, err := exchangeICMPEchoes(hc.network(), hc.IP, start.Add(timeout), hc.echoInterval(), echoes, hc.source())
+	if errors.Is(err, os.ErrPermission) {
+		msg = fmt.Sprintf("%s; raw sockets unavailable (CAP_NET_RAW is required)", msg)
+		return complete(start, msg, false, nil)
+	}
+	if err != nil {
+		return complete(start, msg, false, err)
+	}
+
+	loss := pingLoss(len(echoes), len(rtts))
+	msg = fmt.Sprintf("%s; %d/%d replies received, %.0f%% loss", msg, len(rtts), len(echoes), loss)
+	if len(rtts) > 0 {
+		var total time.Duration
+		for _, rtt := range rtts {
+			total += rtt
+		}
+		msg = fmt.Sprintf("%s, average RTT %v", msg, total/time.Duration(len(rtts)))
+	}
+	success := len(rtts) > 0 && loss <= hc.LossThreshold
+	return complete(start, msg, success, nil)
 }
 
 // NB: The code below borrows heavily from pkg/net/ipraw_test.go.
@@ -158,8 +229,92 @@ func parseICMPEchoReply(msg icmpMsg) (id, seqnum, chksum uint16) {
 	return
 }
 
-func exchangeICMPEcho(network string, ip net.IP, timeout time.Duration, echo icmpMsg) error {
-	c, err := net.ListenPacket(network, "")
+// icmpEchoReply validates a message received from the given address and
+// returns its sequence number, if it is an echo reply from the given IP
+// address with the given identifier. Otherwise ok is false.
+func icmpEchoReply(ip net.IP, addr net.Addr, reply icmpMsg, id uint16) (seqnum uint16, ok bool, err error) {
+	// Compare the IP directly, since the address may include a zone
+	// for IPv6 link-local addresses.
+	if ipAddr, ok := addr.(*net.IPAddr); !ok || !ip.Equal(ipAddr.IP) {
+		return 0, false, nil
+	}
+	if len(reply) < 8 || reply[0] != ICMP4_ECHO_REPLY && reply[0] != ICMP6_ECHO_REPLY {
+		return 0, false, nil
+	}
+	rid, rseqnum, rchksum := parseICMPEchoReply(reply)
+	if rid != id {
+		return 0, false, nil
+	}
+	if reply[0] == ICMP4_ECHO_REPLY {
+		cs := icmpChecksum(reply)
+		if cs != 0 {
+			return 0, false, fmt.Errorf("Bad ICMP checksum: %x", rchksum)
+		}
+	}
+	// TODO(angusc): Validate checksum for IPv6
+	return rseqnum, true, nil
+}
+
+// exchangeICMPEchoes sends the given echo requests, spaced by the given
+// interval, and returns the round trip times for the requests that were
+// replied to before the deadline.
+func exchangeICMPEchoes(network string, ip net.IP, deadline time.Time, interval time.Duration, echoes []icmpMsg, src source) ([]time.Duration, error) {
+	c, err := listenPacket(network, ip, src)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var id uint16
+	if len(echoes) > 0 {
+		id, _, _ = parseICMPEchoReply(echoes[0])
+	}
+	var rtts []time.Duration
+	pending := make(map[uint16]time.Time)
+	reply := make([]byte, 256)
+	for i, echo := range echoes {
+		_, seqnum, _ := parseICMPEchoReply(echo)
+		sent := time.Now()
+		if !sent.Before(deadline) {
+			break
+		}
+		pending[seqnum] = sent
+		if _, err := c.WriteTo(echo, &net.IPAddr{IP: ip}); err != nil {
+			return nil, err
+		}
+
+		// Read replies until the next echo request is due, or for the
+		// final request, until all replies have been received.
+		last := i == len(echoes)-1
+		wait := deadline
+		if next := sent.Add(interval); !last && next.Before(deadline) {
+			wait = next
+		}
+		c.SetReadDeadline(wait)
+		for !last || len(pending) > 0 {
+			n, addr, err := c.ReadFrom(reply)
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			// Replies with a bad checksum are treated as lost.
+			rseqnum, ok, err := icmpEchoReply(ip, addr, reply[:n], id)
+			if err != nil || !ok {
+				continue
+			}
+			if sent, ok := pending[rseqnum]; ok {
+				rtts = append(rtts, time.Since(sent))
+				delete(pending, rseqnum)
+			}
+		}
+	}
+	return rtts, nil
+}
+
+func exchangeICMPEcho(network string, ip net.IP, timeout time.Duration, echo icmpMsg, src source) error {
+	c, err := listenPacket(network, ip, src)
 	if err != nil {
 		return err
 	}
@@ -172,30 +327,19 @@ func exchangeICMPEcho(network string, ip net.IP, timeout time.Duration, echo icm
 
 	c.SetDeadline(time.Now().Add(timeout))
 	reply := make([]byte, 256)
+	xid, xseqnum, _ := parseICMPEchoReply(echo)
 	for {
-		_, addr, err := c.ReadFrom(reply)
+		n, addr, err := c.ReadFrom(reply)
 		if err != nil {
 			return err
 		}
-		if !ip.Equal(net.ParseIP(addr.String())) {
-			continue
-		}
-		if reply[0] != ICMP4_ECHO_REPLY && reply[0] != ICMP6_ECHO_REPLY {
-			continue
-		}
-		xid, xseqnum, _ := parseICMPEchoReply(echo)
-		rid, rseqnum, rchksum := parseICMPEchoReply(reply)
-		if rid != xid || rseqnum != xseqnum {
-			continue
+		rseqnum, ok, err := icmpEchoReply(ip, addr, reply[:n], xid)
+		if err != nil {
+			return err
 		}
-		if reply[0] == ICMP4_ECHO_REPLY {
-			cs := icmpChecksum(reply)
-			if cs != 0 {
-				return fmt.Errorf("Bad ICMP checksum: %x", rchksum)
-			}
+		if ok && rseqnum == xseqnum {
+			break
 		}
-		// TODO(angusc): Validate checksum for IPv6
-		break
 	}
 	return nil
 }
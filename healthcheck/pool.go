@@ -0,0 +1,67 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// This file contains a bounded pool of workers that execute healthchecks,
+// which limits the number of probes that are in progress at any one time.
+
+import (
+	"time"
+)
+
+// workerPool executes probes using a fixed number of worker goroutines.
+type workerPool struct {
+	workers int
+	probes  chan func()
+}
+
+// newWorkerPool returns a workerPool with the given number of running
+// workers.
+func newWorkerPool(workers int) *workerPool {
+	p := &workerPool{
+		workers: workers,
+		probes:  make(chan func()),
+	}
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+// worker executes probes until the probe channel is closed.
+func (p *workerPool) worker() {
+	for probe := range p.probes {
+		probe()
+	}
+}
+
+// submit queues a probe for execution by the next available worker. If wait
+// is positive and no worker becomes available within this time, false is
+// returned and the probe is not executed. Otherwise submit blocks until the
+// probe has been handed to a worker.
+func (p *workerPool) submit(probe func(), wait time.Duration) bool {
+	if wait <= 0 {
+		p.probes <- probe
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case p.probes <- probe:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
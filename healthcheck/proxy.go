@@ -0,0 +1,140 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// This file contains routines for sending PROXY protocol headers, as
+// described at http://www.haproxy.org/download/1.8/doc/proxy-protocol.txt.
+
+import (
+	"fmt"
+	"net"
+)
+
+// ProxyProtocol specifies the version of the PROXY protocol header that is
+// sent at the start of a connection.
+type ProxyProtocol int
+
+const (
+	ProxyProtocolOff ProxyProtocol = iota
+	ProxyProtocolV1
+	ProxyProtocolV2
+)
+
+// String returns the string representation of a ProxyProtocol.
+func (p ProxyProtocol) String() string {
+	switch p {
+	case ProxyProtocolOff:
+		return "off"
+	case ProxyProtocolV1:
+		return "v1"
+	case ProxyProtocolV2:
+		return "v2"
+	}
+	return "(unknown)"
+}
+
+// ParseProxyProtocol returns the ProxyProtocol for the given name.
+func ParseProxyProtocol(name string) (ProxyProtocol, error) {
+	switch name {
+	case "", "off":
+		return ProxyProtocolOff, nil
+	case "v1":
+		return ProxyProtocolV1, nil
+	case "v2":
+		return ProxyProtocolV2, nil
+	}
+	return ProxyProtocolOff, fmt.Errorf("unknown PROXY protocol version %q", name)
+}
+
+// proxyV2Signature is the signature that starts a PROXY protocol v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyV2VersionCommand = 0x21 // Version 2, PROXY command.
+	proxyV2TCPv4          = 0x11
+	proxyV2TCPv6          = 0x21
+	proxyV2TypeAuthority  = 0x02
+)
+
+// proxyHeader returns a PROXY protocol header for a TCP connection from src
+// to dst. For v2 headers, a non-empty authority is included as a TLV.
+func proxyHeader(version ProxyProtocol, src, dst *net.TCPAddr, authority string) ([]byte, error) {
+	src4, dst4 := src.IP.To4(), dst.IP.To4()
+	if (src4 == nil) != (dst4 == nil) {
+		return nil, fmt.Errorf("PROXY source %v and destination %v are different address families", src.IP, dst.IP)
+	}
+
+	switch version {
+	case ProxyProtocolV1:
+		family := "TCP4"
+		if dst4 == nil {
+			family = "TCP6"
+		}
+		return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n",
+			family, src.IP, dst.IP, src.Port, dst.Port)), nil
+
+	case ProxyProtocolV2:
+		family := byte(proxyV2TCPv4)
+		srcIP, dstIP := []byte(src4), []byte(dst4)
+		if dst4 == nil {
+			family = proxyV2TCPv6
+			srcIP, dstIP = src.IP.To16(), dst.IP.To16()
+		}
+		var addrs []byte
+		addrs = append(addrs, srcIP...)
+		addrs = append(addrs, dstIP...)
+		addrs = appendUint16(addrs, uint16(src.Port))
+		addrs = appendUint16(addrs, uint16(dst.Port))
+		if authority != "" {
+			addrs = append(addrs, proxyV2TypeAuthority)
+			addrs = appendUint16(addrs, uint16(len(authority)))
+			addrs = append(addrs, authority...)
+		}
+		hdr := append([]byte{}, proxyV2Signature...)
+		hdr = append(hdr, proxyV2VersionCommand, family)
+		hdr = appendUint16(hdr, uint16(len(addrs)))
+		return append(hdr, addrs...), nil
+	}
+	return nil, fmt.Errorf("unsupported PROXY protocol version %v", version)
+}
+
+// appendUint16 appends the given value to b in network byte order.
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// writeProxyHeader writes a PROXY protocol header to the given connection.
+// The source address is advertised as the given IP, if one is provided, using
+// the local port of the connection. Otherwise the local address of the
+// connection is used.
+func writeProxyHeader(conn net.Conn, version ProxyProtocol, srcIP net.IP, authority string) error {
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("%T is not a *net.TCPAddr", conn.LocalAddr())
+	}
+	remote, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("%T is not a *net.TCPAddr", conn.RemoteAddr())
+	}
+	src := &net.TCPAddr{IP: local.IP, Port: local.Port}
+	if srcIP != nil {
+		src.IP = srcIP
+	}
+	hdr, err := proxyHeader(version, src, remote, authority)
+	if err != nil {
+		return err
+	}
+	return writeFull(conn, hdr)
+}
@@ -217,6 +217,9 @@ func (rp *radiusPacket) decode(b *bytes.Reader) error {
 		if err := attribute.decode(b); err != nil {
 			return err
 		}
+		if uint16(attribute.length) > len {
+			return fmt.Errorf("attribute length %d exceeds remaining packet length %d", attribute.length, len)
+		}
 		rp.attributes = append(rp.attributes, attribute)
 		len -= uint16(attribute.length)
 	}
@@ -304,7 +307,7 @@ func NewRADIUSChecker(ip net.IP, port int) *RADIUSChecker {
 
 // String returns the string representation of a RADIUS healthcheck.
 func (hc *RADIUSChecker) String() string {
-	return fmt.Sprintf("RADIUS %s", hc.Target)
+	return fmt.Sprintf("RADIUS [user %q; response %s] %s", hc.Username, hc.Response, hc.Target)
 }
 
 // Check executes a RADIUS healthcheck.
@@ -316,7 +319,7 @@ func (hc *RADIUSChecker) Check(timeout time.Duration) *Result {
 	}
 	deadline := start.Add(timeout)
 
-	conn, err := dialUDP(hc.network(), hc.addr(), timeout, hc.Mark)
+	conn, err := dialUDP(hc.network(), hc.addr(), timeout, hc.Mark, hc.source())
 	if err != nil {
 		return complete(start, msg, false, err)
 	}
@@ -356,11 +359,12 @@ func (hc *RADIUSChecker) Check(timeout time.Duration) *Result {
 	rp.addAttribute(ra)
 
 	// NAS IP Address.
-	nasIP := net.ParseIP(conn.LocalAddr().String())
-	if ip := nasIP.To4(); ip != nil {
-		ra := &radiusAttribute{raType: ratNASIPAddress}
-		ra.value = ip
-		rp.addAttribute(ra)
+	if laddr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		if ip := laddr.IP.To4(); ip != nil {
+			ra := &radiusAttribute{raType: ratNASIPAddress}
+			ra.value = ip
+			rp.addAttribute(ra)
+		}
 	}
 
 	// NAS Port Type (virtual).
@@ -18,9 +18,11 @@ package healthcheck
 
 import (
 	"bytes"
+	"net"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -306,3 +308,108 @@ func TestRADIUSPacket(t *testing.T) {
 		}
 	}
 }
+
+// radiusServer responds to RADIUS Access-Requests received on the given
+// connection with a response of the given code. Each request is sent on the
+// requests channel, if non-nil. If code is zero, no response is sent.
+func radiusServer(c *net.UDPConn, secret string, code radiusCode, requests chan<- *radiusPacket) {
+	buf := make([]byte, radiusMaximumSize)
+	for {
+		n, addr, err := c.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req := &radiusPacket{}
+		if err := req.decode(bytes.NewReader(buf[:n])); err != nil {
+			continue
+		}
+		if requests != nil {
+			requests <- req
+		}
+		if code == 0 {
+			continue
+		}
+		resp := &radiusPacket{
+			radiusHeader: radiusHeader{
+				Code:       code,
+				Identifier: req.Identifier,
+			},
+		}
+		resp.encode() // Sets the packet length.
+		auth, err := responseAuthenticator(resp, &req.Authenticator, secret)
+		if err != nil {
+			return
+		}
+		resp.Authenticator = *auth
+		if _, err := c.WriteTo(resp.encode(), addr); err != nil {
+			return
+		}
+	}
+}
+
+func TestRADIUSChecker(t *testing.T) {
+	for _, test := range []struct {
+		code     radiusCode
+		secret   string
+		response string
+		expected bool
+		message  string
+	}{
+		{rcAccessAccept, "secret", "accept", true, "got RADIUS Access-Accept response"},
+		{rcAccessReject, "secret", "any", true, "got RADIUS Access-Reject response"},
+		{rcAccessChallenge, "secret", "any", true, "got RADIUS Access-Challenge response"},
+		{rcAccessReject, "secret", "accept", false, "want accept response"},
+		{rcAccessAccept, "wrong", "any", false, "response authenticator mismatch"},
+		{0, "secret", "any", false, "failed to read response"},
+	} {
+		for _, n := range []string{"udp4", "udp6"} {
+			c, a, err := newLocalUDPConn(n)
+			if err != nil {
+				t.Fatalf("Failed to get UDPConn: %v", err)
+			}
+			requests := make(chan *radiusPacket, 1)
+			go radiusServer(c, "secret", test.code, requests)
+
+			hc := NewRADIUSChecker(a.IP, a.Port)
+			hc.Username = "user"
+			hc.Password = "password"
+			hc.Secret = test.secret
+			hc.Response = test.response
+			result := hc.Check(500 * time.Millisecond)
+			c.Close()
+			if result.Success != test.expected {
+				t.Errorf("RADIUS healthcheck %v got success %v, want %v: %v", hc, result.Success, test.expected, result)
+			}
+			if !strings.Contains(result.Message, test.message) {
+				t.Errorf("RADIUS healthcheck %v message %q does not contain %q", hc, result.Message, test.message)
+			}
+
+			var req *radiusPacket
+			select {
+			case req = <-requests:
+			default:
+				t.Fatalf("RADIUS healthcheck %v did not send a request", hc)
+			}
+			if req.Code != rcAccessRequest {
+				t.Errorf("RADIUS healthcheck %v sent %v, want %v", hc, req.Code, rcAccessRequest)
+			}
+			attrs := make(map[radiusAttributeType][]byte)
+			for _, ra := range req.attributes {
+				attrs[ra.raType] = ra.value
+			}
+			if got := string(attrs[ratUserName]); got != "user" {
+				t.Errorf("RADIUS healthcheck %v sent %v %q, want %q", hc, ratUserName, got, "user")
+			}
+			if got, want := attrs[ratUserPassword], radiusPassword("password", test.secret, &req.Authenticator); !bytes.Equal(got, want) {
+				t.Errorf("RADIUS healthcheck %v sent %v %x, want %x", hc, ratUserPassword, got, want)
+			}
+			nasIP, ok := attrs[ratNASIPAddress]
+			if n == "udp4" && !net.IP(nasIP).Equal(a.IP) {
+				t.Errorf("RADIUS healthcheck %v sent %v %v, want %v", hc, ratNASIPAddress, net.IP(nasIP), a.IP)
+			}
+			if n == "udp6" && ok {
+				t.Errorf("RADIUS healthcheck %v sent %v over IPv6", hc, ratNASIPAddress)
+			}
+		}
+	}
+}
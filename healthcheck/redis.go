@@ -0,0 +1,218 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Redis healthcheck implementation.
+
+package healthcheck
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+const (
+	defaultRedisTimeout = 10 * time.Second
+
+	// redisMaxBulkLen is the maximum length of a bulk string reply that
+	// will be read from the server.
+	redisMaxBulkLen = 64 * 1024
+)
+
+// RedisChecker contains configuration specific to a Redis healthcheck.
+type RedisChecker struct {
+	Target
+
+	// Password is sent via AUTH, if non-empty.
+	Password string
+
+	// DB is the database that is selected via SELECT, if non-zero.
+	DB int
+
+	// Role is the replication role (e.g. "master") that the server must
+	// report via INFO replication, if non-empty.
+	Role string
+}
+
+// NewRedisChecker returns an initialised RedisChecker.
+func NewRedisChecker(ip net.IP, port int) *RedisChecker {
+	return &RedisChecker{
+		Target: Target{
+			IP:    ip,
+			Port:  port,
+			Proto: seesaw.IPProtoTCP,
+		},
+	}
+}
+
+// String returns the string representation of a Redis healthcheck.
+func (hc *RedisChecker) String() string {
+	attr := []string{}
+	if hc.Password != "" {
+		attr = append(attr, "auth")
+	}
+	if hc.DB != 0 {
+		attr = append(attr, fmt.Sprintf("db %d", hc.DB))
+	}
+	if hc.Role != "" {
+		attr = append(attr, fmt.Sprintf("role %s", hc.Role))
+	}
+	var s string
+	if len(attr) > 0 {
+		s = fmt.Sprintf(" [%s]", strings.Join(attr, "; "))
+	}
+	return fmt.Sprintf("Redis%s %s", s, hc.Target)
+}
+
+// redisCommand returns the RESP encoding of the given command.
+func redisCommand(args ...string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return b.Bytes()
+}
+
+// readRedisReply reads a single RESP reply. Only simple string, error,
+// integer and bulk string replies are supported.
+func readRedisReply(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("malformed reply %q", snippet([]byte(line)))
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("error reply %q", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("malformed bulk reply length %q", line[1:])
+		}
+		if n < 0 {
+			return "", errors.New("nil bulk reply")
+		}
+		if n > redisMaxBulkLen {
+			return "", fmt.Errorf("bulk reply length %d exceeds maximum of %d", n, redisMaxBulkLen)
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		if !bytes.HasSuffix(buf, []byte("\r\n")) {
+			return "", errors.New("malformed bulk reply")
+		}
+		return string(buf[:n]), nil
+	}
+	return "", fmt.Errorf("unsupported reply type %q", line[0])
+}
+
+// redisRole returns the replication role from an INFO replication reply.
+func redisRole(info string) string {
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "role:") {
+			return strings.TrimPrefix(line, "role:")
+		}
+	}
+	return ""
+}
+
+// Check executes a Redis healthcheck.
+func (hc *RedisChecker) Check(timeout time.Duration) *Result {
+	msg := fmt.Sprintf("Redis to %s", hc.addr())
+	start := time.Now()
+	if timeout == time.Duration(0) {
+		timeout = defaultRedisTimeout
+	}
+
+	conn, err := dialTCP(hc.network(), hc.addr(), hc.connectTimeout(timeout), hc.Mark, hc.source())
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to connect", msg)
+		return complete(start, msg, false, err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(start.Add(timeout))
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to set deadline", msg)
+		return complete(start, msg, false, err)
+	}
+
+	r := bufio.NewReader(conn)
+	command := func(args ...string) (string, error) {
+		if err := writeFull(conn, redisCommand(args...)); err != nil {
+			return "", err
+		}
+		return readRedisReply(r)
+	}
+
+	if hc.Password != "" {
+		reply, err := command("AUTH", hc.Password)
+		if err == nil && reply != "OK" {
+			err = fmt.Errorf("unexpected reply %q", reply)
+		}
+		if err != nil {
+			msg = fmt.Sprintf("%s; AUTH failed", msg)
+			return complete(start, msg, false, err)
+		}
+	}
+	if hc.DB != 0 {
+		reply, err := command("SELECT", strconv.Itoa(hc.DB))
+		if err == nil && reply != "OK" {
+			err = fmt.Errorf("unexpected reply %q", reply)
+		}
+		if err != nil {
+			msg = fmt.Sprintf("%s; SELECT %d failed", msg, hc.DB)
+			return complete(start, msg, false, err)
+		}
+	}
+
+	reply, err := command("PING")
+	if err != nil {
+		msg = fmt.Sprintf("%s; PING failed", msg)
+		return complete(start, msg, false, err)
+	}
+	if reply != "PONG" {
+		msg = fmt.Sprintf("%s; unexpected PING reply %q", msg, snippet([]byte(reply)))
+		return complete(start, msg, false, nil)
+	}
+
+	if hc.Role != "" {
+		info, err := command("INFO", "replication")
+		if err != nil {
+			msg = fmt.Sprintf("%s; INFO replication failed", msg)
+			return complete(start, msg, false, err)
+		}
+		if role := redisRole(info); role != hc.Role {
+			msg = fmt.Sprintf("%s; got role %q, want %q", msg, role, hc.Role)
+			return complete(start, msg, false, nil)
+		}
+	}
+	return complete(start, msg, true, nil)
+}
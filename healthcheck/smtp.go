@@ -0,0 +1,215 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// SMTP healthcheck implementation.
+
+package healthcheck
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wy2745/seesaw/common/seesaw"
+)
+
+const (
+	defaultSMTPTimeout = 10 * time.Second
+
+	defaultSMTPGreetingCode = 220
+	defaultSMTPEHLOCode     = 250
+	smtpStartTLSCode        = 220
+	smtpQuitCode            = 221
+)
+
+// SMTPChecker contains configuration specific to an SMTP healthcheck.
+type SMTPChecker struct {
+	Target
+
+	// Hostname is the name that is sent with the EHLO command. If empty,
+	// the local hostname is used.
+	Hostname string
+
+	// StartTLS requires that the server supports STARTTLS, with EHLO
+	// being sent again once TLS has been negotiated.
+	StartTLS  bool
+	TLSVerify bool
+
+	// TLSServerName is the server name that is sent via SNI and that
+	// the certificate is verified against. If empty, the target IP
+	// address is used.
+	TLSServerName string
+
+	// GreetingCode and EHLOCode are the response codes that are expected
+	// for the server greeting and the EHLO command respectively.
+	GreetingCode int
+	EHLOCode     int
+}
+
+// NewSMTPChecker returns an initialised SMTPChecker.
+func NewSMTPChecker(ip net.IP, port int) *SMTPChecker {
+	return &SMTPChecker{
+		Target: Target{
+			IP:    ip,
+			Port:  port,
+			Proto: seesaw.IPProtoTCP,
+		},
+		TLSVerify:    true,
+		GreetingCode: defaultSMTPGreetingCode,
+		EHLOCode:     defaultSMTPEHLOCode,
+	}
+}
+
+// String returns the string representation of an SMTP healthcheck.
+func (hc *SMTPChecker) String() string {
+	attr := []string{}
+	if hc.Hostname != "" {
+		attr = append(attr, fmt.Sprintf("EHLO %s", hc.Hostname))
+	}
+	if hc.StartTLS {
+		attr = append(attr, "starttls")
+		if hc.TLSVerify {
+			attr = append(attr, "verify")
+		}
+		if hc.TLSServerName != "" {
+			attr = append(attr, fmt.Sprintf("server name %s", hc.TLSServerName))
+		}
+	}
+	var s string
+	if len(attr) > 0 {
+		s = fmt.Sprintf(" [%s]", strings.Join(attr, "; "))
+	}
+	return fmt.Sprintf("SMTP%s %s", s, hc.Target)
+}
+
+// hostname returns the name to send with the EHLO command.
+func (hc *SMTPChecker) hostname() string {
+	if hc.Hostname != "" {
+		return hc.Hostname
+	}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		return name
+	}
+	return "localhost"
+}
+
+// ehlo sends an EHLO command and returns the lines of the response.
+func (hc *SMTPChecker) ehlo(tp *textproto.Conn) ([]string, error) {
+	if _, err := tp.Cmd("EHLO %s", hc.hostname()); err != nil {
+		return nil, err
+	}
+	_, resp, err := tp.ReadResponse(hc.EHLOCode)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(resp, "\n"), nil
+}
+
+// Check executes an SMTP healthcheck.
+func (hc *SMTPChecker) Check(timeout time.Duration) *Result {
+	msg := fmt.Sprintf("SMTP to %s", hc.addr())
+	start := time.Now()
+	if timeout == time.Duration(0) {
+		timeout = defaultSMTPTimeout
+	}
+
+	tcpConn, err := dialTCP(hc.network(), hc.addr(), hc.connectTimeout(timeout), hc.Mark, hc.source())
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to connect", msg)
+		return complete(start, msg, false, err)
+	}
+	conn := net.Conn(tcpConn)
+	defer conn.Close()
+
+	err = conn.SetDeadline(start.Add(timeout))
+	if err != nil {
+		msg = fmt.Sprintf("%s; failed to set deadline", msg)
+		return complete(start, msg, false, err)
+	}
+
+	tp := textproto.NewConn(conn)
+	code, greeting, err := tp.ReadResponse(hc.GreetingCode)
+	if err != nil {
+		msg = fmt.Sprintf("%s; unexpected greeting %d %q", msg, code, greeting)
+		return complete(start, msg, false, err)
+	}
+	if i := strings.Index(greeting, "\n"); i >= 0 {
+		greeting = greeting[:i]
+	}
+	msg = fmt.Sprintf("%s (greeting %q)", msg, greeting)
+
+	ext, err := hc.ehlo(tp)
+	if err != nil {
+		msg = fmt.Sprintf("%s; EHLO failed", msg)
+		return complete(start, msg, false, err)
+	}
+
+	if hc.StartTLS {
+		supported := false
+		for _, e := range ext[1:] {
+			if f := strings.Fields(e); len(f) > 0 && strings.EqualFold(f[0], "STARTTLS") {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			msg = fmt.Sprintf("%s; STARTTLS not supported", msg)
+			return complete(start, msg, false, nil)
+		}
+		if _, err := tp.Cmd("STARTTLS"); err != nil {
+			msg = fmt.Sprintf("%s; STARTTLS failed", msg)
+			return complete(start, msg, false, err)
+		}
+		if _, _, err := tp.ReadResponse(smtpStartTLSCode); err != nil {
+			msg = fmt.Sprintf("%s; STARTTLS failed", msg)
+			return complete(start, msg, false, err)
+		}
+
+		host, _, err := net.SplitHostPort(hc.addr())
+		if err != nil {
+			msg = msg + "; failed to split host"
+			return complete(start, msg, false, err)
+		}
+		if hc.TLSServerName != "" {
+			host = hc.TLSServerName
+		}
+		tlsConn := tls.Client(conn, &tls.Config{
+			InsecureSkipVerify: !hc.TLSVerify,
+			ServerName:         host,
+		})
+		if err := tlsConn.Handshake(); err != nil {
+			msg = fmt.Sprintf("%s; TLS handshake failed", msg)
+			return complete(start, msg, false, err)
+		}
+		tp = textproto.NewConn(tlsConn)
+		if _, err := hc.ehlo(tp); err != nil {
+			msg = fmt.Sprintf("%s; EHLO after STARTTLS failed", msg)
+			return complete(start, msg, false, err)
+		}
+	}
+
+	if _, err := tp.Cmd("QUIT"); err != nil {
+		msg = fmt.Sprintf("%s; QUIT failed", msg)
+		return complete(start, msg, false, err)
+	}
+	if _, _, err := tp.ReadResponse(smtpQuitCode); err != nil {
+		msg = fmt.Sprintf("%s; QUIT failed", msg)
+		return complete(start, msg, false, err)
+	}
+	return complete(start, msg, true, nil)
+}
@@ -0,0 +1,172 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+// This file contains helper routines for TLS based healthchecks.
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// loadClientCert loads a TLS client certificate and its private key from
+// the given PEM encoded files.
+func loadClientCert(certFile, keyFile string) (*tls.Certificate, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both a client certificate and key file must be specified")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate %q with key %q: %v", certFile, keyFile, err)
+	}
+	return &cert, nil
+}
+
+// tlsVersionNames maps TLS protocol versions to their names.
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsVersionName returns the name of the given TLS protocol version.
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersionNames[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("TLS version 0x%04x", version)
+}
+
+// tlsConstraints specifies the TLS protocol versions and cipher suites that
+// a backend must negotiate. Zero values impose no constraint.
+type tlsConstraints struct {
+	minVersion   uint16
+	maxVersion   uint16
+	cipherSuites []uint16
+}
+
+// constrained returns true if any TLS constraints are specified.
+func (c *tlsConstraints) constrained() bool {
+	return c.minVersion != 0 || c.maxVersion != 0 || len(c.cipherSuites) > 0
+}
+
+// attrs returns the string representation of the TLS constraints, for use
+// in the string representation of a healthcheck.
+func (c *tlsConstraints) attrs() []string {
+	attr := []string{}
+	if c.minVersion != 0 {
+		attr = append(attr, fmt.Sprintf("min %s", tlsVersionName(c.minVersion)))
+	}
+	if c.maxVersion != 0 {
+		attr = append(attr, fmt.Sprintf("max %s", tlsVersionName(c.maxVersion)))
+	}
+	if len(c.cipherSuites) > 0 {
+		attr = append(attr, fmt.Sprintf("%d cipher suites", len(c.cipherSuites)))
+	}
+	return attr
+}
+
+// apply configures the given TLS configuration to enforce the constraints.
+//
+// Rather than refusing to offer versions below the minimum, all versions
+// from TLS 1.0 are offered and the negotiated version is verified once the
+// handshake completes, so that the failure reports what the backend
+// actually negotiated. The cipher suites for TLS 1.3 cannot be configured,
+// hence the negotiated cipher suite is also verified - if TLS 1.3 is
+// permitted, its cipher suites must be listed explicitly.
+func (c *tlsConstraints) apply(cfg *tls.Config) {
+	if !c.constrained() {
+		return
+	}
+	if c.minVersion != 0 {
+		cfg.MinVersion = tls.VersionTLS10
+		if c.minVersion < cfg.MinVersion {
+			cfg.MinVersion = c.minVersion
+		}
+	}
+	cfg.MaxVersion = c.maxVersion
+	cfg.CipherSuites = c.cipherSuites
+	cfg.VerifyConnection = c.verify
+}
+
+// verify checks that the negotiated TLS version and cipher suite meet the
+// constraints.
+func (c *tlsConstraints) verify(state tls.ConnectionState) error {
+	if c.minVersion != 0 && state.Version < c.minVersion {
+		return fmt.Errorf("negotiated %s, minimum is %s", tlsVersionName(state.Version), tlsVersionName(c.minVersion))
+	}
+	if c.maxVersion != 0 && state.Version > c.maxVersion {
+		return fmt.Errorf("negotiated %s, maximum is %s", tlsVersionName(state.Version), tlsVersionName(c.maxVersion))
+	}
+	if len(c.cipherSuites) == 0 {
+		return nil
+	}
+	for _, cs := range c.cipherSuites {
+		if cs == state.CipherSuite {
+			return nil
+		}
+	}
+	return fmt.Errorf("negotiated %s with cipher suite %s, which is not permitted",
+		tlsVersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+}
+
+// tlsStateString returns a description of the negotiated TLS version and
+// cipher suite.
+func tlsStateString(state *tls.ConnectionState) string {
+	return fmt.Sprintf("%s with %s", tlsVersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+}
+
+// certExpiry describes the expiry of the leaf certificate that was
+// presented by a backend.
+type certExpiry struct {
+	notAfter time.Time
+	expiring bool
+	expired  bool
+}
+
+// checkCertExpiry determines whether the leaf certificate presented by the
+// peer has expired or will expire within the given warning window. nil is
+// returned if no certificate was presented.
+func checkCertExpiry(state *tls.ConnectionState, warn time.Duration, now time.Time) *certExpiry {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	notAfter := state.PeerCertificates[0].NotAfter
+	expired := !now.Before(notAfter)
+	return &certExpiry{
+		notAfter: notAfter,
+		expiring: expired || (warn > 0 && notAfter.Sub(now) < warn),
+		expired:  expired,
+	}
+}
+
+// String returns the string representation of the certificate expiry.
+func (e *certExpiry) String() string {
+	if e.expired {
+		return fmt.Sprintf("certificate expired at %v", e.notAfter.UTC())
+	}
+	return fmt.Sprintf("certificate expires at %v", e.notAfter.UTC())
+}
+
+// annotate records the certificate expiry in the given result.
+func (e *certExpiry) annotate(r *Result) *Result {
+	if e != nil {
+		r.CertExpiry, r.CertExpiring = e.notAfter, e.expiring
+	}
+	return r
+}
@@ -21,6 +21,7 @@ package healthcheck
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
@@ -28,6 +29,9 @@ import (
 
 const (
 	defaultUDPTimeout = 5 * time.Second
+
+	// udpMaxDatagram is the maximum size of a UDP datagram.
+	udpMaxDatagram = 65535
 )
 
 // UDPChecker contains configuration specific to a UDP healthcheck.
@@ -35,6 +39,13 @@ type UDPChecker struct {
 	Target
 	Receive string
 	Send    string
+
+	// Expect is a regexp that the response datagram must match.
+	Expect string
+
+	// ReadTimeout bounds the time spent waiting for a response. If zero,
+	// the healthcheck timeout applies.
+	ReadTimeout time.Duration
 }
 
 // NewUDPChecker returns an initialised UDPChecker.
@@ -50,6 +61,9 @@ func NewUDPChecker(ip net.IP, port int) *UDPChecker {
 
 // String returns the string representation of a UDP healthcheck.
 func (hc *UDPChecker) String() string {
+	if hc.Expect != "" {
+		return fmt.Sprintf("UDP [expect %q] %s", hc.Expect, hc.Target)
+	}
 	return fmt.Sprintf("UDP %s", hc.Target)
 }
 
@@ -62,7 +76,16 @@ func (hc *UDPChecker) Check(timeout time.Duration) *Result {
 	}
 	deadline := start.Add(timeout)
 
-	conn, err := dialUDP(hc.network(), hc.addr(), timeout, hc.Mark)
+	var expect *regexp.Regexp
+	if hc.Expect != "" {
+		var err error
+		if expect, err = regexp.Compile(hc.Expect); err != nil {
+			msg = fmt.Sprintf("%s; invalid expect regexp", msg)
+			return complete(start, msg, false, err)
+		}
+	}
+
+	conn, err := dialUDP(hc.network(), hc.addr(), timeout, hc.Mark, hc.source())
 	if err != nil {
 		msg = fmt.Sprintf("%s; failed to create socket", msg)
 		return complete(start, msg, false, err)
@@ -80,17 +103,37 @@ func (hc *UDPChecker) Check(timeout time.Duration) *Result {
 		return complete(start, msg, false, err)
 	}
 
-	buf := make([]byte, len(hc.Receive))
+	if hc.ReadTimeout > 0 {
+		if d := time.Now().Add(hc.ReadTimeout); d.Before(deadline) {
+			if err := conn.SetReadDeadline(d); err != nil {
+				msg = fmt.Sprintf("%s; failed to set read deadline", msg)
+				return complete(start, msg, false, err)
+			}
+		}
+	}
+
+	size := len(hc.Receive)
+	if expect != nil {
+		size = udpMaxDatagram
+	}
+	buf := make([]byte, size)
 	n, _, err := conn.ReadFrom(buf)
 	if err != nil {
 		msg = fmt.Sprintf("%s; failed to read response", msg)
 		return complete(start, msg, false, err)
 	}
 
-	got := string(buf[0:n])
-	if got != hc.Receive {
+	got := buf[0:n]
+	if len(got) > len(hc.Receive) {
+		got = got[:len(hc.Receive)]
+	}
+	if string(got) != hc.Receive {
 		msg = fmt.Sprintf("%s; unexpected response - %q", msg, got)
 		return complete(start, msg, false, err)
 	}
+	if expect != nil && !expect.Match(buf[0:n]) {
+		msg = fmt.Sprintf("%s; response %q does not match %q", msg, snippet(buf[0:n]), hc.Expect)
+		return complete(start, msg, false, err)
+	}
 	return complete(start, msg, true, err)
 }
@@ -0,0 +1,240 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipvs
+
+// This file contains functions for applying a batch of IPVS service and
+// destination changes.
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wy2745/seesaw/netlink"
+)
+
+/*
+#include <linux/types.h>
+#include <linux/ip_vs.h>
+*/
+import "C"
+
+// BatchOp specifies the type of an operation in an IPVS batch.
+type BatchOp int
+
+const (
+	BatchAddService BatchOp = iota
+	BatchUpdateService
+	BatchDeleteService
+	BatchAddDestination
+	BatchUpdateDestination
+	BatchDeleteDestination
+)
+
+var batchOpNames = map[BatchOp]string{
+	BatchAddService:        "add service",
+	BatchUpdateService:     "update service",
+	BatchDeleteService:     "delete service",
+	BatchAddDestination:    "add destination",
+	BatchUpdateDestination: "update destination",
+	BatchDeleteDestination: "delete destination",
+}
+
+// String returns the name for the given batch operation.
+func (op BatchOp) String() string {
+	if name, ok := batchOpNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("BatchOp(%d)", op)
+}
+
+// BatchEntry is a single operation in an IPVS batch. The destination is only
+// used by destination operations.
+type BatchEntry struct {
+	Op          BatchOp
+	Service     Service
+	Destination Destination
+}
+
+// String returns a string representation of the batch entry.
+func (e *BatchEntry) String() string {
+	switch e.Op {
+	case BatchAddDestination, BatchUpdateDestination, BatchDeleteDestination:
+		return fmt.Sprintf("%v %v on %v", e.Op, e.Destination, e.Service)
+	}
+	return fmt.Sprintf("%v %v", e.Op, e.Service)
+}
+
+// command returns the IPVS netlink command and attributes for the batch
+// entry.
+func (e *BatchEntry) command() (int, *ipvsCommand, error) {
+	ic := &ipvsCommand{Service: newIPVSService(&e.Service)}
+	switch e.Op {
+	case BatchAddService:
+		return C.IPVS_CMD_NEW_SERVICE, ic, nil
+	case BatchUpdateService:
+		return C.IPVS_CMD_SET_SERVICE, ic, nil
+	case BatchDeleteService:
+		return C.IPVS_CMD_DEL_SERVICE, ic, nil
+	}
+	ic.Destination = newIPVSDestination(&e.Destination)
+	switch e.Op {
+	case BatchAddDestination:
+		return C.IPVS_CMD_NEW_DEST, ic, nil
+	case BatchUpdateDestination:
+		return C.IPVS_CMD_SET_DEST, ic, nil
+	case BatchDeleteDestination:
+		return C.IPVS_CMD_DEL_DEST, ic, nil
+	}
+	return 0, nil, fmt.Errorf("unknown batch operation %v", e.Op)
+}
+
+// Batch accumulates IPVS service and destination changes, so that they can
+// be applied to the IPVS table with a single call to ApplyBatch. Unlike
+// AddService, adding a service to a batch does not add its destinations.
+type Batch struct {
+	Entries []BatchEntry
+}
+
+// Len returns the number of operations in the batch.
+func (b *Batch) Len() int {
+	return len(b.Entries)
+}
+
+func (b *Batch) add(op BatchOp, svc Service, dst Destination) {
+	b.Entries = append(b.Entries, BatchEntry{Op: op, Service: svc, Destination: dst})
+}
+
+// AddService adds the specified service to the batch.
+func (b *Batch) AddService(svc Service) {
+	b.add(BatchAddService, svc, Destination{})
+}
+
+// UpdateService adds an update of the specified service to the batch.
+func (b *Batch) UpdateService(svc Service) {
+	b.add(BatchUpdateService, svc, Destination{})
+}
+
+// DeleteService adds a deletion of the specified service to the batch.
+func (b *Batch) DeleteService(svc Service) {
+	b.add(BatchDeleteService, svc, Destination{})
+}
+
+// AddDestination adds the specified destination to the batch.
+func (b *Batch) AddDestination(svc Service, dst Destination) {
+	b.add(BatchAddDestination, svc, dst)
+}
+
+// UpdateDestination adds an update of the specified destination to the
+// batch.
+func (b *Batch) UpdateDestination(svc Service, dst Destination) {
+	b.add(BatchUpdateDestination, svc, dst)
+}
+
+// DeleteDestination adds a deletion of the specified destination to the
+// batch.
+func (b *Batch) DeleteDestination(svc Service, dst Destination) {
+	b.add(BatchDeleteDestination, svc, dst)
+}
+
+// BatchResult contains the outcome of applying an IPVS batch.
+type BatchResult struct {
+	// Errors contains the error for each entry in the batch, in order.
+	// An empty string indicates that the entry was applied successfully.
+	Errors []string
+
+	// Duration is the time taken to apply the batch.
+	Duration time.Duration
+}
+
+// Failed returns the number of batch entries that failed to apply.
+func (r *BatchResult) Failed() int {
+	n := 0
+	for _, err := range r.Errors {
+		if err != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// ApplyBatch applies the operations in the specified batch to the IPVS
+// table, in order. The operations are sent as netlink messages on a single
+// socket, with several messages being packed into each send, and the
+// acknowledgements are matched to the operations by sequence number, so that
+// failures can be attributed to individual operations. The batch is not
+// applied atomically - a failed operation does not prevent the remaining
+// operations from being applied and the outcome of each operation is
+// reported in the result. An error is only returned if the batch could not be
+// applied at all.
+func ApplyBatch(b *Batch) (*BatchResult, error) {
+	start := time.Now()
+	result := &BatchResult{Errors: make([]string, len(b.Entries))}
+
+	var msgs []*netlink.Message
+	var entries []int
+	defer func() {
+		for _, msg := range msgs {
+			msg.Free()
+		}
+	}()
+	for i := range b.Entries {
+		msg, err := batchEntryMessage(&b.Entries[i])
+		if err != nil {
+			result.Errors[i] = fmt.Sprintf("%v: %v", &b.Entries[i], err)
+			continue
+		}
+		msgs = append(msgs, msg)
+		entries = append(entries, i)
+	}
+
+	if len(msgs) > 0 {
+		s, err := netlink.NewSocket()
+		if err != nil {
+			return nil, err
+		}
+		defer s.Close()
+
+		errs, err := s.SendBatch(msgs)
+		if err != nil {
+			return nil, err
+		}
+		for j, err := range errs {
+			if err != nil {
+				i := entries[j]
+				result.Errors[i] = fmt.Sprintf("%v: %v", &b.Entries[i], err)
+			}
+		}
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// batchEntryMessage returns the netlink message for a batch entry. The
+// message must be freed by the caller.
+func batchEntryMessage(e *BatchEntry) (*netlink.Message, error) {
+	cmd, ic, err := e.command()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := netlink.NewMessage(cmd, family, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := msg.Marshal(ic); err != nil {
+		msg.Free()
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipvs
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestBatchCommands(t *testing.T) {
+	svc := Service{
+		Address:   net.ParseIP("192.168.255.1"),
+		Protocol:  syscall.IPPROTO_TCP,
+		Port:      80,
+		Scheduler: "wlc",
+	}
+	dst := Destination{
+		Address: net.ParseIP("192.168.36.2"),
+		Port:    80,
+		Weight:  1,
+	}
+
+	var b Batch
+	b.AddService(svc)
+	b.UpdateService(svc)
+	b.DeleteService(svc)
+	b.AddDestination(svc, dst)
+	b.UpdateDestination(svc, dst)
+	b.DeleteDestination(svc, dst)
+	if got, want := b.Len(), 6; got != want {
+		t.Fatalf("Batch has %d entries, want %d", got, want)
+	}
+
+	cmds := make(map[int]bool)
+	for i, e := range b.Entries {
+		if e.Op != BatchOp(i) {
+			t.Errorf("Entry %d has op %v, want %v", i, e.Op, BatchOp(i))
+		}
+		cmd, ic, err := e.command()
+		if err != nil {
+			t.Errorf("Entry %d (%v) command() failed: %v", i, &e, err)
+			continue
+		}
+		if cmds[cmd] {
+			t.Errorf("Entry %d (%v) has duplicate command %d", i, &e, cmd)
+		}
+		cmds[cmd] = true
+		if ic.Service == nil {
+			t.Errorf("Entry %d (%v) has no service", i, &e)
+		}
+		isDst := e.Op >= BatchAddDestination
+		if got := ic.Destination != nil; got != isDst {
+			t.Errorf("Entry %d (%v) has destination = %v, want %v", i, &e, got, isDst)
+		}
+	}
+
+	e := BatchEntry{Op: BatchOp(42), Service: svc}
+	if _, _, err := e.command(); err == nil {
+		t.Errorf("command() succeeded for unknown op %v", e.Op)
+	}
+}
+
+func TestBatchResultFailed(t *testing.T) {
+	r := &BatchResult{Errors: []string{"", "add service: file exists", "", "delete destination: no such file"}}
+	if got, want := r.Failed(), 2; got != want {
+		t.Errorf("Failed() = %d, want %d", got, want)
+	}
+}
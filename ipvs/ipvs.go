@@ -18,6 +18,7 @@
 package ipvs
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net"
@@ -90,12 +91,22 @@ func newIPVSService(svc *Service) *ipvsService {
 		PersistenceEngine: svc.PersistenceEngine,
 	}
 
+	// The netmask is used to group clients for persistence. For IPv4 it is
+	// a mask in network byte order, while for IPv6 it is a prefix length.
 	if ip4 := svc.Address.To4(); ip4 != nil {
 		ipvsSvc.AddrFamily = syscall.AF_INET
 		ipvsSvc.Netmask = 0xffffffff
+		if len(svc.Netmask) == net.IPv4len {
+			var b [4]byte
+			copy(b[:], svc.Netmask)
+			ipvsSvc.Netmask = *(*uint32)(unsafe.Pointer(&b))
+		}
 	} else {
 		ipvsSvc.AddrFamily = syscall.AF_INET6
 		ipvsSvc.Netmask = 128
+		if ones, bits := svc.Netmask.Size(); bits == 8*net.IPv6len {
+			ipvsSvc.Netmask = uint32(ones)
+		}
 	}
 
 	return ipvsSvc
@@ -139,6 +150,21 @@ func (ipvsSvc ipvsService) toService() *Service {
 		}
 	}
 
+	// A netmask that covers the entire address is represented as a nil
+	// netmask.
+	switch ipvsSvc.AddrFamily {
+	case syscall.AF_INET:
+		if ipvsSvc.Netmask != 0xffffffff {
+			var b [4]byte
+			*(*uint32)(unsafe.Pointer(&b)) = ipvsSvc.Netmask
+			svc.Netmask = net.IPMask(b[:])
+		}
+	case syscall.AF_INET6:
+		if ipvsSvc.Netmask < 8*net.IPv6len {
+			svc.Netmask = net.CIDRMask(int(ipvsSvc.Netmask), 8*net.IPv6len)
+		}
+	}
+
 	if ipvsSvc.Stats != nil {
 		*svc.Statistics = *ipvsSvc.Stats
 	}
@@ -221,6 +247,12 @@ const (
 	SFPersistent ServiceFlags = ipvsSvcFlagPersist
 	SFHashed     ServiceFlags = ipvsSvcFlagHashed
 	SFOnePacket  ServiceFlags = ipvsSvcFlagOnePacket
+
+	// Scheduler specific flags. For the sh and mh schedulers, SFSched1
+	// enables fallback and SFSched2 includes the source port in the hash.
+	SFSched1 ServiceFlags = ipvsSvcFlagSched1
+	SFSched2 ServiceFlags = ipvsSvcFlagSched2
+	SFSched3 ServiceFlags = ipvsSvcFlagSched3
 )
 
 // Service represents an IPVS service.
@@ -232,6 +264,7 @@ type Service struct {
 	Scheduler         string
 	Flags             ServiceFlags
 	Timeout           uint32
+	Netmask           net.IPMask // for persistence; nil covers the entire address
 	PersistenceEngine string
 	Statistics        *ServiceStats
 	Destinations      []*Destination
@@ -246,6 +279,7 @@ func (svc Service) Equal(other Service) bool {
 		svc.Scheduler == other.Scheduler &&
 		svc.Flags == other.Flags &&
 		svc.Timeout == other.Timeout &&
+		bytes.Equal(svc.Netmask, other.Netmask) &&
 		svc.PersistenceEngine == other.PersistenceEngine
 }
 
@@ -333,6 +367,9 @@ const (
 	ipvsSvcFlagPersist   = 0x1
 	ipvsSvcFlagHashed    = 0x2
 	ipvsSvcFlagOnePacket = 0x4
+	ipvsSvcFlagSched1    = 0x8
+	ipvsSvcFlagSched2    = 0x10
+	ipvsSvcFlagSched3    = 0x20
 
 	ipvsDstFlagFwdMask   = 0x7
 	ipvsDstFlagFwdMasq   = 0x0
@@ -0,0 +1,99 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipvs
+
+// This file contains functions for determining whether the running kernel
+// supports a given IPVS scheduler.
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	osReleaseFile = "/proc/sys/kernel/osrelease"
+	sysModuleDir  = "/sys/module"
+	modulesDir    = "/lib/modules"
+)
+
+// SchedulerSupported returns true if the running kernel supports the named
+// IPVS scheduler. A scheduler is supported if its module is loaded, built
+// into the kernel or available to be loaded on demand.
+func SchedulerSupported(name string) (bool, error) {
+	release, err := ioutil.ReadFile(osReleaseFile)
+	if err != nil {
+		return false, err
+	}
+	dir := filepath.Join(modulesDir, strings.TrimSpace(string(release)))
+	return schedulerSupported(name, sysModuleDir, dir)
+}
+
+// schedulerSupported returns true if the module for the named IPVS scheduler
+// is loaded per the given sysfs module directory, or is listed as built-in
+// or loadable in the given kernel modules directory.
+func schedulerSupported(name, sysModules, modules string) (bool, error) {
+	module := "ip_vs_" + name
+	if _, err := os.Stat(filepath.Join(sysModules, module)); err == nil {
+		return true, nil
+	}
+
+	found := 0
+	for _, f := range []string{"modules.builtin", "modules.dep"} {
+		ok, err := moduleListed(filepath.Join(modules, f), module)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+		found++
+	}
+	if found == 0 {
+		return false, fmt.Errorf("no module lists found in %s", modules)
+	}
+	return false, nil
+}
+
+// moduleListed returns true if the given module is listed in a modules.dep
+// or modules.builtin file. Modules are listed by path, optionally followed
+// by a colon and their dependencies, and may be compressed.
+func moduleListed(file, module string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		p := strings.TrimSpace(s.Text())
+		if i := strings.Index(p, ":"); i >= 0 {
+			p = p[:i]
+		}
+		base := path.Base(p)
+		if base == module+".ko" || strings.HasPrefix(base, module+".ko.") {
+			return true, nil
+		}
+	}
+	return false, s.Err()
+}
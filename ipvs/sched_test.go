@@ -0,0 +1,74 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipvs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchedulerSupported(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipvs-sched")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sysModules := filepath.Join(dir, "sys")
+	modules := filepath.Join(dir, "modules")
+	for _, d := range []string{filepath.Join(sysModules, "ip_vs_wrr"), modules} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	// Without any module lists, support cannot be determined.
+	if _, err := schedulerSupported("mh", sysModules, modules); err == nil {
+		t.Errorf("schedulerSupported without module lists succeeded, want error")
+	}
+
+	files := map[string]string{
+		"modules.builtin": "kernel/net/netfilter/ipvs/ip_vs_rr.ko\n",
+		"modules.dep": "kernel/net/netfilter/ipvs/ip_vs.ko.xz: kernel/net/netfilter/nf_conntrack.ko.xz\n" +
+			"kernel/net/netfilter/ipvs/ip_vs_sh.ko.xz: kernel/net/netfilter/ipvs/ip_vs.ko.xz\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(modules, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{"rr", true},
+		{"wrr", true},
+		{"sh", true},
+		{"mh", false},
+		{"s", false},
+	} {
+		got, err := schedulerSupported(test.name, sysModules, modules)
+		if err != nil {
+			t.Errorf("schedulerSupported(%q) failed: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("schedulerSupported(%q) = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
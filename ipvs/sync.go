@@ -0,0 +1,115 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipvs
+
+// This file contains functions for controlling the IPVS connection
+// synchronisation daemons.
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/wy2745/seesaw/netlink"
+)
+
+/*
+#include <linux/types.h>
+#include <linux/ip_vs.h>
+*/
+import "C"
+
+// SyncState specifies the state of an IPVS connection synchronisation daemon.
+type SyncState uint32
+
+const (
+	SyncMaster SyncState = C.IP_VS_STATE_MASTER
+	SyncBackup SyncState = C.IP_VS_STATE_BACKUP
+)
+
+// String returns the name for the given sync state.
+func (s SyncState) String() string {
+	switch s {
+	case SyncMaster:
+		return "master"
+	case SyncBackup:
+		return "backup"
+	}
+	return fmt.Sprintf("SyncState(%d)", s)
+}
+
+// SyncDaemon represents an IPVS connection synchronisation daemon. A daemon
+// in the master state multicasts connection state changes, while a daemon in
+// the backup state receives them and updates the local connection table.
+type SyncDaemon struct {
+	State     SyncState
+	Interface string // The interface used for multicast.
+	SyncID    uint8  // Only messages with a matching sync ID are accepted.
+	Group     net.IP // The multicast group; if nil the kernel default is used.
+	Port      uint16 // The multicast port; if zero the kernel default is used.
+	TTL       uint8  // The multicast TTL; if zero the kernel default is used.
+}
+
+// String returns a string representation of the sync daemon.
+func (d *SyncDaemon) String() string {
+	return fmt.Sprintf("%v sync daemon on %s (sync ID %d)", d.State, d.Interface, d.SyncID)
+}
+
+type ipvsDaemon struct {
+	State     SyncState         `netlink:"attr:1"`
+	Interface string            `netlink:"attr:2,omitempty,optional"`
+	SyncID    *uint32           `netlink:"attr:3,optional"`
+	Group     [net.IPv4len]byte `netlink:"attr:5,omitempty,optional"`
+	Group6    [net.IPv6len]byte `netlink:"attr:6,omitempty,optional"`
+	Port      uint16            `netlink:"attr:7,network,omitempty,optional"`
+	TTL       uint8             `netlink:"attr:8,omitempty,optional"`
+}
+
+type ipvsDaemonCommand struct {
+	Daemon *ipvsDaemon `netlink:"attr:3"`
+}
+
+// newIPVSDaemon converts a sync daemon to its IPVS representation.
+func newIPVSDaemon(d *SyncDaemon) *ipvsDaemon {
+	syncID := uint32(d.SyncID)
+	ipvsDaemon := &ipvsDaemon{
+		State:     d.State,
+		Interface: d.Interface,
+		SyncID:    &syncID,
+		Port:      d.Port,
+		TTL:       d.TTL,
+	}
+	if ip4 := d.Group.To4(); ip4 != nil {
+		copy(ipvsDaemon.Group[:], ip4)
+	} else if d.Group != nil {
+		copy(ipvsDaemon.Group6[:], d.Group.To16())
+	}
+	return ipvsDaemon
+}
+
+// StartSyncDaemon starts the specified IPVS connection synchronisation daemon.
+func StartSyncDaemon(d SyncDaemon) error {
+	if d.Interface == "" {
+		return fmt.Errorf("no interface specified for %v", &d)
+	}
+	ic := &ipvsDaemonCommand{Daemon: newIPVSDaemon(&d)}
+	return netlink.SendMessageMarshalled(C.IPVS_CMD_NEW_DAEMON, family, 0, ic)
+}
+
+// StopSyncDaemon stops the IPVS connection synchronisation daemon that is
+// running in the specified state.
+func StopSyncDaemon(state SyncState) error {
+	ic := &ipvsDaemonCommand{Daemon: &ipvsDaemon{State: state}}
+	return netlink.SendMessageMarshalled(C.IPVS_CMD_DEL_DAEMON, family, 0, ic)
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipvs
+
+import (
+	"net"
+	"testing"
+)
+
+func TestNewIPVSDaemon(t *testing.T) {
+	tests := []struct {
+		desc   string
+		daemon SyncDaemon
+		group  [net.IPv4len]byte
+		group6 [net.IPv6len]byte
+	}{
+		{
+			desc:   "default group",
+			daemon: SyncDaemon{State: SyncMaster, Interface: "eth0", SyncID: 60},
+		},
+		{
+			desc:   "IPv4 group",
+			daemon: SyncDaemon{State: SyncBackup, Interface: "eth0", SyncID: 1, Group: net.ParseIP("239.0.0.81")},
+			group:  [net.IPv4len]byte{239, 0, 0, 81},
+		},
+		{
+			desc:   "IPv6 group",
+			daemon: SyncDaemon{State: SyncMaster, Interface: "eth1", Group: net.ParseIP("ff02::81")},
+			group6: [net.IPv6len]byte{0xff, 0x02, 15: 0x81},
+		},
+	}
+	for _, test := range tests {
+		d := newIPVSDaemon(&test.daemon)
+		if d.State != test.daemon.State || d.Interface != test.daemon.Interface {
+			t.Errorf("Test %q: got state %v, interface %q, want %v, %q",
+				test.desc, d.State, d.Interface, test.daemon.State, test.daemon.Interface)
+		}
+		if d.SyncID == nil || *d.SyncID != uint32(test.daemon.SyncID) {
+			t.Errorf("Test %q: got sync ID %v, want %d", test.desc, d.SyncID, test.daemon.SyncID)
+		}
+		if d.Group != test.group || d.Group6 != test.group6 {
+			t.Errorf("Test %q: got groups %v, %v, want %v, %v",
+				test.desc, d.Group, d.Group6, test.group, test.group6)
+		}
+	}
+}
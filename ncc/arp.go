@@ -24,7 +24,9 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 
 	ncctypes "github.com/wy2745/seesaw/ncc/types"
@@ -163,3 +165,97 @@ func (ncc *SeesawNCC) ARPSendGratuitous(arp *ncctypes.ARPGratuitous, out *int) e
 	}
 	return sendARP(iface, m)
 }
+
+// announcers tracks the announcements in progress for each interface, keyed
+// by interface name.
+var announcers = struct {
+	sync.Mutex
+	done map[string]chan struct{}
+}{done: make(map[string]chan struct{})}
+
+// startAnnouncements starts sending announcements for the addresses that are
+// configured on the given interface, stopping any announcements that are
+// already in progress for the interface.
+func startAnnouncements(iface *net.Interface, count int, interval time.Duration) {
+	announcers.Lock()
+	defer announcers.Unlock()
+	if done, ok := announcers.done[iface.Name]; ok {
+		close(done)
+	}
+	done := make(chan struct{})
+	announcers.done[iface.Name] = done
+	go announceAddresses(iface, count, interval, done)
+}
+
+// stopAnnouncements stops any announcements that are in progress for the
+// named interface.
+func stopAnnouncements(name string) {
+	announcers.Lock()
+	defer announcers.Unlock()
+	if done, ok := announcers.done[name]; ok {
+		close(done)
+		delete(announcers.done, name)
+	}
+}
+
+// announceAddresses sends gratuitous ARP messages for the IPv4 addresses, and
+// unsolicited neighbor advertisements for the IPv6 addresses, that are
+// configured on the given interface and its VLAN interfaces. The
+// announcements are sent count times, separated by the given interval, or
+// until the done channel is closed.
+func announceAddresses(pIface *net.Interface, count int, interval time.Duration, done <-chan struct{}) {
+	log.Infof("Announcing addresses on %s %d times every %v", pIface.Name, count, interval)
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-done:
+				log.Infof("Stopped announcing addresses on %s", pIface.Name)
+				return
+			case <-time.After(interval):
+			}
+		}
+		ifaces, err := vlanInterfaces(pIface)
+		if err != nil {
+			log.Warningf("Failed to get VLAN interfaces for %s: %v", pIface.Name, err)
+		}
+		ifaces = append(ifaces, pIface)
+		for _, iface := range ifaces {
+			if err := announceInterface(iface); err != nil {
+				log.Warningf("Failed to announce addresses on %s: %v", iface.Name, err)
+			}
+		}
+	}
+}
+
+// announceInterface sends a gratuitous ARP message or an unsolicited neighbor
+// advertisement for each address configured on the given interface. IPv6
+// link-local addresses are not announced.
+func announceInterface(iface *net.Interface) error {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return fmt.Errorf("failed to get addresses: %v", err)
+	}
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err != nil {
+			return fmt.Errorf("failed to parse address %q: %v", addr, err)
+		}
+		switch {
+		case ip.To4() != nil:
+			log.V(2).Infof("Sending gratuitous ARP for %s (%s) via %s", ip, iface.HardwareAddr, iface.Name)
+			var m *arpMessage
+			if m, err = gratuitousARPReply(ip, iface.HardwareAddr); err == nil {
+				err = sendARP(iface, m)
+			}
+		case ip.IsLinkLocalUnicast():
+			continue
+		default:
+			log.V(2).Infof("Sending unsolicited neighbor advertisement for %s (%s) via %s", ip, iface.HardwareAddr, iface.Name)
+			err = sendNeighborAdvert(iface, ip)
+		}
+		if err != nil {
+			log.Warningf("Failed to announce %s via %s: %v", ip, iface.Name, err)
+		}
+	}
+	return nil
+}
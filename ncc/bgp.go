@@ -21,6 +21,7 @@ package ncc
 // Quagga's bgpd via its VTY interface.
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -118,7 +119,8 @@ func (ncc *SeesawNCC) BGPWithdrawAll(unused int, reply *int) error {
 			break
 		}
 		if strings.HasPrefix(line, " network ") {
-			n := strings.Replace(line, " network ", "", 1)
+			// The network may be followed by a route-map.
+			n := strings.Fields(line)[1]
 			vip, ipNet, err := net.ParseCIDR(n)
 			if err != nil {
 				return err
@@ -130,7 +132,9 @@ func (ncc *SeesawNCC) BGPWithdrawAll(unused int, reply *int) error {
 		}
 	}
 
-	return nil
+	// Remove any route-maps that set BGP communities, which are no longer
+	// referenced.
+	return bgp.DeleteUnusedRouteMaps()
 }
 
 // BGPAdvertiseVIP requests the Quagga BGP daemon to advertise the given VIP.
@@ -143,6 +147,44 @@ func (ncc *SeesawNCC) BGPAdvertiseVIP(vip net.IP, unused *int) error {
 	return bgp.Advertise(&net.IPNet{IP: vip, Mask: hostMask(vip)})
 }
 
+// BGPAdvertiseVIPCommunities requests the Quagga BGP daemon to advertise the
+// given VIP, tagged with the given BGP communities.
+func (ncc *SeesawNCC) BGPAdvertiseVIPCommunities(ba *ncctypes.BGPAdvertisement, unused *int) error {
+	if ba == nil {
+		return errors.New("advertisement is nil")
+	}
+	bgp, err := quaggaBGP(seesawASN)
+	if err != nil {
+		return err
+	}
+	defer bgp.Close()
+	return bgp.AdvertiseWithCommunities(&net.IPNet{IP: ba.VIP, Mask: hostMask(ba.VIP)}, ba.Communities)
+}
+
+// BGPConfigurePeers configures the given peers in the Quagga BGP daemon.
+// Peers that are not listed are left unchanged.
+func (ncc *SeesawNCC) BGPConfigurePeers(bp *ncctypes.BGPPeers, unused *int) error {
+	if bp == nil {
+		return errors.New("peers is nil")
+	}
+	for _, p := range bp.Peers {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	bgp, err := quaggaBGP(seesawASN)
+	if err != nil {
+		return err
+	}
+	defer bgp.Close()
+	for _, p := range bp.Peers {
+		if err := bgp.ConfigurePeer(p); err != nil {
+			return fmt.Errorf("failed to configure BGP peer %v: %v", p.Address, err)
+		}
+	}
+	return nil
+}
+
 // BGPWithdrawVIP requests the Quagga BGP daemon to withdraw the given VIP.
 func (ncc *SeesawNCC) BGPWithdrawVIP(vip net.IP, unused *int) error {
 	bgp, err := quaggaBGP(seesawASN)
@@ -59,6 +59,10 @@ type NCC interface {
 	// is peering with.
 	BGPNeighbors() ([]*quagga.Neighbor, error)
 
+	// BGPConfigurePeers configures the given peers in the Quagga BGP
+	// daemon, without affecting sessions with other peers.
+	BGPConfigurePeers(peers []*quagga.Peer) error
+
 	// BGPWithdrawAll requests the Quagga BGP daemon to withdraw all
 	// configured network advertisements.
 	BGPWithdrawAll() error
@@ -67,10 +71,26 @@ type NCC interface {
 	// specified VIP.
 	BGPAdvertiseVIP(vip net.IP) error
 
+	// BGPAdvertiseVIPCommunities requests the Quagga BGP daemon to
+	// advertise the specified VIP, tagged with the given BGP communities.
+	BGPAdvertiseVIPCommunities(vip net.IP, communities []string) error
+
 	// BGPWithdrawVIP requests the Quagga BGP daemon to withdraw the
 	// specified VIP.
 	BGPWithdrawVIP(vip net.IP) error
 
+	// OSPFAdvertiseVIP requests the Quagga OSPF daemon to advertise the
+	// specified VIP into the given area.
+	OSPFAdvertiseVIP(vip net.IP, area quagga.OSPFArea) error
+
+	// OSPFWithdrawVIP requests the Quagga OSPF daemon to withdraw the
+	// specified VIP from the given area.
+	OSPFWithdrawVIP(vip net.IP, area quagga.OSPFArea) error
+
+	// OSPFWithdrawAll requests the Quagga OSPF daemon to withdraw all
+	// host network advertisements from the given area.
+	OSPFWithdrawAll(area quagga.OSPFArea) error
+
 	// IPVSFlush flushes all services and destinations from the IPVS table.
 	IPVSFlush() error
 
@@ -101,6 +121,19 @@ type NCC interface {
 	// the IPVS table.
 	IPVSDeleteDestination(svc *ipvs.Service, dst *ipvs.Destination) error
 
+	// IPVSApplyBatch applies the specified batch of service and
+	// destination changes to the IPVS table. The result reports the
+	// outcome of each change in the batch.
+	IPVSApplyBatch(b *ipvs.Batch) (*ipvs.BatchResult, error)
+
+	// IPVSStartSyncDaemon starts the specified IPVS connection
+	// synchronisation daemon.
+	IPVSStartSyncDaemon(d *ipvs.SyncDaemon) error
+
+	// IPVSStopSyncDaemon stops the IPVS connection synchronisation daemon
+	// that is running in the specified state.
+	IPVSStopSyncDaemon(state ipvs.SyncState) error
+
 	// RouteDefaultIPv4 returns the default route for IPv4 traffic.
 	RouteDefaultIPv4() (net.IP, error)
 }
@@ -235,6 +268,10 @@ func (nc *nccClient) BGPNeighbors() ([]*quagga.Neighbor, error) {
 	return bn.Neighbors, nil
 }
 
+func (nc *nccClient) BGPConfigurePeers(peers []*quagga.Peer) error {
+	return nc.call("SeesawNCC.BGPConfigurePeers", &ncctypes.BGPPeers{Peers: peers}, nil)
+}
+
 func (nc *nccClient) BGPWithdrawAll() error {
 	return nc.call("SeesawNCC.BGPWithdrawAll", 0, nil)
 }
@@ -244,11 +281,28 @@ func (nc *nccClient) BGPAdvertiseVIP(vip net.IP) error {
 	return nc.call("SeesawNCC.BGPAdvertiseVIP", vip, nil)
 }
 
+func (nc *nccClient) BGPAdvertiseVIPCommunities(vip net.IP, communities []string) error {
+	ba := &ncctypes.BGPAdvertisement{VIP: vip, Communities: communities}
+	return nc.call("SeesawNCC.BGPAdvertiseVIPCommunities", ba, nil)
+}
+
 // TODO(ncope): Use seesaw.VIP here for consistency
 func (nc *nccClient) BGPWithdrawVIP(vip net.IP) error {
 	return nc.call("SeesawNCC.BGPWithdrawVIP", vip, nil)
 }
 
+func (nc *nccClient) OSPFAdvertiseVIP(vip net.IP, area quagga.OSPFArea) error {
+	return nc.call("SeesawNCC.OSPFAdvertiseVIP", &ncctypes.OSPFAdvertisement{VIP: vip, Area: area}, nil)
+}
+
+func (nc *nccClient) OSPFWithdrawVIP(vip net.IP, area quagga.OSPFArea) error {
+	return nc.call("SeesawNCC.OSPFWithdrawVIP", &ncctypes.OSPFAdvertisement{VIP: vip, Area: area}, nil)
+}
+
+func (nc *nccClient) OSPFWithdrawAll(area quagga.OSPFArea) error {
+	return nc.call("SeesawNCC.OSPFWithdrawAll", area, nil)
+}
+
 func (nc *nccClient) IPVSFlush() error {
 	return nc.call("SeesawNCC.IPVSFlush", 0, nil)
 }
@@ -296,6 +350,22 @@ func (nc *nccClient) IPVSDeleteDestination(svc *ipvs.Service, dst *ipvs.Destinat
 	return nc.call("SeesawNCC.IPVSDeleteDestination", ipvsDst, nil)
 }
 
+func (nc *nccClient) IPVSApplyBatch(b *ipvs.Batch) (*ipvs.BatchResult, error) {
+	r := &ipvs.BatchResult{}
+	if err := nc.call("SeesawNCC.IPVSApplyBatch", b, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (nc *nccClient) IPVSStartSyncDaemon(d *ipvs.SyncDaemon) error {
+	return nc.call("SeesawNCC.IPVSStartSyncDaemon", d, nil)
+}
+
+func (nc *nccClient) IPVSStopSyncDaemon(state ipvs.SyncState) error {
+	return nc.call("SeesawNCC.IPVSStopSyncDaemon", state, nil)
+}
+
 func (nc *nccClient) RouteDefaultIPv4() (net.IP, error) {
 	var ip net.IP
 	err := nc.call("SeesawNCC.RouteDefaultIPv4", 0, &ip)
@@ -118,3 +118,36 @@ func (ncc *SeesawNCC) IPVSDeleteDestination(dst *ncctypes.IPVSDestination, out *
 	defer ipvsMutex.Unlock()
 	return ipvs.DeleteDestination(*dst.Service, *dst.Destination)
 }
+
+// IPVSStartSyncDaemon starts the specified IPVS connection synchronisation
+// daemon.
+func (ncc *SeesawNCC) IPVSStartSyncDaemon(d *ipvs.SyncDaemon, out *int) error {
+	ipvsMutex.Lock()
+	defer ipvsMutex.Unlock()
+	log.Infof("Starting IPVS %v", d)
+	return ipvs.StartSyncDaemon(*d)
+}
+
+// IPVSStopSyncDaemon stops the IPVS connection synchronisation daemon that is
+// running in the specified state.
+func (ncc *SeesawNCC) IPVSStopSyncDaemon(state ipvs.SyncState, out *int) error {
+	ipvsMutex.Lock()
+	defer ipvsMutex.Unlock()
+	log.Infof("Stopping IPVS %v sync daemon", state)
+	return ipvs.StopSyncDaemon(state)
+}
+
+// IPVSApplyBatch applies a batch of service and destination changes to the
+// IPVS table, reporting the outcome of each change.
+func (ncc *SeesawNCC) IPVSApplyBatch(b *ipvs.Batch, result *ipvs.BatchResult) error {
+	ipvsMutex.Lock()
+	defer ipvsMutex.Unlock()
+	r, err := ipvs.ApplyBatch(b)
+	if err != nil {
+		return err
+	}
+	*result = *r
+	log.Infof("Applied IPVS batch of %d operations in %v (%d failed)",
+		b.Len(), r.Duration, r.Failed())
+	return nil
+}
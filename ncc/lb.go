@@ -177,6 +177,7 @@ func (ncc *SeesawNCC) LBInterfaceDown(iface *ncctypes.LBInterface, out *int) err
 		return err
 	}
 	log.Infof("Bringing down LB interface %s", netIface.Name)
+	stopAnnouncements(netIface.Name)
 	return ifaceDown(netIface)
 }
 
@@ -219,6 +220,12 @@ func (ncc *SeesawNCC) LBInterfaceUp(iface *ncctypes.LBInterface, out *int) error
 		return fmt.Errorf("Failed to configure routing: %v", err)
 	}
 
+	// The kernel sends a single gratuitous ARP when the interface comes up,
+	// which some switches miss - repeat the announcements for all addresses.
+	if iface.GARPCount > 0 {
+		startAnnouncements(netIface, iface.GARPCount, iface.GARPInterval)
+	}
+
 	return nil
 }
 
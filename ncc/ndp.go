@@ -0,0 +1,93 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ncc
+
+// This file contains IPv6 neighbor discovery related functions for the
+// Seesaw Network Control component.
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+const (
+	icmpv6NeighborAdvert = 136
+	ndOptTargetLLAddr    = 2
+	ndFlagOverride       = 0x20
+	ndHopLimit           = 255
+)
+
+// allNodesMulticast is the IPv6 link-local all nodes multicast address.
+var allNodesMulticast = net.ParseIP("ff02::1")
+
+// unsolicitedNeighborAdvert returns an ICMPv6 unsolicited neighbor
+// advertisement for the specified target address, as described in RFC 4861
+// section 7.2.6. The checksum is left as zero, since it is computed by the
+// kernel for ICMPv6 raw sockets.
+func unsolicitedNeighborAdvert(ip net.IP, mac net.HardwareAddr) ([]byte, error) {
+	if ip.To4() != nil || ip.To16() == nil {
+		return nil, fmt.Errorf("%q is not an IPv6 address", ip)
+	}
+	if len(mac) != hwLen {
+		return nil, fmt.Errorf("%q is not an Ethernet MAC address", mac)
+	}
+
+	b := make([]byte, 0, 8+net.IPv6len+8)
+	b = append(b, icmpv6NeighborAdvert, 0, 0, 0) // Type, code and checksum.
+	b = append(b, ndFlagOverride, 0, 0, 0)       // Flags and reserved.
+	b = append(b, ip.To16()...)
+	b = append(b, ndOptTargetLLAddr, 1) // Option type and length (in units of 8 octets).
+	b = append(b, mac...)
+
+	return b, nil
+}
+
+// sendNeighborAdvert sends an unsolicited neighbor advertisement for the
+// given IPv6 address to the all nodes multicast address, via the specified
+// interface.
+func sendNeighborAdvert(iface *net.Interface, ip net.IP) error {
+	b, err := unsolicitedNeighborAdvert(ip, iface.HardwareAddr)
+	if err != nil {
+		return err
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET6, syscall.SOCK_RAW, syscall.IPPROTO_ICMPV6)
+	if err != nil {
+		return fmt.Errorf("failed to get raw socket: %v", err)
+	}
+	defer syscall.Close(fd)
+
+	if err := syscall.BindToDevice(fd, iface.Name); err != nil {
+		return fmt.Errorf("failed to bind to device: %v", err)
+	}
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_HOPS, ndHopLimit); err != nil {
+		return fmt.Errorf("failed to set hop limit: %v", err)
+	}
+
+	// Neighbor advertisements must be sourced from the target address.
+	src := &syscall.SockaddrInet6{ZoneId: uint32(iface.Index)}
+	copy(src.Addr[:], ip.To16())
+	if err := syscall.Bind(fd, src); err != nil {
+		return fmt.Errorf("failed to bind: %v", err)
+	}
+	dst := &syscall.SockaddrInet6{ZoneId: uint32(iface.Index)}
+	copy(dst.Addr[:], allNodesMulticast)
+	if err := syscall.Sendto(fd, b, 0, dst); err != nil {
+		return fmt.Errorf("failed to send: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ncc
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestUnsolicitedNeighborAdvert(t *testing.T) {
+	ip := net.ParseIP("2015:cafe::1")
+	mac, err := net.ParseMAC("00:11:22:33:44:55")
+	if err != nil {
+		t.Fatalf("ParseMAC failed: %v", err)
+	}
+
+	b, err := unsolicitedNeighborAdvert(ip, mac)
+	if err != nil {
+		t.Fatalf("unsolicitedNeighborAdvert failed: %v", err)
+	}
+	if got, want := len(b), 32; got != want {
+		t.Fatalf("Neighbor advertisement has length %d, want %d", got, want)
+	}
+	if got, want := b[0], byte(136); got != want {
+		t.Errorf("ICMPv6 type = %d, want %d", got, want)
+	}
+	if got, want := b[1], byte(0); got != want {
+		t.Errorf("ICMPv6 code = %d, want %d", got, want)
+	}
+	if got, want := b[2:4], []byte{0, 0}; !bytes.Equal(got, want) {
+		t.Errorf("Checksum = %x, want %x", got, want)
+	}
+	if got, want := b[4:8], []byte{0x20, 0, 0, 0}; !bytes.Equal(got, want) {
+		t.Errorf("Flags and reserved = %x, want %x", got, want)
+	}
+	if got := net.IP(b[8:24]); !got.Equal(ip) {
+		t.Errorf("Target = %v, want %v", got, ip)
+	}
+	if got, want := b[24:26], []byte{2, 1}; !bytes.Equal(got, want) {
+		t.Errorf("Option type and length = %v, want %v", got, want)
+	}
+	if got := net.HardwareAddr(b[26:32]); !bytes.Equal(got, mac) {
+		t.Errorf("Target link-layer address = %v, want %v", got, mac)
+	}
+
+	if _, err := unsolicitedNeighborAdvert(net.ParseIP("192.168.36.1"), mac); err == nil {
+		t.Errorf("unsolicitedNeighborAdvert succeeded for IPv4 address")
+	}
+	if _, err := unsolicitedNeighborAdvert(ip, net.HardwareAddr{0, 1}); err == nil {
+		t.Errorf("unsolicitedNeighborAdvert succeeded for invalid MAC address")
+	}
+}
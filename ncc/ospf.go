@@ -0,0 +1,93 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ncc
+
+// This file contains the OSPF related configuration and control functions for
+// the Seesaw v2 Network Control Center. These functions interface with
+// Quagga's ospfd via its VTY interface.
+
+import (
+	"errors"
+	"net"
+
+	ncctypes "github.com/wy2745/seesaw/ncc/types"
+	"github.com/wy2745/seesaw/quagga"
+)
+
+// quaggaOSPF establishes a connection with the Quagga OSPF daemon.
+func quaggaOSPF(area quagga.OSPFArea) (*quagga.OSPF, error) {
+	ospf := quagga.NewOSPF("", area)
+	if err := ospf.Dial(); err != nil {
+		return nil, err
+	}
+	if err := ospf.Enable(); err != nil {
+		ospf.Close()
+		return nil, err
+	}
+	return ospf, nil
+}
+
+// OSPFAdvertiseVIP requests the Quagga OSPF daemon to advertise the given VIP
+// into the given area.
+func (ncc *SeesawNCC) OSPFAdvertiseVIP(oa *ncctypes.OSPFAdvertisement, unused *int) error {
+	if oa == nil {
+		return errors.New("advertisement is nil")
+	}
+	ospf, err := quaggaOSPF(oa.Area)
+	if err != nil {
+		return err
+	}
+	defer ospf.Close()
+	return ospf.Advertise(&net.IPNet{IP: oa.VIP, Mask: hostMask(oa.VIP)})
+}
+
+// OSPFWithdrawVIP requests the Quagga OSPF daemon to withdraw the given VIP
+// from the given area.
+func (ncc *SeesawNCC) OSPFWithdrawVIP(oa *ncctypes.OSPFAdvertisement, unused *int) error {
+	if oa == nil {
+		return errors.New("advertisement is nil")
+	}
+	ospf, err := quaggaOSPF(oa.Area)
+	if err != nil {
+		return err
+	}
+	defer ospf.Close()
+	return ospf.Withdraw(&net.IPNet{IP: oa.VIP, Mask: hostMask(oa.VIP)})
+}
+
+// OSPFWithdrawAll removes all host network advertisements for the given area
+// from the Quagga OSPF daemon. Other networks, such as those for the node's
+// own interfaces, are left in place.
+func (ncc *SeesawNCC) OSPFWithdrawAll(area quagga.OSPFArea, reply *int) error {
+	ospf, err := quaggaOSPF(area)
+	if err != nil {
+		return err
+	}
+	defer ospf.Close()
+	cfg, err := ospf.Configuration()
+	if err != nil {
+		return err
+	}
+	networks, err := ospf.HostNetworks(cfg)
+	if err != nil {
+		return err
+	}
+	for _, n := range networks {
+		if err := ospf.Withdraw(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
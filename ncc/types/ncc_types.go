@@ -20,6 +20,7 @@ package types
 
 import (
 	"net"
+	"time"
 
 	"github.com/wy2745/seesaw/common/seesaw"
 	"github.com/wy2745/seesaw/ipvs"
@@ -37,6 +38,25 @@ type BGPNeighbors struct {
 	Neighbors []*quagga.Neighbor
 }
 
+// BGPAdvertisement specifies a VIP to be advertised by a BGP daemon, along
+// with the BGP communities that it is tagged with.
+type BGPAdvertisement struct {
+	VIP         net.IP
+	Communities []string
+}
+
+// BGPPeers encapsulates a list of BGP peers.
+type BGPPeers struct {
+	Peers []*quagga.Peer
+}
+
+// OSPFAdvertisement specifies a VIP to be advertised by an OSPF daemon, along
+// with the area that it is advertised into.
+type OSPFAdvertisement struct {
+	VIP  net.IP
+	Area quagga.OSPFArea
+}
+
 // BGPConfig encapsulates the configuration for a BGP daemon.
 type BGPConfig struct {
 	Config []string
@@ -57,6 +77,8 @@ type IPVSDestination struct {
 type LBConfig struct {
 	ClusterVIP     seesaw.Host
 	DummyInterface string
+	GARPCount      int
+	GARPInterval   time.Duration
 	NodeInterface  string
 	Node           seesaw.Host
 	RoutingTableID uint8
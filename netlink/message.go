@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"syscall"
 	"unsafe"
 )
 
@@ -27,6 +28,7 @@ import (
 #cgo LDFLAGS: -lnl-3 -lnl-genl-3
 
 #include <stdint.h>
+#include <stdlib.h>
 
 #include <netlink/netlink.h>
 #include <netlink/genl/genl.h>
@@ -226,35 +228,166 @@ func (m *Message) Send() error {
 // SendCallback sends the netlink message. The specified callback function
 // will be called for each message that is received in response.
 func (m *Message) SendCallback(fn CallbackFunc, arg interface{}) error {
-	s, err := newSocket()
+	s, err := NewSocket()
 	if err != nil {
 		return err
 	}
-	defer s.free()
+	defer s.Close()
+
+	return s.SendCallback(m, fn, arg)
+}
+
+// Socket is a connected generic netlink socket. A socket may be used to send
+// a sequence of messages, avoiding the cost of establishing a new netlink
+// connection for each message.
+type Socket struct {
+	s *socket
+}
 
+// NewSocket returns a new generic netlink socket. The socket must be closed
+// when it is no longer needed.
+func NewSocket() (*Socket, error) {
+	s, err := newSocket()
+	if err != nil {
+		return nil, err
+	}
 	if errno := C.genl_connect(s.nls); errno != 0 {
-		return &Error{errno, "failed to connect to netlink"}
+		s.free()
+		return nil, &Error{errno, "failed to connect to netlink"}
 	}
-	defer C.nl_close(s.nls)
+	return &Socket{s: s}, nil
+}
+
+// Close closes the netlink socket.
+func (s *Socket) Close() {
+	C.nl_close(s.s.nls)
+	s.s.free()
+}
+
+// Send sends the netlink message on the socket and waits for the response.
+func (s *Socket) Send(m *Message) error {
+	return s.SendCallback(m, callbackDefault, nil)
+}
 
+// SendCallback sends the netlink message on the socket. The specified
+// callback function will be called for each message that is received in
+// response.
+func (s *Socket) SendCallback(m *Message, fn CallbackFunc, arg interface{}) error {
 	cbArg := &callbackArg{fn: fn, arg: arg}
 	cbID := registerCallback(cbArg)
 	defer unregisterCallback(cbArg)
 
-	if errno := C.nl_socket_modify_cb(s.nls, C.NL_CB_VALID, C.NL_CB_CUSTOM, (C.nl_recvmsg_msg_cb_t)(unsafe.Pointer(C.callbackGateway)), unsafe.Pointer(cbID)); errno != 0 {
+	if errno := C.nl_socket_modify_cb(s.s.nls, C.NL_CB_VALID, C.NL_CB_CUSTOM, (C.nl_recvmsg_msg_cb_t)(unsafe.Pointer(C.callbackGateway)), unsafe.Pointer(cbID)); errno != 0 {
 		return &Error{errno, "failed to modify callback"}
 	}
 	// nl_send_auto_complete returns number of bytes sent or a negative
 	// errno on failure.
-	if errno := C.nl_send_auto_complete(s.nls, m.nlm); errno < 0 {
+	if errno := C.nl_send_auto_complete(s.s.nls, m.nlm); errno < 0 {
 		return &Error{errno, "failed to send netlink message"}
 	}
-	if errno := C.nl_recvmsgs_default(s.nls); errno != 0 {
+	if errno := C.nl_recvmsgs_default(s.s.nls); errno != 0 {
 		return &Error{errno, "failed to receive messages"}
 	}
 	return nil
 }
 
+// maxBatchMessages is the maximum number of messages that SendBatch packs
+// into a single sendmsg call. This bounds the number of acknowledgements that
+// have to be queued on the socket before they are received.
+const maxBatchMessages = 64
+
+// SendBatch sends the given netlink messages on the socket and waits for them
+// to be acknowledged. Several messages are packed into each sendmsg call and
+// the acknowledgements are matched to the messages by sequence number. The
+// kernel processes the messages in order and a failed message does not
+// prevent the remaining messages from being processed. The returned slice
+// contains the error for each message, in order, with a nil error indicating
+// that the message was successful. An error is only returned if the messages
+// could not be sent, or their acknowledgements could not be received.
+func (s *Socket) SendBatch(msgs []*Message) ([]error, error) {
+	errs := make([]error, len(msgs))
+	for start := 0; start < len(msgs); start += maxBatchMessages {
+		end := start + maxBatchMessages
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+		if err := s.sendBatch(msgs[start:end], errs[start:end]); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+// sendBatch sends the given messages in a single sendmsg call, storing the
+// error from the acknowledgement for each message in errs.
+func (s *Socket) sendBatch(msgs []*Message, errs []error) error {
+	var buf []byte
+	pending := make(map[uint32]int)
+	for i, m := range msgs {
+		// Assign the sequence number and request an acknowledgement.
+		C.nl_complete_msg(s.s.nls, m.nlm)
+		b, err := m.Bytes()
+		if err != nil {
+			return err
+		}
+		pending[uint32(C.nlmsg_hdr(m.nlm).nlmsg_seq)] = i
+		buf = append(buf, b...)
+		for len(buf)%syscall.NLMSG_ALIGNTO != 0 {
+			buf = append(buf, 0)
+		}
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	if n := C.nl_sendto(s.s.nls, unsafe.Pointer(&buf[0]), C.size_t(len(buf))); n < 0 {
+		return &Error{n, "failed to send netlink messages"}
+	}
+	for len(pending) > 0 {
+		var nla C.struct_sockaddr_nl
+		var rb *C.uchar
+		n := C.nl_recv(s.s.nls, &nla, &rb, nil)
+		if n < 0 {
+			return &Error{n, "failed to receive messages"}
+		}
+		if n == 0 {
+			return fmt.Errorf("netlink socket closed with %d messages unacknowledged", len(pending))
+		}
+		b := C.GoBytes(unsafe.Pointer(rb), n)
+		C.free(unsafe.Pointer(rb))
+		if err := matchAcks(b, pending, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchAcks parses the netlink messages in b and stores the error for each
+// acknowledged message in errs, removing it from the pending messages, which
+// map sequence numbers to indexes in errs. Other messages are ignored.
+func matchAcks(b []byte, pending map[uint32]int, errs []error) error {
+	nlms, err := syscall.ParseNetlinkMessage(b)
+	if err != nil {
+		return fmt.Errorf("failed to parse netlink messages: %v", err)
+	}
+	for _, nlm := range nlms {
+		if nlm.Header.Type != syscall.NLMSG_ERROR {
+			continue
+		}
+		i, ok := pending[nlm.Header.Seq]
+		if !ok {
+			continue
+		}
+		if len(nlm.Data) < 4 {
+			return fmt.Errorf("truncated acknowledgement for netlink message %d", nlm.Header.Seq)
+		}
+		if errno := *(*int32)(unsafe.Pointer(&nlm.Data[0])); errno != 0 {
+			errs[i] = syscall.Errno(-errno)
+		}
+		delete(pending, nlm.Header.Seq)
+	}
+	return nil
+}
+
 // SendMessage creates and sends a netlink message.
 func SendMessage(command, family, flags int) error {
 	return SendMessageCallback(command, family, flags, callbackDefault, nil)
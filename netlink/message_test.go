@@ -20,6 +20,7 @@ import (
 	"reflect"
 	"syscall"
 	"testing"
+	"unsafe"
 )
 
 type ipvsInfo struct {
@@ -397,3 +398,40 @@ func TestMessageUnmarshalNested(t *testing.T) {
 		t.Errorf("Got IPVS service %#v, want %#v", got.Service, want)
 	}
 }
+
+// ackMessage returns a netlink message of the given type and sequence number,
+// with a payload containing the given error code.
+func ackMessage(msgType uint16, seq uint32, errno int32) []byte {
+	b := make([]byte, 2*syscall.NLMSG_HDRLEN+4)
+	hdr := (*syscall.NlMsghdr)(unsafe.Pointer(&b[0]))
+	hdr.Len = uint32(len(b))
+	hdr.Type = msgType
+	hdr.Seq = seq
+	*(*int32)(unsafe.Pointer(&b[syscall.NLMSG_HDRLEN])) = errno
+	return b
+}
+
+func TestMatchAcks(t *testing.T) {
+	var b []byte
+	b = append(b, ackMessage(syscall.NLMSG_ERROR, 11, -int32(syscall.EEXIST))...)
+	b = append(b, ackMessage(syscall.NLMSG_ERROR, 10, 0)...)
+	b = append(b, ackMessage(syscall.NLMSG_DONE, 12, 0)...)
+	b = append(b, ackMessage(syscall.NLMSG_ERROR, 99, -int32(syscall.ENOENT))...)
+
+	pending := map[uint32]int{10: 0, 11: 1, 12: 2}
+	errs := make([]error, 3)
+	if err := matchAcks(b, pending, errs); err != nil {
+		t.Fatalf("matchAcks failed: %v", err)
+	}
+	want := []error{nil, syscall.EEXIST, nil}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("matchAcks returned errors %v, want %v", errs, want)
+	}
+	if _, ok := pending[12]; len(pending) != 1 || !ok {
+		t.Errorf("Got pending messages %v, want only sequence 12", pending)
+	}
+
+	if err := matchAcks(b[:syscall.NLMSG_HDRLEN], pending, errs); err == nil {
+		t.Error("matchAcks succeeded with truncated message")
+	}
+}
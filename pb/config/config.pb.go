@@ -123,35 +123,59 @@ func (Host_Status) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []
 type Healthcheck_Type int32
 
 const (
-	Healthcheck_ICMP_PING Healthcheck_Type = 1
-	Healthcheck_UDP       Healthcheck_Type = 2
-	Healthcheck_TCP       Healthcheck_Type = 3
-	Healthcheck_HTTP      Healthcheck_Type = 4
-	Healthcheck_HTTPS     Healthcheck_Type = 5
-	Healthcheck_DNS       Healthcheck_Type = 6
-	Healthcheck_TCP_TLS   Healthcheck_Type = 7
-	Healthcheck_RADIUS    Healthcheck_Type = 8
+	Healthcheck_ICMP_PING     Healthcheck_Type = 1
+	Healthcheck_UDP           Healthcheck_Type = 2
+	Healthcheck_TCP           Healthcheck_Type = 3
+	Healthcheck_HTTP          Healthcheck_Type = 4
+	Healthcheck_HTTPS         Healthcheck_Type = 5
+	Healthcheck_DNS           Healthcheck_Type = 6
+	Healthcheck_TCP_TLS       Healthcheck_Type = 7
+	Healthcheck_RADIUS        Healthcheck_Type = 8
+	Healthcheck_GRPC          Healthcheck_Type = 9
+	Healthcheck_GRPC_TLS      Healthcheck_Type = 10
+	Healthcheck_EXEC          Healthcheck_Type = 11
+	Healthcheck_SMTP          Healthcheck_Type = 12
+	Healthcheck_SMTP_STARTTLS Healthcheck_Type = 13
+	Healthcheck_REDIS         Healthcheck_Type = 14
+	Healthcheck_MYSQL         Healthcheck_Type = 15
+	Healthcheck_NTP           Healthcheck_Type = 16
 )
 
 var Healthcheck_Type_name = map[int32]string{
-	1: "ICMP_PING",
-	2: "UDP",
-	3: "TCP",
-	4: "HTTP",
-	5: "HTTPS",
-	6: "DNS",
-	7: "TCP_TLS",
-	8: "RADIUS",
+	1:  "ICMP_PING",
+	2:  "UDP",
+	3:  "TCP",
+	4:  "HTTP",
+	5:  "HTTPS",
+	6:  "DNS",
+	7:  "TCP_TLS",
+	8:  "RADIUS",
+	9:  "GRPC",
+	10: "GRPC_TLS",
+	11: "EXEC",
+	12: "SMTP",
+	13: "SMTP_STARTTLS",
+	14: "REDIS",
+	15: "MYSQL",
+	16: "NTP",
 }
 var Healthcheck_Type_value = map[string]int32{
-	"ICMP_PING": 1,
-	"UDP":       2,
-	"TCP":       3,
-	"HTTP":      4,
-	"HTTPS":     5,
-	"DNS":       6,
-	"TCP_TLS":   7,
-	"RADIUS":    8,
+	"ICMP_PING":     1,
+	"UDP":           2,
+	"TCP":           3,
+	"HTTP":          4,
+	"HTTPS":         5,
+	"DNS":           6,
+	"TCP_TLS":       7,
+	"RADIUS":        8,
+	"GRPC":          9,
+	"GRPC_TLS":      10,
+	"EXEC":          11,
+	"SMTP":          12,
+	"SMTP_STARTTLS": 13,
+	"REDIS":         14,
+	"MYSQL":         15,
+	"NTP":           16,
 }
 
 func (x Healthcheck_Type) Enum() *Healthcheck_Type {
@@ -215,6 +239,7 @@ const (
 	VserverEntry_LC  VserverEntry_Scheduler = 3
 	VserverEntry_WLC VserverEntry_Scheduler = 4
 	VserverEntry_SH  VserverEntry_Scheduler = 5
+	VserverEntry_MH  VserverEntry_Scheduler = 6
 )
 
 var VserverEntry_Scheduler_name = map[int32]string{
@@ -223,6 +248,7 @@ var VserverEntry_Scheduler_name = map[int32]string{
 	3: "LC",
 	4: "WLC",
 	5: "SH",
+	6: "MH",
 }
 var VserverEntry_Scheduler_value = map[string]int32{
 	"RR":  1,
@@ -230,6 +256,7 @@ var VserverEntry_Scheduler_value = map[string]int32{
 	"LC":  3,
 	"WLC": 4,
 	"SH":  5,
+	"MH":  6,
 }
 
 func (x VserverEntry_Scheduler) Enum() *VserverEntry_Scheduler {
@@ -257,15 +284,19 @@ const (
 	VserverEntry_DSR VserverEntry_Mode = 1
 	// See --masquerding in man ipvsadm(8)
 	VserverEntry_NAT VserverEntry_Mode = 2
+	// See --ipip in man ipvsadm(8)
+	VserverEntry_TUN VserverEntry_Mode = 3
 )
 
 var VserverEntry_Mode_name = map[int32]string{
 	1: "DSR",
 	2: "NAT",
+	3: "TUN",
 }
 var VserverEntry_Mode_value = map[string]int32{
 	"DSR": 1,
 	"NAT": 2,
+	"TUN": 3,
 }
 
 func (x VserverEntry_Mode) Enum() *VserverEntry_Mode {
@@ -354,6 +385,49 @@ func (x *AccessGrant_Type) UnmarshalJSON(data []byte) error {
 }
 func (AccessGrant_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{5, 1} }
 
+// The action to take when all backends for a service are down.
+type Vserver_OnAllDown int32
+
+const (
+	// Leave the VIP configured without any backends.
+	Vserver_BLACKHOLE Vserver_OnAllDown = 1
+	// Remove unicast VIPs from the load balancing interface while they have
+	// no healthy services, so that clients can fail over elsewhere. The BGP
+	// routes for anycast VIPs are always withdrawn.
+	Vserver_WITHDRAW Vserver_OnAllDown = 2
+	// Send connections to the fallback backend.
+	Vserver_FALLBACK Vserver_OnAllDown = 3
+)
+
+var Vserver_OnAllDown_name = map[int32]string{
+	1: "BLACKHOLE",
+	2: "WITHDRAW",
+	3: "FALLBACK",
+}
+var Vserver_OnAllDown_value = map[string]int32{
+	"BLACKHOLE": 1,
+	"WITHDRAW":  2,
+	"FALLBACK":  3,
+}
+
+func (x Vserver_OnAllDown) Enum() *Vserver_OnAllDown {
+	p := new(Vserver_OnAllDown)
+	*p = x
+	return p
+}
+func (x Vserver_OnAllDown) String() string {
+	return proto.EnumName(Vserver_OnAllDown_name, int32(x))
+}
+func (x *Vserver_OnAllDown) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Vserver_OnAllDown_value, data, "Vserver_OnAllDown")
+	if err != nil {
+		return err
+	}
+	*x = Vserver_OnAllDown(value)
+	return nil
+}
+func (Vserver_OnAllDown) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 0} }
+
 type Host struct {
 	// Fully qualified hostname
 	Fqdn *string `protobuf:"bytes,1,req,name=fqdn" json:"fqdn,omitempty"`
@@ -400,8 +474,15 @@ func (m *Host) GetStatus() Host_Status {
 }
 
 type Backend struct {
-	Host             *Host  `protobuf:"bytes,1,req,name=host" json:"host,omitempty"`
-	Weight           *int32 `protobuf:"varint,2,opt,name=weight,def=1" json:"weight,omitempty"`
+	Host   *Host  `protobuf:"bytes,1,req,name=host" json:"host,omitempty"`
+	Weight *int32 `protobuf:"varint,2,opt,name=weight,def=1" json:"weight,omitempty"`
+	// Connection thresholds for this backend, see -x and -y in man ipvsadm(8).
+	// Once the upper threshold is reached, no new connections are sent to the
+	// backend until its connection count drops below the lower threshold. These
+	// override the thresholds for the vserver entry. A value of zero means no
+	// threshold.
+	UpperThreshold   *int32 `protobuf:"varint,3,opt,name=upper_threshold" json:"upper_threshold,omitempty"`
+	LowerThreshold   *int32 `protobuf:"varint,4,opt,name=lower_threshold" json:"lower_threshold,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -426,6 +507,20 @@ func (m *Backend) GetWeight() int32 {
 	return Default_Backend_Weight
 }
 
+func (m *Backend) GetUpperThreshold() int32 {
+	if m != nil && m.UpperThreshold != nil {
+		return *m.UpperThreshold
+	}
+	return 0
+}
+
+func (m *Backend) GetLowerThreshold() int32 {
+	if m != nil && m.LowerThreshold != nil {
+		return *m.LowerThreshold
+	}
+	return 0
+}
+
 type Vlan struct {
 	VlanId           *int32 `protobuf:"varint,1,req,name=vlan_id" json:"vlan_id,omitempty"`
 	Host             *Host  `protobuf:"bytes,2,req,name=host" json:"host,omitempty"`
@@ -463,22 +558,41 @@ type Healthcheck struct {
 	// For VserverEntry healthchecks, it is optional and uses the VserverEntry
 	// port by default.
 	Port *int32 `protobuf:"varint,4,opt,name=port" json:"port,omitempty"`
-	// String to send for UDP/TCP/HTTP(S) healthcheck.
+	// String to send for UDP/TCP/HTTP(S) healthcheck. For HTTP(S)
+	// healthchecks this is the request, which may contain {ip}, {port} and
+	// {name} placeholders for the backend IP, healthcheck port and backend
+	// hostname respectively.
 	Send *string `protobuf:"bytes,5,opt,name=send" json:"send,omitempty"`
-	// Expected response for UDP/TCP/HTTP(S) healthcheck.
+	// Expected response for UDP/TCP/HTTP(S) healthcheck. For NTP
+	// healthchecks this is the acceptable stratum range, for example "1-4".
 	Receive *string `protobuf:"bytes,6,opt,name=receive" json:"receive,omitempty"`
 	// Expected response code for healthcheck.
 	Code *int32 `protobuf:"varint,7,opt,name=code" json:"code,omitempty"`
 	// The Mode of this healthcheck.
 	Mode *Healthcheck_Mode `protobuf:"varint,8,opt,name=mode,enum=Healthcheck_Mode,def=1" json:"mode,omitempty"`
-	// The HTTP request method to use for an HTTP(S) healthcheck.
+	// The HTTP request method to use for an HTTP(S) healthcheck - one of GET
+	// (the default), HEAD, OPTIONS or POST. A response body cannot be matched
+	// for HEAD requests.
 	Method *string `protobuf:"bytes,9,opt,name=method" json:"method,omitempty"`
 	// Perform a healthcheck against an HTTP proxy.
 	Proxy *bool `protobuf:"varint,10,opt,name=proxy" json:"proxy,omitempty"`
 	// Do TLS verification.
 	TlsVerify *bool `protobuf:"varint,11,opt,name=tls_verify,def=1" json:"tls_verify,omitempty"`
 	// Number of retries before a healthcheck is considered to have failed.
-	Retries          *int32 `protobuf:"varint,12,opt,name=retries" json:"retries,omitempty"`
+	Retries *int32 `protobuf:"varint,12,opt,name=retries" json:"retries,omitempty"`
+	// Latency in milliseconds above which a successful healthcheck is logged
+	// as being slow. If unset, slow healthchecks are not logged.
+	SlowThresholdMs *int32 `protobuf:"varint,13,opt,name=slow_threshold_ms" json:"slow_threshold_ms,omitempty"`
+	// Require that a MySQL backend is not read-only.
+	ExpectWritable *bool `protobuf:"varint,14,opt,name=expect_writable" json:"expect_writable,omitempty"`
+	// Local IP address and network interface to send healthchecks from.
+	SourceIp        *string `protobuf:"bytes,15,opt,name=source_ip" json:"source_ip,omitempty"`
+	SourceInterface *string `protobuf:"bytes,16,opt,name=source_interface" json:"source_interface,omitempty"`
+	// Request body for an HTTP(S) POST healthcheck.
+	Body *string `protobuf:"bytes,17,opt,name=body" json:"body,omitempty"`
+	// Healthcheck connect timeout in seconds. If unset, connections are
+	// subject only to the healthcheck timeout.
+	ConnectTimeout   *int32 `protobuf:"varint,18,opt,name=connect_timeout" json:"connect_timeout,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -576,6 +690,48 @@ func (m *Healthcheck) GetRetries() int32 {
 	return 0
 }
 
+func (m *Healthcheck) GetSlowThresholdMs() int32 {
+	if m != nil && m.SlowThresholdMs != nil {
+		return *m.SlowThresholdMs
+	}
+	return 0
+}
+
+func (m *Healthcheck) GetExpectWritable() bool {
+	if m != nil && m.ExpectWritable != nil {
+		return *m.ExpectWritable
+	}
+	return false
+}
+
+func (m *Healthcheck) GetSourceIp() string {
+	if m != nil && m.SourceIp != nil {
+		return *m.SourceIp
+	}
+	return ""
+}
+
+func (m *Healthcheck) GetSourceInterface() string {
+	if m != nil && m.SourceInterface != nil {
+		return *m.SourceInterface
+	}
+	return ""
+}
+
+func (m *Healthcheck) GetBody() string {
+	if m != nil && m.Body != nil {
+		return *m.Body
+	}
+	return ""
+}
+
+func (m *Healthcheck) GetConnectTimeout() int32 {
+	if m != nil && m.ConnectTimeout != nil {
+		return *m.ConnectTimeout
+	}
+	return 0
+}
+
 type VserverEntry struct {
 	Protocol  *Protocol               `protobuf:"varint,1,req,name=protocol,enum=Protocol" json:"protocol,omitempty"`
 	Port      *int32                  `protobuf:"varint,2,req,name=port" json:"port,omitempty"`
@@ -600,9 +756,27 @@ type VserverEntry struct {
 	Uthreshold *int32 `protobuf:"varint,12,opt,name=uthreshold" json:"uthreshold,omitempty"`
 	// The healthchecks to perform on the backends
 	Healthcheck []*Healthcheck `protobuf:"bytes,13,rep,name=healthcheck" json:"healthcheck,omitempty"`
-	// Use "one packet" load balancing
-	OnePacket        *bool  `protobuf:"varint,14,opt,name=one_packet" json:"one_packet,omitempty"`
-	XXX_unrecognized []byte `json:"-"`
+	// Use "one packet" load balancing, scheduling each packet independently
+	// rather than creating a connection entry. Only valid for UDP services.
+	OnePacket *bool `protobuf:"varint,14,opt,name=one_packet" json:"one_packet,omitempty"`
+	// Maglev scheduler flags, see --sched-flags in man ipvsadm(8). Only valid
+	// with the MH scheduler. If fallback is set, connections are assigned to
+	// another backend when the selected backend has a weight of zero. If port
+	// is set, the source port is included in the hash.
+	MhFallback *bool `protobuf:"varint,15,opt,name=mh_fallback" json:"mh_fallback,omitempty"`
+	MhPort     *bool `protobuf:"varint,16,opt,name=mh_port" json:"mh_port,omitempty"`
+	// Source hash scheduler flags, see --sched-flags in man ipvsadm(8). Only
+	// valid with the SH scheduler. If fallback is set, connections are assigned
+	// to another backend when the selected backend is unavailable, rather than
+	// being dropped. If port is set, the source port is included in the hash.
+	ShFallback *bool `protobuf:"varint,17,opt,name=sh_fallback" json:"sh_fallback,omitempty"`
+	ShPort     *bool `protobuf:"varint,18,opt,name=sh_port" json:"sh_port,omitempty"`
+	// The prefix lengths used to group client addresses for persistence, see
+	// --netmask in man ipvsadm(8). If unset, each client address is treated
+	// individually. Only valid if persistence is set.
+	PersistencePrefixIpv4 *int32 `protobuf:"varint,19,opt,name=persistence_prefix_ipv4" json:"persistence_prefix_ipv4,omitempty"`
+	PersistencePrefixIpv6 *int32 `protobuf:"varint,20,opt,name=persistence_prefix_ipv6" json:"persistence_prefix_ipv6,omitempty"`
+	XXX_unrecognized      []byte `json:"-"`
 }
 
 func (m *VserverEntry) Reset()                    { *m = VserverEntry{} }
@@ -697,6 +871,48 @@ func (m *VserverEntry) GetOnePacket() bool {
 	return false
 }
 
+func (m *VserverEntry) GetMhFallback() bool {
+	if m != nil && m.MhFallback != nil {
+		return *m.MhFallback
+	}
+	return false
+}
+
+func (m *VserverEntry) GetMhPort() bool {
+	if m != nil && m.MhPort != nil {
+		return *m.MhPort
+	}
+	return false
+}
+
+func (m *VserverEntry) GetShFallback() bool {
+	if m != nil && m.ShFallback != nil {
+		return *m.ShFallback
+	}
+	return false
+}
+
+func (m *VserverEntry) GetShPort() bool {
+	if m != nil && m.ShPort != nil {
+		return *m.ShPort
+	}
+	return false
+}
+
+func (m *VserverEntry) GetPersistencePrefixIpv4() int32 {
+	if m != nil && m.PersistencePrefixIpv4 != nil {
+		return *m.PersistencePrefixIpv4
+	}
+	return 0
+}
+
+func (m *VserverEntry) GetPersistencePrefixIpv6() int32 {
+	if m != nil && m.PersistencePrefixIpv6 != nil {
+		return *m.PersistencePrefixIpv6
+	}
+	return 0
+}
+
 type AccessGrant struct {
 	// The user or group
 	Grantee *string `protobuf:"bytes,1,req,name=grantee" json:"grantee,omitempty"`
@@ -741,6 +957,8 @@ type Vserver struct {
 	// Contact info for Responsible Party
 	Rp *string `protobuf:"bytes,3,req,name=rp" json:"rp,omitempty"`
 	// Use firewall mark instead of individual service configurations.
+	// All of the vserver entries are served by a single IPVS service, hence
+	// they must have the same scheduler, mode, persistence and thresholds.
 	UseFwm       *bool           `protobuf:"varint,4,opt,name=use_fwm" json:"use_fwm,omitempty"`
 	VserverEntry []*VserverEntry `protobuf:"bytes,5,rep,name=vserver_entry" json:"vserver_entry,omitempty"`
 	// The list of backends for this vserver.
@@ -754,7 +972,34 @@ type Vserver struct {
 	AccessGrant []*AccessGrant `protobuf:"bytes,8,rep,name=access_grant" json:"access_grant,omitempty"`
 	// Warning messages about this Vserver (such as misconfigured backends) to be
 	// displayed on operator consoles.
-	Warning          []string `protobuf:"bytes,9,rep,name=warning" json:"warning,omitempty"`
+	Warning []string `protobuf:"bytes,9,rep,name=warning" json:"warning,omitempty"`
+	// Duration in seconds over which the weight of a backend that becomes
+	// healthy is ramped up to its configured weight. If unset, backends
+	// receive their full weight immediately.
+	SlowStartDuration *int32 `protobuf:"varint,11,opt,name=slow_start_duration" json:"slow_start_duration,omitempty"`
+	// Duration in seconds to drain active connections when this Vserver is
+	// removed. Backends are given a weight of zero and the IPVS services are
+	// deleted once there are no active connections or the timeout expires. If
+	// unset, the IPVS services are deleted immediately.
+	DrainTimeout *int32 `protobuf:"varint,12,opt,name=drain_timeout" json:"drain_timeout,omitempty"`
+	// Set the weight of unhealthy backends to zero instead of removing them, so
+	// that existing connections are retained until they close. Backends are
+	// only removed when they are removed from the configuration.
+	Quiescent *bool `protobuf:"varint,13,opt,name=quiescent" json:"quiescent,omitempty"`
+	// The action to take when all backends for a service are down.
+	OnAllDown *Vserver_OnAllDown `protobuf:"varint,14,opt,name=on_all_down,enum=Vserver_OnAllDown,def=1" json:"on_all_down,omitempty"`
+	// The backend that connections are sent to when all other backends for a
+	// service are down, if on_all_down is FALLBACK. It is not healthchecked.
+	FallbackBackend *Backend `protobuf:"bytes,15,opt,name=fallback_backend" json:"fallback_backend,omitempty"`
+	// Additional VIPs in CIDR format, which are served by the same vserver
+	// entries and backends as the entry address. The healthchecks for each
+	// backend are performed once, rather than once per VIP. Firewall mark
+	// vservers do not support additional VIPs.
+	AdditionalVip []string `protobuf:"bytes,16,rep,name=additional_vip" json:"additional_vip,omitempty"`
+	// BGP communities that the routes for anycast VIPs are tagged with, given
+	// as standard ("AS:value" or a well-known name such as "no-export") or
+	// large ("ASN:value:value") communities.
+	BgpCommunity     []string `protobuf:"bytes,17,rep,name=bgp_community" json:"bgp_community,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -763,6 +1008,8 @@ func (m *Vserver) String() string            { return proto.CompactTextString(m)
 func (*Vserver) ProtoMessage()               {}
 func (*Vserver) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
+const Default_Vserver_OnAllDown Vserver_OnAllDown = Vserver_BLACKHOLE
+
 func (m *Vserver) GetName() string {
 	if m != nil && m.Name != nil {
 		return *m.Name
@@ -833,6 +1080,55 @@ func (m *Vserver) GetWarning() []string {
 	return nil
 }
 
+func (m *Vserver) GetSlowStartDuration() int32 {
+	if m != nil && m.SlowStartDuration != nil {
+		return *m.SlowStartDuration
+	}
+	return 0
+}
+
+func (m *Vserver) GetDrainTimeout() int32 {
+	if m != nil && m.DrainTimeout != nil {
+		return *m.DrainTimeout
+	}
+	return 0
+}
+
+func (m *Vserver) GetQuiescent() bool {
+	if m != nil && m.Quiescent != nil {
+		return *m.Quiescent
+	}
+	return false
+}
+
+func (m *Vserver) GetOnAllDown() Vserver_OnAllDown {
+	if m != nil && m.OnAllDown != nil {
+		return *m.OnAllDown
+	}
+	return Default_Vserver_OnAllDown
+}
+
+func (m *Vserver) GetFallbackBackend() *Backend {
+	if m != nil {
+		return m.FallbackBackend
+	}
+	return nil
+}
+
+func (m *Vserver) GetAdditionalVip() []string {
+	if m != nil {
+		return m.AdditionalVip
+	}
+	return nil
+}
+
+func (m *Vserver) GetBgpCommunity() []string {
+	if m != nil {
+		return m.BgpCommunity
+	}
+	return nil
+}
+
 type MisconfiguredVserver struct {
 	Name             *string `protobuf:"bytes,1,req,name=name" json:"name,omitempty"`
 	ErrorMessage     *string `protobuf:"bytes,2,opt,name=error_message" json:"error_message,omitempty"`
@@ -1039,82 +1335,107 @@ func init() {
 	proto.RegisterEnum("VserverEntry_Mode", VserverEntry_Mode_name, VserverEntry_Mode_value)
 	proto.RegisterEnum("AccessGrant_Role", AccessGrant_Role_name, AccessGrant_Role_value)
 	proto.RegisterEnum("AccessGrant_Type", AccessGrant_Type_name, AccessGrant_Type_value)
+	proto.RegisterEnum("Vserver_OnAllDown", Vserver_OnAllDown_name, Vserver_OnAllDown_value)
 }
 
 var fileDescriptor0 = []byte{
-	// 1178 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x55, 0xdf, 0x6e, 0xea, 0xc6,
-	0x13, 0x16, 0xc6, 0x06, 0x7b, 0xf8, 0xf3, 0x23, 0xfe, 0x25, 0xe7, 0xb8, 0x3d, 0xa9, 0x9a, 0x5a,
-	0x6d, 0x15, 0x55, 0x95, 0x4f, 0x12, 0x25, 0xe7, 0x82, 0x5e, 0x54, 0x04, 0x68, 0x82, 0x44, 0x08,
-	0xc2, 0x70, 0x8e, 0x7a, 0x53, 0xcb, 0xb1, 0x37, 0x60, 0xc5, 0xd8, 0x3e, 0xeb, 0x35, 0x34, 0x97,
-	0xbd, 0xed, 0x1b, 0xf4, 0x05, 0xfa, 0x0e, 0x7d, 0x85, 0x3e, 0x55, 0x67, 0xd7, 0x86, 0x90, 0x28,
-	0x52, 0x14, 0x76, 0x66, 0x76, 0xc7, 0xb3, 0xdf, 0xf7, 0xcd, 0x2c, 0xbc, 0x49, 0xee, 0xde, 0x7b,
-	0x71, 0x74, 0x1f, 0xcc, 0x8b, 0x1f, 0x2b, 0xa1, 0x31, 0x8b, 0xcd, 0x7f, 0x4a, 0x20, 0x5f, 0xc7,
-	0x29, 0xd3, 0xeb, 0x20, 0xdf, 0x7f, 0xf6, 0x23, 0xa3, 0x74, 0x24, 0x1d, 0x6b, 0xdc, 0x0a, 0x92,
-	0xd5, 0xb9, 0x21, 0x1d, 0x95, 0xb6, 0xd6, 0x07, 0xa3, 0x2c, 0xac, 0x43, 0xa8, 0xa4, 0xcc, 0x65,
-	0x59, 0x6a, 0xc8, 0x68, 0x37, 0xcf, 0xea, 0x16, 0x4f, 0x60, 0xd9, 0xc2, 0x67, 0x06, 0x50, 0xc9,
-	0x57, 0x7a, 0x13, 0x60, 0x3c, 0xb9, 0xed, 0xcd, 0xba, 0xd3, 0xc1, 0xed, 0xa8, 0x55, 0xd2, 0x6b,
-	0x50, 0x9d, 0xf6, 0xed, 0xe9, 0x60, 0x74, 0xd5, 0x92, 0x30, 0xa5, 0x7a, 0x39, 0x1b, 0x0c, 0x7b,
-	0xdc, 0x2a, 0xf3, 0x90, 0x3d, 0xed, 0x8c, 0x7a, 0x97, 0xbf, 0xb6, 0x64, 0x6e, 0xfc, 0xd2, 0x19,
-	0x0c, 0x67, 0x93, 0x7e, 0x4b, 0xe1, 0xfb, 0x7a, 0x03, 0xbb, 0x73, 0x39, 0xec, 0xf7, 0x5a, 0x15,
-	0x6e, 0x61, 0xca, 0xf1, 0xad, 0x8d, 0x56, 0xd5, 0x3c, 0x85, 0xea, 0xa5, 0xeb, 0x3d, 0x90, 0xc8,
-	0xd7, 0xff, 0x0f, 0xf2, 0x02, 0x8b, 0x10, 0xd5, 0xd7, 0xce, 0x14, 0x51, 0x91, 0xbe, 0x07, 0x95,
-	0x35, 0x09, 0xe6, 0x0b, 0x26, 0xae, 0xa1, 0xb4, 0x4b, 0xa7, 0xe6, 0x8f, 0x20, 0x7f, 0x0c, 0xdd,
-	0x48, 0xff, 0x1f, 0x54, 0x57, 0xf8, 0xeb, 0x04, 0xbe, 0x38, 0xa2, 0x6c, 0x13, 0x48, 0x3b, 0x09,
-	0xcc, 0x3f, 0xca, 0x50, 0xbb, 0x26, 0x6e, 0xc8, 0x16, 0xde, 0x82, 0x78, 0x0f, 0xfa, 0xd7, 0x20,
-	0xb3, 0xc7, 0x84, 0x88, 0x23, 0xcd, 0xb3, 0x3d, 0x6b, 0x27, 0x66, 0x4d, 0x31, 0xa0, 0xef, 0x83,
-	0x1a, 0x44, 0x8c, 0xd0, 0x95, 0x1b, 0x16, 0xdf, 0x94, 0x4e, 0x4f, 0x74, 0x1d, 0xaa, 0x2c, 0x58,
-	0x92, 0x38, 0x63, 0x02, 0x41, 0x2c, 0xe4, 0x82, 0x43, 0x9a, 0xc4, 0x94, 0x09, 0x08, 0xf9, 0x2d,
-	0xe5, 0x14, 0xaf, 0x61, 0x28, 0x02, 0x60, 0x2c, 0x8e, 0x12, 0x8f, 0x04, 0x2b, 0x62, 0x54, 0x36,
-	0xf8, 0x7b, 0xb1, 0x4f, 0x8c, 0xaa, 0xd8, 0xfc, 0x3d, 0xc8, 0x4b, 0x6e, 0xa9, 0x02, 0xfd, 0xe7,
-	0x55, 0xdc, 0x60, 0xa0, 0xad, 0x8c, 0x87, 0x9d, 0xc1, 0x08, 0xf1, 0xaf, 0x2c, 0x09, 0x5b, 0xc4,
-	0xbe, 0xa1, 0x89, 0x2c, 0x0d, 0x50, 0x90, 0xf3, 0xdf, 0x1f, 0x0d, 0x40, 0x53, 0xd5, 0x0d, 0x00,
-	0x16, 0xa6, 0xce, 0x8a, 0xd0, 0xe0, 0xfe, 0xd1, 0xa8, 0x71, 0x5f, 0x5b, 0x66, 0x34, 0x23, 0xf9,
-	0xf7, 0x19, 0x0d, 0x48, 0x6a, 0xd4, 0xf9, 0x17, 0xcd, 0xdf, 0x40, 0x16, 0xd7, 0x6b, 0x80, 0x36,
-	0xe8, 0xde, 0x8c, 0x9d, 0x31, 0x67, 0xad, 0xa4, 0x57, 0xa1, 0x3c, 0xeb, 0x8d, 0x91, 0x4c, 0x5c,
-	0x4c, 0xbb, 0x63, 0xe4, 0x51, 0x45, 0x31, 0x4d, 0xa7, 0x63, 0x24, 0x51, 0x03, 0x85, 0xaf, 0x6c,
-	0xa4, 0x10, 0xa3, 0xbd, 0x91, 0x8d, 0xec, 0x71, 0x01, 0x74, 0xc7, 0xce, 0x74, 0x68, 0xb7, 0xaa,
-	0x3a, 0x40, 0x65, 0xd2, 0xe9, 0x0d, 0x66, 0x76, 0x4b, 0x35, 0xbf, 0x04, 0x99, 0x17, 0xce, 0x0f,
-	0x89, 0xd2, 0xf3, 0xdc, 0x3d, 0x7b, 0xd2, 0x92, 0xcc, 0xbf, 0xcb, 0x50, 0xff, 0x98, 0x22, 0xa4,
-	0x84, 0xf6, 0x23, 0x46, 0x1f, 0xf5, 0x77, 0xa0, 0x0a, 0xe9, 0x7a, 0x71, 0x58, 0x10, 0xa1, 0x59,
-	0xe3, 0xc2, 0xb1, 0x85, 0x55, 0x12, 0xa4, 0xbe, 0x07, 0x2d, 0x45, 0x5c, 0xfc, 0x2c, 0x24, 0x54,
-	0x60, 0xdb, 0x3c, 0x7b, 0x6b, 0xed, 0x26, 0xb3, 0xec, 0x4d, 0xb8, 0x5d, 0xfe, 0x34, 0xec, 0xea,
-	0xdf, 0x15, 0xd0, 0x56, 0xc4, 0x5e, 0xfd, 0xf9, 0x5e, 0x81, 0x2d, 0xaf, 0x0a, 0xc5, 0x52, 0x4b,
-	0x08, 0x4d, 0x83, 0x94, 0x91, 0xc8, 0xdb, 0xd0, 0xb2, 0x07, 0xda, 0xe7, 0x0c, 0x31, 0xf3, 0x48,
-	0xc4, 0x04, 0x37, 0x2a, 0x76, 0xca, 0x7e, 0x9e, 0xc0, 0x09, 0xe3, 0xb5, 0xb3, 0x76, 0x51, 0x19,
-	0x4b, 0x97, 0x3e, 0x08, 0x3e, 0x24, 0xfd, 0x2b, 0x38, 0x28, 0xa2, 0x0b, 0x14, 0xe9, 0x4e, 0x18,
-	0x44, 0x18, 0x11, 0x42, 0x5a, 0x29, 0x49, 0x17, 0x71, 0xe8, 0x0b, 0x7e, 0x14, 0xee, 0xcb, 0x9e,
-	0x7c, 0x82, 0x1c, 0xfd, 0x1b, 0xa8, 0x2d, 0x9e, 0x14, 0x60, 0x34, 0x8e, 0xca, 0x28, 0xe0, 0xfa,
-	0xae, 0x2a, 0xf8, 0xb1, 0x38, 0x22, 0x4e, 0xc2, 0x9b, 0x85, 0x19, 0x4d, 0x5e, 0x9b, 0x79, 0x01,
-	0xda, 0xf6, 0xf2, 0x7a, 0x05, 0xa4, 0xc9, 0x24, 0x47, 0xfd, 0x13, 0x2e, 0x24, 0xee, 0x18, 0x76,
-	0x91, 0x50, 0xee, 0xc0, 0x85, 0xcc, 0x1d, 0xf6, 0x75, 0x4b, 0x31, 0x8d, 0x82, 0xaa, 0x82, 0x1f,
-	0x71, 0x64, 0xd4, 0x99, 0x22, 0x51, 0x7f, 0x95, 0xa0, 0xd6, 0xf1, 0x3c, 0x92, 0xa6, 0x57, 0xd4,
-	0x8d, 0x18, 0x57, 0xd1, 0x9c, 0x2f, 0x08, 0x29, 0x66, 0x0a, 0x76, 0x0f, 0x8d, 0x43, 0x22, 0xb8,
-	0xe1, 0xba, 0xdd, 0xd9, 0x6c, 0x4d, 0x30, 0xb0, 0x6d, 0xaf, 0xf2, 0x2b, 0x1b, 0xb8, 0xfe, 0xb8,
-	0x4e, 0xc4, 0x46, 0xd4, 0x49, 0xa7, 0x77, 0xb3, 0xd1, 0xc9, 0x2d, 0xaa, 0x4c, 0x32, 0xdf, 0x15,
-	0x1a, 0x45, 0x09, 0xce, 0xec, 0x3e, 0xaf, 0x0c, 0x77, 0x5d, 0x4d, 0x6e, 0x67, 0x28, 0x50, 0xf3,
-	0x4f, 0x09, 0xaa, 0x05, 0x97, 0x5c, 0x22, 0x91, 0xbb, 0xdc, 0x14, 0x75, 0x08, 0x0d, 0xc2, 0xd9,
-	0x75, 0x5c, 0xdf, 0x47, 0x5c, 0xd3, 0x67, 0x03, 0x00, 0x71, 0x93, 0x68, 0x22, 0xea, 0x11, 0x5d,
-	0x99, 0xa5, 0xc4, 0xb9, 0x5f, 0x2f, 0x45, 0xd3, 0xaa, 0xfa, 0xb7, 0xd0, 0x58, 0x15, 0x04, 0x8a,
-	0x14, 0xa8, 0x30, 0x0e, 0x7d, 0xe3, 0x99, 0x6a, 0x90, 0xe5, 0x66, 0x48, 0xe6, 0xae, 0xf7, 0xe8,
-	0xdc, 0xe5, 0xb3, 0x0a, 0xc5, 0x55, 0x7e, 0xfa, 0xc2, 0x17, 0x50, 0xdd, 0xf8, 0x41, 0xf8, 0x55,
-	0x6b, 0x33, 0xd3, 0x5e, 0x10, 0x5b, 0x7d, 0x85, 0x58, 0x13, 0xea, 0xae, 0x00, 0xc9, 0x11, 0x50,
-	0xa3, 0xec, 0xf2, 0x3d, 0x2f, 0x78, 0x58, 0xbb, 0x34, 0x0a, 0xa2, 0x39, 0xea, 0xae, 0x7c, 0xac,
-	0x99, 0x3f, 0xc1, 0xfe, 0x4d, 0x90, 0xe6, 0xaf, 0x40, 0x46, 0x89, 0xff, 0x3a, 0x30, 0x07, 0x08,
-	0x0c, 0xa5, 0x31, 0x75, 0x96, 0x98, 0xc9, 0x9d, 0x93, 0xfc, 0x29, 0x30, 0x8f, 0x41, 0xeb, 0x30,
-	0x9c, 0x0d, 0x77, 0x19, 0x23, 0x2f, 0x4e, 0xe0, 0x7c, 0xc1, 0xb9, 0x97, 0xe5, 0x04, 0x6b, 0xe6,
-	0xcf, 0xa0, 0xde, 0x10, 0xe6, 0xfa, 0x2e, 0x73, 0x71, 0x2e, 0xd6, 0x43, 0x37, 0x65, 0x4e, 0x96,
-	0xa0, 0x49, 0xf2, 0x99, 0x5b, 0x46, 0x68, 0x34, 0x77, 0x93, 0x0b, 0x0f, 0xf1, 0xd2, 0xc1, 0xda,
-	0x66, 0x37, 0xff, 0x45, 0xd2, 0xba, 0x61, 0x86, 0x4d, 0x46, 0x11, 0x26, 0x48, 0x09, 0x49, 0xdd,
-	0xb5, 0xb3, 0x0a, 0x92, 0xe7, 0x53, 0x1e, 0x27, 0x77, 0xc4, 0x7b, 0x56, 0xda, 0x85, 0x15, 0xa5,
-	0xb4, 0x5a, 0xba, 0x5e, 0xfe, 0x62, 0xb5, 0xf7, 0x4e, 0x4e, 0xda, 0xf8, 0x77, 0xd1, 0xe7, 0xff,
-	0x4f, 0x4e, 0xf1, 0x8f, 0xf3, 0x7e, 0x37, 0x4f, 0xb0, 0x2f, 0x3d, 0x37, 0x74, 0xdc, 0x34, 0x12,
-	0x9c, 0x36, 0xda, 0xca, 0x87, 0xf3, 0x8b, 0xd3, 0x33, 0xfd, 0x0d, 0x34, 0x79, 0x94, 0x92, 0x65,
-	0xcc, 0x88, 0x08, 0xf3, 0xe9, 0xd1, 0xd0, 0xdf, 0x82, 0xca, 0xfd, 0x09, 0xc1, 0x79, 0xf2, 0x92,
-	0xc6, 0x42, 0x0b, 0x05, 0x4f, 0xea, 0x46, 0x05, 0xbc, 0x3e, 0xfe, 0xd4, 0x14, 0xdc, 0x28, 0x96,
-	0x78, 0x7f, 0xce, 0xe1, 0x60, 0xb9, 0xcb, 0x81, 0xb3, 0x39, 0xad, 0x89, 0x5d, 0x07, 0xd6, 0xab,
-	0x0c, 0xe1, 0xe8, 0x5b, 0x16, 0x90, 0x8a, 0x21, 0x51, 0xc3, 0xd1, 0xb7, 0xc5, 0x18, 0x87, 0x8d,
-	0x4f, 0xfc, 0xc0, 0xe3, 0x00, 0x73, 0x94, 0x9c, 0x34, 0xbb, 0x8b, 0xb0, 0xdd, 0x6b, 0x9c, 0xf4,
-	0x1f, 0x0e, 0xf1, 0xe5, 0xdc, 0x0c, 0xc9, 0x62, 0x5c, 0x3f, 0x0d, 0xf0, 0xff, 0x02, 0x00, 0x00,
-	0xff, 0xff, 0x5d, 0xb7, 0x57, 0xd0, 0x1e, 0x08, 0x00, 0x00,
+	// 1553 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x96, 0x5f, 0x6f, 0xe3, 0xb8,
+	0x11, 0xc0, 0x61, 0x5b, 0xb2, 0xa5, 0xf1, 0x9f, 0xd0, 0x4c, 0x72, 0xd1, 0x76, 0xb3, 0xd8, 0x9c,
+	0xd0, 0x16, 0x41, 0x51, 0xf8, 0x92, 0x60, 0x77, 0x1f, 0x5c, 0x14, 0x85, 0x63, 0xfb, 0x36, 0xc6,
+	0x39, 0x89, 0x6b, 0x39, 0xb7, 0xbd, 0x27, 0x81, 0x91, 0x18, 0x5b, 0x58, 0xfd, 0x3b, 0x92, 0xb2,
+	0x37, 0xdf, 0xa2, 0xaf, 0xfd, 0x28, 0x05, 0xfa, 0xda, 0x97, 0x7e, 0x92, 0x7e, 0x8c, 0x82, 0x94,
+	0xe4, 0x38, 0xbb, 0xe9, 0x8b, 0x4d, 0xce, 0x8c, 0x38, 0xc3, 0x99, 0x1f, 0x87, 0x84, 0xef, 0xd2,
+	0xfb, 0x1f, 0xbc, 0x24, 0x7e, 0x08, 0x96, 0xc5, 0x5f, 0x2f, 0x65, 0x89, 0x48, 0xec, 0x7f, 0x56,
+	0x40, 0xbb, 0x4a, 0xb8, 0xc0, 0x2d, 0xd0, 0x1e, 0x7e, 0xf5, 0x63, 0xab, 0x72, 0x52, 0x3d, 0x35,
+	0xe5, 0x2c, 0x48, 0xd7, 0xef, 0xac, 0xea, 0x49, 0x65, 0x3b, 0xfb, 0x60, 0xd5, 0xd4, 0xec, 0x18,
+	0xea, 0x5c, 0x10, 0x91, 0x71, 0x4b, 0x3b, 0xa9, 0x9c, 0x76, 0x2e, 0x5a, 0x3d, 0xb9, 0x40, 0xcf,
+	0x51, 0x32, 0x3b, 0x80, 0x7a, 0x3e, 0xc2, 0x1d, 0x80, 0xd9, 0xfc, 0x76, 0x74, 0x37, 0x5c, 0x4c,
+	0x6e, 0x6f, 0x50, 0x05, 0x37, 0xa1, 0xb1, 0x18, 0x3b, 0x8b, 0xc9, 0xcd, 0x47, 0x54, 0xc5, 0x2d,
+	0x30, 0x2e, 0xef, 0x26, 0xd3, 0x91, 0x9c, 0xd5, 0xa4, 0xca, 0x59, 0x0c, 0x6e, 0x46, 0x97, 0xbf,
+	0x20, 0x4d, 0x4e, 0x7e, 0x1c, 0x4c, 0xa6, 0x77, 0xf3, 0x31, 0xd2, 0xa5, 0xdd, 0x68, 0xe2, 0x0c,
+	0x2e, 0xa7, 0xe3, 0x11, 0xaa, 0xcb, 0xd9, 0x6c, 0x7e, 0x3b, 0xbb, 0x75, 0xc6, 0x23, 0xd4, 0xb0,
+	0x3d, 0x68, 0x5c, 0x12, 0xef, 0x33, 0x8d, 0x7d, 0xbc, 0x0f, 0xda, 0x2a, 0xe1, 0x42, 0x45, 0xdf,
+	0xbc, 0xd0, 0x55, 0x44, 0xb8, 0x0b, 0xf5, 0x0d, 0x0d, 0x96, 0x2b, 0xa1, 0xb6, 0xa1, 0xf7, 0x2b,
+	0xe7, 0xf8, 0x08, 0xf6, 0xb2, 0x34, 0xa5, 0xcc, 0x15, 0x2b, 0x46, 0xf9, 0x2a, 0x09, 0x7d, 0xb5,
+	0x29, 0x5d, 0x2a, 0xc2, 0x64, 0xf3, 0x4c, 0x21, 0x77, 0xa7, 0xdb, 0x7f, 0x04, 0xed, 0xe7, 0x90,
+	0xc4, 0x78, 0x0f, 0x1a, 0xeb, 0x90, 0xc4, 0x6e, 0xe0, 0x2b, 0x27, 0xfa, 0xd6, 0x65, 0x75, 0xc7,
+	0xa5, 0xfd, 0x5f, 0x0d, 0x9a, 0x57, 0x94, 0x84, 0x62, 0xe5, 0xad, 0xa8, 0xf7, 0x19, 0xbf, 0x05,
+	0x4d, 0x3c, 0xa6, 0x54, 0x7d, 0xd2, 0xb9, 0xe8, 0xf6, 0x76, 0x74, 0xbd, 0xc5, 0x63, 0x4a, 0xf1,
+	0x01, 0x18, 0x41, 0x2c, 0x28, 0x5b, 0x93, 0xb0, 0x88, 0xb2, 0x7a, 0x7e, 0x86, 0x31, 0x34, 0x44,
+	0x10, 0xd1, 0x24, 0x13, 0x79, 0x78, 0xfd, 0xca, 0x7b, 0x59, 0x84, 0x34, 0x61, 0x22, 0x0f, 0x4b,
+	0xce, 0x38, 0x8d, 0x7d, 0x4b, 0x57, 0x25, 0xd9, 0x83, 0x06, 0xa3, 0x1e, 0x0d, 0xd6, 0xd4, 0xaa,
+	0x97, 0x15, 0xf3, 0x12, 0x9f, 0x5a, 0x0d, 0x65, 0xfc, 0x7b, 0xd0, 0x22, 0x39, 0x33, 0x4e, 0x2a,
+	0xdf, 0x44, 0x71, 0x9d, 0xf8, 0xb4, 0xaf, 0xcf, 0xa6, 0x83, 0xc9, 0x0d, 0xee, 0x40, 0x3d, 0xa2,
+	0x62, 0x95, 0xf8, 0x96, 0xa9, 0x56, 0x69, 0x83, 0x9e, 0xb2, 0xe4, 0xcb, 0xa3, 0x05, 0x27, 0x95,
+	0x53, 0x03, 0x5b, 0x00, 0x22, 0xe4, 0xee, 0x9a, 0xb2, 0xe0, 0xe1, 0xd1, 0x6a, 0x4a, 0x59, 0x5f,
+	0x13, 0x2c, 0xa3, 0xb9, 0x7f, 0xc1, 0x02, 0xca, 0xad, 0x96, 0xf2, 0xf8, 0x0a, 0xba, 0x3c, 0x4c,
+	0x36, 0x4f, 0xd9, 0x74, 0x23, 0x6e, 0xb5, 0xcb, 0x4c, 0xd3, 0x2f, 0x29, 0xf5, 0x84, 0xbb, 0x61,
+	0x81, 0x20, 0xf7, 0x21, 0xb5, 0x3a, 0x6a, 0xf9, 0x2e, 0x98, 0x3c, 0xc9, 0x98, 0x47, 0xdd, 0x20,
+	0xb5, 0xf6, 0x54, 0x00, 0x16, 0xa0, 0x52, 0x24, 0x93, 0xf4, 0x40, 0x3c, 0x6a, 0xa1, 0x72, 0x83,
+	0xf7, 0x89, 0xff, 0x68, 0x75, 0xd5, 0xec, 0x08, 0xf6, 0xbc, 0x24, 0x8e, 0xe5, 0xa2, 0x65, 0xde,
+	0xb0, 0xaa, 0xde, 0xbf, 0x2a, 0xa0, 0xa9, 0x3c, 0xb7, 0xc1, 0x9c, 0x0c, 0xaf, 0x67, 0xee, 0x4c,
+	0x02, 0x57, 0xc1, 0x0d, 0xa8, 0xdd, 0x8d, 0x66, 0xa8, 0x2a, 0x07, 0x8b, 0xe1, 0x0c, 0xd5, 0xb0,
+	0x01, 0xda, 0xd5, 0x62, 0x31, 0x43, 0x1a, 0x36, 0x41, 0x97, 0x23, 0x07, 0xe9, 0x52, 0x3b, 0xba,
+	0x71, 0x50, 0x5d, 0xb1, 0x3b, 0x9c, 0xb9, 0x8b, 0xa9, 0x83, 0x1a, 0x18, 0xa0, 0x3e, 0x1f, 0x8c,
+	0x26, 0x77, 0x0e, 0x32, 0xe4, 0x67, 0x1f, 0xe7, 0xb3, 0x21, 0x92, 0x11, 0x19, 0x72, 0xa4, 0x6c,
+	0x40, 0xca, 0xc7, 0x7f, 0x1b, 0x0f, 0x51, 0x53, 0x8e, 0x9c, 0xeb, 0xc5, 0x0c, 0xb5, 0x70, 0x17,
+	0xda, 0x72, 0xe4, 0x3a, 0x8b, 0xc1, 0x7c, 0x21, 0xcd, 0xda, 0xd2, 0xd7, 0x7c, 0x3c, 0x9a, 0x38,
+	0xa8, 0x23, 0x87, 0xd7, 0xbf, 0x38, 0x7f, 0x9d, 0xa2, 0x3d, 0xe9, 0xf6, 0x66, 0x31, 0x43, 0xc8,
+	0xfe, 0x0d, 0x68, 0xb2, 0x3e, 0x52, 0xa7, 0x2a, 0x94, 0x47, 0x3e, 0x72, 0xe6, 0xa8, 0x6a, 0xff,
+	0x5b, 0x83, 0xd6, 0xcf, 0x9c, 0xb2, 0x35, 0x65, 0xe3, 0x58, 0xb0, 0x47, 0xfc, 0x1a, 0x0c, 0x75,
+	0xa6, 0xbd, 0x24, 0x2c, 0x78, 0x33, 0x7b, 0xb3, 0x42, 0xb0, 0xa5, 0xa7, 0xaa, 0xd8, 0xfd, 0x01,
+	0x4c, 0xee, 0xad, 0xa8, 0x9f, 0x85, 0x94, 0x29, 0x84, 0x3a, 0x17, 0x47, 0xbd, 0xdd, 0xc5, 0x7a,
+	0x4e, 0xa9, 0xee, 0xd7, 0x3e, 0x4d, 0x87, 0xf8, 0x77, 0x05, 0x41, 0x75, 0x65, 0x8b, 0x9f, 0xdb,
+	0x2a, 0x84, 0x64, 0x54, 0x78, 0x1f, 0x9a, 0x29, 0x65, 0x3c, 0xe0, 0x82, 0xc6, 0x5e, 0x49, 0x5f,
+	0x17, 0xcc, 0x5f, 0xb3, 0x80, 0x72, 0x8f, 0xc6, 0x42, 0x21, 0x68, 0xe0, 0x63, 0x38, 0xc8, 0x17,
+	0x70, 0x25, 0x24, 0x1b, 0x22, 0x28, 0x8b, 0x08, 0xfb, 0xac, 0xb0, 0xab, 0xe2, 0x37, 0x70, 0x58,
+	0x68, 0x57, 0xc1, 0x72, 0xb5, 0xa3, 0x06, 0xa5, 0xc6, 0x00, 0xe1, 0xd3, 0x29, 0x6d, 0x2a, 0x1f,
+	0x18, 0x20, 0x7b, 0x92, 0xe5, 0x0c, 0x7e, 0x0f, 0xcd, 0xd5, 0x13, 0xe8, 0x56, 0xfb, 0xa4, 0x76,
+	0xda, 0x94, 0xcd, 0xea, 0x49, 0x26, 0x3f, 0x4b, 0x62, 0xea, 0xa6, 0xb2, 0x8b, 0x88, 0x02, 0xc3,
+	0x7d, 0x68, 0x46, 0x2b, 0xf7, 0x81, 0x84, 0xe1, 0x3d, 0xf1, 0x3e, 0x2b, 0x10, 0x0d, 0x09, 0x78,
+	0xb4, 0x72, 0x55, 0x06, 0x51, 0x69, 0xc5, 0x77, 0xac, 0xba, 0xa5, 0x15, 0x2f, 0xac, 0xb0, 0x12,
+	0xbc, 0x85, 0xa3, 0x9d, 0x7c, 0xb8, 0x29, 0xa3, 0x0f, 0xc1, 0x17, 0x57, 0x75, 0xd6, 0x7d, 0x15,
+	0xe3, 0xff, 0x35, 0xf8, 0x60, 0x1d, 0x28, 0x80, 0xff, 0x0c, 0xe6, 0xb6, 0x14, 0xb8, 0x0e, 0xd5,
+	0xf9, 0x3c, 0x67, 0xe0, 0xd3, 0x7c, 0x8e, 0xaa, 0x52, 0x30, 0x1d, 0xa2, 0x9a, 0x12, 0x4c, 0x87,
+	0x48, 0x93, 0x02, 0xe7, 0x0a, 0xe9, 0xf2, 0xff, 0xfa, 0x0a, 0xd5, 0xed, 0xef, 0x0b, 0x80, 0x0a,
+	0x6a, 0xd4, 0xa7, 0x37, 0x83, 0x45, 0x01, 0xfe, 0xdd, 0x0d, 0xaa, 0xd9, 0xff, 0xa8, 0x40, 0x73,
+	0xe0, 0x79, 0x94, 0xf3, 0x8f, 0x8c, 0xc4, 0x42, 0x6e, 0x62, 0x29, 0x07, 0x94, 0x16, 0x77, 0xc1,
+	0x5b, 0xd0, 0x58, 0x12, 0x52, 0x85, 0x8e, 0xec, 0x1e, 0x3b, 0xc6, 0xbd, 0x79, 0x12, 0xd2, 0x6d,
+	0x93, 0xab, 0xbd, 0x60, 0x20, 0x0f, 0x9f, 0xc4, 0x58, 0x19, 0x9a, 0xa0, 0x0f, 0x46, 0xd7, 0x25,
+	0xc6, 0xb7, 0x33, 0x07, 0x55, 0xed, 0xd7, 0xc5, 0x01, 0x35, 0x40, 0xbb, 0x73, 0xc6, 0x32, 0x44,
+	0x13, 0xf4, 0x8f, 0xf3, 0xdb, 0xbb, 0x19, 0xaa, 0xda, 0x7f, 0xd7, 0xa0, 0x51, 0xa0, 0x26, 0x09,
+	0x8e, 0x49, 0x54, 0x06, 0x75, 0x0c, 0x6d, 0x2a, 0xe1, 0x73, 0x89, 0xef, 0x33, 0xca, 0xf9, 0xb3,
+	0x36, 0x8c, 0x01, 0xaa, 0x2c, 0x55, 0xf1, 0xa8, 0xde, 0x98, 0x71, 0xea, 0x3e, 0x6c, 0x22, 0xd5,
+	0x3a, 0x0d, 0xfc, 0x5b, 0x68, 0xaf, 0x0b, 0xbe, 0xd4, 0x12, 0x96, 0xae, 0xc8, 0x68, 0x3f, 0x83,
+	0x1a, 0xbf, 0x81, 0x4e, 0x48, 0x97, 0xc4, 0x7b, 0x74, 0xef, 0xf3, 0x3b, 0xc6, 0xaa, 0x9f, 0xd4,
+	0x9e, 0x3c, 0xbc, 0x82, 0x46, 0x29, 0x07, 0x25, 0x37, 0x7a, 0xe5, 0x5d, 0xf4, 0x15, 0x77, 0x8d,
+	0x17, 0xb8, 0xb3, 0xa1, 0x45, 0x54, 0x92, 0x5c, 0x95, 0x6a, 0xcb, 0x28, 0x6c, 0xbe, 0xaa, 0xc3,
+	0x86, 0xb0, 0x38, 0x88, 0x97, 0x96, 0x79, 0x52, 0x3b, 0x35, 0xf1, 0x6b, 0xd8, 0x57, 0x3d, 0x95,
+	0x0b, 0xc2, 0x84, 0xeb, 0x67, 0x8c, 0x88, 0x20, 0x89, 0x8b, 0x03, 0x70, 0x08, 0x6d, 0x9f, 0x91,
+	0x20, 0xde, 0xf6, 0xbf, 0xd6, 0xb7, 0x67, 0xaf, 0xad, 0xb6, 0x7f, 0x01, 0xcd, 0x24, 0x76, 0x49,
+	0x18, 0xba, 0x7e, 0xb2, 0x89, 0xad, 0xce, 0xf3, 0x13, 0xdd, 0xbb, 0x8d, 0x07, 0x61, 0x38, 0x4a,
+	0x36, 0x71, 0xdf, 0xbc, 0x9c, 0x0e, 0x86, 0x3f, 0x5d, 0xdd, 0x4e, 0xc7, 0xd8, 0x06, 0x54, 0xa2,
+	0xbe, 0x4d, 0x87, 0x3c, 0x18, 0xbb, 0xdb, 0xfe, 0x0e, 0x3a, 0xc4, 0xf7, 0x03, 0x19, 0x13, 0x09,
+	0xdd, 0x75, 0x90, 0x5a, 0x48, 0x85, 0x7d, 0x08, 0xed, 0xfb, 0x65, 0xea, 0x7a, 0x49, 0x14, 0x65,
+	0x71, 0x20, 0x64, 0xcb, 0xae, 0x9d, 0x9a, 0xf6, 0x07, 0x30, 0xb7, 0xae, 0x64, 0x77, 0xde, 0x3a,
+	0x43, 0x15, 0xd9, 0x4a, 0x3f, 0x4d, 0x16, 0x57, 0xa3, 0xf9, 0xe0, 0x53, 0xfe, 0x54, 0xf8, 0x71,
+	0x30, 0x9d, 0x5e, 0x0e, 0x86, 0x3f, 0xa1, 0x9a, 0xfd, 0x27, 0x38, 0xb8, 0x0e, 0x78, 0xfe, 0x86,
+	0xc9, 0x18, 0xf5, 0x5f, 0xc6, 0xe3, 0x10, 0xda, 0x94, 0xb1, 0x84, 0xb9, 0x11, 0xe5, 0x9c, 0x2c,
+	0x69, 0xfe, 0x90, 0xb1, 0x4f, 0xc1, 0x1c, 0x08, 0xc1, 0x82, 0xfb, 0x4c, 0xd0, 0xaf, 0xbe, 0x68,
+	0x83, 0xbe, 0x26, 0x61, 0x96, 0x63, 0x6e, 0xda, 0x7f, 0x01, 0xe3, 0x9a, 0x0a, 0xe2, 0x13, 0x41,
+	0xf0, 0x01, 0xb4, 0x42, 0xc2, 0x85, 0x9b, 0xa5, 0x3e, 0x11, 0x34, 0xbf, 0xff, 0x6b, 0xf8, 0x0d,
+	0x98, 0xa4, 0x5c, 0xcb, 0xaa, 0xaa, 0x02, 0x42, 0x6f, 0xbb, 0xba, 0xfd, 0x9f, 0x2a, 0x34, 0x86,
+	0x61, 0xc6, 0x05, 0x65, 0xf8, 0x15, 0x00, 0xa7, 0x94, 0x93, 0x8d, 0x4a, 0xcb, 0xb3, 0x37, 0xca,
+	0x3e, 0x68, 0x71, 0xe2, 0x97, 0x0b, 0x14, 0xc2, 0xb7, 0xa0, 0xad, 0x23, 0xe2, 0xe5, 0xef, 0xad,
+	0x7e, 0xf7, 0xec, 0xac, 0x7f, 0x76, 0xd6, 0x7f, 0x3f, 0x96, 0xbf, 0x67, 0xe7, 0xfd, 0xb3, 0x73,
+	0x7c, 0x9c, 0xe7, 0x34, 0x4c, 0x3c, 0x12, 0xba, 0x84, 0xc7, 0x8a, 0xec, 0x76, 0x5f, 0xff, 0xf0,
+	0xee, 0xfd, 0xf9, 0x85, 0xac, 0x84, 0xd4, 0x32, 0x1a, 0x25, 0x82, 0x2a, 0xb5, 0x6c, 0xf1, 0x6d,
+	0x7c, 0x04, 0x86, 0x94, 0xa7, 0x94, 0xb2, 0x6f, 0x60, 0x2e, 0x4e, 0x44, 0x41, 0xab, 0x51, 0xe2,
+	0x20, 0xe3, 0x93, 0xcf, 0x9e, 0x82, 0x50, 0xbd, 0xa7, 0xde, 0x42, 0xef, 0xe0, 0x30, 0xda, 0xad,
+	0x81, 0x5b, 0x7e, 0x6d, 0x2a, 0xab, 0xc3, 0xde, 0x8b, 0x15, 0x7a, 0x0d, 0x46, 0x54, 0xa4, 0x54,
+	0x75, 0xf2, 0xe6, 0x85, 0xd9, 0xdb, 0xe6, 0xf8, 0x18, 0x0e, 0x7c, 0xea, 0x07, 0x9e, 0x4c, 0xb0,
+	0xcc, 0x92, 0xcb, 0xb3, 0xfb, 0x98, 0x0a, 0xab, 0x29, 0x61, 0xf9, 0xc3, 0x31, 0x18, 0xdb, 0x9b,
+	0xac, 0xb8, 0xb1, 0x9f, 0xee, 0xf0, 0xff, 0x0d, 0x00, 0x12, 0x69, 0xe5, 0x0a, 0xdc, 0x0a, 0x00,
+	0x00,
 }
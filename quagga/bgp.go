@@ -20,9 +20,13 @@ package quagga
 // the Quagga BGP daemon.
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -138,6 +142,13 @@ type Neighbor struct {
 	Description string
 	BGPState    BGPState
 	Uptime      time.Duration
+	BFDStatus   string // The state of the neighbor's BFD session (empty if BFD is disabled).
+
+	// PrefixesAdvertised and PrefixesReceived are the numbers of prefixes
+	// that have been advertised to and accepted from the neighbor.
+	PrefixesAdvertised uint64
+	PrefixesReceived   uint64
+
 	MessageStats
 }
 
@@ -195,42 +206,344 @@ func (b *BGP) Neighbors() ([]*Neighbor, error) {
 	if err != nil {
 		return nil, err
 	}
-	// TODO(jsing): Include details for advertised/received routes.
-	return parseNeighbors(ni), nil
+	neighbors := parseNeighbors(ni)
+	for _, n := range neighbors {
+		if n.BGPState != BGPStateEstablished {
+			continue
+		}
+		cmd := fmt.Sprintf("show ip bgp neighbors %s advertised-routes", n.IP)
+		if n.IP.To4() == nil {
+			cmd = fmt.Sprintf("show bgp ipv6 neighbors %s advertised-routes", n.IP)
+		}
+		ar, err := b.vty.Command(cmd)
+		if err != nil {
+			return nil, err
+		}
+		n.PrefixesAdvertised = parseAdvertisedRoutes(ar)
+	}
+	return neighbors, nil
 }
 
 // network adds or removes a network statement from the BGP configuration.
-func (b *BGP) network(n *net.IPNet, advertise bool) error {
-	var prefix string
-	if !advertise {
-		prefix = "no "
+// Community route-maps that are no longer referenced as a result, such as
+// the route-map for the previous communities of the network, are deleted.
+func (b *BGP) network(n *net.IPNet, advertise bool, communities []string) error {
+	if err := ValidateCommunities(communities); err != nil {
+		return err
 	}
+	bgpConfigLock.Lock()
+	defer bgpConfigLock.Unlock()
+	if err := b.vty.Commands(b.networkCommands(n, advertise, communities)); err != nil {
+		return err
+	}
+	return b.deleteUnusedRouteMaps()
+}
+
+// networkCommands returns the commands needed to add or remove a network
+// statement. If communities are specified, the network is advertised via a
+// route-map that sets them. The route-map is named after its communities and
+// is never modified, so that changing the communities for a network only
+// replaces its route-map - the route is then re-advertised with the new
+// communities, without the BGP session being reset.
+func (b *BGP) networkCommands(n *net.IPNet, advertise bool, communities []string) []string {
 	family := "ipv4 unicast"
 	if n.IP.To4() == nil {
 		family = "ipv6"
 	}
 	prefixLen, _ := n.Mask.Size()
-	cmds := []string{
-		"configure terminal",
+	network := fmt.Sprintf("network %s/%d", n.IP, prefixLen)
+
+	cmds := []string{"configure terminal"}
+	switch {
+	case !advertise:
+		network = "no " + network
+	case len(communities) > 0:
+		standard, large := splitCommunities(communities)
+		routeMap := CommunityRouteMap(communities)
+		cmds = append(cmds, fmt.Sprintf("route-map %s permit 10", routeMap))
+		if len(standard) > 0 {
+			cmds = append(cmds, fmt.Sprintf("set community %s", strings.Join(standard, " ")))
+		}
+		if len(large) > 0 {
+			cmds = append(cmds, fmt.Sprintf("set large-community %s", strings.Join(large, " ")))
+		}
+		cmds = append(cmds, "exit")
+		network += " route-map " + routeMap
+	}
+	return append(cmds,
 		fmt.Sprintf("router bgp %d", b.asn),
 		fmt.Sprintf("address-family %s", family),
-		fmt.Sprintf("%snetwork %s/%d", prefix, n.IP, prefixLen),
+		network,
 		"end",
-	}
-	bgpConfigLock.Lock()
-	defer bgpConfigLock.Unlock()
-	return b.vty.Commands(cmds)
+	)
 }
 
 // Advertise requests the BGP daemon to advertise the specified network.
 func (b *BGP) Advertise(n *net.IPNet) error {
-	return b.network(n, true)
+	return b.network(n, true, nil)
+}
+
+// AdvertiseWithCommunities requests the BGP daemon to advertise the specified
+// network, tagged with the given BGP communities. If the network is already
+// being advertised, it is re-advertised with the given communities.
+func (b *BGP) AdvertiseWithCommunities(n *net.IPNet, communities []string) error {
+	return b.network(n, true, communities)
 }
 
 // Withdraw requests the BGP daemon to withdraw advertisements for the
 // specified network.
 func (b *BGP) Withdraw(n *net.IPNet) error {
-	return b.network(n, false)
+	return b.network(n, false, nil)
+}
+
+// DeleteUnusedRouteMaps requests the BGP daemon to delete the community
+// route-maps that are not referenced by any network statement.
+func (b *BGP) DeleteUnusedRouteMaps() error {
+	bgpConfigLock.Lock()
+	defer bgpConfigLock.Unlock()
+	return b.deleteUnusedRouteMaps()
+}
+
+// deleteUnusedRouteMaps deletes the community route-maps that are not
+// referenced by any network statement. The caller must hold bgpConfigLock.
+func (b *BGP) deleteUnusedRouteMaps() error {
+	cfg, err := b.Configuration()
+	if err != nil {
+		return err
+	}
+	if cmds := deleteRouteMapCommands(unusedRouteMaps(cfg)); cmds != nil {
+		return b.vty.Commands(cmds)
+	}
+	return nil
+}
+
+// unusedRouteMaps returns the community route-maps that are defined in the
+// given BGP configuration, but are not referenced by any network statement.
+func unusedRouteMaps(cfg []string) []string {
+	defined := make(map[string]bool)
+	used := make(map[string]bool)
+	for _, line := range cfg {
+		f := strings.Fields(line)
+		switch {
+		case len(f) > 1 && f[0] == "route-map" && strings.HasPrefix(f[1], CommunityRouteMapPrefix):
+			defined[f[1]] = true
+		case len(f) > 3 && f[0] == "network" && f[2] == "route-map":
+			used[f[3]] = true
+		}
+	}
+	var unused []string
+	for routeMap := range defined {
+		if !used[routeMap] {
+			unused = append(unused, routeMap)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// deleteRouteMapCommands returns the commands needed to delete the given
+// route-maps, or nil if there are none.
+func deleteRouteMapCommands(routeMaps []string) []string {
+	if len(routeMaps) == 0 {
+		return nil
+	}
+	cmds := []string{"configure terminal"}
+	for _, routeMap := range routeMaps {
+		cmds = append(cmds, fmt.Sprintf("no route-map %s", routeMap))
+	}
+	return append(cmds, "end")
+}
+
+// CommunityRouteMapPrefix is the prefix for the names of the route-maps that
+// tag advertised networks with BGP communities.
+const CommunityRouteMapPrefix = "seesaw-communities-"
+
+// CommunityRouteMap returns the name of the route-map that sets the given
+// BGP communities. The name does not depend on the order of the communities.
+func CommunityRouteMap(communities []string) string {
+	sorted := append([]string(nil), communities...)
+	sort.Strings(sorted)
+	sum := sha1.Sum([]byte(strings.Join(sorted, " ")))
+	return CommunityRouteMapPrefix + hex.EncodeToString(sum[:4])
+}
+
+// wellKnownCommunities are the well-known standard BGP communities that may be
+// given by name.
+var wellKnownCommunities = map[string]bool{
+	"internet":     true,
+	"local-AS":     true,
+	"no-advertise": true,
+	"no-export":    true,
+}
+
+// ValidateCommunities returns an error if any of the given BGP communities is
+// invalid. Standard communities are given as "AS:value" (each a 16 bit
+// number) or as a well-known community name, while large communities are
+// given as "ASN:value:value" (each a 32 bit number).
+func ValidateCommunities(communities []string) error {
+	for _, c := range communities {
+		if wellKnownCommunities[c] {
+			continue
+		}
+		parts := strings.Split(c, ":")
+		bits := 16
+		switch len(parts) {
+		case 2:
+		case 3:
+			bits = 32
+		default:
+			return fmt.Errorf("invalid BGP community %q", c)
+		}
+		for _, p := range parts {
+			if _, err := strconv.ParseUint(p, 10, bits); err != nil {
+				return fmt.Errorf("invalid BGP community %q", c)
+			}
+		}
+	}
+	return nil
+}
+
+// splitCommunities splits the given BGP communities into standard and large
+// communities.
+func splitCommunities(communities []string) (standard, large []string) {
+	for _, c := range communities {
+		if strings.Count(c, ":") == 2 {
+			large = append(large, c)
+		} else {
+			standard = append(standard, c)
+		}
+	}
+	return standard, large
+}
+
+// Peer specifies the configuration for a BGP peer.
+type Peer struct {
+	Address     net.IP
+	RemoteAS    uint32
+	LocalAS     uint32 // The AS presented to the peer instead of our own (disabled if zero).
+	MultihopTTL uint8  // The maximum hop count for an eBGP session (disabled if zero).
+
+	// BFD ties the BGP session to a BFD session, so that the loss of the
+	// peer is detected within BFDInterval * BFDMultiplier. The BFD daemon's
+	// defaults are used if BFDInterval or BFDMultiplier is zero.
+	BFDEnabled    bool
+	BFDInterval   time.Duration // The minimum interval for sending and receiving BFD packets.
+	BFDMultiplier uint8         // The number of BFD packets that may be missed.
+}
+
+// Default and permitted BFD session parameters.
+const (
+	DefaultBFDInterval   = 300 * time.Millisecond
+	DefaultBFDMultiplier = 3
+
+	minBFDInterval   = 50 * time.Millisecond
+	maxBFDInterval   = 60 * time.Second
+	minBFDMultiplier = 2
+)
+
+// asTrans is the AS number used to represent 4 octet AS numbers to peers that
+// only support 2 octet AS numbers (RFC 6793).
+const asTrans = 23456
+
+// ParseASN parses an AS number, in either asplain ("65536") or asdot ("1.0")
+// notation.
+func ParseASN(s string) (uint32, error) {
+	if parts := strings.Split(s, "."); len(parts) == 2 {
+		high, err1 := strconv.ParseUint(parts[0], 10, 16)
+		low, err2 := strconv.ParseUint(parts[1], 10, 16)
+		if err1 != nil || err2 != nil {
+			return 0, fmt.Errorf("invalid AS number %q", s)
+		}
+		return uint32(high<<16 | low), nil
+	}
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid AS number %q", s)
+	}
+	return uint32(asn), nil
+}
+
+// validASN returns an error if the given AS number may not be used by a peer.
+func validASN(asn uint32) error {
+	switch asn {
+	case 0:
+		return errors.New("AS number 0 is reserved")
+	case asTrans:
+		return fmt.Errorf("AS number %d is reserved", asTrans)
+	}
+	return nil
+}
+
+// Validate returns an error if the peer configuration is invalid.
+func (p *Peer) Validate() error {
+	if p.Address == nil {
+		return errors.New("peer address is not specified")
+	}
+	if err := validASN(p.RemoteAS); err != nil {
+		return fmt.Errorf("invalid remote AS for peer %v: %v", p.Address, err)
+	}
+	if p.LocalAS != 0 {
+		if err := validASN(p.LocalAS); err != nil {
+			return fmt.Errorf("invalid local AS for peer %v: %v", p.Address, err)
+		}
+	}
+	if i := p.BFDInterval; i != 0 && (i < minBFDInterval || i > maxBFDInterval || i%time.Millisecond != 0) {
+		return fmt.Errorf("invalid BFD interval %v for peer %v - must be a number of milliseconds between %v and %v",
+			i, p.Address, minBFDInterval, maxBFDInterval)
+	}
+	if m := p.BFDMultiplier; m != 0 && m < minBFDMultiplier {
+		return fmt.Errorf("invalid BFD multiplier %d for peer %v - must be between %d and 255",
+			m, p.Address, minBFDMultiplier)
+	}
+	return nil
+}
+
+// ConfigurePeer configures the specified peer in the BGP daemon. Only the
+// configuration for the given peer is changed, so that sessions with other
+// peers are not affected.
+func (b *BGP) ConfigurePeer(p *Peer) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	bgpConfigLock.Lock()
+	defer bgpConfigLock.Unlock()
+	return b.vty.Commands(b.peerCommands(p))
+}
+
+// peerCommands returns the commands needed to configure a peer. Options that
+// are not enabled for the peer are explicitly removed, so that a change to
+// the peer's configuration is applied when it is reconfigured.
+func (b *BGP) peerCommands(p *Peer) []string {
+	neighbor := fmt.Sprintf("neighbor %s", p.Address)
+	cmds := []string{
+		"configure terminal",
+		fmt.Sprintf("router bgp %d", b.asn),
+		fmt.Sprintf("%s remote-as %d", neighbor, p.RemoteAS),
+	}
+	if p.MultihopTTL > 0 {
+		cmds = append(cmds, fmt.Sprintf("%s ebgp-multihop %d", neighbor, p.MultihopTTL))
+	} else {
+		cmds = append(cmds, fmt.Sprintf("no %s ebgp-multihop", neighbor))
+	}
+	if p.LocalAS > 0 {
+		cmds = append(cmds, fmt.Sprintf("%s local-as %d", neighbor, p.LocalAS))
+	} else {
+		cmds = append(cmds, fmt.Sprintf("no %s local-as", neighbor))
+	}
+	if p.BFDEnabled {
+		interval, multiplier := p.BFDInterval, p.BFDMultiplier
+		if interval == 0 {
+			interval = DefaultBFDInterval
+		}
+		if multiplier == 0 {
+			multiplier = DefaultBFDMultiplier
+		}
+		ms := interval / time.Millisecond
+		cmds = append(cmds, fmt.Sprintf("%s bfd %d %d %d", neighbor, multiplier, ms, ms))
+	} else {
+		cmds = append(cmds, fmt.Sprintf("no %s bfd", neighbor))
+	}
+	return append(cmds, "end")
 }
 
 var (
@@ -239,14 +552,31 @@ var (
 	neighborStateRE   = regexp.MustCompile(`^  BGP state = (\w+)(, up for ([0-9wdhm:]+))?$`)
 	neighborStatsRE   = regexp.MustCompile(`^    (\w+): +(\d+) +(\d+)$`)
 	neighborVersionRE = regexp.MustCompile(`^  BGP version (\d), remote router ID ([a-f0-9.:]+)`)
+	neighborBFDRE     = regexp.MustCompile(`^ +BFD: `)
+	neighborBFDStatRE = regexp.MustCompile(`^ +Status: (\w+),`)
+	neighborPrefixRE  = regexp.MustCompile(`^  (\d+) accepted prefixes$`)
+	advertisedRE      = regexp.MustCompile(`^Total number of prefixes (\d+)`)
 )
 
+// parseAdvertisedRoutes parses the "show ip bgp neighbors <ip>
+// advertised-routes" output from the Quagga BGP daemon and returns the number
+// of prefixes advertised to the neighbor.
+func parseAdvertisedRoutes(ar string) uint64 {
+	for _, s := range strings.Split(ar, "\n") {
+		if m := advertisedRE.FindStringSubmatch(s); m != nil {
+			n, _ := strconv.ParseUint(m[1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
 // parseNeighbors parses the "show ip bgp neighbors" output from the Quagga
 // BGP daemon and returns a slice of Neighbor structs.
 func parseNeighbors(sn string) []*Neighbor {
 	neighbors := make([]*Neighbor, 0)
 	var neighbor *Neighbor
-	var msgStats bool
+	var msgStats, bfd bool
 	for _, s := range strings.Split(sn, "\n") {
 		if nm := neighborRE.FindStringSubmatch(s); nm != nil {
 			asn, _ := strconv.ParseUint(nm[2], 10, 32)
@@ -255,6 +585,7 @@ func parseNeighbors(sn string) []*Neighbor {
 				ASN: uint32(asn),
 			}
 			neighbors = append(neighbors, neighbor)
+			bfd = false
 		}
 		if neighbor == nil {
 			continue
@@ -298,6 +629,14 @@ func parseNeighbors(sn string) []*Neighbor {
 			neighbor.RouterID = net.ParseIP(nm[2])
 		} else if s == "  Message statistics:" {
 			msgStats = true
+		} else if nm := neighborPrefixRE.FindStringSubmatch(s); nm != nil {
+			prefixes, _ := strconv.ParseUint(nm[1], 10, 64)
+			neighbor.PrefixesReceived += prefixes
+		} else if neighborBFDRE.MatchString(s) {
+			bfd = true
+		} else if nm := neighborBFDStatRE.FindStringSubmatch(s); nm != nil && bfd {
+			neighbor.BFDStatus = nm[1]
+			bfd = false
 		}
 	}
 	return neighbors
@@ -17,10 +17,13 @@
 package quagga
 
 import (
+	"bufio"
 	"io/ioutil"
 	"net"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -79,6 +82,9 @@ var testNeighbors = []*Neighbor{
 		RouterID:    net.ParseIP("192.168.1.254"),
 		BGPState:    BGPStateEstablished,
 		Uptime:      ParseUptime("03w2d10h"),
+		BFDStatus:   "Up",
+
+		PrefixesReceived: 2,
 		MessageStats: MessageStats{
 			MessageStat{1, 1},
 			MessageStat{0, 0},
@@ -127,3 +133,311 @@ func TestParseNeighbors(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAdvertisedRoutes(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join(testDataDir, "advertised-routes"))
+	if err != nil {
+		t.Fatalf("Failed to open %v", err)
+	}
+	if got, want := parseAdvertisedRoutes(string(b)), uint64(3); got != want {
+		t.Errorf("parseAdvertisedRoutes = %d, want %d", got, want)
+	}
+	if got := parseAdvertisedRoutes(""); got != 0 {
+		t.Errorf("parseAdvertisedRoutes with no routes = %d, want 0", got)
+	}
+}
+
+func TestNetworkCommands(t *testing.T) {
+	b := NewBGP("", 64512)
+	n := &net.IPNet{IP: net.ParseIP("192.168.255.1"), Mask: net.CIDRMask(32, 32)}
+	n6 := &net.IPNet{IP: net.ParseIP("2015:cafe:ffff::1"), Mask: net.CIDRMask(128, 128)}
+	communities := []string{"64512:100", "no-export", "64512:1:2"}
+	routeMap := CommunityRouteMap(communities)
+
+	tests := []struct {
+		desc        string
+		n           *net.IPNet
+		advertise   bool
+		communities []string
+		want        []string
+	}{
+		{
+			"advertise", n, true, nil,
+			[]string{"configure terminal", "router bgp 64512", "address-family ipv4 unicast", "network 192.168.255.1/32", "end"},
+		},
+		{
+			"withdraw", n6, false, nil,
+			[]string{"configure terminal", "router bgp 64512", "address-family ipv6", "no network 2015:cafe:ffff::1/128", "end"},
+		},
+		{
+			"advertise with communities", n, true, communities,
+			[]string{
+				"configure terminal",
+				"route-map " + routeMap + " permit 10",
+				"set community 64512:100 no-export",
+				"set large-community 64512:1:2",
+				"exit",
+				"router bgp 64512",
+				"address-family ipv4 unicast",
+				"network 192.168.255.1/32 route-map " + routeMap,
+				"end",
+			},
+		},
+	}
+	for _, test := range tests {
+		got := b.networkCommands(test.n, test.advertise, test.communities)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("networkCommands (%s) = %q, want %q", test.desc, got, test.want)
+		}
+	}
+}
+
+// fakeBGPDaemon is a fake BGP daemon VTY that records the commands it
+// receives and returns the given configuration for "write terminal".
+type fakeBGPDaemon struct {
+	config   []string
+	commands []string
+}
+
+func (d *fakeBGPDaemon) serve(conn net.Conn, done chan<- bool) {
+	defer close(done)
+	r := bufio.NewReader(conn)
+	for {
+		cmd, err := r.ReadString(0)
+		if err != nil {
+			return
+		}
+		cmd = strings.TrimSuffix(cmd, "\x00")
+		d.commands = append(d.commands, cmd)
+		var reply string
+		if cmd == "write terminal" {
+			reply = strings.Join(d.config, "\n")
+		}
+		if _, err := conn.Write(append([]byte(reply), 0, 0, 0, 0)); err != nil {
+			return
+		}
+	}
+}
+
+func TestNetworkRouteMapCleanup(t *testing.T) {
+	n := &net.IPNet{IP: net.ParseIP("192.168.255.1"), Mask: net.CIDRMask(32, 32)}
+	oldMap := CommunityRouteMap([]string{"64512:100"})
+	newMap := CommunityRouteMap([]string{"64512:200"})
+	otherMap := CommunityRouteMap([]string{"64512:300"})
+
+	// The configuration as it is after the network statement has changed.
+	// The route-map for another network is still referenced, while route-maps
+	// not created by Seesaw are left alone.
+	config := func(networks ...string) []string {
+		cfg := []string{
+			"route-map " + newMap + " permit 10",
+			" set community 64512:200",
+			"!",
+			"route-map " + oldMap + " permit 10",
+			" set community 64512:100",
+			"!",
+			"route-map " + otherMap + " permit 10",
+			" set community 64512:300",
+			"!",
+			"route-map other permit 10",
+			"!",
+			"router bgp 64512",
+			" address-family ipv4 unicast",
+			"  network 192.168.255.2/32 route-map " + otherMap,
+		}
+		for _, network := range networks {
+			cfg = append(cfg, "  network "+network)
+		}
+		return append(cfg, " exit-address-family", "!")
+	}
+
+	tests := []struct {
+		desc      string
+		apply     func(b *BGP) error
+		config    []string
+		network   []string
+		routeMaps []string
+	}{
+		{
+			"communities changed",
+			func(b *BGP) error { return b.AdvertiseWithCommunities(n, []string{"64512:200"}) },
+			config("192.168.255.1/32 route-map " + newMap),
+			NewBGP("", 64512).networkCommands(n, true, []string{"64512:200"}),
+			[]string{oldMap},
+		},
+		{
+			"withdrawn",
+			func(b *BGP) error { return b.Withdraw(n) },
+			config(),
+			NewBGP("", 64512).networkCommands(n, false, nil),
+			[]string{newMap, oldMap},
+		},
+		{
+			"no unused route-maps",
+			func(b *BGP) error { return b.Advertise(n) },
+			config("192.168.255.1/32 route-map "+newMap, "192.168.255.3/32 route-map "+oldMap),
+			NewBGP("", 64512).networkCommands(n, true, nil),
+			nil,
+		},
+	}
+	for _, test := range tests {
+		client, server := net.Pipe()
+		daemon := &fakeBGPDaemon{config: test.config}
+		done := make(chan bool)
+		go daemon.serve(server, done)
+
+		b := NewBGP("", 64512)
+		b.vty.conn = client
+		if err := test.apply(b); err != nil {
+			t.Errorf("Test %q: failed to apply network: %v", test.desc, err)
+		}
+		client.Close()
+		<-done
+
+		want := append(test.network, "write terminal")
+		if len(test.routeMaps) > 0 {
+			sort.Strings(test.routeMaps)
+			want = append(want, "configure terminal")
+			for _, routeMap := range test.routeMaps {
+				want = append(want, "no route-map "+routeMap)
+			}
+			want = append(want, "end")
+		}
+		if !reflect.DeepEqual(daemon.commands, want) {
+			t.Errorf("Test %q: got commands %q, want %q", test.desc, daemon.commands, want)
+		}
+	}
+}
+
+func TestCommunityRouteMap(t *testing.T) {
+	a := CommunityRouteMap([]string{"64512:100", "64512:200"})
+	if b := CommunityRouteMap([]string{"64512:200", "64512:100"}); a != b {
+		t.Errorf("Route-maps for reordered communities differ: %q != %q", a, b)
+	}
+	if b := CommunityRouteMap([]string{"64512:100"}); a == b {
+		t.Errorf("Route-maps for different communities are both %q", a)
+	}
+	if !strings.HasPrefix(a, CommunityRouteMapPrefix) {
+		t.Errorf("Route-map %q does not have prefix %q", a, CommunityRouteMapPrefix)
+	}
+}
+
+func TestValidateCommunities(t *testing.T) {
+	tests := []struct {
+		communities []string
+		ok          bool
+	}{
+		{nil, true},
+		{[]string{"64512:100", "65535:65535", "no-export", "local-AS"}, true},
+		{[]string{"64512:1:2", "4294967295:4294967295:0"}, true},
+		{[]string{"65536:100"}, false},
+		{[]string{"64512:100", "bogus"}, false},
+		{[]string{"4294967296:1:2"}, false},
+		{[]string{"1:2:3:4"}, false},
+		{[]string{":100"}, false},
+	}
+	for _, test := range tests {
+		err := ValidateCommunities(test.communities)
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("ValidateCommunities(%q) returned %v", test.communities, err)
+		}
+	}
+}
+
+func TestParseASN(t *testing.T) {
+	tests := []struct {
+		s    string
+		want uint32
+		ok   bool
+	}{
+		{"64512", 64512, true},
+		{"4200000000", 4200000000, true},
+		{"1.0", 65536, true},
+		{"65535.65535", 4294967295, true},
+		{"4294967296", 0, false},
+		{"65536.0", 0, false},
+		{"1.2.3", 0, false},
+		{"AS64512", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		got, err := ParseASN(test.s)
+		if (err == nil) != test.ok {
+			t.Errorf("ParseASN(%q) returned error %v, want ok %t", test.s, err, test.ok)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseASN(%q) = %d, want %d", test.s, got, test.want)
+		}
+	}
+}
+
+func TestPeerCommands(t *testing.T) {
+	b := NewBGP("", 64512)
+	peer := &Peer{Address: net.ParseIP("192.168.10.1"), RemoteAS: 65001, LocalAS: 65100, MultihopTTL: 4}
+	want := []string{
+		"configure terminal",
+		"router bgp 64512",
+		"neighbor 192.168.10.1 remote-as 65001",
+		"neighbor 192.168.10.1 ebgp-multihop 4",
+		"neighbor 192.168.10.1 local-as 65100",
+		"no neighbor 192.168.10.1 bfd",
+		"end",
+	}
+	if got := b.peerCommands(peer); !reflect.DeepEqual(got, want) {
+		t.Errorf("peerCommands = %q, want %q", got, want)
+	}
+
+	// Options that are not enabled are removed from the peer.
+	peer = &Peer{Address: net.ParseIP("2015:cafe::1"), RemoteAS: 65002}
+	want = []string{
+		"configure terminal",
+		"router bgp 64512",
+		"neighbor 2015:cafe::1 remote-as 65002",
+		"no neighbor 2015:cafe::1 ebgp-multihop",
+		"no neighbor 2015:cafe::1 local-as",
+		"no neighbor 2015:cafe::1 bfd",
+		"end",
+	}
+	if got := b.peerCommands(peer); !reflect.DeepEqual(got, want) {
+		t.Errorf("peerCommands = %q, want %q", got, want)
+	}
+
+	// BFD uses the default parameters unless they are specified.
+	peer.BFDEnabled = true
+	want[5] = "neighbor 2015:cafe::1 bfd 3 300 300"
+	if got := b.peerCommands(peer); !reflect.DeepEqual(got, want) {
+		t.Errorf("peerCommands = %q, want %q", got, want)
+	}
+	peer.BFDInterval = 100 * time.Millisecond
+	peer.BFDMultiplier = 5
+	want[5] = "neighbor 2015:cafe::1 bfd 5 100 100"
+	if got := b.peerCommands(peer); !reflect.DeepEqual(got, want) {
+		t.Errorf("peerCommands = %q, want %q", got, want)
+	}
+}
+
+func TestPeerValidate(t *testing.T) {
+	ip := net.ParseIP("192.168.10.1")
+	tests := []struct {
+		peer Peer
+		ok   bool
+	}{
+		{Peer{Address: ip, RemoteAS: 65001}, true},
+		{Peer{Address: ip, RemoteAS: 65001, LocalAS: 4200000000, MultihopTTL: 255}, true},
+		{Peer{RemoteAS: 65001}, false},
+		{Peer{Address: ip}, false},
+		{Peer{Address: ip, RemoteAS: 23456}, false},
+		{Peer{Address: ip, RemoteAS: 65001, LocalAS: 23456}, false},
+		{Peer{Address: ip, RemoteAS: 65001, BFDEnabled: true, BFDInterval: 50 * time.Millisecond, BFDMultiplier: 2}, true},
+		{Peer{Address: ip, RemoteAS: 65001, BFDEnabled: true, BFDInterval: 10 * time.Millisecond}, false},
+		{Peer{Address: ip, RemoteAS: 65001, BFDEnabled: true, BFDInterval: 2 * time.Minute}, false},
+		{Peer{Address: ip, RemoteAS: 65001, BFDEnabled: true, BFDInterval: 1500 * time.Microsecond}, false},
+		{Peer{Address: ip, RemoteAS: 65001, BFDEnabled: true, BFDMultiplier: 1}, false},
+	}
+	for _, test := range tests {
+		if err := test.peer.Validate(); (err == nil) != test.ok {
+			t.Errorf("Validate(%+v) returned error %v, want ok %t", test.peer, err, test.ok)
+		}
+	}
+}
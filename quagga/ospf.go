@@ -0,0 +1,174 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quagga
+
+// This file contains structures and functions for manipulating the Quagga
+// OSPF daemon.
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const OSPFSocketPath = "/var/run/quagga/ospfd.vty"
+
+var ospfConfigLock sync.Mutex
+
+// OSPFArea is an OSPF area ID.
+type OSPFArea uint32
+
+// ParseOSPFArea parses an OSPF area ID, in either decimal ("0") or dotted
+// quad ("0.0.0.0") notation.
+func ParseOSPFArea(s string) (OSPFArea, error) {
+	if strings.Contains(s, ".") {
+		ip := net.ParseIP(s).To4()
+		if ip == nil {
+			return 0, fmt.Errorf("invalid OSPF area %q", s)
+		}
+		return OSPFArea(uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])), nil
+	}
+	area, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid OSPF area %q", s)
+	}
+	return OSPFArea(area), nil
+}
+
+// String returns the OSPF area ID in dotted quad notation.
+func (a OSPFArea) String() string {
+	return net.IPv4(byte(a>>24), byte(a>>16), byte(a>>8), byte(a)).String()
+}
+
+// OSPF contains the data needed to interface with the Quagga OSPF daemon.
+type OSPF struct {
+	vty  *VTY
+	area OSPFArea
+}
+
+// NewOSPF returns an initialised OSPF structure, which advertises networks
+// into the given area.
+func NewOSPF(socket string, area OSPFArea) *OSPF {
+	if socket == "" {
+		socket = OSPFSocketPath
+	}
+	return &OSPF{
+		vty:  NewVTY(socket),
+		area: area,
+	}
+}
+
+// Dial establishes a connection to the VTY of the OSPF daemon.
+func (o *OSPF) Dial() error {
+	return o.vty.Dial()
+}
+
+// Close closes a connection to the VTY of the OSPF daemon.
+func (o *OSPF) Close() error {
+	return o.vty.Close()
+}
+
+// Enable issues an "enable" command to the OSPF daemon.
+func (o *OSPF) Enable() error {
+	_, err := o.vty.Command("enable")
+	return err
+}
+
+// Configuration returns the current running configuration from the OSPF
+// daemon, as a slice of strings.
+func (o *OSPF) Configuration() ([]string, error) {
+	cfg, err := o.vty.Command("write terminal")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(cfg, "\n"), nil
+}
+
+// network adds or removes a network statement from the OSPF configuration.
+func (o *OSPF) network(n *net.IPNet, advertise bool) error {
+	if n.IP.To4() == nil {
+		return errors.New("OSPF only supports IPv4 networks")
+	}
+	ospfConfigLock.Lock()
+	defer ospfConfigLock.Unlock()
+	return o.vty.Commands(o.networkCommands(n, advertise))
+}
+
+// networkCommands returns the commands needed to add or remove a network
+// statement. The OSPF daemon advertises the addresses of the interfaces that
+// match a network statement, hence a host network results in a host route for
+// an address that is configured on one of this node's interfaces.
+func (o *OSPF) networkCommands(n *net.IPNet, advertise bool) []string {
+	prefixLen, _ := n.Mask.Size()
+	network := fmt.Sprintf("network %s/%d area %s", n.IP, prefixLen, o.area)
+	if !advertise {
+		network = "no " + network
+	}
+	return []string{
+		"configure terminal",
+		"router ospf",
+		network,
+		"end",
+	}
+}
+
+// Advertise requests the OSPF daemon to advertise the specified network.
+func (o *OSPF) Advertise(n *net.IPNet) error {
+	return o.network(n, true)
+}
+
+// Withdraw requests the OSPF daemon to withdraw advertisements for the
+// specified network.
+func (o *OSPF) Withdraw(n *net.IPNet) error {
+	return o.network(n, false)
+}
+
+// HostNetworks returns the host networks from the given OSPF configuration
+// that are advertised into this area.
+func (o *OSPF) HostNetworks(cfg []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	found := false
+	for _, line := range cfg {
+		if line == "router ospf" {
+			found = true
+			continue
+		}
+		if !found || line == "!" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			break
+		}
+		f := strings.Fields(line)
+		if len(f) != 4 || f[0] != "network" || f[2] != "area" {
+			continue
+		}
+		ip, n, err := net.ParseCIDR(f[1])
+		if err != nil {
+			return nil, err
+		}
+		if ones, bits := n.Mask.Size(); ones != bits {
+			continue
+		}
+		if area, err := ParseOSPFArea(f[3]); err != nil || area != o.area {
+			continue
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: n.Mask})
+	}
+	return networks, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quagga
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseOSPFArea(t *testing.T) {
+	tests := []struct {
+		s    string
+		want OSPFArea
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"0.0.0.0", 0, true},
+		{"10", 10, true},
+		{"0.0.0.10", 10, true},
+		{"1.0.0.0", 1 << 24, true},
+		{"4294967295", 4294967295, true},
+		{"4294967296", 0, false},
+		{"1.2.3", 0, false},
+		{"backbone", 0, false},
+	}
+	for _, test := range tests {
+		got, err := ParseOSPFArea(test.s)
+		if (err == nil) != test.ok {
+			t.Errorf("ParseOSPFArea(%q) returned error %v, want ok %t", test.s, err, test.ok)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseOSPFArea(%q) = %v, want %v", test.s, got, test.want)
+		}
+	}
+	if got, want := OSPFArea(10).String(), "0.0.0.10"; got != want {
+		t.Errorf("OSPFArea(10).String() = %q, want %q", got, want)
+	}
+}
+
+func TestOSPFNetworkCommands(t *testing.T) {
+	o := NewOSPF("", 10)
+	n := &net.IPNet{IP: net.ParseIP("192.168.255.1"), Mask: net.CIDRMask(32, 32)}
+	want := []string{"configure terminal", "router ospf", "network 192.168.255.1/32 area 0.0.0.10", "end"}
+	if got := o.networkCommands(n, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("networkCommands (advertise) = %q, want %q", got, want)
+	}
+	want[2] = "no network 192.168.255.1/32 area 0.0.0.10"
+	if got := o.networkCommands(n, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("networkCommands (withdraw) = %q, want %q", got, want)
+	}
+
+	n6 := &net.IPNet{IP: net.ParseIP("2015:cafe:ffff::1"), Mask: net.CIDRMask(128, 128)}
+	if err := o.Advertise(n6); err == nil {
+		t.Errorf("Advertise succeeded for IPv6 network %v", n6)
+	}
+}
+
+func TestOSPFHostNetworks(t *testing.T) {
+	cfg := []string{
+		"interface eth0",
+		"!",
+		"router ospf",
+		" ospf router-id 192.168.10.2",
+		" network 192.168.10.0/24 area 0.0.0.0",
+		" network 192.168.255.1/32 area 0.0.0.0",
+		" network 192.168.255.2/32 area 0.0.0.10",
+		" network 192.168.255.3/32 area 0.0.0.0",
+		"!",
+		"line vty",
+		" network 192.168.255.4/32 area 0.0.0.0",
+	}
+	networks, err := NewOSPF("", 0).HostNetworks(cfg)
+	if err != nil {
+		t.Fatalf("HostNetworks failed: %v", err)
+	}
+	var got []string
+	for _, n := range networks {
+		got = append(got, n.String())
+	}
+	want := []string{"192.168.255.1/32", "192.168.255.3/32"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HostNetworks = %q, want %q", got, want)
+	}
+}